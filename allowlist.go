@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AllowListReport は許可リストとの照合結果です。
+type AllowListReport struct {
+	// Unexpected は許可リストに無いのにアーカイブに存在するフォルダです。
+	Unexpected []FolderCount
+	// Missing は許可リストにあるのにアーカイブにファイルが無いフォルダです。
+	Missing []string
+	// Matched は許可リストにあり、アーカイブにも存在するフォルダの数です。
+	Matched int
+}
+
+// LoadAllowList は許可リストを読み込みます。
+// 拡張子が .db / .sqlite / .sqlite3 の場合はSQLiteの allowed_folders テーブルから、
+// それ以外はCSVの1列目から読み込みます。
+func LoadAllowList(listPath string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(listPath)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return loadAllowListSQLite(listPath)
+	}
+	f, err := os.Open(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open allow list: %w", err)
+	}
+	defer f.Close()
+	return ParseAllowListCSV(f)
+}
+
+// ParseAllowListCSV はCSVの1列目をフォルダパスとして読み込みます。
+// BOM、空行、"Folder Path" の見出し行は無視します。
+func ParseAllowListCSV(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})))
+	reader.FieldsPerRecord = -1
+
+	var paths []string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse allow list: %w", err)
+		}
+		p := normalizeAllowedPath(record[0])
+		if p == "" || (len(paths) == 0 && strings.EqualFold(p, "Folder Path")) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// loadAllowListSQLite はSQLiteの allowed_folders テーブルの folder_path 列を読み込みます。
+func loadAllowListSQLite(dbPath string) ([]string, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open allow list: %w", err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT folder_path FROM allowed_folders")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query allowed_folders: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		if p = normalizeAllowedPath(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, rows.Err()
+}
+
+// normalizeAllowedPath は許可リストのパスを集計キーと同じ "/" 区切りに揃えます。(純粋関数)
+func normalizeAllowedPath(p string) string {
+	p = strings.ReplaceAll(strings.TrimSpace(p), "\\", "/")
+	return strings.Trim(p, "/")
+}
+
+// CompareAllowList は集計結果を許可リストと照合します。(純粋関数)
+// Unexpected は件数の降順、Missing はパスの昇順でソートされます。
+func CompareAllowList(results []FolderCount, allowed []string) AllowListReport {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, p := range allowed {
+		allowedSet[p] = true
+	}
+
+	var report AllowListReport
+	found := make(map[string]bool, len(results))
+	for _, r := range results {
+		found[r.Path] = true
+		if allowedSet[r.Path] {
+			report.Matched++
+		} else {
+			report.Unexpected = append(report.Unexpected, r)
+		}
+	}
+	for p := range allowedSet {
+		if !found[p] {
+			report.Missing = append(report.Missing, p)
+		}
+	}
+	sort.Strings(report.Missing)
+	return report
+}
+
+// WriteAllowListReport は照合結果をプレーンテキストでWriterに出力します。
+func WriteAllowListReport(w io.Writer, report AllowListReport, lang Lang) error {
+	_, err := fmt.Fprintf(w, "%s: %d, %s: %d, %s: %d\n",
+		lang.T("Matched"), report.Matched, lang.T("Unexpected"), len(report.Unexpected), lang.T("Missing"), len(report.Missing))
+	if err != nil {
+		return err
+	}
+	if len(report.Unexpected) > 0 {
+		fmt.Fprintf(w, "\n%s:\n", lang.T("Unexpected folders"))
+		for _, r := range report.Unexpected {
+			if _, err := fmt.Fprintf(w, "  + %s (%d)\n", r.Path, r.Count); err != nil {
+				return err
+			}
+		}
+	}
+	if len(report.Missing) > 0 {
+		fmt.Fprintf(w, "\n%s:\n", lang.T("Missing folders"))
+		for _, p := range report.Missing {
+			if _, err := fmt.Fprintf(w, "  - %s\n", p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runAllowList は allowlist コマンドを実行します。
+// 想定外のフォルダまたは欠けているフォルダがあれば終了コード1で終了します。
+func runAllowList(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "allowlist", "allowlist -zip <path> -list <allowed.csv|allowed.db> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	listPath := fs.String("list", "", "許可するフォルダの一覧 (必須、CSVまたはSQLite)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	if *listPath == "" {
+		return usageError(fs, "allow list path is required")
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	allowed, err := LoadAllowList(*listPath)
+	if err != nil {
+		return err
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	results, _, err := app.Analyze(*zipPath, opts)
+	if err != nil {
+		return err
+	}
+
+	report := CompareAllowList(results, allowed)
+	styled := AllowListReport{Unexpected: cfg.Paths.Folders(report.Unexpected), Missing: cfg.Paths.Paths(report.Missing), Matched: report.Matched}
+	if err := WriteAllowListReport(env.Stdout, styled, env.lang); err != nil {
+		return err
+	}
+	if n := len(report.Unexpected) + len(report.Missing); n > 0 {
+		return fmt.Errorf("%d problem(s) found", n)
+	}
+	return nil
+}
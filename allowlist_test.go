@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ParseAllowListCSV のテスト
+func TestParseAllowListCSV(t *testing.T) {
+	input := "\xEF\xBB\xBFFolder Path,File Count\ndocs/2023,10\n\n\"a\\b\",3\n(Root)\n"
+	paths, err := ParseAllowListCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"docs/2023", "a/b", "(Root)"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
+
+// CompareAllowList のテスト
+func TestCompareAllowList(t *testing.T) {
+	results := []FolderCount{
+		{Path: "docs", Count: 5},
+		{Path: "tmp", Count: 2},
+		{Path: "(Root)", Count: 1},
+	}
+	report := CompareAllowList(results, []string{"docs", "(Root)", "scans", "images"})
+	expected := AllowListReport{
+		Unexpected: []FolderCount{{Path: "tmp", Count: 2}},
+		Missing:    []string{"images", "scans"},
+		Matched:    2,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+}
+
+// LoadAllowList のテスト (SQLiteからの読み込み)
+func TestLoadAllowListSQLite(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "allowed.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE allowed_folders (folder_path TEXT); INSERT INTO allowed_folders VALUES ('docs/2023'), ('scans');"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	paths, err := LoadAllowList(dbPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"docs/2023", "scans"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	if _, err := LoadAllowList(filepath.Join(t.TempDir(), "missing.db")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// エントリ名の文字コードの判定結果です。
+const (
+	// NameEncodingASCII はすべての名前がASCIIの範囲であることです。
+	NameEncodingASCII = "ascii"
+	// NameEncodingUTF8 はASCII以外の名前がUTF-8で記録されていることです。
+	NameEncodingUTF8 = "utf-8"
+	// NameEncodingLegacy はASCII以外の名前がUTF-8以外で記録され、-encoding-fallbacks で復号したことです。
+	NameEncodingLegacy = "non-utf-8"
+	// NameEncodingMixed はUTF-8の名前とUTF-8以外の名前が混在することです。
+	NameEncodingMixed = "mixed"
+)
+
+// ArchiveInfo はエントリの一覧と合わせて求める、アーカイブ全体の情報です。
+type ArchiveInfo struct {
+	// Size はアーカイブのバイト数です。URLのアーカイブでは0です。
+	Size int64 `json:"size,omitempty"`
+	// Entries はアーカイブ内のエントリの総数です。時間の上限で読み込みを打ち切った場合も、読み込まなかったエントリを含みます。
+	Entries int `json:"entries"`
+	// Comment はZIPのアーカイブのコメントです。
+	Comment string `json:"comment,omitempty"`
+	// Zip64 はZIP64の終端レコードを持つかを表します。
+	Zip64 bool `json:"zip64,omitempty"`
+	// EarliestModified と LatestModified は読み込んだファイルの更新日時の範囲です。ファイルが無い場合はゼロ値です。
+	EarliestModified time.Time `json:"earliestModified,omitzero"`
+	LatestModified   time.Time `json:"latestModified,omitzero"`
+	// Encoding はエントリ名の文字コードの判定結果 (ascii, utf-8, non-utf-8, mixed) です。
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// ArchiveInfoReader はエントリの一覧からは求められない、形式に固有のアーカイブ全体の情報を読み込める ArchiveReader です。
+type ArchiveInfoReader interface {
+	ArchiveReader
+	// ReadArchiveInfo はコメントなど形式に固有の情報を読み込みます。エントリの一覧から求める項目は設定しません。
+	ReadArchiveInfo(path string) (ArchiveInfo, error)
+}
+
+// ReadArchiveInfo は ArchiveInfoReader の実装です。ZIP以外の形式では形式に固有の情報はありません。
+func (a AutoArchiveReader) ReadArchiveInfo(archivePath string) (ArchiveInfo, error) {
+	if _, ok := registeredArchiveReader(archivePath); ok || a.kind(archivePath) != "zip" {
+		return ArchiveInfo{}, nil
+	}
+	return a.Zip.ReadArchiveInfo(archivePath)
+}
+
+// ReadArchiveInfo は ArchiveInfoReader の実装です。終端レコードからコメントとZIP64かどうかを読み込みます。
+// UTF-8でないコメントは Decoder で復号します。
+func (z ZipArchiveReader) ReadArchiveInfo(zipPath string) (ArchiveInfo, error) {
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return ArchiveInfo{}, fmt.Errorf("failed to open zip: %w", err)
+	}
+	end, err := readZipEnd(f, st.Size())
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+	comment := end.comment
+	if !utf8.ValidString(comment) && z.Decoder != nil {
+		if decoded, confidence := z.Decoder.Decode([]byte(comment)); confidence > ConfidenceNone {
+			comment = decoded
+		}
+	}
+	return ArchiveInfo{Comment: comment, Zip64: end.zip64}, nil
+}
+
+// readArchiveInfo はアーカイブ全体の情報を、読み込んだエントリと reader が読み込める形式に固有の情報から求めます。
+// total はアーカイブ内のエントリの総数です。形式に固有の情報は補足のため、読み込めない場合も失敗にしません。
+func readArchiveInfo(reader ArchiveReader, archivePath string, entries []FileEntry, total int) ArchiveInfo {
+	var info ArchiveInfo
+	if !isRemotePath(archivePath) {
+		if ir, ok := reader.(ArchiveInfoReader); ok {
+			if read, err := ir.ReadArchiveInfo(archivePath); err == nil {
+				info = read
+			}
+		}
+		if st, err := os.Stat(archivePath); err == nil {
+			info.Size = st.Size()
+		}
+	}
+	info.Entries = total
+	info.EarliestModified, info.LatestModified = modifiedRange(entries)
+	info.Encoding = DetectNameEncoding(entries)
+	return info
+}
+
+// modifiedRange はファイルの更新日時の最も古いものと新しいものを返します。日時の無いエントリは除きます。(純粋関数)
+func modifiedRange(entries []FileEntry) (earliest, latest time.Time) {
+	for _, e := range entries {
+		if e.IsDir || e.Modified.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || e.Modified.Before(earliest) {
+			earliest = e.Modified
+		}
+		if e.Modified.After(latest) {
+			latest = e.Modified
+		}
+	}
+	return earliest, latest
+}
+
+// DetectNameEncoding はエントリ名の記録された文字コードを判定します。エントリが無い場合は空文字列を返します。(純粋関数)
+// 復号前の名前がUTF-8として妥当で、復号後の名前と同じものをUTF-8とみなします。
+func DetectNameEncoding(entries []FileEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	utf8Names, legacyNames := false, false
+	for _, e := range entries {
+		raw := e.RawName
+		if raw == "" {
+			raw = e.Name
+		}
+		switch {
+		case isASCII(raw):
+		case utf8.ValidString(raw) && raw == e.Name:
+			utf8Names = true
+		default:
+			legacyNames = true
+		}
+	}
+	switch {
+	case utf8Names && legacyNames:
+		return NameEncodingMixed
+	case legacyNames:
+		return NameEncodingLegacy
+	case utf8Names:
+		return NameEncodingUTF8
+	}
+	return NameEncodingASCII
+}
+
+// isASCII は文字列がASCIIの範囲の文字だけからなるかを判定します。(純粋関数)
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// archiveInfoLines はアーカイブ全体の情報をテキストとMarkdownの出力の行にします。(純粋関数)
+func archiveInfoLines(info *ArchiveInfo, lang Lang) []string {
+	summary := []string{fmt.Sprintf("%s: %d", lang.T("Entries"), info.Entries)}
+	if info.Size > 0 {
+		summary = append([]string{fmt.Sprintf("%s: %s", lang.T("Size"), FormatSize(uint64(info.Size)))}, summary...)
+	}
+	if info.Encoding != "" {
+		summary = append(summary, fmt.Sprintf("%s: %s", lang.T("Encoding"), info.Encoding))
+	}
+	if info.Zip64 {
+		summary = append(summary, "ZIP64")
+	}
+	lines := []string{strings.Join(summary, ", ")}
+	if !info.EarliestModified.IsZero() {
+		lines = append(lines, fmt.Sprintf("%s: %s - %s", lang.T("Modified"),
+			info.EarliestModified.Format("2006-01-02 15:04:05"), info.LatestModified.Format("2006-01-02 15:04:05")))
+	}
+	if info.Comment != "" {
+		lines = append(lines, fmt.Sprintf("%s: %s", lang.T("Comment"), info.Comment))
+	}
+	return lines
+}
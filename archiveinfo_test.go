@@ -0,0 +1,76 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// DetectNameEncoding のテスト
+func TestDetectNameEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []FileEntry
+		expected string
+	}{
+		{name: "エントリなし", entries: nil, expected: ""},
+		{name: "ASCIIのみ", entries: []FileEntry{{Name: "a.txt", RawName: "a.txt"}}, expected: NameEncodingASCII},
+		{name: "UTF-8", entries: []FileEntry{{Name: "a.txt"}, {Name: "資料.txt", RawName: "資料.txt"}}, expected: NameEncodingUTF8},
+		{name: "UTF-8以外", entries: []FileEntry{{Name: "資料.txt", RawName: "\x8e\x91\x97\xbf.txt"}}, expected: NameEncodingLegacy},
+		{
+			name:     "混在",
+			entries:  []FileEntry{{Name: "資料.txt", RawName: "\x8e\x91\x97\xbf.txt"}, {Name: "写真.jpg", RawName: "写真.jpg"}},
+			expected: NameEncodingMixed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectNameEncoding(tt.entries); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// readArchiveInfo のテスト (ZIPのコメントと更新日時の範囲)
+func TestReadArchiveInfo(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.SetComment("delivery 2024-04")
+	if _, err := zw.Create("dir1/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "delivery.zip")
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	early, late := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 4, 30, 18, 0, 0, 0, time.UTC)
+	entries := []FileEntry{
+		{Name: "dir1/", IsDir: true, Modified: early.AddDate(-1, 0, 0)},
+		{Name: "dir1/a.txt", Modified: late},
+		{Name: "dir1/b.txt", Modified: early},
+	}
+
+	info := readArchiveInfo(AutoArchiveReader{}, zipPath, entries, 4)
+	expected := ArchiveInfo{Size: int64(buf.Len()), Entries: 4, Comment: "delivery 2024-04", EarliestModified: early, LatestModified: late, Encoding: NameEncodingASCII}
+	if info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	var out bytes.Buffer
+	if err := WriteText(&out, &Report{Archive: zipPath, Info: &info}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Entries: 4", "Comment: delivery 2024-04", "Modified: 2024-04-01 09:00:00 - 2024-04-30 18:00:00"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q, got: %s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic は write で書き出した内容を outPath と同じフォルダの一時ファイルに書いてから、outPath に置き換えます。
+// 書き出しの途中で失敗または中断しても、outPath に途中までの内容が残ることはありません。
+// 既存のファイルを置き換える場合はそのパーミッションを引き継ぎます。noClobber が true の場合、outPath が既にあればエラーを返します。
+// noClobber の確認と作成の間に別のプロセスが outPath を作成しても上書きしないよう、置き換えではなくハードリンクで作成します。
+func writeFileAtomic(outPath string, noClobber bool, write func(w io.Writer) error) error {
+	if noClobber {
+		if err := checkNoClobber(outPath); err != nil {
+			return err
+		}
+	}
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(outPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	// 出力先のフォルダを監視する後続の処理が拾わないよう、一時ファイルはドットで始まる名前にする
+	f, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if noClobber {
+		// os.Link は outPath が既にあれば失敗するため、確認と作成の間に作成されたファイルも上書きしない
+		if err := os.Link(f.Name(), outPath); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("output file already exists: %s", outPath)
+			}
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		return nil
+	}
+	if err := os.Rename(f.Name(), outPath); err != nil {
+		return fmt.Errorf("failed to replace output file: %w", err)
+	}
+	return nil
+}
+
+// checkNoClobber は出力先のファイルが既に存在する場合にエラーを返します。
+func checkNoClobber(outPath string) error {
+	_, err := os.Lstat(outPath)
+	switch {
+	case err == nil:
+		return fmt.Errorf("output file already exists: %s", outPath)
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	default:
+		return fmt.Errorf("failed to check output file: %w", err)
+	}
+}
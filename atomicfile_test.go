@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeFileAtomic のテスト
+func TestWriteFileAtomic(t *testing.T) {
+	writeString := func(s string) func(io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		}
+	}
+	failAfter := func(s string) func(io.Writer) error {
+		return func(w io.Writer) error {
+			io.WriteString(w, s)
+			return errors.New("disk full")
+		}
+	}
+
+	tests := []struct {
+		name      string
+		existing  string
+		noClobber bool
+		write     func(io.Writer) error
+		expected  string
+		wantErr   string
+	}{
+		{name: "新しいファイル", write: writeString("new"), expected: "new"},
+		{name: "既存のファイルを置き換え", existing: "old", write: writeString("new"), expected: "new"},
+		{name: "書き出しに失敗すると元のまま", existing: "old", write: failAfter("partial"), expected: "old", wantErr: "disk full"},
+		{name: "-no-clobber は既存のファイルを残す", existing: "old", noClobber: true, write: writeString("new"), expected: "old", wantErr: "already exists"},
+		{name: "-no-clobber で新しいファイル", noClobber: true, write: writeString("new"), expected: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := filepath.Join(dir, "report.csv")
+			if tt.existing != "" {
+				if err := os.WriteFile(p, []byte(tt.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := writeFileAtomic(p, tt.noClobber, tt.write)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(p); string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, data)
+			}
+			// 一時ファイルは成否によらず残らない
+			if files, _ := os.ReadDir(dir); len(files) > 1 {
+				t.Errorf("temporary file is left: %v", files)
+			}
+		})
+	}
+
+	t.Run("-no-clobber は書き出している間に作成されたファイルも残す", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "report.csv")
+		err := writeFileAtomic(p, true, func(w io.Writer) error {
+			// 確認の後、置き換えの前に別のプロセスが作成する
+			if err := os.WriteFile(p, []byte("other"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := io.WriteString(w, "new")
+			return err
+		})
+		if err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Fatalf("expected error containing %q, got %v", "already exists", err)
+		}
+		if data, _ := os.ReadFile(p); string(data) != "other" {
+			t.Errorf("expected %q, got %q", "other", data)
+		}
+	})
+
+	t.Run("パーミッションを引き継ぐ", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("permissions are not supported on windows")
+		}
+		p := filepath.Join(t.TempDir(), "report.csv")
+		if err := os.WriteFile(p, []byte("old"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFileAtomic(p, false, writeString("new")); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(p); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("expected mode 0600, got %v, %v", info.Mode(), err)
+		}
+	})
+}
+
+// count の -no-clobber のテスト
+func TestRunCLINoClobber(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/b.txt")
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.csv")
+	if err := os.WriteFile(existing, []byte("previous report"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	appendPath := filepath.Join(dir, "daily.csv")
+	if err := os.WriteFile(appendPath, []byte("Run Timestamp,Archive,Folder Path,File Count\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{name: "既存のファイルがあれば集計しない", args: []string{"-csv", existing, "-no-clobber"}, expectedCode: 1},
+		{name: "詳細レポートも対象", args: []string{"-csv", filepath.Join(dir, "new.csv"), "-detail-csv", existing, "-no-clobber"}, expectedCode: 1},
+		{name: "追記するファイルは対象外", args: []string{"-csv-append", appendPath, "-no-clobber"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"count", "-zip", zipPath, "-threshold", "1"}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if data, _ := os.ReadFile(existing); string(data) != "previous report" {
+				t.Errorf("existing file should not be changed, got %q", data)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.csv")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("no output should be written when a file already exists, got %v", err)
+	}
+	if data, _ := os.ReadFile(appendPath); !strings.Contains(string(data), ",test.zip,dir1,2") {
+		t.Errorf("rows should be appended, got %q", data)
+	}
+}
+
+// count の -overwrite と、一時ファイルを介さない -csv-append のテスト
+func TestRunCLIOverwriteAndAppend(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/b.txt")
+	dir := t.TempDir()
+
+	t.Run("-overwrite は既存のファイルを置き換える", func(t *testing.T) {
+		p := filepath.Join(dir, "result.csv")
+		if err := os.WriteFile(p, []byte("previous report"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-csv", p, "-overwrite"}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		if data, _ := os.ReadFile(p); !strings.Contains(string(data), "dir1,2") {
+			t.Errorf("file should be replaced, got %q", data)
+		}
+	})
+
+	t.Run("-overwrite と -no-clobber は同時に指定できない", func(t *testing.T) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"count", "-zip", zipPath, "-csv", filepath.Join(dir, "x.csv"), "-overwrite", "-no-clobber"}, stdout, stderr); code != 2 {
+			t.Fatalf("expected code 2, got %d", code)
+		}
+		if !strings.Contains(stderr.String(), "-overwrite cannot be combined with -no-clobber") {
+			t.Errorf("expected conflict message, got: %s", stderr.String())
+		}
+	})
+
+	t.Run("-csv-append は中断で途中までの行が残ったファイルにも追記する", func(t *testing.T) {
+		p := filepath.Join(dir, "daily.csv")
+		// 前回の追記が行の途中で中断された状態
+		partial := "Run Timestamp,Archive,Folder Path,File Count\n2024-04-01T00:00:00Z,test.zip,di"
+		if err := os.WriteFile(p, []byte(partial), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-csv-append", p, "-csv-encoding", "utf8"}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		// 途中までの行は残り、追記した行はその次の行から始まる
+		if len(lines) != 3 || lines[1] != "2024-04-01T00:00:00Z,test.zip,di" || !strings.HasSuffix(lines[2], ",test.zip,dir1,2") {
+			t.Errorf("unexpected content: %q", data)
+		}
+	})
+}
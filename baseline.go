@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// LoadBaseline は以前に count で保存したCSVまたはJSONのレポートを、比較の基準の集計結果として読み込みます。
+// 拡張子が .json の場合、または内容が "{" で始まる場合はJSON、それ以外はCSVとして読み込みます。
+func LoadBaseline(baselinePath string) ([]FolderCount, error) {
+	data, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	if strings.EqualFold(filepath.Ext(baselinePath), ".json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ParseBaselineJSON(bytes.NewReader(data))
+	}
+	return ParseBaselineCSV(bytes.NewReader(data))
+}
+
+// ParseBaselineJSON は count の json 形式のレポートからフォルダごとのファイル数を読み込みます。
+// ルートフォルダを表す空のパスは "(Root)" にします。
+func ParseBaselineJSON(r io.Reader) ([]FolderCount, error) {
+	var report struct {
+		Folders []struct {
+			Path  string `json:"path"`
+			Count int    `json:"count"`
+		} `json:"folders"`
+	}
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	results := make([]FolderCount, 0, len(report.Folders))
+	for _, f := range report.Folders {
+		results = append(results, FolderCount{Path: baselineKey(f.Path), Count: f.Count})
+	}
+	return results, nil
+}
+
+// ParseBaselineCSV は count の csv 形式のレポートからフォルダごとのファイル数を読み込みます。
+// 区切り文字 (カンマ、タブ、セミコロン) は見出し行から判定し、"Folder Path" と "File Count" の列を -columns の順序にかかわらず探します。
+// UTF-8でない内容は Shift_JIS として読み込みます。
+func ParseBaselineCSV(r io.Reader) ([]FolderCount, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		if data, err = japanese.ShiftJIS.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("failed to parse baseline: %w", err)
+		}
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comma = baselineDelimiter(data)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	pathCol, countCol := -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case "Folder Path":
+			pathCol = i
+		case "File Count":
+			countCol = i
+		}
+	}
+	if pathCol < 0 || countCol < 0 {
+		return nil, errors.New("failed to parse baseline: Folder Path and File Count columns are required")
+	}
+
+	var results []FolderCount
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return results, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse baseline: %w", err)
+		}
+		if len(record) <= max(pathCol, countCol) {
+			continue
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(record[countCol]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse baseline: invalid file count %q", record[countCol])
+		}
+		results = append(results, FolderCount{Path: baselineKey(record[pathCol]), Count: count})
+	}
+}
+
+// baselineDelimiter は見出し行に含まれる区切り文字を判定します。判定できない場合はカンマです。(純粋関数)
+func baselineDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	for _, d := range []rune{'\t', ';'} {
+		if bytes.ContainsRune(line, d) && !bytes.ContainsRune(line, ',') {
+			return d
+		}
+	}
+	return ','
+}
+
+// baselineKey はレポートに出力されたフォルダパスを "/" 区切りの集計キーに戻します。(純粋関数)
+// -root-label で変えたルートの表記は区別できないため、"(Root)" と空のパスだけをルートとみなします。
+func baselineKey(p string) string {
+	p = strings.Trim(strings.ReplaceAll(strings.TrimSpace(p), "\\", "/"), "/")
+	if p == "" {
+		return "(Root)"
+	}
+	return p
+}
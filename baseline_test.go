@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ParseBaselineCSV と ParseBaselineJSON のテスト
+func TestParseBaseline(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		json     bool
+		expected []FolderCount
+	}{
+		{
+			name:     "既定の列",
+			input:    "Folder Path,File Count\ndir1\\sub,3\n(Root),1\n",
+			expected: []FolderCount{{Path: "dir1/sub", Count: 3}, {Path: "(Root)", Count: 1}},
+		},
+		{
+			name:     "列の順序と区切り文字",
+			input:    "File Count\tPercent of Total\tFolder Path\n3\t75.0\tdir1/sub\n",
+			expected: []FolderCount{{Path: "dir1/sub", Count: 3}},
+		},
+		{
+			name:     "JSONのルートは空のパス",
+			input:    `{"archive": "old.zip", "folders": [{"path": "dir1", "count": 2}, {"path": "", "count": 1}]}`,
+			json:     true,
+			expected: []FolderCount{{Path: "dir1", Count: 2}, {Path: "(Root)", Count: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParseBaselineCSV
+			if tt.json {
+				parse = ParseBaselineJSON
+			}
+			got, err := parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("必要な列が無い", func(t *testing.T) {
+		if _, err := ParseBaselineCSV(strings.NewReader("Folder Path,Total Size\ndir1,10\n")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+// diff -baseline のテスト
+func TestRunDiffBaseline(t *testing.T) {
+	oldZip := writeTestZip(t, "dir1/a.txt", "dir1/b.txt", "old/c.txt")
+	newZip := writeTestZip(t, "dir1/a.txt", "dir1/b.txt", "dir1/c.txt", "new/d.txt")
+
+	for _, format := range []string{"csv", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var saved bytes.Buffer
+			if code := runCLI([]string{"count", "-zip", oldZip, "-threshold", "1", "-format", format}, &saved, new(bytes.Buffer)); code != 0 {
+				t.Fatalf("count failed with code %d", code)
+			}
+			baselinePath := filepath.Join(t.TempDir(), "old."+format)
+			if err := os.WriteFile(baselinePath, saved.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI([]string{"diff", "-format", "csv", "-baseline", baselinePath, newZip}, stdout, stderr); code != 0 {
+				t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+			}
+			expected := "\xEF\xBB\xBFFolder Path,Old Count,New Count,Delta,Change\n" +
+				"dir1,2,3,1,changed\nnew,0,1,1,added\nold,1,0,-1,removed\n"
+			if stdout.String() != expected {
+				t.Errorf("expected %q, got %q", expected, stdout.String())
+			}
+		})
+	}
+
+	t.Run("CSVの文字コードと区切り文字", func(t *testing.T) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"diff", "-format", "csv", "-csv-encoding", "utf8", "-csv-delimiter", "tab", oldZip, newZip}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		expected := "Folder Path\tOld Count\tNew Count\tDelta\tChange\n" +
+			"dir1\t2\t3\t1\tchanged\nnew\t0\t1\t1\tadded\nold\t1\t0\t-1\tremoved\n"
+		if stdout.String() != expected {
+			t.Errorf("expected %q, got %q", expected, stdout.String())
+		}
+	})
+
+	t.Run("-baseline ではアーカイブは1つ", func(t *testing.T) {
+		if code := runCLI([]string{"diff", "-baseline", "old.csv", oldZip, newZip}, new(bytes.Buffer), new(bytes.Buffer)); code != 2 {
+			t.Errorf("expected code 2, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// バッチ処理でのアーカイブごとの結果です。
+const (
+	BatchOK      = "OK"
+	BatchFailed  = "FAILED"
+	BatchSkipped = "SKIPPED"
+	// BatchUnchanged は状態ファイルに記録済みで、前回から変わっていないため処理しなかったことを表します。
+	BatchUnchanged = "UNCHANGED"
+)
+
+// ErrorPolicy はバッチ処理でアーカイブの処理に失敗した場合の扱いです。
+type ErrorPolicy struct {
+	// Abort が true の場合、失敗した時点で残りのアーカイブを処理せずに終了します。
+	Abort bool
+	// Retries は失敗したアーカイブを再試行する回数です。再試行しても失敗した場合は次のアーカイブに進みます。
+	Retries int
+	// RetryDelay は再試行までの待ち時間です。
+	RetryDelay time.Duration
+}
+
+// ParseErrorPolicy は -on-error の値 (skip, abort, retry:N) を解析します。(純粋関数)
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch s {
+	case "", "skip":
+		return ErrorPolicy{}, nil
+	case "abort":
+		return ErrorPolicy{Abort: true}, nil
+	}
+	if n, ok := strings.CutPrefix(s, "retry:"); ok {
+		retries, err := strconv.Atoi(n)
+		if err != nil || retries < 1 {
+			return ErrorPolicy{}, fmt.Errorf("invalid retry count: %s", n)
+		}
+		return ErrorPolicy{Retries: retries}, nil
+	}
+	return ErrorPolicy{}, fmt.Errorf("unknown error policy: %s", s)
+}
+
+// ArchiveStatus はバッチ処理での1つのアーカイブの結果です。
+type ArchiveStatus struct {
+	Archive string
+	// Status は BatchOK、BatchFailed、BatchSkipped、BatchUnchanged のいずれかです。
+	Status string
+	// Attempts は処理を試みた回数です。処理しなかった場合は0です。
+	Attempts int
+	// Reason は失敗または処理しなかった理由です。
+	Reason string
+}
+
+// RunBatch はアーカイブを1つずつ process で処理し、アーカイブごとの結果を入力の順に返します。
+// 失敗したアーカイブは policy に従って再試行、または残りの処理を中止します。
+// state が nil でない場合は、記録済みで変わっていないアーカイブを処理せず、処理できたアーカイブを記録します。
+func RunBatch(paths []string, process func(string) error, policy ErrorPolicy, state *BatchState, logger *slog.Logger) []ArchiveStatus {
+	statuses := make([]ArchiveStatus, 0, len(paths))
+	aborted := false
+	for _, p := range paths {
+		if aborted {
+			statuses = append(statuses, ArchiveStatus{Archive: p, Status: BatchSkipped, Reason: "aborted after a previous failure"})
+			continue
+		}
+		var fingerprint string
+		if state != nil {
+			// 読めないアーカイブは処理で失敗として報告するため、ここではエラーにしない
+			fingerprint, _ = archiveFingerprint(p)
+			if fingerprint != "" && state.Done(p, fingerprint) {
+				statuses = append(statuses, ArchiveStatus{Archive: p, Status: BatchUnchanged, Reason: "already processed"})
+				continue
+			}
+		}
+		st := ArchiveStatus{Archive: p}
+		for {
+			st.Attempts++
+			err := process(p)
+			if err == nil {
+				st.Status, st.Reason = BatchOK, ""
+				break
+			}
+			st.Status, st.Reason = BatchFailed, err.Error()
+			logger.Error("アーカイブの処理に失敗しました", slog.String("zipPath", p), slog.Int("attempt", st.Attempts), slog.String("error", err.Error()))
+			if st.Attempts > policy.Retries {
+				break
+			}
+			time.Sleep(policy.RetryDelay)
+		}
+		if st.Status == BatchOK && state != nil && fingerprint != "" {
+			if err := state.Record(p, fingerprint); err != nil {
+				logger.Warn("状態ファイルに記録できませんでした", slog.String("zipPath", p), slog.String("error", err.Error()))
+			}
+		}
+		statuses = append(statuses, st)
+		aborted = st.Status == BatchFailed && policy.Abort
+	}
+	return statuses
+}
+
+// WriteBatchStatus はアーカイブごとの結果の表をプレーンテキストで出力します。
+func WriteBatchStatus(w io.Writer, statuses []ArchiveStatus, lang Lang) error {
+	if _, err := fmt.Fprintf(w, "\n%-50s | %-7s | %8s | %s\n", lang.T("Archive"), lang.T("Status"), lang.T("Attempts"), lang.T("Reason")); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 90))
+	for _, st := range statuses {
+		if _, err := fmt.Fprintf(w, "%-50s | %-7s | %8d | %s\n", st.Archive, st.Status, st.Attempts, st.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBatch は batch コマンドを実行します。
+func runBatch(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "batch", "batch -out-dir <dir> [-on-error skip|abort|retry:N] [-state <file>] [-daemon <schedule> [-keep-reports N]] [options] <zip|dir>...")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 10000)
+	outDir := fs.String("out-dir", "", "レポートを出力するフォルダ (必須)。別のフォルダにある同じ名前のアーカイブのレポートには、パスのハッシュを付けて区別する")
+	formats := fs.String("formats", "csv,json", "出力する形式 (カンマ区切り)")
+	onError := fs.String("on-error", "skip", "処理に失敗したアーカイブの扱い (skip: 次に進む, abort: 残りを処理しない, retry:N: N回まで再試行してから次に進む)")
+	retryDelay := fs.Duration("retry-delay", time.Second, "-on-error retry:N で再試行するまでの待ち時間")
+	statePath := fs.String("state", "", "処理済みのアーカイブを記録する状態ファイル。再実行時は前回から変わっていないアーカイブを処理しない")
+	daemon := fs.String("daemon", "", "cron形式のスケジュール (分 時 日 月 曜日。例: \"0 2 * * *\") で常駐し、実行のたびにフォルダを探し直して -out-dir 配下の実行日時のフォルダにレポートを出力する")
+	keepReports := fs.Int("keep-reports", 0, "-daemon で残す新しい実行のレポートのフォルダの数 (0は無制限)")
+	var nf notifyFlags
+	nf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *outDir == "" || fs.NArg() == 0 {
+		return usageError(fs, "out-dir and at least one zip path are required")
+	}
+	policy, err := ParseErrorPolicy(*onError)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	var schedule CronSchedule
+	if *daemon != "" {
+		if schedule, err = ParseCronSchedule(*daemon); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+	if *keepReports < 0 {
+		return usageError(fs, "keep reports must not be negative")
+	}
+	policy.RetryDelay = *retryDelay
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	notify, err := nf.newSettings(fs)
+	if err != nil {
+		return err
+	}
+	w := &Watcher{Config: cfg, OutDir: *outDir, Notify: notify}
+	for _, f := range strings.Split(*formats, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if _, err := NewResultWriter(f, WriterOptions{}); err != nil {
+			return usageError(fs, "%v", err)
+		}
+		w.Formats = append(w.Formats, f)
+	}
+	if len(w.Formats) == 0 {
+		return usageError(fs, "at least one format is required")
+	}
+	// ルールファイル等の誤りはすべてのアーカイブで失敗するため、処理を始める前に検出する
+	if _, err := cfg.aggregateOptions(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	w.App = &App{Reader: reader, Logger: env.Logger}
+	var state *BatchState
+	if *statePath != "" {
+		if state, err = LoadBatchState(*statePath); err != nil {
+			return err
+		}
+	}
+	if *daemon != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		d := &BatchDaemon{Watcher: w, Schedule: schedule, Inputs: fs.Args(), Policy: policy, State: state, KeepReports: *keepReports, Lang: env.lang}
+		return d.Run(ctx, env.Stdout)
+	}
+	paths, err := expandArchivePaths(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	w.ReportNames = uniqueReportNames(paths)
+	statuses := RunBatch(paths, w.Process, policy, state, env.Logger)
+	if err := WriteBatchStatus(env.Stdout, statuses, env.lang); err != nil {
+		return err
+	}
+	failed := 0
+	for _, st := range statuses {
+		if st.Status == BatchFailed || st.Status == BatchSkipped {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d archive(s) were not processed", failed, len(statuses))
+	}
+	return nil
+}
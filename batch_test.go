@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RunBatch のテスト
+func TestRunBatch(t *testing.T) {
+	paths := []string{"a.zip", "bad.zip", "flaky.zip", "c.zip"}
+	newProcess := func() func(string) error {
+		calls := map[string]int{}
+		return func(p string) error {
+			calls[p]++
+			switch {
+			case p == "bad.zip":
+				return errors.New("broken")
+			case p == "flaky.zip" && calls[p] == 1:
+				return errors.New("timeout")
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   string
+		expected []ArchiveStatus
+	}{
+		{
+			name:   "失敗しても次に進む",
+			policy: "skip",
+			expected: []ArchiveStatus{
+				{Archive: "a.zip", Status: BatchOK, Attempts: 1},
+				{Archive: "bad.zip", Status: BatchFailed, Attempts: 1, Reason: "broken"},
+				{Archive: "flaky.zip", Status: BatchFailed, Attempts: 1, Reason: "timeout"},
+				{Archive: "c.zip", Status: BatchOK, Attempts: 1},
+			},
+		},
+		{
+			name:   "失敗で中止",
+			policy: "abort",
+			expected: []ArchiveStatus{
+				{Archive: "a.zip", Status: BatchOK, Attempts: 1},
+				{Archive: "bad.zip", Status: BatchFailed, Attempts: 1, Reason: "broken"},
+				{Archive: "flaky.zip", Status: BatchSkipped, Reason: "aborted after a previous failure"},
+				{Archive: "c.zip", Status: BatchSkipped, Reason: "aborted after a previous failure"},
+			},
+		},
+		{
+			name:   "再試行",
+			policy: "retry:2",
+			expected: []ArchiveStatus{
+				{Archive: "a.zip", Status: BatchOK, Attempts: 1},
+				{Archive: "bad.zip", Status: BatchFailed, Attempts: 3, Reason: "broken"},
+				{Archive: "flaky.zip", Status: BatchOK, Attempts: 2},
+				{Archive: "c.zip", Status: BatchOK, Attempts: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseErrorPolicy(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := RunBatch(paths, newProcess(), policy, nil, slog.New(slog.DiscardHandler))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	for _, s := range []string{"retry:0", "retry:x", "ignore"} {
+		if _, err := ParseErrorPolicy(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+// -state による再開のテスト
+func TestRunBatchState(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.zip", "a.zip", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, err := expandArchivePaths([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "a.zip"), filepath.Join(dir, "b.zip")}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+
+	statePath := filepath.Join(dir, "state.json")
+	var processed []string
+	process := func(p string) error {
+		processed = append(processed, filepath.Base(p))
+		if filepath.Base(p) == "b.zip" && len(processed) == 2 {
+			return errors.New("broken")
+		}
+		return nil
+	}
+	run := func() []string {
+		processed = nil
+		state, err := LoadBatchState(statePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RunBatch(paths, process, ErrorPolicy{}, state, slog.New(slog.DiscardHandler))
+		return processed
+	}
+
+	if got := run(); !reflect.DeepEqual(got, []string{"a.zip", "b.zip"}) {
+		t.Errorf("first run: unexpected archives %v", got)
+	}
+	// 失敗した b.zip だけを再び処理する
+	if got := run(); !reflect.DeepEqual(got, []string{"b.zip"}) {
+		t.Errorf("second run: unexpected archives %v", got)
+	}
+	if got := run(); len(got) != 0 {
+		t.Errorf("third run: expected no archives, got %v", got)
+	}
+	// 更新されたアーカイブは処理し直す
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(paths[0], later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); !reflect.DeepEqual(got, []string{"a.zip"}) {
+		t.Errorf("after update: unexpected archives %v", got)
+	}
+}
+
+// uniqueReportNames のテスト
+func TestUniqueReportNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []string
+		expected map[string]string
+	}{
+		{
+			name:     "重ならない名前",
+			paths:    []string{"dir1/a.zip", "dir2/b.zip"},
+			expected: map[string]string{"dir1/a.zip": "a", "dir2/b.zip": "b"},
+		},
+		{
+			name:     "別のフォルダの同じ名前",
+			paths:    []string{"dir1/a.zip", "dir2/a.zip", "dir2/b.zip"},
+			expected: map[string]string{"dir1/a.zip": "a-f22dd0cf", "dir2/a.zip": "a-dc0773c5", "dir2/b.zip": "b"},
+		},
+		{
+			name:     "大文字と小文字だけが異なる名前",
+			paths:    []string{"dir1/A.zip", "dir1/a.ZIP"},
+			expected: map[string]string{"dir1/A.zip": "A-9742e50b", "dir1/a.ZIP": "a-d5dc99df"},
+		},
+		{
+			name:     "同じアーカイブの重複",
+			paths:    []string{"dir1/a.zip", "./dir1/a.zip"},
+			expected: map[string]string{"dir1/a.zip": "a", "./dir1/a.zip": "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := uniqueReportNames(tt.paths)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// batch のテスト (別のフォルダにある同じ名前のアーカイブのレポートを上書きしない)
+func TestRunCLIBatchSameBaseName(t *testing.T) {
+	dir := t.TempDir()
+	outDir := filepath.Join(dir, "reports")
+	var inputs []string
+	for _, sub := range []string{"dir1", "dir2"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(writeTestZip(t, sub+"/a.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zipPath := filepath.Join(dir, sub, "a.zip")
+		if err := os.WriteFile(zipPath, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, zipPath)
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	args := append([]string{"batch", "-threshold", "0", "-out-dir", outDir, "-formats", "csv"}, inputs...)
+	if code := runCLI(args, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	names := uniqueReportNames(inputs)
+	for i, sub := range []string{"dir1", "dir2"} {
+		report, err := os.ReadFile(filepath.Join(outDir, names[inputs[i]]+".csv"))
+		if err != nil {
+			t.Fatalf("report for %s was not written: %v", inputs[i], err)
+		}
+		if !strings.Contains(string(report), sub) {
+			t.Errorf("report for %s should contain %s, got: %s", inputs[i], sub, report)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "a.csv")); !os.IsNotExist(err) {
+		t.Errorf("expected no report named a.csv, got stat error %v", err)
+	}
+}
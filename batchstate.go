@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BatchState はバッチ処理で処理済みのアーカイブを記録する状態ファイルです。
+// 再実行時は、前回から変わっていないアーカイブを処理せずに次へ進みます。
+type BatchState struct {
+	path string
+	// Archives はアーカイブのパスと、処理した時点のフィンガープリントの組です。
+	Archives map[string]string `json:"archives"`
+}
+
+// LoadBatchState は状態ファイルを読み込みます。ファイルが無い場合は空の状態を返します。
+func LoadBatchState(statePath string) (*BatchState, error) {
+	state := &BatchState{path: statePath, Archives: map[string]string{}}
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", statePath, err)
+	}
+	if state.Archives == nil {
+		state.Archives = map[string]string{}
+	}
+	return state, nil
+}
+
+// Done はアーカイブが前回と同じ内容のまま処理済みかを判定します。
+func (s *BatchState) Done(archivePath, fingerprint string) bool {
+	return s.Archives[archivePath] == fingerprint
+}
+
+// Record はアーカイブを処理済みとして記録し、状態ファイルに保存します。
+// 途中で中断しても記録済みの分から再開できるよう、アーカイブごとに一時ファイルへ書いてから置き換えます。
+func (s *BatchState) Record(archivePath, fingerprint string) error {
+	s.Archives[archivePath] = fingerprint
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// archiveFingerprint はアーカイブのパス、サイズ、更新日時から求めたハッシュ値を返します。
+// 内容を読まずに求められるため、大きなアーカイブが並ぶ共有フォルダでも変更を安く検出できます。
+func archiveFingerprint(archivePath string) (string, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return "", err
+	}
+	abs, err := canonicalLocalPath(archivePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%d", abs, info.Size(), info.ModTime().UnixNano()))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// expandArchivePaths はフォルダを指定した場合に、その直下の .zip ファイルを名前の昇順で展開します。
+// フォルダ以外のパスはそのまま返します。
+func expandArchivePaths(paths []string) ([]string, error) {
+	var expanded []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.IsDir() {
+			expanded = append(expanded, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory: %w", err)
+		}
+		var names []string
+		for _, e := range entries {
+			if !e.IsDir() && isZipName(e.Name()) && !strings.HasPrefix(e.Name(), ".") {
+				names = append(names, filepath.Join(p, e.Name()))
+			}
+		}
+		sort.Strings(names)
+		expanded = append(expanded, names...)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"time"
+)
+
+// ベンチマークで計測する処理の段階です。
+var benchPhases = []string{"read", "decode", "aggregate", "write"}
+
+// BenchSample は1回の実行での段階ごとの経過時間と割り当てたバイト数です。
+type BenchSample struct {
+	Durations map[string]time.Duration
+	Allocs    map[string]uint64
+}
+
+// BenchPhase は複数回の実行から求めた1つの段階の統計です。
+type BenchPhase struct {
+	Name          string
+	Min, Avg, Max time.Duration
+	// AllocPerRun は1回の実行あたりに割り当てたバイト数の平均です。
+	AllocPerRun uint64
+}
+
+// SummarizeBench は実行ごとの計測結果を段階ごとに集計し、末尾に全段階の合計 (total) を加えます。(純粋関数)
+func SummarizeBench(samples []BenchSample) []BenchPhase {
+	if len(samples) == 0 {
+		return nil
+	}
+	totals := make([]BenchSample, len(samples))
+	for i, s := range samples {
+		totals[i] = BenchSample{Durations: map[string]time.Duration{}, Allocs: map[string]uint64{}}
+		for _, name := range benchPhases {
+			totals[i].Durations["total"] += s.Durations[name]
+			totals[i].Allocs["total"] += s.Allocs[name]
+		}
+	}
+	phases := make([]BenchPhase, 0, len(benchPhases)+1)
+	for _, name := range benchPhases {
+		phases = append(phases, summarizePhase(name, samples))
+	}
+	return append(phases, summarizePhase("total", totals))
+}
+
+// summarizePhase は1つの段階の最小、平均、最大の経過時間と平均の割り当てバイト数を求めます。(純粋関数)
+func summarizePhase(name string, samples []BenchSample) BenchPhase {
+	p := BenchPhase{Name: name}
+	var sum time.Duration
+	var allocs uint64
+	for i, s := range samples {
+		d := s.Durations[name]
+		if i == 0 || d < p.Min {
+			p.Min = d
+		}
+		p.Max = max(p.Max, d)
+		sum += d
+		allocs += s.Allocs[name]
+	}
+	p.Avg = sum / time.Duration(len(samples))
+	p.AllocPerRun = allocs / uint64(len(samples))
+	return p
+}
+
+// WriteBenchText は段階ごとの統計をプレーンテキストで出力します。
+func WriteBenchText(w io.Writer, archivePath string, entries, runs int, phases []BenchPhase, lang Lang) error {
+	if _, err := fmt.Fprintf(w, "\n%s: %s (%d %s, %d %s)\n\n", lang.T("Archive"), archivePath, entries, lang.T("entries"), runs, lang.T("runs")); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%-10s | %12s | %12s | %12s | %12s\n", lang.T("Phase"), lang.T("Min"), lang.T("Avg"), lang.T("Max"), lang.T("Alloc/Run"))
+	fmt.Fprintln(w, strings.Repeat("-", 72))
+	for _, p := range phases {
+		if _, err := fmt.Fprintf(w, "%-10s | %12s | %12s | %12s | %12s\n", p.Name,
+			p.Min.Round(time.Microsecond), p.Avg.Round(time.Microsecond), p.Max.Round(time.Microsecond), FormatSize(p.AllocPerRun)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchTimer は段階ごとの経過時間と割り当てたバイト数を計測します。
+type benchTimer struct {
+	sample BenchSample
+}
+
+func newBenchTimer() *benchTimer {
+	return &benchTimer{sample: BenchSample{Durations: make(map[string]time.Duration), Allocs: make(map[string]uint64)}}
+}
+
+// measure は fn の経過時間と割り当てたバイト数を phase の値として記録します。
+func (t *benchTimer) measure(phase string, fn func() error) error {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	err := fn()
+	t.sample.Durations[phase] = time.Since(start)
+	runtime.ReadMemStats(&after)
+	t.sample.Allocs[phase] = after.TotalAlloc - before.TotalAlloc
+	return err
+}
+
+// benchOnce はアーカイブの読み込みから書き出しまでを1回実行し、段階ごとに計測します。
+// ZIPはセントラルディレクトリの読み込み (read) とエントリ名の復号を含む変換 (decode) を分けて計測し、
+// それ以外の形式は read にまとめます。キャッシュは使いません。
+func benchOnce(reader AutoArchiveReader, archivePath string, opts AggregateOptions, writer ResultWriter) (BenchSample, int, error) {
+	t := newBenchTimer()
+	var entries []FileEntry
+	if _, ok := registeredArchiveReader(archivePath); !ok && reader.kind(archivePath) == "zip" {
+		var files []*zip.File
+		if err := t.measure("read", func() (err error) {
+			files, err = reader.Zip.open(archivePath)
+			return err
+		}); err != nil {
+			return BenchSample{}, 0, err
+		}
+		t.measure("decode", func() error {
+			entries, _ = reader.Zip.entries(files, time.Time{}, nil)
+			return nil
+		})
+	} else {
+		reader.Cache = nil
+		if err := t.measure("read", func() (err error) {
+			entries, err = reader.ReadEntries(archivePath)
+			return err
+		}); err != nil {
+			return BenchSample{}, 0, err
+		}
+	}
+
+	var report *Report
+	t.measure("aggregate", func() error {
+		results, totalFiles := Aggregate(entries, opts)
+		report = &Report{Archive: archivePath, TotalFiles: totalFiles, Folders: results}
+		return nil
+	})
+	if err := t.measure("write", func() error {
+		return writer.Write(io.Discard, report)
+	}); err != nil {
+		return BenchSample{}, 0, err
+	}
+	return t.sample, len(entries), nil
+}
+
+// startProfiles はフラグで指定されたCPUプロファイルと実行トレースの記録を始めます。戻り値の関数で記録を終えます。
+func startProfiles(cpuPath, tracePath string) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for _, s := range stops {
+			s()
+		}
+	}
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cpu profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to start cpu profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if tracePath != "" {
+		f, err := os.Create(tracePath)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+	return stop, nil
+}
+
+// writeHeapProfile はGCの後のヒープのプロファイルを書き出します。
+func writeHeapProfile(memPath string) error {
+	f, err := os.Create(memPath)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	return f.Close()
+}
+
+// runBench は bench コマンドを実行します。
+func runBench(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "bench", "bench [-runs N] [-cpuprofile <file>] [-memprofile <file>] [-trace <file>] [options] <zip>")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 10000)
+	runs := fs.Int("runs", 3, "計測する実行の回数")
+	format := fs.String("format", "text", "書き出しの段階で計測する出力形式 (出力は破棄する)")
+	cpuProfile := fs.String("cpuprofile", "", "すべての実行のCPUプロファイルを書き出すファイル")
+	memProfile := fs.String("memprofile", "", "最後の実行の後のヒーププロファイルを書き出すファイル")
+	tracePath := fs.String("trace", "", "すべての実行の実行トレースを書き出すファイル (go tool trace で表示)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "exactly one zip path is required")
+	}
+	if *runs <= 0 {
+		return usageError(fs, "runs must be positive")
+	}
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	writer, err := NewResultWriter(*format, WriterOptions{Paths: cfg.Paths})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	archivePath := fs.Arg(0)
+
+	stop, err := startProfiles(*cpuProfile, *tracePath)
+	if err != nil {
+		return err
+	}
+	var samples []BenchSample
+	entries := 0
+	for i := 0; i < *runs; i++ {
+		sample, n, err := benchOnce(reader, archivePath, opts, writer)
+		if err != nil {
+			stop()
+			return err
+		}
+		samples = append(samples, sample)
+		entries = n
+	}
+	stop()
+	if *memProfile != "" {
+		if err := writeHeapProfile(*memProfile); err != nil {
+			return err
+		}
+	}
+	return WriteBenchText(env.Stdout, archivePath, entries, *runs, SummarizeBench(samples), env.lang)
+}
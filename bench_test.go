@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// SummarizeBench のテスト
+func TestSummarizeBench(t *testing.T) {
+	sample := func(read, decode, aggregate, write time.Duration, alloc uint64) BenchSample {
+		return BenchSample{
+			Durations: map[string]time.Duration{"read": read, "decode": decode, "aggregate": aggregate, "write": write},
+			Allocs:    map[string]uint64{"read": alloc, "decode": alloc, "aggregate": alloc, "write": alloc},
+		}
+	}
+	tests := []struct {
+		name     string
+		samples  []BenchSample
+		expected []BenchPhase
+	}{
+		{name: "計測なし", samples: nil, expected: nil},
+		{
+			name:    "最小、平均、最大と合計",
+			samples: []BenchSample{sample(10, 2, 4, 1, 100), sample(20, 4, 2, 1, 300)},
+			expected: []BenchPhase{
+				{Name: "read", Min: 10, Avg: 15, Max: 20, AllocPerRun: 200},
+				{Name: "decode", Min: 2, Avg: 3, Max: 4, AllocPerRun: 200},
+				{Name: "aggregate", Min: 2, Avg: 3, Max: 4, AllocPerRun: 200},
+				{Name: "write", Min: 1, Avg: 1, Max: 1, AllocPerRun: 200},
+				{Name: "total", Min: 17, Avg: 22, Max: 27, AllocPerRun: 800},
+			},
+		},
+		{
+			name:    "計測していない段階は0",
+			samples: []BenchSample{{Durations: map[string]time.Duration{"read": 5}, Allocs: map[string]uint64{}}},
+			expected: []BenchPhase{
+				{Name: "read", Min: 5, Avg: 5, Max: 5},
+				{Name: "decode"},
+				{Name: "aggregate"},
+				{Name: "write"},
+				{Name: "total", Min: 5, Avg: 5, Max: 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeBench(tt.samples); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("SummarizeBench() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// bench コマンドが段階ごとの計測結果とプロファイルを書き出すことのテスト
+func TestRunBench(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/b.txt", "c.txt")
+	dir := t.TempDir()
+	cpu, mem, trace := filepath.Join(dir, "cpu.prof"), filepath.Join(dir, "mem.prof"), filepath.Join(dir, "trace.out")
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	args := []string{"bench", "-runs", "2", "-format", "json", "-cpuprofile", cpu, "-memprofile", mem, "-trace", trace, zipPath}
+	if code := runCLI(args, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	for _, want := range []string{"3 entries, 2 runs", "read", "decode", "aggregate", "write", "total"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output does not contain %q, got: %s", want, stdout.String())
+		}
+	}
+	for _, p := range []string{cpu, mem, trace} {
+		if info, err := os.Stat(p); err != nil || info.Size() == 0 {
+			t.Errorf("profile %s was not written: %v", filepath.Base(p), err)
+		}
+	}
+}
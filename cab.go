@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Microsoft Cabinet (.cab) のヘッダとファイルエントリの定数です。
+const (
+	cabHeaderSize       = 36
+	cabAttribNameIsUTF8 = 0x80
+	cabMaxNameLength    = 256
+)
+
+// cabSignature はキャビネットファイルの先頭のシグネチャです。
+var cabSignature = []byte("MSCF")
+
+// CabArchiveReader はキャビネットファイル (.cab) 内のファイルを一覧する実装です。
+// キャビネットはファイルごとの圧縮後のサイズを持たないため、CompressedSize は Size と同じ値になります。
+type CabArchiveReader struct {
+	// Decoder はUTF-8の指定が無いファイル名の復号方法です。nil の場合は Shift_JIS を使います。
+	Decoder NameDecoder
+}
+
+func (c CabArchiveReader) ReadEntries(cabPath string) ([]FileEntry, error) {
+	data, err := os.ReadFile(cabPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cab: %w", err)
+	}
+	return c.parse(data, "")
+}
+
+// parse はキャビネットの内容を解析します。prefix はエントリ名の前に付けるフォルダです。
+func (c CabArchiveReader) parse(data []byte, prefix string) ([]FileEntry, error) {
+	if len(data) < cabHeaderSize || !bytes.Equal(data[:4], cabSignature) {
+		return nil, errors.New("failed to open cab: invalid signature")
+	}
+	decoder := c.Decoder
+	if decoder == nil {
+		decoder = EncodingDecoder{Encoding: japanese.ShiftJIS}
+	}
+
+	offset := int(binary.LittleEndian.Uint32(data[16:20]))
+	count := int(binary.LittleEndian.Uint16(data[28:30]))
+	entries := make([]FileEntry, 0, count)
+	for i := 0; i < count; i++ {
+		// CFFILE: サイズ(4) フォルダ内の位置(4) フォルダ番号(2) 日付(2) 時刻(2) 属性(2) 名前(NUL終端)
+		if offset+16 > len(data) {
+			return nil, errors.New("failed to read cab: file entry exceeds archive")
+		}
+		h := data[offset : offset+16]
+		end := bytes.IndexByte(data[offset+16:min(len(data), offset+16+cabMaxNameLength)], 0)
+		if end < 0 {
+			return nil, errors.New("failed to read cab: unterminated file name")
+		}
+		raw := data[offset+16 : offset+16+end]
+		offset += 16 + end + 1
+
+		name := string(raw)
+		if binary.LittleEndian.Uint16(h[14:16])&cabAttribNameIsUTF8 == 0 {
+			if decoded, confidence := decoder.Decode(raw); confidence > ConfidenceNone {
+				name = decoded
+			}
+		}
+		size := uint64(binary.LittleEndian.Uint32(h[0:4]))
+		entries = append(entries, FileEntry{
+			Name:           prefix + strings.ReplaceAll(name, "\\", "/"),
+			RawName:        prefix + string(raw),
+			Modified:       dosDateTime(binary.LittleEndian.Uint16(h[10:12]), binary.LittleEndian.Uint16(h[12:14])),
+			Size:           size,
+			CompressedSize: size,
+		})
+	}
+	return entries, nil
+}
+
+// dosDateTime はMS-DOS形式の日付と時刻を変換します。日付が0の場合はゼロ値を返します。(純粋関数)
+// タイムゾーンを持たないため、archive/zip と同じくUTCとして扱います。
+func dosDateTime(date, t uint16) time.Time {
+	if date == 0 {
+		return time.Time{}
+	}
+	return time.Date(
+		int(date>>9)+1980, time.Month(date>>5&0x0f), int(date&0x1f),
+		int(t>>11), int(t>>5&0x3f), int(t&0x1f)*2, 0, time.UTC,
+	)
+}
+
+// 複合ファイル (MSIの格納形式) の定数です。
+const (
+	cfbHeaderSize     = 512
+	cfbDirEntrySize   = 128
+	cfbEndOfChain     = 0xFFFFFFFE
+	cfbFreeSector     = 0xFFFFFFFF
+	cfbTypeStream     = 2
+	cfbTypeRoot       = 5
+	cfbMaxChainLength = 1 << 24
+)
+
+// cfbSignature は複合ファイルの先頭のシグネチャです。
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// MsiArchiveReader はWindowsインストーラ (.msi) に埋め込まれたキャビネット内のファイルを一覧する実装です。
+// エントリ名は "<キャビネットのストリーム名>/<Fileテーブルのキー>" です。
+// インストール先のフォルダ構成 (Directoryテーブル) は解決しません。
+type MsiArchiveReader struct {
+	Cab CabArchiveReader
+}
+
+func (m MsiArchiveReader) ReadEntries(msiPath string) ([]FileEntry, error) {
+	data, err := os.ReadFile(msiPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open msi: %w", err)
+	}
+	streams, err := readCFBStreams(data)
+	if err != nil {
+		return nil, err
+	}
+	var entries []FileEntry
+	found := false
+	for _, s := range streams {
+		if !bytes.HasPrefix(s.data, cabSignature) {
+			continue
+		}
+		found = true
+		cabEntries, err := m.Cab.parse(s.data, s.name+"/")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cab stream %s: %w", s.name, err)
+		}
+		entries = append(entries, cabEntries...)
+	}
+	if !found {
+		return nil, errors.New("failed to open msi: no embedded cab streams found")
+	}
+	return entries, nil
+}
+
+// cfbStream は複合ファイルのストリームの名前と内容です。
+type cfbStream struct {
+	name string
+	data []byte
+}
+
+// readCFBStreams は複合ファイルのすべてのストリームを読み込みます。(純粋関数)
+func readCFBStreams(data []byte) ([]cfbStream, error) {
+	if len(data) < cfbHeaderSize || !bytes.Equal(data[:8], cfbSignature) {
+		return nil, errors.New("failed to open msi: invalid signature")
+	}
+	f := &cfbFile{data: data, sectorSize: 1 << binary.LittleEndian.Uint16(data[0x1E:]), miniSectorSize: 1 << binary.LittleEndian.Uint16(data[0x20:])}
+	if f.sectorSize < cfbHeaderSize || f.sectorSize > 1<<16 {
+		return nil, errors.New("failed to open msi: invalid sector size")
+	}
+	miniCutoff := uint64(binary.LittleEndian.Uint32(data[0x38:]))
+	if err := f.loadFAT(); err != nil {
+		return nil, err
+	}
+
+	dir, err := f.chain(binary.LittleEndian.Uint32(data[0x30:]), f.fat, f.sectorAt)
+	if err != nil {
+		return nil, err
+	}
+	// ストリームの内容は、ミニストリームを読み込んだ後に解決する
+	type location struct {
+		start uint32
+		size  uint64
+	}
+	var miniStream []byte
+	var streams []cfbStream
+	var locations []location
+	for off := 0; off+cfbDirEntrySize <= len(dir); off += cfbDirEntrySize {
+		e := dir[off : off+cfbDirEntrySize]
+		nameLen := int(binary.LittleEndian.Uint16(e[64:]))
+		if nameLen < 2 || nameLen > 64 {
+			continue
+		}
+		start := binary.LittleEndian.Uint32(e[116:])
+		size := binary.LittleEndian.Uint64(e[120:])
+		if f.sectorSize == cfbHeaderSize {
+			// バージョン3の形式ではサイズの上位32ビットは未定義
+			size &= 0xFFFFFFFF
+		}
+		switch e[66] {
+		case cfbTypeRoot:
+			if miniStream, err = f.chain(start, f.fat, f.sectorAt); err != nil {
+				return nil, err
+			}
+		case cfbTypeStream:
+			u := make([]uint16, (nameLen-2)/2)
+			for i := range u {
+				u[i] = binary.LittleEndian.Uint16(e[i*2:])
+			}
+			streams = append(streams, cfbStream{name: decodeMSIStreamName(u)})
+			locations = append(locations, location{start: start, size: size})
+		}
+	}
+
+	var miniFAT []uint32
+	if binary.LittleEndian.Uint32(data[0x40:]) > 0 {
+		raw, err := f.chain(binary.LittleEndian.Uint32(data[0x3C:]), f.fat, f.sectorAt)
+		if err != nil {
+			return nil, err
+		}
+		miniFAT = bytesToUint32s(raw)
+	}
+	miniSectorAt := func(sector uint32) ([]byte, error) {
+		off := int(sector) * f.miniSectorSize
+		if off+f.miniSectorSize > len(miniStream) {
+			return nil, errors.New("failed to read msi: mini sector out of range")
+		}
+		return miniStream[off : off+f.miniSectorSize], nil
+	}
+	for i, loc := range locations {
+		if loc.size == 0 {
+			continue
+		}
+		var b []byte
+		if loc.size < miniCutoff {
+			b, err = f.chain(loc.start, miniFAT, miniSectorAt)
+		} else {
+			b, err = f.chain(loc.start, f.fat, f.sectorAt)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(b)) < loc.size {
+			return nil, errors.New("failed to read msi: stream is truncated")
+		}
+		streams[i].data = b[:loc.size]
+	}
+	return streams, nil
+}
+
+// cfbFile は複合ファイルの内容とセクタの割り当て表です。
+type cfbFile struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	fat            []uint32
+}
+
+// sectorAt はセクタ番号の内容を返します。ヘッダの直後がセクタ0です。
+func (f *cfbFile) sectorAt(sector uint32) ([]byte, error) {
+	off := (int(sector) + 1) * f.sectorSize
+	if sector >= cfbMaxChainLength || off+f.sectorSize > len(f.data) {
+		return nil, errors.New("failed to read msi: sector out of range")
+	}
+	return f.data[off : off+f.sectorSize], nil
+}
+
+// loadFAT はヘッダと DIFAT セクタからFATのセクタを集め、割り当て表を読み込みます。
+func (f *cfbFile) loadFAT() error {
+	difat := bytesToUint32s(f.data[0x4C:cfbHeaderSize])
+	next := binary.LittleEndian.Uint32(f.data[0x44:])
+	for n := binary.LittleEndian.Uint32(f.data[0x48:]); n > 0 && next < cfbEndOfChain; n-- {
+		sector, err := f.sectorAt(next)
+		if err != nil {
+			return err
+		}
+		// 各 DIFAT セクタの最後の4バイトは次の DIFAT セクタの番号
+		ids := bytesToUint32s(sector)
+		difat = append(difat, ids[:len(ids)-1]...)
+		next = ids[len(ids)-1]
+	}
+	count := int(binary.LittleEndian.Uint32(f.data[0x2C:]))
+	for _, id := range difat {
+		if count == 0 {
+			break
+		}
+		if id == cfbFreeSector {
+			continue
+		}
+		sector, err := f.sectorAt(id)
+		if err != nil {
+			return err
+		}
+		f.fat = append(f.fat, bytesToUint32s(sector)...)
+		count--
+	}
+	return nil
+}
+
+// chain は割り当て表をたどり、start から始まるセクタの内容を連結して返します。
+func (f *cfbFile) chain(start uint32, table []uint32, sectorAt func(uint32) ([]byte, error)) ([]byte, error) {
+	var out []byte
+	for sector, n := start, 0; sector != cfbEndOfChain; n++ {
+		if n > len(table) || int(sector) >= len(table) {
+			return nil, errors.New("failed to read msi: broken sector chain")
+		}
+		b, err := sectorAt(sector)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+		sector = table[sector]
+	}
+	return out, nil
+}
+
+// bytesToUint32s はリトルエンディアンの32ビット値の並びを変換します。(純粋関数)
+func bytesToUint32s(b []byte) []uint32 {
+	u := make([]uint32, len(b)/4)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return u
+}
+
+// msiNameChars はMSIのストリーム名の圧縮に使われる64文字です。
+const msiNameChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz._"
+
+// decodeMSIStreamName はMSIが1文字に2文字を詰めて格納したストリーム名を復元します。(純粋関数)
+// テーブルのストリームを表す先頭の U+4840 は "!" に置き換えます。
+func decodeMSIStreamName(u []uint16) string {
+	var b strings.Builder
+	for _, c := range u {
+		switch {
+		case c == 0x4840:
+			b.WriteByte('!')
+		case c >= 0x4800 && c < 0x4840:
+			b.WriteByte(msiNameChars[c-0x4800])
+		case c >= 0x3800 && c < 0x4800:
+			c -= 0x3800
+			b.WriteByte(msiNameChars[c&0x3f])
+			b.WriteByte(msiNameChars[c>>6&0x3f])
+		default:
+			b.WriteString(string(utf16.Decode([]uint16{c})))
+		}
+	}
+	return b.String()
+}
+
+// detectArchiveKind はファイルの先頭のシグネチャからアーカイブの形式を判定します。
+// 判定できない場合は "zip" を返します。
+func detectArchiveKind(r io.ReaderAt) string {
+	head := make([]byte, tarMagicOffset+len(tarMagic))
+	n, _ := r.ReadAt(head, 0)
+	switch {
+	case bytes.HasPrefix(head[:n], cabSignature):
+		return "cab"
+	case bytes.HasPrefix(head[:n], cfbSignature):
+		return "msi"
+	case bytes.HasPrefix(head[:n], gzipSignature):
+		return "gzip"
+	case bytes.HasPrefix(head[:n], bzip2Signature):
+		return "bzip2"
+	case bytes.HasPrefix(head[:n], xzSignature):
+		return "xz"
+	case isTarHeader(head[:n]):
+		return "tar"
+	case isISOImage(r):
+		return "iso"
+	default:
+		return "zip"
+	}
+}
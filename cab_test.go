@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// cabTestFile はテスト用のキャビネットのファイルエントリです。
+type cabTestFile struct {
+	name   []byte
+	size   uint32
+	attrib uint16
+}
+
+// buildTestCab はファイルの内容を持たない、ファイルエントリだけのキャビネットを作成します。
+func buildTestCab(files ...cabTestFile) []byte {
+	b := make([]byte, cabHeaderSize)
+	copy(b, cabSignature)
+	binary.LittleEndian.PutUint32(b[16:], cabHeaderSize)
+	b[24], b[25] = 3, 1
+	binary.LittleEndian.PutUint16(b[28:], uint16(len(files)))
+	for _, f := range files {
+		h := make([]byte, 16)
+		binary.LittleEndian.PutUint32(h[0:], f.size)
+		binary.LittleEndian.PutUint16(h[10:], (2023-1980)<<9|6<<5|15) // 2023-06-15
+		binary.LittleEndian.PutUint16(h[12:], 9<<11|30<<5|5)          // 09:30:10
+		binary.LittleEndian.PutUint16(h[14:], f.attrib)
+		b = append(append(append(b, h...), f.name...), 0)
+	}
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)))
+	return b
+}
+
+// encodeMSIStreamName は decodeMSIStreamName の逆変換です。
+func encodeMSIStreamName(name string) []uint16 {
+	var u []uint16
+	for i := 0; i < len(name); i += 2 {
+		a := uint16(strings.IndexByte(msiNameChars, name[i]))
+		if i+1 == len(name) {
+			u = append(u, 0x4800+a)
+			break
+		}
+		u = append(u, 0x3800+a+uint16(strings.IndexByte(msiNameChars, name[i+1]))<<6)
+	}
+	return u
+}
+
+// buildTestMsi は1つのストリームを持つ、セクタサイズ512の複合ファイルを作成します。
+// ミニストリームを使わないよう、ミニストリームのしきい値は0にします。
+func buildTestMsi(streamName string, stream []byte) []byte {
+	const sector = 512
+	dataSectors := (len(stream) + sector - 1) / sector
+	img := make([]byte, sector*(3+dataSectors))
+
+	h := img[:sector]
+	copy(h, cfbSignature)
+	binary.LittleEndian.PutUint16(h[0x1A:], 3)
+	binary.LittleEndian.PutUint16(h[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(h[0x1E:], 9)
+	binary.LittleEndian.PutUint16(h[0x20:], 6)
+	binary.LittleEndian.PutUint32(h[0x2C:], 1)
+	binary.LittleEndian.PutUint32(h[0x30:], 1)
+	binary.LittleEndian.PutUint32(h[0x3C:], cfbEndOfChain)
+	binary.LittleEndian.PutUint32(h[0x44:], cfbEndOfChain)
+	for i := 0x4C; i < sector; i += 4 {
+		binary.LittleEndian.PutUint32(h[i:], cfbFreeSector)
+	}
+	binary.LittleEndian.PutUint32(h[0x4C:], 0)
+
+	fat := img[sector : 2*sector]
+	for i := 0; i < sector; i += 4 {
+		binary.LittleEndian.PutUint32(fat[i:], cfbFreeSector)
+	}
+	binary.LittleEndian.PutUint32(fat[0:], 0xFFFFFFFD)
+	binary.LittleEndian.PutUint32(fat[4:], cfbEndOfChain)
+	for i := 0; i < dataSectors; i++ {
+		next := uint32(cfbEndOfChain)
+		if i+1 < dataSectors {
+			next = uint32(i + 3)
+		}
+		binary.LittleEndian.PutUint32(fat[(i+2)*4:], next)
+	}
+
+	dir := img[2*sector : 3*sector]
+	putEntry := func(e []byte, name []uint16, typ byte, start uint32, size uint64) {
+		for i, c := range name {
+			binary.LittleEndian.PutUint16(e[i*2:], c)
+		}
+		binary.LittleEndian.PutUint16(e[64:], uint16(len(name)*2+2))
+		e[66] = typ
+		binary.LittleEndian.PutUint32(e[116:], start)
+		binary.LittleEndian.PutUint64(e[120:], size)
+	}
+	putEntry(dir[0:], utf16.Encode([]rune("Root Entry")), cfbTypeRoot, cfbEndOfChain, 0)
+	putEntry(dir[cfbDirEntrySize:], encodeMSIStreamName(streamName), cfbTypeStream, 2, uint64(len(stream)))
+
+	copy(img[3*sector:], stream)
+	return img
+}
+
+// CabArchiveReader のテスト
+func TestCabArchiveReader(t *testing.T) {
+	modified := time.Date(2023, 6, 15, 9, 30, 10, 0, time.UTC)
+	cab := buildTestCab(
+		cabTestFile{name: []byte(`bin\app.exe`), size: 100},
+		cabTestFile{name: []byte("\x8f\x91\x97\xde\\a.txt"), size: 5},
+		cabTestFile{name: []byte("docs\\日本語.txt"), size: 3, attrib: cabAttribNameIsUTF8},
+	)
+
+	entries, err := (CabArchiveReader{}).parse(cab, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []FileEntry{
+		{Name: "bin/app.exe", RawName: `bin\app.exe`, Modified: modified, Size: 100, CompressedSize: 100},
+		{Name: "書類/a.txt", RawName: "\x8f\x91\x97\xde\\a.txt", Modified: modified, Size: 5, CompressedSize: 5},
+		{Name: "docs/日本語.txt", RawName: `docs\日本語.txt`, Modified: modified, Size: 3, CompressedSize: 3},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %+v, got %+v", expected, entries)
+	}
+
+	t.Run("壊れたキャビネット", func(t *testing.T) {
+		if _, err := (CabArchiveReader{}).parse(cab[:cabHeaderSize+20], ""); err == nil {
+			t.Error("expected error for truncated cab")
+		}
+	})
+}
+
+// MsiArchiveReader と形式の判定のテスト
+func TestMsiArchiveReader(t *testing.T) {
+	var files []cabTestFile
+	for i := 0; i < 40; i++ {
+		files = append(files, cabTestFile{name: []byte("File" + strings.Repeat("x", i) + ".dll"), size: 1})
+	}
+	dir := t.TempDir()
+	msiPath := filepath.Join(dir, "setup.msi")
+	if err := os.WriteFile(msiPath, buildTestMsi("Data1.cab", buildTestCab(files...)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cabPath := filepath.Join(dir, "data.cab")
+	if err := os.WriteFile(cabPath, buildTestCab(files[:2]...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := (AutoArchiveReader{}).ReadEntries(msiPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != len(files) || entries[0].Name != "Data1.cab/File.dll" {
+		t.Errorf("unexpected entries: %+v", entries[:min(len(entries), 2)])
+	}
+
+	entries, err = (AutoArchiveReader{}).ReadEntries(cabPath)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("unexpected cab result: %+v, %v", entries, err)
+	}
+
+	t.Run("キャビネットを含まないMSI", func(t *testing.T) {
+		if _, err := (MsiArchiveReader{}).ReadEntries(msiPath + ".none"); err == nil {
+			t.Error("expected error for missing file")
+		}
+		noCab := filepath.Join(dir, "nocab.msi")
+		if err := os.WriteFile(noCab, buildTestMsi("Property", bytes.Repeat([]byte("x"), 10)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (MsiArchiveReader{}).ReadEntries(noCab); err == nil || !strings.Contains(err.Error(), "no embedded cab") {
+			t.Errorf("expected no cab error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// グラフの描画に使う寸法 (ピクセル) です。
+const (
+	chartRowHeight   = 20
+	chartBarWidth    = 400
+	chartCharWidth   = 7
+	chartMargin      = 10
+	chartLabelMax    = 40
+	chartValueWidth  = 80
+	chartTitleHeight = 24
+)
+
+// ChartOptions はグラフの内容を指定します。
+type ChartOptions struct {
+	// Top は表示するフォルダの数です。0以下の場合は既定値の20を使います。
+	Top int
+	// By はフォルダの順位と棒の長さに使う値 (count, size) です。
+	By string
+}
+
+// chartBar はグラフの1本の棒です。
+type chartBar struct {
+	Label string
+	Value uint64
+	Text  string
+}
+
+// chartBars は集計結果から、値の大きい順に上位のフォルダの棒を求めます。(純粋関数)
+// size の場合はフォルダごとの合計サイズを使うため、集計時にサイズの統計を求めている必要があります。
+func chartBars(report *Report, opts ChartOptions) []chartBar {
+	top := opts.Top
+	if top <= 0 {
+		top = 20
+	}
+	bars := make([]chartBar, 0, len(report.Folders))
+	for _, f := range report.Folders {
+		b := chartBar{Label: f.Path, Value: uint64(f.Count), Text: strconv.Itoa(f.Count)}
+		if opts.By == "size" {
+			if f.Stats == nil {
+				continue
+			}
+			b.Value, b.Text = f.Stats.TotalSize, FormatSize(f.Stats.TotalSize)
+		}
+		bars = append(bars, b)
+	}
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Value > bars[j].Value })
+	return bars[:min(top, len(bars))]
+}
+
+// chartTitle はグラフの見出しを返します。(純粋関数)
+func chartTitle(report *Report, opts ChartOptions) string {
+	metric := "file count"
+	if opts.By == "size" {
+		metric = "total size"
+	}
+	return fmt.Sprintf("Top folders by %s: %s", metric, filepath.Base(report.Archive))
+}
+
+// truncateLabel は長いフォルダパスの先頭を省略し、末尾の階層が見えるようにします。(純粋関数)
+func truncateLabel(s string) string {
+	r := []rune(s)
+	if len(r) <= chartLabelMax {
+		return s
+	}
+	return "…" + string(r[len(r)-chartLabelMax+1:])
+}
+
+// chartLayout は棒の数とラベルの長さから、ラベルの幅と画像全体の大きさを求めます。(純粋関数)
+func chartLayout(bars []chartBar) (labelWidth, width, height int) {
+	longest := 0
+	for _, b := range bars {
+		longest = max(longest, utf8.RuneCountInString(truncateLabel(b.Label)))
+	}
+	labelWidth = longest * chartCharWidth
+	width = chartMargin*3 + labelWidth + chartBarWidth + chartValueWidth
+	height = chartMargin*2 + chartTitleHeight + len(bars)*chartRowHeight
+	return labelWidth, width, height
+}
+
+// barLength は最大値に対する棒の長さを求めます。(純粋関数)
+func barLength(v, maxValue uint64) int {
+	if maxValue == 0 {
+		return 0
+	}
+	return int(float64(v) / float64(maxValue) * chartBarWidth)
+}
+
+// WriteChartSVG は上位のフォルダの横棒グラフをSVG形式でWriterに出力します。
+func WriteChartSVG(w io.Writer, title string, bars []chartBar) error {
+	labelWidth, width, height := chartLayout(bars)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height)
+	fmt.Fprintf(&b, "<rect width=\"100%%\" height=\"100%%\" fill=\"#fff\"/>\n")
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" font-size=\"14\" font-weight=\"bold\">%s</text>\n", chartMargin, chartMargin+14, html.EscapeString(title))
+	var maxValue uint64
+	if len(bars) > 0 {
+		maxValue = bars[0].Value
+	}
+	barX := chartMargin*2 + labelWidth
+	for i, bar := range bars {
+		y := chartMargin + chartTitleHeight + i*chartRowHeight
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", barX-chartMargin/2, y+14, html.EscapeString(truncateLabel(bar.Label)))
+		n := barLength(bar.Value, maxValue)
+		fmt.Fprintf(&b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#4472c4\"/>\n", barX, y+3, n, chartRowHeight-6)
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">%s</text>\n", barX+n+chartMargin/2, y+14, html.EscapeString(bar.Text))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteChartPNG は上位のフォルダの横棒グラフをPNG形式でWriterに出力します。
+// 埋め込みのフォントはASCIIの文字だけを含むため、日本語のフォルダ名を正しく表示するにはSVGを使ってください。
+func WriteChartPNG(w io.Writer, title string, bars []chartBar) error {
+	labelWidth, width, height := chartLayout(bars)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	drawText := func(s string, x, y int) {
+		d := &font.Drawer{Dst: img, Src: image.Black, Face: basicfont.Face7x13, Dot: fixed.P(x, y)}
+		d.DrawString(s)
+	}
+	drawText(title, chartMargin, chartMargin+13)
+
+	var maxValue uint64
+	if len(bars) > 0 {
+		maxValue = bars[0].Value
+	}
+	barX := chartMargin*2 + labelWidth
+	fill := image.NewUniform(color.RGBA{0x44, 0x72, 0xc4, 0xff})
+	for i, bar := range bars {
+		y := chartMargin + chartTitleHeight + i*chartRowHeight
+		label := truncateLabel(bar.Label)
+		drawText(label, barX-chartMargin/2-utf8.RuneCountInString(label)*chartCharWidth, y+14)
+		n := barLength(bar.Value, maxValue)
+		draw.Draw(img, image.Rect(barX, y+3, barX+n, y+chartRowHeight-3), fill, image.Point{}, draw.Src)
+		drawText(bar.Text, barX+n+chartMargin/2, y+14)
+	}
+	return png.Encode(w, img)
+}
+
+// chartWriter はファイルの拡張子に対応するグラフの書き出し関数を返します。
+func chartWriter(chartPath string) (func(io.Writer, string, []chartBar) error, error) {
+	switch strings.ToLower(filepath.Ext(chartPath)) {
+	case ".svg":
+		return WriteChartSVG, nil
+	case ".png":
+		return WriteChartPNG, nil
+	default:
+		return nil, fmt.Errorf("unknown chart format: %s (use .png or .svg)", chartPath)
+	}
+}
+
+// writeChartFile は集計結果の横棒グラフを一時ファイルに書いてからファイルに置き換えます。形式は拡張子から決めます。
+// noClobber が true の場合、ファイルが既にあればエラーを返します。
+func writeChartFile(chartPath string, report *Report, opts ChartOptions, noClobber bool) error {
+	write, err := chartWriter(chartPath)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(chartPath, noClobber, func(file io.Writer) error {
+		if err := write(file, chartTitle(report, opts), chartBars(report, opts)); err != nil {
+			return fmt.Errorf("failed to write chart: %w", err)
+		}
+		return nil
+	})
+}
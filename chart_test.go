@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// chartBars のテスト
+func TestChartBars(t *testing.T) {
+	report := &Report{Folders: []FolderCount{
+		{Path: "a", Count: 30, Stats: &FolderStats{TotalSize: 10}},
+		{Path: "b", Count: 20, Stats: &FolderStats{TotalSize: 5 << 20}},
+		{Path: "c", Count: 10, Stats: &FolderStats{TotalSize: 2048}},
+	}}
+	tests := []struct {
+		name     string
+		opts     ChartOptions
+		expected []chartBar
+	}{
+		{name: "件数の上位", opts: ChartOptions{Top: 2}, expected: []chartBar{{"a", 30, "30"}, {"b", 20, "20"}}},
+		{name: "サイズの上位", opts: ChartOptions{Top: 2, By: "size"}, expected: []chartBar{{"b", 5 << 20, "5.0 MB"}, {"c", 2048, "2.0 KB"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chartBars(report, tt.opts); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// WriteChartSVG と WriteChartPNG のテスト
+func TestWriteChart(t *testing.T) {
+	bars := []chartBar{{"書類\\<見積>", 100, "100"}, {"b", 50, "50"}}
+
+	buf := new(bytes.Buffer)
+	if err := WriteChartSVG(buf, "Top folders", bars); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"<svg", "書類\\&lt;見積&gt;", `width="400"`, `width="200"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("svg does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteChartPNG(buf, "Top folders", bars); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("invalid png: %v", err)
+	}
+	_, width, height := chartLayout(bars)
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Errorf("expected %dx%d, got %v", width, height, b)
+	}
+}
+
+// count の -chart のテスト
+func TestRunCLIChart(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt", "c.txt").WriteFile(t)
+	chartPath := filepath.Join(t.TempDir(), "chart.svg")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-chart", chartPath, "-chart-by", "size"}, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	data, err := os.ReadFile(chartPath)
+	if err != nil || !strings.Contains(string(data), "Top folders by total size") {
+		t.Errorf("unexpected chart: %s, %v", data, err)
+	}
+
+	if code := runCLI([]string{"count", "-zip", zipPath, "-chart", "chart.gif"}, stdout, stderr); code != 1 {
+		t.Errorf("expected code 1 for unknown chart format, got %d", code)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// chatTopFolders はチャットの通知に載せるファイル数の多いフォルダの数です。
+const chatTopFolders = 10
+
+// chatSummary はチャットの通知に載せる処理結果の要約です。
+type chatSummary struct {
+	Title      string
+	TotalFiles int
+	Folders    int
+	// Top はファイル数の多い順のフォルダです。最大 chatTopFolders 件です。
+	Top []FolderCount
+	// Violations は ERROR の検出事項を "フォルダ: メッセージ" の形にしたものです。
+	Violations []string
+	// Warnings は WARN の検出事項の数です。
+	Warnings int
+	// HasReport は集計結果があるかを表します。集計する前に失敗した場合は false です。
+	HasReport bool
+}
+
+// summarizeNotification は通知の集計結果をチャット向けに要約します。(純粋関数)
+func summarizeNotification(n Notification) chatSummary {
+	s := chatSummary{Title: fmt.Sprintf("%s: %s", filepath.Base(n.Archive), n.Status)}
+	if n.Error != "" {
+		s.Violations = append(s.Violations, n.Error)
+	}
+	var report Report
+	if len(n.Report) == 0 || json.Unmarshal(n.Report, &report) != nil {
+		return s
+	}
+	s.HasReport = true
+	s.TotalFiles, s.Folders = report.TotalFiles, len(report.Folders)
+	top := append([]FolderCount(nil), report.Folders...)
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if len(top) > chatTopFolders {
+		top = top[:chatTopFolders]
+	}
+	for i := range top {
+		if top[i].Path == "" {
+			top[i].Path = "(Root)"
+		}
+	}
+	s.Top = top
+	for _, f := range report.Findings {
+		switch f.Severity {
+		case SeverityError:
+			// 終了条件の違反は n.Error にも含まれるため、フォルダごとの内容だけを加える
+			if f.Folder != "" {
+				s.Violations = append(s.Violations, f.Folder+": "+f.Message)
+			}
+		case SeverityWarn:
+			s.Warnings++
+		}
+	}
+	return s
+}
+
+// lines は要約を通知の本文の行にします。bold は強調の書式です。(純粋関数)
+func (s chatSummary) lines(bold func(string) string) []string {
+	var lines []string
+	if s.HasReport {
+		lines = append(lines, fmt.Sprintf("Total files: %d in %d folder(s), warnings: %d", s.TotalFiles, s.Folders, s.Warnings))
+	}
+	if len(s.Top) > 0 {
+		lines = append(lines, bold(fmt.Sprintf("Top %d folders", len(s.Top))))
+		for _, f := range s.Top {
+			lines = append(lines, fmt.Sprintf("%s: %d", f.Path, f.Count))
+		}
+	}
+	if len(s.Violations) > 0 {
+		lines = append(lines, bold("Violations"))
+		lines = append(lines, s.Violations...)
+	}
+	return lines
+}
+
+// SlackNotifier は通知の要約をSlackの Incoming Webhook に投稿します。
+type SlackNotifier struct {
+	URL string
+	// Client はリクエストに使うクライアントです。nil の場合は http.DefaultClient を使います。
+	Client *http.Client
+}
+
+func (s SlackNotifier) Notify(n Notification) error {
+	return postJSON(s.Client, s.URL, slackPayload(summarizeNotification(n)))
+}
+
+func (s SlackNotifier) String() string {
+	return "slack"
+}
+
+// slackPayload はSlackに投稿するメッセージを作成します。(純粋関数)
+func slackPayload(s chatSummary) map[string]any {
+	text := "*" + s.Title + "*"
+	if lines := s.lines(func(t string) string { return "*" + t + "*" }); len(lines) > 0 {
+		text += "\n" + strings.Join(lines, "\n")
+	}
+	return map[string]any{
+		"text": s.Title,
+		"blocks": []any{
+			map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": text}},
+		},
+	}
+}
+
+// TeamsNotifier は通知の要約をMicrosoft Teamsの Incoming Webhook にアダプティブカードで投稿します。
+type TeamsNotifier struct {
+	URL string
+	// Client はリクエストに使うクライアントです。nil の場合は http.DefaultClient を使います。
+	Client *http.Client
+}
+
+func (t TeamsNotifier) Notify(n Notification) error {
+	return postJSON(t.Client, t.URL, teamsPayload(summarizeNotification(n)))
+}
+
+func (t TeamsNotifier) String() string {
+	return "teams"
+}
+
+// teamsPayload はTeamsに投稿するアダプティブカードのメッセージを作成します。(純粋関数)
+func teamsPayload(s chatSummary) map[string]any {
+	body := []any{map[string]any{"type": "TextBlock", "text": s.Title, "weight": "Bolder", "size": "Medium", "wrap": true}}
+	for _, line := range s.lines(func(t string) string { return "**" + t + "**" }) {
+		body = append(body, map[string]any{"type": "TextBlock", "text": line, "wrap": true, "spacing": "None"})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// postJSON は値をJSONにしてURLにPOSTし、2xx以外の応答をエラーにします。
+func postJSON(client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
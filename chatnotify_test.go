@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// summarizeNotification のテスト
+func TestSummarizeNotification(t *testing.T) {
+	report := &Report{
+		Archive:    "in/a.zip",
+		TotalFiles: 10,
+		Findings: []Finding{
+			{Severity: SeverityError, Rule: RuleFailIfOver, Folder: "big", Message: "120 files exceed 100"},
+			{Severity: SeverityWarn, Rule: RuleThreshold, Folder: "big", Message: "120 files"},
+			{Severity: SeverityInfo, Rule: RuleSymlink, Message: "1 symlink"},
+		},
+	}
+	report.Folders = append(report.Folders, FolderCount{Path: "", Count: 2})
+	for i := 1; i <= 11; i++ {
+		report.Folders = append(report.Folders, FolderCount{Path: fmt.Sprintf("d%02d", i), Count: i})
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("集計結果あり", func(t *testing.T) {
+		got := summarizeNotification(Notification{Archive: "in/a.zip", Status: BatchFailed, Error: "1 folder(s) exceed 100 files", Report: data})
+		if got.Title != "a.zip: FAILED" || got.TotalFiles != 10 || got.Folders != 12 || got.Warnings != 1 {
+			t.Errorf("summary = %+v", got)
+		}
+		if len(got.Top) != chatTopFolders || got.Top[0].Path != "d11" || got.Top[chatTopFolders-1].Path != "(Root)" {
+			t.Errorf("Top = %+v", got.Top)
+		}
+		want := []string{"1 folder(s) exceed 100 files", "big: 120 files exceed 100"}
+		if !reflect.DeepEqual(got.Violations, want) {
+			t.Errorf("Violations = %v, want %v", got.Violations, want)
+		}
+	})
+	t.Run("集計前に失敗", func(t *testing.T) {
+		got := summarizeNotification(Notification{Archive: "in/b.zip", Status: BatchFailed, Error: "read entries error"})
+		if got.HasReport || len(got.Top) != 0 || !reflect.DeepEqual(got.Violations, []string{"read entries error"}) {
+			t.Errorf("summary = %+v", got)
+		}
+	})
+}
+
+// slackPayload と teamsPayload のテスト
+func TestChatPayloads(t *testing.T) {
+	s := chatSummary{Title: "a.zip: OK", TotalFiles: 3, Folders: 1, Top: []FolderCount{{Path: "dir1", Count: 3}}, HasReport: true}
+
+	slack, err := json.Marshal(slackPayload(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"text":"a.zip: OK"`, `*Top 1 folders*\ndir1: 3`, `"type":"mrkdwn"`} {
+		if !strings.Contains(string(slack), want) {
+			t.Errorf("slack payload does not contain %q: %s", want, slack)
+		}
+	}
+
+	teams, err := json.Marshal(teamsPayload(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"contentType":"application/vnd.microsoft.card.adaptive"`, `"text":"**Top 1 folders**"`, `"text":"dir1: 3"`} {
+		if !strings.Contains(string(teams), want) {
+			t.Errorf("teams payload does not contain %q: %s", want, teams)
+		}
+	}
+}
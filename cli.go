@@ -0,0 +1,821 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// cliEnv はサブコマンドの実行に必要な入出力をまとめたものです。
+type cliEnv struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Logger *slog.Logger
+	// log は -log-format などで指定されたログの出力方法です。
+	log logOptions
+	// lang は -lang または環境変数のロケールで決めたメッセージの言語です。
+	lang Lang
+	// errorFormat は -errors で指定された終了時のエラーの出力形式 (text, json) です。空の場合は text です。
+	errorFormat string
+}
+
+// command はサブコマンドの定義です。
+type command struct {
+	Name    string
+	Summary string
+	Run     func(env *cliEnv, args []string) error
+}
+
+// commands は利用可能なサブコマンドの一覧です。
+// 各コマンドがヘルプ表示のために commands を参照するため、初期化は init で行います。
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{Name: "allowlist", Summary: "許可リストと照合し、想定外のフォルダと欠けているフォルダを報告します", Run: runAllowList},
+		{Name: "analyze-threshold", Summary: "フォルダごとのファイル数の分布を調べ、しきい値を提案します", Run: runAnalyzeThreshold},
+		{Name: "batch", Summary: "複数のZIPファイルを順に集計し、アーカイブごとの成否を一覧で報告します", Run: runBatch},
+		{Name: "bench", Summary: "ZIPファイルの読み込み、変換、集計、書き出しの段階ごとの処理時間を計測します", Run: runBench},
+		{Name: "compare", Summary: "2つのZIPファイルのエントリを表記の違いを除いて比較します", Run: runCompare},
+		{Name: "compression", Summary: "フォルダごとの圧縮率を集計し、圧縮率の悪いフォルダを検出します", Run: runCompression},
+		{Name: "count", Summary: "ZIPファイル内のフォルダごとのファイル数を集計します", Run: runCount},
+		{Name: "diff", Summary: "2つのZIPファイル、または以前のレポートとZIPファイルのフォルダごとのファイル数を比較します", Run: runDiff},
+		{Name: "extract", Summary: "集計と同じ条件で絞り込んだエントリをフォルダに展開します", Run: runExtract},
+		{Name: "inodes", Summary: "フォルダ配下に展開されるファイルとフォルダの数を集計し、上限を超えるものを警告します", Run: runInodes},
+		{Name: "methods", Summary: "フォルダごとに圧縮方式 (Store、Deflate など) 別のエントリ数を集計し、展開できない方式を検出します", Run: runMethods},
+		{Name: "perms", Summary: "誰でも書き込めるエントリと setuid、setgid のエントリをフォルダごとに報告します", Run: runPerms},
+		{Name: "prune", Summary: "履歴データベースから保持条件を外れた実行記録を削除します", Run: runPrune},
+		{Name: "quarters", Summary: "最上位フォルダと更新日時の四半期の組み合わせごとにファイル数を集計します", Run: runQuarters},
+		{Name: "raw-names", Summary: "エントリ名を復号せずにフォルダごとのファイル数を集計し、復号後の名前と対比します", Run: runRawNames},
+		{Name: "score", Summary: "フォルダごとの納品仕様への適合度をスコアで評価します", Run: runScore},
+		{Name: "serve", Summary: "集計機能をHTTPサービスとして提供します", Run: runServe},
+		{Name: "trend", Summary: "履歴データベースからフォルダごとのファイル数の推移を表示し、変化の大きいフォルダを示します", Run: runTrend},
+		{Name: "types", Summary: "フォルダごとに画像・文書・アーカイブ・実行ファイルなどの分類別のファイル数とサイズを集計します", Run: runTypes},
+		{Name: "update", Summary: "リリースのURLから新しいバージョンを取得し、署名とチェックサムを検証して実行ファイルを置き換えます", Run: runUpdate},
+		{Name: "variance", Summary: "複数のZIPファイル間でフォルダごとの件数のばらつきを集計します", Run: runVariance},
+		{Name: "validate", Summary: "ZIPファイルを集計せずに構造の問題だけを検査します", Run: runValidate},
+		{Name: "version", Summary: "バージョンとビルド情報、対応する入力形式・出力形式・文字コードの一覧を表示します", Run: runVersion},
+		{Name: "watch", Summary: "受け入れフォルダを監視し、届いたZIPファイルごとにレポートを出力します", Run: runWatch},
+	}
+}
+
+// errUsage は引数の誤りを表し、終了コード2で終了させるために使います。
+var errUsage = errors.New("usage error")
+
+// runCLI はコマンドライン引数に応じてサブコマンドを実行し、終了コードを返します。
+// 先頭の引数がフラグの場合は、従来の互換のため count として扱います。
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	env := &cliEnv{
+		Stdout: stdout,
+		Stderr: stderr,
+		lang:   langFromEnv(os.Getenv),
+	}
+	env.rebuildLogger()
+	defer env.closeLog()
+
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+	name := "count"
+	if !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		return runHelp(env, args)
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command: %s\n\n", name)
+		printUsage(stderr)
+		return 2
+	}
+
+	if err := cmd.Run(env, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		code := exitCode(err)
+		if env.errorFormat == "json" {
+			writeErrorJSON(env.Stderr, err, code)
+			return code
+		}
+		var violation *PolicyViolation
+		switch {
+		case errors.Is(err, errUsage):
+		case errors.As(err, &violation):
+			env.Logger.Warn("集計結果が終了条件に該当しました", slog.String("reason", violation.Reason))
+		default:
+			env.Logger.Error("アプリケーションエラー", slog.String("error", env.lang.Error(err.Error())))
+		}
+		return code
+	}
+	return 0
+}
+
+// exitCode はエラーに対応する終了コードを返します。(純粋関数)
+func exitCode(err error) int {
+	var violation *PolicyViolation
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.As(err, &violation):
+		return violation.Code
+	}
+	return 1
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// runHelp は "help <command>" でサブコマンドのヘルプを表示します。
+func runHelp(env *cliEnv, args []string) int {
+	if len(args) == 0 {
+		printUsage(env.Stdout)
+		return 0
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(env.Stderr, "unknown command: %s\n", args[0])
+		return 2
+	}
+	cmd.Run(&cliEnv{Stdout: env.Stdout, Stderr: env.Stdout, Logger: env.Logger}, []string{"-h"})
+	return 0
+}
+
+// printUsage はサブコマンドの一覧を表示します。
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "使い方: obuzipcount <command> [options]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "コマンド:")
+	names := make([]string, 0, len(commands))
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(w, "  %-18s %s\n", n, findCommand(n).Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "各コマンドの詳細は \"obuzipcount help <command>\" を参照してください。")
+}
+
+// newFlagSet はサブコマンド用のFlagSetをヘルプ文付きで作成します。
+func newFlagSet(env *cliEnv, name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	env.registerLogFlags(fs)
+	registerProfileFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使い方: obuzipcount %s\n\n%s\n\nオプション:\n", usage, findCommand(name).Summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags はフラグを解析し、ヘルプ以外の解析エラーを errUsage に変換します。
+// -profile が指定された場合は、コマンドラインで指定されていないフラグにプロファイルの値を設定します。
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return applyProfile(fs)
+}
+
+// flagSet はフラグがコマンドラインまたはプロファイルで指定されたかを判定します。
+func flagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) { found = found || f.Name == name })
+	return found
+}
+
+// usageError は引数の誤りをメッセージとヘルプ付きで報告し、errUsage をラップしたエラーを返します。
+// -lang の指定に合わせてメッセージを訳します。
+func usageError(fs *flag.FlagSet, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	// -errors json では終了時にJSONで報告するため、メッセージとヘルプを出力しない
+	if f := fs.Lookup("errors"); f != nil && f.Value.String() == "json" {
+		return &usageErr{msg: msg}
+	}
+	var lang Lang
+	if f := fs.Lookup("lang"); f != nil {
+		lang = Lang(f.Value.String())
+	}
+	fmt.Fprintf(fs.Output(), "%s\n\n", lang.Error(msg))
+	fs.Usage()
+	return &usageErr{msg: msg}
+}
+
+// aggregateFlags は count, diff など集計を行うコマンドで共通のフラグです。
+type aggregateFlags struct {
+	filterFlags
+	threshold     int
+	thresholdPct  float64
+	rules         thresholdRuleFlag
+	segments      string
+	rollupPath    string
+	normalization string
+	groupSegment  int
+	follow        bool
+	paths         FolderFormat
+	maxMemory     string
+	spillDir      string
+}
+
+func (f *aggregateFlags) register(fs *flag.FlagSet, defaultThreshold int) {
+	fs.IntVar(&f.threshold, "threshold", defaultThreshold, "抽出するファイル数のしきい値")
+	fs.Float64Var(&f.thresholdPct, "threshold-pct", 0, "全体のファイル数に対する割合 (パーセント) のしきい値。-threshold も指定した場合は両方を満たすフォルダを抽出し、省略した場合は割合だけで判定する (例: 5)")
+	fs.Var(&f.rules, "rule", "フォルダパスのパターンごとのしきい値 <パターン>=<しきい値> (繰り返し指定可、先に指定したものを優先、一致しないフォルダは -threshold。例: -rule \"images/**=50000\")")
+	fs.StringVar(&f.segments, "segments", "", "集計に使うフォルダ階層の範囲 (例: 2:4, :3)")
+	fs.StringVar(&f.rollupPath, "rollup", "", "フォルダ集約ルールの定義ファイル (例: scans/2023*/** -> scans/2023)")
+	fs.StringVar(&f.normalization, "normalize", "nfc", "フォルダパスのUnicode正規化 (nfc, nfd, nfkc, nfkd, none)")
+	fs.IntVar(&f.groupSegment, "group-segment", 0, "フォルダパスのこの番号 (1始まり) の階層の名前ごとに集計する (例: */2024/* を年ごとにまとめるには 2。-segments, -rollup とは併用不可)")
+	fs.BoolVar(&f.follow, "follow-symlinks", false, "シンボリックリンクを通常のファイルとして集計する (既定ではファイル数に含めず、別に数える)")
+	fs.StringVar(&f.maxMemory, "max-memory", "", "フォルダごとの集計に使うメモリの目安 (例: 512MB)。超えた分は一時ファイルに書き出して最後に併合する (エントリの一覧は対象外)")
+	fs.StringVar(&f.spillDir, "spill-dir", "", "-max-memory で書き出す一時ファイルのフォルダ (省略時は既定の一時フォルダ)")
+	registerFolderFormatFlags(fs, &f.paths)
+	f.filterFlags.register(fs, "集計")
+}
+
+// apply はフラグの値を設定に反映します。
+func (f *aggregateFlags) apply(fs *flag.FlagSet, cfg *AppConfig) error {
+	segmentRange, err := ParseSegmentRange(f.segments)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if _, err := ParseNormalization(f.normalization); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if f.thresholdPct < 0 || f.thresholdPct > 100 {
+		return usageError(fs, "threshold percentage must be between 0 and 100")
+	}
+	if f.groupSegment < 0 {
+		return usageError(fs, "group segment must not be negative")
+	}
+	if f.groupSegment > 0 && (f.segments != "" || f.rollupPath != "") {
+		return usageError(fs, "-group-segment cannot be combined with -segments or -rollup")
+	}
+	if cfg.Filter, err = f.filterFlags.filter(fs); err != nil {
+		return err
+	}
+	if cfg.MaxMemory, err = ParseSize(f.maxMemory); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	cfg.SpillDir = f.spillDir
+	cfg.Filter.Symlinks = f.follow
+	cfg.Threshold = f.threshold
+	// 割合だけを指定した場合は、件数の既定のしきい値を使わない
+	if f.thresholdPct > 0 && !flagSet(fs, "threshold") {
+		cfg.Threshold = 0
+	}
+	cfg.ThresholdPercent = f.thresholdPct
+	cfg.ThresholdRules = f.rules
+	cfg.Segments = segmentRange
+	cfg.RollupPath = f.rollupPath
+	cfg.Normalization = f.normalization
+	cfg.GroupSegment = f.groupSegment
+	cfg.Paths = f.paths
+	return nil
+}
+
+// filterFlags は集計や展開の対象とするエントリを絞り込む共通のフラグです。
+type filterFlags struct {
+	modifiedAfter  string
+	modifiedBefore string
+	minSize        string
+	maxSize        string
+	include        string
+	exclude        string
+	includeComment string
+	excludeComment string
+}
+
+// register はフラグを登録します。action はヘルプに表示する「集計」「展開」などの処理の名前です。
+func (f *filterFlags) register(fs *flag.FlagSet, action string) {
+	fs.StringVar(&f.modifiedAfter, "modified-after", "", "この日時以降に更新されたファイルだけを"+action+"する (例: 2024-04-01)")
+	fs.StringVar(&f.modifiedBefore, "modified-before", "", "この日時より前に更新されたファイルだけを"+action+"する (例: 2024-05-01)")
+	fs.StringVar(&f.minSize, "min-size", "", "このサイズ以上のファイルだけを"+action+"する (例: 1, 10KB)")
+	fs.StringVar(&f.maxSize, "max-size", "", "このサイズ以下のファイルだけを"+action+"する (例: 10MB, 1.5GB)")
+	fs.StringVar(&f.include, "include", "", "パスが一致するエントリだけを"+action+"するパターン (カンマ区切り、\"**\" は0個以上の階層、例: docs/**/*.pdf)")
+	fs.StringVar(&f.exclude, "exclude", "", "フォルダ名またはファイル名が一致するエントリを除くパターン (カンマ区切り、例: __MACOSX,.DS_Store,Thumbs.db)")
+	fs.StringVar(&f.includeComment, "include-comment", "", "エントリのコメントが一致するエントリだけを"+action+"する正規表現 (例: ^APPROVED)")
+	fs.StringVar(&f.excludeComment, "exclude-comment", "", "エントリのコメントが一致するエントリを除く正規表現 (例: (?i)draft)")
+}
+
+// filter はフラグの値から EntryFilter を作成します。
+func (f *filterFlags) filter(fs *flag.FlagSet) (EntryFilter, error) {
+	var filter EntryFilter
+	var err error
+	if filter.ModifiedAfter, err = ParseFilterTime(f.modifiedAfter); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.ModifiedBefore, err = ParseFilterTime(f.modifiedBefore); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.MinSize, err = ParseSize(f.minSize); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.MaxSize, err = ParseSize(f.maxSize); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.Include, err = ParseIncludePatterns(f.include); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.Exclude, err = ParseExcludePatterns(f.exclude); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.IncludeComment, err = ParseCommentPattern(f.includeComment); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	if filter.ExcludeComment, err = ParseCommentPattern(f.excludeComment); err != nil {
+		return EntryFilter{}, usageError(fs, "%v", err)
+	}
+	return filter, nil
+}
+
+// csvFlags はCSVを出力するコマンドに共通の、文字コードと区切り文字のフラグです。
+type csvFlags struct {
+	encoding  string
+	delimiter string
+}
+
+func (f *csvFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.encoding, "csv-encoding", "", "CSVの文字コード (utf8-bom, utf8, shift_jis, cp932、省略時は utf8-bom)")
+	fs.StringVar(&f.delimiter, "csv-delimiter", "", "CSVの区切り文字 (comma, tab, semicolon、省略時は comma)")
+}
+
+// options はフラグの値からCSV出力の形式を返します。値が不正な場合は使い方のエラーを返します。
+func (f *csvFlags) options(fs *flag.FlagSet) (CSVOptions, error) {
+	opts := CSVOptions{Encoding: f.encoding, Delimiter: f.delimiter}
+	if err := opts.validate(); err != nil {
+		return CSVOptions{}, usageError(fs, "%v", err)
+	}
+	return opts, nil
+}
+
+// readerFlags はアーカイブの読み込み方法に関する共通のフラグです。
+type readerFlags struct {
+	encodings         string
+	remoteParallelism int
+	remoteChunkSize   int64
+	plugins           string
+	cacheDir          string
+	decodeWorkers     int
+	zipExtensions     string
+	opaqueExtensions  string
+}
+
+func (f *readerFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.encodings, "encoding-fallbacks", "cp932", "UTF-8でないエントリ名の復号に順に試す文字コード (例: cp932,cp437,latin1)")
+	fs.IntVar(&f.remoteParallelism, "remote-parallelism", defaultRemoteParallelism, "URLのアーカイブを範囲指定で読み込む際の同時リクエスト数")
+	fs.Int64Var(&f.remoteChunkSize, "remote-chunk-size", defaultRemoteChunkSize, "URLのアーカイブを範囲指定で読み込む際の1リクエストのバイト数")
+	fs.StringVar(&f.plugins, "reader-plugin", "", "独自のアーカイブ形式を読み込むGoのプラグイン (.so) のパス (カンマ区切り)")
+	fs.IntVar(&f.decodeWorkers, "decode-workers", runtime.NumCPU(), "ZIPのエントリ名の復号を並行して行うゴルーチンの数 (結果の順序は変わらない)")
+	fs.StringVar(&f.zipExtensions, "zip-extensions", extensionList(zipContainerExtensions), "形式を判定せずにZIPとして読み込む、ZIP形式を内部に使う拡張子 (カンマ区切り)")
+	fs.StringVar(&f.opaqueExtensions, "opaque-extensions", extensionList(documentContainerExtensions), "入れ子のアーカイブを読み込む際に、中まで降りずに1つのファイルとして扱う拡張子 (カンマ区切り)")
+	fs.StringVar(&f.cacheDir, "cache-dir", "", "読み込んだエントリの一覧をアーカイブのパス・サイズ・更新日時ごとに保存するフォルダ。変わっていないアーカイブの再読み込みを省く")
+}
+
+// newReader はフラグの値に従って、ZIPファイル、ディスクイメージ、キャビネット、MSI、tar (gzip、bzip2の圧縮を含む) を読み込める ArchiveReader を作成します。
+func (f *readerFlags) newReader(fs *flag.FlagSet) (AutoArchiveReader, error) {
+	decoder, err := ParseNameDecoders(f.encodings)
+	if err != nil {
+		return AutoArchiveReader{}, usageError(fs, "%v", err)
+	}
+	if f.remoteParallelism <= 0 || f.remoteChunkSize <= 0 {
+		return AutoArchiveReader{}, usageError(fs, "remote parallelism and chunk size must be positive")
+	}
+	if f.decodeWorkers <= 0 {
+		return AutoArchiveReader{}, usageError(fs, "decode workers must be positive")
+	}
+	zipExts, err := ParseExtensionList(f.zipExtensions)
+	if err != nil {
+		return AutoArchiveReader{}, usageError(fs, "%v", err)
+	}
+	opaqueExts, err := ParseExtensionList(f.opaqueExtensions)
+	if err != nil {
+		return AutoArchiveReader{}, usageError(fs, "%v", err)
+	}
+	containers := ContainerOptions{Zip: zipExts, Opaque: opaqueExts}
+	for _, p := range strings.Split(f.plugins, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if err := LoadReaderPlugin(p); err != nil {
+			return AutoArchiveReader{}, err
+		}
+	}
+	remote := RemoteOptions{Parallelism: f.remoteParallelism, ChunkSize: f.remoteChunkSize}
+	var cache *EntryCache
+	if f.cacheDir != "" {
+		cache = &EntryCache{Dir: f.cacheDir, Salt: f.encodings + "\x00" + f.plugins + "\x00" + extensionList(zipExts)}
+	}
+	if len(decoder) == 0 {
+		return AutoArchiveReader{Zip: ZipArchiveReader{Remote: remote, Workers: f.decodeWorkers}, Cache: cache, Containers: containers}, nil
+	}
+	cab := CabArchiveReader{Decoder: decoder}
+	tar := TarArchiveReader{Decoder: decoder}
+	return AutoArchiveReader{Zip: ZipArchiveReader{Decoder: decoder, Remote: remote, Workers: f.decodeWorkers}, Cab: cab, Msi: MsiArchiveReader{Cab: cab}, Tar: tar, Compressed: CompressedArchiveReader{Tar: tar}, Cache: cache, Containers: containers}, nil
+}
+
+// outputFlag は繰り返し指定でき、カンマ区切りで複数の出力先を指定できる -output フラグです。
+type outputFlag []OutputTarget
+
+func (f *outputFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, t := range *f {
+		parts[i] = t.Format
+		if t.Path != "" {
+			parts[i] += ":" + t.Path
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *outputFlag) Set(s string) error {
+	targets, err := ParseOutputTargets(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, targets...)
+	return nil
+}
+
+// runCount は count コマンドを実行します。
+func runCount(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "count", "count -zip <path> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 10000)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	csvPath := fs.String("csv", "", "結果を出力するCSVファイルのパス (省略時は画面表示。-format も指定した場合は画面表示と併用)")
+	csvAppendPath := fs.String("csv-append", "", "結果を実行日時とアーカイブ名の列を付けて追記するCSVファイルのパス (見出し行はファイルが新しい場合だけ出力)。他の出力ファイルと異なり一時ファイルを介さずに直接追記するため、書き出しの途中で中断すると途中までの行が残ることがある (次の追記は改行を補ってから行う)")
+	overwrite := fs.Bool("overwrite", false, "結果などの出力ファイルが既にあれば置き換える (既定の動作)")
+	noClobber := fs.Bool("no-clobber", false, "結果などの出力ファイルが既にあれば集計せずにエラーとする (-csv-append のファイルを除く)")
+	detailCsvPath := fs.String("detail-csv", "", "ロールアップ等を適用しない末端フォルダの集計を出力するCSVファイルのパス")
+	manifestPath := fs.String("manifest", "", "エントリごとの名前、サイズ、CRC-32、更新日時の一覧を出力するファイルのパス")
+	manifestFormat := fs.String("manifest-format", "", "エントリごとの一覧の形式 (csv, jsonl、省略時は拡張子が .jsonl なら jsonl、それ以外は csv)")
+	format := fs.String("format", "text", "画面出力の形式 (text, csv, markdown, html, json, sarif, template)")
+	templatePath := fs.String("template", "", "text/template のテンプレートファイル。-format 省略時は画面出力に使う (-output template:<パス> でファイルにも出力可)")
+	colorMode := fs.String("color", "auto", "画面へのテキスト出力で重要度に応じて色を付けるか (auto, always, never)")
+	sqlitePath := fs.String("sqlite", "", "実行記録と結果を追記するSQLiteデータベースのパス")
+	sqliteKeepRuns := fs.Int("sqlite-keep-runs", 0, "追記後、アーカイブごとに残す新しい実行記録の件数 (0は無制限)")
+	sqliteKeepDays := fs.Int("sqlite-keep-days", 0, "追記後、この日数より古い実行記録を削除する (0は無制限)")
+	computeHash := fs.Bool("sha256", false, "アーカイブ自体のSHA-256を計算してレポートに含める")
+	var outputs outputFlag
+	fs.Var(&outputs, "output", "追加の出力先 <形式>[=<パス>] (カンマ区切りまたは繰り返し指定可、パス省略時または \"-\" は画面表示。例: -output csv=result.csv,text=-,json=result.json)")
+	failIfOver := fs.Int("fail-if-over", 0, "ファイル数がこの値を超えるフォルダがあれば終了コード2で終了する (0は判定しない)")
+	failIfEmpty := fs.Bool("fail-if-empty", false, "集計対象のファイルが1件もなければ終了コード3で終了する")
+	expectPath := fs.String("expect", "", "期待するフォルダとファイル数の範囲を定義した仕様 (YAMLまたはCSV)。満たさないフォルダがあれば終了コード4で終了する")
+	flagExecutables := fs.Bool("flag-executables", false, "実行ファイルやスクリプトを検出事項として報告する (拡張子は -executable-extensions)")
+	executableExts := fs.String("executable-extensions", defaultExecutableExtensions, "-flag-executables で報告する拡張子 (カンマ区切り)")
+	failOnFlagged := fs.Bool("fail-on-flagged", false, "実行ファイルやスクリプトがあれば終了コード5で終了する (-flag-executables を含む)")
+	var namingRules namingRuleFlag
+	fs.Var(&namingRules, "naming-rule", "フォルダパスの末尾に \"/\" を付けた文字列が一致すべき正規表現 (繰り返し指定可、すべてに一致する必要がある。例: -naming-rule \"^\\d{8}_[A-Z]{3}/\")")
+	namingRulesPath := fs.String("naming-rules", "", "1行に1つの命名規則の正規表現を記述したファイル (-naming-rule と併用可、# で始まる行は無視)")
+	failOnNaming := fs.Bool("fail-on-naming", false, "命名規則に違反するフォルダがあれば終了コード6で終了する")
+	controlFile := fs.String("control", "", "アーカイブ内の管理ファイルの名前 (例: control.csv)。\"フォルダ,ファイル数\" の記載と実際のファイル数が一致しなければ終了コード7で終了する")
+	dedupe := fs.String("dedupe", "last", "同じ名前のエントリが複数ある場合の集計方法 (last: 最後のエントリ、first: 最初のエントリ、count-all: すべて数える)。重複する名前は検出事項として報告する")
+	reportSymlinks := fs.Bool("report-symlinks", false, "シンボリックリンクとデバイスなどの特殊なエントリを1件ずつ検出事項として報告する")
+	stats := fs.Bool("stats", false, "フォルダごとの合計・平均サイズと最大のファイルを出力に含める")
+	cumulative := fs.Bool("cumulative", false, "配下のフォルダを含む累積の件数で集計し、親フォルダと全体に対する割合の列を出力に含める")
+	columns := fs.String("columns", "", "text, csv, json 形式で出力する列と順序 (カンマ区切り。"+strings.Join(columnNames, ", ")+"。例: path,count,size,pct)")
+	collapseOther := fs.Bool("other", false, "しきい値未満のフォルダを除外せず、合計の件数を1行の (Other: N folders) にまとめて出力する (-cumulative とは併用不可)")
+	chartPath := fs.String("chart", "", "上位のフォルダの横棒グラフを出力するファイルのパス (.png, .svg。日本語のフォルダ名は .svg を推奨)")
+	chartTop := fs.Int("chart-top", 20, "グラフに表示するフォルダの数")
+	chartBy := fs.String("chart-by", "count", "グラフの順位と棒の長さに使う値 (count, size)")
+	tui := fs.Bool("tui", false, "フォルダツリーを展開・検索・並べ替えできる対話的な画面で結果を表示する")
+	timeBudget := fs.Duration("time-budget", 0, "読み込みの時間の上限 (例: 5m)。超えた時点で打ち切り、推定値を含む部分的な結果を出力する (0は無制限)")
+	sample := fs.String("sample", "", "集計するファイルを抽出する割合 (例: 5%)。フォルダごとのファイル数を95%信頼区間付きの推定値で出力する")
+	csvPreset := fs.String("csv-preset", "", "CSVの出力先に合わせた既定値 (sharepoint)")
+	csvEncoding := fs.String("csv-encoding", "", "CSVの文字コード (utf8-bom, utf8, shift_jis, cp932、省略時はプリセットの既定値)")
+	csvDelimiter := fs.String("csv-delimiter", "", "CSVの区切り文字 (comma, tab, semicolon、省略時は comma)")
+	csvPathPrefix := fs.String("csv-path-prefix", "", "パスの長さの検証時にフォルダパスの前に付ける文字列 (例: sites/records/Shared Documents)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	csvOpts, err := CSVPreset(*csvPreset)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if *csvEncoding != "" {
+		if err := validateCSVEncoding(*csvEncoding); err != nil {
+			return usageError(fs, "%v", err)
+		}
+		csvOpts.Encoding = *csvEncoding
+	}
+	if *csvDelimiter != "" {
+		if err := validateCSVDelimiter(*csvDelimiter); err != nil {
+			return usageError(fs, "%v", err)
+		}
+		csvOpts.Delimiter = *csvDelimiter
+	}
+	if *csvPathPrefix != "" {
+		csvOpts.PathPrefix = *csvPathPrefix
+	}
+	// -template だけを指定した場合はテンプレートで画面に出力する
+	if *templatePath != "" && !flagSet(fs, "format") {
+		*format = "template"
+	}
+	cols, err := ParseColumns(*columns)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if slices.Contains(cols, "parent-pct") && !*cumulative {
+		return usageError(fs, "column parent-pct requires -cumulative")
+	}
+	if *collapseOther && *cumulative {
+		return usageError(fs, "-other cannot be combined with -cumulative")
+	}
+	dedupePolicy, err := ParseDedupePolicy(*dedupe)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	samplePercent, err := ParseSamplePercent(*sample)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	// エントリの一覧と詳細レポートは部分的な結果を表せないため、抽出や打ち切りとは併用できない
+	if (*manifestPath != "" || *detailCsvPath != "") && (samplePercent > 0 || *timeBudget > 0) {
+		return usageError(fs, "-manifest and -detail-csv cannot be combined with -sample or -time-budget")
+	}
+	if *chartBy != "count" && *chartBy != "size" {
+		return usageError(fs, "unknown chart metric: %s", *chartBy)
+	}
+	var flagExts []string
+	if *flagExecutables || *failOnFlagged {
+		if flagExts, err = ParseExtensions(*executableExts); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+	if *namingRulesPath != "" {
+		rules, err := LoadNamingRules(*namingRulesPath)
+		if err != nil {
+			return err
+		}
+		namingRules = append(namingRules, rules...)
+	}
+	if *failOnNaming && len(namingRules) == 0 {
+		return usageError(fs, "-fail-on-naming requires -naming-rule or -naming-rules")
+	}
+	if *overwrite && *noClobber {
+		return usageError(fs, "-overwrite cannot be combined with -no-clobber")
+	}
+	// -csv または -csv-append と -format を併せて指定した場合はCSVファイルと画面の両方に出力する
+	if (*csvPath != "" || *csvAppendPath != "") && flagSet(fs, "format") {
+		outputs = append(outputFlag{{Format: *format}}, outputs...)
+	}
+	color, err := useColor(*colorMode, env.Stdout)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	cfg := AppConfig{
+		ZipPath:        *zipPath,
+		CsvPath:        *csvPath,
+		CsvAppendPath:  *csvAppendPath,
+		NoClobber:      *noClobber,
+		DetailCsvPath:  *detailCsvPath,
+		ManifestPath:   *manifestPath,
+		ManifestFormat: *manifestFormat,
+		Format:         *format,
+		Outputs:        outputs,
+		Policy:         ResultPolicy{FailIfOver: *failIfOver, FailIfEmpty: *failIfEmpty},
+		ExpectPath:     *expectPath,
+		FlagExtensions: flagExts,
+		FailOnFlagged:  *failOnFlagged,
+		NamingRules:    namingRules,
+		FailOnNaming:   *failOnNaming,
+		ControlFile:    *controlFile,
+		ReportSymlinks: *reportSymlinks,
+		Dedupe:         dedupePolicy,
+		TemplatePath:   *templatePath,
+		ChartPath:      *chartPath,
+		Chart:          ChartOptions{Top: *chartTop, By: *chartBy},
+		SQLitePath:     *sqlitePath,
+		Retention:      RetentionPolicy{KeepRuns: *sqliteKeepRuns, KeepDays: *sqliteKeepDays},
+		SHA256:         *computeHash,
+		CSV:            csvOpts,
+		Stats:          *stats,
+		Cumulative:     *cumulative,
+		CollapseOther:  *collapseOther,
+		Columns:        cols,
+		TimeBudget:     *timeBudget,
+		SamplePercent:  samplePercent,
+		Color:          color,
+		Lang:           env.lang,
+	}
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	if *tui {
+		return runTUI(reader, cfg, nil, env.Stdout)
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	return app.Run(cfg, env.Stdout)
+}
+
+// useColor は -color の指定と出力先から、色を付けるかを判定します。
+// auto の場合は出力先が端末で、環境変数 NO_COLOR が設定されていないときに色を付けます。
+func useColor(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "", "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		f, ok := w.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("unknown color mode: %s", mode)
+	}
+}
+
+// runDiff は diff コマンドを実行します。
+func runDiff(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "diff", "diff [options] <old.zip> <new.zip>\n       obuzipcount diff [options] -baseline <report.csv|report.json> <new.zip>")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	all := fs.Bool("all", false, "件数が変わらないフォルダも出力する")
+	baseline := fs.String("baseline", "", "古いアーカイブの代わりに比較の基準とする、以前に count で保存したCSVまたはJSONのレポート (しきい値未満で出力されなかったフォルダは0件とみなす)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch {
+	case *baseline != "" && fs.NArg() != 1:
+		return usageError(fs, "diff with -baseline requires exactly one zip path")
+	case *baseline == "" && fs.NArg() != 2:
+		return usageError(fs, "diff requires exactly two zip paths")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectDiffWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	// しきい値は比較後の差分に対して適用するため、集計時は全フォルダを対象にする
+	opts.Threshold = 0
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	var oldResults []FolderCount
+	if *baseline != "" {
+		oldResults, err = LoadBaseline(*baseline)
+	} else {
+		oldResults, _, err = app.Analyze(fs.Arg(0), opts)
+	}
+	if err != nil {
+		return err
+	}
+	newResults, _, err := app.Analyze(fs.Arg(fs.NArg()-1), opts)
+	if err != nil {
+		return err
+	}
+
+	diffs := DiffFolderCounts(oldResults, newResults, cfg.Threshold, *all)
+	for i := range diffs {
+		diffs[i].Path = cfg.Paths.Folder(diffs[i].Path)
+	}
+	return write(env.Stdout, diffs)
+}
+
+// runValidate は validate コマンドを実行します。
+func runValidate(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "validate", "validate -zip <path> [-verify]")
+	var rf readerFlags
+	rf.register(fs)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	verify := fs.Bool("verify", false, "すべてのエントリを展開してCRC-32を検証する (ローカルのZIPファイルのみ。一覧の検査より大幅に時間がかかる)")
+	verifyWorkers := fs.Int("verify-workers", runtime.NumCPU(), "-verify で同時に展開するエントリの数")
+	var paths FolderFormat
+	registerFolderFormatFlags(fs, &paths)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	if *verifyWorkers <= 0 {
+		return usageError(fs, "verify workers must be positive")
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	// 構造の問題でエントリを読み込めない場合も報告できるよう、構造の検査を先に行う
+	var issues []ValidationIssue
+	if canInspectZip(*zipPath) {
+		structure, err := inspectZipFile(*zipPath)
+		if err != nil {
+			structure = []ValidationIssue{{Problem: ProblemCentralDirectory, Detail: err.Error()}}
+		}
+		issues = structure
+	} else {
+		env.Logger.Info("このアーカイブではヘッダの構造は検査しません", slog.String("zipPath", *zipPath))
+	}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		if len(issues) > 0 {
+			if werr := WriteValidationReport(env.Stdout, 0, issues, env.lang); werr != nil {
+				return werr
+			}
+		}
+		return err
+	}
+	issues = append(issues, ValidateEntries(entries)...)
+	if *verify {
+		if !canInspectZip(*zipPath) {
+			return fmt.Errorf("verify is only supported for local zip files: %s", *zipPath)
+		}
+		corrupt, err := verifyEntries(env.Logger, reader.Zip, *zipPath, *verifyWorkers)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, corrupt...)
+	}
+	if err := WriteValidationReport(env.Stdout, len(entries), issues, env.lang); err != nil {
+		return err
+	}
+	if err := WriteCorruptFolders(env.Stdout, issues, paths); err != nil {
+		return err
+	}
+	// 区切り文字の数の違いは復号後の名前で正しく集計されるため、問題としては数えない
+	if depth := FindDepthDiscrepancies(entries); len(depth) > 0 {
+		if err := WriteDepthDiscrepancies(env.Stdout, depth); err != nil {
+			return err
+		}
+		env.Logger.Warn("復号によってパスの階層が変わるエントリがあります", slog.Int("entries", len(depth)))
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(issues))
+	}
+	return nil
+}
+
+// verifyEntries はエントリの内容を検証し、進捗をログに出力します。
+func verifyEntries(logger *slog.Logger, reader ZipArchiveReader, zipPath string, workers int) ([]ValidationIssue, error) {
+	logger.Info("エントリの内容の検証を開始します", slog.String("zipPath", zipPath), slog.Int("workers", workers))
+	issues, verified, err := reader.Verify(zipPath, VerifyOptions{
+		Workers: workers,
+		Progress: func(p VerifyProgress) {
+			logger.Info("検証中", slog.Int("entries", p.Entries), slog.Int("totalEntries", p.TotalEntries),
+				slog.String("bytes", FormatSize(p.Bytes)), slog.String("totalBytes", FormatSize(p.TotalBytes)))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("エントリの内容の検証が完了しました", slog.Int("verified", verified), slog.Int("corrupt", len(issues)))
+	return issues, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// writeTestZip は指定した名前のエントリを持つZIPファイルを一時ディレクトリに作成します。
+func writeTestZip(t *testing.T, names ...string) string {
+	t.Helper()
+	return obuziptest.New().Files(names...).WriteFile(t)
+}
+
+// runCLI のテスト (コマンドの振り分けと終了コード)
+func TestRunCLI(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/b.txt", "c.txt")
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{name: "引数なしは使い方を表示", args: nil, expectedCode: 2},
+		{name: "未知のコマンド", args: []string{"unknown"}, expectedCode: 2},
+		{name: "version", args: []string{"version"}, expectedCode: 0, expectedOut: "obuzipcount dev"},
+		{name: "count", args: []string{"count", "-zip", zipPath, "-threshold", "2"}, expectedCode: 0, expectedOut: "dir1"},
+		{name: "従来形式のフラグはcountとして扱う", args: []string{"-zip", zipPath, "-threshold", "1"}, expectedCode: 0, expectedOut: "(Root)"},
+		{name: "不正なフラグ", args: []string{"count", "-segments", "x"}, expectedCode: 2},
+		{name: "ヘルプ", args: []string{"count", "-h"}, expectedCode: 0},
+		{name: "diffは2つのパスが必要", args: []string{"diff", zipPath}, expectedCode: 2},
+		{name: "validate", args: []string{"validate", "-zip", zipPath}, expectedCode: 0, expectedOut: "Entries: 3, Problems: 0"},
+		{name: "上限を超えるフォルダがあれば終了コード2", args: []string{"count", "-zip", zipPath, "-fail-if-over", "1"}, expectedCode: 2, expectedOut: "Folder Path"},
+		{name: "上限以内なら成功", args: []string{"count", "-zip", zipPath, "-fail-if-over", "2"}, expectedCode: 0},
+		{name: "対象ファイルがなければ終了コード3", args: []string{"count", "-zip", zipPath, "-fail-if-empty", "-modified-after", "2100-01-01"}, expectedCode: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			code := runCLI(tt.args, stdout, stderr)
+			if code != tt.expectedCode {
+				t.Errorf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+		})
+	}
+}
+
+// obuziptest の読み込み関数が count の出力と互換であることのテスト
+func TestRunCLIWithObuziptest(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt", "c.txt").WriteFile(t)
+	want := []obuziptest.FolderCount{{Path: "dir1", Count: 2}, {Path: "(Root)", Count: 1}}
+
+	for _, format := range []string{"json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-format", format}, stdout, stderr); code != 0 {
+				t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+			}
+			var got []obuziptest.FolderCount
+			if format == "json" {
+				report, err := obuziptest.ReadJSONReport(stdout)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = report.Folders
+			} else {
+				var err error
+				if got, err = obuziptest.ReadCSV(stdout); err != nil {
+					t.Fatal(err)
+				}
+			}
+			obuziptest.AssertFolderCounts(t, got, want)
+		})
+	}
+}
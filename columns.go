@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// reportColumn は -columns で選べる出力の列です。
+type reportColumn struct {
+	// Header はテキスト出力の見出しです。言語に合わせて訳します。
+	Header string
+	// CSVHeader はCSV出力の見出しです。
+	CSVHeader string
+	// Width はテキスト出力の列幅です。負の値は左寄せを表します。
+	Width int
+	// Value は列の値です。JSONにはそのまま、CSVとテキストには文字列にして出力します。
+	Value func(r FolderCount, report *Report) any
+	// Size が true の場合、テキスト出力では値を読みやすいサイズの表記にします。
+	Size bool
+	// Percent が true の場合、値は割合 (パーセント) です。
+	Percent bool
+}
+
+// columnNames は -columns で指定できる列の名前です。
+var columnNames = []string{"path", "count", "estimate", "pct", "parent-pct", "size", "avg", "largest", "largest-size"}
+
+// reportColumns は列の名前ごとの定義です。
+var reportColumns = map[string]reportColumn{
+	"path": {Header: "Folder Path", CSVHeader: "Folder Path", Width: -60,
+		Value: func(r FolderCount, _ *Report) any { return r.Path }},
+	"count": {Header: "File Count", CSVHeader: "File Count", Width: 10,
+		Value: func(r FolderCount, _ *Report) any { return r.Count }},
+	"estimate": {Header: "Estimated", CSVHeader: "Estimated Count", Width: 10,
+		Value: func(r FolderCount, _ *Report) any { return r.Estimate }},
+	"pct": {Header: "% of Total", CSVHeader: "Percent of Total", Width: 10, Percent: true,
+		Value: func(r FolderCount, report *Report) any {
+			if r.Share != nil {
+				return r.Share.OfTotal
+			}
+			return percent(r.Count, report.TotalFiles)
+		}},
+	"parent-pct": {Header: "% of Parent", CSVHeader: "Percent of Parent", Width: 11, Percent: true,
+		Value: func(r FolderCount, _ *Report) any { return r.shareOrZero().OfParent }},
+	"size": {Header: "Total Size", CSVHeader: "Total Size", Width: 10, Size: true,
+		Value: func(r FolderCount, _ *Report) any { return r.statsOrZero().TotalSize }},
+	"avg": {Header: "Avg Size", CSVHeader: "Average Size", Width: 10, Size: true,
+		Value: func(r FolderCount, _ *Report) any { return r.statsOrZero().AverageSize }},
+	"largest": {Header: "Largest File", CSVHeader: "Largest File", Width: -30,
+		Value: func(r FolderCount, _ *Report) any { return r.statsOrZero().LargestFile }},
+	"largest-size": {Header: "Largest Size", CSVHeader: "Largest Size", Width: 12, Size: true,
+		Value: func(r FolderCount, _ *Report) any { return r.statsOrZero().LargestSize }},
+}
+
+// ParseColumns はカンマ区切りの列の名前を解析します。空の場合は nil を返します。(純粋関数)
+func ParseColumns(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var columns []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := reportColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column: %s (available: %s)", name, strings.Join(columnNames, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		seen[name] = true
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// columnsNeedStats はサイズの統計が必要な列を含むかを判定します。(純粋関数)
+func columnsNeedStats(columns []string) bool {
+	for _, name := range columns {
+		if reportColumns[name].Size || name == "largest" {
+			return true
+		}
+	}
+	return false
+}
+
+// columnText は列の値をCSVの文字列にします。(純粋関数)
+func columnText(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// columnDisplay は列の値をテキスト出力の表記にします。(純粋関数)
+func columnDisplay(c reportColumn, v any) string {
+	switch {
+	case c.Size:
+		return FormatSize(v.(uint64))
+	case c.Percent:
+		return fmt.Sprintf("%.1f%%", v.(float64))
+	}
+	return fmt.Sprint(v)
+}
+
+// writeCSVColumns は指定された列だけを指定された順でCSVに出力します。
+func writeCSVColumns(writer csvRecordWriter, report *Report, opts CSVOptions) error {
+	header := make([]string, len(opts.Columns))
+	for i, name := range opts.Columns {
+		header[i] = reportColumns[name].CSVHeader
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, r := range report.Folders {
+		if opts.PathSeparator != "" {
+			r.Path = strings.ReplaceAll(r.Path, "\\", opts.PathSeparator)
+		}
+		record := make([]string, len(opts.Columns))
+		for i, name := range opts.Columns {
+			c := reportColumns[name]
+			record[i] = columnText(c.Value(r, report))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTextColumns は指定された列だけを指定された順でプレーンテキストに出力します。
+func writeTextColumns(w io.Writer, report *Report, columns []string, color bool, lang Lang) error {
+	if err := writeTextPreamble(w, report, color, lang); err != nil {
+		return err
+	}
+	cells := func(value func(c reportColumn) string) string {
+		parts := make([]string, len(columns))
+		for i, name := range columns {
+			c := reportColumns[name]
+			parts[i] = fmt.Sprintf("%*s", c.Width, value(c))
+		}
+		return strings.TrimRight(strings.Join(parts, " | "), " ")
+	}
+	header := cells(func(c reportColumn) string { return lang.T(c.Header) })
+	if _, err := fmt.Fprintf(w, "\n%s\n", header); err != nil {
+		return err
+	}
+	width := 3 * (len(columns) - 1)
+	for _, name := range columns {
+		width += max(reportColumns[name].Width, -reportColumns[name].Width)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", width))
+	severities := folderSeverities(report.Findings)
+	for _, r := range report.Folders {
+		line := cells(func(c reportColumn) string { return columnDisplay(c, c.Value(r, report)) })
+		if _, err := fmt.Fprintln(w, colorize(line, severities[r.Path], color)); err != nil {
+			return err
+		}
+	}
+	return writeTextFindings(w, report.Findings, color, lang)
+}
+
+// columnRow は列を指定された順に並べたJSONのオブジェクトです。
+type columnRow struct {
+	names  []string
+	values []any
+}
+
+// MarshalJSON は列の順序を保ってオブジェクトを書き出します。
+func (r columnRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range r.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// writeJSONColumns は folders の各要素を指定された列だけにして、結果をJSON形式で出力します。
+func writeJSONColumns(w io.Writer, report *Report, columns []string) error {
+	out := struct {
+		*Report
+		Folders []columnRow `json:"folders"`
+	}{Report: report, Folders: make([]columnRow, 0, len(report.Folders))}
+	for _, r := range report.Folders {
+		row := columnRow{names: columns, values: make([]any, len(columns))}
+		for i, name := range columns {
+			row.values[i] = reportColumns[name].Value(r, report)
+		}
+		out.Folders = append(out.Folders, row)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
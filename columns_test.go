@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ParseColumns のテスト
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "空", input: "", expected: nil},
+		{name: "順序を保つ", input: "pct, Path,count", expected: []string{"pct", "path", "count"}},
+		{name: "不明な列", input: "path,owner", wantErr: true},
+		{name: "重複", input: "path,count,path", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseColumns(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v, %v", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+// 列を指定した text, csv, json 形式の出力のテスト
+func TestWriteColumns(t *testing.T) {
+	report := &Report{
+		Archive:    "test.zip",
+		TotalFiles: 8,
+		Folders: []FolderCount{
+			{Path: "a\\b", Count: 6, Stats: &FolderStats{TotalSize: 2048}},
+			{Path: "c", Count: 2, Stats: &FolderStats{TotalSize: 10}},
+		},
+	}
+	opts := WriterOptions{Columns: []string{"pct", "path", "size"}, CSV: CSVOptions{Encoding: "utf8", PathSeparator: "/"}}
+
+	t.Run("CSV", func(t *testing.T) {
+		w, _ := NewResultWriter("csv", opts)
+		out := new(bytes.Buffer)
+		if err := w.Write(out, report); err != nil {
+			t.Fatal(err)
+		}
+		expected := "Percent of Total,Folder Path,Total Size\n75.0,a/b,2048\n25.0,c,10\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("テキスト", func(t *testing.T) {
+		w, _ := NewResultWriter("text", opts)
+		out := new(bytes.Buffer)
+		if err := w.Write(out, report); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 4 || !strings.HasPrefix(lines[0], "% of Total | Folder Path") || lines[2] != "     75.0% | a\\b"+strings.Repeat(" ", 57)+" |     2.0 KB" {
+			t.Errorf("unexpected text output:\n%s", out.String())
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		w, _ := NewResultWriter("json", opts)
+		out := new(bytes.Buffer)
+		if err := w.Write(out, report); err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Archive string            `json:"archive"`
+			Folders []json.RawMessage `json:"folders"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Archive != "test.zip" || len(got.Folders) != 2 {
+			t.Fatalf("unexpected json: %s", out.String())
+		}
+		var compact bytes.Buffer
+		json.Compact(&compact, got.Folders[0])
+		if expected := `{"pct":75,"path":"a\\b","size":2048}`; compact.String() != expected {
+			t.Errorf("expected %s, got %s", expected, compact.String())
+		}
+	})
+}
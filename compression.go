@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FolderCompression はフォルダごとの圧縮前後のバイト数と圧縮率を保持します。
+type FolderCompression struct {
+	Path             string
+	Files            int
+	CompressedSize   uint64
+	UncompressedSize uint64
+	// Ratio は圧縮後 / 圧縮前のバイト数です。1に近いほど圧縮が効いていません。
+	Ratio   float64
+	Flagged bool
+}
+
+// CompressionReport は圧縮率の集計結果です。
+type CompressionReport struct {
+	Folders          []FolderCompression
+	CompressedSize   uint64
+	UncompressedSize uint64
+	Ratio            float64
+}
+
+// ComputeCompression はフォルダごとの圧縮前後のバイト数と圧縮率を求めます。(純粋関数)
+// ファイル数がしきい値以上のフォルダが対象で、圧縮率が maxRatio を超えるものを Flagged とします。
+// 全体の合計はしきい値に関係なくすべてのファイルから求めます。
+// 結果は圧縮率の降順、同じ場合はパスの昇順でソートされます。
+func ComputeCompression(entries []FileEntry, opts AggregateOptions, maxRatio float64) CompressionReport {
+	folders := make(map[string]*FolderCompression)
+	var report CompressionReport
+	for _, f := range entries {
+		if f.IsDir || !opts.Filter.Match(f) {
+			continue
+		}
+		key := FolderKey(f.Name, opts)
+		fc, ok := folders[key]
+		if !ok {
+			fc = &FolderCompression{Path: key}
+			folders[key] = fc
+		}
+		fc.Files++
+		fc.CompressedSize += f.CompressedSize
+		fc.UncompressedSize += f.Size
+		report.CompressedSize += f.CompressedSize
+		report.UncompressedSize += f.Size
+	}
+	report.Ratio = compressionRatio(report.CompressedSize, report.UncompressedSize)
+
+	for _, fc := range folders {
+		if fc.Files < opts.FolderThreshold(fc.Path) {
+			continue
+		}
+		fc.Ratio = compressionRatio(fc.CompressedSize, fc.UncompressedSize)
+		fc.Flagged = fc.Ratio > maxRatio
+		report.Folders = append(report.Folders, *fc)
+	}
+
+	sort.Slice(report.Folders, func(i, j int) bool {
+		if report.Folders[i].Ratio == report.Folders[j].Ratio {
+			return report.Folders[i].Path < report.Folders[j].Path
+		}
+		return report.Folders[i].Ratio > report.Folders[j].Ratio
+	})
+	return report
+}
+
+// compressionRatio は圧縮率を求めます。圧縮前のバイト数が0の場合は0を返します。(純粋関数)
+func compressionRatio(compressed, uncompressed uint64) float64 {
+	if uncompressed == 0 {
+		return 0
+	}
+	return float64(compressed) / float64(uncompressed)
+}
+
+// WriteCompressionText は圧縮率の集計結果をプレーンテキストでWriterに出力します。
+func WriteCompressionText(w io.Writer, report CompressionReport, lang Lang) error {
+	_, err := fmt.Fprintf(w, "%s: %s -> %s (%s %.3f)\n", lang.T("Total"), FormatSize(report.UncompressedSize), FormatSize(report.CompressedSize), lang.T(lang.T("Ratio")), report.Ratio)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\n%-50s | %8s | %10s | %10s | %6s | %s\n", lang.T("Folder Path"), lang.T("Files"), lang.T("Original"), lang.T("Compressed"), lang.T("Ratio"), lang.T("Flag"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 110))
+	for _, fc := range report.Folders {
+		_, err := fmt.Fprintf(w, "%-50s | %8d | %10s | %10s | %6.3f | %s\n",
+			fc.Path, fc.Files, FormatSize(fc.UncompressedSize), FormatSize(fc.CompressedSize), fc.Ratio, compressionFlag(fc))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCompressionCSV は圧縮率の集計結果をCSV形式でWriterに出力します。
+func WriteCompressionCSV(w io.Writer, report CompressionReport, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Files", "Uncompressed Size", "Compressed Size", "Ratio", "Flag"}); err != nil {
+			return err
+		}
+		for _, fc := range report.Folders {
+			record := []string{
+				fc.Path,
+				strconv.Itoa(fc.Files),
+				strconv.FormatUint(fc.UncompressedSize, 10),
+				strconv.FormatUint(fc.CompressedSize, 10),
+				strconv.FormatFloat(fc.Ratio, 'f', 4, 64),
+				compressionFlag(fc),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectCompressionWriter は出力形式名に対応する圧縮率集計結果の書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectCompressionWriter(format string, opts WriterOptions) (func(io.Writer, CompressionReport) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, r CompressionReport) error {
+			return WriteCompressionText(w, r, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, r CompressionReport) error {
+			return WriteCompressionCSV(w, r, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func compressionFlag(fc FolderCompression) string {
+	if fc.Flagged {
+		return "HIGH"
+	}
+	return ""
+}
+
+// runCompression は compression コマンドを実行します。
+func runCompression(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "compression", "compression -zip <path> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	maxRatio := fs.Float64("max-ratio", 0.95, "圧縮率 (圧縮後 / 圧縮前) がこの値を超えるフォルダを検出する")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectCompressionWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	report := ComputeCompression(entries, opts, *maxRatio)
+	for i := range report.Folders {
+		report.Folders[i].Path = cfg.Paths.Folder(report.Folders[i].Path)
+	}
+	return write(env.Stdout, report)
+}
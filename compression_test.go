@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// ComputeCompression のテスト
+func TestComputeCompression(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "text/a.txt", Size: 1000, CompressedSize: 200},
+		{Name: "text/b.txt", Size: 1000, CompressedSize: 300},
+		{Name: "jpeg/a.jpg", Size: 1000, CompressedSize: 990},
+		{Name: "empty/x.txt"},
+		{Name: "jpeg/", IsDir: true},
+	}
+
+	report := ComputeCompression(entries, AggregateOptions{Threshold: 1}, 0.95)
+	expected := CompressionReport{
+		Folders: []FolderCompression{
+			{Path: "jpeg", Files: 1, CompressedSize: 990, UncompressedSize: 1000, Ratio: 0.99, Flagged: true},
+			{Path: "text", Files: 2, CompressedSize: 500, UncompressedSize: 2000, Ratio: 0.25},
+			{Path: "empty", Files: 1},
+		},
+		CompressedSize:   1490,
+		UncompressedSize: 3000,
+		Ratio:            1490.0 / 3000.0,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+
+	t.Run("しきい値未満のフォルダは除外", func(t *testing.T) {
+		report := ComputeCompression(entries, AggregateOptions{Threshold: 2}, 0.95)
+		if len(report.Folders) != 1 || report.Folders[0].Path != "text" {
+			t.Errorf("unexpected folders: %+v", report.Folders)
+		}
+		if report.UncompressedSize != 3000 {
+			t.Errorf("expected total 3000 regardless of threshold, got %d", report.UncompressedSize)
+		}
+	})
+
+	t.Run("CSV出力", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteCompressionCSV(out, report, CSVOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := "jpeg,1,1000,990,0.9900,HIGH\n"
+		if !bytes.Contains(out.Bytes(), []byte(want)) {
+			t.Errorf("output does not contain %q, got: %s", want, out.String())
+		}
+	})
+
+	t.Run("CSVの区切り文字", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteCompressionCSV(out, report, CSVOptions{Encoding: "utf8", Delimiter: "tab"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := "jpeg\t1\t1000\t990\t0.9900\tHIGH\n"
+		if !bytes.Contains(out.Bytes(), []byte(want)) || bytes.HasPrefix(out.Bytes(), []byte("\xEF\xBB\xBF")) {
+			t.Errorf("output does not contain %q without BOM, got: %s", want, out.String())
+		}
+	})
+}
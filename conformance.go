@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 適合度評価の観点の名前です。-weights の指定にも使います。
+const (
+	CheckCount      = "count"
+	CheckSize       = "size"
+	CheckNaming     = "naming"
+	CheckEncoding   = "encoding"
+	CheckPathLength = "pathlen"
+	CheckPairing    = "pairing"
+)
+
+// conformanceChecks は評価の観点を出力順に並べたものです。
+var conformanceChecks = []string{CheckCount, CheckSize, CheckNaming, CheckEncoding, CheckPathLength, CheckPairing}
+
+// DefaultConformanceWeights は観点ごとの既定の重みです。
+var DefaultConformanceWeights = map[string]float64{
+	CheckCount:      3,
+	CheckSize:       1,
+	CheckNaming:     1,
+	CheckEncoding:   2,
+	CheckPathLength: 1,
+	CheckPairing:    1,
+}
+
+// FilePair は同じフォルダに同じ名前で揃っているべきファイルの拡張子の組です (例: pdf と xml)。
+type FilePair struct {
+	Primary string
+	Partner string
+}
+
+// ConformanceSpec は納品仕様への適合度を評価する条件と重みです。
+// 値がゼロ値の観点は評価せず、スコアの計算からも除外します (count と encoding は常に評価します)。
+type ConformanceSpec struct {
+	// MaxFiles はフォルダあたりのファイル数の上限 (この値未満が適合) です。
+	MaxFiles      int
+	MaxFileSize   uint64
+	NamePattern   *regexp.Regexp
+	MaxPathLength int
+	Pairs         []FilePair
+	Weights       map[string]float64
+}
+
+// FolderConformance はフォルダごとの評価結果です。Checks は観点ごとの適合率 (0〜1) です。
+type FolderConformance struct {
+	Path   string             `json:"path"`
+	Files  int                `json:"files"`
+	Checks map[string]float64 `json:"checks"`
+	Score  float64            `json:"score"`
+	Grade  string             `json:"grade"`
+}
+
+// ConformanceReport はアーカイブ全体の評価結果です。
+type ConformanceReport struct {
+	Folders []FolderConformance `json:"folders"`
+	Score   float64             `json:"score"`
+	Grade   string              `json:"grade"`
+}
+
+// folderTally はフォルダごとの観点別の適合ファイル数を数えるための作業領域です。
+type folderTally struct {
+	files  int
+	passed map[string]int
+	paired int
+	pairs  int
+}
+
+// EvaluateConformance はエントリを仕様に照らして評価し、フォルダごとのスコアと全体の評価を返します。(純粋関数)
+// 全体のスコアはファイル数で重み付けしたフォルダのスコアの平均です。
+// 結果はスコアの昇順 (問題の大きい順)、同じ場合はパスの昇順でソートされます。
+func EvaluateConformance(entries []FileEntry, opts AggregateOptions, spec ConformanceSpec) ConformanceReport {
+	tallies := make(map[string]*folderTally)
+	stems := make(map[string]map[string]bool) // 実際のフォルダ/拡張子を除いた名前 → 拡張子の集合
+
+	for _, e := range entries {
+		if e.IsDir || !opts.Filter.Match(e) {
+			continue
+		}
+		key := FolderKey(e.Name, opts)
+		t, ok := tallies[key]
+		if !ok {
+			t = &folderTally{passed: make(map[string]int)}
+			tallies[key] = t
+		}
+		t.files++
+
+		if spec.MaxFileSize > 0 && e.Size <= spec.MaxFileSize {
+			t.passed[CheckSize]++
+		}
+		if spec.NamePattern != nil && spec.NamePattern.MatchString(path.Base(e.Name)) {
+			t.passed[CheckNaming]++
+		}
+		if isPrintableUTF8(e.Name) {
+			t.passed[CheckEncoding]++
+		}
+		if spec.MaxPathLength > 0 && len([]rune(e.Name)) <= spec.MaxPathLength {
+			t.passed[CheckPathLength]++
+		}
+		if len(spec.Pairs) > 0 {
+			stem, ext := splitExt(e.Name)
+			if stems[stem] == nil {
+				stems[stem] = make(map[string]bool)
+			}
+			stems[stem][ext] = true
+		}
+	}
+
+	// 組になるべきファイルの有無は、ロールアップ前の実際のフォルダ単位で判定する
+	for stem, exts := range stems {
+		t := tallies[FolderKey(stem, opts)]
+		for _, p := range spec.Pairs {
+			if exts[p.Primary] {
+				t.pairs++
+				if exts[p.Partner] {
+					t.paired++
+				}
+			}
+		}
+	}
+
+	var report ConformanceReport
+	totalFiles := 0
+	weightedSum := 0.0
+	for key, t := range tallies {
+		fc := FolderConformance{Path: key, Files: t.files, Checks: make(map[string]float64)}
+
+		fc.Checks[CheckCount] = 1
+		if spec.MaxFiles > 0 && t.files >= spec.MaxFiles {
+			fc.Checks[CheckCount] = float64(spec.MaxFiles-1) / float64(t.files)
+		}
+		if spec.MaxFileSize > 0 {
+			fc.Checks[CheckSize] = float64(t.passed[CheckSize]) / float64(t.files)
+		}
+		if spec.NamePattern != nil {
+			fc.Checks[CheckNaming] = float64(t.passed[CheckNaming]) / float64(t.files)
+		}
+		fc.Checks[CheckEncoding] = float64(t.passed[CheckEncoding]) / float64(t.files)
+		if spec.MaxPathLength > 0 {
+			fc.Checks[CheckPathLength] = float64(t.passed[CheckPathLength]) / float64(t.files)
+		}
+		if len(spec.Pairs) > 0 {
+			fc.Checks[CheckPairing] = 1
+			if t.pairs > 0 {
+				fc.Checks[CheckPairing] = float64(t.paired) / float64(t.pairs)
+			}
+		}
+
+		fc.Score = weightedScore(fc.Checks, spec.Weights)
+		fc.Grade = conformanceGrade(fc.Score)
+		report.Folders = append(report.Folders, fc)
+
+		totalFiles += t.files
+		weightedSum += fc.Score * float64(t.files)
+	}
+	if totalFiles > 0 {
+		report.Score = weightedSum / float64(totalFiles)
+	} else {
+		report.Score = 100
+	}
+	report.Grade = conformanceGrade(report.Score)
+
+	sort.Slice(report.Folders, func(i, j int) bool {
+		if report.Folders[i].Score == report.Folders[j].Score {
+			return report.Folders[i].Path < report.Folders[j].Path
+		}
+		return report.Folders[i].Score < report.Folders[j].Score
+	})
+	return report
+}
+
+// splitExt はエントリ名を拡張子を除いた部分と小文字の拡張子 (ドットなし) に分けます。(純粋関数)
+func splitExt(name string) (stem, ext string) {
+	e := path.Ext(name)
+	return strings.TrimSuffix(name, e), strings.ToLower(strings.TrimPrefix(e, "."))
+}
+
+// weightedScore は評価した観点の適合率を重み付き平均し、0〜100のスコアにします。(純粋関数)
+func weightedScore(checks map[string]float64, weights map[string]float64) float64 {
+	if weights == nil {
+		weights = DefaultConformanceWeights
+	}
+	sum, total := 0.0, 0.0
+	for name, ratio := range checks {
+		w := weights[name]
+		sum += ratio * w
+		total += w
+	}
+	if total == 0 {
+		return 100
+	}
+	return sum / total * 100
+}
+
+// conformanceGrade はスコアを A〜F の評価に変換します。(純粋関数)
+func conformanceGrade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// ParseConformanceWeights は "count=3,size=1" 形式の重み指定を既定値に上書きして返します。
+func ParseConformanceWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(DefaultConformanceWeights))
+	for k, v := range DefaultConformanceWeights {
+		weights[k] = v
+	}
+	if s == "" {
+		return weights, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if _, known := DefaultConformanceWeights[name]; !found || !known {
+			return nil, fmt.Errorf("invalid weight: %s", item)
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight: %s", item)
+		}
+		weights[name] = w
+	}
+	return weights, nil
+}
+
+// ParseFilePairs は "pdf:xml,tif:txt" 形式の拡張子の組を解析します。
+func ParseFilePairs(s string) ([]FilePair, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var pairs []FilePair
+	for _, item := range strings.Split(s, ",") {
+		primary, partner, found := strings.Cut(strings.TrimSpace(item), ":")
+		if !found || primary == "" || partner == "" {
+			return nil, fmt.Errorf("invalid pair: %s", item)
+		}
+		pairs = append(pairs, FilePair{
+			Primary: strings.ToLower(strings.TrimPrefix(primary, ".")),
+			Partner: strings.ToLower(strings.TrimPrefix(partner, ".")),
+		})
+	}
+	return pairs, nil
+}
+
+// WriteConformanceText は評価結果をプレーンテキストでWriterに出力します。評価しなかった観点は "-" で表示します。
+func WriteConformanceText(w io.Writer, report ConformanceReport, lang Lang) error {
+	header := fmt.Sprintf("\n%-50s | %8s", lang.T("Folder Path"), lang.T("Files"))
+	for _, c := range conformanceChecks {
+		header += fmt.Sprintf(" | %8s", c)
+	}
+	if _, err := fmt.Fprintf(w, "%s | %6s | %s\n", header, lang.T("Score"), lang.T("Grade")); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 140))
+	for _, f := range report.Folders {
+		line := fmt.Sprintf("%-50s | %8d", f.Path, f.Files)
+		for _, c := range conformanceChecks {
+			if ratio, ok := f.Checks[c]; ok {
+				line += fmt.Sprintf(" | %7.1f%%", ratio*100)
+			} else {
+				line += fmt.Sprintf(" | %8s", "-")
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s | %6.1f | %s\n", line, f.Score, f.Grade); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%s: %.1f (%s)\n", lang.T("Archive score"), report.Score, report.Grade)
+	return err
+}
+
+// WriteConformanceJSON は評価結果をJSON形式でWriterに出力します。
+func WriteConformanceJSON(w io.Writer, report ConformanceReport) error {
+	if report.Folders == nil {
+		report.Folders = []FolderConformance{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// selectConformanceWriter は出力形式名に対応する評価結果の書き出し関数を返します。テキストの見出しは lang に合わせて訳します。
+func selectConformanceWriter(format string, lang Lang) (func(io.Writer, ConformanceReport) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, r ConformanceReport) error {
+			return WriteConformanceText(w, r, lang)
+		}, nil
+	case "json":
+		return WriteConformanceJSON, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// runScore は score コマンドを実行します。
+func runScore(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "score", "score -zip <path> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 10000)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	maxFileSize := fs.String("max-file-size", "", "ファイルサイズの上限 (例: 100MB、省略時は評価しない)")
+	namePattern := fs.String("name-pattern", "", "ファイル名が満たすべき正規表現 (省略時は評価しない)")
+	maxPath := fs.Int("max-path", 260, "パスの文字数の上限 (0で評価しない)")
+	pairs := fs.String("pair", "", "同じ名前で揃うべき拡張子の組 (例: pdf:xml,tif:txt、省略時は評価しない)")
+	weights := fs.String("weights", "", "観点ごとの重み (例: count=3,encoding=2)")
+	format := fs.String("format", "text", "出力形式 (text, json)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	spec := ConformanceSpec{MaxFiles: cfg.Threshold, MaxPathLength: *maxPath}
+	var err error
+	if spec.MaxFileSize, err = ParseSize(*maxFileSize); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if *namePattern != "" {
+		if spec.NamePattern, err = regexp.Compile(*namePattern); err != nil {
+			return usageError(fs, "invalid name pattern: %v", err)
+		}
+	}
+	if spec.Pairs, err = ParseFilePairs(*pairs); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if spec.Weights, err = ParseConformanceWeights(*weights); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	write, err := selectConformanceWriter(*format, env.lang)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	report := EvaluateConformance(entries, opts, spec)
+	for i := range report.Folders {
+		report.Folders[i].Path = cfg.Paths.Folder(report.Folders[i].Path)
+	}
+	return write(env.Stdout, report)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"testing"
+)
+
+// EvaluateConformance のテスト
+func TestEvaluateConformance(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "ok/001.pdf", Size: 10},
+		{Name: "ok/001.xml", Size: 10},
+		{Name: "bad/002.pdf", Size: 10},
+		{Name: "bad/readme.pdf", Size: 1000},
+		{Name: "bad/\x80.pdf", Size: 10},
+		{Name: "bad/dir/", IsDir: true},
+	}
+	spec := ConformanceSpec{
+		MaxFiles:    3,
+		MaxFileSize: 100,
+		NamePattern: regexp.MustCompile(`^\d{3}\.`),
+		Pairs:       []FilePair{{Primary: "pdf", Partner: "xml"}},
+		Weights:     DefaultConformanceWeights,
+	}
+	report := EvaluateConformance(entries, AggregateOptions{}, spec)
+	if len(report.Folders) != 2 {
+		t.Fatalf("expected 2 folders, got %v", report.Folders)
+	}
+
+	bad, ok := report.Folders[0], report.Folders[1]
+	if bad.Path != "bad" || ok.Path != "ok" {
+		t.Fatalf("expected worst folder first, got %v", report.Folders)
+	}
+	if ok.Score != 100 || ok.Grade != "A" {
+		t.Errorf("expected perfect score for ok folder, got %+v", ok)
+	}
+	if _, evaluated := ok.Checks[CheckPathLength]; evaluated {
+		t.Errorf("path length must not be evaluated when MaxPathLength is 0")
+	}
+
+	expectedChecks := map[string]float64{
+		CheckCount:    2.0 / 3,
+		CheckSize:     2.0 / 3,
+		CheckNaming:   1.0 / 3,
+		CheckEncoding: 2.0 / 3,
+		CheckPairing:  0,
+	}
+	for name, expected := range expectedChecks {
+		if math.Abs(bad.Checks[name]-expected) > 1e-9 {
+			t.Errorf("check %s: expected %f, got %f", name, expected, bad.Checks[name])
+		}
+	}
+	if bad.Grade != "F" {
+		t.Errorf("expected grade F, got %+v", bad)
+	}
+
+	expectedScore := (ok.Score*2 + bad.Score*3) / 5
+	if math.Abs(report.Score-expectedScore) > 1e-9 {
+		t.Errorf("expected archive score %f, got %f", expectedScore, report.Score)
+	}
+}
+
+// ParseConformanceWeights のテスト
+func TestParseConformanceWeights(t *testing.T) {
+	weights, err := ParseConformanceWeights("count=5, naming=0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if weights[CheckCount] != 5 || weights[CheckNaming] != 0 || weights[CheckEncoding] != DefaultConformanceWeights[CheckEncoding] {
+		t.Errorf("unexpected weights: %v", weights)
+	}
+	for _, bad := range []string{"unknown=1", "count", "count=-1"} {
+		if _, err := ParseConformanceWeights(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
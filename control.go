@@ -0,0 +1,189 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// exitControl は -control の管理ファイルと実際のファイル数が一致しないフォルダがあった場合の終了コードです。
+const exitControl = 7
+
+// maxControlFileSize は読み込む管理ファイルのバイト数の上限です。
+const maxControlFileSize = 16 << 20
+
+// ControlEntry は納品物に同梱された管理ファイルの1行で、フォルダと期待するファイル数です。
+type ControlEntry struct {
+	// Folder は集計キーと同じ "/" 区切りのフォルダパスです。ルートフォルダは "(Root)" です。
+	Folder   string
+	Expected int
+}
+
+// ArchiveFileReader はアーカイブ内のファイルの内容を読み込める ArchiveReader です。
+type ArchiveFileReader interface {
+	ArchiveReader
+	// ReadArchiveFile は復号後のエントリ名が name (大文字と小文字は区別しない) のファイルの内容を返します。
+	// 見つからない場合は fs.ErrNotExist を含むエラーを返します。
+	ReadArchiveFile(archivePath, name string) ([]byte, error)
+}
+
+// ReadArchiveFile は ArchiveFileReader の実装です。ZIP以外の形式には対応しません。
+func (a AutoArchiveReader) ReadArchiveFile(archivePath, name string) ([]byte, error) {
+	if _, ok := registeredArchiveReader(archivePath); ok {
+		return nil, fmt.Errorf("reading files is not supported for %s", archivePath)
+	}
+	if kind := a.kind(archivePath); kind != "zip" {
+		return nil, fmt.Errorf("reading files is supported only in zip archives: %s is %s", archivePath, kind)
+	}
+	return a.Zip.ReadArchiveFile(archivePath, name)
+}
+
+// ReadArchiveFile は ArchiveFileReader の実装です。URLのアーカイブは対象のエントリの範囲だけを取得します。
+func (z ZipArchiveReader) ReadArchiveFile(zipPath, name string) ([]byte, error) {
+	var files []*zip.File
+	if isRemotePath(zipPath) {
+		var err error
+		if files, err = z.openRemote(zipPath); err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := zip.OpenReader(zipPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer r.Close()
+		files = r.File
+	}
+
+	decoder := z.Decoder
+	if decoder == nil {
+		decoder = EncodingDecoder{Encoding: japanese.ShiftJIS}
+	}
+	name = strings.Trim(name, "/")
+	for _, f := range files {
+		e := zipFileEntry(f, decoder)
+		if e.IsDir || !strings.EqualFold(e.Name, name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxControlFileSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(data) > maxControlFileSize {
+			return nil, fmt.Errorf("%s exceeds %d bytes", name, maxControlFileSize)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+}
+
+// ParseControlFile は "フォルダ,ファイル数" のCSV形式の管理ファイルを読み込みます。
+// 区切り文字 (カンマ、タブ、セミコロン) は先頭行から判定し、ファイル数が数値でない先頭行は見出しとして無視します。
+// BOM、空行、"#" で始まる行も無視します。UTF-8でない内容は Shift_JIS として読み込みます。
+// 空のフォルダパスと "(Root)" はルートフォルダを表します。
+func ParseControlFile(data []byte) ([]ControlEntry, error) {
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	if !utf8.Valid(data) {
+		var err error
+		if data, err = japanese.ShiftJIS.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("failed to parse control file: %w", err)
+		}
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.Comma = baselineDelimiter(data)
+
+	var entries []ControlEntry
+	seen := make(map[string]bool)
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse control file: %w", err)
+		}
+		if len(record) < 2 {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("failed to parse control file: line %d: folder and file count are required", line)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			if first {
+				continue
+			}
+			return nil, fmt.Errorf("failed to parse control file: invalid file count %q", record[1])
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("failed to parse control file: negative file count %d", count)
+		}
+		folder := baselineKey(record[0])
+		if seen[folder] {
+			return nil, fmt.Errorf("failed to parse control file: duplicate folder %s", folder)
+		}
+		seen[folder] = true
+		entries = append(entries, ControlEntry{Folder: folder, Expected: count})
+	}
+}
+
+// ControlFindings は管理ファイルと実際のファイル数を照合した検出事項を返します。(純粋関数)
+// ファイル数が一致しないフォルダは ERROR、管理ファイルに記載の無いファイルを含むフォルダは WARN とします。
+// folders にはしきい値で絞り込む前の、管理ファイル自体を除いたすべてのフォルダが含まれている必要があります。
+func ControlFindings(control []ControlEntry, folders []FolderCount) []Finding {
+	counts := make(map[string]int, len(folders))
+	for _, f := range folders {
+		counts[f.Path] = f.Count
+	}
+	listed := make(map[string]bool, len(control))
+	var findings []Finding
+	for _, c := range control {
+		listed[c.Folder] = true
+		if n := counts[c.Folder]; n != c.Expected {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Rule:     RuleControl,
+				Folder:   c.Folder,
+				Message:  fmt.Sprintf("%d files differ from %d in the control file", n, c.Expected),
+			})
+		}
+	}
+	for _, f := range folders {
+		if !listed[f.Path] && f.Count > 0 {
+			findings = append(findings, Finding{
+				Severity: SeverityWarn,
+				Rule:     RuleControl,
+				Folder:   f.Path,
+				Message:  fmt.Sprintf("%d files in a folder not listed in the control file", f.Count),
+			})
+		}
+	}
+	return findings
+}
+
+// withoutEntry は名前が name (大文字と小文字は区別しない) のファイルを除いたエントリを返します。(純粋関数)
+func withoutEntry(entries []FileEntry, name string) []FileEntry {
+	name = strings.Trim(name, "/")
+	result := make([]FileEntry, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir && strings.EqualFold(e.Name, name) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// ParseControlFile のテスト
+func TestParseControlFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []ControlEntry
+		wantErr  string
+	}{
+		{
+			name:  "見出しとBOMとコメント",
+			input: "\xEF\xBB\xBFFolder,Count\n# 納品物の管理ファイル\nscans\\2024,120\n(Root),1\n\n/docs/,0\n",
+			expected: []ControlEntry{
+				{Folder: "scans/2024", Expected: 120},
+				{Folder: "(Root)", Expected: 1},
+				{Folder: "docs", Expected: 0},
+			},
+		},
+		{name: "見出しなしのタブ区切り", input: "a\t3\nb\t4\n", expected: []ControlEntry{{Folder: "a", Expected: 3}, {Folder: "b", Expected: 4}}},
+		{name: "Shift_JIS", input: "\x8e\x91\x97\xbf,2\n", expected: []ControlEntry{{Folder: "資料", Expected: 2}}},
+		{name: "空のパスはルート", input: ",5\n", expected: []ControlEntry{{Folder: "(Root)", Expected: 5}}},
+		{name: "件数の無い行", input: "a,1\nb\n", wantErr: "line 2"},
+		{name: "数値でない件数", input: "a,1\nb,x\n", wantErr: "invalid file count"},
+		{name: "負の件数", input: "a,-1\n", wantErr: "negative file count"},
+		{name: "重複するフォルダ", input: "a,1\na/,2\n", wantErr: "duplicate folder a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseControlFile([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ControlFindings のテスト
+func TestControlFindings(t *testing.T) {
+	control := []ControlEntry{
+		{Folder: "a", Expected: 2},
+		{Folder: "b", Expected: 3},
+		{Folder: "c", Expected: 1},
+		{Folder: "empty", Expected: 0},
+	}
+	folders := []FolderCount{
+		{Path: "a", Count: 2},
+		{Path: "b", Count: 4},
+		{Path: "extra", Count: 5},
+	}
+	expected := []Finding{
+		{Severity: SeverityError, Rule: RuleControl, Folder: "b", Message: "4 files differ from 3 in the control file"},
+		{Severity: SeverityError, Rule: RuleControl, Folder: "c", Message: "0 files differ from 1 in the control file"},
+		{Severity: SeverityWarn, Rule: RuleControl, Folder: "extra", Message: "5 files in a folder not listed in the control file"},
+	}
+	if got := ControlFindings(control, folders); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+// ZipArchiveReader.ReadArchiveFile のテスト
+func TestReadArchiveFile(t *testing.T) {
+	zipPath := obuziptest.New().
+		Dir("control.csv").
+		File("sub/control.csv", []byte("sub")).
+		File("Control.CSV", []byte("a,1\n")).
+		WriteFile(t)
+
+	data, err := (AutoArchiveReader{}).ReadArchiveFile(zipPath, "control.csv")
+	if err != nil || string(data) != "a,1\n" {
+		t.Errorf("ReadArchiveFile() = %q, %v", data, err)
+	}
+	if data, err := (ZipArchiveReader{}).ReadArchiveFile(zipPath, "sub/control.csv"); err != nil || string(data) != "sub" {
+		t.Errorf("ReadArchiveFile(sub/control.csv) = %q, %v", data, err)
+	}
+	if _, err := (ZipArchiveReader{}).ReadArchiveFile(zipPath, "missing.csv"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+// count の -control のテスト
+func TestRunCLIControl(t *testing.T) {
+	build := func(control string) string {
+		return obuziptest.New().
+			Files("20240401_ABC/a.pdf", "20240401_ABC/scans/b.pdf", "20240401_ABC/scans/c.pdf", "root.txt").
+			File("control.csv", []byte(control)).
+			WriteFile(t)
+	}
+	matching := build("Folder,Count\n20240401_ABC,1\n20240401_ABC\\scans,2\n(Root),1\n")
+	mismatched := build("20240401_ABC,1\n20240401_ABC/scans,3\n")
+	broken := build("20240401_ABC,many\n20240401_ABC/scans,x\n")
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{name: "一致すれば成功", args: []string{"-zip", matching, "-control", "control.csv"}, expectedOut: "Folder Path"},
+		{name: "不一致は終了コード7", args: []string{"-zip", mismatched, "-control", "control.csv"}, expectedCode: exitControl, expectedOut: "ERROR control 20240401_ABC\\scans: 2 files differ from 3"},
+		{name: "記載の無いフォルダは警告", args: []string{"-zip", mismatched, "-control", "control.csv"}, expectedCode: exitControl, expectedOut: "control (Root): 1 files in a folder not listed"},
+		{name: "管理ファイルが無い", args: []string{"-zip", matching, "-control", "manifest/control.csv"}, expectedCode: exitControl, expectedOut: "control file is missing: manifest/control.csv"},
+		{name: "解析できない管理ファイル", args: []string{"-zip", broken, "-control", "control.csv"}, expectedCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"count", "-threshold", "100"}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+		})
+	}
+
+	t.Run("標準入力", func(t *testing.T) {
+		data, err := os.ReadFile(mismatched)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app := &App{Reader: AutoArchiveReader{}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Stdin: bytes.NewReader(data)}
+		stdout := new(bytes.Buffer)
+		err = app.Run(AppConfig{ZipPath: StdinPath, Threshold: 100, ControlFile: "control.csv"}, stdout)
+		var violation *PolicyViolation
+		if !errors.As(err, &violation) || violation.Code != exitControl {
+			t.Fatalf("expected control violation, got %v", err)
+		}
+	})
+}
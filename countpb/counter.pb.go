@@ -0,0 +1,498 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: obuzipcount/v1/counter.proto
+
+package countpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CountRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// zip_path はサーバーから見たZIPファイルのパスです。
+	ZipPath string `protobuf:"bytes,1,opt,name=zip_path,json=zipPath,proto3" json:"zip_path,omitempty"`
+	// threshold を省略した場合はサーバーの既定値を使います。
+	Threshold *int32 `protobuf:"varint,2,opt,name=threshold,proto3,oneof" json:"threshold,omitempty"`
+	// segments は集計に使うフォルダ階層の範囲です (例: "2:4")。
+	Segments string `protobuf:"bytes,3,opt,name=segments,proto3" json:"segments,omitempty"`
+	// normalize はUnicode正規化の方式です。省略時は "nfc" です。
+	Normalize     string `protobuf:"bytes,4,opt,name=normalize,proto3" json:"normalize,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountRequest) ProtoMessage() {}
+
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_obuzipcount_v1_counter_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CountRequest) GetZipPath() string {
+	if x != nil {
+		return x.ZipPath
+	}
+	return ""
+}
+
+func (x *CountRequest) GetThreshold() int32 {
+	if x != nil && x.Threshold != nil {
+		return *x.Threshold
+	}
+	return 0
+}
+
+func (x *CountRequest) GetSegments() string {
+	if x != nil {
+		return x.Segments
+	}
+	return ""
+}
+
+func (x *CountRequest) GetNormalize() string {
+	if x != nil {
+		return x.Normalize
+	}
+	return ""
+}
+
+type FolderCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FolderCount) Reset() {
+	*x = FolderCount{}
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FolderCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FolderCount) ProtoMessage() {}
+
+func (x *FolderCount) ProtoReflect() protoreflect.Message {
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FolderCount.ProtoReflect.Descriptor instead.
+func (*FolderCount) Descriptor() ([]byte, []int) {
+	return file_obuzipcount_v1_counter_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FolderCount) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FolderCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Archive       string                 `protobuf:"bytes,1,opt,name=archive,proto3" json:"archive,omitempty"`
+	TotalFiles    int64                  `protobuf:"varint,2,opt,name=total_files,json=totalFiles,proto3" json:"total_files,omitempty"`
+	Folders       []*FolderCount         `protobuf:"bytes,3,rep,name=folders,proto3" json:"folders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_obuzipcount_v1_counter_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CountResponse) GetArchive() string {
+	if x != nil {
+		return x.Archive
+	}
+	return ""
+}
+
+func (x *CountResponse) GetTotalFiles() int64 {
+	if x != nil {
+		return x.TotalFiles
+	}
+	return 0
+}
+
+func (x *CountResponse) GetFolders() []*FolderCount {
+	if x != nil {
+		return x.Folders
+	}
+	return nil
+}
+
+type DiffRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	OldZipPath string                 `protobuf:"bytes,1,opt,name=old_zip_path,json=oldZipPath,proto3" json:"old_zip_path,omitempty"`
+	NewZipPath string                 `protobuf:"bytes,2,opt,name=new_zip_path,json=newZipPath,proto3" json:"new_zip_path,omitempty"`
+	// threshold を省略した場合は0 (すべてのフォルダ) です。
+	Threshold        int32  `protobuf:"varint,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	IncludeUnchanged bool   `protobuf:"varint,4,opt,name=include_unchanged,json=includeUnchanged,proto3" json:"include_unchanged,omitempty"`
+	Normalize        string `protobuf:"bytes,5,opt,name=normalize,proto3" json:"normalize,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DiffRequest) Reset() {
+	*x = DiffRequest{}
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffRequest) ProtoMessage() {}
+
+func (x *DiffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffRequest.ProtoReflect.Descriptor instead.
+func (*DiffRequest) Descriptor() ([]byte, []int) {
+	return file_obuzipcount_v1_counter_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DiffRequest) GetOldZipPath() string {
+	if x != nil {
+		return x.OldZipPath
+	}
+	return ""
+}
+
+func (x *DiffRequest) GetNewZipPath() string {
+	if x != nil {
+		return x.NewZipPath
+	}
+	return ""
+}
+
+func (x *DiffRequest) GetThreshold() int32 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *DiffRequest) GetIncludeUnchanged() bool {
+	if x != nil {
+		return x.IncludeUnchanged
+	}
+	return false
+}
+
+func (x *DiffRequest) GetNormalize() string {
+	if x != nil {
+		return x.Normalize
+	}
+	return ""
+}
+
+type FolderDiff struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Old           int64                  `protobuf:"varint,2,opt,name=old,proto3" json:"old,omitempty"`
+	New           int64                  `protobuf:"varint,3,opt,name=new,proto3" json:"new,omitempty"`
+	Delta         int64                  `protobuf:"varint,4,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FolderDiff) Reset() {
+	*x = FolderDiff{}
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FolderDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FolderDiff) ProtoMessage() {}
+
+func (x *FolderDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FolderDiff.ProtoReflect.Descriptor instead.
+func (*FolderDiff) Descriptor() ([]byte, []int) {
+	return file_obuzipcount_v1_counter_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FolderDiff) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FolderDiff) GetOld() int64 {
+	if x != nil {
+		return x.Old
+	}
+	return 0
+}
+
+func (x *FolderDiff) GetNew() int64 {
+	if x != nil {
+		return x.New
+	}
+	return 0
+}
+
+func (x *FolderDiff) GetDelta() int64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+type DiffResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Folders       []*FolderDiff          `protobuf:"bytes,1,rep,name=folders,proto3" json:"folders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffResponse) Reset() {
+	*x = DiffResponse{}
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffResponse) ProtoMessage() {}
+
+func (x *DiffResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_obuzipcount_v1_counter_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffResponse.ProtoReflect.Descriptor instead.
+func (*DiffResponse) Descriptor() ([]byte, []int) {
+	return file_obuzipcount_v1_counter_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DiffResponse) GetFolders() []*FolderDiff {
+	if x != nil {
+		return x.Folders
+	}
+	return nil
+}
+
+var File_obuzipcount_v1_counter_proto protoreflect.FileDescriptor
+
+const file_obuzipcount_v1_counter_proto_rawDesc = "" +
+	"\n" +
+	"\x1cobuzipcount/v1/counter.proto\x12\x0eobuzipcount.v1\"\x94\x01\n" +
+	"\fCountRequest\x12\x19\n" +
+	"\bzip_path\x18\x01 \x01(\tR\azipPath\x12!\n" +
+	"\tthreshold\x18\x02 \x01(\x05H\x00R\tthreshold\x88\x01\x01\x12\x1a\n" +
+	"\bsegments\x18\x03 \x01(\tR\bsegments\x12\x1c\n" +
+	"\tnormalize\x18\x04 \x01(\tR\tnormalizeB\f\n" +
+	"\n" +
+	"_threshold\"7\n" +
+	"\vFolderCount\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"\x81\x01\n" +
+	"\rCountResponse\x12\x18\n" +
+	"\aarchive\x18\x01 \x01(\tR\aarchive\x12\x1f\n" +
+	"\vtotal_files\x18\x02 \x01(\x03R\n" +
+	"totalFiles\x125\n" +
+	"\afolders\x18\x03 \x03(\v2\x1b.obuzipcount.v1.FolderCountR\afolders\"\xba\x01\n" +
+	"\vDiffRequest\x12 \n" +
+	"\fold_zip_path\x18\x01 \x01(\tR\n" +
+	"oldZipPath\x12 \n" +
+	"\fnew_zip_path\x18\x02 \x01(\tR\n" +
+	"newZipPath\x12\x1c\n" +
+	"\tthreshold\x18\x03 \x01(\x05R\tthreshold\x12+\n" +
+	"\x11include_unchanged\x18\x04 \x01(\bR\x10includeUnchanged\x12\x1c\n" +
+	"\tnormalize\x18\x05 \x01(\tR\tnormalize\"Z\n" +
+	"\n" +
+	"FolderDiff\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x10\n" +
+	"\x03old\x18\x02 \x01(\x03R\x03old\x12\x10\n" +
+	"\x03new\x18\x03 \x01(\x03R\x03new\x12\x14\n" +
+	"\x05delta\x18\x04 \x01(\x03R\x05delta\"D\n" +
+	"\fDiffResponse\x124\n" +
+	"\afolders\x18\x01 \x03(\v2\x1a.obuzipcount.v1.FolderDiffR\afolders2\xf4\x01\n" +
+	"\aCounter\x12K\n" +
+	"\fCountArchive\x12\x1c.obuzipcount.v1.CountRequest\x1a\x1d.obuzipcount.v1.CountResponse\x12I\n" +
+	"\fDiffArchives\x12\x1b.obuzipcount.v1.DiffRequest\x1a\x1c.obuzipcount.v1.DiffResponse\x12Q\n" +
+	"\x12StreamFolderCounts\x12\x1c.obuzipcount.v1.CountRequest\x1a\x1b.obuzipcount.v1.FolderCount0\x01B\x18Z\x16go-ObuZipCount/countpbb\x06proto3"
+
+var (
+	file_obuzipcount_v1_counter_proto_rawDescOnce sync.Once
+	file_obuzipcount_v1_counter_proto_rawDescData []byte
+)
+
+func file_obuzipcount_v1_counter_proto_rawDescGZIP() []byte {
+	file_obuzipcount_v1_counter_proto_rawDescOnce.Do(func() {
+		file_obuzipcount_v1_counter_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_obuzipcount_v1_counter_proto_rawDesc), len(file_obuzipcount_v1_counter_proto_rawDesc)))
+	})
+	return file_obuzipcount_v1_counter_proto_rawDescData
+}
+
+var file_obuzipcount_v1_counter_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_obuzipcount_v1_counter_proto_goTypes = []any{
+	(*CountRequest)(nil),  // 0: obuzipcount.v1.CountRequest
+	(*FolderCount)(nil),   // 1: obuzipcount.v1.FolderCount
+	(*CountResponse)(nil), // 2: obuzipcount.v1.CountResponse
+	(*DiffRequest)(nil),   // 3: obuzipcount.v1.DiffRequest
+	(*FolderDiff)(nil),    // 4: obuzipcount.v1.FolderDiff
+	(*DiffResponse)(nil),  // 5: obuzipcount.v1.DiffResponse
+}
+var file_obuzipcount_v1_counter_proto_depIdxs = []int32{
+	1, // 0: obuzipcount.v1.CountResponse.folders:type_name -> obuzipcount.v1.FolderCount
+	4, // 1: obuzipcount.v1.DiffResponse.folders:type_name -> obuzipcount.v1.FolderDiff
+	0, // 2: obuzipcount.v1.Counter.CountArchive:input_type -> obuzipcount.v1.CountRequest
+	3, // 3: obuzipcount.v1.Counter.DiffArchives:input_type -> obuzipcount.v1.DiffRequest
+	0, // 4: obuzipcount.v1.Counter.StreamFolderCounts:input_type -> obuzipcount.v1.CountRequest
+	2, // 5: obuzipcount.v1.Counter.CountArchive:output_type -> obuzipcount.v1.CountResponse
+	5, // 6: obuzipcount.v1.Counter.DiffArchives:output_type -> obuzipcount.v1.DiffResponse
+	1, // 7: obuzipcount.v1.Counter.StreamFolderCounts:output_type -> obuzipcount.v1.FolderCount
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_obuzipcount_v1_counter_proto_init() }
+func file_obuzipcount_v1_counter_proto_init() {
+	if File_obuzipcount_v1_counter_proto != nil {
+		return
+	}
+	file_obuzipcount_v1_counter_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_obuzipcount_v1_counter_proto_rawDesc), len(file_obuzipcount_v1_counter_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_obuzipcount_v1_counter_proto_goTypes,
+		DependencyIndexes: file_obuzipcount_v1_counter_proto_depIdxs,
+		MessageInfos:      file_obuzipcount_v1_counter_proto_msgTypes,
+	}.Build()
+	File_obuzipcount_v1_counter_proto = out.File
+	file_obuzipcount_v1_counter_proto_goTypes = nil
+	file_obuzipcount_v1_counter_proto_depIdxs = nil
+}
@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: obuzipcount/v1/counter.proto
+
+package countpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Counter_CountArchive_FullMethodName       = "/obuzipcount.v1.Counter/CountArchive"
+	Counter_DiffArchives_FullMethodName       = "/obuzipcount.v1.Counter/DiffArchives"
+	Counter_StreamFolderCounts_FullMethodName = "/obuzipcount.v1.Counter/StreamFolderCounts"
+)
+
+// CounterClient is the client API for Counter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Counter はZIPファイル内のフォルダごとのファイル数を集計するサービスです。
+type CounterClient interface {
+	// CountArchive はアーカイブを集計し、しきい値以上のフォルダをまとめて返します。
+	CountArchive(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	// DiffArchives は2つのアーカイブのフォルダごとの件数を比較します。
+	DiffArchives(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error)
+	// StreamFolderCounts はしきい値以上のフォルダを1件ずつ返します。大きなアーカイブ向けです。
+	StreamFolderCounts(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FolderCount], error)
+}
+
+type counterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCounterClient(cc grpc.ClientConnInterface) CounterClient {
+	return &counterClient{cc}
+}
+
+func (c *counterClient) CountArchive(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, Counter_CountArchive_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *counterClient) DiffArchives(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiffResponse)
+	err := c.cc.Invoke(ctx, Counter_DiffArchives_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *counterClient) StreamFolderCounts(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FolderCount], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Counter_ServiceDesc.Streams[0], Counter_StreamFolderCounts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CountRequest, FolderCount]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Counter_StreamFolderCountsClient = grpc.ServerStreamingClient[FolderCount]
+
+// CounterServer is the server API for Counter service.
+// All implementations must embed UnimplementedCounterServer
+// for forward compatibility.
+//
+// Counter はZIPファイル内のフォルダごとのファイル数を集計するサービスです。
+type CounterServer interface {
+	// CountArchive はアーカイブを集計し、しきい値以上のフォルダをまとめて返します。
+	CountArchive(context.Context, *CountRequest) (*CountResponse, error)
+	// DiffArchives は2つのアーカイブのフォルダごとの件数を比較します。
+	DiffArchives(context.Context, *DiffRequest) (*DiffResponse, error)
+	// StreamFolderCounts はしきい値以上のフォルダを1件ずつ返します。大きなアーカイブ向けです。
+	StreamFolderCounts(*CountRequest, grpc.ServerStreamingServer[FolderCount]) error
+	mustEmbedUnimplementedCounterServer()
+}
+
+// UnimplementedCounterServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCounterServer struct{}
+
+func (UnimplementedCounterServer) CountArchive(context.Context, *CountRequest) (*CountResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CountArchive not implemented")
+}
+func (UnimplementedCounterServer) DiffArchives(context.Context, *DiffRequest) (*DiffResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DiffArchives not implemented")
+}
+func (UnimplementedCounterServer) StreamFolderCounts(*CountRequest, grpc.ServerStreamingServer[FolderCount]) error {
+	return status.Error(codes.Unimplemented, "method StreamFolderCounts not implemented")
+}
+func (UnimplementedCounterServer) mustEmbedUnimplementedCounterServer() {}
+func (UnimplementedCounterServer) testEmbeddedByValue()                 {}
+
+// UnsafeCounterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CounterServer will
+// result in compilation errors.
+type UnsafeCounterServer interface {
+	mustEmbedUnimplementedCounterServer()
+}
+
+func RegisterCounterServer(s grpc.ServiceRegistrar, srv CounterServer) {
+	// If the following call panics, it indicates UnimplementedCounterServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Counter_ServiceDesc, srv)
+}
+
+func _Counter_CountArchive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CounterServer).CountArchive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Counter_CountArchive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CounterServer).CountArchive(ctx, req.(*CountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Counter_DiffArchives_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CounterServer).DiffArchives(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Counter_DiffArchives_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CounterServer).DiffArchives(ctx, req.(*DiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Counter_StreamFolderCounts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CountRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CounterServer).StreamFolderCounts(m, &grpc.GenericServerStream[CountRequest, FolderCount]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Counter_StreamFolderCountsServer = grpc.ServerStreamingServer[FolderCount]
+
+// Counter_ServiceDesc is the grpc.ServiceDesc for Counter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Counter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "obuzipcount.v1.Counter",
+	HandlerType: (*CounterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CountArchive",
+			Handler:    _Counter_CountArchive_Handler,
+		},
+		{
+			MethodName: "DiffArchives",
+			Handler:    _Counter_DiffArchives_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamFolderCounts",
+			Handler:       _Counter_StreamFolderCounts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "obuzipcount/v1/counter.proto",
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// CSVOptions はCSV出力の形式を指定します。ゼロ値は従来どおりBOM付きUTF-8・バックスラッシュ区切りです。
+type CSVOptions struct {
+	// Encoding は出力の文字コード (utf8-bom, utf8, shift_jis, cp932) です。空の場合は utf8-bom です。
+	Encoding string
+	// Delimiter は列の区切り (comma, tab, semicolon) です。空の場合は comma です。
+	Delimiter string
+	// PathSeparator はフォルダパスの区切り文字です。空の場合はバックスラッシュのままにします。
+	PathSeparator string
+	// PathPrefix はパスの長さを検証する際にフォルダパスの前に付ける文字列です (例: SharePointのサイトとライブラリ)。
+	PathPrefix string
+	// MaxPathLength が正の場合、パスの文字数と上限超過の有無を列として追加します。
+	MaxPathLength int
+	// Columns は出力する列の名前です。指定した場合は指定した列だけを指定した順に出力し、パスの長さの列は追加しません。
+	Columns []string
+	// RunStamp が true の場合、各行の先頭に実行日時とアーカイブ名の列を追加します。-csv-append で使います。
+	RunStamp bool
+}
+
+// csvStampColumns は RunStamp の指定時に各行の先頭に追加する列の見出しです。
+var csvStampColumns = []string{"Run Timestamp", "Archive"}
+
+// csvRecordWriter はCSVの1行を書き出します。*csv.Writer が実装します。
+type csvRecordWriter interface {
+	Write(record []string) error
+}
+
+// stampedCSVWriter は各行の先頭に実行日時とアーカイブ名の列を追加します。最初の行は見出しとして扱います。
+type stampedCSVWriter struct {
+	csvRecordWriter
+	stamp       []string
+	wroteHeader bool
+}
+
+func (s *stampedCSVWriter) Write(record []string) error {
+	prefix := s.stamp
+	if !s.wroteHeader {
+		prefix, s.wroteHeader = csvStampColumns, true
+	}
+	return s.csvRecordWriter.Write(append(slices.Clone(prefix), record...))
+}
+
+// csvStamp は集計結果の実行日時とアーカイブ名の列の値を返します。(純粋関数)
+func csvStamp(report *Report) []string {
+	return []string{report.RunAt.Format(time.RFC3339), filepath.Base(report.Archive)}
+}
+
+// csvPresets は -csv-preset で指定できる出力先ごとの既定値です。
+var csvPresets = map[string]CSVOptions{
+	"default": {},
+	// SharePointはURLの区切りが "/" で、デコード後のパスは400文字までに制限されています。
+	"sharepoint": {PathSeparator: "/", MaxPathLength: 400},
+}
+
+// CSVPreset は名前に対応するCSV出力の既定値を返します。
+func CSVPreset(name string) (CSVOptions, error) {
+	if name == "" {
+		return CSVOptions{}, nil
+	}
+	opts, ok := csvPresets[name]
+	if !ok {
+		return CSVOptions{}, fmt.Errorf("unknown csv preset: %s", name)
+	}
+	return opts, nil
+}
+
+// csvDelimiters は -csv-delimiter で指定できる区切り文字です。
+var csvDelimiters = map[string]rune{
+	"":          ',',
+	"comma":     ',',
+	"tab":       '\t',
+	"semicolon": ';',
+}
+
+// validateCSVEncoding はCSVの文字コード名を検証します。
+func validateCSVEncoding(name string) error {
+	_, _, err := csvEncoding(name)
+	return err
+}
+
+// validateCSVDelimiter はCSVの区切り文字の名前を検証します。
+func validateCSVDelimiter(name string) error {
+	if _, ok := csvDelimiters[name]; !ok {
+		return fmt.Errorf("unknown csv delimiter: %s", name)
+	}
+	return nil
+}
+
+// validate はCSV出力の文字コードと区切り文字を検証します。
+func (o CSVOptions) validate() error {
+	if err := validateCSVEncoding(o.Encoding); err != nil {
+		return err
+	}
+	return validateCSVDelimiter(o.Delimiter)
+}
+
+// csvEncoding は文字コード名に対応するエンコーディングとBOMの要否を返します。
+// UTF-8 の場合、エンコーディングは nil です。
+func csvEncoding(name string) (encoding.Encoding, bool, error) {
+	switch name {
+	case "", "utf8-bom":
+		return nil, true, nil
+	case "utf8":
+		return nil, false, nil
+	case "shift_jis", "cp932":
+		// x/text の ShiftJIS はWindowsの拡張文字 (CP932) も扱える
+		return japanese.ShiftJIS, false, nil
+	default:
+		return nil, false, fmt.Errorf("unknown csv encoding: %s", name)
+	}
+}
+
+// writeCSVRecords は opts の文字コードと区切り文字で、write が書き出す行をCSVとしてWriterに出力します。
+// BOM付きUTF-8の場合は先頭にBOMを出力し、Shift_JIS で表せない文字は置換文字 (0x1A) に置き換えます。
+// 集計以外のコマンドのCSVもこの関数で出力し、-csv-encoding と -csv-delimiter の扱いをそろえます。
+func writeCSVRecords(w io.Writer, opts CSVOptions, write func(records csvRecordWriter) error) (err error) {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	enc, bom, _ := csvEncoding(opts.Encoding)
+	if bom {
+		if _, err := w.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
+			return err
+		}
+	}
+	if enc != nil {
+		tw := transform.NewWriter(w, encoding.ReplaceUnsupported(enc.NewEncoder()))
+		defer func() {
+			if cerr := tw.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = tw
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = csvDelimiters[opts.Delimiter]
+	if err := write(writer); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeCSVRecordsFile は writeCSVRecords の出力を writeFileAtomic で outPath に書き出します。
+func writeCSVRecordsFile(outPath string, opts CSVOptions, noClobber bool, write func(records csvRecordWriter) error) error {
+	return writeFileAtomic(outPath, noClobber, func(file io.Writer) error {
+		if err := writeCSVRecords(file, opts, write); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+		return nil
+	})
+}
+
+// WriteCSVWithOptions は結果を指定された形式のCSVでWriterに出力します。
+// Shift_JIS で表せない文字は置換文字 (0x1A) に置き換えます。
+func WriteCSVWithOptions(w io.Writer, report *Report, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		return writeReportCSV(writer, report, opts)
+	})
+}
+
+// writeReportCSV は集計結果の見出し行とフォルダごとの行を書き出します。
+func writeReportCSV(writer csvRecordWriter, report *Report, opts CSVOptions) error {
+	records := writer
+	if opts.RunStamp {
+		records = &stampedCSVWriter{csvRecordWriter: writer, stamp: csvStamp(report)}
+	}
+
+	if len(opts.Columns) > 0 {
+		return writeCSVColumns(records, report, opts)
+	}
+	header := []string{"Folder Path", "File Count"}
+	if report.Partial != nil {
+		header = append(header, "Estimated Count")
+		if report.Partial.SamplePercent > 0 {
+			header = append(header, "Estimate Margin")
+		}
+	}
+	if report.Cumulative {
+		header = append(header, "Percent of Parent", "Percent of Total")
+	}
+	if report.Stats {
+		header = append(header, "Total Size", "Average Size", "Largest File", "Largest Size")
+	}
+	if opts.MaxPathLength > 0 {
+		header = append(header, "Path Length", "Exceeds Limit")
+	}
+	if err := records.Write(header); err != nil {
+		return err
+	}
+	for _, r := range report.Folders {
+		p := r.Path
+		if opts.PathSeparator != "" {
+			p = strings.ReplaceAll(p, "\\", opts.PathSeparator)
+		}
+		record := []string{p, strconv.Itoa(r.Count)}
+		if report.Partial != nil {
+			record = append(record, strconv.Itoa(r.Estimate))
+			if report.Partial.SamplePercent > 0 {
+				record = append(record, strconv.Itoa(r.EstimateMargin))
+			}
+		}
+		if report.Cumulative {
+			share := r.shareOrZero()
+			record = append(record, strconv.FormatFloat(share.OfParent, 'f', 1, 64), strconv.FormatFloat(share.OfTotal, 'f', 1, 64))
+		}
+		if report.Stats {
+			st := r.statsOrZero()
+			record = append(record,
+				strconv.FormatUint(st.TotalSize, 10),
+				strconv.FormatUint(st.AverageSize, 10),
+				st.LargestFile,
+				strconv.FormatUint(st.LargestSize, 10),
+			)
+		}
+		if opts.MaxPathLength > 0 {
+			length := prefixedPathLength(opts.PathPrefix, p, r.isRoot())
+			exceeds := ""
+			if length > opts.MaxPathLength {
+				exceeds = "yes"
+			}
+			record = append(record, strconv.Itoa(length), exceeds)
+		}
+		if err := records.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixedPathLength は接頭辞を付けたフォルダパスの文字数を返します。(純粋関数)
+// ルートフォルダの場合は接頭辞だけの長さです。
+func prefixedPathLength(prefix, p string, isRoot bool) int {
+	prefix = strings.TrimRight(prefix, "/")
+	switch {
+	case isRoot:
+		return len([]rune(prefix))
+	case prefix == "":
+		return len([]rune(p))
+	default:
+		return len([]rune(prefix)) + 1 + len([]rune(p))
+	}
+}
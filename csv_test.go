@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// WriteCSVWithOptions のテスト
+func TestWriteCSVWithOptions(t *testing.T) {
+	report := &Report{Folders: []FolderCount{
+		{Path: `a\b`, Count: 2},
+		{Path: "(Root)", Count: 1},
+		{Path: strings.Repeat("x", 20), Count: 1},
+	}}
+
+	t.Run("既定値はBOM付き", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteCSVWithOptions(out, report, CSVOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "\xEF\xBB\xBFFolder Path,File Count\na\\b,2\n(Root),1\n" + strings.Repeat("x", 20) + ",1\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("SharePointプリセット", func(t *testing.T) {
+		opts, err := CSVPreset("sharepoint")
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Encoding = "utf8"
+		opts.PathPrefix = "sites/x/"
+		opts.MaxPathLength = 25
+		out := new(bytes.Buffer)
+		if err := WriteCSVWithOptions(out, report, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "Folder Path,File Count,Path Length,Exceeds Limit\n" +
+			"a/b,2,11,\n" +
+			"(Root),1,7,\n" +
+			strings.Repeat("x", 20) + ",1,28,yes\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("サイズ統計", func(t *testing.T) {
+		statsReport := &Report{Stats: true, Folders: []FolderCount{
+			{Path: "dir1", Count: 2, Stats: &FolderStats{TotalSize: 30, AverageSize: 15, LargestFile: "a.txt", LargestSize: 20}},
+		}}
+		out := new(bytes.Buffer)
+		if err := WriteCSVWithOptions(out, statsReport, CSVOptions{Encoding: "utf8"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "Folder Path,File Count,Total Size,Average Size,Largest File,Largest Size\ndir1,2,30,15,a.txt,20\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("Shift_JISとタブ区切り", func(t *testing.T) {
+		sjisReport := &Report{Folders: []FolderCount{{Path: `書類\2023`, Count: 2}, {Path: "😀", Count: 1}}}
+		out := new(bytes.Buffer)
+		if err := WriteCSVWithOptions(out, sjisReport, CSVOptions{Encoding: "shift_jis", Delimiter: "tab"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "Folder Path\tFile Count\n\x8f\x91\x97\xde\\2023\t2\n\x1a\t1\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("実行日時とアーカイブ名の列", func(t *testing.T) {
+		stamped := &Report{
+			Archive: "/data/inbox/20240401.zip",
+			RunAt:   time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC),
+			Folders: []FolderCount{{Path: "dir1", Count: 2}},
+		}
+		for _, columns := range [][]string{nil, {"path", "count"}} {
+			out := new(bytes.Buffer)
+			if err := WriteCSVWithOptions(out, stamped, CSVOptions{Encoding: "utf8", RunStamp: true, Columns: columns}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := "Run Timestamp,Archive,Folder Path,File Count\n2024-04-01T09:30:00Z,20240401.zip,dir1,2\n"
+			if out.String() != expected {
+				t.Errorf("columns %v: expected %q, got %q", columns, expected, out.String())
+			}
+		}
+	})
+
+	t.Run("不正な指定", func(t *testing.T) {
+		if _, err := CSVPreset("excel95"); err == nil {
+			t.Error("expected error for unknown preset")
+		}
+		if err := WriteCSVWithOptions(new(bytes.Buffer), report, CSVOptions{Encoding: "utf16"}); err == nil {
+			t.Error("expected error for unknown encoding")
+		}
+		if err := WriteCSVWithOptions(new(bytes.Buffer), report, CSVOptions{Delimiter: "pipe"}); err == nil {
+			t.Error("expected error for unknown delimiter")
+		}
+	})
+}
+
+// writeCSVRecordsFile のテスト
+func TestWriteCSVRecordsFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "out.csv")
+	rows := func(records csvRecordWriter) error {
+		if err := records.Write([]string{"Folder Path", "Files"}); err != nil {
+			return err
+		}
+		return records.Write([]string{"書類", "2"})
+	}
+	if err := writeCSVRecordsFile(p, CSVOptions{Encoding: "cp932", Delimiter: "semicolon"}, false, rows); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(p); string(data) != "Folder Path;Files\n\x8f\x91\x97\xde;2\n" {
+		t.Errorf("unexpected content: %q", data)
+	}
+
+	// 途中で失敗した場合は元のファイルを残す
+	err := writeCSVRecordsFile(p, CSVOptions{}, false, func(records csvRecordWriter) error {
+		records.Write([]string{"partial"})
+		return errors.New("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected error, got %v", err)
+	}
+	if data, _ := os.ReadFile(p); !strings.HasPrefix(string(data), "Folder Path;Files") {
+		t.Errorf("file should not be changed, got %q", data)
+	}
+
+	if err := writeCSVRecordsFile(p, CSVOptions{Delimiter: "pipe"}, false, rows); err == nil {
+		t.Error("expected error for unknown delimiter")
+	}
+}
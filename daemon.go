@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// reportDirLayout は常駐モードで実行ごとに作るレポートのフォルダ名の書式です。
+const reportDirLayout = "20060102-150405"
+
+// BatchDaemon はスケジュールに従ってバッチ処理を繰り返し実行する常駐モードです。
+// 実行のたびに Inputs のフォルダを探し直し、Watcher.OutDir 配下の実行日時のフォルダにレポートを出力します。
+type BatchDaemon struct {
+	// Watcher はアーカイブごとの集計とレポートの出力に使います。OutDir は実行ごとのフォルダの親です。
+	Watcher  *Watcher
+	Schedule CronSchedule
+	// Inputs は処理するアーカイブまたはフォルダのパスです。
+	Inputs []string
+	Policy ErrorPolicy
+	// State が nil でない場合は、前回の実行から変わっていないアーカイブを処理しません。
+	State *BatchState
+	// KeepReports は残す新しい実行のフォルダの数です。0の場合は削除しません。
+	KeepReports int
+	// Lang は結果の表の見出しの言語です。
+	Lang Lang
+}
+
+// Run は ctx が終了するまで、スケジュールの時刻ごとにバッチ処理を実行します。
+// 1回の実行の失敗は記録して次の実行を待ちます。
+func (d *BatchDaemon) Run(ctx context.Context, stdout io.Writer) error {
+	logger := d.Watcher.App.Logger
+	for {
+		next := d.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("schedule never fires")
+		}
+		logger.Info("次の実行を待ちます", slog.Time("next", next))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		if err := d.RunOnce(next, stdout); err != nil {
+			logger.Error("スケジュールの実行に失敗しました", slog.Time("runAt", next), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// RunOnce は runAt の実行として1回のバッチ処理を行い、古い実行のフォルダを削除します。
+func (d *BatchDaemon) RunOnce(runAt time.Time, stdout io.Writer) error {
+	logger := d.Watcher.App.Logger
+	paths, err := expandArchivePaths(d.Inputs)
+	if err != nil {
+		return err
+	}
+	parent := d.Watcher.OutDir
+	dir := filepath.Join(parent, runAt.Format(reportDirLayout))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	w := *d.Watcher
+	w.OutDir = dir
+	w.ReportNames = uniqueReportNames(paths)
+	logger.Info("スケジュールの実行を開始します", slog.String("outDir", dir), slog.Int("archives", len(paths)))
+	statuses := RunBatch(paths, w.Process, d.Policy, d.State, logger)
+	if err := WriteBatchStatus(stdout, statuses, d.Lang); err != nil {
+		return err
+	}
+	if d.KeepReports == 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return fmt.Errorf("failed to list output directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	for _, name := range expiredReportDirs(names, d.KeepReports) {
+		if err := os.RemoveAll(filepath.Join(parent, name)); err != nil {
+			logger.Warn("古いレポートを削除できませんでした", slog.String("dir", name), slog.String("error", err.Error()))
+			continue
+		}
+		logger.Info("古いレポートを削除しました", slog.String("dir", name))
+	}
+	return nil
+}
+
+// expiredReportDirs は実行ごとのフォルダ名のうち、新しい keep 件を除いた削除するものを古い順に返します。(純粋関数)
+// 実行日時の書式に一致しないフォルダは対象にしません。
+func expiredReportDirs(names []string, keep int) []string {
+	var runs []string
+	for _, name := range names {
+		if _, err := time.Parse(reportDirLayout, name); err == nil {
+			runs = append(runs, name)
+		}
+	}
+	if len(runs) <= keep {
+		return nil
+	}
+	// 書式が固定長のため、名前の順が日時の順になる
+	sort.Strings(runs)
+	return runs[:len(runs)-keep]
+}
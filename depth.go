@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DepthDiscrepancy は復号前のバイト列と復号後の名前で、パス区切り文字の数が異なるエントリです。
+// Shift_JIS の2バイト目の 0x5C (「表」「ソ」等) をバックスラッシュとして数えると階層を誤ります。
+type DepthDiscrepancy struct {
+	Name    string
+	RawName string
+	// StoredDepth は復号前のバイト列を1バイトずつ見たときの区切り文字 ('/' と '\') の数です。
+	StoredDepth int
+	// DecodedDepth は復号後の名前の区切り文字の数です。集計にはこちらを使います。
+	DecodedDepth int
+}
+
+// FindDepthDiscrepancies は復号によって区切り文字の数が変わるエントリを返します。(純粋関数)
+func FindDepthDiscrepancies(entries []FileEntry) []DepthDiscrepancy {
+	var results []DepthDiscrepancy
+	for _, e := range entries {
+		if e.RawName == "" {
+			continue
+		}
+		stored := separatorCount(strings.TrimSuffix(e.RawName, "/"))
+		decoded := separatorCount(strings.TrimSuffix(e.Name, "/"))
+		if stored != decoded {
+			results = append(results, DepthDiscrepancy{Name: e.Name, RawName: e.RawName, StoredDepth: stored, DecodedDepth: decoded})
+		}
+	}
+	return results
+}
+
+// separatorCount は文字列をバイト列として見たときの '/' と '\' の数を返します。(純粋関数)
+func separatorCount(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' || s[i] == '\\' {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteDepthDiscrepancies は区切り文字の数が変わるエントリをプレーンテキストでWriterに出力します。
+func WriteDepthDiscrepancies(w io.Writer, results []DepthDiscrepancy) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\nSeparator depth changed by decoding: %d\n", len(results)); err != nil {
+		return err
+	}
+	for _, r := range results {
+		_, err := fmt.Fprintf(w, "  %s (stored %d, decoded %d): %s\n", EscapeRawName(r.RawName), r.StoredDepth, r.DecodedDepth, r.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
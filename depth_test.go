@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// 2バイト目が 0x5C の Shift_JIS の名前の集計と検出のテスト
+func TestFindDepthDiscrepancies(t *testing.T) {
+	zipPath := obuziptest.New().
+		RawFile([]byte("\x95\x5c/a.txt")).       // 表/a.txt
+		RawFile([]byte("\x95\x5c\\b.txt")).      // 表\b.txt (バックスラッシュ区切り)
+		RawFile([]byte("\x83\x5c\x83t\\c.txt")). // ソフ\c.txt
+		Files("plain/d.txt").
+		WriteFile(t)
+
+	entries, err := (ZipArchiveReader{}).ReadEntries(zipPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results, _ := AggregateFolders(entries, 0)
+	expected := []FolderCount{{Path: "表", Count: 2}, {Path: "plain", Count: 1}, {Path: "ソフ", Count: 1}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+
+	depth := FindDepthDiscrepancies(entries)
+	if len(depth) != 3 {
+		t.Fatalf("expected 3 discrepancies, got %+v", depth)
+	}
+	if d := depth[1]; d.Name != "表/b.txt" || d.StoredDepth != 2 || d.DecodedDepth != 1 {
+		t.Errorf("unexpected discrepancy: %+v", d)
+	}
+
+	out := new(bytes.Buffer)
+	if err := WriteDepthDiscrepancies(out, depth[:1]); err != nil {
+		t.Fatal(err)
+	}
+	expectedOut := "\nSeparator depth changed by decoding: 1\n  \\x95\\x5c/a.txt (stored 2, decoded 1): 表/a.txt\n"
+	if out.String() != expectedOut {
+		t.Errorf("expected %q, got %q", expectedOut, out.String())
+	}
+}
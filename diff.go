@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FolderDiff は2つの集計結果間のフォルダごとの件数差を保持します。
+type FolderDiff struct {
+	Path  string
+	Old   int
+	New   int
+	Delta int
+}
+
+// DiffFolderCounts は2つの集計結果を比較します。(純粋関数)
+// 新旧いずれかの件数がしきい値以上のフォルダが対象で、includeUnchanged が false の場合は差分のあるものだけを返します。
+// 結果は差分の絶対値の降順、同じ場合はパスの昇順でソートされます。
+func DiffFolderCounts(oldResults, newResults []FolderCount, threshold int, includeUnchanged bool) []FolderDiff {
+	merged := make(map[string]*FolderDiff)
+	for _, r := range oldResults {
+		merged[r.Path] = &FolderDiff{Path: r.Path, Old: r.Count}
+	}
+	for _, r := range newResults {
+		d, ok := merged[r.Path]
+		if !ok {
+			d = &FolderDiff{Path: r.Path}
+			merged[r.Path] = d
+		}
+		d.New = r.Count
+	}
+
+	var diffs []FolderDiff
+	for _, d := range merged {
+		d.Delta = d.New - d.Old
+		if d.Old < threshold && d.New < threshold {
+			continue
+		}
+		if d.Delta == 0 && !includeUnchanged {
+			continue
+		}
+		diffs = append(diffs, *d)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		ai, aj := abs(diffs[i].Delta), abs(diffs[j].Delta)
+		if ai == aj {
+			return diffs[i].Path < diffs[j].Path
+		}
+		return ai > aj
+	})
+	return diffs
+}
+
+// Change は比較による変化の種類 (added, removed, changed, unchanged) を返します。(純粋関数)
+// 件数が0のフォルダは集計結果に現れないため、一方だけが0のフォルダを追加または削除とみなします。
+func (d FolderDiff) Change() string {
+	switch {
+	case d.Old == 0 && d.New > 0:
+		return "added"
+	case d.New == 0 && d.Old > 0:
+		return "removed"
+	case d.Delta != 0:
+		return "changed"
+	}
+	return "unchanged"
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// WriteDiffText は比較結果をプレーンテキストでWriterに出力します。
+func WriteDiffText(w io.Writer, diffs []FolderDiff, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-60s | %10s | %10s | %10s | %s\n", lang.T("Folder Path"), lang.T("Old"), lang.T("New"), lang.T("Delta"), lang.T("Change"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	for _, d := range diffs {
+		_, err := fmt.Fprintf(w, "%-60s | %10d | %10d | %+10d | %s\n", d.Path, d.Old, d.New, d.Delta, d.Change())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDiffCSV は比較結果をCSV形式でWriterに出力します。
+func WriteDiffCSV(w io.Writer, diffs []FolderDiff, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Old Count", "New Count", "Delta", "Change"}); err != nil {
+			return err
+		}
+		for _, d := range diffs {
+			record := []string{d.Path, strconv.Itoa(d.Old), strconv.Itoa(d.New), strconv.Itoa(d.Delta), d.Change()}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectDiffWriter は出力形式名に対応する比較結果の書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectDiffWriter(format string, opts WriterOptions) (func(io.Writer, []FolderDiff) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, diffs []FolderDiff) error {
+			return WriteDiffText(w, diffs, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, diffs []FolderDiff) error {
+			return WriteDiffCSV(w, diffs, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// DiffFolderCounts のテスト
+func TestDiffFolderCounts(t *testing.T) {
+	oldResults := []FolderCount{
+		{Path: "same", Count: 5},
+		{Path: "grown", Count: 1},
+		{Path: "removed", Count: 3},
+		{Path: "small", Count: 1},
+	}
+	newResults := []FolderCount{
+		{Path: "same", Count: 5},
+		{Path: "grown", Count: 4},
+		{Path: "added", Count: 2},
+		{Path: "small", Count: 0},
+	}
+
+	t.Run("差分のあるフォルダのみ", func(t *testing.T) {
+		result := DiffFolderCounts(oldResults, newResults, 2, false)
+		expected := []FolderDiff{
+			{Path: "grown", Old: 1, New: 4, Delta: 3},
+			{Path: "removed", Old: 3, New: 0, Delta: -3},
+			{Path: "added", Old: 0, New: 2, Delta: 2},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("変化のないフォルダも含める", func(t *testing.T) {
+		result := DiffFolderCounts(oldResults, newResults, 5, true)
+		expected := []FolderDiff{
+			{Path: "same", Old: 5, New: 5, Delta: 0},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %v, got %v", expected, result)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DedupePolicy は同じ名前のエントリが中央ディレクトリに複数ある場合の扱いです。
+// 上書きされたファイルを追記するツールで作成したZIPに現れます。ゼロ値は DedupeLast と同じです。
+type DedupePolicy string
+
+const (
+	// DedupeLast は最後のエントリだけを集計します。展開ツールが上書きした結果と同じです。既定の扱いです。
+	DedupeLast DedupePolicy = "last"
+	// DedupeFirst は最初のエントリだけを集計します。
+	DedupeFirst DedupePolicy = "first"
+	// DedupeCountAll はすべてのエントリを別のファイルとして集計します。
+	DedupeCountAll DedupePolicy = "count-all"
+)
+
+// ParseDedupePolicy は -dedupe の指定を解析します。空の場合は DedupeLast です。
+func ParseDedupePolicy(s string) (DedupePolicy, error) {
+	switch policy := DedupePolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case "":
+		return DedupeLast, nil
+	case DedupeLast, DedupeFirst, DedupeCountAll:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown dedupe policy: %s (available: last, first, count-all)", s)
+	}
+}
+
+// DuplicateEntry は中央ディレクトリに複数回現れるエントリ名です。
+type DuplicateEntry struct {
+	Name string
+	// Folder は集計結果と同じ集計キーのフォルダパスです。
+	Folder string
+	// Count はその名前のエントリの数です。
+	Count int
+}
+
+// FindDuplicates は中央ディレクトリに複数回現れるファイルの名前を、名前の昇順で返します。(純粋関数)
+// 名前は復号後の名前で比較します。ディレクトリのエントリはファイル数に影響しないため対象外です。
+func FindDuplicates(entries []FileEntry, opts AggregateOptions) []DuplicateEntry {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		if !e.IsDir {
+			counts[e.Name]++
+		}
+	}
+	var dups []DuplicateEntry
+	for name, n := range counts {
+		if n > 1 {
+			dups = append(dups, DuplicateEntry{Name: name, Folder: FolderKey(name, opts), Count: n})
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].Name < dups[j].Name })
+	return dups
+}
+
+// DedupeEntries は同じ名前のファイルのエントリを policy に従って1件に絞り込みます。(純粋関数)
+// 残したエントリは元の順序を保ちます。DedupeCountAll の場合は entries をそのまま返します。
+func DedupeEntries(entries []FileEntry, policy DedupePolicy) []FileEntry {
+	if policy == DedupeCountAll {
+		return entries
+	}
+	// 残すエントリの位置
+	keep := make(map[string]int)
+	for i, e := range entries {
+		if e.IsDir {
+			continue
+		}
+		if _, seen := keep[e.Name]; !seen || policy != DedupeFirst {
+			keep[e.Name] = i
+		}
+	}
+	out := make([]FileEntry, 0, len(entries))
+	for i, e := range entries {
+		if e.IsDir || keep[e.Name] == i {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// DuplicateFindings は重複するエントリ名を1件ずつ WARN の検出事項として返します。(純粋関数)
+func DuplicateFindings(dups []DuplicateEntry, policy DedupePolicy) []Finding {
+	if policy == "" {
+		policy = DedupeLast
+	}
+	findings := make([]Finding, 0, len(dups))
+	for _, d := range dups {
+		counted := "counted once (" + string(policy) + ")"
+		if policy == DedupeCountAll {
+			counted = "all counted"
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityWarn,
+			Rule:     RuleDuplicate,
+			Folder:   d.Folder,
+			Message:  fmt.Sprintf("%d entries named %s, %s", d.Count, d.Name, counted),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// DedupeEntries のテスト
+func TestDedupeEntries(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "docs/", IsDir: true},
+		{Name: "docs/a.txt", Size: 1},
+		{Name: "docs/b.txt"},
+		{Name: "docs/a.txt", Size: 2},
+		{Name: "docs/", IsDir: true},
+	}
+	tests := []struct {
+		name     string
+		policy   DedupePolicy
+		expected []FileEntry
+	}{
+		{name: "最後のエントリを残す", policy: DedupeLast, expected: []FileEntry{entries[0], entries[2], entries[3], entries[4]}},
+		{name: "ゼロ値は最後のエントリを残す", policy: "", expected: []FileEntry{entries[0], entries[2], entries[3], entries[4]}},
+		{name: "最初のエントリを残す", policy: DedupeFirst, expected: []FileEntry{entries[0], entries[1], entries[2], entries[4]}},
+		{name: "すべて数える", policy: DedupeCountAll, expected: entries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DedupeEntries(entries, tt.policy); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	want := []DuplicateEntry{{Name: "docs/a.txt", Folder: "docs", Count: 2}}
+	if got := FindDuplicates(entries, AggregateOptions{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+// count の -dedupe のテスト
+func TestRunCountDedupe(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/a.txt", "dir1/b.txt")
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		code     int
+	}{
+		{name: "既定では1件として数える", expected: `"count": 2`},
+		{name: "すべて数える", args: []string{"-dedupe", "count-all"}, expected: `"count": 3`},
+		{name: "重複する名前を報告する", expected: "2 entries named dir1/a.txt, counted once (last)"},
+		{name: "不正な指定", args: []string{"-dedupe", "newest"}, code: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			args := append([]string{"count", "-zip", zipPath, "-threshold", "1", "-format", "json"}, tt.args...)
+			if code := runCLI(args, stdout, stderr); code != tt.code {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expected) {
+				t.Errorf("output does not contain %q, got: %s", tt.expected, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// namedEncodings は -encoding-fallbacks で指定できる文字コード名の一覧です。
+var namedEncodings = map[string]encoding.Encoding{
+	"utf8":        xunicode.UTF8,
+	"cp932":       japanese.ShiftJIS,
+	"shift_jis":   japanese.ShiftJIS,
+	"sjis":        japanese.ShiftJIS,
+	"euc-jp":      japanese.EUCJP,
+	"iso-2022-jp": japanese.ISO2022JP,
+	"cp437":       charmap.CodePage437,
+	"cp850":       charmap.CodePage850,
+	"cp1252":      charmap.Windows1252,
+	"latin1":      charmap.ISO8859_1,
+	"cp949":       korean.EUCKR,
+	"gbk":         simplifiedchinese.GBK,
+	"big5":        traditionalchinese.Big5,
+}
+
+// ParseEncodingList はカンマ区切りの文字コード名を順序どおりに解決します。
+func ParseEncodingList(s string) ([]encoding.Encoding, error) {
+	var encs []encoding.Encoding
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		enc, ok := namedEncodings[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown encoding: %s", name)
+		}
+		encs = append(encs, enc)
+	}
+	return encs, nil
+}
+
+// decodeName は文字コードを順に試し、最初に妥当で印字可能なUTF-8になった結果を返します。(純粋関数)
+// どの文字コードでも妥当な結果にならない場合は、最初に変換エラーなく復号できた結果を返します。
+// すべて失敗した場合は元の文字列を返します。
+func decodeName(s string, encs []encoding.Encoding) string {
+	chain := make(ChainDecoder, len(encs))
+	for i, enc := range encs {
+		chain[i] = EncodingDecoder{Encoding: enc}
+	}
+	name, _ := chain.Decode([]byte(s))
+	return name
+}
+
+// isPrintableUTF8 は文字列が妥当なUTF-8で、置換文字や制御文字を含まないか判定します。(純粋関数)
+// 全角スペースなどの空白文字は印字可能として扱います。
+func isPrintableUTF8(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == utf8.RuneError || !unicode.IsGraphic(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// decodeName のフォールバックのテスト
+func TestDecodeName(t *testing.T) {
+	chain := []encoding.Encoding{japanese.ShiftJIS, charmap.CodePage437}
+
+	tests := []struct {
+		name     string
+		input    string
+		encs     []encoding.Encoding
+		expected string
+	}{
+		{name: "Shift_JISで復号できる", input: "\x93\xfa\x96\x7b/a.txt", encs: chain, expected: "日本/a.txt"},
+		{name: "Shift_JISで不正ならCP437", input: "\x80\xa0/a.txt", encs: chain, expected: "Çá/a.txt"},
+		{name: "全角スペースは印字可能", input: "\x81\x40", encs: chain, expected: "　"},
+		{name: "妥当な結果がなければ最初の復号結果", input: "\x80", encs: []encoding.Encoding{japanese.ShiftJIS}, expected: "\u0080"},
+		{name: "文字コード指定なし", input: "\x80", encs: nil, expected: "\x80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeName(tt.input, tt.encs); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ParseEncodingList のテスト
+func TestParseEncodingList(t *testing.T) {
+	encs, err := ParseEncodingList("cp932, CP437,latin1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(encs) != 3 || encs[0] != japanese.ShiftJIS || encs[1] != charmap.CodePage437 || encs[2] != charmap.ISO8859_1 {
+		t.Errorf("unexpected encodings: %v", encs)
+	}
+	if _, err := ParseEncodingList("cp932,unknown"); err == nil {
+		t.Error("expected error for unknown encoding, got nil")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// entryCacheVersion はキャッシュの形式の版です。FileEntry の項目を変えた場合は増やします。
+const entryCacheVersion = 2
+
+// EntryCache は読み込んだエントリの一覧を、アーカイブのフィンガープリント (パス、サイズ、更新日時) をキーに保存します。
+// 出力形式やしきい値を変えて同じアーカイブを繰り返し集計する場合に、中央ディレクトリの読み込みを省けます。
+type EntryCache struct {
+	Dir string
+	// Salt はエントリ名の復号方法など、同じアーカイブでも読み込み結果を変える設定です。キーに含めます。
+	Salt string
+}
+
+// key はアーカイブのキャッシュファイルのパスを返します。URLとアーカイブを読めない場合は false を返します。
+func (c *EntryCache) key(archivePath string) (string, bool) {
+	if isRemotePath(archivePath) {
+		return "", false
+	}
+	fingerprint, err := archiveFingerprint(archivePath)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d\x00%s\x00%s", entryCacheVersion, fingerprint, c.Salt))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".gob.gz"), true
+}
+
+// Load はキャッシュからエントリの一覧を読み込みます。キャッシュが無い、または読めない場合は false を返します。
+func (c *EntryCache) Load(archivePath string) ([]FileEntry, bool) {
+	cachePath, ok := c.key(archivePath)
+	if !ok {
+		return nil, false
+	}
+	f, err := os.Open(cachePath)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false
+	}
+	var entries []FileEntry
+	if err := gob.NewDecoder(zr).Decode(&entries); err != nil {
+		return nil, false
+	}
+	return entries, true
+}
+
+// Store はエントリの一覧をキャッシュに保存します。
+// 同時に実行した別のプロセスが読みかけのファイルを読まないよう、一時ファイルに書いてから置き換えます。
+func (c *EntryCache) Store(archivePath string, entries []FileEntry) error {
+	cachePath, ok := c.key(archivePath)
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	f, err := os.CreateTemp(c.Dir, "entries-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(f.Name())
+	zw := gzip.NewWriter(f)
+	if err := gob.NewEncoder(zw).Encode(entries); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(f.Name(), cachePath); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
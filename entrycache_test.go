@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// EntryCache と AutoArchiveReader のキャッシュのテスト
+func TestEntryCache(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt").WriteFile(t)
+	cache := &EntryCache{Dir: t.TempDir(), Salt: "cp932"}
+	reader := AutoArchiveReader{Cache: cache}
+
+	entries, err := reader.ReadEntries(zipPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cached, ok := cache.Load(zipPath)
+	if !ok || !reflect.DeepEqual(cached, entries) {
+		t.Fatalf("expected cached entries %+v, got %+v (ok=%v)", entries, cached, ok)
+	}
+
+	// キャッシュがあれば、アーカイブを読まずにキャッシュの内容を返す
+	fake := []FileEntry{{Name: "cached/only.txt"}}
+	if err := cache.Store(zipPath, fake); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := reader.ReadEntriesUntil(zipPath, time.Time{}); err != nil || !reflect.DeepEqual(got, fake) {
+		t.Errorf("expected cached entries, got %+v (%v)", got, err)
+	}
+	if _, ok := (&EntryCache{Dir: cache.Dir, Salt: "utf8"}).Load(zipPath); ok {
+		t.Error("expected cache miss for different reader settings")
+	}
+
+	// 更新されたアーカイブは読み直す
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(zipPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reader.ReadEntries(zipPath); err != nil || len(got) != len(entries) {
+		t.Errorf("expected entries to be re-read, got %+v (%v)", got, err)
+	}
+}
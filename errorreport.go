@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+)
+
+// 構造化したエラーの出力 (-errors json) で使う、安定したエラーコードです。
+// 値はスクリプトから参照されるため、変更せずに追加だけを行います。
+const (
+	ErrorCodeUsage             = "usage"
+	ErrorCodeArchiveNotFound   = "archive_not_found"
+	ErrorCodeArchivePermission = "archive_permission"
+	ErrorCodeArchiveIsDir      = "archive_is_dir"
+	ErrorCodeArchiveEmpty      = "archive_empty"
+	ErrorCodeNotArchive        = "not_archive"
+	ErrorCodeInvalidArchive    = "invalid_archive"
+	ErrorCodeUnsupported       = "unsupported"
+	ErrorCodeChecksum          = "checksum"
+	ErrorCodeUnsafePath        = "unsafe_path"
+	ErrorCodeNetwork           = "network"
+	ErrorCodeTimeout           = "timeout"
+	ErrorCodeOverLimit         = "over_limit"
+	ErrorCodeEmptyResult       = "empty_result"
+	ErrorCodeExpectation       = "expectation"
+	ErrorCodeFlagged           = "flagged"
+	ErrorCodeNamingViolation   = "naming_violation"
+	ErrorCodeControlMismatch   = "control_mismatch"
+	ErrorCodeInternal          = "error"
+)
+
+// usageErr は引数の誤りのメッセージを持つエラーです。errors.Is(err, errUsage) で判定できます。
+type usageErr struct {
+	msg string
+}
+
+func (e *usageErr) Error() string {
+	return e.msg
+}
+
+func (e *usageErr) Unwrap() error {
+	return errUsage
+}
+
+// ErrorCode はエラーの種類に対応するエラーコードを返します。(純粋関数)
+func ErrorCode(err error) string {
+	var violation *PolicyViolation
+	if errors.As(err, &violation) {
+		switch violation.Code {
+		case exitOverLimit:
+			return ErrorCodeOverLimit
+		case exitEmpty:
+			return ErrorCodeEmptyResult
+		case exitExpectation:
+			return ErrorCodeExpectation
+		case exitFlagged:
+			return ErrorCodeFlagged
+		case exitNaming:
+			return ErrorCodeNamingViolation
+		case exitControl:
+			return ErrorCodeControlMismatch
+		}
+		return ErrorCodeInternal
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errUsage):
+		return ErrorCodeUsage
+	case errors.Is(err, ErrArchiveNotFound), errors.Is(err, fs.ErrNotExist):
+		return ErrorCodeArchiveNotFound
+	case errors.Is(err, ErrArchivePermission), errors.Is(err, fs.ErrPermission):
+		return ErrorCodeArchivePermission
+	case errors.Is(err, ErrArchiveIsDir):
+		return ErrorCodeArchiveIsDir
+	case errors.Is(err, ErrArchiveEmpty):
+		return ErrorCodeArchiveEmpty
+	case errors.Is(err, ErrNotZip):
+		return ErrorCodeNotArchive
+	case errors.Is(err, zip.ErrFormat):
+		return ErrorCodeInvalidArchive
+	case errors.Is(err, zip.ErrAlgorithm), errors.Is(err, ErrUDFOnly):
+		return ErrorCodeUnsupported
+	case errors.Is(err, zip.ErrChecksum):
+		return ErrorCodeChecksum
+	case errors.Is(err, ErrUnsafePath):
+		return ErrorCodeUnsafePath
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorCodeTimeout
+		}
+		return ErrorCodeNetwork
+	}
+	return ErrorCodeInternal
+}
+
+// ErrorReport は -errors json で標準エラー出力に書き出す1行のJSONです。
+type ErrorReport struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// writeErrorJSON はエラーを1行のJSONで出力します。
+func writeErrorJSON(w io.Writer, err error, exitCode int) error {
+	return json.NewEncoder(w).Encode(ErrorReport{Code: ErrorCode(err), Message: err.Error(), ExitCode: exitCode})
+}
+
+// errorFormatValue は -errors の値を env に設定する flag.Value です。
+type errorFormatValue struct {
+	env *cliEnv
+}
+
+func (v errorFormatValue) String() string {
+	if v.env == nil {
+		return ""
+	}
+	return v.env.errorFormat
+}
+
+func (v errorFormatValue) Set(s string) error {
+	if s != "text" && s != "json" {
+		return fmt.Errorf("unknown error format: %s", s)
+	}
+	v.env.errorFormat = s
+	return nil
+}
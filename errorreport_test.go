@@ -0,0 +1,71 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// ErrorCode のテスト
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "引数の誤り", err: &usageErr{msg: "zip path is required"}, expected: ErrorCodeUsage},
+		{name: "アーカイブが無い", err: fmt.Errorf("read entries error: %w", &ArchiveOpenError{Path: "a.zip", Err: ErrArchiveNotFound}), expected: ErrorCodeArchiveNotFound},
+		{name: "ZIPの形式の誤り", err: fmt.Errorf("failed to open zip: %w", zip.ErrFormat), expected: ErrorCodeInvalidArchive},
+		{name: "上限を超える", err: &PolicyViolation{Code: exitOverLimit}, expected: ErrorCodeOverLimit},
+		{name: "実行ファイル", err: &PolicyViolation{Code: exitFlagged}, expected: ErrorCodeFlagged},
+		{name: "命名規則の違反", err: &PolicyViolation{Code: exitNaming}, expected: ErrorCodeNamingViolation},
+		{name: "管理ファイルとの不一致", err: &PolicyViolation{Code: exitControl}, expected: ErrorCodeControlMismatch},
+		{name: "その他", err: fmt.Errorf("boom"), expected: ErrorCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// -errors json のテスト
+func TestRunCLIErrorsJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected ErrorReport
+	}{
+		{
+			name:     "引数の誤り",
+			args:     []string{"count", "-errors", "json", "-zip", "a.zip", "-segments", "x"},
+			expected: ErrorReport{Code: ErrorCodeUsage, Message: "invalid segment range: x", ExitCode: 2},
+		},
+		{
+			name:     "アーカイブが無い",
+			args:     []string{"count", "-errors", "json", "-log-level", "error", "-zip", filepath.Join(t.TempDir(), "missing.zip")},
+			expected: ErrorReport{Code: ErrorCodeArchiveNotFound, ExitCode: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			code := runCLI(tt.args, stdout, stderr)
+			if code != tt.expected.ExitCode {
+				t.Fatalf("expected code %d, got %d", tt.expected.ExitCode, code)
+			}
+			var got ErrorReport
+			if err := json.Unmarshal(stderr.Bytes(), &got); err != nil {
+				t.Fatalf("stderr is not a JSON line: %v (%s)", err, stderr.String())
+			}
+			if got.Code != tt.expected.Code || got.ExitCode != tt.expected.ExitCode || (tt.expected.Message != "" && got.Message != tt.expected.Message) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exitFlagged は -fail-on-flagged の指定時に実行ファイルやスクリプトがあった場合の終了コードです。
+const exitFlagged = 5
+
+// defaultExecutableExtensions は -flag-executables で報告する既定の拡張子です。
+const defaultExecutableExtensions = ".exe,.dll,.bat,.ps1,.js"
+
+// FlaggedEntry はセキュリティの確認のために報告するエントリです。
+type FlaggedEntry struct {
+	Name string
+	// Folder は集計結果と同じ集計キーのフォルダパスです。
+	Folder string
+}
+
+// ParseExtensions はカンマ区切りの拡張子を解析し、小文字で "." 始まりに揃えて返します。(純粋関数)
+func ParseExtensions(s string) ([]string, error) {
+	var exts []string
+	for _, ext := range strings.Split(s, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == "." || strings.ContainsAny(ext, "/\\") {
+			return nil, fmt.Errorf("invalid extension: %s", ext)
+		}
+		exts = append(exts, ext)
+	}
+	return exts, nil
+}
+
+// FlagExecutables は名前が拡張子のいずれかで終わるファイルを返します。(純粋関数)
+// 見落としを防ぐため、集計の絞り込みの条件にかかわらずすべてのファイルを対象にします。
+// フォルダは opts の正規化、ロールアップ、階層範囲を適用した集計キーです。
+func FlagExecutables(entries []FileEntry, exts []string, opts AggregateOptions) []FlaggedEntry {
+	if len(exts) == 0 {
+		return nil
+	}
+	var flagged []FlaggedEntry
+	for _, e := range entries {
+		if e.IsDir {
+			continue
+		}
+		name := strings.ToLower(e.Name)
+		for _, ext := range exts {
+			if strings.HasSuffix(name, ext) {
+				flagged = append(flagged, FlaggedEntry{Name: e.Name, Folder: FolderKey(e.Name, opts)})
+				break
+			}
+		}
+	}
+	return flagged
+}
+
+// FlaggedFindings は報告するエントリを検出事項として返します。(純粋関数)
+// fail が true の場合は ERROR、それ以外は WARN とします。
+func FlaggedFindings(flagged []FlaggedEntry, fail bool) []Finding {
+	severity := SeverityWarn
+	if fail {
+		severity = SeverityError
+	}
+	findings := make([]Finding, 0, len(flagged))
+	for _, f := range flagged {
+		findings = append(findings, Finding{
+			Severity: severity,
+			Rule:     RuleExecutable,
+			Folder:   f.Folder,
+			Message:  "executable or script: " + f.Name,
+		})
+	}
+	return findings
+}
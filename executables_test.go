@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// FlagExecutables と PolicyError のテスト
+func TestFlagExecutables(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "setup.EXE"},
+		{Name: "a/b/run.ps1"},
+		{Name: "a/readme.txt"},
+		{Name: "a/lib.js/", IsDir: true},
+		{Name: "a/app.json"},
+	}
+	exts, err := ParseExtensions(" exe, .PS1 ,js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".exe", ".ps1", ".js"}; !reflect.DeepEqual(exts, want) {
+		t.Fatalf("expected %v, got %v", want, exts)
+	}
+	if _, err := ParseExtensions("a/b"); err == nil {
+		t.Error("expected error for extension with separator")
+	}
+
+	flagged := FlagExecutables(entries, exts, AggregateOptions{})
+	want := []FlaggedEntry{{Name: "setup.EXE", Folder: "(Root)"}, {Name: "a/b/run.ps1", Folder: "a/b"}}
+	if !reflect.DeepEqual(flagged, want) {
+		t.Fatalf("expected %v, got %v", want, flagged)
+	}
+
+	tests := []struct {
+		name     string
+		fail     bool
+		severity Severity
+		expected int
+	}{
+		{name: "報告のみ", fail: false, severity: SeverityWarn, expected: 0},
+		{name: "検出で失敗", fail: true, severity: SeverityError, expected: exitFlagged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := FlaggedFindings(flagged, tt.fail)
+			if len(findings) != 2 || findings[0].Severity != tt.severity || findings[0].Rule != RuleExecutable {
+				t.Fatalf("unexpected findings: %+v", findings)
+			}
+			code := 0
+			var violation *PolicyViolation
+			if err := PolicyError(findings); errors.As(err, &violation) {
+				code = violation.Code
+			}
+			if code != tt.expected {
+				t.Errorf("expected code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
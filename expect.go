@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exitExpectation は -expect の仕様を満たさないフォルダがあった場合の終了コードです。
+const exitExpectation = 4
+
+// FolderExpectation は納品仕様で期待するフォルダとファイル数の範囲です。
+type FolderExpectation struct {
+	// Path は集計キーと同じ "/" 区切りのフォルダパスです。仕様では "\" 区切りでも指定できます。
+	Path string `yaml:"path"`
+	// Min はファイル数の下限です。0の場合は、フォルダが無くても違反としません。
+	Min int `yaml:"min"`
+	// Max はファイル数の上限です。0の場合は上限を設けません。
+	Max int `yaml:"max"`
+}
+
+// expectSpec は YAML 形式の仕様ファイルの内容です。
+//
+//	folders:
+//	  - path: scans/2023
+//	    min: 1
+//	    max: 5000
+type expectSpec struct {
+	Folders []FolderExpectation `yaml:"folders"`
+}
+
+// LoadExpectations は仕様ファイルを読み込みます。
+// 拡張子が .yaml / .yml の場合はYAML、それ以外は "Folder Path,Min,Max" のCSVとして読み込みます。
+func LoadExpectations(specPath string) ([]FolderExpectation, error) {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expectation spec: %w", err)
+	}
+	var expectations []FolderExpectation
+	switch strings.ToLower(filepath.Ext(specPath)) {
+	case ".yaml", ".yml":
+		expectations, err = ParseExpectationsYAML(data)
+	default:
+		expectations, err = ParseExpectationsCSV(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expectation spec %s: %w", specPath, err)
+	}
+	return expectations, nil
+}
+
+// ParseExpectationsYAML はYAML形式の仕様を読み込みます。
+func ParseExpectationsYAML(data []byte) ([]FolderExpectation, error) {
+	var spec expectSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	for i := range spec.Folders {
+		spec.Folders[i].Path = normalizeAllowedPath(spec.Folders[i].Path)
+		if err := spec.Folders[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return spec.Folders, nil
+}
+
+// ParseExpectationsCSV は "Folder Path,Min,Max" のCSV形式の仕様を読み込みます。
+// BOM、空行、見出し行は無視し、Min と Max の空欄は0 (制限なし) とします。
+func ParseExpectationsCSV(r io.Reader) ([]FolderExpectation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})))
+	reader.FieldsPerRecord = -1
+
+	var expectations []FolderExpectation
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := FolderExpectation{Path: normalizeAllowedPath(record[0])}
+		if e.Path == "" || (line == 1 && strings.EqualFold(e.Path, "Folder Path")) {
+			continue
+		}
+		for i, dst := range []*int{&e.Min, &e.Max} {
+			if len(record) <= i+1 || strings.TrimSpace(record[i+1]) == "" {
+				continue
+			}
+			if *dst, err = strconv.Atoi(strings.TrimSpace(record[i+1])); err != nil {
+				return nil, fmt.Errorf("line %d: invalid count: %s", line, record[i+1])
+			}
+		}
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		expectations = append(expectations, e)
+	}
+	return expectations, nil
+}
+
+// validate はファイル数の範囲が正しいか検査します。
+func (e FolderExpectation) validate() error {
+	if e.Path == "" {
+		return errors.New("folder path is required")
+	}
+	if e.Min < 0 || e.Max < 0 || (e.Max > 0 && e.Min > e.Max) {
+		return fmt.Errorf("invalid range for %s: min %d, max %d", e.Path, e.Min, e.Max)
+	}
+	return nil
+}
+
+// ExpectationFindings は仕様を満たさないフォルダを ERROR の検出事項として返します。(純粋関数)
+// folders にはしきい値で絞り込む前のすべてのフォルダが含まれている必要があります。
+func ExpectationFindings(expectations []FolderExpectation, folders []FolderCount) []Finding {
+	counts := make(map[string]int, len(folders))
+	for _, f := range folders {
+		counts[f.Path] = f.Count
+	}
+	var findings []Finding
+	for _, e := range expectations {
+		n, found := counts[e.Path]
+		var message string
+		switch {
+		case e.Min > 0 && !found:
+			message = fmt.Sprintf("expected folder is missing (min %d)", e.Min)
+		case n < e.Min:
+			message = fmt.Sprintf("%d files are fewer than the expected minimum %d", n, e.Min)
+		case e.Max > 0 && n > e.Max:
+			message = fmt.Sprintf("%d files exceed the expected maximum %d", n, e.Max)
+		default:
+			continue
+		}
+		findings = append(findings, Finding{Severity: SeverityError, Rule: RuleExpect, Folder: e.Path, Message: message})
+	}
+	return findings
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// 仕様ファイルの読み込みのテスト
+func TestParseExpectations(t *testing.T) {
+	want := []FolderExpectation{{Path: "scans/2023", Min: 1, Max: 100}, {Path: "docs", Min: 5}}
+
+	t.Run("YAML", func(t *testing.T) {
+		got, err := ParseExpectationsYAML([]byte("folders:\n  - path: scans/2023\n    min: 1\n    max: 100\n  - path: docs\n    min: 5\n"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("BOM付きCSV", func(t *testing.T) {
+		got, err := ParseExpectationsCSV(strings.NewReader("\xEF\xBB\xBFFolder Path,Min,Max\nscans\\2023,1,100\n\ndocs,5,\n"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+
+	for _, input := range []string{"docs,x\n", "docs,10,5\n", "docs,-1\n"} {
+		if _, err := ParseExpectationsCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+// ExpectationFindings のテスト
+func TestExpectationFindings(t *testing.T) {
+	folders := []FolderCount{{Path: "a", Count: 10}, {Path: "b", Count: 2}}
+	expectations := []FolderExpectation{
+		{Path: "a", Min: 1, Max: 5},
+		{Path: "b", Min: 3},
+		{Path: "c", Min: 1},
+		{Path: "d"},
+		{Path: "a", Max: 10},
+	}
+	var got []string
+	for _, f := range ExpectationFindings(expectations, folders) {
+		got = append(got, f.Folder+": "+f.Message)
+	}
+	want := []string{
+		"a: 10 files exceed the expected maximum 5",
+		"b: 2 files are fewer than the expected minimum 3",
+		"c: expected folder is missing (min 1)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// count の -expect のテスト
+func TestRunCLIExpect(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt", "c.txt").WriteFile(t)
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	tests := []struct {
+		name         string
+		spec         string
+		expectedCode int
+		expectedOut  string
+	}{
+		{name: "仕様を満たす", spec: write("ok.csv", "dir1,2,2\n(Root),1\n"), expectedCode: 0},
+		{name: "しきい値未満のフォルダも照合", spec: write("ng.yaml", "folders:\n  - path: dir1\n    max: 1\n  - path: missing\n    min: 1\n"), expectedCode: 4, expectedOut: "ERROR expect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "100", "-expect", tt.spec}, stdout, stderr)
+			if code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExtractOptions は展開方法を指定します。
+type ExtractOptions struct {
+	// Filter は展開するエントリの条件です。
+	Filter EntryFilter
+	// Overwrite が true の場合、展開先に同じ名前のファイルがあれば上書きします。false の場合はエラーにします。
+	Overwrite bool
+}
+
+// ExtractResult は展開の結果です。
+type ExtractResult struct {
+	Files int
+	Bytes uint64
+	// Filtered は条件に一致せず展開しなかったファイルの数です。
+	Filtered int
+	// Unsafe は展開先の外を指す、または絶対パスのため展開しなかったエントリの名前です。
+	Unsafe []string
+	// Symlinks はシンボリックリンクのため展開しなかったエントリの名前です。
+	Symlinks []string
+}
+
+// ErrUnsafePath はエントリ名が展開先のフォルダの外を指すことを表すエラーです。
+var ErrUnsafePath = errors.New("entry path escapes the destination")
+
+// extractPath はエントリ名から展開先のパスを求めます。(純粋関数)
+// 絶対パス、ドライブ名、".." で展開先の外を指す名前 (Zip Slip) は ErrUnsafePath を返します。
+func extractPath(dest, name string) (string, error) {
+	name = strings.TrimSuffix(strings.ReplaceAll(name, "\\", "/"), "/")
+	if name == "" || strings.HasPrefix(name, "/") || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return filepath.Join(dest, filepath.FromSlash(name)), nil
+}
+
+// Extract はローカルのZIPファイルから条件に一致するエントリを dest に展開します。
+// エントリ名は集計と同じく復号した名前を使い、更新日時も復元します。
+// 展開先の外を指すエントリとシンボリックリンクは展開せず、結果に記録します。
+func (z ZipArchiveReader) Extract(zipPath, dest string, opts ExtractOptions) (ExtractResult, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return ExtractResult{}, fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer r.Close()
+
+	// シンボリックリンクは絞り込みで除かずに、展開しなかったエントリとして記録する
+	filter := opts.Filter
+	filter.Symlinks = true
+	var result ExtractResult
+	entries, _ := z.entries(r.File, time.Time{}, nil)
+	for i, e := range entries {
+		if !filter.Match(e) {
+			if !e.IsDir {
+				result.Filtered++
+			}
+			continue
+		}
+		target, err := extractPath(dest, e.Name)
+		if err != nil {
+			result.Unsafe = append(result.Unsafe, e.Name)
+			continue
+		}
+		f := r.File[i]
+		if e.Mode&fs.ModeSymlink != 0 {
+			result.Symlinks = append(result.Symlinks, e.Name)
+			continue
+		}
+		if e.IsDir {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return result, fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		}
+		n, err := extractFile(f, target, opts.Overwrite)
+		if err != nil {
+			return result, fmt.Errorf("failed to extract %s: %w", e.Name, err)
+		}
+		if !e.Modified.IsZero() {
+			// 更新日時を復元できなくても内容は展開できているため、エラーにしない
+			_ = os.Chtimes(target, e.Modified, e.Modified)
+		}
+		result.Files++
+		result.Bytes += n
+	}
+	return result, nil
+}
+
+// extractFile は1件のエントリを target に書き出し、書き出したバイト数を返します。
+func extractFile(f *zip.File, target string, overwrite bool) (uint64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return 0, err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(target, flags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, rc)
+	if err != nil {
+		out.Close()
+		return uint64(n), err
+	}
+	return uint64(n), out.Close()
+}
+
+// WriteExtractResult は展開の結果をプレーンテキストでWriterに出力します。
+func WriteExtractResult(w io.Writer, result ExtractResult) error {
+	if _, err := fmt.Fprintf(w, "Extracted: %d files (%s), Filtered out: %d\n", result.Files, FormatSize(result.Bytes), result.Filtered); err != nil {
+		return err
+	}
+	for _, name := range result.Unsafe {
+		if _, err := fmt.Fprintf(w, "unsafe path (skipped): %q\n", name); err != nil {
+			return err
+		}
+	}
+	for _, name := range result.Symlinks {
+		if _, err := fmt.Fprintf(w, "symlink (skipped): %q\n", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runExtract は extract コマンドを実行します。
+func runExtract(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "extract", "extract -zip <path> -dest <dir> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var ff filterFlags
+	ff.register(fs, "展開")
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパス (必須、\"-\" で標準入力)")
+	dest := fs.String("dest", "", "展開先のフォルダ (必須、存在しない場合は作成)")
+	overwrite := fs.Bool("overwrite", false, "展開先に同じ名前のファイルがあれば上書きする")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" || *dest == "" {
+		return usageError(fs, "zip path and destination are required")
+	}
+	if isRemotePath(*zipPath) {
+		return usageError(fs, "extract does not support remote archives")
+	}
+	filter, err := ff.filter(fs)
+	if err != nil {
+		return err
+	}
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	return extractArchive(env, reader.Zip, *zipPath, *dest, ExtractOptions{Filter: filter, Overwrite: *overwrite})
+}
+
+// extractArchive は標準入力の場合は一時ファイルに書き出してから展開し、結果を出力します。
+func extractArchive(env *cliEnv, reader ZipArchiveReader, zipPath, dest string, opts ExtractOptions) error {
+	localPath := zipPath
+	if zipPath == StdinPath {
+		tmpPath, _, cleanup, err := spoolToTempFile(os.Stdin)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		localPath = tmpPath
+	} else if err := CheckArchiveFile(zipPath); err != nil {
+		return err
+	}
+	if kind := (AutoArchiveReader{}).kind(localPath); kind != "zip" {
+		return fmt.Errorf("extract supports only zip archives: %s is %s", zipPath, kind)
+	}
+
+	env.Logger.Info("展開を開始します", slog.String("zipPath", zipPath), slog.String("dest", dest))
+	result, err := reader.Extract(localPath, dest, opts)
+	if werr := WriteExtractResult(env.Stdout, result); werr != nil && err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+	env.Logger.Info("展開が完了しました", slog.Int("files", result.Files), slog.Int("filtered", result.Filtered))
+	if len(result.Unsafe) > 0 {
+		return fmt.Errorf("%d entries with unsafe paths were not extracted", len(result.Unsafe))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// extractPath のテスト
+func TestExtractPath(t *testing.T) {
+	dest := filepath.Join("out", "dest")
+	tests := []struct {
+		name     string
+		entry    string
+		expected string
+	}{
+		{name: "通常のパス", entry: "dir1/a.txt", expected: filepath.Join(dest, "dir1", "a.txt")},
+		{name: "ディレクトリ", entry: "dir1/", expected: filepath.Join(dest, "dir1")},
+		{name: "内側に戻る..", entry: "dir1/../a.txt", expected: filepath.Join(dest, "a.txt")},
+		{name: "外側を指す..", entry: "../a.txt"},
+		{name: "途中で外側を指す..", entry: "dir1/../../a.txt"},
+		{name: "バックスラッシュ区切りの..", entry: "..\\a.txt"},
+		{name: "絶対パス", entry: "/etc/passwd"},
+		{name: "空の名前", entry: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractPath(dest, tt.entry)
+			if tt.expected == "" {
+				if !errors.Is(err, ErrUnsafePath) {
+					t.Errorf("expected ErrUnsafePath, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("expected %q, got %q, %v", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+// ZipArchiveReader.Extract のテスト
+func TestExtract(t *testing.T) {
+	zipPath := obuziptest.New().
+		File("docs/a.pdf", []byte("pdf")).
+		File("docs/b.txt", []byte("text")).
+		File("docs/sub/c.PDF", []byte("pdf2")).
+		File("../evil.pdf", []byte("evil")).
+		RawFile([]byte("\x95\x5c/d.pdf")). // 表/d.pdf
+		Dir("empty").
+		WriteFile(t)
+	dest := t.TempDir()
+
+	filter := EntryFilter{Include: []string{"**/*.pdf"}}
+	result, err := ZipArchiveReader{}.Extract(zipPath, dest, ExtractOptions{Filter: filter})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Files != 3 || result.Filtered != 1 || !reflect.DeepEqual(result.Unsafe, []string{"../evil.pdf"}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	for _, name := range []string{"docs/a.pdf", "docs/sub/c.PDF", "表/d.pdf"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %s to be extracted: %v", name, err)
+		}
+	}
+	for _, name := range []string{"docs/b.txt", "empty", "../evil.pdf"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err == nil {
+			t.Errorf("expected %s not to be extracted", name)
+		}
+	}
+
+	t.Run("既存のファイル", func(t *testing.T) {
+		if _, err := (ZipArchiveReader{}).Extract(zipPath, dest, ExtractOptions{Filter: filter}); !errors.Is(err, os.ErrExist) {
+			t.Errorf("expected ErrExist, got %v", err)
+		}
+		if _, err := (ZipArchiveReader{}).Extract(zipPath, dest, ExtractOptions{Filter: filter, Overwrite: true}); err != nil {
+			t.Errorf("unexpected error with overwrite: %v", err)
+		}
+	})
+}
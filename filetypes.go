@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// otherCategory はどの分類にも当てはまらない拡張子の分類名です。
+const otherCategory = "other"
+
+// defaultFileCategories は既定の分類と拡張子です。設定ファイルの categories で追加・変更できます。
+var defaultFileCategories = map[string][]string{
+	"image":      {".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tif", ".tiff", ".webp", ".heic", ".svg"},
+	"document":   {".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".txt", ".csv", ".rtf", ".odt", ".ods", ".odp", ".md"},
+	"archive":    {".zip", ".7z", ".rar", ".lzh", ".tar", ".gz", ".tgz", ".tar.gz", ".bz2", ".xz", ".cab", ".iso"},
+	"executable": {".exe", ".dll", ".msi", ".bat", ".cmd", ".com", ".ps1", ".vbs", ".js", ".sh", ".jar", ".scr"},
+	"audio":      {".mp3", ".wav", ".m4a", ".flac", ".aac", ".wma"},
+	"video":      {".mp4", ".mov", ".avi", ".wmv", ".mkv", ".mpg", ".mpeg"},
+}
+
+// FileClassifier は拡張子 (小文字、"." 始まり) と分類名の対応です。
+type FileClassifier map[string]string
+
+// NewFileClassifier は既定の分類に categories を追加した FileClassifier を作成します。(純粋関数)
+// categories に既定の分類と同じ拡張子がある場合は categories の分類を使います。
+func NewFileClassifier(categories map[string][]string) FileClassifier {
+	c := make(FileClassifier)
+	for _, cats := range []map[string][]string{defaultFileCategories, categories} {
+		for category, exts := range cats {
+			for _, ext := range exts {
+				ext = strings.ToLower(strings.TrimSpace(ext))
+				if !strings.HasPrefix(ext, ".") {
+					ext = "." + ext
+				}
+				c[ext] = category
+			}
+		}
+	}
+	return c
+}
+
+// Classify はエントリ名の拡張子から分類名を返します。(純粋関数)
+// ".tar.gz" のような2つの拡張子を先に調べ、当てはまらない場合は "other" を返します。
+func (c FileClassifier) Classify(name string) string {
+	base := strings.ToLower(path.Base(name))
+	ext := path.Ext(base)
+	if ext == "" {
+		return otherCategory
+	}
+	if ext2 := path.Ext(strings.TrimSuffix(base, ext)); ext2 != "" {
+		if category, ok := c[ext2+ext]; ok {
+			return category
+		}
+	}
+	if category, ok := c[ext]; ok {
+		return category
+	}
+	return otherCategory
+}
+
+// CategoryCount はフォルダと分類の組み合わせごとのファイル数と合計サイズです。
+type CategoryCount struct {
+	Folder   string
+	Category string
+	Files    int
+	// Size は展開後の合計バイト数です。
+	Size uint64
+}
+
+// ComputeCategoryCounts はファイルを集計キーのフォルダと分類の組み合わせで集計します。(純粋関数)
+// ファイル数が opts.Threshold 未満の組み合わせは除外されます。
+// 結果はフォルダの昇順、同じフォルダではファイル数の降順、分類名の昇順でソートされます。
+func ComputeCategoryCounts(entries []FileEntry, opts AggregateOptions, c FileClassifier) []CategoryCount {
+	type key struct{ folder, category string }
+	counts := make(map[key]*CategoryCount)
+	for _, e := range entries {
+		if e.IsDir || !opts.Filter.Match(e) {
+			continue
+		}
+		k := key{FolderKey(e.Name, opts), c.Classify(e.Name)}
+		cc, ok := counts[k]
+		if !ok {
+			cc = &CategoryCount{Folder: k.folder, Category: k.category}
+			counts[k] = cc
+		}
+		cc.Files++
+		cc.Size += e.Size
+	}
+
+	results := make([]CategoryCount, 0, len(counts))
+	for _, cc := range counts {
+		if cc.Files >= opts.Threshold {
+			results = append(results, *cc)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Folder != b.Folder {
+			return a.Folder < b.Folder
+		}
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Category < b.Category
+	})
+	return results
+}
+
+// WriteCategoryCountsText はフォルダと分類ごとのファイル数をプレーンテキストでWriterに出力します。
+func WriteCategoryCountsText(w io.Writer, results []CategoryCount, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-50s | %-10s | %10s | %s\n", lang.T("Folder Path"), lang.T("Category"), lang.T("File Count"), lang.T("Total Size"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 90))
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "%-50s | %-10s | %10d | %s\n", r.Folder, r.Category, r.Files, FormatSize(r.Size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCategoryCountsCSV はフォルダと分類ごとのファイル数をCSV形式でWriterに出力します。
+func WriteCategoryCountsCSV(w io.Writer, results []CategoryCount, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Category", "File Count", "Total Size"}); err != nil {
+			return err
+		}
+		for _, r := range results {
+			if err := writer.Write([]string{r.Folder, r.Category, strconv.Itoa(r.Files), strconv.FormatUint(r.Size, 10)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectCategoryCountsWriter は出力形式名に対応する書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectCategoryCountsWriter(format string, opts WriterOptions) (func(io.Writer, []CategoryCount) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, results []CategoryCount) error {
+			return WriteCategoryCountsText(w, results, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, results []CategoryCount) error {
+			return WriteCategoryCountsCSV(w, results, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// loadFileClassifier は設定ファイルの categories を読み込み、既定の分類に追加した FileClassifier を作成します。
+// -config を省略し、既定の場所に設定ファイルが無い場合は既定の分類だけを使います。
+func loadFileClassifier(fs *flag.FlagSet) (FileClassifier, error) {
+	configPath := fs.Lookup("config").Value.String()
+	explicit := configPath != ""
+	if !explicit {
+		var err error
+		if configPath, err = defaultConfigPath(); err != nil {
+			return NewFileClassifier(nil), nil
+		}
+	}
+	cfg, err := LoadConfigFile(configPath)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return NewFileClassifier(nil), nil
+		}
+		return nil, err
+	}
+	return NewFileClassifier(cfg.Categories), nil
+}
+
+// runTypes は types コマンドを実行します。
+func runTypes(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "types", "types -zip <path> [-threshold N] [-format text|csv] [-sniff]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	sniff := fs.Bool("sniff", false, "各ファイルの先頭を読んで内容から種類を判定し、拡張子と一致しないファイルを報告する (ローカルのZIPファイルのみ、text 形式のみ)")
+	sniffBytes := fs.Int("sniff-bytes", defaultSniffBytes, "-sniff で1件のファイルから読む先頭のバイト数の上限")
+	sniffWorkers := fs.Int("sniff-workers", runtime.NumCPU(), "-sniff で同時に読み込むファイルの数")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	if *sniff {
+		if *format != "" && *format != "text" {
+			return usageError(fs, "sniff is only supported with text format")
+		}
+		if *sniffBytes <= 0 || *sniffWorkers <= 0 {
+			return usageError(fs, "sniff bytes and workers must be positive")
+		}
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectCategoryCountsWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	classifier, err := loadFileClassifier(fs)
+	if err != nil {
+		return err
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	counts := ComputeCategoryCounts(entries, opts, classifier)
+	for i := range counts {
+		counts[i].Folder = cfg.Paths.Folder(counts[i].Folder)
+	}
+	if err := write(env.Stdout, counts); err != nil {
+		return err
+	}
+	if !*sniff {
+		return nil
+	}
+	if !canInspectZip(*zipPath) {
+		return fmt.Errorf("sniff is only supported for local zip files: %s", *zipPath)
+	}
+	env.Logger.Info("内容による種類の判定を開始します", slog.String("zipPath", *zipPath), slog.Int("workers", *sniffWorkers))
+	results, err := reader.Zip.Sniff(*zipPath, SniffOptions{Workers: *sniffWorkers, Bytes: *sniffBytes, Filter: opts.Filter})
+	if err != nil {
+		return err
+	}
+	mismatches := FindSniffMismatches(results, classifier, reader.Containers)
+	env.Logger.Info("内容による種類の判定が完了しました", slog.Int("sniffed", len(results)), slog.Int("mismatches", len(mismatches)))
+	return WriteSniffMismatches(env.Stdout, mismatches)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// FileClassifier.Classify のテスト
+func TestFileClassifier(t *testing.T) {
+	c := NewFileClassifier(map[string][]string{"cad": {"dwg", ".DXF"}, "document": {".jpg"}})
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "dir/a.PDF", expected: "document"},
+		{name: "dir/b.png", expected: "image"},
+		{name: "dir/c.tar.gz", expected: "archive"},
+		{name: "dir/d.backup.gz", expected: "archive"},
+		{name: "dir/setup.exe", expected: "executable"},
+		{name: "dir/plan.dwg", expected: "cad"},
+		{name: "dir/plan.dxf", expected: "cad"},
+		{name: "dir/photo.jpg", expected: "document"},
+		{name: "dir/README", expected: "other"},
+		{name: "dir/.hidden", expected: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.name); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ComputeCategoryCounts のテスト
+func TestComputeCategoryCounts(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "納品/a.jpg", Size: 100},
+		{Name: "納品/b.png", Size: 50},
+		{Name: "納品/c.pdf", Size: 10},
+		{Name: "納品/tools/run.bat", Size: 1},
+		{Name: "納品/", IsDir: true},
+		{Name: "readme.txt", Size: 5},
+	}
+	result := ComputeCategoryCounts(entries, AggregateOptions{}, NewFileClassifier(nil))
+	expected := []CategoryCount{
+		{Folder: "(Root)", Category: "document", Files: 1, Size: 5},
+		{Folder: "納品", Category: "image", Files: 2, Size: 150},
+		{Folder: "納品", Category: "document", Files: 1, Size: 10},
+		{Folder: "納品/tools", Category: "executable", Files: 1, Size: 1},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	t.Run("しきい値で絞り込みCSV出力", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteCategoryCountsCSV(out, ComputeCategoryCounts(entries, AggregateOptions{Threshold: 2}, NewFileClassifier(nil)), CSVOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		expected := "\xEF\xBB\xBFFolder Path,Category,File Count,Total Size\n納品,image,2,150\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("BOMなしUTF-8のセミコロン区切り", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteCategoryCountsCSV(out, ComputeCategoryCounts(entries, AggregateOptions{Threshold: 2}, NewFileClassifier(nil)), CSVOptions{Encoding: "utf8", Delimiter: "semicolon"}); err != nil {
+			t.Fatal(err)
+		}
+		expected := "Folder Path;Category;File Count;Total Size\n納品;image;2;150\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+}
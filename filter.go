@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EntryFilter は集計前にエントリを絞り込む条件です。ゼロ値はシンボリックリンクと特殊なエントリを除くすべてのエントリを通します。
+type EntryFilter struct {
+	// ModifiedAfter 以降 (この時刻を含む) に更新されたエントリだけを対象にします。
+	ModifiedAfter time.Time
+	// ModifiedBefore より前 (この時刻を含まない) に更新されたエントリだけを対象にします。
+	ModifiedBefore time.Time
+	// MinSize 以上 (展開後のバイト数) のエントリだけを対象にします。
+	MinSize uint64
+	// MaxSize 以下のエントリだけを対象にします。0の場合は上限を設けません。
+	MaxSize uint64
+	// Include はエントリのパス全体と比較するパターン (MatchPathPattern の構文) です。
+	// 指定した場合は、いずれかに一致するエントリだけを対象にします。大文字と小文字は区別しません。
+	Include []string
+	// Exclude はパスの要素 (フォルダ名またはファイル名) のいずれかが一致するエントリを除くパターンです。
+	// __MACOSX や Thumbs.db などの不要なファイルを除くために使います。大文字と小文字は区別しません。
+	Exclude []string
+	// IncludeComment を指定した場合は、エントリのコメントが一致するエントリだけを対象にします。
+	// 取引先がエントリのコメントで付けた目印で絞り込むために使います。
+	IncludeComment *regexp.Regexp
+	// ExcludeComment はエントリのコメントが一致するエントリを除く正規表現です。
+	ExcludeComment *regexp.Regexp
+	// Symlinks が true の場合、シンボリックリンクも通常のファイルと同じく対象にします。
+	// デバイスなどの特殊なエントリは常に対象にしません。
+	Symlinks bool
+}
+
+// Match はエントリが条件を満たすか判定します。(純粋関数)
+func (f EntryFilter) Match(e FileEntry) bool {
+	if e.Mode&specialModes != 0 || (!f.Symlinks && e.Mode&fs.ModeSymlink != 0) {
+		return false
+	}
+	if !f.ModifiedAfter.IsZero() && e.Modified.Before(f.ModifiedAfter) {
+		return false
+	}
+	if !f.ModifiedBefore.IsZero() && !e.Modified.Before(f.ModifiedBefore) {
+		return false
+	}
+	if e.Size < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && e.Size > f.MaxSize {
+		return false
+	}
+	if len(f.Include) > 0 {
+		name := strings.ToLower(strings.TrimSuffix(e.Name, "/"))
+		matched := false
+		for _, pattern := range f.Include {
+			if MatchPathPattern(pattern, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.IncludeComment != nil && !f.IncludeComment.MatchString(e.Comment) {
+		return false
+	}
+	if f.ExcludeComment != nil && f.ExcludeComment.MatchString(e.Comment) {
+		return false
+	}
+	if len(f.Exclude) > 0 {
+		for _, elem := range strings.Split(strings.ToLower(e.Name), "/") {
+			for _, pattern := range f.Exclude {
+				if ok, _ := path.Match(pattern, elem); ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// ParseExcludePatterns はカンマ区切りの除外パターンを解析し、小文字に揃えて返します。(純粋関数)
+// 空文字列の場合は nil を返します。
+func ParseExcludePatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %s", p)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// ParseIncludePatterns はカンマ区切りのパスのパターンを解析し、小文字に揃えて返します。(純粋関数)
+// 空文字列の場合は nil を返します。
+func ParseIncludePatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.ToLower(strings.Trim(strings.TrimSpace(p), "/"))
+		if p == "" {
+			continue
+		}
+		if err := validatePathPattern(p); err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %s", p)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// ParseCommentPattern はエントリのコメントと比較する正規表現を解析します。(純粋関数)
+// 空文字列の場合は nil を返します。
+func ParseCommentPattern(s string) (*regexp.Regexp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment pattern: %w", err)
+	}
+	return re, nil
+}
+
+// filterTimeLayouts は日時指定として受け付ける書式です。
+var filterTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseFilterTime は日時指定を解析します。空文字列の場合はゼロ値を返します。
+// タイムゾーンを含まない指定は、ZIPに記録された日時 (DOS形式は壁時計時刻をUTCとして保持) と
+// 比較できるようUTCとして解釈します。
+func ParseFilterTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s (use YYYY-MM-DD or RFC 3339)", s)
+}
+
+// sizeUnits はサイズ指定で使える単位です。Windowsのエクスプローラーと同じく1024倍で換算します。
+var sizeUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize は "10MB", "1.5G", "512" のようなサイズ指定をバイト数に変換します。(純粋関数)
+// 空文字列の場合は0を返します。
+func ParseSize(s string) (uint64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	if str == "" {
+		return 0, nil
+	}
+	multiplier := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str, multiplier = strings.TrimSpace(strings.TrimSuffix(str, u.suffix)), u.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	bytes := n * multiplier
+	if bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return uint64(bytes), nil
+}
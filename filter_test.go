@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// ParseFilterTime のテスト
+func TestParseFilterTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{input: "", expected: time.Time{}},
+		{input: "2024-04-01", expected: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2024-04-01T09:30:00", expected: time.Date(2024, 4, 1, 9, 30, 0, 0, time.UTC)},
+		{input: "2024-04-01T09:30:00+09:00", expected: time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC)},
+		{input: "2024/04/01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseFilterTime(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+// Aggregate の更新日時による絞り込みのテスト
+func TestAggregateModifiedFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 4, d, 12, 0, 0, 0, time.UTC) }
+	entries := []FileEntry{
+		{Name: "a/1.txt", Modified: day(1)},
+		{Name: "a/2.txt", Modified: day(2)},
+		{Name: "b/3.txt", Modified: day(3)},
+		{Name: "b/4.txt", Modified: day(4)},
+	}
+	filter := EntryFilter{
+		ModifiedAfter:  day(2),
+		ModifiedBefore: day(4),
+	}
+	result, total := Aggregate(entries, AggregateOptions{Threshold: 1, Filter: filter})
+	expected := []FolderCount{
+		{Path: "a", Count: 1},
+		{Path: "b", Count: 1},
+	}
+	if total != 2 {
+		t.Errorf("expected total 2, got %d", total)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+// ParseSize のテスト
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint64
+		wantErr  bool
+	}{
+		{input: "", expected: 0},
+		{input: "512", expected: 512},
+		{input: "10KB", expected: 10 << 10},
+		{input: "10mb", expected: 10 << 20},
+		{input: "1.5G", expected: 3 << 29},
+		{input: "2 TiB", expected: 2 << 40},
+		{input: "-1", wantErr: true},
+		{input: "10XB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+// EntryFilter のサイズ条件のテスト
+func TestEntryFilterSize(t *testing.T) {
+	filter := EntryFilter{MinSize: 1, MaxSize: 100}
+	for size, expected := range map[uint64]bool{0: false, 1: true, 100: true, 101: false} {
+		if got := filter.Match(FileEntry{Name: "a", Size: size}); got != expected {
+			t.Errorf("size %d: expected %v, got %v", size, expected, got)
+		}
+	}
+	if !(EntryFilter{}).Match(FileEntry{Name: "a", Size: 1 << 40}) {
+		t.Error("zero filter must match every entry")
+	}
+}
+
+// EntryFilter の除外パターンのテスト
+func TestEntryFilterExclude(t *testing.T) {
+	patterns, err := ParseExcludePatterns("__MACOSX, .DS_Store,Thumbs.db,~$*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filter := EntryFilter{Exclude: patterns}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "dir1/a.txt", expected: true},
+		{name: "__MACOSX/dir1/._a.txt", expected: false},
+		{name: "dir1/.DS_Store", expected: false},
+		{name: "dir1/thumbs.DB", expected: false},
+		{name: "dir1/~$report.docx", expected: false},
+		{name: "dir1/my_thumbs.db", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Match(FileEntry{Name: tt.name}); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := ParseExcludePatterns("[a"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+// EntryFilter の対象パターンのテスト
+func TestEntryFilterInclude(t *testing.T) {
+	patterns, err := ParseIncludePatterns("docs/**/*.PDF, /images/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filter := EntryFilter{Include: patterns}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "docs/a.pdf", expected: true},
+		{name: "Docs/sub/b.Pdf", expected: true},
+		{name: "docs/a.txt", expected: false},
+		{name: "images/", expected: true},
+		{name: "images/a.png", expected: false},
+		{name: "other/docs/a.pdf", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Match(FileEntry{Name: tt.name}); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := ParseIncludePatterns("docs/[a"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+// EntryFilter のコメントによる絞り込みのテスト
+func TestEntryFilterComment(t *testing.T) {
+	include, err := ParseCommentPattern(`^APPROVED`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exclude, err := ParseCommentPattern(`(?i)draft`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name     string
+		filter   EntryFilter
+		comment  string
+		expected bool
+	}{
+		{name: "対象の正規表現に一致", filter: EntryFilter{IncludeComment: include}, comment: "APPROVED 2024-04", expected: true},
+		{name: "対象の正規表現に不一致", filter: EntryFilter{IncludeComment: include}, comment: "pending", expected: false},
+		{name: "コメントなしは対象外", filter: EntryFilter{IncludeComment: include}, comment: "", expected: false},
+		{name: "除外の正規表現に一致", filter: EntryFilter{ExcludeComment: exclude}, comment: "Draft v2", expected: false},
+		{name: "コメントなしは除外しない", filter: EntryFilter{ExcludeComment: exclude}, comment: "", expected: true},
+		{name: "両方を指定", filter: EntryFilter{IncludeComment: include, ExcludeComment: exclude}, comment: "APPROVED (draft)", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(FileEntry{Name: "a/b.txt", Comment: tt.comment}); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if re, err := ParseCommentPattern(""); re != nil || err != nil {
+		t.Errorf("ParseCommentPattern(\"\") = %v, %v, want nil, nil", re, err)
+	}
+	if _, err := ParseCommentPattern("(unclosed"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+// count の -include-comment と -exclude-comment のテスト
+func TestRunCLICommentFilter(t *testing.T) {
+	zipPath := obuziptest.New().
+		CommentedFile("a/1.pdf", "APPROVED by vendor").
+		CommentedFile("a/2.pdf", "APPROVED draft").
+		CommentedFile("b/3.pdf", "pending").
+		Files("b/4.pdf").
+		WriteFile(t)
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		want         []obuziptest.FolderCount
+	}{
+		{name: "コメントで対象を絞り込む", args: []string{"-include-comment", "^APPROVED"}, want: []obuziptest.FolderCount{{Path: "a", Count: 2}}},
+		{name: "コメントで除外する", args: []string{"-exclude-comment", "(?i)draft|pending"}, want: []obuziptest.FolderCount{{Path: "a", Count: 1}, {Path: "b", Count: 1}}},
+		{name: "両方を指定", args: []string{"-include-comment", "APPROVED", "-exclude-comment", "draft"}, want: []obuziptest.FolderCount{{Path: "a", Count: 1}}},
+		{name: "不正な正規表現", args: []string{"-include-comment", "(unclosed"}, expectedCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"count", "-zip", zipPath, "-threshold", "1", "-format", "json"}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if tt.expectedCode != 0 {
+				return
+			}
+			report, err := obuziptest.ReadJSONReport(stdout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obuziptest.AssertFolderCounts(t, report.Folders, tt.want)
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity は検出事項の重要度です。
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarn
+	SeverityError
+)
+
+// String は重要度の表示名 (INFO, WARN, ERROR) を返します。
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarn:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// MarshalText は JSON などで重要度を表示名で出力するための実装です。
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText は表示名から重要度を復元します。
+func (s *Severity) UnmarshalText(text []byte) error {
+	for _, v := range []Severity{SeverityInfo, SeverityWarn, SeverityError} {
+		if v.String() == string(text) {
+			*s = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity: %s", text)
+}
+
+// 検出事項の規則IDです。
+const (
+	// RuleThreshold はファイル数がしきい値以上のフォルダです。集計結果の各行に対応します。
+	RuleThreshold = "threshold"
+	// RulePathLength は出力先のパスの長さの上限を超えるフォルダです。
+	RulePathLength = "path-length"
+	// RuleFailIfOver は -fail-if-over の上限を超えるフォルダです。
+	RuleFailIfOver = "fail-if-over"
+	// RuleFailIfEmpty は -fail-if-empty の指定時に集計対象のファイルが無いことです。
+	RuleFailIfEmpty = "fail-if-empty"
+	// RulePartial は時間の上限で読み込みを打ち切ったため、結果が推定値であることです。
+	RulePartial = "partial"
+	// RuleExpect は -expect の仕様で期待するファイル数の範囲を外れるフォルダです。
+	RuleExpect = "expect"
+	// RuleExecutable は -flag-executables で報告する実行ファイルやスクリプトです。
+	RuleExecutable = "executable"
+	// RuleSymlink はファイル数に含めないシンボリックリンクと特殊なエントリです。
+	RuleSymlink = "symlink"
+	// RuleDuplicate は中央ディレクトリに複数回現れるエントリ名です。
+	RuleDuplicate = "duplicate"
+	// RuleControl は -control の管理ファイルとファイル数が一致しないフォルダと、記載の無いフォルダです。
+	RuleControl = "control"
+	// RuleNaming は -naming-rule の命名規則を満たさないフォルダです。
+	RuleNaming = "naming"
+)
+
+// ruleDescriptions は規則IDごとの説明です。SARIFの規則の定義にも使います。
+var ruleDescriptions = map[string]string{
+	RuleThreshold:   "Folder file count is at or above the threshold.",
+	RulePathLength:  "Folder path exceeds the maximum path length of the destination.",
+	RuleFailIfOver:  "Folder file count exceeds the -fail-if-over limit.",
+	RuleFailIfEmpty: "Archive has no files to count.",
+	RulePartial:     "Scan stopped at the time budget or sampled entries; counts are extrapolated estimates.",
+	RuleExpect:      "Folder file count is outside the range declared in the expectation spec.",
+	RuleExecutable:  "Archive contains an executable or script that needs security review.",
+	RuleSymlink:     "Archive contains symlinks or special entries that are not counted as regular files.",
+	RuleDuplicate:   "Archive contains several entries with the same name, such as overwritten files.",
+	RuleNaming:      "Folder path does not match the required naming convention.",
+	RuleControl:     "Folder file count does not match the control file shipped in the archive.",
+}
+
+// Finding はフォルダまたはアーカイブ全体についての検出事項です。
+// すべての出力形式と終了コードは同じ検出事項から決まります。
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	// Folder は対象のフォルダです。アーカイブ全体についての検出事項では空です。
+	Folder  string `json:"folder,omitempty"`
+	Message string `json:"message"`
+}
+
+// FindingsOptions は検出事項を求めるための設定です。
+type FindingsOptions struct {
+	Threshold int
+	// ThresholdRules はフォルダパスのパターンごとのしきい値です。メッセージにはフォルダに適用したしきい値を示します。
+	ThresholdRules []ThresholdRule
+	// ThresholdPercent は全体のファイル数に対する割合のしきい値です。0より大きい場合はメッセージに示します。
+	ThresholdPercent float64
+	CSV              CSVOptions
+	Policy           ResultPolicy
+	// PolicyFolders は Policy の判定に使う、しきい値で絞り込む前のフォルダです。
+	PolicyFolders []FolderCount
+	// Expectations は納品仕様で期待するフォルダとファイル数の範囲です。
+	Expectations []FolderExpectation
+	// ExpectFolders は Expectations の判定に使う、しきい値で絞り込む前のすべてのフォルダです。
+	ExpectFolders []FolderCount
+	// Flagged はセキュリティの確認のために報告する実行ファイルやスクリプトです。
+	Flagged []FlaggedEntry
+	// FailOnFlagged が true の場合、Flagged を ERROR として報告します。
+	FailOnFlagged bool
+	// NamingViolations は命名規則に違反するフォルダです。
+	NamingViolations []NamingViolation
+	// FailOnNaming が true の場合、NamingViolations を ERROR として報告します。
+	FailOnNaming bool
+	// ControlFile はアーカイブ内の管理ファイルの名前です。空の場合は照合しません。
+	ControlFile string
+	// Control は管理ファイルに記載されたフォルダと期待するファイル数です。
+	Control []ControlEntry
+	// ControlMissing は管理ファイルがアーカイブ内に無かったことを表します。
+	ControlMissing bool
+	// ControlFolders は Control の照合に使う、管理ファイル自体を除いたすべてのフォルダです。
+	ControlFolders []FolderCount
+	// Special はシンボリックリンクと特殊なエントリです。
+	Special []SpecialEntry
+	// ReportSpecial が true の場合、Special を1件ずつ報告します。それ以外は集計から除いた件数だけを報告します。
+	ReportSpecial bool
+	// FollowSymlinks はシンボリックリンクをファイルとして集計したかを表します。
+	FollowSymlinks bool
+	// Duplicates は中央ディレクトリに複数回現れるエントリ名です。
+	Duplicates []DuplicateEntry
+	// Dedupe は Duplicates を集計した方法です。
+	Dedupe DedupePolicy
+}
+
+// BuildFindings は集計結果から検出事項を求めます。(純粋関数)
+// 結果は重要度の降順、同じ場合はフォルダ、規則IDの昇順でソートされます。
+func BuildFindings(report *Report, opts FindingsOptions) []Finding {
+	var findings []Finding
+	if p := report.Partial; p != nil {
+		message := fmt.Sprintf("scanned %d of %d entries within %s", p.ScannedEntries, p.TotalEntries, p.Budget)
+		if p.SamplePercent > 0 {
+			message = fmt.Sprintf("sampled %d files at %g%% of %d scanned entries", p.SampledFiles, p.SamplePercent, p.ScannedEntries)
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityWarn,
+			Rule:     RulePartial,
+			Message:  message,
+		})
+	}
+	thresholds := AggregateOptions{Threshold: opts.Threshold, ThresholdRules: opts.ThresholdRules}
+	for _, r := range report.Folders {
+		if r.OtherFolders > 0 {
+			continue
+		}
+		threshold := fmt.Sprint(thresholds.FolderThreshold(r.Path))
+		if opts.ThresholdPercent > 0 {
+			threshold += fmt.Sprintf(", %g%% of total", opts.ThresholdPercent)
+		}
+		message := fmt.Sprintf("%d files (threshold %s)", r.Count, threshold)
+		if report.Partial != nil {
+			message = fmt.Sprintf("%d files scanned, estimated %s (threshold %s)", r.Count, r.estimateText(), threshold)
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityInfo,
+			Rule:     RuleThreshold,
+			Folder:   r.Path,
+			Message:  message,
+		})
+		if opts.CSV.MaxPathLength > 0 {
+			p := r.Path
+			if opts.CSV.PathSeparator != "" {
+				p = strings.ReplaceAll(p, "/", opts.CSV.PathSeparator)
+			}
+			if n := prefixedPathLength(opts.CSV.PathPrefix, p, r.isRoot()); n > opts.CSV.MaxPathLength {
+				findings = append(findings, Finding{
+					Severity: SeverityWarn,
+					Rule:     RulePathLength,
+					Folder:   r.Path,
+					Message:  fmt.Sprintf("path length %d exceeds %d", n, opts.CSV.MaxPathLength),
+				})
+			}
+		}
+	}
+	findings = append(findings, opts.Policy.Findings(opts.PolicyFolders, report.TotalFiles)...)
+	findings = append(findings, ExpectationFindings(opts.Expectations, opts.ExpectFolders)...)
+	findings = append(findings, FlaggedFindings(opts.Flagged, opts.FailOnFlagged)...)
+	findings = append(findings, NamingFindings(opts.NamingViolations, opts.FailOnNaming)...)
+	switch {
+	case opts.ControlFile == "":
+	case opts.ControlMissing:
+		findings = append(findings, Finding{Severity: SeverityError, Rule: RuleControl, Message: "control file is missing: " + opts.ControlFile})
+	default:
+		findings = append(findings, ControlFindings(opts.Control, opts.ControlFolders)...)
+	}
+	findings = append(findings, SpecialEntryFindings(opts.Special, opts.ReportSpecial, opts.FollowSymlinks)...)
+	findings = append(findings, DuplicateFindings(opts.Duplicates, opts.Dedupe)...)
+	SortFindings(findings)
+	return findings
+}
+
+// SortFindings は検出事項を重要度の降順、同じ場合はフォルダ、規則IDの昇順に並べ替えます。
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Folder != b.Folder {
+			return a.Folder < b.Folder
+		}
+		return a.Rule < b.Rule
+	})
+}
+
+// folderSeverities はフォルダごとの最も高い重要度を返します。(純粋関数)
+func folderSeverities(findings []Finding) map[string]Severity {
+	m := make(map[string]Severity)
+	for _, f := range findings {
+		if f.Folder != "" && f.Severity > m[f.Folder] {
+			m[f.Folder] = f.Severity
+		}
+	}
+	return m
+}
+
+// notableFindings は重要度が WARN 以上の検出事項を返します。(純粋関数)
+// INFO の検出事項は集計結果の各行と同じ内容のため、表とは別に表示する必要はありません。
+func notableFindings(findings []Finding) []Finding {
+	var notable []Finding
+	for _, f := range findings {
+		if f.Severity >= SeverityWarn {
+			notable = append(notable, f)
+		}
+	}
+	return notable
+}
+
+// ansiColors は端末出力で重要度ごとに使う色です。
+var ansiColors = map[Severity]string{
+	SeverityWarn:  "\x1b[33m",
+	SeverityError: "\x1b[31m",
+}
+
+// colorize は重要度に応じた色で文字列を囲みます。色を使わない場合や INFO の場合はそのまま返します。(純粋関数)
+func colorize(s string, sev Severity, color bool) string {
+	code, ok := ansiColors[sev]
+	if !color || !ok {
+		return s
+	}
+	return code + s + "\x1b[0m"
+}
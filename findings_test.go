@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// BuildFindings のテスト
+func TestBuildFindings(t *testing.T) {
+	report := &Report{TotalFiles: 30, Folders: []FolderCount{
+		{Path: "short", Count: 10},
+		{Path: strings.Repeat("x", 20), Count: 20},
+	}}
+	findings := BuildFindings(report, FindingsOptions{
+		Threshold:     10,
+		CSV:           CSVOptions{PathPrefix: "sites/", MaxPathLength: 15},
+		Policy:        ResultPolicy{FailIfOver: 15},
+		PolicyFolders: report.Folders,
+	})
+
+	expected := []Finding{
+		{Severity: SeverityError, Rule: RuleFailIfOver, Folder: strings.Repeat("x", 20), Message: "20 files exceed 15"},
+		{Severity: SeverityWarn, Rule: RulePathLength, Folder: strings.Repeat("x", 20), Message: "path length 26 exceeds 15"},
+		{Severity: SeverityInfo, Rule: RuleThreshold, Folder: "short", Message: "10 files (threshold 10)"},
+		{Severity: SeverityInfo, Rule: RuleThreshold, Folder: strings.Repeat("x", 20), Message: "20 files (threshold 10)"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %+v", len(expected), findings)
+	}
+	for i := range expected {
+		if findings[i] != expected[i] {
+			t.Errorf("finding %d: expected %+v, got %+v", i, expected[i], findings[i])
+		}
+	}
+	if err := PolicyError(findings); err == nil || err.(*PolicyViolation).Code != exitOverLimit {
+		t.Errorf("expected over-limit violation, got %v", err)
+	}
+}
+
+// 検出事項の出力形式ごとのテスト
+func TestFindingsOutput(t *testing.T) {
+	report := &Report{Archive: "a.zip", TotalFiles: 3, Folders: []FolderCount{{Path: "dir1", Count: 3}}, Findings: []Finding{
+		{Severity: SeverityError, Rule: RuleFailIfOver, Folder: "dir1", Message: "3 files exceed 2"},
+		{Severity: SeverityInfo, Rule: RuleThreshold, Folder: "dir1", Message: "3 files (threshold 1)"},
+	}}
+
+	t.Run("テキストの色付け", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := writeText(out, report, true, ""); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "\x1b[31mdir1") || !strings.Contains(out.String(), "ERROR fail-if-over dir1: 3 files exceed 2") {
+			t.Errorf("unexpected output: %q", out.String())
+		}
+		out.Reset()
+		if err := WriteText(out, report); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out.String(), "\x1b[") {
+			t.Errorf("expected no color, got %q", out.String())
+		}
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteMarkdown(out, report); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "- **ERROR** `fail-if-over` dir1: 3 files exceed 2\n") || strings.Contains(out.String(), "threshold 1") {
+			t.Errorf("unexpected output: %q", out.String())
+		}
+	})
+
+	t.Run("SARIF", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteSARIF(out, report); err != nil {
+			t.Fatal(err)
+		}
+		var log sarifLog
+		if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+		if log.Version != "2.1.0" || len(log.Runs) != 1 {
+			t.Fatalf("unexpected log: %+v", log)
+		}
+		run := log.Runs[0]
+		if len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+			t.Fatalf("unexpected run: %+v", run)
+		}
+		r := run.Results[0]
+		if r.Level != "error" || r.RuleID != RuleFailIfOver || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "a.zip" ||
+			r.Locations[0].LogicalLocations[0].FullyQualifiedName != "dir1" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+		if run.Results[1].Level != "note" {
+			t.Errorf("expected note, got %s", run.Results[1].Level)
+		}
+	})
+}
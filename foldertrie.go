@@ -0,0 +1,130 @@
+package main
+
+import "strings"
+
+// folderTrie はフォルダごとの集計途中の値を、パスの階層ごとの木で保持します。
+// 深い階層のアーカイブではフォルダパスの多くが長い上位のパスを共有するため、パス全体を文字列のキーで持つよりメモリを抑えられます。
+// 各階層の名前は木の中で1つの文字列を共有し (intern)、エントリ名の文字列も参照しません。
+type folderTrie struct {
+	// top は最上位のフォルダを子に持つ、パスを持たない節です。
+	top folderNode
+	// names は階層の名前の文字列です。同じ名前の節はこの文字列を共有します。
+	names map[string]string
+	// last は直前に求めた節です。同じフォルダのエントリは続けて並ぶことが多いため、木をたどる処理を省きます。
+	lastKey string
+	last    *folderNode
+}
+
+// folderNode はフォルダパスの1つの階層です。count が0の節は、配下のフォルダのパスのためだけにある節です。
+type folderNode struct {
+	parent   *folderNode
+	name     string
+	children map[string]*folderNode
+	count    int
+	stats    *FolderStats
+}
+
+func newFolderTrie() *folderTrie {
+	return &folderTrie{names: make(map[string]string)}
+}
+
+// empty は節が1つもないかを表します。
+func (t *folderTrie) empty() bool {
+	return len(t.top.children) == 0
+}
+
+// node は集計キーのフォルダの節を返し、なければ作成します。2つ目の戻り値は新しく確保したおおよそのバイト数です。
+func (t *folderTrie) node(key string) (*folderNode, uint64) {
+	if t.last != nil && key == t.lastKey {
+		return t.last, 0
+	}
+	n, added := &t.top, uint64(0)
+	rest := key
+	if t.last != nil {
+		// 直前のフォルダと共通する上位の階層から、残りの階層だけをたどる
+		if b := commonFolderPrefix(key, t.lastKey); b >= 0 {
+			n = t.last
+			for range strings.Count(t.lastKey[b:], "/") {
+				n = n.parent
+			}
+			rest = key[b+1:]
+		}
+	}
+	for {
+		name, tail, more := strings.Cut(rest, "/")
+		child, ok := n.children[name]
+		if !ok {
+			interned, ok := t.names[name]
+			if !ok {
+				interned = strings.Clone(name)
+				t.names[interned] = interned
+				added += uint64(len(interned))
+			}
+			if n.children == nil {
+				n.children = make(map[string]*folderNode)
+			}
+			child = &folderNode{parent: n, name: interned}
+			n.children[interned] = child
+			added += folderEntryOverhead
+		}
+		n = child
+		if !more {
+			break
+		}
+		rest = tail
+	}
+	t.lastKey, t.last = key, n
+	return n, added
+}
+
+// commonFolderPrefix は key と last が共有する上位のフォルダパスの長さを返します。
+// key[:n] が last の上位の階層 (または last 自身) で、key[n] が区切り文字になる最大の n です。共有しない場合は -1 を返します。(純粋関数)
+func commonFolderPrefix(key, last string) int {
+	c := 0
+	for c < len(key) && c < len(last) && key[c] == last[c] {
+		c++
+	}
+	if c == len(last) && c < len(key) && key[c] == '/' {
+		return c
+	}
+	return strings.LastIndexByte(key[:c], '/')
+}
+
+// find は集計キーのフォルダの節を返します。ない場合は nil を返します。
+func (t *folderTrie) find(key string) *folderNode {
+	n := &t.top
+	for _, name := range strings.Split(key, "/") {
+		if n = n.children[name]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// isRoot は節がルートフォルダ "(Root)" かを表します。
+func (t *folderTrie) isRoot(n *folderNode) bool {
+	return n.parent == &t.top && n.name == "(Root)"
+}
+
+// isTop は節が最上位のフォルダかを表します。parentFolder と同じく、最上位のフォルダの親は "(Root)" です。
+func (t *folderTrie) isTop(n *folderNode) bool {
+	return n.parent == &t.top
+}
+
+// walk は件数を持つすべての節を、集計キーのフォルダパスとともに fn に渡します。順序は決まっていません。
+func (t *folderTrie) walk(fn func(key string, n *folderNode)) {
+	var visit func(prefix string, n *folderNode)
+	visit = func(prefix string, n *folderNode) {
+		for name, child := range n.children {
+			key := name
+			if n != &t.top {
+				key = prefix + "/" + name
+			}
+			if child.count > 0 {
+				fn(key, child)
+			}
+			visit(key, child)
+		}
+	}
+	visit("", &t.top)
+}
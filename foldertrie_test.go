@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// folderTrie が集計キーを区切り文字の位置を含めてそのまま復元することのテスト
+func TestFolderTrie(t *testing.T) {
+	keys := []string{"(Root)", "a", "a/b", "a/b/c", "a b", "x/y", "/lead", "a//b", "", "(Root)/x"}
+	trie := newFolderTrie()
+	for i, k := range keys {
+		n, _ := trie.node(k)
+		n.count = i + 1
+	}
+	got := map[string]int{}
+	trie.walk(func(k string, n *folderNode) {
+		got[k] = n.count
+	})
+	expected := map[string]int{}
+	for i, k := range keys {
+		expected[k] = i + 1
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("walk() = %v, want %v", got, expected)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		isRoot bool
+		isTop  bool
+	}{
+		{name: "ルート", key: "(Root)", isRoot: true, isTop: true},
+		{name: "最上位のフォルダ", key: "a", isTop: true},
+		{name: "下位のフォルダ", key: "a/b/c"},
+		{name: "ルートと同じ名前の下位のフォルダ", key: "(Root)/x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := trie.find(tt.key)
+			if n == nil {
+				t.Fatalf("find(%q) = nil", tt.key)
+			}
+			if trie.isRoot(n) != tt.isRoot || trie.isTop(n) != tt.isTop {
+				t.Errorf("isRoot() = %v, isTop() = %v, want %v, %v", trie.isRoot(n), trie.isTop(n), tt.isRoot, tt.isTop)
+			}
+		})
+	}
+	if n := trie.find("x"); n == nil || n.count != 0 {
+		t.Errorf("intermediate folder x should exist without count, got %+v", n)
+	}
+	if n := trie.find("a/b/d"); n != nil {
+		t.Errorf("find(a/b/d) = %+v, want nil", n)
+	}
+	if _, added := trie.node("a/b"); added != 0 {
+		t.Errorf("existing folder should not add memory, got %d", added)
+	}
+}
+
+// commonFolderPrefix のテスト
+func TestCommonFolderPrefix(t *testing.T) {
+	tests := []struct {
+		key, last string
+		expected  int
+	}{
+		{key: "a/b/c", last: "a/b", expected: 3},
+		{key: "a/b/d", last: "a/b/c", expected: 3},
+		{key: "a/bc", last: "a/b", expected: 1},
+		{key: "a/b", last: "a/b/c", expected: 1},
+		{key: "x/y", last: "a/b", expected: -1},
+		{key: "a//c", last: "a//b", expected: 2},
+		{key: "a", last: "a/b", expected: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.last, func(t *testing.T) {
+			if got := commonFolderPrefix(tt.key, tt.last); got != tt.expected {
+				t.Errorf("commonFolderPrefix(%q, %q) = %d, want %d", tt.key, tt.last, got, tt.expected)
+			}
+		})
+	}
+}
+
+// 深い階層のフォルダパスの一覧です。上位の階層を共有し、同じ名前の階層を多く含みます。
+func deepFolderKeys() []string {
+	var keys []string
+	for top := 0; top < 20; top++ {
+		p := fmt.Sprintf("projects/department-%02d", top)
+		for depth := 0; depth < 40; depth++ {
+			p += fmt.Sprintf("/archive-%04d/documents", 2000+depth%25)
+			for leaf := 0; leaf < 5; leaf++ {
+				keys = append(keys, fmt.Sprintf("%s/scans-%d", p, leaf))
+			}
+		}
+	}
+	return keys
+}
+
+// retainedHeap は fn が返した値を保持したままのヒープの増加量を返します。
+func retainedHeap(fn func() any) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := fn()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(v)
+	return after.HeapAlloc - min(before.HeapAlloc, after.HeapAlloc)
+}
+
+// 深い階層のアーカイブでの、フォルダパス全体をキーにしたマップと folderTrie のメモリ使用量の比較
+func BenchmarkFolderStorage(b *testing.B) {
+	keys := deepFolderKeys()
+	// エントリ名はファイル名を含むため、集計キーはそれぞれ別の文字列になる
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k + "/file.pdf"
+	}
+	sort.Strings(names)
+
+	b.Run("パス全体のマップ", func(b *testing.B) {
+		var retained uint64
+		for i := 0; i < b.N; i++ {
+			retained = retainedHeap(func() any {
+				counts := make(map[string]int)
+				for _, name := range names {
+					counts[strings.Clone(FolderKey(name, AggregateOptions{}))]++
+				}
+				return counts
+			})
+		}
+		b.ReportMetric(float64(retained), "retained-B")
+	})
+	b.Run("木", func(b *testing.B) {
+		var retained uint64
+		for i := 0; i < b.N; i++ {
+			retained = retainedHeap(func() any {
+				acc := NewAccumulator(AggregateOptions{Threshold: 1})
+				for _, name := range names {
+					acc.Add(FileEntry{Name: name})
+				}
+				return acc
+			})
+		}
+		b.ReportMetric(float64(retained), "retained-B")
+	})
+}
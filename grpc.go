@@ -0,0 +1,172 @@
+package main
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=go-ObuZipCount --go-grpc_out=. --go-grpc_opt=module=go-ObuZipCount proto/obuzipcount/v1/counter.proto
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+
+	"go-ObuZipCount/countpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countServer は Counter gRPCサービスの実装です。
+type countServer struct {
+	countpb.UnimplementedCounterServer
+	app              *App
+	defaultThreshold int
+	// root はリクエストで指定できるアーカイブを置くフォルダです。
+	root string
+}
+
+// NewGRPCServer は集計用のgRPCサーバーを作成します。リクエストのパスには root からの相対パスを指定します。
+func NewGRPCServer(app *App, defaultThreshold int, root string) *grpc.Server {
+	s := grpc.NewServer()
+	countpb.RegisterCounterServer(s, &countServer{app: app, defaultThreshold: defaultThreshold, root: root})
+	return s
+}
+
+// serveGRPC は addr で待ち受け、gRPCサーバーを実行します。
+func serveGRPC(s *grpc.Server, addr string, logger *slog.Logger) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	logger.Info("gRPCサービスを開始します", slog.String("listen", addr))
+	return s.Serve(lis)
+}
+
+func (s *countServer) CountArchive(ctx context.Context, req *countpb.CountRequest) (*countpb.CountResponse, error) {
+	results, totalFiles, err := s.count(req)
+	if err != nil {
+		return nil, err
+	}
+	res := &countpb.CountResponse{Archive: req.GetZipPath(), TotalFiles: int64(totalFiles)}
+	for _, r := range results {
+		res.Folders = append(res.Folders, &countpb.FolderCount{Path: FolderFormat{}.Folder(r.Path), Count: int64(r.Count)})
+	}
+	return res, nil
+}
+
+func (s *countServer) StreamFolderCounts(req *countpb.CountRequest, stream countpb.Counter_StreamFolderCountsServer) error {
+	results, _, err := s.count(req)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := stream.Send(&countpb.FolderCount{Path: FolderFormat{}.Folder(r.Path), Count: int64(r.Count)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *countServer) DiffArchives(ctx context.Context, req *countpb.DiffRequest) (*countpb.DiffResponse, error) {
+	if req.GetOldZipPath() == "" || req.GetNewZipPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "old and new zip paths are required")
+	}
+	opts, err := grpcAggregateOptions(0, "", req.GetNormalize())
+	if err != nil {
+		return nil, err
+	}
+	oldPath, err := s.resolve(req.GetOldZipPath())
+	if err != nil {
+		return nil, err
+	}
+	newPath, err := s.resolve(req.GetNewZipPath())
+	if err != nil {
+		return nil, err
+	}
+	oldResults, _, err := s.app.Analyze(oldPath, opts)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	newResults, _, err := s.app.Analyze(newPath, opts)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &countpb.DiffResponse{}
+	for _, d := range DiffFolderCounts(oldResults, newResults, int(req.GetThreshold()), req.GetIncludeUnchanged()) {
+		res.Folders = append(res.Folders, &countpb.FolderDiff{
+			Path:  FolderFormat{}.Folder(d.Path),
+			Old:   int64(d.Old),
+			New:   int64(d.New),
+			Delta: int64(d.Delta),
+		})
+	}
+	return res, nil
+}
+
+// count はリクエストに従ってアーカイブを集計します。
+func (s *countServer) count(req *countpb.CountRequest) ([]FolderCount, int, error) {
+	if req.GetZipPath() == "" {
+		return nil, 0, status.Error(codes.InvalidArgument, "zip path is required")
+	}
+	threshold := s.defaultThreshold
+	if req.Threshold != nil {
+		threshold = int(req.GetThreshold())
+	}
+	opts, err := grpcAggregateOptions(threshold, req.GetSegments(), req.GetNormalize())
+	if err != nil {
+		return nil, 0, err
+	}
+	zipPath, err := s.resolve(req.GetZipPath())
+	if err != nil {
+		return nil, 0, err
+	}
+	results, totalFiles, err := s.app.Analyze(zipPath, opts)
+	if err != nil {
+		return nil, 0, grpcError(err)
+	}
+	return results, totalFiles, nil
+}
+
+// resolve はリクエストのパスを root 配下のパスに変換します。root の外を指すパスは InvalidArgument にします。
+func (s *countServer) resolve(p string) (string, error) {
+	resolved, err := resolveServePath(s.root, p)
+	if errors.Is(err, ErrOutsideRoot) {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	return resolved, nil
+}
+
+// grpcAggregateOptions はリクエストの値から集計オプションを組み立てます。
+// 正規化の方式は省略時に nfc とし、HTTPの /count と同じ既定値にそろえます。
+func grpcAggregateOptions(threshold int, segments, normalization string) (AggregateOptions, error) {
+	if normalization == "" {
+		normalization = "nfc"
+	}
+	segmentRange, err := ParseSegmentRange(segments)
+	if err != nil {
+		return AggregateOptions{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	cfg := AppConfig{Threshold: threshold, Segments: segmentRange, Normalization: normalization}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return AggregateOptions{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return opts, nil
+}
+
+// grpcError は集計時のエラーをgRPCのステータスに変換します。
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrArchiveNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrArchivePermission):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrArchiveIsDir), errors.Is(err, ErrArchiveEmpty), errors.Is(err, ErrNotZip):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
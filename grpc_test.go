@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"go-ObuZipCount/countpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// Counter gRPCサービスのテスト
+func TestGRPCCounter(t *testing.T) {
+	app := &App{
+		Reader: MockArchiveReader{Entries: []FileEntry{{Name: "dir1/a.txt"}, {Name: "dir1/b.txt"}, {Name: "c.txt"}}},
+		Logger: slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+	}
+	lis := bufconn.Listen(1 << 20)
+	s := NewGRPCServer(app, 2, t.TempDir())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := countpb.NewCounterClient(conn)
+	ctx := context.Background()
+
+	t.Run("CountArchive (既定のしきい値)", func(t *testing.T) {
+		res, err := client.CountArchive(ctx, &countpb.CountRequest{ZipPath: "a.zip"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.GetTotalFiles() != 3 || len(res.GetFolders()) != 1 || res.GetFolders()[0].GetPath() != "dir1" {
+			t.Errorf("unexpected response: %v", res)
+		}
+	})
+
+	t.Run("StreamFolderCounts", func(t *testing.T) {
+		stream, err := client.StreamFolderCounts(ctx, &countpb.CountRequest{ZipPath: "a.zip", Threshold: proto.Int32(1)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var paths []string
+		for {
+			fc, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			paths = append(paths, fc.GetPath())
+		}
+		if len(paths) != 2 || paths[0] != "dir1" || paths[1] != "(Root)" {
+			t.Errorf("unexpected folders: %v", paths)
+		}
+	})
+
+	t.Run("DiffArchives", func(t *testing.T) {
+		res, err := client.DiffArchives(ctx, &countpb.DiffRequest{OldZipPath: "a.zip", NewZipPath: "b.zip", IncludeUnchanged: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.GetFolders()) != 2 || res.GetFolders()[0].GetDelta() != 0 {
+			t.Errorf("unexpected response: %v", res)
+		}
+	})
+
+	t.Run("不正なリクエスト", func(t *testing.T) {
+		_, err := client.CountArchive(ctx, &countpb.CountRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument, got %v", err)
+		}
+		_, err = client.CountArchive(ctx, &countpb.CountRequest{ZipPath: "a.zip", Segments: "x"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument, got %v", err)
+		}
+		_, err = client.CountArchive(ctx, &countpb.CountRequest{ZipPath: "../a.zip"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for a path outside the root, got %v", err)
+		}
+		_, err = client.DiffArchives(ctx, &countpb.DiffRequest{OldZipPath: "a.zip", NewZipPath: "/tmp/b.zip"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for an absolute path, got %v", err)
+		}
+	})
+}
+
+// grpcError のテスト
+func TestGRPCError(t *testing.T) {
+	err := grpcError(&ArchiveOpenError{Err: ErrArchiveNotFound})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	if status.Code(grpcError(errors.New("boom"))) != codes.Internal {
+		t.Error("expected Internal for unknown error")
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Lang はログ、表の見出し、エラーのメッセージの言語です。
+// ゼロ値は言語を指定しないことを表し、従来どおりログは日本語、見出しとエラーは英語で出力します。
+type Lang string
+
+const (
+	LangJA Lang = "ja"
+	LangEN Lang = "en"
+)
+
+// ParseLang は -lang の値またはロケール名 (ja_JP.UTF-8 など) から言語を返します。(純粋関数)
+func ParseLang(s string) (Lang, error) {
+	s = strings.ToLower(s)
+	switch {
+	case s == "":
+		return "", nil
+	case s == "ja" || strings.HasPrefix(s, "ja_") || strings.HasPrefix(s, "ja."):
+		return LangJA, nil
+	case s == "en" || strings.HasPrefix(s, "en_") || strings.HasPrefix(s, "en."):
+		return LangEN, nil
+	}
+	return "", fmt.Errorf("unknown language: %s", s)
+}
+
+// langFromEnv は環境変数 LC_ALL、LC_MESSAGES、LANG の順に最初に設定されているロケールから言語を返します。(純粋関数)
+// ロケールが C または POSIX の場合と設定されていない場合は言語を指定しません。日本語以外のロケールは英語とします。
+func langFromEnv(getenv func(string) string) Lang {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := getenv(name)
+		if locale == "" {
+			continue
+		}
+		if base, _, _ := strings.Cut(locale, "."); base == "C" || base == "POSIX" {
+			return ""
+		}
+		if lang, err := ParseLang(locale); err == nil && lang == LangJA {
+			return LangJA
+		}
+		return LangEN
+	}
+	return ""
+}
+
+// englishMessages は日本語で書かれたログなどのメッセージの英訳です。
+var englishMessages = map[string]string{
+	"HTTPサービスを開始します":                  "starting HTTP service",
+	"ZIPファイルの解析を開始します":                "starting to analyze zip file",
+	"gRPCサービスを開始します":                  "starting gRPC service",
+	"このアーカイブではヘッダの構造は検査しません":          "header structure is not inspected for this archive",
+	"アプリケーションエラー":                     "application error",
+	"アーカイブの処理に失敗しました":                 "failed to process archive",
+	"エントリの一覧を出力しました":                  "wrote entry manifest",
+	"エントリの内容の検証が完了しました":               "finished verifying entry contents",
+	"エントリの内容の検証を開始します":                "starting to verify entry contents",
+	"エントリを読み込みました":                    "read entries",
+	"エントリ名を復号しました":                    "decoded entry name",
+	"エントリ名を復号できませんでした":                "could not decode entry name",
+	"オブジェクト数の上限を超えるフォルダがあります":         "some folders exceed the object quota",
+	"グラフを出力しました":                      "wrote chart",
+	"スケジュールの実行に失敗しました":                "scheduled run failed",
+	"スケジュールの実行を開始します":                 "starting scheduled run",
+	"ファイルを抽出して集計します":                  "counting a sample of files",
+	"フォルダの監視を開始します":                   "starting to watch folder",
+	"メモリの上限を超えたため、一時ファイルを使って集計しました":   "exceeded the memory limit and aggregated using temporary files",
+	"リクエストの処理に失敗しました":                 "failed to handle request",
+	"一時ファイルに書き出せなかったため、メモリ上で集計を続けました": "could not write temporary files and continued aggregating in memory",
+	"公開鍵が無いため、リリースの署名を検証しません":         "no public key is configured; release signature is not verified",
+	"受け入れフォルダの監視を開始します":               "starting to watch intake folder",
+	"受け入れフォルダを確認できませんでした":             "failed to check intake folder",
+	"内容による種類の判定が完了しました":               "finished content sniffing",
+	"内容による種類の判定を開始します":                "starting content sniffing",
+	"処理結果を通知しました":                     "sent result notification",
+	"処理結果を通知できませんでした":                 "failed to send result notification",
+	"古いレポートを削除しました":                   "deleted old reports",
+	"古いレポートを削除できませんでした":               "failed to delete old reports",
+	"古い実行記録を削除しました":                   "deleted old run records",
+	"同じ名前のエントリが複数あります":                "some entries share the same name",
+	"実行ファイルを更新しました":                   "updated executable",
+	"展開が完了しました":                       "finished extracting",
+	"展開できない圧縮方式のエントリがあります":            "some entries use compression methods that cannot be extracted",
+	"展開を開始します":                        "starting to extract",
+	"復号によってパスの階層が変わるエントリがあります":        "decoding changes the path depth of some entries",
+	"応答を書き出せませんでした":                   "failed to write response",
+	"時間の上限に達したため、読み込みを打ち切りました":        "stopped reading at the time budget",
+	"検証中":       "verifying",
+	"次の実行を待ちます": "waiting for next run",
+	"状態ファイルに記録できませんでした":   "failed to record archive in state file",
+	"監視中にエラーが発生しました":      "error while watching",
+	"管理ファイルがアーカイブ内にありません": "control file is not in the archive",
+	"結果の出力の処理時間":          "output time",
+	"結果をSQLiteに登録しました":    "saved results to SQLite",
+	"結果をファイルに出力しました":      "wrote results to file",
+	"詳細レポートをCSVに出力しました":   "wrote detail report to CSV",
+	"集計の処理時間":             "aggregation time",
+	"集計完了":                "aggregation finished",
+	"集計結果が終了条件に該当しました":    "result matched an exit condition",
+}
+
+// japaneseMessages は英語で書かれた表の見出しとエラーのメッセージの和訳です。
+// エラーは動的な値を含むため、": " より前の部分も訳します。
+var japaneseMessages = map[string]string{
+	"Folder Path":         "フォルダパス",
+	"File Count":          "ファイル数",
+	"Estimated":           "推定",
+	"% of Parent":         "親に対する割合",
+	"% of Total":          "全体に対する割合",
+	"Total Size":          "合計サイズ",
+	"Avg Size":            "平均サイズ",
+	"Largest File":        "最大のファイル",
+	"Largest Size":        "最大のサイズ",
+	"Findings":            "検出事項",
+	"Archive":             "アーカイブ",
+	"Category":            "分類",
+	"World-Writable":      "誰でも書き込み可",
+	"Entries":             "エントリ",
+	"Size":                "サイズ",
+	"Modified":            "更新日時",
+	"Comment":             "コメント",
+	"Encoding":            "文字コード",
+	"Files":               "ファイル",
+	"Folders":             "フォルダ",
+	"Objects":             "オブジェクト",
+	"Original":            "圧縮前",
+	"Compressed":          "圧縮後",
+	"Ratio":               "圧縮率",
+	"Flag":                "フラグ",
+	"Total":               "合計",
+	"Store":               "無圧縮",
+	"Unsupported":         "未対応",
+	"Min":                 "最小",
+	"Max":                 "最大",
+	"Avg":                 "平均",
+	"CV":                  "変動係数",
+	"Top-Level Folder":    "最上位のフォルダ",
+	"Quarter":             "四半期",
+	"Raw Path":            "復号前のパス",
+	"Decoded Path":        "復号後のパス",
+	"Count":               "件数",
+	"Old":                 "変更前",
+	"New":                 "変更後",
+	"Delta":               "増減",
+	"Change":              "変化",
+	"Max Change":          "最大の変化率",
+	"Trend":               "推移",
+	"runs":                "回",
+	"entries":             "エントリ",
+	"Status":              "状態",
+	"Attempts":            "試行回数",
+	"Reason":              "理由",
+	"Phase":               "段階",
+	"Alloc/Run":           "割り当て/回",
+	"Score":               "スコア",
+	"Grade":               "評価",
+	"Archive score":       "アーカイブのスコア",
+	"Matched":             "一致",
+	"Unexpected":          "想定外",
+	"Missing":             "欠落",
+	"Problems":            "問題",
+	"Unsupported entries": "未対応のエントリ",
+	"Unexpected folders":  "想定外のフォルダ",
+	"Missing folders":     "欠けているフォルダ",
+	"Structural problems": "構造上の問題",
+	"Name anomalies (break extraction on Windows)": "名前の問題 (Windowsで展開できません)",
+
+	"zip path is required":                         "ZIPファイルのパスを指定してください",
+	"unknown format":                               "不明な出力形式です",
+	"unknown language":                             "不明な言語です",
+	"unknown log format":                           "不明なログの形式です",
+	"unknown log level":                            "不明なログのレベルです",
+	"unknown profile":                              "不明なプロファイルです",
+	"unknown encoding":                             "不明な文字コードです",
+	"unknown normalization":                        "不明な正規化の方式です",
+	"invalid extension":                            "不正な拡張子です",
+	"unknown manifest format":                      "不明なマニフェストの形式です",
+	"invalid threshold rule":                       "しきい値の規則が不正です",
+	"invalid segment range":                        "階層の範囲が不正です",
+	"invalid size":                                 "サイズが不正です",
+	"failed to open zip":                           "ZIPファイルを開けません",
+	"failed to read zip":                           "ZIPファイルを読み込めません",
+	"read entries error":                           "エントリを読み込めません",
+	"failed to open split zip":                     "分割ZIPファイルを開けません",
+	"failed to open remote archive":                "リモートのアーカイブを開けません",
+	"failed to read remote archive":                "リモートのアーカイブを読み込めません",
+	"failed to spool stdin":                        "標準入力を一時ファイルに保存できません",
+	"failed to read config file":                   "設定ファイルを読み込めません",
+	"failed to open log file":                      "ログファイルを開けません",
+	"failed to create manifest file":               "マニフェストのファイルを作成できません",
+	"failed to write manifest":                     "マニフェストを出力できません",
+	"failed to open sqlite":                        "SQLiteのデータベースを開けません",
+	"failed to check release":                      "リリースを確認できません",
+	"failed to replace executable":                 "実行ファイルを置き換えられません",
+	"invalid release signature":                    "リリースの署名が不正です",
+	"verify is only supported for local zip files": "-verify はローカルのZIPファイルでのみ使えます",
+	"sniff is only supported for local zip files":  "-sniff はローカルのZIPファイルでのみ使えます",
+	"failed to read sources file":                  "受け入れフォルダの定義ファイルを読み込めません",
+	"failed to parse sources file":                 "受け入れフォルダの定義ファイルを解析できません",
+	"invalid sources file":                         "受け入れフォルダの定義ファイルが不正です",
+	"no sources are defined":                       "受け入れフォルダが定義されていません",
+	"source name is required":                      "受け入れフォルダの名前を指定してください",
+	"duplicate source name":                        "受け入れフォルダの名前が重複しています",
+	"dir and outDir are required":                  "dir と outDir を指定してください",
+	"threshold must not be negative":               "しきい値に負の値は指定できません",
+	"intake directory is used by another source":   "受け入れフォルダが他の定義と重複しています",
+	"report directory is used by another source":   "レポートの出力先が他の定義と重複しています",
+	"failed to list intake directory":              "受け入れフォルダの一覧を取得できません",
+	"cannot be combined with -sources":             "-sources と同時に指定できません",
+	"-poll-interval must be positive":              "-poll-interval には正の値を指定してください",
+	"invalid s3 path":                              "S3のパスが不正です",
+	"invalid s3 endpoint":                          "S3の接続先が不正です",
+	"failed to list s3 prefix":                     "S3のプレフィックスの一覧を取得できません",
+}
+
+// T はメッセージを言語に合わせて返します。カタログに無いメッセージと、言語を指定しない場合はそのまま返します。(純粋関数)
+func (l Lang) T(s string) string {
+	var catalog map[string]string
+	switch l {
+	case LangEN:
+		catalog = englishMessages
+	case LangJA:
+		catalog = japaneseMessages
+	default:
+		return s
+	}
+	if t, ok := catalog[s]; ok {
+		return t
+	}
+	return s
+}
+
+// Error はエラーのメッセージを言語に合わせて返します。(純粋関数)
+// 全体がカタログに無い場合は、ラップされたエラーの ": " で区切られた部分をそれぞれ訳します。
+func (l Lang) Error(msg string) string {
+	if t := l.T(msg); t != msg {
+		return t
+	}
+	segments := strings.Split(msg, ": ")
+	for i, s := range segments {
+		segments[i] = l.T(s)
+	}
+	return strings.Join(segments, ": ")
+}
+
+// localizedHandler はログのメッセージを言語に合わせて訳す slog.Handler です。
+type localizedHandler struct {
+	slog.Handler
+	lang Lang
+}
+
+func (h localizedHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Message = h.lang.T(r.Message)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h localizedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return localizedHandler{Handler: h.Handler.WithAttrs(attrs), lang: h.lang}
+}
+
+func (h localizedHandler) WithGroup(name string) slog.Handler {
+	return localizedHandler{Handler: h.Handler.WithGroup(name), lang: h.lang}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// langFromEnv のテスト
+func TestLangFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected Lang
+	}{
+		{name: "未設定", env: nil, expected: ""},
+		{name: "日本語", env: map[string]string{"LANG": "ja_JP.UTF-8"}, expected: LangJA},
+		{name: "英語", env: map[string]string{"LANG": "en_US.UTF-8"}, expected: LangEN},
+		{name: "日本語以外は英語", env: map[string]string{"LANG": "de_DE.UTF-8"}, expected: LangEN},
+		{name: "Cロケールは指定なし", env: map[string]string{"LANG": "C.UTF-8"}, expected: ""},
+		{name: "LC_ALL を優先", env: map[string]string{"LC_ALL": "en_US", "LANG": "ja_JP.UTF-8"}, expected: LangEN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := langFromEnv(func(k string) string { return tt.env[k] }); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Lang.T と Lang.Error のテスト
+func TestLangMessages(t *testing.T) {
+	if got := LangEN.T("集計完了"); got != "aggregation finished" {
+		t.Errorf("unexpected translation: %q", got)
+	}
+	if got := LangJA.T("Folder Path"); got != "フォルダパス" {
+		t.Errorf("unexpected translation: %q", got)
+	}
+	if got := Lang("").T("Folder Path"); got != "Folder Path" {
+		t.Errorf("expected untranslated message, got %q", got)
+	}
+	if got := LangJA.Error("failed to open zip: no such file"); got != "ZIPファイルを開けません: no such file" {
+		t.Errorf("unexpected translation: %q", got)
+	}
+	if got := LangJA.Error("read entries error: failed to open zip: no such file"); got != "エントリを読み込めません: ZIPファイルを開けません: no such file" {
+		t.Errorf("unexpected translation of wrapped error: %q", got)
+	}
+	// 受け入れフォルダの名前などの値を除いた部分を訳す
+	if got := LangJA.Error("invalid sources file: b: intake directory is used by another source: a"); got != "受け入れフォルダの定義ファイルが不正です: b: 受け入れフォルダが他の定義と重複しています: a" {
+		t.Errorf("unexpected translation of sources file error: %q", got)
+	}
+	if got := LangJA.Error("something else: detail"); got != "something else: detail" {
+		t.Errorf("expected untranslated message, got %q", got)
+	}
+	if _, err := ParseLang("fr"); err == nil {
+		t.Error("expected error for unknown language")
+	}
+}
+
+// -lang のテスト
+func TestRunCLILang(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt").WriteFile(t)
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if code := runCLI([]string{"count", "-lang", "en", "-zip", zipPath, "-threshold", "1"}, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "aggregation finished") || !strings.Contains(stdout.String(), "Folder Path") {
+		t.Errorf("expected English output, got stdout: %s stderr: %s", stdout.String(), stderr.String())
+	}
+
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+	if code := runCLI([]string{"count", "-lang", "ja", "-zip", zipPath, "-threshold", "1"}, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "集計完了") || !strings.Contains(stdout.String(), "フォルダパス") {
+		t.Errorf("expected Japanese output, got stdout: %s stderr: %s", stdout.String(), stderr.String())
+	}
+
+	// 集計以外のコマンドの表の見出しも訳す
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+	if code := runCLI([]string{"compression", "-lang", "ja", "-zip", zipPath}, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "圧縮前") || strings.Contains(stdout.String(), "Original") {
+		t.Errorf("expected Japanese headers, got stdout: %s", stdout.String())
+	}
+}
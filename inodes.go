@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubtreeObjects はフォルダ配下に展開されるオブジェクト (ファイルとフォルダ) の数です。
+type SubtreeObjects struct {
+	Path    string
+	Files   int
+	Folders int
+	// Objects は配下のファイルとフォルダの合計です。フォルダ自身は含みません。
+	Objects int
+	// OverQuota は Objects が上限を超えることを表します。
+	OverQuota bool
+}
+
+// ComputeSubtreeObjects はフォルダごとに配下のファイル数とフォルダ数を求めます。(純粋関数)
+// ディレクトリのエントリが無いフォルダも、ファイルのパスから展開時に作られるものとして数えます。
+// maxDepth が正の場合はその階層までのフォルダを、minObjects 以上のものだけ返します。
+// quota が正の場合、Objects が quota を超えるフォルダを OverQuota とします。
+// 結果はオブジェクト数の降順、同じ場合はパスの昇順でソートされます。
+func ComputeSubtreeObjects(entries []FileEntry, maxDepth, minObjects, quota int) []SubtreeObjects {
+	folders := map[string]bool{".": true}
+	var files []string
+	for _, e := range entries {
+		name := strings.Trim(e.Name, "/")
+		if name == "" {
+			continue
+		}
+		dir := path.Dir(name)
+		if e.IsDir {
+			dir = name
+		} else {
+			files = append(files, name)
+		}
+		for ; dir != "."; dir = path.Dir(dir) {
+			if folders[dir] {
+				break
+			}
+			folders[dir] = true
+		}
+	}
+
+	subtrees := make(map[string]*SubtreeObjects, len(folders))
+	for dir := range folders {
+		subtrees[dir] = &SubtreeObjects{Path: subtreeDisplayPath(dir)}
+	}
+	for _, f := range files {
+		for dir := path.Dir(f); ; dir = path.Dir(dir) {
+			subtrees[dir].Files++
+			if dir == "." {
+				break
+			}
+		}
+	}
+	for dir := range folders {
+		if dir == "." {
+			continue
+		}
+		for parent := path.Dir(dir); ; parent = path.Dir(parent) {
+			subtrees[parent].Folders++
+			if parent == "." {
+				break
+			}
+		}
+	}
+
+	var results []SubtreeObjects
+	for dir, st := range subtrees {
+		if maxDepth > 0 && dir != "." && strings.Count(dir, "/")+1 > maxDepth {
+			continue
+		}
+		st.Objects = st.Files + st.Folders
+		if st.Objects < minObjects {
+			continue
+		}
+		st.OverQuota = quota > 0 && st.Objects > quota
+		results = append(results, *st)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Objects == results[j].Objects {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].Objects > results[j].Objects
+	})
+	return results
+}
+
+// subtreeDisplayPath はフォルダパスを集計キーと同じ表記に変換します。(純粋関数)
+func subtreeDisplayPath(dir string) string {
+	if dir == "." {
+		return "(Root)"
+	}
+	return dir
+}
+
+// WriteSubtreeObjectsText はフォルダ配下のオブジェクト数をプレーンテキストでWriterに出力します。
+func WriteSubtreeObjectsText(w io.Writer, results []SubtreeObjects, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-60s | %8s | %8s | %8s | %s\n", lang.T("Folder Path"), lang.T("Files"), lang.T("Folders"), lang.T("Objects"), lang.T("Flag"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 105))
+	for _, r := range results {
+		_, err := fmt.Fprintf(w, "%-60s | %8d | %8d | %8d | %s\n", r.Path, r.Files, r.Folders, r.Objects, quotaFlag(r))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSubtreeObjectsCSV はフォルダ配下のオブジェクト数をCSV形式でWriterに出力します。
+func WriteSubtreeObjectsCSV(w io.Writer, results []SubtreeObjects, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Files", "Folders", "Objects", "Flag"}); err != nil {
+			return err
+		}
+		for _, r := range results {
+			record := []string{r.Path, strconv.Itoa(r.Files), strconv.Itoa(r.Folders), strconv.Itoa(r.Objects), quotaFlag(r)}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectSubtreeObjectsWriter は出力形式名に対応する書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectSubtreeObjectsWriter(format string, opts WriterOptions) (func(io.Writer, []SubtreeObjects) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, results []SubtreeObjects) error {
+			return WriteSubtreeObjectsText(w, results, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, results []SubtreeObjects) error {
+			return WriteSubtreeObjectsCSV(w, results, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func quotaFlag(r SubtreeObjects) string {
+	if r.OverQuota {
+		return "OVER QUOTA"
+	}
+	return ""
+}
+
+// runInodes は inodes コマンドを実行します。
+func runInodes(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "inodes", "inodes -zip <path> [-quota N] [options]")
+	var rf readerFlags
+	rf.register(fs)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	quota := fs.Int("quota", 0, "フォルダ配下のオブジェクト数の上限 (0は判定しない)")
+	depth := fs.Int("depth", 0, "出力するフォルダの階層の深さ (0はすべて)")
+	minObjects := fs.Int("threshold", 0, "出力するフォルダの配下のオブジェクト数の下限")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var paths FolderFormat
+	registerFolderFormatFlags(fs, &paths)
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectSubtreeObjectsWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	results := ComputeSubtreeObjects(entries, *depth, *minObjects, *quota)
+	for i := range results {
+		results[i].Path = paths.Folder(results[i].Path)
+	}
+	if err := write(env.Stdout, results); err != nil {
+		return err
+	}
+	over := 0
+	for _, r := range results {
+		if r.OverQuota {
+			over++
+		}
+	}
+	if over > 0 {
+		env.Logger.Warn("オブジェクト数の上限を超えるフォルダがあります", slog.Int("folders", over), slog.Int("quota", *quota))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// ComputeSubtreeObjects のテスト (暗黙のフォルダを含む配下のオブジェクト数)
+func TestComputeSubtreeObjects(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "a/b/c/1.txt"},
+		{Name: "a/b/2.txt"},
+		{Name: "a/3.txt"},
+		{Name: "a/empty/", IsDir: true},
+		{Name: "4.txt"},
+	}
+
+	result := ComputeSubtreeObjects(entries, 0, 0, 4)
+	expected := []SubtreeObjects{
+		{Path: "(Root)", Files: 4, Folders: 4, Objects: 8, OverQuota: true},
+		{Path: "a", Files: 3, Folders: 3, Objects: 6, OverQuota: true},
+		{Path: "a/b", Files: 2, Folders: 1, Objects: 3},
+		{Path: "a/b/c", Files: 1, Objects: 1},
+		{Path: "a/empty"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	t.Run("階層と下限で絞り込み", func(t *testing.T) {
+		result := ComputeSubtreeObjects(entries, 2, 1, 0)
+		expected := []SubtreeObjects{
+			{Path: "(Root)", Files: 4, Folders: 4, Objects: 8},
+			{Path: "a", Files: 3, Folders: 3, Objects: 6},
+			{Path: "a/b", Files: 2, Folders: 1, Objects: 3},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %+v, got %+v", expected, result)
+		}
+	})
+}
+
+// WriteSubtreeObjectsCSV のテスト (文字コードと区切り文字)
+func TestWriteSubtreeObjectsCSV(t *testing.T) {
+	results := []SubtreeObjects{{Path: "資料", Files: 3, Folders: 1, Objects: 4, OverQuota: true}}
+	buf := new(bytes.Buffer)
+	if err := WriteSubtreeObjectsCSV(buf, results, CSVOptions{Encoding: "cp932", Delimiter: "tab"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Folder Path\tFiles\tFolders\tObjects\tFlag\n\x8e\x91\x97\xbf\t3\t1\t4\tOVER QUOTA\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IntakeSource は serve -sources と watch -sources で監視する受け入れフォルダ1つ分の設定です。
+// しきい値と出力は受け入れフォルダごとに独立しています。
+type IntakeSource struct {
+	Name string `json:"name"`
+	// Dir は受け入れフォルダです。s3://<bucket>/<prefix> の場合はS3のプレフィックス直下を一定間隔で確認します。
+	Dir string `json:"dir"`
+	// OutDir はレポートを出力するフォルダです。レポートはアーカイブと同じ名前で形式ごとの拡張子を付けます。
+	OutDir string `json:"outDir"`
+	// Threshold はしきい値です。0の場合はコマンドの -threshold を使います。
+	Threshold int `json:"threshold"`
+	// Formats は出力する形式です。省略時は csv と json です。
+	Formats []string `json:"formats"`
+}
+
+// intakeFile は serve -sources と watch -sources で指定する受け入れフォルダの定義ファイルの内容です。
+//
+//	{"sources": [
+//	  {"name": "vendorA", "dir": "/intake/vendorA", "outDir": "/reports/vendorA", "threshold": 500},
+//	  {"name": "vendorB", "dir": "s3://intake-bucket/vendorB/", "outDir": "/reports/vendorB", "threshold": 100, "formats": ["csv"]}
+//	]}
+type intakeFile struct {
+	Sources []IntakeSource `json:"sources"`
+}
+
+// LoadIntakeSources は受け入れフォルダの定義ファイルを読み込み、検証します。
+func LoadIntakeSources(path string) ([]IntakeSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sources file: %w", err)
+	}
+	var f intakeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse sources file: %w", err)
+	}
+	if err := validateIntakeSources(f.Sources); err != nil {
+		return nil, fmt.Errorf("invalid sources file: %w", err)
+	}
+	return f.Sources, nil
+}
+
+// validateIntakeSources は受け入れフォルダの定義を検証し、省略された出力形式を補います。
+// 同じフォルダを複数の受け入れフォルダで監視したり、同じ出力先に書き出したりする定義はエラーにします。
+// エラーは言語に合わせて訳せるよう、受け入れフォルダの名前などの値を ": " で区切って含めます。
+func validateIntakeSources(sources []IntakeSource) error {
+	if len(sources) == 0 {
+		return errors.New("no sources are defined")
+	}
+	names := make(map[string]bool)
+	dirs, outDirs := make(map[string]string), make(map[string]string)
+	for i := range sources {
+		s := &sources[i]
+		switch {
+		case s.Name == "":
+			return fmt.Errorf("source name is required: entry %d", i+1)
+		case names[s.Name]:
+			return fmt.Errorf("duplicate source name: %s", s.Name)
+		case s.Dir == "" || s.OutDir == "":
+			return fmt.Errorf("%s: dir and outDir are required", s.Name)
+		case s.Threshold < 0:
+			return fmt.Errorf("%s: threshold must not be negative", s.Name)
+		}
+		names[s.Name] = true
+		if len(s.Formats) == 0 {
+			s.Formats = []string{"csv", "json"}
+		}
+		for _, format := range s.Formats {
+			if _, err := NewResultWriter(format, WriterOptions{}); err != nil {
+				return fmt.Errorf("%s: %w", s.Name, err)
+			}
+		}
+		dir, err := intakeDirKey(s.Dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+		outDir, err := filepath.Abs(s.OutDir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+		if other, ok := dirs[dir]; ok {
+			return fmt.Errorf("%s: intake directory is used by another source: %s", s.Name, other)
+		}
+		if other, ok := outDirs[outDir]; ok {
+			return fmt.Errorf("%s: report directory is used by another source: %s", s.Name, other)
+		}
+		dirs[dir], outDirs[outDir] = s.Name, s.Name
+	}
+	return nil
+}
+
+// intakeDirKey は受け入れフォルダの重複を判定するためのキーを返します。
+// ローカルのフォルダは絶対パス、S3のプレフィックスは s3://<bucket>/<prefix> です。
+func intakeDirKey(dir string) (string, error) {
+	if !isS3Path(dir) {
+		return filepath.Abs(dir)
+	}
+	bucket, prefix, err := parseS3Path(dir)
+	if err != nil {
+		return "", err
+	}
+	return "s3://" + bucket + "/" + prefix, nil
+}
+
+// fileStamp はファイルが変わったかを判定するためのサイズと更新日時です。
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// readyArchives は前回と今回の確認でサイズと更新日時が変わっておらず、まだ処理していないアーカイブを昇順で返します。(純粋関数)
+// コピー途中のファイルは確認のたびにサイズか更新日時が変わるため、次の確認まで待ちます。
+// 処理済みのアーカイブも、同じ名前で置き換えられて変わった場合は再び返します。
+func readyArchives(prev, cur, done map[string]fileStamp) []string {
+	var ready []string
+	for p, stamp := range cur {
+		if before, ok := prev[p]; !ok || before != stamp {
+			continue
+		}
+		if processed, ok := done[p]; ok && processed == stamp {
+			continue
+		}
+		ready = append(ready, p)
+	}
+	sort.Strings(ready)
+	return ready
+}
+
+// intakeLister は受け入れフォルダのアーカイブの一覧と、アーカイブの読み込み先を返します。
+type intakeLister interface {
+	// List はアーカイブの名前ごとのサイズと更新日時を返します。
+	List(ctx context.Context) (map[string]fileStamp, error)
+	// Locate はアーカイブの名前から App.Run で読み込むパスまたはURLを返します。
+	Locate(name string) (string, error)
+}
+
+// localIntake はローカルのフォルダの受け入れフォルダです。アーカイブの名前はファイルのパスです。
+type localIntake string
+
+func (d localIntake) List(ctx context.Context) (map[string]fileStamp, error) {
+	return scanIntakeDir(string(d))
+}
+
+func (d localIntake) Locate(name string) (string, error) {
+	return name, nil
+}
+
+// scanIntakeDir は受け入れフォルダ直下のZIPファイルのサイズと更新日時を返します。
+func scanIntakeDir(dir string) (map[string]fileStamp, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list intake directory: %w", err)
+	}
+	stamps := make(map[string]fileStamp)
+	for _, e := range entries {
+		if e.IsDir() || !isZipName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// 確認の間に削除されたファイルは対象外
+			continue
+		}
+		stamps[filepath.Join(dir, e.Name())] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+	}
+	return stamps, nil
+}
+
+// Intake は複数の受け入れフォルダを並行して監視し、届いたZIPファイルを受け入れフォルダごとの設定で集計します。
+type Intake struct {
+	App     *App
+	Sources []IntakeSource
+	// Config は各アーカイブの集計に使う設定です。Threshold は受け入れフォルダに指定があればその値で上書きします。
+	Config AppConfig
+	// Interval は受け入れフォルダを確認する間隔です。
+	Interval time.Duration
+	// Settle が0より大きい場合は、一定間隔で確認する代わりにファイルの変更の通知で監視し (watch -sources)、
+	// ファイルの更新が止まってからこの時間だけ待って処理します。
+	Settle time.Duration
+	// Notify が nil でない場合は、アーカイブごとの処理結果を条件に従って通知します。
+	Notify *NotifySettings
+	// S3 は s3:// で指定した受け入れフォルダの接続先と認証情報です。
+	S3 S3Options
+}
+
+// Run は ctx が終了するまで、受け入れフォルダごとに独立して監視します。
+// 受け入れフォルダごとに到着順に1件ずつ処理するため、ある受け入れフォルダの大きなアーカイブが他の受け入れフォルダの処理を待たせることはありません。
+// いずれかの受け入れフォルダの監視を始められなかった場合は、すべての監視を止めてエラーを返します。
+func (in *Intake) Run(ctx context.Context) error {
+	listers := make([]intakeLister, len(in.Sources))
+	for i, src := range in.Sources {
+		if err := os.MkdirAll(src.OutDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		listers[i] = localIntake(src.Dir)
+		if isS3Path(src.Dir) {
+			s3, err := newS3Intake(src.Dir, in.S3)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Name, err)
+			}
+			listers[i] = s3
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(in.Sources))
+	var wg sync.WaitGroup
+	for i, src := range in.Sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := in.watcher(src)
+			// S3のプレフィックスは変更の通知を受け取れないため、常に一定間隔で確認する
+			if in.Settle <= 0 || isS3Path(src.Dir) {
+				in.poll(ctx, src, listers[i], w)
+				return
+			}
+			if err := w.Watch(ctx, src.Dir); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", src.Name, err)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// watcher は受け入れフォルダの設定でアーカイブを処理する Watcher を作成します。
+// ログには受け入れフォルダの名前を含めます。
+func (in *Intake) watcher(src IntakeSource) *Watcher {
+	app := *in.App
+	app.Logger = in.App.Logger.With(slog.String("source", src.Name))
+	cfg := in.Config
+	if src.Threshold > 0 {
+		cfg.Threshold = src.Threshold
+	}
+	return &Watcher{App: &app, Config: cfg, OutDir: src.OutDir, Formats: src.Formats, Settle: in.Settle, Notify: in.Notify}
+}
+
+// poll は ctx が終了するまで1つの受け入れフォルダを一定間隔で確認し、届いたアーカイブを w で処理します。
+func (in *Intake) poll(ctx context.Context, src IntakeSource, lister intakeLister, w *Watcher) {
+	logger := w.App.Logger
+	logger.Info("受け入れフォルダの監視を開始します", slog.String("dir", src.Dir), slog.String("outDir", src.OutDir))
+	ticker := time.NewTicker(in.Interval)
+	defer ticker.Stop()
+	var prev map[string]fileStamp
+	done := make(map[string]fileStamp)
+	for {
+		cur, err := lister.List(ctx)
+		if err != nil {
+			logger.Warn("受け入れフォルダを確認できませんでした", slog.String("error", err.Error()))
+		} else {
+			for _, p := range readyArchives(prev, cur, done) {
+				if ctx.Err() != nil {
+					return
+				}
+				zipPath, err := lister.Locate(p)
+				if err == nil {
+					err = w.ProcessAs(p, zipPath)
+				}
+				if err != nil {
+					logger.Error("アーカイブの処理に失敗しました", slog.String("zipPath", p), slog.String("error", err.Error()))
+				}
+				// 失敗したアーカイブも、置き換えられるまでは繰り返し処理しない
+				done[p] = cur[p]
+			}
+			prev = cur
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validateIntakeSources のテスト
+func TestValidateIntakeSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		sources     []IntakeSource
+		expectedErr string
+	}{
+		{
+			name: "正常系",
+			sources: []IntakeSource{
+				{Name: "a", Dir: "in/a", OutDir: "out/a", Threshold: 10},
+				{Name: "b", Dir: "in/b", OutDir: "out/b", Formats: []string{"csv"}},
+			},
+		},
+		{name: "定義なし", expectedErr: "no sources are defined"},
+		{name: "名前なし", sources: []IntakeSource{{Dir: "in/a", OutDir: "out/a"}}, expectedErr: "source name is required: entry 1"},
+		{
+			name: "名前の重複",
+			sources: []IntakeSource{
+				{Name: "a", Dir: "in/a", OutDir: "out/a"},
+				{Name: "a", Dir: "in/b", OutDir: "out/b"},
+			},
+			expectedErr: "duplicate source name: a",
+		},
+		{name: "出力先なし", sources: []IntakeSource{{Name: "a", Dir: "in/a"}}, expectedErr: "dir and outDir are required"},
+		{name: "負のしきい値", sources: []IntakeSource{{Name: "a", Dir: "in/a", OutDir: "out/a", Threshold: -1}}, expectedErr: "threshold must not be negative"},
+		{name: "未知の形式", sources: []IntakeSource{{Name: "a", Dir: "in/a", OutDir: "out/a", Formats: []string{"xml"}}}, expectedErr: "unknown format: xml"},
+		{
+			name: "監視フォルダの重複",
+			sources: []IntakeSource{
+				{Name: "a", Dir: "in/a", OutDir: "out/a"},
+				{Name: "b", Dir: "in/../in/a", OutDir: "out/b"},
+			},
+			expectedErr: "b: intake directory is used by another source: a",
+		},
+		{
+			name: "S3のプレフィックスの重複",
+			sources: []IntakeSource{
+				{Name: "a", Dir: "s3://bucket/in", OutDir: "out/a"},
+				{Name: "b", Dir: "s3://bucket/in/", OutDir: "out/b"},
+			},
+			expectedErr: "b: intake directory is used by another source: a",
+		},
+		{name: "不正なS3のパス", sources: []IntakeSource{{Name: "a", Dir: "s3:///in", OutDir: "out/a"}}, expectedErr: "a: invalid s3 path"},
+		{
+			name: "出力先の重複",
+			sources: []IntakeSource{
+				{Name: "a", Dir: "in/a", OutDir: "out"},
+				{Name: "b", Dir: "in/b", OutDir: "out/"},
+			},
+			expectedErr: "b: report directory is used by another source: a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIntakeSources(tt.sources)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(tt.sources[0].Formats, []string{"csv", "json"}) {
+					t.Errorf("expected default formats, got %v", tt.sources[0].Formats)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+// readyArchives のテスト
+func TestReadyArchives(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stable := fileStamp{size: 10, modTime: t0}
+	grown := fileStamp{size: 20, modTime: t0.Add(time.Second)}
+
+	tests := []struct {
+		name     string
+		prev     map[string]fileStamp
+		cur      map[string]fileStamp
+		done     map[string]fileStamp
+		expected []string
+	}{
+		{name: "初回の確認", cur: map[string]fileStamp{"a.zip": stable}},
+		{name: "変化なし", prev: map[string]fileStamp{"b.zip": stable, "a.zip": stable}, cur: map[string]fileStamp{"b.zip": stable, "a.zip": stable}, expected: []string{"a.zip", "b.zip"}},
+		{name: "コピー途中", prev: map[string]fileStamp{"a.zip": stable}, cur: map[string]fileStamp{"a.zip": grown}},
+		{name: "処理済み", prev: map[string]fileStamp{"a.zip": stable}, cur: map[string]fileStamp{"a.zip": stable}, done: map[string]fileStamp{"a.zip": stable}},
+		{name: "処理済みを置き換え", prev: map[string]fileStamp{"a.zip": grown}, cur: map[string]fileStamp{"a.zip": grown}, done: map[string]fileStamp{"a.zip": stable}, expected: []string{"a.zip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readyArchives(tt.prev, tt.cur, tt.done)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// Intake.Run のテスト (一定間隔での確認と、変更の通知による監視)
+func TestIntakeRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		settle   time.Duration
+	}{
+		{name: "一定間隔で確認", interval: 10 * time.Millisecond},
+		{name: "変更の通知で監視", settle: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			sources := []IntakeSource{
+				{Name: "a", Dir: filepath.Join(root, "in-a"), OutDir: filepath.Join(root, "out-a"), Threshold: 1, Formats: []string{"csv"}},
+				// しきい値を省略した受け入れフォルダは Config のしきい値を使う
+				{Name: "b", Dir: filepath.Join(root, "in-b"), OutDir: filepath.Join(root, "out-b"), Formats: []string{"csv", "json"}},
+			}
+			for _, s := range sources {
+				if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			app := &App{Reader: ZipArchiveReader{}, Logger: slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))}
+			intake := &Intake{App: app, Sources: sources, Config: AppConfig{Threshold: 5}, Interval: tt.interval, Settle: tt.settle}
+			ctx, cancel := context.WithCancel(context.Background())
+			errCh := make(chan error, 1)
+			go func() { errCh <- intake.Run(ctx) }()
+			// 監視の開始を待ってからファイルを置く
+			time.Sleep(100 * time.Millisecond)
+
+			data, err := os.ReadFile(writeTestZip(t, "dir1/a.txt", "dir1/b.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range sources {
+				if err := os.WriteFile(filepath.Join(s.Dir, "batch.zip"), data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected := []string{
+				filepath.Join(root, "out-a", "batch.csv"),
+				filepath.Join(root, "out-b", "batch.csv"),
+				filepath.Join(root, "out-b", "batch.json"),
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for _, p := range expected {
+				for {
+					if _, err := os.Stat(p); err == nil {
+						break
+					}
+					if time.Now().After(deadline) {
+						t.Fatalf("report was not written: %s", p)
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			cancel()
+			if err := <-errCh; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// しきい値は受け入れフォルダごとに適用される
+			a, err := os.ReadFile(expected[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(a), "dir1,2") {
+				t.Errorf("source a should report dir1 over threshold 1, got: %s", a)
+			}
+			b, err := os.ReadFile(expected[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(b), "dir1,2") {
+				t.Errorf("source b should not report dir1 under threshold 5, got: %s", b)
+			}
+			if _, err := os.Stat(filepath.Join(root, "out-a", "batch.json")); !os.IsNotExist(err) {
+				t.Errorf("source a should only write csv, got stat error %v", err)
+			}
+		})
+	}
+}
+
+// Intake.Run のテスト (監視を始められない受け入れフォルダがあればすべて止める)
+func TestIntakeRunWatchError(t *testing.T) {
+	root := t.TempDir()
+	sources := []IntakeSource{
+		{Name: "ok", Dir: t.TempDir(), OutDir: filepath.Join(root, "out-ok"), Formats: []string{"csv"}},
+		{Name: "missing", Dir: filepath.Join(root, "missing"), OutDir: filepath.Join(root, "out-missing"), Formats: []string{"csv"}},
+	}
+	app := &App{Reader: ZipArchiveReader{}, Logger: slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))}
+	intake := &Intake{App: app, Sources: sources, Settle: 50 * time.Millisecond}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- intake.Run(context.Background()) }()
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "missing: failed to watch directory") {
+			t.Errorf("expected watch error for source missing, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after a source failed to start")
+	}
+}
+
+// watch -sources のテスト (受け入れフォルダごとの設定と同時に指定できないフラグ)
+func TestRunCLIWatchSourcesConflict(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if code := runCLI([]string{"watch", "-sources", "sources.json", "-out-dir", "reports"}, stdout, stderr); code != 2 {
+		t.Fatalf("expected code 2, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "cannot be combined with -sources: -out-dir") {
+		t.Errorf("expected conflict message, got: %s", stderr.String())
+	}
+}
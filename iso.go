@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ISO 9660 のボリューム記述子とディレクトリレコードの定数です。
+const (
+	isoSectorSize       = 2048
+	isoDescriptorStart  = 16
+	isoMaxDescriptors   = 64
+	isoTypePrimary      = 1
+	isoTypeSupplemental = 2
+	isoTypeTerminator   = 255
+	isoFlagDir          = 0x02
+	isoFlagMultiExtent  = 0x80
+)
+
+// isoStandardID はボリューム記述子の識別子です。
+var isoStandardID = []byte("CD001")
+
+// udfStandardIDs はUDFのボリューム認識シーケンスに現れる識別子です。
+var udfStandardIDs = [][]byte{[]byte("BEA01"), []byte("NSR02"), []byte("NSR03")}
+
+// ErrUDFOnly はISO 9660のディレクトリ構造を持たないUDF専用のイメージであることを表します。
+var ErrUDFOnly = errors.New("udf-only images are not supported")
+
+// IsoArchiveReader はディスクイメージ (.iso) 内のファイルを一覧する実装です。
+// ISO 9660 のディレクトリ構造を読み、Rock Ridge の名前、Joliet の名前、ISO 9660 の名前の順に優先して使います。
+// ISO 9660 の構造を併せ持つUDFブリッジ形式のイメージも読み込めます。
+type IsoArchiveReader struct{}
+
+func (IsoArchiveReader) ReadEntries(isoPath string) ([]FileEntry, error) {
+	f, err := os.Open(isoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open iso: %w", err)
+	}
+	defer f.Close()
+	return readISOEntries(f)
+}
+
+// isoVolume はボリューム記述子から読み取ったルートディレクトリの位置です。
+type isoVolume struct {
+	root   isoRecord
+	joliet bool
+}
+
+// isoRecord はディレクトリレコードの内容です。
+type isoRecord struct {
+	extent   uint32
+	size     uint32
+	flags    byte
+	name     string
+	modified time.Time
+	// rrName は Rock Ridge の NM エントリの名前です。無い場合は空です。
+	rrName string
+}
+
+// readISOEntries はイメージのボリューム記述子を読み、ディレクトリ構造をたどってエントリを返します。
+func readISOEntries(r io.ReaderAt) ([]FileEntry, error) {
+	var primary, joliet *isoVolume
+	udf := false
+	buf := make([]byte, isoSectorSize)
+	for i := 0; i < isoMaxDescriptors; i++ {
+		if _, err := r.ReadAt(buf, int64(isoDescriptorStart+i)*isoSectorSize); err != nil {
+			return nil, fmt.Errorf("failed to read volume descriptor: %w", err)
+		}
+		id := buf[1:6]
+		if !bytes.Equal(id, isoStandardID) {
+			if isUDFDescriptor(id) {
+				udf = true
+				continue
+			}
+			break
+		}
+		if buf[0] == isoTypeTerminator {
+			continue
+		}
+		root, err := parseISORecord(buf[156:190], false)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case buf[0] == isoTypePrimary && primary == nil:
+			primary = &isoVolume{root: root}
+		case buf[0] == isoTypeSupplemental && joliet == nil && isJolietEscape(buf[88:120]):
+			root, err = parseISORecord(buf[156:190], true)
+			if err != nil {
+				return nil, err
+			}
+			joliet = &isoVolume{root: root, joliet: true}
+		}
+	}
+	if primary == nil {
+		if udf {
+			return nil, ErrUDFOnly
+		}
+		return nil, errors.New("failed to open iso: primary volume descriptor not found")
+	}
+
+	entries, rockRidge, err := walkISO(r, primary)
+	if err != nil {
+		return nil, err
+	}
+	if !rockRidge && joliet != nil {
+		entries, _, err = walkISO(r, joliet)
+	}
+	return entries, err
+}
+
+// isUDFDescriptor は識別子がUDFのボリューム認識シーケンスのものか判定します。(純粋関数)
+func isUDFDescriptor(id []byte) bool {
+	for _, udfID := range udfStandardIDs {
+		if bytes.Equal(id, udfID) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJolietEscape は補助ボリューム記述子のエスケープシーケンスが Joliet (UCS-2) を表すか判定します。(純粋関数)
+func isJolietEscape(esc []byte) bool {
+	for _, level := range []string{"%/@", "%/C", "%/E"} {
+		if bytes.HasPrefix(esc, []byte(level)) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkISO はルートディレクトリから幅優先でディレクトリをたどり、エントリを返します。
+// 2つ目の戻り値は Rock Ridge の名前が1つでも見つかったかを表します。
+func walkISO(r io.ReaderAt, vol *isoVolume) ([]FileEntry, bool, error) {
+	type dir struct {
+		path string
+		rec  isoRecord
+	}
+	var entries []FileEntry
+	rockRidge := false
+	visited := map[uint32]bool{vol.root.extent: true}
+	queue := []dir{{rec: vol.root}}
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		records, err := readISODir(r, d.rec, vol.joliet)
+		if err != nil {
+			return nil, false, err
+		}
+		for i := 0; i < len(records); i++ {
+			rec := records[i]
+			name := rec.name
+			if rec.rrName != "" {
+				name = rec.rrName
+				rockRidge = true
+			}
+			fullPath := name
+			if d.path != "" {
+				fullPath = d.path + "/" + name
+			}
+			if rec.flags&isoFlagDir != 0 {
+				entries = append(entries, FileEntry{Name: fullPath + "/", RawName: fullPath + "/", IsDir: true, Modified: rec.modified})
+				if !visited[rec.extent] {
+					visited[rec.extent] = true
+					queue = append(queue, dir{path: fullPath, rec: rec})
+				}
+				continue
+			}
+			// 4GiBを超えるファイルは同じ名前の複数のレコードに分かれて記録される
+			size := uint64(rec.size)
+			for rec.flags&isoFlagMultiExtent != 0 && i+1 < len(records) {
+				i++
+				rec = records[i]
+				size += uint64(rec.size)
+			}
+			entries = append(entries, FileEntry{Name: fullPath, RawName: fullPath, Modified: records[i].modified, Size: size, CompressedSize: size})
+		}
+	}
+	return entries, rockRidge, nil
+}
+
+// readISODir はディレクトリの領域を読み、自身と親を除くレコードを返します。
+func readISODir(r io.ReaderAt, d isoRecord, joliet bool) ([]isoRecord, error) {
+	data := make([]byte, d.size)
+	if _, err := r.ReadAt(data, int64(d.extent)*isoSectorSize); err != nil {
+		return nil, fmt.Errorf("failed to read iso directory: %w", err)
+	}
+	var records []isoRecord
+	for off := 0; off < len(data); {
+		n := int(data[off])
+		if n == 0 {
+			// レコードはセクタをまたがないため、残りは次のセクタの先頭まで詰め物になる
+			off = (off/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+		if off+n > len(data) {
+			return nil, errors.New("failed to read iso directory: record exceeds directory extent")
+		}
+		rec, err := parseISORecord(data[off:off+n], joliet)
+		if err != nil {
+			return nil, err
+		}
+		off += n
+		if rec.name == "\x00" || rec.name == "\x01" {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// parseISORecord はディレクトリレコードを解析します。(純粋関数)
+func parseISORecord(b []byte, joliet bool) (isoRecord, error) {
+	if len(b) < 34 || int(b[0]) > len(b) || 33+int(b[32]) > int(b[0]) {
+		return isoRecord{}, errors.New("failed to read iso directory: malformed record")
+	}
+	nameLen := int(b[32])
+	rawName := b[33 : 33+nameLen]
+	rec := isoRecord{
+		extent:   binary.LittleEndian.Uint32(b[2:6]),
+		size:     binary.LittleEndian.Uint32(b[10:14]),
+		flags:    b[25],
+		modified: isoRecordTime(b[18:25]),
+	}
+	switch {
+	case nameLen == 1 && (rawName[0] == 0 || rawName[0] == 1):
+		rec.name = string(rawName)
+		return rec, nil
+	case joliet:
+		rec.name = decodeUCS2(rawName)
+	default:
+		rec.name = string(rawName)
+	}
+	rec.name = trimISOVersion(rec.name, rec.flags&isoFlagDir != 0)
+
+	// システム使用領域はファイル名の後、偶数の位置から始まる
+	su := 33 + nameLen
+	if nameLen%2 == 0 {
+		su++
+	}
+	if !joliet && su < int(b[0]) {
+		rec.rrName = rockRidgeName(b[su:b[0]])
+	}
+	return rec, nil
+}
+
+// trimISOVersion はファイル名の末尾の ";1" 等のバージョン番号と、拡張子が無い場合の "." を除きます。(純粋関数)
+func trimISOVersion(name string, isDir bool) string {
+	if isDir {
+		return name
+	}
+	if i := strings.LastIndexByte(name, ';'); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSuffix(name, ".")
+}
+
+// decodeUCS2 は Joliet のビッグエンディアンのUCS-2の名前を復号します。(純粋関数)
+func decodeUCS2(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// rockRidgeName はシステム使用領域から Rock Ridge の NM エントリを探し、名前を返します。(純粋関数)
+// 継続領域 (CE) に置かれた名前は読みません。
+func rockRidgeName(su []byte) string {
+	var name strings.Builder
+	for len(su) >= 4 {
+		n := int(su[2])
+		if n < 4 || n > len(su) {
+			break
+		}
+		if string(su[:2]) == "NM" && n >= 5 {
+			// フラグが自身または親を表す場合は名前を持たない
+			if su[4]&0x06 == 0 {
+				name.Write(su[5:n])
+			}
+		}
+		su = su[n:]
+	}
+	return name.String()
+}
+
+// isoRecordTime はディレクトリレコードの記録日時を解析します。(純粋関数)
+// 年が0の場合は記録が無いものとしてゼロ値を返します。
+func isoRecordTime(b []byte) time.Time {
+	if b[0] == 0 {
+		return time.Time{}
+	}
+	zone := time.FixedZone("", int(int8(b[6]))*15*60)
+	return time.Date(1900+int(b[0]), time.Month(b[1]), int(b[2]), int(b[3]), int(b[4]), int(b[5]), 0, zone)
+}
+
+// isISOImage はファイルの先頭がISO 9660またはUDFのボリューム認識シーケンスか判定します。
+func isISOImage(r io.ReaderAt) bool {
+	id := make([]byte, 5)
+	if _, err := r.ReadAt(id, isoDescriptorStart*isoSectorSize+1); err != nil {
+		return false
+	}
+	return bytes.Equal(id, isoStandardID) || isUDFDescriptor(id)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// isoTestRecord はテスト用のディレクトリレコードを作成します。
+func isoTestRecord(name []byte, extent, size uint32, flags byte, su []byte) []byte {
+	n := 33 + len(name)
+	if len(name)%2 == 0 {
+		n++
+	}
+	n += len(su)
+	b := make([]byte, n)
+	b[0] = byte(n)
+	binary.LittleEndian.PutUint32(b[2:], extent)
+	binary.BigEndian.PutUint32(b[6:], extent)
+	binary.LittleEndian.PutUint32(b[10:], size)
+	binary.BigEndian.PutUint32(b[14:], size)
+	copy(b[18:25], []byte{123, 6, 15, 9, 30, 0, 36}) // 2023-06-15 09:30:00 +09:00
+	b[25] = flags
+	b[32] = byte(len(name))
+	copy(b[33:], name)
+	copy(b[n-len(su):], su)
+	return b
+}
+
+// isoTestDir は自身と親のレコードに続けてレコードを並べたディレクトリの領域を作成します。
+func isoTestDir(extent, parent uint32, records ...[]byte) []byte {
+	dir := append(isoTestRecord([]byte{0}, extent, isoSectorSize, isoFlagDir, nil), isoTestRecord([]byte{1}, parent, isoSectorSize, isoFlagDir, nil)...)
+	for _, r := range records {
+		dir = append(dir, r...)
+	}
+	return dir
+}
+
+func ucs2(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.BigEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+func rrNM(name string) []byte {
+	return append([]byte{'N', 'M', byte(5 + len(name)), 1, 0}, name...)
+}
+
+// buildTestISO はボリューム記述子とディレクトリを指定のセクタに配置したイメージを作成します。
+func buildTestISO(descriptors [][]byte, dirs map[uint32][]byte) []byte {
+	img := make([]byte, 24*isoSectorSize)
+	for i, d := range descriptors {
+		copy(img[(isoDescriptorStart+i)*isoSectorSize:], d)
+	}
+	for sector, d := range dirs {
+		copy(img[int(sector)*isoSectorSize:], d)
+	}
+	return img
+}
+
+func isoTestDescriptor(typ byte, rootExtent uint32, escape string) []byte {
+	d := make([]byte, isoSectorSize)
+	d[0] = typ
+	copy(d[1:], "CD001")
+	d[6] = 1
+	copy(d[88:], escape)
+	copy(d[156:], isoTestRecord([]byte{0}, rootExtent, isoSectorSize, isoFlagDir, nil))
+	return d
+}
+
+// readISOEntries のテスト
+func TestReadISOEntries(t *testing.T) {
+	modified := time.Date(2023, 6, 15, 9, 30, 0, 0, time.FixedZone("", 9*60*60))
+	terminator := append([]byte{isoTypeTerminator}, "CD001\x01"...)
+	primaryDirs := map[uint32][]byte{
+		20: isoTestDir(20, 20,
+			isoTestRecord([]byte("DOCS"), 21, isoSectorSize, isoFlagDir, nil),
+			isoTestRecord([]byte("README.TXT;1"), 0, 10, 0, nil),
+		),
+		21: isoTestDir(21, 20,
+			isoTestRecord([]byte("A.PDF;1"), 0, 100, isoFlagMultiExtent, nil),
+			isoTestRecord([]byte("A.PDF;1"), 0, 50, 0, nil),
+			isoTestRecord([]byte("NOEXT.;1"), 0, 1, 0, nil),
+		),
+	}
+
+	tests := []struct {
+		name     string
+		image    []byte
+		expected []FileEntry
+	}{
+		{
+			name:  "ISO 9660の名前",
+			image: buildTestISO([][]byte{isoTestDescriptor(isoTypePrimary, 20, ""), terminator}, primaryDirs),
+			expected: []FileEntry{
+				{Name: "DOCS/", RawName: "DOCS/", IsDir: true, Modified: modified},
+				{Name: "README.TXT", RawName: "README.TXT", Modified: modified, Size: 10, CompressedSize: 10},
+				{Name: "DOCS/A.PDF", RawName: "DOCS/A.PDF", Modified: modified, Size: 150, CompressedSize: 150},
+				{Name: "DOCS/NOEXT", RawName: "DOCS/NOEXT", Modified: modified, Size: 1, CompressedSize: 1},
+			},
+		},
+		{
+			name: "Jolietの名前を優先",
+			image: buildTestISO([][]byte{isoTestDescriptor(isoTypePrimary, 20, ""), isoTestDescriptor(isoTypeSupplemental, 22, "%/E"), terminator},
+				map[uint32][]byte{
+					20: primaryDirs[20],
+					21: primaryDirs[21],
+					22: isoTestDir(22, 22, isoTestRecord(ucs2("書類"), 23, isoSectorSize, isoFlagDir, nil)),
+					23: isoTestDir(23, 22, isoTestRecord(ucs2("報告書.pdf;1"), 0, 5, 0, nil)),
+				}),
+			expected: []FileEntry{
+				{Name: "書類/", RawName: "書類/", IsDir: true, Modified: modified},
+				{Name: "書類/報告書.pdf", RawName: "書類/報告書.pdf", Modified: modified, Size: 5, CompressedSize: 5},
+			},
+		},
+		{
+			name: "Rock Ridgeの名前を優先",
+			image: buildTestISO([][]byte{isoTestDescriptor(isoTypePrimary, 20, ""), isoTestDescriptor(isoTypeSupplemental, 22, "%/E"), terminator},
+				map[uint32][]byte{
+					20: isoTestDir(20, 20, isoTestRecord([]byte("READM000.TXT;1"), 0, 3, 0, rrNM("readme-long.txt"))),
+					22: isoTestDir(22, 22, isoTestRecord(ucs2("joliet.txt;1"), 0, 3, 0, nil)),
+				}),
+			expected: []FileEntry{
+				{Name: "readme-long.txt", RawName: "readme-long.txt", Modified: modified, Size: 3, CompressedSize: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := readISOEntries(bytes.NewReader(tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range entries {
+				entries[i].Modified = entries[i].Modified.In(modified.Location())
+			}
+			if !reflect.DeepEqual(entries, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, entries)
+			}
+		})
+	}
+
+	t.Run("UDF専用のイメージ", func(t *testing.T) {
+		image := buildTestISO([][]byte{[]byte("\x00BEA01\x01"), []byte("\x00NSR03\x01"), []byte("\x00TEA01\x01")}, nil)
+		if _, err := readISOEntries(bytes.NewReader(image)); !errors.Is(err, ErrUDFOnly) {
+			t.Errorf("expected ErrUDFOnly, got %v", err)
+		}
+	})
+}
+
+// AutoArchiveReader の形式の判定のテスト
+func TestAutoArchiveReader(t *testing.T) {
+	dir := t.TempDir()
+	isoPath := filepath.Join(dir, "delivery.iso")
+	image := buildTestISO([][]byte{isoTestDescriptor(isoTypePrimary, 20, "")}, map[uint32][]byte{
+		20: isoTestDir(20, 20, isoTestRecord([]byte("A.TXT;1"), 0, 1, 0, nil)),
+	})
+	if err := os.WriteFile(isoPath, image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt").WriteFile(t)
+	// ZIP形式を内部に使う拡張子はZIPとして読み込む
+	jarPath := filepath.Join(dir, "app.jar")
+	data, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jarPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]int{isoPath: 1, zipPath: 2, jarPath: 2} {
+		entries, err := (AutoArchiveReader{}).ReadEntries(path)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", path, err)
+		}
+		if len(entries) != expected {
+			t.Errorf("%s: expected %d entries, got %+v", path, expected, entries)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// logOptions はログの出力方法です。ゼロ値は標準エラー出力へのテキスト形式・INFOレベルです。
+type logOptions struct {
+	format string
+	level  slog.Level
+	file   *os.File
+	// quiet はエラー以外のログを出力しないかです。-log-level より優先します。
+	quiet bool
+	// verbose はエントリ名の復号の判定や処理時間などのDEBUGレベルのログも出力するかです。-log-level より優先します。
+	verbose bool
+}
+
+// effectiveLevel は -quiet と -verbose を反映した、出力するログのレベルを返します。(純粋関数)
+func (o logOptions) effectiveLevel() slog.Level {
+	switch {
+	case o.quiet:
+		return slog.LevelError
+	case o.verbose:
+		return slog.LevelDebug
+	}
+	return o.level
+}
+
+// newLogger はログの出力方法に従って Logger を作成します。(純粋関数)
+func newLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+}
+
+// registerLogFlags は全コマンド共通のログ関連のフラグを登録します。
+// フラグの解析中に env.Logger を作り直すため、各コマンドで個別に処理する必要はありません。
+// 集計結果などの機械可読な出力は常に標準出力に、ログなどの診断情報は常に標準エラー出力 (または -log-file) に書き出します。
+func (env *cliEnv) registerLogFlags(fs *flag.FlagSet) {
+	fs.BoolFunc("quiet", "結果だけを標準出力に出力し、エラー以外のログを出力しない", func(s string) error {
+		quiet, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		env.log.quiet = quiet
+		return env.rebuildLogger()
+	})
+	fs.BoolFunc("verbose", "エントリ名の復号の判定や処理時間などの詳細なログ (DEBUGレベル) を出力する", func(s string) error {
+		verbose, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		env.log.verbose = verbose
+		return env.rebuildLogger()
+	})
+	fs.Func("log-format", "ログの形式 (text, json、省略時は text)", func(s string) error {
+		env.log.format = s
+		return env.rebuildLogger()
+	})
+	fs.Func("log-level", "出力するログのレベル (debug, info, warn, error、省略時は info)", func(s string) error {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("unknown log level: %s", s)
+		}
+		env.log.level = level
+		return env.rebuildLogger()
+	})
+	fs.Func("log-file", "ログを追記するファイルのパス (省略時は標準エラー出力)", func(s string) error {
+		f, err := os.OpenFile(s, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		env.closeLog()
+		env.log.file = f
+		return env.rebuildLogger()
+	})
+	fs.Var(errorFormatValue{env}, "errors", "終了時のエラーの出力形式 (text, json。json では安定したエラーコードを含む1行のJSONを標準エラー出力に書き出す)")
+	fs.Var(langValue{env}, "lang", "ログ、表の見出し、エラーのメッセージの言語 (ja, en、省略時は環境変数 LANG などのロケールに従う)")
+}
+
+// langValue は -lang の値を env に設定する flag.Value です。
+type langValue struct {
+	env *cliEnv
+}
+
+func (v langValue) String() string {
+	if v.env == nil {
+		return ""
+	}
+	return string(v.env.lang)
+}
+
+func (v langValue) Set(s string) error {
+	lang, err := ParseLang(s)
+	if err != nil {
+		return err
+	}
+	v.env.lang = lang
+	return v.env.rebuildLogger()
+}
+
+// rebuildLogger は現在のログの設定で env.Logger を作り直します。
+func (env *cliEnv) rebuildLogger() error {
+	var w io.Writer = env.Stderr
+	if env.log.file != nil {
+		w = env.log.file
+	}
+	if env.log.quiet && env.log.verbose {
+		return errors.New("-quiet cannot be combined with -verbose")
+	}
+	logger, err := newLogger(w, env.log.format, env.log.effectiveLevel())
+	if err != nil {
+		return err
+	}
+	if env.lang != "" {
+		logger = slog.New(localizedHandler{Handler: logger.Handler(), lang: env.lang})
+	}
+	env.Logger = logger
+	return nil
+}
+
+// closeLog はログファイルを開いていれば閉じます。
+func (env *cliEnv) closeLog() {
+	if env.log.file != nil {
+		env.log.file.Close()
+		env.log.file = nil
+	}
+}
+
+// logDecodeDecisions はエントリ名を復号した結果をエントリごとにDEBUGレベルのログに出力します。
+// 復号しなかったエントリと、復号前の名前を持たない形式のエントリは出力しません。
+func (app *App) logDecodeDecisions(entries []FileEntry) {
+	if !app.Logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	for _, e := range entries {
+		switch {
+		case e.RawName == "":
+		case !utf8.ValidString(e.Name):
+			app.Logger.Debug("エントリ名を復号できませんでした", slog.String("rawName", fmt.Sprintf("%q", e.RawName)))
+		case e.Name != e.RawName:
+			app.Logger.Debug("エントリ名を復号しました", slog.String("rawName", fmt.Sprintf("%q", e.RawName)), slog.String("name", e.Name))
+		}
+	}
+}
+
+// newCorrelationID はアーカイブごとの処理をログ上で追跡するためのIDを生成します。
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// withCorrelationID はログに相関IDを付けた App の複製を返します。
+func (app *App) withCorrelationID() *App {
+	c := *app
+	c.Logger = app.Logger.With(slog.String("correlationId", newCorrelationID()))
+	return &c
+}
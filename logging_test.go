@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// newLogger のテスト
+func TestNewLogger(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger, err := newLogger(out, "json", slog.LevelWarn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger.Info("ignored")
+	logger.Warn("written")
+	if strings.Contains(out.String(), "ignored") || !strings.Contains(out.String(), `"msg":"written"`) {
+		t.Errorf("unexpected log output: %s", out.String())
+	}
+	if _, err := newLogger(out, "xml", slog.LevelInfo); err == nil {
+		t.Error("expected error for unknown log format, got nil")
+	}
+}
+
+// ログ関連のフラグのテスト (JSON形式のファイル出力と相関ID)
+func TestRunCLILogFlags(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt")
+	logPath := filepath.Join(t.TempDir(), "app.log")
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := runCLI([]string{"count", "-zip", zipPath, "-log-format", "json", "-log-level", "debug", "-log-file", logPath}, stdout, stderr)
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no log on stderr, got: %s", stderr.String())
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	ids := make(map[string]bool)
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid json log line %q: %v", line, err)
+		}
+		id, _ := record["correlationId"].(string)
+		ids[id] = true
+	}
+	if len(lines) < 2 || len(ids) != 1 || ids[""] {
+		t.Errorf("expected all records to share one correlation id, got %v in %d lines", ids, len(lines))
+	}
+
+	if code := runCLI([]string{"count", "-zip", zipPath, "-log-level", "verbose"}, stdout, stderr); code != 2 {
+		t.Errorf("expected code 2 for unknown log level, got %d", code)
+	}
+}
+
+// -quiet と -verbose のテスト (結果は標準出力、ログは標準エラー出力)
+func TestRunCLIQuietVerbose(t *testing.T) {
+	zipPath := obuziptest.New().
+		Files("dir1/a.txt").
+		RawFile([]byte("\x8e\x91\x97\xbf/b.txt")). // 資料/b.txt
+		WriteFile(t)
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		stderrHas    []string
+		stderrLacks  []string
+	}{
+		{name: "既定", args: nil, stderrHas: []string{"aggregation finished"}, stderrLacks: []string{"level=DEBUG"}},
+		{name: "-quiet はログを出力しない", args: []string{"-quiet"}},
+		{name: "-quiet は -log-level より優先", args: []string{"-log-level", "debug", "-quiet"}},
+		{
+			name:      "-verbose は復号の判定と処理時間を出力",
+			args:      []string{"-verbose"},
+			stderrHas: []string{"decoded entry name", `name=資料/b.txt`, "aggregation time", "elapsed="},
+		},
+		{name: "-quiet と -verbose の併用", args: []string{"-quiet", "-verbose"}, expectedCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"count", "-zip", zipPath, "-threshold", "1", "-lang", "en"}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if tt.expectedCode != 0 {
+				return
+			}
+			// 結果は標準出力だけに、ログは標準エラー出力だけに書き出す
+			if !strings.Contains(stdout.String(), "Folder Path") || strings.Contains(stdout.String(), "level=") {
+				t.Errorf("stdout should contain only the result, got: %s", stdout.String())
+			}
+			if strings.Contains(stderr.String(), "Folder Path") {
+				t.Errorf("stderr should not contain the result, got: %s", stderr.String())
+			}
+			if tt.stderrHas == nil && tt.stderrLacks == nil && stderr.Len() != 0 {
+				t.Errorf("expected no log, got: %s", stderr.String())
+			}
+			for _, want := range tt.stderrHas {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr does not contain %q, got: %s", want, stderr.String())
+				}
+			}
+			for _, unwanted := range tt.stderrLacks {
+				if strings.Contains(stderr.String(), unwanted) {
+					t.Errorf("stderr should not contain %q, got: %s", unwanted, stderr.String())
+				}
+			}
+		})
+	}
+}
@@ -2,74 +2,260 @@ package main
 
 import (
 	"archive/zip"
-	"encoding/csv"
 	"errors"
-	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path"
-	"sort"
+	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
 )
 
 // FolderCount はフォルダの情報を保持します。
 type FolderCount struct {
-	Path  string
-	Count int
+	// Path は "/" 区切りの集計キーのフォルダパスです。ルートは "(Root)" です。出力時は FolderFormat で表記を変換します。
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+	// Stats はサイズの統計です。集計時に統計を求めなかった場合は nil です。
+	Stats *FolderStats `json:"stats,omitempty"`
+	// Estimate は読み込みを打ち切った場合の、全体を読み込んだときのファイル数の推定値です。
+	Estimate int `json:"estimate,omitempty"`
+	// EstimateMargin は -sample で抽出した場合の、Estimate の95%信頼区間の幅 (±) です。
+	EstimateMargin int `json:"estimateMargin,omitempty"`
+	// Share は累積モードでの親フォルダと全体に対する割合です。累積モードでない場合は nil です。
+	Share *FolderShare `json:"share,omitempty"`
+	// OtherFolders はしきい値未満のフォルダをまとめた行の場合に、まとめたフォルダの数です。それ以外の行では0です。
+	OtherFolders int `json:"otherFolders,omitempty"`
+	// root は FolderFormat でルートの表記を変換した行であることを表します。
+	root bool
+}
+
+// isRoot はルートフォルダの行かを、ルートの表記に関係なく判定します。
+func (r FolderCount) isRoot() bool {
+	return r.root || r.Path == "(Root)"
+}
+
+// FolderShare はフォルダ配下のファイル数が占める割合 (パーセント) です。
+type FolderShare struct {
+	// OfParent は親フォルダ配下のファイル数に対する割合です。最上位のフォルダの親はルートです。
+	OfParent float64 `json:"ofParent"`
+	// OfTotal は集計したすべてのファイル数に対する割合です。
+	OfTotal float64 `json:"ofTotal"`
+}
+
+// FolderStats はフォルダ内のファイルサイズ (展開後のバイト数) の統計です。
+type FolderStats struct {
+	TotalSize   uint64 `json:"totalSize"`
+	AverageSize uint64 `json:"averageSize"`
+	LargestFile string `json:"largestFile"`
+	LargestSize uint64 `json:"largestSize"`
+}
+
+// Report は出力対象となる集計結果と、解析したアーカイブの情報をまとめたものです。
+type Report struct {
+	Archive string `json:"archive"`
+	// SHA256 はアーカイブ自体のハッシュ値です。計算しなかった場合は空文字列です。
+	SHA256     string        `json:"sha256,omitempty"`
+	TotalFiles int           `json:"totalFiles"`
+	Folders    []FolderCount `json:"folders"`
+	// Findings は集計結果から求めた検出事項です。すべての出力形式と終了コードはこれに基づきます。
+	Findings []Finding `json:"findings,omitempty"`
+	// Partial は時間の上限で読み込みを打ち切った場合の情報です。すべて読み込んだ場合は nil です。
+	Partial *PartialScan `json:"partial,omitempty"`
+	// Stats はフォルダごとのサイズの統計を出力に含めるかを表します。
+	Stats bool `json:"-"`
+	// Cumulative は件数が配下のフォルダを含む累積の件数で、割合の列を出力に含めるかを表します。
+	Cumulative bool `json:"-"`
+	// Symlinks はシンボリックリンクの数です。-follow-symlinks を指定しない場合、TotalFiles には含みません。
+	Symlinks int `json:"symlinks,omitempty"`
+	// SpecialEntries はデバイスなど、TotalFiles に含まない特殊なエントリの数です。
+	SpecialEntries int `json:"specialEntries,omitempty"`
+	// Info はアーカイブ全体の情報です。求めなかった場合は nil です。
+	Info *ArchiveInfo `json:"archiveInfo,omitempty"`
+	// RunAt は集計を実行した日時です。追記するCSVの実行日時の列に使います。
+	RunAt time.Time `json:"-"`
 }
 
 // FileEntry はアーカイブ内のエントリ情報を抽象化します。
 type FileEntry struct {
-	Name  string
-	IsDir bool
+	Name string
+	// RawName はZIPに格納されたままの復号前のエントリ名です。
+	RawName  string
+	IsDir    bool
+	Modified time.Time
+	// Size は展開後のバイト数です。
+	Size uint64
+	// CompressedSize は圧縮後のバイト数です。
+	CompressedSize uint64
+	// Method はZIPの圧縮方式 (zip.Store、zip.Deflate など) です。ZIP以外の形式では0 (無圧縮) です。
+	Method uint16
+	// CRC32 はZIPに記録された展開後の内容のCRC-32です。記録されない形式では0です。
+	CRC32 uint32
+	// Mode はエントリの種類とパーミッションです。Unixで作成されたZIPでは外部属性から求め、それ以外では0 (通常のファイル) です。
+	Mode fs.FileMode
+	// Owner は拡張フィールドに記録されたUnixの所有者です。記録されていない場合は nil です。
+	Owner *UnixOwner
+	// Created と Accessed は拡張フィールドに記録された作成日時とアクセス日時です。記録されていない場合はゼロ値です。
+	Created  time.Time
+	Accessed time.Time
+	// TimeSource は Modified の記録元 (TimeSourceNTFS など) です。ZIP以外の形式では空です。
+	TimeSource string
+	// Comment はZIPのエントリごとのコメントです。エントリ名と同じ方法で復号します。記録されない形式では空です。
+	Comment string
 }
 
 // =====================================================================
 // Domain / Pure Functions (ビジネスロジック)
 // =====================================================================
 
-// AggregateFolders はファイルエントリのリストを集計し、しきい値以上のものを抽出・ソートします。(純粋関数)
-func AggregateFolders(entries []FileEntry, threshold int) ([]FolderCount, int) {
-	counts := make(map[string]int)
-	processedFiles := 0
+// AggregateOptions は集計方法を指定するオプションです。
+type AggregateOptions struct {
+	Threshold int
+	// ThresholdRules はフォルダパスのパターンごとのしきい値です。先に定義されたものが優先され、一致しないフォルダには Threshold を使います。
+	ThresholdRules []ThresholdRule
+	// ThresholdPercent は集計したファイル数全体に対する割合 (パーセント) のしきい値です。
+	// 0より大きい場合は、Threshold (またはルール) と合わせて両方を満たすフォルダだけを抽出します。
+	ThresholdPercent float64
+	// Filter は集計対象とするエントリの条件です。
+	Filter EntryFilter
+	// Normalize はフォルダパスに適用するUnicode正規化です。nilの場合は正規化しません。
+	Normalize func(string) string
+	// Rollup は階層範囲の適用前にフォルダを集約するルールです。先に定義されたものが優先されます。
+	Rollup []RollupRule
+	// Segments はフォルダパスのうち集計キーとして使う階層の範囲です。ゼロ値は全階層を表します。
+	Segments SegmentRange
+	// Key は正規化後のスラッシュ区切りのフォルダパス (ルートは ".") から集計キーを求める関数です。
+	// 指定した場合は Rollup と Segments の代わりに使います。nil の場合はフォルダパスを集計キーにします。
+	Key func(dir string) string
+	// Stats が true の場合、フォルダごとのサイズの統計も求めます。
+	Stats bool
+	// Cumulative が true の場合、ファイルを集計キーのフォルダとそのすべての上位のフォルダ (ルートを含む) に数え、
+	// 親フォルダと全体に対する割合も求めます。
+	Cumulative bool
+	// CollapseOther が true の場合、しきい値未満のフォルダを除外せず、1行の "(Other: N folders)" にまとめます。
+	CollapseOther bool
+	// MaxMemory が正の場合、フォルダごとの集計途中の値がこのバイト数を超えた時点で一時ファイルに書き出し、最後に併合します。
+	MaxMemory uint64
+	// SpillDir は一時ファイルを作成するフォルダです。空の場合は既定の一時フォルダです。
+	SpillDir string
+}
 
-	for _, f := range entries {
-		if f.IsDir {
-			continue
-		}
-		processedFiles++
+// SegmentRange はフォルダパスの階層範囲 (1始まり、両端を含む) を表します。
+// From, To が0の場合はそれぞれ先頭、末尾までを表します。
+type SegmentRange struct {
+	From int
+	To   int
+}
 
-		dirPath := path.Dir(f.Name)
-		if dirPath == "." {
-			dirPath = "(Root)"
-		} else {
-			dirPath = strings.ReplaceAll(dirPath, "/", "\\")
+// ParseSegmentRange は "2:4", "2:", ":3", "2" 形式の文字列を SegmentRange に変換します。(純粋関数)
+func ParseSegmentRange(s string) (SegmentRange, error) {
+	if s == "" {
+		return SegmentRange{}, nil
+	}
+	fromStr, toStr, found := strings.Cut(s, ":")
+	if !found {
+		toStr = fromStr
+	}
+	var r SegmentRange
+	var err error
+	if fromStr != "" {
+		if r.From, err = strconv.Atoi(fromStr); err != nil || r.From < 1 {
+			return SegmentRange{}, fmt.Errorf("invalid segment range: %s", s)
 		}
-		counts[dirPath]++
 	}
-
-	var results []FolderCount
-	for k, v := range counts {
-		if v >= threshold {
-			results = append(results, FolderCount{Path: k, Count: v})
+	if toStr != "" {
+		if r.To, err = strconv.Atoi(toStr); err != nil || r.To < 1 {
+			return SegmentRange{}, fmt.Errorf("invalid segment range: %s", s)
 		}
 	}
+	if r.From > 0 && r.To > 0 && r.From > r.To {
+		return SegmentRange{}, fmt.Errorf("invalid segment range: %s", s)
+	}
+	return r, nil
+}
+
+// Apply はスラッシュ区切りのフォルダパスから範囲内の階層だけを取り出します。(純粋関数)
+// 範囲内に階層が存在しない場合は空文字列を返します。
+func (r SegmentRange) Apply(dir string) string {
+	if r.From == 0 && r.To == 0 {
+		return dir
+	}
+	comps := strings.Split(dir, "/")
+	from, to := 0, len(comps)
+	if r.From > 0 {
+		from = r.From - 1
+	}
+	if r.To > 0 && r.To < to {
+		to = r.To
+	}
+	if from >= to {
+		return ""
+	}
+	return strings.Join(comps[from:to], "/")
+}
+
+// noSegmentKey は GroupSegment で指定した階層が存在しないフォルダの集計キーです。
+const noSegmentKey = "(None)"
 
-	// 件数の降順、件数が同じ場合はパスの昇順で安定ソート
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Count == results[j].Count {
-			return results[i].Path < results[j].Path
+// SegmentKey はフォルダパスの n 番目 (1始まり) の階層の名前を集計キーとする AggregateOptions.Key を返します。
+// "*/2024/*" のように階層の位置で意味が決まるフォルダを、その階層の値ごとにまとめるために使います。
+// 階層が n 個に満たないフォルダは "(None)" にまとめます。
+func SegmentKey(n int) func(dir string) string {
+	return func(dir string) string {
+		if dir == "." {
+			return noSegmentKey
 		}
-		return results[i].Count > results[j].Count
-	})
+		comps := strings.Split(dir, "/")
+		if n < 1 || n > len(comps) || comps[n-1] == "" {
+			return noSegmentKey
+		}
+		return comps[n-1]
+	}
+}
+
+// AggregateFolders はファイルエントリのリストを集計し、しきい値以上のものを抽出・ソートします。(純粋関数)
+func AggregateFolders(entries []FileEntry, threshold int) ([]FolderCount, int) {
+	return Aggregate(entries, AggregateOptions{Threshold: threshold})
+}
 
-	return results, processedFiles
+// Aggregate はオプションに従ってファイルエントリのリストを集計し、しきい値以上のものを抽出・ソートします。(純粋関数)
+// opts.MaxMemory を指定した場合は一時ファイルを使いますが、集計を終えると削除します。
+func Aggregate(entries []FileEntry, opts AggregateOptions) ([]FolderCount, int) {
+	acc := NewAccumulator(opts)
+	defer acc.Close()
+	for _, f := range entries {
+		acc.Add(f)
+	}
+	return acc.Result()
+}
+
+// FolderKey はエントリ名から集計キーとなるフォルダパスを求めます。(純粋関数)
+// 正規化、ロールアップ、階層範囲の順に適用します。区切り文字は "/" のままで、出力時に FolderFormat で変換します。
+// opts.Key を指定した場合は、正規化したフォルダパスから opts.Key で求めた値を返します。
+func FolderKey(name string, opts AggregateOptions) string {
+	dirPath := path.Dir(name)
+	if opts.Normalize != nil {
+		dirPath = opts.Normalize(dirPath)
+	}
+	if opts.Key != nil {
+		return opts.Key(dirPath)
+	}
+	if dirPath != "." {
+		dirPath = opts.Segments.Apply(ApplyRollup(opts.Rollup, dirPath))
+	}
+	if dirPath == "." || dirPath == "" {
+		return "(Root)"
+	}
+	return dirPath
 }
 
 // =====================================================================
@@ -82,79 +268,238 @@ type ArchiveReader interface {
 }
 
 // ZipArchiveReader はZIPファイルを実際に読み込む実装です。
-type ZipArchiveReader struct{}
+type ZipArchiveReader struct {
+	// Decoder は NonUTF8 のエントリ名の復号方法です。nil の場合は Shift_JIS を使います。
+	Decoder NameDecoder
+	// Remote はパスがHTTP/HTTPSのURLの場合の読み込み方法です。
+	Remote RemoteOptions
+	// Workers はエントリ名の復号を含む変換を並行して行うゴルーチンの数です。0以下の場合はCPUの数を使います。
+	Workers int
+}
 
 func (z ZipArchiveReader) ReadEntries(zipPath string) ([]FileEntry, error) {
+	entries, _, err := z.ReadEntriesUntil(zipPath, time.Time{})
+	return entries, err
+}
+
+// ReadEntriesUntil は PartialReader の実装です。
+// セントラルディレクトリはすべて読み込み、期限を過ぎた時点でエントリの変換を打ち切ります。
+func (z ZipArchiveReader) ReadEntriesUntil(zipPath string, deadline time.Time) ([]FileEntry, int, error) {
+	files, err := z.open(zipPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, _ := z.entries(files, deadline, nil)
+	return entries, len(files), nil
+}
+
+// ReadEntriesSample は SamplingReader の実装です。
+// セントラルディレクトリはすべて読み込みますが、抽出しないファイルのエントリは名前を復号せずに除きます。
+func (z ZipArchiveReader) ReadEntriesSample(zipPath string, deadline time.Time, percent float64) ([]FileEntry, int, int, error) {
+	files, err := z.open(zipPath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	keep := func(f *zip.File) bool {
+		return isDirHeader(&f.FileHeader) || sampledName(f.Name, percent)
+	}
+	entries, scanned := z.entries(files, deadline, keep)
+	return entries, scanned, len(files), nil
+}
+
+// open はローカルのファイル、分割アーカイブ、URLのいずれかからセントラルディレクトリを読み込みます。
+func (z ZipArchiveReader) open(zipPath string) ([]*zip.File, error) {
+	if isRemotePath(zipPath) {
+		return z.openRemote(zipPath)
+	}
+	if volumes := splitVolumes(zipPath); len(volumes) > 1 {
+		return openSplit(volumes)
+	}
+	if err := missingVolumesError(zipPath); err != nil {
+		return nil, err
+	}
+	if err := CheckArchiveFile(zipPath); err != nil {
+		return nil, err
+	}
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open zip: %w", err)
 	}
 	defer r.Close()
+	return r.File, nil
+}
 
-	var entries []FileEntry
-	for _, f := range r.File {
-		name := f.Name
-
-		// ZIPのフラグを見てUTF-8でない（Shift_JISの可能性が高い）と判定された場合の処理
-		if f.NonUTF8 {
-			decodedName, err := decodeShiftJIS(name)
-			if err == nil {
-				name = decodedName // 変換に成功した場合のみ上書き
+// openRemote はURLのアーカイブを範囲指定のリクエストで読み込みます。
+// セントラルディレクトリだけを並行して取得するため、アーカイブ全体はダウンロードしません。
+func (z ZipArchiveReader) openRemote(url string) ([]*zip.File, error) {
+	ra, err := openRangeReader(url, z.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := prefetchCentralDirectory(ra); err != nil {
+		return nil, err
+	}
+	r, err := zip.NewReader(ra, ra.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip: %w", err)
+	}
+	return r.File, nil
+}
+
+// entryCheckInterval は期限を確認するエントリの間隔です。
+const entryCheckInterval = 1024
+
+// entries はZIPのエントリを FileEntry に変換し、変換したエントリと読み込んだエントリの数を返します。
+// エントリを entryCheckInterval 件ずつに分け、Workers 個のゴルーチンで並行して変換しますが、結果はエントリの順に並びます。
+// deadline がゼロ値でない場合は、期限を過ぎた時点で先頭から続けて変換済みのエントリだけを返します。
+// keep が nil でない場合は、keep が false を返すエントリを変換せずに除きます。
+func (z ZipArchiveReader) entries(files []*zip.File, deadline time.Time, keep func(*zip.File) bool) ([]FileEntry, int) {
+	decoder := z.Decoder
+	if decoder == nil {
+		decoder = EncodingDecoder{Encoding: japanese.ShiftJIS}
+	}
+
+	entries := make([]FileEntry, len(files))
+	chunks := (len(files) + entryCheckInterval - 1) / entryCheckInterval
+	// converted は変換を終えた区間です。区間ごとに1つのゴルーチンだけが書き込みます。
+	converted := make([]bool, chunks)
+	// kept は keep で残したエントリです。keep が nil の場合は使いません。
+	var kept []bool
+	if keep != nil {
+		kept = make([]bool, len(files))
+	}
+	var next atomic.Int64
+	work := func() {
+		for {
+			c := int(next.Add(1) - 1)
+			if c >= chunks {
+				return
+			}
+			// 先頭の区間は期限を過ぎていても変換し、空の結果にしない
+			if c > 0 && !deadline.IsZero() && time.Now().After(deadline) {
+				return
 			}
+			end := min((c+1)*entryCheckInterval, len(files))
+			for i := c * entryCheckInterval; i < end; i++ {
+				if keep != nil {
+					if !keep(files[i]) {
+						continue
+					}
+					kept[i] = true
+				}
+				entries[i] = zipFileEntry(files[i], decoder)
+			}
+			converted[c] = true
 		}
+	}
 
-		entries = append(entries, FileEntry{
-			Name:  name,
-			IsDir: f.FileInfo().IsDir(),
-		})
+	workers := z.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers = min(workers, chunks); workers <= 1 {
+		work()
+	} else {
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		wg.Wait()
 	}
-	return entries, nil
-}
 
-// decodeShiftJIS はShift_JISの文字列をUTF-8に変換するヘルパー関数です。(純粋関数)
-func decodeShiftJIS(s string) (string, error) {
-	decoder := japanese.ShiftJIS.NewDecoder()
-	b, _, err := transform.Bytes(decoder, []byte(s))
-	if err != nil {
-		return "", err
+	done := 0
+	for done < chunks && converted[done] {
+		done++
+	}
+	scanned := min(done*entryCheckInterval, len(files))
+	if keep == nil {
+		return entries[:scanned], scanned
+	}
+	result := entries[:0]
+	for i := range scanned {
+		if kept[i] {
+			result = append(result, entries[i])
+		}
 	}
-	return string(b), nil
+	return result, scanned
 }
 
-// WriteCSV は結果をCSV形式でWriterに出力します。
-func WriteCSV(w io.Writer, results []FolderCount) error {
-	// BOMを出力
-	if _, err := w.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
-		return err
+// zipFileEntry は1件のZIPのエントリを FileEntry に変換します。
+func zipFileEntry(f *zip.File, decoder NameDecoder) FileEntry {
+	name, comment := f.Name, f.Comment
+
+	// ZIPのフラグを見てUTF-8でない（Shift_JISの可能性が高い）と判定された場合の処理
+	if f.NonUTF8 {
+		if decoded, confidence := decoder.Decode([]byte(name)); confidence > ConfidenceNone {
+			// 復号後に残るバックスラッシュは古いWindowsのツールが使う区切り文字
+			// (2バイト文字の一部だった 0x5C は復号で文字に含まれる)
+			name = strings.ReplaceAll(decoded, "\\", "/")
+		}
+		// コメントもエントリ名と同じフラグで文字コードが示される
+		if comment != "" {
+			if decoded, confidence := decoder.Decode([]byte(comment)); confidence > ConfidenceNone {
+				comment = decoded
+			}
+		}
 	}
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
 
-	if err := writer.Write([]string{"Folder Path", "File Count"}); err != nil {
-		return err
+	// Unix以外で作成されたエントリの Mode() は属性から補ったパーミッションのため使わない
+	var mode fs.FileMode
+	if creator := f.CreatorVersion >> 8; creator == creatorUnix || creator == creatorMacOSX {
+		mode = f.Mode()
 	}
-	for _, r := range results {
-		if err := writer.Write([]string{r.Path, strconv.Itoa(r.Count)}); err != nil {
-			return err
-		}
+	// 更新日時は archive/zip が拡張フィールドを優先して求めるため、記録元と他の日時だけを読む
+	times := parseEntryTimes(f.Extra)
+
+	return FileEntry{
+		Name:           name,
+		RawName:        f.Name,
+		IsDir:          isDirHeader(&f.FileHeader),
+		Modified:       f.Modified,
+		Size:           f.UncompressedSize64,
+		CompressedSize: f.CompressedSize64,
+		Method:         f.Method,
+		CRC32:          f.CRC32,
+		Mode:           mode,
+		Owner:          parseUnixOwner(f.Extra),
+		Created:        times.Created,
+		Accessed:       times.Accessed,
+		TimeSource:     times.Source,
+		Comment:        comment,
 	}
-	return nil
 }
 
-// WriteText は結果をプレーンテキストでWriterに出力します。
-func WriteText(w io.Writer, results []FolderCount) error {
-	_, err := fmt.Fprintf(w, "\n%-60s | %s\n", "Folder Path", "File Count")
-	if err != nil {
-		return err
+// ZIPの作成元OS (CreatorVersion の上位バイト) と外部属性のうち、ディレクトリ判定に使う値です。
+const (
+	creatorFAT    = 0
+	creatorUnix   = 3
+	creatorNTFS   = 11
+	creatorVFAT   = 14
+	creatorMacOSX = 19
+
+	unixTypeMask = 0xf000
+	unixTypeDir  = 0x4000
+	msdosDir     = 0x10
+)
+
+// isDirHeader はエントリがディレクトリかを、末尾のスラッシュと外部属性から判定します。(純粋関数)
+// f.FileInfo().IsDir() と同じ結果になりますが、エントリごとの FileInfo の割り当てを避けられるため
+// 数百万エントリのアーカイブで割り当て回数を大きく減らせます。
+func isDirHeader(h *zip.FileHeader) bool {
+	if strings.HasSuffix(h.Name, "/") {
+		return true
 	}
-	fmt.Fprintln(w, strings.Repeat("-", 80))
-	for _, r := range results {
-		_, err := fmt.Fprintf(w, "%-60s | %d\n", r.Path, r.Count)
-		if err != nil {
-			return err
-		}
+	switch h.CreatorVersion >> 8 {
+	case creatorUnix, creatorMacOSX:
+		return (h.ExternalAttrs>>16)&unixTypeMask == unixTypeDir
+	case creatorNTFS, creatorVFAT, creatorFAT:
+		return h.ExternalAttrs&msdosDir != 0
 	}
-	return nil
+	return false
 }
 
 // =====================================================================
@@ -162,14 +507,107 @@ func WriteText(w io.Writer, results []FolderCount) error {
 // =====================================================================
 
 type AppConfig struct {
-	ZipPath   string
-	Threshold int
-	CsvPath   string
+	ZipPath string
+	// ArchiveName はレポートと履歴に記録するアーカイブの名前です。空の場合は ZipPath を記録します。
+	// 署名付きURLから読み込む場合に、署名を含まない名前を記録するために使います。
+	ArchiveName string
+	Threshold   int
+	// ThresholdRules はフォルダパスのパターンごとのしきい値です。
+	ThresholdRules []ThresholdRule
+	// ThresholdPercent は全体のファイル数に対する割合のしきい値です。
+	ThresholdPercent float64
+	Segments         SegmentRange
+	// GroupSegment が正の場合、フォルダパスのこの番号 (1始まり) の階層の名前ごとに集計します。
+	GroupSegment int
+	Filter       EntryFilter
+	// MaxMemory が正の場合、フォルダごとの集計途中の値がこのバイト数を超えると一時ファイルに書き出します。
+	MaxMemory uint64
+	// SpillDir は集計途中の値を書き出す一時ファイルのフォルダです。空の場合は既定の一時フォルダです。
+	SpillDir string
+	// Normalization はフォルダパスのUnicode正規化方式 (nfc, nfd, nfkc, nfkd) です。空の場合は正規化しません。
+	Normalization string
+	// RollupPath はフォルダ集約ルールの定義ファイルのパスです。
+	RollupPath string
+	CsvPath    string
+	// CsvAppendPath が指定された場合、結果を実行日時とアーカイブ名の列を付けてCSVファイルに追記します。
+	CsvAppendPath string
+	// NoClobber が true の場合、結果などのファイルが既にあれば集計せずにエラーとします。追記するCSVファイルには適用しません。
+	// いずれの場合もファイルは一時ファイルに書いてから置き換えるため、途中まで書いたファイルは残りません。
+	NoClobber bool
+	// DetailCsvPath が指定された場合、ロールアップや階層範囲を適用しない末端フォルダの集計もCSVに出力します。
+	DetailCsvPath string
+	// ChartPath が指定された場合、上位のフォルダの横棒グラフを拡張子に応じてPNGまたはSVGで出力します。
+	ChartPath string
+	Chart     ChartOptions
+	// ManifestPath が指定された場合、集計と同じ読み込みからエントリごとの一覧を出力します。
+	ManifestPath string
+	// ManifestFormat はエントリごとの一覧の形式 (csv, jsonl) です。空の場合は ManifestPath の拡張子から決めます。
+	ManifestFormat string
+	// CSV はCSVファイルおよびCSV形式の画面出力の形式です。
+	CSV    CSVOptions
+	Format string
+	// Outputs は追加の出力先です。CsvPath と合わせていずれも指定されない場合は Format で標準出力に書き出します。
+	Outputs []OutputTarget
+	// Stats が true の場合、フォルダごとのサイズの統計を求めてすべての出力形式に含めます。
+	Stats bool
+	// Cumulative が true の場合、配下のフォルダを含む累積の件数と、親フォルダと全体に対する割合を出力します。
+	Cumulative bool
+	// CollapseOther が true の場合、しきい値未満のフォルダを1行にまとめて出力し、すべてのファイルを結果に含めます。
+	CollapseOther bool
+	// Columns は text, csv, json 形式で出力する列の名前と順序です。空の場合は形式ごとの既定の列です。
+	Columns []string
+	// Paths は出力するフォルダパスの区切り文字とルートフォルダの表記です。
+	Paths FolderFormat
+	// SHA256 が true の場合、アーカイブ自体のSHA-256を計算してレポートに含めます。
+	SHA256 bool
+	// SQLitePath が指定された場合、実行記録と集計結果をSQLiteデータベースに追記します。
+	SQLitePath string
+	// Retention は SQLitePath に追記した後に適用する保持条件です。
+	Retention RetentionPolicy
+	// Policy は集計結果を判定し、ERROR の検出事項と終了コードを決める条件です。
+	Policy ResultPolicy
+	// FlagExtensions が指定された場合、名前がこれらの拡張子で終わるファイルを検出事項として報告します。
+	FlagExtensions []string
+	// FailOnFlagged が true の場合、FlagExtensions で報告するファイルを ERROR とし、終了コード5で終了します。
+	FailOnFlagged bool
+	// NamingRules が指定された場合、フォルダパスがすべての規則に一致しないフォルダを検出事項として報告します。
+	NamingRules []*regexp.Regexp
+	// FailOnNaming が true の場合、NamingRules に違反するフォルダを ERROR とし、終了コード6で終了します。
+	FailOnNaming bool
+	// ControlFile が指定された場合、アーカイブ内のこの名前の管理ファイル (フォルダと期待するファイル数のCSV) と
+	// 実際のファイル数を照合し、一致しなければ終了コード7で終了します。管理ファイル自体は照合の件数に含めません。
+	ControlFile string
+	// ReportSymlinks が true の場合、シンボリックリンクと特殊なエントリを1件ずつ検出事項として報告します。
+	ReportSymlinks bool
+	// ExpectPath が指定された場合、期待するフォルダとファイル数の範囲を定義した仕様と照合します。
+	ExpectPath string
+	// Dedupe は同じ名前のエントリが複数ある場合の集計方法です。ゼロ値は DedupeLast と同じです。
+	Dedupe DedupePolicy
+	// TimeBudget は読み込みの時間の上限です。0の場合は上限なしです。
+	TimeBudget time.Duration
+	// SamplePercent は集計するファイルを抽出する割合 (パーセント) です。0の場合はすべてのファイルを集計します。
+	SamplePercent float64
+	// Color は標準出力へのテキスト出力で、重要度に応じて色を付けるかを表します。
+	Color bool
+	// Lang はテキスト出力の見出しの言語です。
+	Lang Lang
+	// TemplatePath は template 形式の出力に使うテンプレートファイルのパスです。
+	TemplatePath string
 }
 
 type App struct {
 	Reader ArchiveReader
 	Logger *slog.Logger
+	// Stdin はパスに "-" が指定された場合の入力です。nilの場合は os.Stdin を使います。
+	Stdin io.Reader
+}
+
+// archiveName はレポートと履歴に記録するアーカイブの名前を返します。(純粋関数)
+func (cfg AppConfig) archiveName() string {
+	if cfg.ArchiveName != "" {
+		return cfg.ArchiveName
+	}
+	return cfg.ZipPath
 }
 
 // Run はアプリケーションのメインフローを実行します。
@@ -177,60 +615,392 @@ func (app *App) Run(cfg AppConfig, outStream io.Writer) error {
 	if cfg.ZipPath == "" {
 		return errors.New("zip path is required")
 	}
+	app = app.withCorrelationID()
+	// 長時間の解析後に失敗しないよう、出力形式は事前に検証する
+	outputs, err := newOutputDispatcher(cfg.outputTargets(), WriterOptions{CSV: cfg.CSV, Color: cfg.Color, Template: cfg.TemplatePath, Lang: cfg.Lang, Columns: cfg.Columns, Paths: cfg.Paths}, cfg.NoClobber)
+	if err != nil {
+		return err
+	}
+	if cfg.NoClobber {
+		for _, p := range []string{cfg.DetailCsvPath, cfg.ChartPath, cfg.ManifestPath} {
+			if p == "" {
+				continue
+			}
+			if err := checkNoClobber(p); err != nil {
+				return err
+			}
+		}
+	}
+	outputs.onFile = func(t OutputTarget) {
+		app.Logger.Info("結果をファイルに出力しました", slog.String("format", t.Format), slog.String("path", t.Path))
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	if cfg.ChartPath != "" {
+		if _, err := chartWriter(cfg.ChartPath); err != nil {
+			return err
+		}
+		// サイズのグラフにはフォルダごとの合計サイズが必要
+		opts.Stats = opts.Stats || cfg.Chart.By == "size"
+	}
+	opts.Stats = opts.Stats || columnsNeedStats(cfg.Columns)
+	var expectations []FolderExpectation
+	if cfg.ExpectPath != "" {
+		if expectations, err = LoadExpectations(cfg.ExpectPath); err != nil {
+			return err
+		}
+	}
+	var manifest string
+	if cfg.ManifestPath != "" {
+		if manifest, err = manifestFormat(cfg.ManifestPath, cfg.ManifestFormat); err != nil {
+			return err
+		}
+	}
 
-	app.Logger.Info("ZIPファイルの解析を開始します", slog.String("zipPath", cfg.ZipPath))
-
-	entries, err := app.Reader.ReadEntries(cfg.ZipPath)
+	var deadline time.Time
+	if cfg.TimeBudget > 0 {
+		deadline = time.Now().Add(cfg.TimeBudget)
+	}
+	var control []ControlEntry
+	controlMissing := false
+	var readControl func(ArchiveReader, string) error
+	if cfg.ControlFile != "" {
+		readControl = func(reader ArchiveReader, localPath string) error {
+			fr, ok := reader.(ArchiveFileReader)
+			if !ok {
+				return fmt.Errorf("control file is not supported for %s", cfg.ZipPath)
+			}
+			data, err := fr.ReadArchiveFile(localPath, cfg.ControlFile)
+			if errors.Is(err, fs.ErrNotExist) {
+				controlMissing = true
+				app.Logger.Warn("管理ファイルがアーカイブ内にありません", slog.String("controlFile", cfg.ControlFile))
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			control, err = ParseControlFile(data)
+			return err
+		}
+	}
+	entries, sum, info, partial, err := app.readEntriesUntil(cfg.ZipPath, cfg.SHA256, deadline, cfg.SamplePercent, readControl)
 	if err != nil {
-		return fmt.Errorf("read entries error: %w", err)
+		return err
+	}
+	aggOpts := opts
+	if partial != nil {
+		if partial.ScannedEntries < partial.TotalEntries {
+			partial.Budget = cfg.TimeBudget.String()
+			app.Logger.Warn("時間の上限に達したため、読み込みを打ち切りました", slog.Int("scannedEntries", partial.ScannedEntries), slog.Int("totalEntries", partial.TotalEntries))
+		}
+		if partial.SamplePercent > 0 {
+			app.Logger.Info("ファイルを抽出して集計します", slog.Float64("samplePercent", partial.SamplePercent), slog.Int("sampledFiles", partial.SampledFiles))
+		}
+		aggOpts = partial.scaledOptions(opts)
+	}
+	if cfg.ManifestPath != "" {
+		if err := writeManifestFile(cfg.ManifestPath, manifest, entries, cfg.CSV, cfg.NoClobber); err != nil {
+			return err
+		}
+		app.Logger.Info("エントリの一覧を出力しました", slog.String("format", manifest), slog.String("manifestPath", cfg.ManifestPath), slog.Int("entries", len(entries)))
+	}
+	duplicates := FindDuplicates(entries, opts)
+	if len(duplicates) > 0 {
+		entries = DedupeEntries(entries, cfg.Dedupe)
+		app.Logger.Warn("同じ名前のエントリが複数あります", slog.Int("names", len(duplicates)), slog.String("dedupe", string(cfg.Dedupe)))
+	}
+	results, totalFiles, err := app.aggregate(entries, aggOpts)
+	if err != nil {
+		return err
+	}
+	if partial != nil {
+		results = partial.applyEstimates(results, opts)
+		partial.EstimatedTotalFiles, partial.EstimatedTotalMargin = partial.estimate(totalFiles), partial.margin(totalFiles)
+	}
+	report := &Report{Archive: cfg.archiveName(), SHA256: sum, TotalFiles: totalFiles, Folders: results, Stats: cfg.Stats, Cumulative: cfg.Cumulative, Partial: partial, Info: &info, RunAt: time.Now()}
+	special := FindSpecialEntries(entries, opts)
+	report.Symlinks, report.SpecialEntries = countSpecialEntries(special)
+
+	// 詳細レポートの指定がある場合は、同じエントリからロールアップ等を適用しない集計も出力する
+	if cfg.DetailCsvPath != "" {
+		detail := *report
+		detailOpts := opts
+		detailOpts.Rollup, detailOpts.Segments, detailOpts.Key = nil, SegmentRange{}, nil
+		detail.Folders, _ = Aggregate(entries, detailOpts)
+		if err := writeCSVFile(cfg.DetailCsvPath, cfg.Paths.Report(&detail), cfg.CSV, cfg.NoClobber); err != nil {
+			return err
+		}
+		app.Logger.Info("詳細レポートをCSVに出力しました", slog.String("detailCsvPath", cfg.DetailCsvPath))
 	}
 
-	results, totalFiles := AggregateFolders(entries, cfg.Threshold)
-	app.Logger.Info("集計完了", slog.Int("totalFiles", totalFiles), slog.Int("extractedFolders", len(results)))
+	if cfg.ChartPath != "" {
+		if err := writeChartFile(cfg.ChartPath, cfg.Paths.Report(report), cfg.Chart, cfg.NoClobber); err != nil {
+			return err
+		}
+		app.Logger.Info("グラフを出力しました", slog.String("chartPath", cfg.ChartPath))
+	}
 
-	// CSV出力指定がある場合
+	if cfg.SQLitePath != "" {
+		if err := saveToSQLite(cfg, cfg.Paths.Folders(results), totalFiles); err != nil {
+			return err
+		}
+		app.Logger.Info("結果をSQLiteに登録しました", slog.String("sqlitePath", cfg.SQLitePath))
+	}
+
+	// しきい値で除外されたフォルダも上限の判定に含める
+	policyFolders := results
+	// 上限と仕様は累積でないフォルダ直下の件数で判定する
+	if cfg.Policy.FailIfOver > 0 && (opts.Threshold > cfg.Policy.overLimitThreshold() || len(opts.ThresholdRules) > 0 || opts.ThresholdPercent > 0 || opts.Cumulative || opts.CollapseOther) {
+		policyOpts := opts
+		policyOpts.Threshold, policyOpts.ThresholdRules, policyOpts.ThresholdPercent = cfg.Policy.overLimitThreshold(), nil, 0
+		policyOpts.Cumulative, policyOpts.CollapseOther = false, false
+		policyFolders, _ = Aggregate(entries, policyOpts)
+	}
+	// 仕様と命名規則はしきい値によらずすべてのフォルダで判定する
+	var expectFolders []FolderCount
+	if len(expectations) > 0 || len(cfg.NamingRules) > 0 {
+		allOpts := opts
+		allOpts.Threshold, allOpts.ThresholdRules, allOpts.ThresholdPercent = 0, nil, 0
+		allOpts.Cumulative, allOpts.CollapseOther = false, false
+		expectFolders, _ = Aggregate(entries, allOpts)
+	}
+	var controlFolders []FolderCount
+	if cfg.ControlFile != "" && !controlMissing {
+		allOpts := opts
+		allOpts.Threshold, allOpts.ThresholdRules, allOpts.ThresholdPercent = 0, nil, 0
+		allOpts.Cumulative, allOpts.CollapseOther = false, false
+		controlFolders, _ = Aggregate(withoutEntry(entries, cfg.ControlFile), allOpts)
+	}
+	report.Findings = BuildFindings(report, FindingsOptions{
+		Threshold:        opts.Threshold,
+		ThresholdRules:   opts.ThresholdRules,
+		ThresholdPercent: opts.ThresholdPercent,
+		CSV:              cfg.CSV,
+		Policy:           cfg.Policy,
+		PolicyFolders:    policyFolders,
+		Expectations:     expectations,
+		ExpectFolders:    expectFolders,
+		Flagged:          FlagExecutables(entries, cfg.FlagExtensions, opts),
+		FailOnFlagged:    cfg.FailOnFlagged,
+		NamingViolations: CheckFolderNames(expectFolders, cfg.NamingRules),
+		FailOnNaming:     cfg.FailOnNaming,
+		ControlFile:      cfg.ControlFile,
+		Control:          control,
+		ControlMissing:   controlMissing,
+		ControlFolders:   controlFolders,
+		Special:          special,
+		ReportSpecial:    cfg.ReportSymlinks,
+		FollowSymlinks:   opts.Filter.Symlinks,
+		Duplicates:       duplicates,
+		Dedupe:           cfg.Dedupe,
+	})
+
+	start := time.Now()
+	if err := outputs.Write(outStream, report); err != nil {
+		return err
+	}
+	app.Logger.Debug("結果の出力の処理時間", slog.Duration("elapsed", time.Since(start)))
+	return PolicyError(report.Findings)
+}
+
+// outputTargets は設定から出力先の一覧を組み立てます。
+// CsvPath、CsvAppendPath、Outputs のいずれも指定されない場合は、Format で標準出力に書き出します。
+// CsvPath と画面表示を併用する場合は、Outputs に標準出力の出力先を含めます。
+func (cfg AppConfig) outputTargets() []OutputTarget {
+	var targets []OutputTarget
 	if cfg.CsvPath != "" {
-		file, err := os.Create(cfg.CsvPath)
+		targets = append(targets, OutputTarget{Format: "csv", Path: cfg.CsvPath})
+	}
+	if cfg.CsvAppendPath != "" {
+		targets = append(targets, OutputTarget{Format: "csv", Path: cfg.CsvAppendPath, Append: true})
+	}
+	targets = append(targets, cfg.Outputs...)
+	if len(targets) == 0 {
+		targets = append(targets, OutputTarget{Format: cfg.Format})
+	}
+	return targets
+}
+
+// Analyze はアーカイブを読み込んで集計し、抽出結果と総ファイル数を返します。
+func (app *App) Analyze(zipPath string, opts AggregateOptions) ([]FolderCount, int, error) {
+	app = app.withCorrelationID()
+	entries, _, err := app.readEntries(zipPath, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return app.aggregate(entries, opts)
+}
+
+// readEntries はアーカイブのエントリを読み込みます。
+// computeHash が true の場合はアーカイブ自体のSHA-256も計算して返します。
+// パスが "-" の場合は標準入力を一時ファイルに書き出してから読み込みます (ハッシュは常に計算されます)。
+func (app *App) readEntries(zipPath string, computeHash bool) ([]FileEntry, string, error) {
+	entries, sum, _, _, err := app.readEntriesUntil(zipPath, computeHash, time.Time{}, 0, nil)
+	return entries, sum, err
+}
+
+// readEntriesUntil は readEntries と同様にエントリを読み込みますが、期限を過ぎた時点で読み込みを打ち切ります。
+// samplePercent が0より大きい場合は、読み込みながらファイルのエントリをその割合で抽出します。
+// 3つ目の戻り値はアーカイブ全体の情報で、Entries はアーカイブ内のエントリの総数です。
+// 4つ目の戻り値は打ち切ったか抽出した場合の集計した範囲で、すべてのエントリを読み込んだ場合は nil です。
+// withArchive が nil でない場合は、エントリを読み込んだ後、標準入力の一時ファイルを削除する前に、
+// 読み込みに使った ArchiveReader とローカルのパスを渡して呼び出します。
+func (app *App) readEntriesUntil(zipPath string, computeHash bool, deadline time.Time, samplePercent float64, withArchive func(reader ArchiveReader, localPath string) error) ([]FileEntry, string, ArchiveInfo, *PartialScan, error) {
+	app.Logger.Info("ZIPファイルの解析を開始します", slog.String("zipPath", zipPath))
+
+	localPath, sum := zipPath, ""
+	if zipPath == StdinPath {
+		stdin := app.Stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		tmpPath, tmpSum, cleanup, err := spoolToTempFile(stdin)
 		if err != nil {
-			return fmt.Errorf("failed to create csv file: %w", err)
+			return nil, "", ArchiveInfo{}, nil, err
 		}
-		defer file.Close()
-
-		if err := WriteCSV(file, results); err != nil {
-			return fmt.Errorf("failed to write csv: %w", err)
+		defer cleanup()
+		localPath, sum = tmpPath, tmpSum
+	} else if computeHash {
+		if isRemotePath(zipPath) {
+			return nil, "", ArchiveInfo{}, nil, errors.New("sha256 is not supported for remote archives")
+		}
+		var err error
+		if sum, err = hashFile(zipPath); err != nil {
+			return nil, "", ArchiveInfo{}, nil, err
 		}
-		app.Logger.Info("結果をCSVに出力しました", slog.String("csvPath", cfg.CsvPath))
-		return nil
 	}
 
-	// 画面出力指定の場合
-	return WriteText(outStream, results)
+	reader := app.Reader
+	if auto, ok := reader.(AutoArchiveReader); ok && zipPath == StdinPath {
+		// 一時ファイルは毎回パスが変わり再利用できないため、キャッシュに保存しない
+		auto.Cache = nil
+		reader = auto
+	}
+	start := time.Now()
+	var entries []FileEntry
+	var scanned, total int
+	var err error
+	if samplePercent > 0 {
+		entries, scanned, total, err = readEntriesSample(reader, localPath, deadline, samplePercent)
+	} else {
+		entries, total, err = readEntriesUntil(reader, localPath, deadline)
+		scanned = len(entries)
+	}
+	if err != nil {
+		return nil, "", ArchiveInfo{}, nil, fmt.Errorf("read entries error: %w", err)
+	}
+	app.logDecodeDecisions(entries)
+	app.Logger.Debug("エントリを読み込みました", slog.Int("entries", len(entries)), slog.Duration("elapsed", time.Since(start)))
+	if withArchive != nil {
+		if err := withArchive(reader, localPath); err != nil {
+			return nil, "", ArchiveInfo{}, nil, err
+		}
+	}
+	var partial *PartialScan
+	if scanned < total || samplePercent > 0 {
+		partial = &PartialScan{ScannedEntries: scanned, TotalEntries: total}
+		if samplePercent > 0 {
+			partial.SamplePercent = samplePercent
+			for _, e := range entries {
+				if !e.IsDir {
+					partial.SampledFiles++
+				}
+			}
+		}
+	}
+	return entries, sum, readArchiveInfo(reader, localPath, entries, total), partial, nil
 }
 
-// =====================================================================
-// Entry Point
-// =====================================================================
+func (app *App) aggregate(entries []FileEntry, opts AggregateOptions) ([]FolderCount, int, error) {
+	start := time.Now()
+	acc := NewAccumulator(opts)
+	defer acc.Close()
+	for _, f := range entries {
+		acc.Add(f)
+	}
+	results, totalFiles := acc.Result()
+	if acc.Spilled() {
+		app.Logger.Info("メモリの上限を超えたため、一時ファイルを使って集計しました", slog.Uint64("maxMemory", opts.MaxMemory))
+	}
+	if err := acc.Err(); errors.Is(err, errIncompleteResult) {
+		return nil, 0, err
+	} else if err != nil {
+		app.Logger.Warn("一時ファイルに書き出せなかったため、メモリ上で集計を続けました", slog.String("error", err.Error()))
+	}
+	app.Logger.Info("集計完了", slog.Int("totalFiles", totalFiles), slog.Int("extractedFolders", len(results)))
+	app.Logger.Debug("集計の処理時間", slog.Duration("elapsed", time.Since(start)))
+	return results, totalFiles, nil
+}
 
-func main() {
-	zipPath := flag.String("zip", "", "対象のZIPファイルのパス (必須)")
-	threshold := flag.Int("threshold", 10000, "抽出するファイル数のしきい値")
-	csvPath := flag.String("csv", "", "結果を出力するCSVファイルのパス (省略時は画面表示)")
-	flag.Parse()
+// writeCSVFile は結果を一時ファイルに書いてからCSVファイルに置き換えます。
+// noClobber が true の場合、ファイルが既にあればエラーを返します。
+func writeCSVFile(csvPath string, report *Report, opts CSVOptions, noClobber bool) error {
+	return writeCSVRecordsFile(csvPath, opts, noClobber, func(records csvRecordWriter) error {
+		return writeReportCSV(records, report, opts)
+	})
+}
 
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	app := &App{
-		Reader: ZipArchiveReader{},
-		Logger: logger,
+// aggregateOptions は設定から集計オプションを組み立てます。ルールファイルの読み込みもここで行います。
+func (cfg AppConfig) aggregateOptions() (AggregateOptions, error) {
+	opts := AggregateOptions{
+		Threshold:        cfg.Threshold,
+		ThresholdRules:   cfg.ThresholdRules,
+		ThresholdPercent: cfg.ThresholdPercent,
+		Filter:           cfg.Filter,
+		Segments:         cfg.Segments,
+		Stats:            cfg.Stats,
+		Cumulative:       cfg.Cumulative,
+		CollapseOther:    cfg.CollapseOther,
+		MaxMemory:        cfg.MaxMemory,
+		SpillDir:         cfg.SpillDir,
+	}
+	normalize, err := ParseNormalization(cfg.Normalization)
+	if err != nil {
+		return AggregateOptions{}, err
+	}
+	opts.Normalize = normalize
+	if cfg.GroupSegment > 0 {
+		opts.Key = SegmentKey(cfg.GroupSegment)
+	}
+	if cfg.RollupPath != "" {
+		rules, err := LoadRollupRules(cfg.RollupPath)
+		if err != nil {
+			return AggregateOptions{}, err
+		}
+		opts.Rollup = rules
 	}
+	return opts, nil
+}
 
-	cfg := AppConfig{
-		ZipPath:   *zipPath,
-		Threshold: *threshold,
-		CsvPath:   *csvPath,
+// saveToSQLite は集計結果を履歴データベースに登録します。
+func saveToSQLite(cfg AppConfig, results []FolderCount, totalFiles int) error {
+	store, err := OpenSQLiteStore(cfg.SQLitePath)
+	if err != nil {
+		return err
 	}
+	defer store.Close()
 
-	if err := app.Run(cfg, os.Stdout); err != nil {
-		logger.Error("アプリケーションエラー", slog.String("error", err.Error()))
-		os.Exit(1)
+	run := RunRecord{
+		Archive:    cfg.archiveName(),
+		RunAt:      time.Now(),
+		Threshold:  cfg.Threshold,
+		TotalFiles: totalFiles,
+	}
+	if _, err := store.SaveRun(run, results); err != nil {
+		return fmt.Errorf("failed to save to sqlite: %w", err)
 	}
+	if _, err := store.Prune(cfg.Retention, run.RunAt); err != nil {
+		return fmt.Errorf("failed to prune sqlite: %w", err)
+	}
+	return nil
+}
+
+// =====================================================================
+// Entry Point
+// =====================================================================
+
+func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
 }
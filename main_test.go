@@ -1,11 +1,20 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"go-ObuZipCount/obuziptest"
 )
 
 // MockArchiveReader はテスト用のモックです。
@@ -120,4 +129,402 @@ func TestAppRun(t *testing.T) {
 			t.Errorf("output does not contain expected text, got: %s", outStream.String())
 		}
 	})
+
+	t.Run("正常系：詳細レポートを同じエントリから出力", func(t *testing.T) {
+		app := &App{
+			Reader: MockArchiveReader{
+				Entries: []FileEntry{{Name: "a/b/1.txt"}, {Name: "a/c/2.txt"}},
+			},
+			Logger: logger,
+		}
+		detailPath := filepath.Join(t.TempDir(), "detail.csv")
+		outStream := new(bytes.Buffer)
+		cfg := AppConfig{ZipPath: "dummy.zip", Threshold: 1, Segments: SegmentRange{To: 1}, DetailCsvPath: detailPath}
+		if err := app.Run(cfg, outStream); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Contains(outStream.Bytes(), []byte("a ")) {
+			t.Errorf("rolled-up output does not contain folder a, got: %s", outStream.String())
+		}
+		detail, err := os.ReadFile(detailPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(detail, []byte(`a\b,1`)) || !bytes.Contains(detail, []byte(`a\c,1`)) {
+			t.Errorf("detail output does not contain leaf folders, got: %s", detail)
+		}
+	})
+
+	t.Run("異常系：未知の出力形式は読み込み前にエラー", func(t *testing.T) {
+		app := &App{
+			Reader: MockArchiveReader{Err: errors.New("must not be called")},
+			Logger: logger,
+		}
+		err := app.Run(AppConfig{ZipPath: "dummy.zip", Format: "xml"}, bytes.NewBuffer(nil))
+		if err == nil || err.Error() != "unknown format: xml" {
+			t.Errorf("expected 'unknown format: xml' error, got %v", err)
+		}
+	})
+}
+
+// WriteMarkdown のテスト (エスケープ処理の検証)
+func TestWriteMarkdown(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := WriteMarkdown(out, &Report{Folders: []FolderCount{{Path: `dir1\a|b`, Count: 3}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "| Folder Path | File Count |\n| --- | ---: |\n| dir1\\\\a\\|b | 3 |\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+// WriteMarkdown のサイズ統計の列のテスト
+func TestWriteMarkdownStats(t *testing.T) {
+	out := new(bytes.Buffer)
+	report := &Report{Stats: true, Folders: []FolderCount{
+		{Path: "dir1", Count: 2, Stats: &FolderStats{TotalSize: 2048, AverageSize: 1024, LargestFile: "a.pdf", LargestSize: 1536}},
+	}}
+	if err := WriteMarkdown(out, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "| Folder Path | File Count | Total Size | Avg Size | Largest File | Largest Size |\n" +
+		"| --- | ---: | ---: | ---: | --- | ---: |\n" +
+		"| dir1 | 2 | 2.0 KB | 1.0 KB | a.pdf | 1.5 KB |\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+// WriteHTML のテスト (HTMLエスケープと棒グラフ幅の検証)
+func TestWriteHTML(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := WriteHTML(out, &Report{Folders: []FolderCount{
+		{Path: "<dir1>", Count: 4},
+		{Path: "dir2", Count: 1},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"&lt;dir1&gt;", "width: 100.0%", "width: 25.0%", "Files: 5"} {
+		if !bytes.Contains(out.Bytes(), []byte(want)) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+}
+
+// ParseSegmentRange のテスト
+func TestParseSegmentRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected SegmentRange
+		wantErr  bool
+	}{
+		{input: "", expected: SegmentRange{}},
+		{input: "2:4", expected: SegmentRange{From: 2, To: 4}},
+		{input: "2:", expected: SegmentRange{From: 2}},
+		{input: ":3", expected: SegmentRange{To: 3}},
+		{input: "3", expected: SegmentRange{From: 3, To: 3}},
+		{input: "4:2", wantErr: true},
+		{input: "0:2", wantErr: true},
+		{input: "a:b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseSegmentRange(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+// Aggregate の階層範囲指定のテスト
+func TestAggregateSegments(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "del1/wardA/case1/doc/a.pdf"},
+		{Name: "del2/wardA/case1/doc/b.pdf"},
+		{Name: "del1/wardA/case2/c.pdf"},
+		{Name: "del1/d.pdf"},
+		{Name: "e.pdf"},
+	}
+	result, total := Aggregate(entries, AggregateOptions{Threshold: 1, Segments: SegmentRange{From: 2, To: 3}})
+	expected := []FolderCount{
+		{Path: "(Root)", Count: 2},
+		{Path: "wardA/case1", Count: 2},
+		{Path: "wardA/case2", Count: 1},
+	}
+	if total != 5 {
+		t.Errorf("expected total 5, got %d", total)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+// Aggregate の階層の名前ごとの集計のテスト
+func TestAggregateGroupSegment(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "vendorA/2024/01/a.pdf"},
+		{Name: "vendorB/2024/b.pdf"},
+		{Name: "vendorA/2023/c.pdf"},
+		{Name: "vendorA/d.pdf"},
+		{Name: "e.pdf"},
+	}
+	result, total := Aggregate(entries, AggregateOptions{Threshold: 1, Key: SegmentKey(2)})
+	expected := []FolderCount{
+		{Path: "(None)", Count: 2},
+		{Path: "2024", Count: 2},
+		{Path: "2023", Count: 1},
+	}
+	if total != 5 {
+		t.Errorf("expected total 5, got %d", total)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+// 標準入力からの読み込みとSHA-256の計算のテスト
+func TestAppRunStdinAndHash(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+	zipPath := writeTestZip(t, "dir1/a.txt", "b.txt")
+	data, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	expectedSum := hex.EncodeToString(sum[:])
+
+	t.Run("標準入力", func(t *testing.T) {
+		app := &App{Reader: ZipArchiveReader{}, Logger: logger, Stdin: bytes.NewReader(data)}
+		out := new(bytes.Buffer)
+		if err := app.Run(AppConfig{ZipPath: StdinPath, Threshold: 1, Format: "json"}, out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var report Report
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+		if report.SHA256 != expectedSum || report.TotalFiles != 2 || len(report.Folders) != 2 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("ファイル", func(t *testing.T) {
+		app := &App{Reader: ZipArchiveReader{}, Logger: logger}
+		out := new(bytes.Buffer)
+		if err := app.Run(AppConfig{ZipPath: zipPath, Threshold: 1, SHA256: true}, out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Contains(out.Bytes(), []byte("SHA-256: "+expectedSum)) {
+			t.Errorf("output does not contain hash, got: %s", out.String())
+		}
+	})
+
+	t.Run("不正なデータ", func(t *testing.T) {
+		app := &App{Reader: ZipArchiveReader{}, Logger: logger, Stdin: bytes.NewReader([]byte("not a zip"))}
+		if err := app.Run(AppConfig{ZipPath: StdinPath}, new(bytes.Buffer)); err == nil {
+			t.Error("expected error for invalid stdin data, got nil")
+		}
+	})
+}
+
+// Aggregate のUnicode正規化のテスト (NFC/NFDの違いだけのフォルダを統合)
+func TestAggregateNormalize(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "ガ/a.txt"},  // ガ (NFC)
+		{Name: "ガ/b.txt"}, // カ + 濁点 (NFD)
+	}
+
+	result, _ := Aggregate(entries, AggregateOptions{Threshold: 1})
+	if len(result) != 2 {
+		t.Errorf("expected 2 folders without normalization, got %v", result)
+	}
+
+	normalize, err := ParseNormalization("nfc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ = Aggregate(entries, AggregateOptions{Threshold: 1, Normalize: normalize})
+	expected := []FolderCount{{Path: "ガ", Count: 2}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+
+	if _, err := ParseNormalization("nfx"); err == nil {
+		t.Error("expected error for unknown normalization, got nil")
+	}
+}
+
+// Aggregate のサイズ統計のテスト
+func TestAggregateStats(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "dir1/a.txt", Size: 100},
+		{Name: "dir1/b.txt", Size: 300},
+		{Name: "dir1/c.txt", Size: 200},
+		{Name: "d.txt", Size: 0},
+	}
+	result, _ := Aggregate(entries, AggregateOptions{Threshold: 1, Stats: true})
+	expected := []FolderCount{
+		{Path: "dir1", Count: 3, Stats: &FolderStats{TotalSize: 600, AverageSize: 200, LargestFile: "b.txt", LargestSize: 300}},
+		{Path: "(Root)", Count: 1, Stats: &FolderStats{LargestFile: "d.txt"}},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	result, _ = Aggregate(entries, AggregateOptions{Threshold: 1})
+	if result[0].Stats != nil {
+		t.Errorf("expected nil stats without Stats option, got %+v", result[0].Stats)
+	}
+}
+
+// FormatSize のテスト
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		input    uint64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 << 20, "5.0 MB"},
+		{3 << 30, "3.0 GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.input); got != tt.expected {
+			t.Errorf("FormatSize(%d): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// isDirHeader が FileInfo().IsDir() と同じ判定をすることのテスト
+func TestIsDirHeader(t *testing.T) {
+	headers := []zip.FileHeader{
+		{Name: "dir/"},
+		{Name: "file.txt"},
+		{Name: "unixdir", CreatorVersion: 3 << 8, ExternalAttrs: (0o040755) << 16},
+		{Name: "unixfile", CreatorVersion: 3 << 8, ExternalAttrs: (0o100644) << 16},
+		{Name: "unixlink", CreatorVersion: 3 << 8, ExternalAttrs: (0o120777) << 16},
+		{Name: "macdir", CreatorVersion: 19 << 8, ExternalAttrs: (0o040755) << 16},
+		{Name: "fatdir", CreatorVersion: 0, ExternalAttrs: 0x10},
+		{Name: "fatfile", CreatorVersion: 0, ExternalAttrs: 0x20},
+		{Name: "ntfsdir", CreatorVersion: 11 << 8, ExternalAttrs: 0x10},
+		{Name: "vfatdir", CreatorVersion: 14 << 8, ExternalAttrs: 0x10},
+		{Name: "otherOS", CreatorVersion: 7 << 8, ExternalAttrs: 0x10},
+		{Name: "unixfile/", CreatorVersion: 3 << 8, ExternalAttrs: (0o100644) << 16},
+	}
+
+	for _, h := range headers {
+		t.Run(h.Name, func(t *testing.T) {
+			expected := h.FileInfo().IsDir()
+			if got := isDirHeader(&h); got != expected {
+				t.Errorf("expected %v, got %v", expected, got)
+			}
+		})
+	}
+}
+
+// ZipArchiveReader がエントリ名を並行して復号しても、結果がエントリの順に並ぶことのテスト
+func TestZipArchiveReaderWorkers(t *testing.T) {
+	b := obuziptest.New()
+	for i := 0; i < 5000; i++ {
+		if i%3 == 0 {
+			// Shift_JIS の "資料" (2バイト目に 0x5C を含む "表" も混ぜる)
+			b.RawFile([]byte(fmt.Sprintf("\x8e\x91\x97\xbf%d/\x95\x5c%04d.txt", i%5, i)))
+			continue
+		}
+		b.Files(fmt.Sprintf("dir%d/%04d.txt", i%7, i))
+	}
+	zipPath := b.WriteFile(t)
+
+	expected, err := (ZipArchiveReader{Workers: 1}).ReadEntries(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected) != 5000 || expected[0].Name != "資料0/表0000.txt" {
+		t.Fatalf("unexpected sequential result: %d entries, first %q", len(expected), expected[0].Name)
+	}
+	for _, workers := range []int{2, 3, 16} {
+		t.Run(fmt.Sprintf("%dゴルーチン", workers), func(t *testing.T) {
+			got, err := (ZipArchiveReader{Workers: workers}).ReadEntries(zipPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("entries differ from sequential decoding")
+			}
+		})
+	}
+}
+
+// ZipArchiveReader がエントリのコメントを読み込み、UTF-8でない場合はエントリ名と同じく復号することのテスト
+func TestZipArchiveReaderComment(t *testing.T) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	headers := []*zip.FileHeader{
+		{Name: "a/utf8.txt", Comment: "承認済み"},
+		// Shift_JIS の "資料/表.txt" と "承認済み"
+		{Name: "\x8e\x91\x97\xbf/\x95\x5c.txt", Comment: "\x8f\xb3\x94\x46\x8d\xcf\x82\xdd", NonUTF8: true},
+		{Name: "a/none.txt"},
+	}
+	for _, h := range headers {
+		if _, err := zw.CreateHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "comment.zip")
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := (ZipArchiveReader{}).ReadEntries(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, e := range entries {
+		got[e.Name] = e.Comment
+	}
+	expected := map[string]string{"a/utf8.txt": "承認済み", "資料/表.txt": "承認済み", "a/none.txt": ""}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+// ZipArchiveReader の列挙性能のベンチマーク
+func BenchmarkZipArchiveReader(b *testing.B) {
+	zipPath := filepath.Join(b.TempDir(), "bench.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for i := 0; i < 10000; i++ {
+		if _, err := zw.Create(fmt.Sprintf("dir%d/file%d.txt", i%100, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := (ZipArchiveReader{}).ReadEntries(zipPath); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
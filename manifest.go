@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// manifestFormats は -manifest-format で指定できる形式です。CSVOptions は csv の場合のみ使います。
+var manifestFormats = map[string]func(io.Writer, []FileEntry, CSVOptions) error{
+	"csv": WriteManifestCSV,
+	"jsonl": func(w io.Writer, entries []FileEntry, _ CSVOptions) error {
+		return WriteManifestJSONL(w, entries)
+	},
+}
+
+// manifestFormat は形式の指定とファイルの拡張子から、マニフェストの形式を決めます。(純粋関数)
+// 指定が空の場合、拡張子が .jsonl または .ndjson なら jsonl、それ以外は csv とします。
+func manifestFormat(manifestPath, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(manifestPath)) {
+		case ".jsonl", ".ndjson":
+			return "jsonl", nil
+		default:
+			return "csv", nil
+		}
+	}
+	if _, ok := manifestFormats[format]; !ok {
+		return "", fmt.Errorf("unknown manifest format: %s", format)
+	}
+	return format, nil
+}
+
+// manifestEntryType はマニフェストのエントリの種類 (file, dir) を返します。(純粋関数)
+func manifestEntryType(e FileEntry) string {
+	if e.IsDir {
+		return "dir"
+	}
+	return "file"
+}
+
+// WriteManifestCSV はエントリごとの一覧をCSV形式でWriterに出力します。
+// CRC-32 は8桁の16進数、日時は RFC 3339 で出力します。作成日時が記録されていない場合は空です。
+func WriteManifestCSV(w io.Writer, entries []FileEntry, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Name", "Type", "Size", "Compressed Size", "CRC32", "Modified", "Created", "Time Source", "Comment"}); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			err := writer.Write([]string{
+				e.Name,
+				manifestEntryType(e),
+				strconv.FormatUint(e.Size, 10),
+				strconv.FormatUint(e.CompressedSize, 10),
+				fmt.Sprintf("%08x", e.CRC32),
+				e.Modified.Format(time.RFC3339),
+				formatOptionalTime(e.Created),
+				e.TimeSource,
+				e.Comment,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// formatOptionalTime は日時を RFC 3339 で返します。ゼロ値の場合は空文字列を返します。(純粋関数)
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// manifestRecord は JSONL 形式のマニフェストの1行です。
+type manifestRecord struct {
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Size           uint64    `json:"size"`
+	CompressedSize uint64    `json:"compressedSize"`
+	CRC32          string    `json:"crc32"`
+	Modified       time.Time `json:"modified"`
+	// Created は作成日時です。記録されていない場合は出力しません。
+	Created    *time.Time `json:"created,omitempty"`
+	TimeSource string     `json:"timeSource,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+}
+
+// WriteManifestJSONL はエントリごとの一覧を1行1エントリのJSON (JSON Lines) でWriterに出力します。
+func WriteManifestJSONL(w io.Writer, entries []FileEntry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		var created *time.Time
+		if !e.Created.IsZero() {
+			created = &e.Created
+		}
+		err := enc.Encode(manifestRecord{
+			Name:           e.Name,
+			Type:           manifestEntryType(e),
+			Size:           e.Size,
+			CompressedSize: e.CompressedSize,
+			CRC32:          fmt.Sprintf("%08x", e.CRC32),
+			Modified:       e.Modified,
+			Created:        created,
+			TimeSource:     e.TimeSource,
+			Comment:        e.Comment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeManifestFile はエントリごとの一覧を指定の形式で一時ファイルに書いてからファイルに置き換えます。
+// CSVの文字コードと区切り文字は opts に従います。noClobber が true の場合、ファイルが既にあればエラーを返します。
+func writeManifestFile(manifestPath, format string, entries []FileEntry, opts CSVOptions, noClobber bool) error {
+	return writeFileAtomic(manifestPath, noClobber, func(file io.Writer) error {
+		if err := manifestFormats[format](file, entries, opts); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		return nil
+	})
+}
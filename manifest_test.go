@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// WriteManifestCSV と WriteManifestJSONL のテスト
+func TestWriteManifest(t *testing.T) {
+	modified := time.Date(2023, 6, 15, 9, 30, 0, 0, time.UTC)
+	entries := []FileEntry{
+		{Name: "dir1/", IsDir: true, Modified: modified},
+		{Name: "dir1/報告書.pdf", Modified: modified, Size: 4096, CompressedSize: 1024, CRC32: 0x1A2B, Created: modified.Add(-time.Hour), TimeSource: TimeSourceNTFS, Comment: "承認済み, 2版"},
+	}
+	tests := []struct {
+		name     string
+		write    func(io.Writer, []FileEntry, CSVOptions) error
+		opts     CSVOptions
+		expected string
+	}{
+		{
+			name:  "CSV",
+			write: WriteManifestCSV,
+			expected: "\xEF\xBB\xBFName,Type,Size,Compressed Size,CRC32,Modified,Created,Time Source,Comment\n" +
+				"dir1/,dir,0,0,00000000,2023-06-15T09:30:00Z,,,\n" +
+				"dir1/報告書.pdf,file,4096,1024,00001a2b,2023-06-15T09:30:00Z,2023-06-15T08:30:00Z,ntfs,\"承認済み, 2版\"\n",
+		},
+		{
+			name:  "CSVの文字コードと区切り文字",
+			write: WriteManifestCSV,
+			opts:  CSVOptions{Encoding: "utf8", Delimiter: "tab"},
+			expected: "Name\tType\tSize\tCompressed Size\tCRC32\tModified\tCreated\tTime Source\tComment\n" +
+				"dir1/\tdir\t0\t0\t00000000\t2023-06-15T09:30:00Z\t\t\t\n" +
+				"dir1/報告書.pdf\tfile\t4096\t1024\t00001a2b\t2023-06-15T09:30:00Z\t2023-06-15T08:30:00Z\tntfs\t承認済み, 2版\n",
+		},
+		{
+			name:  "JSONL",
+			write: manifestFormats["jsonl"],
+			expected: `{"name":"dir1/","type":"dir","size":0,"compressedSize":0,"crc32":"00000000","modified":"2023-06-15T09:30:00Z"}` + "\n" +
+				`{"name":"dir1/報告書.pdf","type":"file","size":4096,"compressedSize":1024,"crc32":"00001a2b","modified":"2023-06-15T09:30:00Z","created":"2023-06-15T08:30:00Z","timeSource":"ntfs","comment":"承認済み, 2版"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := tt.write(buf, entries, tt.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+// count の -manifest のテスト
+func TestRunCLIManifest(t *testing.T) {
+	zipPath := obuziptest.New().File("dir1/a.txt", []byte("hello")).Files("dir1/b.txt").WriteFile(t)
+	dir := t.TempDir()
+
+	for path, want := range map[string]string{
+		filepath.Join(dir, "manifest.csv"):   "dir1/a.txt,file,5,",
+		filepath.Join(dir, "manifest.jsonl"): `"crc32":"3610a686"`,
+	} {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-manifest", path}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "dir1") {
+			t.Errorf("summary missing: %s", stdout.String())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not contain %q, got: %s", filepath.Base(path), want, data)
+		}
+	}
+
+	code := runCLI([]string{"count", "-zip", zipPath, "-manifest", filepath.Join(dir, "m.txt"), "-manifest-format", "xml"}, new(bytes.Buffer), new(bytes.Buffer))
+	if code != 1 {
+		t.Errorf("expected code 1 for unknown manifest format, got %d", code)
+	}
+}
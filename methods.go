@@ -0,0 +1,195 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MethodName は圧縮方式の名前を返します。不明な方式は "method N" です。(純粋関数)
+func MethodName(method uint16) string {
+	if name, ok := zipMethodNames[method]; ok {
+		return name
+	}
+	return fmt.Sprintf("method %d", method)
+}
+
+// FolderMethods はフォルダごとの圧縮方式別のファイル数です。
+type FolderMethods struct {
+	Folder  string
+	Store   int
+	Deflate int
+	// Unsupported は Store と Deflate 以外の、展開に失敗する方式のファイル数です。
+	Unsupported int
+}
+
+// UnsupportedEntry は展開に失敗する圧縮方式のエントリです。
+type UnsupportedEntry struct {
+	Name string
+	// Folder は集計結果と同じ集計キーのフォルダパスです。
+	Folder string
+	Method uint16
+}
+
+// ComputeMethods はフォルダごとに圧縮方式別のファイル数を数え、展開に失敗する方式のエントリを返します。(純粋関数)
+// Windows の標準機能と archive/zip (extract コマンド) が展開できるのは Store と Deflate だけで、それ以外の方式は展開に失敗するものとして数えます。
+// フォルダは展開に失敗するファイル数の降順、同じ場合はパスの昇順、エントリは名前の昇順でソートされます。
+func ComputeMethods(entries []FileEntry, opts AggregateOptions) ([]FolderMethods, []UnsupportedEntry) {
+	var unsupported []UnsupportedEntry
+	counts := make(map[string]*FolderMethods)
+	for _, e := range entries {
+		if e.IsDir || !opts.Filter.Match(e) {
+			continue
+		}
+		folder := FolderKey(e.Name, opts)
+		fm, ok := counts[folder]
+		if !ok {
+			fm = &FolderMethods{Folder: folder}
+			counts[folder] = fm
+		}
+		switch e.Method {
+		case zip.Store:
+			fm.Store++
+		case zip.Deflate:
+			fm.Deflate++
+		default:
+			fm.Unsupported++
+			unsupported = append(unsupported, UnsupportedEntry{Name: e.Name, Folder: folder, Method: e.Method})
+		}
+	}
+
+	folders := make([]FolderMethods, 0, len(counts))
+	for _, fm := range counts {
+		folders = append(folders, *fm)
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if folders[i].Unsupported != folders[j].Unsupported {
+			return folders[i].Unsupported > folders[j].Unsupported
+		}
+		return folders[i].Folder < folders[j].Folder
+	})
+	sort.Slice(unsupported, func(i, j int) bool { return unsupported[i].Name < unsupported[j].Name })
+	return folders, unsupported
+}
+
+// WriteMethodsText はフォルダごとの件数と、展開に失敗する方式のエントリの一覧をプレーンテキストで出力します。
+func WriteMethodsText(w io.Writer, folders []FolderMethods, unsupported []UnsupportedEntry, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-50s | %8s | %8s | %11s\n", lang.T("Folder Path"), lang.T("Store"), "Deflate", lang.T("Unsupported"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 86))
+	for _, f := range folders {
+		if _, err := fmt.Fprintf(w, "%-50s | %8d | %8d | %11d\n", f.Folder, f.Store, f.Deflate, f.Unsupported); err != nil {
+			return err
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n%s:\n", lang.T("Unsupported entries")); err != nil {
+		return err
+	}
+	for _, u := range unsupported {
+		if _, err := fmt.Fprintf(w, "  %-10s %s\n", MethodName(u.Method), u.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMethodsCSV はフォルダごとの圧縮方式別のファイル数をCSV形式で出力します。
+func WriteMethodsCSV(w io.Writer, folders []FolderMethods, _ []UnsupportedEntry, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Store", "Deflate", "Unsupported"}); err != nil {
+			return err
+		}
+		for _, f := range folders {
+			if err := writer.Write([]string{f.Folder, strconv.Itoa(f.Store), strconv.Itoa(f.Deflate), strconv.Itoa(f.Unsupported)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectMethodsWriter は出力形式名に対応する圧縮方式の集計結果の書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectMethodsWriter(format string, opts WriterOptions) (func(io.Writer, []FolderMethods, []UnsupportedEntry) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, folders []FolderMethods, unsupported []UnsupportedEntry) error {
+			return WriteMethodsText(w, folders, unsupported, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, folders []FolderMethods, unsupported []UnsupportedEntry) error {
+			return WriteMethodsCSV(w, folders, unsupported, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// runMethods は methods コマンドを実行します。
+// 展開に失敗する圧縮方式のエントリがあればエラーを返します。
+func runMethods(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "methods", "methods -zip <path> [-format text|csv]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	format := fs.String("format", "text", "出力形式 (text: フォルダごとの件数と展開できないエントリの一覧, csv: フォルダごとの件数)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectMethodsWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	folders, unsupported := ComputeMethods(entries, opts)
+	for i := range folders {
+		folders[i].Folder = cfg.Paths.Folder(folders[i].Folder)
+	}
+	for i := range unsupported {
+		unsupported[i].Folder = cfg.Paths.Folder(unsupported[i].Folder)
+	}
+	if err := write(env.Stdout, folders, unsupported); err != nil {
+		return err
+	}
+	if len(unsupported) > 0 {
+		env.Logger.Warn("展開できない圧縮方式のエントリがあります", slog.Int("entries", len(unsupported)))
+		return fmt.Errorf("%d entries use unsupported compression methods", len(unsupported))
+	}
+	return nil
+}
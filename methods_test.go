@@ -0,0 +1,71 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// MethodName のテスト
+func TestMethodName(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   uint16
+		expected string
+	}{
+		{name: "Store", method: zip.Store, expected: "store"},
+		{name: "Deflate", method: zip.Deflate, expected: "deflate"},
+		{name: "LZMA", method: 14, expected: "lzma"},
+		{name: "不明な方式", method: 200, expected: "method 200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MethodName(tt.method); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ComputeMethods のテスト
+func TestComputeMethods(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "docs/", IsDir: true},
+		{Name: "docs/a.txt", Method: zip.Deflate},
+		{Name: "docs/b.png", Method: zip.Store},
+		{Name: "data/z.bin", Method: 14},
+		{Name: "data/y.bin", Method: 9},
+		{Name: "data/x.bin", Method: zip.Deflate},
+		{Name: "readme.txt", Method: zip.Deflate},
+	}
+	folders, unsupported := ComputeMethods(entries, AggregateOptions{})
+	wantFolders := []FolderMethods{
+		{Folder: "data", Deflate: 1, Unsupported: 2},
+		{Folder: "(Root)", Deflate: 1},
+		{Folder: "docs", Store: 1, Deflate: 1},
+	}
+	if !reflect.DeepEqual(folders, wantFolders) {
+		t.Errorf("expected %+v, got %+v", wantFolders, folders)
+	}
+	wantUnsupported := []UnsupportedEntry{
+		{Name: "data/y.bin", Folder: "data", Method: 9},
+		{Name: "data/z.bin", Folder: "data", Method: 14},
+	}
+	if !reflect.DeepEqual(unsupported, wantUnsupported) {
+		t.Errorf("expected %+v, got %+v", wantUnsupported, unsupported)
+	}
+}
+
+// WriteMethodsCSV のテスト (文字コードと区切り文字)
+func TestWriteMethodsCSV(t *testing.T) {
+	folders := []FolderMethods{{Folder: "画像", Store: 2, Deflate: 1}, {Folder: "docs", Deflate: 3, Unsupported: 1}}
+	buf := new(bytes.Buffer)
+	if err := WriteMethodsCSV(buf, folders, nil, CSVOptions{Encoding: "utf8", Delimiter: "semicolon"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Folder Path;Store;Deflate;Unsupported\n画像;2;1;0\ndocs;0;3;1\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
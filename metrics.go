@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serveMetrics は serve コマンドが /metrics で公開するメトリクスです。
+type serveMetrics struct {
+	archives prometheus.Counter
+	entries  prometheus.Counter
+	duration prometheus.Histogram
+	errors   *prometheus.CounterVec
+}
+
+// newServeMetrics はメトリクスを作成してレジストリに登録します。
+func newServeMetrics(reg prometheus.Registerer) *serveMetrics {
+	m := &serveMetrics{
+		archives: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "obuzipcount_archives_processed_total",
+			Help: "Number of archives counted successfully.",
+		}),
+		entries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "obuzipcount_entries_read_total",
+			Help: "Number of archive entries read.",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "obuzipcount_processing_duration_seconds",
+			Help:    "Time taken to process a count request.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "obuzipcount_errors_total",
+			Help: "Number of failed count requests by error type.",
+		}, []string{"type"}),
+	}
+	reg.MustRegister(m.archives, m.entries, m.duration, m.errors)
+	return m
+}
+
+// metricsReader は読み込んだエントリ数をメトリクスに記録する ArchiveReader です。
+type metricsReader struct {
+	ArchiveReader
+	metrics *serveMetrics
+}
+
+func (r metricsReader) ReadEntries(path string) ([]FileEntry, error) {
+	entries, err := r.ArchiveReader.ReadEntries(path)
+	if err == nil {
+		r.metrics.entries.Add(float64(len(entries)))
+	}
+	return entries, err
+}
+
+// errorType はメトリクスのラベルに使うエラーの種類を返します。(純粋関数)
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, ErrArchiveNotFound):
+		return "not_found"
+	case errors.Is(err, ErrArchivePermission):
+		return "permission"
+	case errors.Is(err, ErrArchiveIsDir), errors.Is(err, ErrArchiveEmpty), errors.Is(err, ErrNotZip):
+		return "invalid_archive"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/encoding"
+)
+
+// NameDecoder はUTF-8でないエントリ名のバイト列を文字列に復号します。
+// ZIPのエントリ名は複数のゴルーチンから同時に復号するため、実装は並行して呼び出せる必要があります。
+type NameDecoder interface {
+	// Decode は復号した文字列と、その確からしさ (0〜1) を返します。
+	// 確からしさが0の場合、復号できなかったことを表します。
+	Decode(raw []byte) (string, float64)
+}
+
+// 復号結果の確からしさです。
+const (
+	// ConfidenceNone は復号できなかったことを表します。
+	ConfidenceNone = 0.0
+	// ConfidenceDecoded は変換エラーなく復号できたが、印字できない文字を含むことを表します。
+	ConfidenceDecoded = 0.5
+	// ConfidenceCertain は妥当で印字可能な文字列に復号できたことを表します。
+	ConfidenceCertain = 1.0
+)
+
+// EncodingDecoder は文字コードでエントリ名を復号します。
+type EncodingDecoder struct {
+	Encoding encoding.Encoding
+}
+
+// Decode は NameDecoder の実装です。
+func (d EncodingDecoder) Decode(raw []byte) (string, float64) {
+	decoded, err := d.Encoding.NewDecoder().Bytes(raw)
+	if err != nil {
+		return "", ConfidenceNone
+	}
+	if isPrintableUTF8(string(decoded)) {
+		return string(decoded), ConfidenceCertain
+	}
+	return string(decoded), ConfidenceDecoded
+}
+
+// TableDecoder は変換表に登録されたエントリ名をそのまま置き換えます。
+// 文字コードでは復号できない独自の外字を含む名前などに使います。
+type TableDecoder map[string]string
+
+// Decode は NameDecoder の実装です。
+func (d TableDecoder) Decode(raw []byte) (string, float64) {
+	if name, ok := d[string(raw)]; ok {
+		return name, ConfidenceCertain
+	}
+	return "", ConfidenceNone
+}
+
+// ChainDecoder は複数の NameDecoder を順に試し、最も確からしい結果を採用します。
+// 確からしさが同じ場合は先に試したものを優先します。
+type ChainDecoder []NameDecoder
+
+// Decode は NameDecoder の実装です。どれも復号できない場合は元のバイト列をそのまま返します。
+func (c ChainDecoder) Decode(raw []byte) (string, float64) {
+	best, bestConfidence := string(raw), ConfidenceNone
+	for _, d := range c {
+		name, confidence := d.Decode(raw)
+		if confidence > bestConfidence {
+			best, bestConfidence = name, confidence
+		}
+		if bestConfidence >= ConfidenceCertain {
+			break
+		}
+	}
+	return best, bestConfidence
+}
+
+var (
+	nameDecodersMu sync.RWMutex
+	// nameDecoders は -encoding-fallbacks で指定できる復号方法の一覧です。
+	nameDecoders = make(map[string]NameDecoder)
+)
+
+func init() {
+	for name, enc := range namedEncodings {
+		RegisterNameDecoder(name, EncodingDecoder{Encoding: enc})
+	}
+}
+
+// RegisterNameDecoder は復号方法を名前で登録します。同じ名前が登録済みの場合は置き換えます。
+func RegisterNameDecoder(name string, d NameDecoder) {
+	nameDecodersMu.Lock()
+	defer nameDecodersMu.Unlock()
+	nameDecoders[strings.ToLower(name)] = d
+}
+
+// NameDecoderNames は登録済みの復号方法の名前を昇順で返します。
+func NameDecoderNames() []string {
+	nameDecodersMu.RLock()
+	defer nameDecodersMu.RUnlock()
+	names := make([]string, 0, len(nameDecoders))
+	for name := range nameDecoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseNameDecoders はカンマ区切りの復号方法の名前を順序どおりに解決し、ChainDecoder を返します。
+func ParseNameDecoders(s string) (ChainDecoder, error) {
+	nameDecodersMu.RLock()
+	defer nameDecodersMu.RUnlock()
+
+	var chain ChainDecoder
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		d, ok := nameDecoders[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown encoding: %s", name)
+		}
+		chain = append(chain, d)
+	}
+	return chain, nil
+}
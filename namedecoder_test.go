@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// ChainDecoder のテスト (確からしさによる選択)
+func TestChainDecoder(t *testing.T) {
+	table := TableDecoder{"\xf0\x40/a.txt": "髙/a.txt"}
+	chain := ChainDecoder{EncodingDecoder{Encoding: japanese.ShiftJIS}, table}
+
+	tests := []struct {
+		name       string
+		input      string
+		expected   string
+		confidence float64
+	}{
+		{name: "Shift_JISで復号できる", input: "\x93\xfa\x96\x7b", expected: "日本", confidence: ConfidenceCertain},
+		{name: "変換表の方が確からしい", input: "\xf0\x40/a.txt", expected: "髙/a.txt", confidence: ConfidenceCertain},
+		{name: "印字できない復号結果", input: "\x80", expected: "\u0080", confidence: ConfidenceDecoded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence := chain.Decode([]byte(tt.input))
+			if got != tt.expected || confidence != tt.confidence {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.expected, tt.confidence, got, confidence)
+			}
+		})
+	}
+
+	t.Run("復号できなければ元のバイト列", func(t *testing.T) {
+		got, confidence := ChainDecoder{table}.Decode([]byte("\x80"))
+		if got != "\x80" || confidence != ConfidenceNone {
+			t.Errorf("expected raw name, got %q (%v)", got, confidence)
+		}
+	})
+}
+
+// RegisterNameDecoder と ParseNameDecoders のテスト
+func TestParseNameDecoders(t *testing.T) {
+	RegisterNameDecoder("Vendor-Table", TableDecoder{"\x01": "外字"})
+	t.Cleanup(func() {
+		nameDecodersMu.Lock()
+		delete(nameDecoders, "vendor-table")
+		nameDecodersMu.Unlock()
+	})
+
+	chain, err := ParseNameDecoders("vendor-table, cp932")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := chain.Decode([]byte("\x01")); got != "外字" {
+		t.Errorf("expected registered decoder to be used, got %q", got)
+	}
+	if _, err := ParseNameDecoders("cp932,unknown"); err == nil {
+		t.Error("expected error for unknown decoder, got nil")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// exitNaming は -fail-on-naming の指定時に命名規則に違反するフォルダがあった場合の終了コードです。
+const exitNaming = 6
+
+// NamingViolation は命名規則に違反するフォルダです。
+type NamingViolation struct {
+	// Folder は集計結果と同じ集計キーのフォルダパスです。
+	Folder string
+	// Count はフォルダ直下のファイル数です。
+	Count int
+	// Rule は満たさなかった最初の命名規則です。
+	Rule string
+}
+
+// ParseNamingRule は命名規則の正規表現を解析します。(純粋関数)
+func ParseNamingRule(s string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming rule: %w", err)
+	}
+	return re, nil
+}
+
+// LoadNamingRules は1行に1つの正規表現を記述した命名規則のファイルを読み込みます。
+// BOM、空行、"#" で始まる行は無視します。
+func LoadNamingRules(rulesPath string) ([]*regexp.Regexp, error) {
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read naming rules: %w", err)
+	}
+	var rules []*regexp.Regexp
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})))
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		re, err := ParseNamingRule(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse naming rules %s: line %d: %w", rulesPath, line, err)
+		}
+		rules = append(rules, re)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read naming rules: %w", err)
+	}
+	return rules, nil
+}
+
+// CheckFolderNames はすべての命名規則を満たさないフォルダを返します。(純粋関数)
+// 規則はフォルダパスの末尾に "/" を付けた文字列と比較するため、^\d{8}_[A-Z]{3}/ のような規則で最上位のフォルダ名を検証できます。
+// ルートフォルダはフォルダ名を持たないため検証しません。
+// folders にはしきい値で絞り込む前のすべてのフォルダが含まれている必要があります。
+func CheckFolderNames(folders []FolderCount, rules []*regexp.Regexp) []NamingViolation {
+	if len(rules) == 0 {
+		return nil
+	}
+	var violations []NamingViolation
+	for _, f := range folders {
+		if f.isRoot() {
+			continue
+		}
+		for _, re := range rules {
+			if !re.MatchString(f.Path + "/") {
+				violations = append(violations, NamingViolation{Folder: f.Path, Count: f.Count, Rule: re.String()})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// NamingFindings は命名規則に違反するフォルダを検出事項として返します。(純粋関数)
+// fail が true の場合は ERROR、それ以外は WARN とします。
+func NamingFindings(violations []NamingViolation, fail bool) []Finding {
+	severity := SeverityWarn
+	if fail {
+		severity = SeverityError
+	}
+	findings := make([]Finding, 0, len(violations))
+	for _, v := range violations {
+		findings = append(findings, Finding{
+			Severity: severity,
+			Rule:     RuleNaming,
+			Folder:   v.Folder,
+			Message:  fmt.Sprintf("%d files; folder path does not match naming rule %s", v.Count, v.Rule),
+		})
+	}
+	return findings
+}
+
+// namingRuleFlag は繰り返し指定できる -naming-rule フラグです。
+type namingRuleFlag []*regexp.Regexp
+
+func (f *namingRuleFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, re := range *f {
+		parts[i] = re.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *namingRuleFlag) Set(s string) error {
+	re, err := ParseNamingRule(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, re)
+	return nil
+}
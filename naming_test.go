@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// CheckFolderNames のテスト
+func TestCheckFolderNames(t *testing.T) {
+	top := regexp.MustCompile(`^\d{8}_[A-Z]{3}/`)
+	// 日付で始まるか、小文字だけのフォルダ名
+	prefix := regexp.MustCompile(`^(\d{8}_|[a-z]+/)`)
+	folders := []FolderCount{
+		{Path: "20240401_ABC", Count: 3},
+		{Path: "20240401_ABC/scans", Count: 5},
+		{Path: "2024_ABC/scans", Count: 2},
+		{Path: "misc", Count: 1},
+		{Path: "(Root)", Count: 4},
+	}
+	tests := []struct {
+		name     string
+		rules    []*regexp.Regexp
+		expected []NamingViolation
+	}{
+		{name: "規則なし", rules: nil, expected: nil},
+		{
+			name:  "最上位のフォルダ名の規則",
+			rules: []*regexp.Regexp{top},
+			expected: []NamingViolation{
+				{Folder: "2024_ABC/scans", Count: 2, Rule: top.String()},
+				{Folder: "misc", Count: 1, Rule: top.String()},
+			},
+		},
+		{
+			name:  "満たさない最初の規則を報告",
+			rules: []*regexp.Regexp{prefix, top},
+			expected: []NamingViolation{
+				{Folder: "2024_ABC/scans", Count: 2, Rule: prefix.String()},
+				{Folder: "misc", Count: 1, Rule: top.String()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckFolderNames(folders, tt.rules); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// LoadNamingRules のテスト
+func TestLoadNamingRules(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+		wantErr  string
+	}{
+		{name: "コメントと空行を無視", content: "\xEF\xBB\xBF# 納品規約\n^\\d{8}_[A-Z]{3}/\n\n  ^[^ ]+$  \n", expected: []string{`^\d{8}_[A-Z]{3}/`, `^[^ ]+$`}},
+		{name: "空のファイル", content: "", expected: nil},
+		{name: "不正な正規表現", content: "# ok\n^a/\n(unclosed\n", wantErr: "line 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(dir, "rules.txt")
+			if err := os.WriteFile(p, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rules, err := LoadNamingRules(p)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, re := range rules {
+				got = append(got, re.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+	if _, err := LoadNamingRules(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+// count の -naming-rule と -fail-on-naming のテスト
+func TestRunCLINamingRule(t *testing.T) {
+	zipPath := writeTestZip(t, "20240401_ABC/a.pdf", "20240401_ABC/scans/b.pdf", "bad_folder/c.pdf", "bad_folder/d.pdf", "root.txt")
+	rulesPath := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(rulesPath, []byte("^\\d{8}_[A-Z]{3}/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{name: "違反は警告", args: []string{"-naming-rule", `^\d{8}_[A-Z]{3}/`}, expectedOut: "naming bad_folder: 2 files"},
+		{name: "しきい値未満のフォルダも検証", args: []string{"-naming-rule", `^\d{8}_[A-Z]{3}/`, "-threshold", "100"}, expectedOut: "naming bad_folder"},
+		{name: "-fail-on-naming は終了コード6", args: []string{"-naming-rules", rulesPath, "-fail-on-naming"}, expectedCode: exitNaming, expectedOut: "ERROR naming bad_folder"},
+		{name: "違反がなければ成功", args: []string{"-naming-rule", `^[A-Za-z0-9_]+/`, "-fail-on-naming"}, expectedOut: "Folder Path"},
+		{name: "規則なしの -fail-on-naming", args: []string{"-fail-on-naming"}, expectedCode: 2},
+		{name: "不正な正規表現", args: []string{"-naming-rule", "(unclosed"}, expectedCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"count", "-zip", zipPath, "-threshold", "1"}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizationForms は -normalize で指定できるUnicode正規化の方式です。
+var normalizationForms = map[string]norm.Form{
+	"nfc":  norm.NFC,
+	"nfd":  norm.NFD,
+	"nfkc": norm.NFKC,
+	"nfkd": norm.NFKD,
+}
+
+// ParseNormalization は正規化方式の名前に対応する正規化関数を返します。
+// 空文字列または "none" の場合は正規化しないことを表す nil を返します。
+func ParseNormalization(name string) (func(string) string, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == "none" {
+		return nil, nil
+	}
+	form, ok := normalizationForms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown normalization: %s", name)
+	}
+	return form.String, nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// smtpPasswordEnv はSMTP認証のパスワードを渡す環境変数です。コマンドラインに残さないようフラグでは受け取りません。
+const smtpPasswordEnv = "OBUZIPCOUNT_SMTP_PASSWORD"
+
+// 通知する条件です。
+const (
+	// NotifyOnFailure はアーカイブの処理に失敗した場合 (-fail-if-over 等の条件を満たさなかった場合を含む) に通知します。
+	NotifyOnFailure = "failure"
+	// NotifyOnFindings は失敗に加えて、しきい値の超過など WARN 以上の検出事項がある場合に通知します。
+	NotifyOnFindings = "findings"
+	// NotifyOnAlways はすべてのアーカイブについて通知します。
+	NotifyOnAlways = "always"
+)
+
+// Notification はアーカイブ1つの処理結果の通知です。
+type Notification struct {
+	Archive string `json:"archive"`
+	// Status は BatchOK または BatchFailed です。
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Report は json 形式の集計結果です。集計する前に失敗した場合は空です。
+	Report json.RawMessage `json:"report,omitempty"`
+	// Attachments は出力したレポートファイルのパスです。メールに添付します。
+	Attachments []string `json:"-"`
+}
+
+// Notifier は処理結果を外部に通知する方法です。
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// NotifySettings は処理結果を通知する条件と通知先です。
+type NotifySettings struct {
+	Notifiers []Notifier
+	// On は通知する条件 (NotifyOnFailure、NotifyOnFindings、NotifyOnAlways) です。
+	On string
+	// Retries は通知に失敗した場合に再試行する回数です。
+	Retries int
+	// RetryDelay は再試行までの待ち時間です。
+	RetryDelay time.Duration
+}
+
+// ShouldNotify は処理結果が通知する条件を満たすかを判定します。(純粋関数)
+func (s *NotifySettings) ShouldNotify(n Notification) bool {
+	switch {
+	case s.On == NotifyOnAlways || n.Status == BatchFailed:
+		return true
+	case s.On != NotifyOnFindings || len(n.Report) == 0:
+		return false
+	}
+	var report struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal(n.Report, &report); err != nil {
+		return false
+	}
+	for _, f := range report.Findings {
+		if f.Severity >= SeverityWarn {
+			return true
+		}
+	}
+	return false
+}
+
+// Send は条件を満たす場合に、すべての通知先に通知します。
+// 通知に失敗した通知先は Retries 回まで再試行し、それでも失敗した場合は記録して次の通知先に進みます。
+func (s *NotifySettings) Send(n Notification, logger *slog.Logger) {
+	if !s.ShouldNotify(n) {
+		return
+	}
+	for _, notifier := range s.Notifiers {
+		for attempt := 1; ; attempt++ {
+			err := notifier.Notify(n)
+			if err == nil {
+				logger.Info("処理結果を通知しました", slog.String("zipPath", n.Archive), slog.String("notifier", fmt.Sprint(notifier)))
+				break
+			}
+			logger.Warn("処理結果を通知できませんでした", slog.String("zipPath", n.Archive), slog.String("notifier", fmt.Sprint(notifier)), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+			if attempt > s.Retries {
+				break
+			}
+			time.Sleep(s.RetryDelay)
+		}
+	}
+}
+
+// WebhookNotifier は通知をJSONでURLにPOSTします。
+type WebhookNotifier struct {
+	URL string
+	// Client はリクエストに使うクライアントです。nil の場合は http.DefaultClient を使います。
+	Client *http.Client
+}
+
+func (w WebhookNotifier) Notify(n Notification) error {
+	return postJSON(w.Client, w.URL, n)
+}
+
+func (w WebhookNotifier) String() string {
+	return "webhook"
+}
+
+// SMTPNotifier は通知をメールで送り、レポートファイルを添付します。
+type SMTPNotifier struct {
+	// Addr はSMTPサーバの "host:port" です。
+	Addr string
+	From string
+	To   []string
+	// Username が空でない場合は PLAIN 認証を使います。
+	Username, Password string
+}
+
+func (s SMTPNotifier) Notify(n Notification) error {
+	msg := buildNotificationMail(s.From, s.To, n, os.ReadFile)
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := strings.Cut(s.Addr, ":")
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	if err := smtp.SendMail(s.Addr, auth, s.From, s.To, msg); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+func (s SMTPNotifier) String() string {
+	return "smtp"
+}
+
+// mailBoundary はメールのパートの区切りです。
+const mailBoundary = "obuzipcount-report"
+
+// buildNotificationMail は通知のメールをマルチパートのMIMEメッセージにします。
+// 添付ファイルは readFile で読み込み、読み込めないものは本文に記載して添付しません。
+func buildNotificationMail(from string, to []string, n Notification, readFile func(string) ([]byte, error)) []byte {
+	var b bytes.Buffer
+	subject := fmt.Sprintf("go-ObuZipCount: %s %s", filepath.Base(n.Archive), n.Status)
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mailBoundary)
+
+	body := []string{fmt.Sprintf("Archive: %s", n.Archive), fmt.Sprintf("Status: %s", n.Status)}
+	if n.Error != "" {
+		body = append(body, fmt.Sprintf("Error: %s", n.Error))
+	}
+	var attachments [][]byte
+	var names []string
+	for _, p := range n.Attachments {
+		data, err := readFile(p)
+		if err != nil {
+			body = append(body, fmt.Sprintf("Attachment not available: %s", filepath.Base(p)))
+			continue
+		}
+		attachments = append(attachments, data)
+		names = append(names, filepath.Base(p))
+	}
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", mailBoundary, strings.Join(body, "\r\n"))
+	for i, data := range attachments {
+		fmt.Fprintf(&b, "--%s\r\n", mailBoundary)
+		b.WriteString("Content-Type: application/octet-stream\r\nContent-Transfer-Encoding: base64\r\n")
+		fmt.Fprintf(&b, "Content-Disposition: attachment; filename=%q\r\n\r\n", mime.QEncoding.Encode("utf-8", names[i]))
+		encoded := base64.StdEncoding.EncodeToString(data)
+		// 1行は76文字まで
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", mailBoundary)
+	return b.Bytes()
+}
+
+// notifyFlags は処理結果を通知するフラグです。watch と batch で共通です。
+type notifyFlags struct {
+	webhook    string
+	slack      string
+	teams      string
+	smtpAddr   string
+	mailFrom   string
+	mailTo     string
+	smtpUser   string
+	on         string
+	retries    int
+	retryDelay time.Duration
+}
+
+func (f *notifyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.webhook, "notify-webhook", "", "処理結果のJSONをPOSTするURL")
+	fs.StringVar(&f.slack, "notify-slack", "", "処理結果の要約 (ファイル数の多いフォルダ、合計、違反) を投稿するSlackの Incoming Webhook のURL")
+	fs.StringVar(&f.teams, "notify-teams", "", "処理結果の要約を投稿するMicrosoft Teamsの Incoming Webhook のURL")
+	fs.StringVar(&f.smtpAddr, "notify-smtp", "", "処理結果をレポートを添付したメールで送るSMTPサーバ (host:port)")
+	fs.StringVar(&f.mailFrom, "notify-from", "", "通知のメールの送信者")
+	fs.StringVar(&f.mailTo, "notify-to", "", "通知のメールの宛先 (カンマ区切り)")
+	fs.StringVar(&f.smtpUser, "notify-smtp-user", "", "SMTP認証のユーザ名。パスワードは環境変数 "+smtpPasswordEnv+" で指定する")
+	fs.StringVar(&f.on, "notify-on", NotifyOnFailure, "通知する条件 (failure: 処理の失敗と終了条件, findings: 加えてしきい値の超過などの検出事項, always: すべて)")
+	fs.IntVar(&f.retries, "notify-retries", 3, "通知に失敗した場合に再試行する回数")
+	fs.DurationVar(&f.retryDelay, "notify-retry-delay", 5*time.Second, "通知を再試行するまでの待ち時間")
+}
+
+// newSettings はフラグの値から通知の設定を作成します。通知先が指定されない場合は nil を返します。
+func (f *notifyFlags) newSettings(fs *flag.FlagSet) (*NotifySettings, error) {
+	switch f.on {
+	case NotifyOnFailure, NotifyOnFindings, NotifyOnAlways:
+	default:
+		return nil, usageError(fs, "unknown notify condition: %s", f.on)
+	}
+	if f.retries < 0 {
+		return nil, usageError(fs, "notify retries must not be negative")
+	}
+	s := &NotifySettings{On: f.on, Retries: f.retries, RetryDelay: f.retryDelay}
+	for _, u := range []string{f.webhook, f.slack, f.teams} {
+		if u != "" && !isRemotePath(u) {
+			return nil, usageError(fs, "notify webhook must be an http or https URL: %s", u)
+		}
+	}
+	if f.webhook != "" {
+		s.Notifiers = append(s.Notifiers, WebhookNotifier{URL: f.webhook})
+	}
+	if f.slack != "" {
+		s.Notifiers = append(s.Notifiers, SlackNotifier{URL: f.slack})
+	}
+	if f.teams != "" {
+		s.Notifiers = append(s.Notifiers, TeamsNotifier{URL: f.teams})
+	}
+	if f.smtpAddr != "" {
+		var to []string
+		for _, addr := range strings.Split(f.mailTo, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+		if f.mailFrom == "" || len(to) == 0 {
+			return nil, usageError(fs, "notify-from and notify-to are required with notify-smtp")
+		}
+		s.Notifiers = append(s.Notifiers, SMTPNotifier{Addr: f.smtpAddr, From: f.mailFrom, To: to, Username: f.smtpUser, Password: os.Getenv(smtpPasswordEnv)})
+	} else if f.mailTo != "" {
+		return nil, usageError(fs, "notify-smtp is required with notify-to")
+	}
+	if len(s.Notifiers) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// NotifySettings.ShouldNotify のテスト
+func TestNotifySettingsShouldNotify(t *testing.T) {
+	warn := json.RawMessage(`{"findings":[{"severity":"WARN","rule":"threshold","message":"x"}]}`)
+	info := json.RawMessage(`{"findings":[{"severity":"INFO","rule":"symlink","message":"x"}]}`)
+	tests := []struct {
+		name     string
+		on       string
+		n        Notification
+		expected bool
+	}{
+		{name: "失敗は通知", on: NotifyOnFailure, n: Notification{Status: BatchFailed}, expected: true},
+		{name: "成功は通知しない", on: NotifyOnFailure, n: Notification{Status: BatchOK, Report: warn}, expected: false},
+		{name: "WARNの検出事項", on: NotifyOnFindings, n: Notification{Status: BatchOK, Report: warn}, expected: true},
+		{name: "INFOの検出事項だけ", on: NotifyOnFindings, n: Notification{Status: BatchOK, Report: info}, expected: false},
+		{name: "常に通知", on: NotifyOnAlways, n: Notification{Status: BatchOK}, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &NotifySettings{On: tt.on}
+			if got := s.ShouldNotify(tt.n); got != tt.expected {
+				t.Errorf("ShouldNotify() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// Watcher.Process の処理結果をWebhookに通知するテスト
+func TestWatcherProcessNotify(t *testing.T) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		// 1回目は失敗させて再試行を確認する
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	w := &Watcher{
+		App:     &App{Reader: ZipArchiveReader{}, Logger: slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))},
+		OutDir:  t.TempDir(),
+		Formats: []string{"csv"},
+		Notify:  &NotifySettings{Notifiers: []Notifier{WebhookNotifier{URL: server.URL}}, On: NotifyOnAlways, Retries: 1},
+	}
+	if err := w.Process(writeTestZip(t, "dir1/a.txt", "dir1/b.txt")); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("requests = %d, want 2", len(bodies))
+	}
+	var got struct {
+		Status string `json:"status"`
+		Report struct {
+			TotalFiles int `json:"totalFiles"`
+		} `json:"report"`
+	}
+	if err := json.Unmarshal(bodies[1], &got); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if got.Status != BatchOK || got.Report.TotalFiles != 2 {
+		t.Errorf("notification = %+v", got)
+	}
+}
+
+// buildNotificationMail のテスト
+func TestBuildNotificationMail(t *testing.T) {
+	readFile := func(p string) ([]byte, error) {
+		if p == "out/a.csv" {
+			return []byte("Folder Path,File Count\n"), nil
+		}
+		return nil, errors.New("not found")
+	}
+	n := Notification{Archive: "in/a.zip", Status: BatchFailed, Error: "1 folder(s) exceed 10 files", Attachments: []string{"out/a.csv", "out/a.json"}}
+	msg := string(buildNotificationMail("from@example.com", []string{"a@example.com", "b@example.com"}, n, readFile))
+
+	for _, want := range []string{
+		"To: a@example.com, b@example.com\r\n",
+		"Error: 1 folder(s) exceed 10 files",
+		"Attachment not available: a.json",
+		`filename="a.csv"`,
+		"Rm9sZGVyIFBhdGgsRmlsZSBDb3VudAo=",
+		"--" + mailBoundary + "--\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("mail does not contain %q:\n%s", want, msg)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package obuziptest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// FolderCount は obuzipcount の出力に含まれるフォルダごとのファイル数です。
+type FolderCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// Report は obuzipcount の JSON 出力 (-format json) です。
+type Report struct {
+	Archive    string        `json:"archive"`
+	SHA256     string        `json:"sha256,omitempty"`
+	TotalFiles int           `json:"totalFiles"`
+	Folders    []FolderCount `json:"folders"`
+}
+
+// ReadJSONReport は JSON 出力を読み込みます。
+// JSON 出力ではルートフォルダを空文字列で表すため、CSV 出力と比較できるよう "(Root)" に揃えます。
+func ReadJSONReport(r io.Reader) (Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return Report{}, fmt.Errorf("failed to decode report: %w", err)
+	}
+	for i, f := range report.Folders {
+		if f.Path == "" {
+			report.Folders[i].Path = "(Root)"
+		}
+	}
+	return report, nil
+}
+
+// ReadCSV は CSV 出力の "Folder Path" と "File Count" の列を読み込みます。
+// BOM の有無と、区切り文字 (カンマ、タブ、セミコロン) は自動で判定します。
+func ReadCSV(r io.Reader) ([]FolderCount, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	pathCol, countCol := -1, -1
+	for i, h := range header {
+		switch h {
+		case "Folder Path":
+			pathCol = i
+		case "File Count":
+			countCol = i
+		}
+	}
+	if pathCol < 0 || countCol < 0 {
+		return nil, errors.New("csv has no Folder Path / File Count columns")
+	}
+
+	var folders []FolderCount
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		n, err := strconv.Atoi(record[countCol])
+		if err != nil {
+			return nil, fmt.Errorf("invalid file count %q: %w", record[countCol], err)
+		}
+		folders = append(folders, FolderCount{Path: record[pathCol], Count: n})
+	}
+	return folders, nil
+}
+
+// detectDelimiter は見出し行から区切り文字を判定します。
+func detectDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	for _, d := range []rune{'\t', ';'} {
+		if bytes.ContainsRune(line, d) {
+			return d
+		}
+	}
+	return ','
+}
+
+// AssertFolderCounts は集計結果が期待どおりか、順序を含めて検証します。
+func AssertFolderCounts(tb testing.TB, got, want []FolderCount) {
+	tb.Helper()
+	if diff := diffFolderCounts(got, want); diff != "" {
+		tb.Errorf("folder counts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// AssertFolderCount は指定したフォルダのファイル数を検証します。フォルダが無い場合も失敗します。
+func AssertFolderCount(tb testing.TB, got []FolderCount, path string, want int) {
+	tb.Helper()
+	for _, fc := range got {
+		if fc.Path == path {
+			if fc.Count != want {
+				tb.Errorf("folder %q: want %d files, got %d", path, want, fc.Count)
+			}
+			return
+		}
+	}
+	tb.Errorf("folder %q not found in %v", path, got)
+}
+
+// diffFolderCounts は2つの集計結果の違いを行ごとに表した文字列を返します。同じ場合は空文字列です。
+func diffFolderCounts(got, want []FolderCount) string {
+	var b strings.Builder
+	for i := 0; i < max(len(got), len(want)); i++ {
+		switch {
+		case i >= len(got):
+			fmt.Fprintf(&b, "- [%d] %s: %d\n", i, want[i].Path, want[i].Count)
+		case i >= len(want):
+			fmt.Fprintf(&b, "+ [%d] %s: %d\n", i, got[i].Path, got[i].Count)
+		case got[i] != want[i]:
+			fmt.Fprintf(&b, "- [%d] %s: %d\n+ [%d] %s: %d\n", i, want[i].Path, want[i].Count, i, got[i].Path, got[i].Count)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+// Package obuziptest は obuzipcount と組み合わせるパイプラインのテストを書くための補助関数です。
+// メモリ上でZIPファイルを組み立てるビルダーと、集計結果を検証するアサーションを提供します。
+package obuziptest
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// entry はビルダーに追加されたエントリです。
+type entry struct {
+	name     string
+	content  []byte
+	modified time.Time
+	nonUTF8  bool
+	comment  string
+}
+
+// Builder はメモリ上でZIPファイルを組み立てます。メソッドはチェーンして呼び出せます。
+type Builder struct {
+	entries []entry
+}
+
+// New は空のビルダーを作成します。
+func New() *Builder {
+	return &Builder{}
+}
+
+// Files は内容が空のファイルを追加します。
+func (b *Builder) Files(names ...string) *Builder {
+	for _, name := range names {
+		b.entries = append(b.entries, entry{name: name})
+	}
+	return b
+}
+
+// File は内容を指定してファイルを追加します。
+func (b *Builder) File(name string, content []byte) *Builder {
+	b.entries = append(b.entries, entry{name: name, content: content})
+	return b
+}
+
+// FileAt は更新日時を指定してファイルを追加します。
+func (b *Builder) FileAt(name string, modified time.Time) *Builder {
+	b.entries = append(b.entries, entry{name: name, modified: modified})
+	return b
+}
+
+// CommentedFile はエントリのコメントを指定してファイルを追加します。
+func (b *Builder) CommentedFile(name, comment string) *Builder {
+	b.entries = append(b.entries, entry{name: name, comment: comment})
+	return b
+}
+
+// Dir はディレクトリのエントリを追加します。名前の末尾に "/" が無ければ補います。
+func (b *Builder) Dir(name string) *Builder {
+	if len(name) == 0 || name[len(name)-1] != '/' {
+		name += "/"
+	}
+	b.entries = append(b.entries, entry{name: name})
+	return b
+}
+
+// RawFile はUTF-8フラグを立てずに、名前のバイト列をそのまま格納したファイルを追加します。
+// Shift_JIS などで名前を格納する古いアーカイバの出力を再現するために使います。
+func (b *Builder) RawFile(raw []byte) *Builder {
+	b.entries = append(b.entries, entry{name: string(raw), nonUTF8: true})
+	return b
+}
+
+// Count はビルダーに追加されたエントリの数を返します。
+func (b *Builder) Count() int {
+	return len(b.entries)
+}
+
+// Bytes はZIPファイルの内容を返します。
+func (b *Builder) Bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, e := range b.entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: e.modified, NonUTF8: e.nonUTF8, Comment: e.comment}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(e.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile はZIPファイルをテスト用の一時ディレクトリに書き出し、そのパスを返します。
+func (b *Builder) WriteFile(tb testing.TB) string {
+	tb.Helper()
+	data, err := b.Bytes()
+	if err != nil {
+		tb.Fatalf("failed to build zip: %v", err)
+	}
+	zipPath := filepath.Join(tb.TempDir(), "test.zip")
+	if err := os.WriteFile(zipPath, data, 0o644); err != nil {
+		tb.Fatalf("failed to write zip: %v", err)
+	}
+	return zipPath
+}
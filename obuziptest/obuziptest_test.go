@@ -0,0 +1,103 @@
+package obuziptest
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Builder のテスト
+func TestBuilder(t *testing.T) {
+	data, err := New().Files("dir1/a.txt", "b.txt").Dir("empty").RawFile([]byte("\x93\xfa/c.txt")).Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	if len(zr.File) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(zr.File))
+	}
+	if zr.File[2].Name != "empty/" || !zr.File[2].FileInfo().IsDir() {
+		t.Errorf("expected directory entry, got %q", zr.File[2].Name)
+	}
+	if !zr.File[3].NonUTF8 || zr.File[3].Name != "\x93\xfa/c.txt" {
+		t.Errorf("expected raw non-UTF-8 name, got %q (NonUTF8=%v)", zr.File[3].Name, zr.File[3].NonUTF8)
+	}
+}
+
+// ReadCSV と ReadJSONReport のテスト
+func TestReadOutputs(t *testing.T) {
+	want := []FolderCount{{Path: `dir1\sub`, Count: 2}, {Path: "(Root)", Count: 1}}
+
+	t.Run("BOM付きCSV", func(t *testing.T) {
+		got, err := ReadCSV(strings.NewReader("\xEF\xBB\xBFFolder Path,File Count\ndir1\\sub,2\n(Root),1\n"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		AssertFolderCounts(t, got, want)
+	})
+
+	t.Run("タブ区切りと追加の列", func(t *testing.T) {
+		got, err := ReadCSV(strings.NewReader("Folder Path\tFile Count\tPath Length\ndir1\\sub\t2\t8\n(Root)\t1\t0\n"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		AssertFolderCounts(t, got, want)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		report, err := ReadJSONReport(strings.NewReader(`{"archive":"a.zip","totalFiles":3,"folders":[{"path":"dir1\\sub","count":2},{"path":"(Root)","count":1}]}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if report.TotalFiles != 3 {
+			t.Errorf("expected 3 files, got %d", report.TotalFiles)
+		}
+		AssertFolderCounts(t, report.Folders, want)
+		AssertFolderCount(t, report.Folders, `dir1\sub`, 2)
+	})
+}
+
+// diffFolderCounts のテスト
+func TestDiffFolderCounts(t *testing.T) {
+	got := []FolderCount{{Path: "a", Count: 1}, {Path: "b", Count: 2}}
+	if diff := diffFolderCounts(got, got); diff != "" {
+		t.Errorf("expected no diff, got %q", diff)
+	}
+	expected := "- [1] b: 3\n+ [1] b: 2\n- [2] c: 1\n"
+	if diff := diffFolderCounts(got, []FolderCount{{Path: "a", Count: 1}, {Path: "b", Count: 3}, {Path: "c", Count: 1}}); diff != expected {
+		t.Errorf("expected %q, got %q", expected, diff)
+	}
+}
+
+// Synthetic のテスト
+func TestSynthetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []SyntheticEntry
+	}{
+		{name: "32ビットに収まる", entries: []SyntheticEntry{{Name: "a.txt", Size: 10, CompressedSize: 5}}},
+		{name: "4GBを超えるサイズと位置", entries: []SyntheticEntry{{Name: "big.bin", Size: 5 << 30, CompressedSize: 1 << 32, Offset: 6 << 30}, {Name: "b.txt", Size: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Synthetic(tt.entries)
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("invalid zip: %v", err)
+			}
+			if len(zr.File) != len(tt.entries) {
+				t.Fatalf("expected %d entries, got %d", len(tt.entries), len(zr.File))
+			}
+			for i, e := range tt.entries {
+				f := zr.File[i]
+				if f.Name != e.Name || f.UncompressedSize64 != e.Size || f.CompressedSize64 != e.CompressedSize {
+					t.Errorf("expected %+v, got %s (%d, %d)", e, f.Name, f.UncompressedSize64, f.CompressedSize64)
+				}
+			}
+		})
+	}
+}
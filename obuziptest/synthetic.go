@@ -0,0 +1,142 @@
+package obuziptest
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// SyntheticEntry は Synthetic で作成するZIPファイルのエントリです。
+// サイズと位置はセントラルディレクトリに記録する値で、実際の内容は書き込みません。
+type SyntheticEntry struct {
+	Name           string
+	Size           uint64
+	CompressedSize uint64
+	// Offset はローカルファイルヘッダの位置として記録する値です。0 の場合は実際の位置を記録します。
+	Offset   uint64
+	Modified time.Time
+}
+
+// zip64Limit 以上の値はZIP64の拡張フィールドに記録します。
+const zip64Limit = 0xFFFFFFFF
+
+// Synthetic はファイルの内容を持たず、ローカルファイルヘッダとセントラルディレクトリだけのZIPファイルを作成します。
+// エントリの一覧だけを読み込む処理のテストに、数GBのファイルや65535件を超えるエントリを小さなデータで再現できます。
+// エントリ数、サイズ、位置のいずれかが32ビット (件数は16ビット) に収まらない場合はZIP64の形式で記録します。
+func Synthetic(entries []SyntheticEntry) []byte {
+	var b []byte
+	offsets := make([]uint64, len(entries))
+	for i, e := range entries {
+		offsets[i] = e.Offset
+		if e.Offset == 0 {
+			offsets[i] = uint64(len(b))
+		}
+		date, tm := dosDateTime(e.Modified)
+		b = binary.LittleEndian.AppendUint32(b, 0x04034b50)
+		b = binary.LittleEndian.AppendUint16(b, 20)
+		b = binary.LittleEndian.AppendUint16(b, 0x800)
+		b = binary.LittleEndian.AppendUint16(b, 8)
+		b = binary.LittleEndian.AppendUint16(b, tm)
+		b = binary.LittleEndian.AppendUint16(b, date)
+		b = append(b, make([]byte, 12)...) // CRC-32 とサイズ
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(e.Name)))
+		b = binary.LittleEndian.AppendUint16(b, 0)
+		b = append(b, e.Name...)
+	}
+
+	start := uint64(len(b))
+	zip64 := len(entries) >= 0xFFFF || start >= zip64Limit
+	for i, e := range entries {
+		var extra []byte
+		usize, csize, offset := uint32(e.Size), uint32(e.CompressedSize), uint32(offsets[i])
+		// 拡張フィールドには展開後のサイズ、圧縮後のサイズ、位置の順に、必要なものだけを記録する
+		if e.Size >= zip64Limit {
+			usize, extra = zip64Limit, binary.LittleEndian.AppendUint64(extra, e.Size)
+		}
+		if e.CompressedSize >= zip64Limit {
+			csize, extra = zip64Limit, binary.LittleEndian.AppendUint64(extra, e.CompressedSize)
+		}
+		if offsets[i] >= zip64Limit {
+			offset, extra = zip64Limit, binary.LittleEndian.AppendUint64(extra, offsets[i])
+		}
+		version := uint16(20)
+		if len(extra) > 0 {
+			extra = append(binary.LittleEndian.AppendUint16(binary.LittleEndian.AppendUint16(nil, 0x0001), uint16(len(extra))), extra...)
+			version = 45
+		}
+		date, tm := dosDateTime(e.Modified)
+
+		b = binary.LittleEndian.AppendUint32(b, 0x02014b50)
+		b = binary.LittleEndian.AppendUint16(b, version) // 作成バージョン
+		b = binary.LittleEndian.AppendUint16(b, version) // 展開に必要なバージョン
+		b = binary.LittleEndian.AppendUint16(b, 0x800)   // UTF-8
+		b = binary.LittleEndian.AppendUint16(b, 8)       // Deflate
+		b = binary.LittleEndian.AppendUint16(b, tm)
+		b = binary.LittleEndian.AppendUint16(b, date)
+		b = binary.LittleEndian.AppendUint32(b, 0) // CRC-32
+		b = binary.LittleEndian.AppendUint32(b, csize)
+		b = binary.LittleEndian.AppendUint32(b, usize)
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(e.Name)))
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(extra)))
+		b = binary.LittleEndian.AppendUint16(b, 0) // コメント長
+		b = binary.LittleEndian.AppendUint16(b, 0) // 開始ディスク
+		b = binary.LittleEndian.AppendUint16(b, 0) // 内部属性
+		b = binary.LittleEndian.AppendUint32(b, 0) // 外部属性
+		b = binary.LittleEndian.AppendUint32(b, offset)
+		b = append(append(b, e.Name...), extra...)
+	}
+	size := uint64(len(b)) - start
+
+	records, cdSize, cdOffset := uint16(len(entries)), uint32(size), uint32(start)
+	if zip64 || size >= zip64Limit {
+		end := uint64(len(b))
+		b = binary.LittleEndian.AppendUint32(b, 0x06064b50)
+		b = binary.LittleEndian.AppendUint64(b, 44) // 以降のレコード長
+		b = binary.LittleEndian.AppendUint16(b, 45)
+		b = binary.LittleEndian.AppendUint16(b, 45)
+		b = binary.LittleEndian.AppendUint32(b, 0) // このディスクの番号
+		b = binary.LittleEndian.AppendUint32(b, 0) // セントラルディレクトリの開始ディスク
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(entries)))
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(entries)))
+		b = binary.LittleEndian.AppendUint64(b, size)
+		b = binary.LittleEndian.AppendUint64(b, start)
+
+		b = binary.LittleEndian.AppendUint32(b, 0x07064b50)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint64(b, end)
+		b = binary.LittleEndian.AppendUint32(b, 1) // 全体のディスク数
+
+		records, cdSize, cdOffset = 0xFFFF, zip64Limit, zip64Limit
+	}
+
+	b = binary.LittleEndian.AppendUint32(b, 0x06054b50)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, records)
+	b = binary.LittleEndian.AppendUint16(b, records)
+	b = binary.LittleEndian.AppendUint32(b, cdSize)
+	b = binary.LittleEndian.AppendUint32(b, cdOffset)
+	return binary.LittleEndian.AppendUint16(b, 0) // コメント長
+}
+
+// WriteSynthetic は Synthetic で作成したZIPファイルをテスト用の一時ディレクトリに書き出し、そのパスを返します。
+func WriteSynthetic(tb testing.TB, entries []SyntheticEntry) string {
+	tb.Helper()
+	zipPath := filepath.Join(tb.TempDir(), "synthetic.zip")
+	if err := os.WriteFile(zipPath, Synthetic(entries), 0o644); err != nil {
+		tb.Fatalf("failed to write zip: %v", err)
+	}
+	return zipPath
+}
+
+// dosDateTime は時刻をMS-DOS形式の日付と時刻に変換します。1980年より前の時刻は1980-01-01とします。
+func dosDateTime(t time.Time) (date, tm uint16) {
+	if t.Year() < 1980 {
+		return 1<<5 | 1, 0
+	}
+	date = uint16((t.Year()-1980)<<9 | int(t.Month())<<5 | t.Day())
+	tm = uint16(t.Hour()<<11 | t.Minute()<<5 | t.Second()/2)
+	return date, tm
+}
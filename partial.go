@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PartialReader は期限までに読み込めたエントリだけを返せる ArchiveReader です。
+type PartialReader interface {
+	ArchiveReader
+	// ReadEntriesUntil は期限までに読み込んだエントリと、アーカイブ内のエントリの総数を返します。
+	// deadline がゼロ値の場合はすべてのエントリを読み込みます。
+	ReadEntriesUntil(path string, deadline time.Time) ([]FileEntry, int, error)
+}
+
+// SamplingReader は読み込みと同時にファイルのエントリを抽出できる ArchiveReader です。
+// 抽出しないファイルのエントリは名前の復号などの変換を行いません。
+type SamplingReader interface {
+	ArchiveReader
+	// ReadEntriesSample は期限までに読み込んだエントリのうち、抽出したファイルとすべてのフォルダのエントリを返します。
+	// 期限までに読み込んだエントリの数と、アーカイブ内のエントリの総数も返します。
+	ReadEntriesSample(path string, deadline time.Time, percent float64) ([]FileEntry, int, int, error)
+}
+
+// PartialScan は時間の上限で読み込みを打ち切った場合、または -sample でエントリを抽出した場合の、集計した範囲と推定値です。
+type PartialScan struct {
+	// Budget は読み込みの時間の上限です。時間の上限で打ち切っていない場合は空です。
+	Budget         string `json:"budget,omitempty"`
+	ScannedEntries int    `json:"scannedEntries"`
+	TotalEntries   int    `json:"totalEntries"`
+	// SamplePercent は読み込んだエントリから抽出したファイルの割合 (パーセント) です。抽出しない場合は0です。
+	SamplePercent float64 `json:"samplePercent,omitempty"`
+	// SampledFiles は抽出したファイルの数です。
+	SampledFiles int `json:"sampledFiles,omitempty"`
+	// EstimatedTotalFiles は読み込んだ範囲の割合から推定したファイルの総数です。
+	EstimatedTotalFiles int `json:"estimatedTotalFiles"`
+	// EstimatedTotalMargin は抽出した場合の EstimatedTotalFiles の95%信頼区間の幅 (±) です。
+	EstimatedTotalMargin int `json:"estimatedTotalMargin,omitempty"`
+}
+
+// ratio は集計したエントリの割合です。
+func (p PartialScan) ratio() float64 {
+	r := 1.0
+	if p.TotalEntries > 0 {
+		r = float64(p.ScannedEntries) / float64(p.TotalEntries)
+	}
+	if p.SamplePercent > 0 {
+		r *= p.SamplePercent / 100
+	}
+	return r
+}
+
+// estimate は読み込んだ範囲の件数から、全体を読み込んだ場合の件数を推定します。(純粋関数)
+// 残りのエントリも読み込んだ範囲と同じ分布であると仮定します。
+func (p PartialScan) estimate(n int) int {
+	if p.ScannedEntries == 0 {
+		return 0
+	}
+	return int(float64(n)/p.ratio() + 0.5)
+}
+
+// margin は抽出した件数 n から求めた推定値の95%信頼区間の幅 (±) を返します。抽出しない場合は0です。(純粋関数)
+// 各ファイルが独立に抽出されたとみなし、二項分布の正規近似を使います。時間の上限による打ち切りの偏りは含みません。
+func (p PartialScan) margin(n int) int {
+	if p.SamplePercent <= 0 || p.SamplePercent >= 100 || p.ScannedEntries == 0 {
+		return 0
+	}
+	q := p.SamplePercent / 100
+	return int(math.Ceil(1.96 * math.Sqrt(float64(n)*(1-q)) / p.ratio()))
+}
+
+// scaledThreshold は推定値がしきい値以上になるフォルダを抽出するため、読み込んだ割合に合わせてしきい値を下げます。(純粋関数)
+func (p PartialScan) scaledThreshold(threshold int) int {
+	return int(float64(threshold) * p.ratio())
+}
+
+// scaledOptions は全体のしきい値とフォルダパスのパターンごとのしきい値を scaledThreshold で下げた集計オプションを返します。(純粋関数)
+func (p PartialScan) scaledOptions(opts AggregateOptions) AggregateOptions {
+	opts.Threshold = p.scaledThreshold(opts.Threshold)
+	rules := make([]ThresholdRule, len(opts.ThresholdRules))
+	for i, r := range opts.ThresholdRules {
+		rules[i] = ThresholdRule{Pattern: r.Pattern, Threshold: p.scaledThreshold(r.Threshold)}
+	}
+	opts.ThresholdRules = rules
+	return opts
+}
+
+// applyEstimates はフォルダごとの推定値を設定し、推定値がフォルダのしきい値以上のフォルダだけを返します。(純粋関数)
+// CollapseOther が指定されている場合は、推定値がしきい値未満のフォルダも末尾のまとめた行に加えます。
+func (p PartialScan) applyEstimates(folders []FolderCount, opts AggregateOptions) []FolderCount {
+	var results []FolderCount
+	var other FolderCount
+	for _, f := range folders {
+		f.Estimate, f.EstimateMargin = p.estimate(f.Count), p.margin(f.Count)
+		switch {
+		case f.OtherFolders == 0 && f.Estimate >= opts.FolderThreshold(f.Path):
+			results = append(results, f)
+		case f.OtherFolders > 0 || opts.CollapseOther:
+			other.absorb(f)
+		}
+	}
+	if other.OtherFolders > 0 {
+		other.Estimate, other.EstimateMargin = p.estimate(other.Count), p.margin(other.Count)
+		results = append(results, other)
+	}
+	return results
+}
+
+// estimateText はフォルダの推定値を、抽出した場合は信頼区間の幅を付けて表記します。(純粋関数)
+func (r FolderCount) estimateText() string {
+	if r.EstimateMargin > 0 {
+		return fmt.Sprintf("%d ±%d", r.Estimate, r.EstimateMargin)
+	}
+	return strconv.Itoa(r.Estimate)
+}
+
+// notice は部分的な結果であることを示す1行の説明です。
+func (p PartialScan) notice() string {
+	if p.SamplePercent > 0 {
+		scanned := ""
+		if p.Budget != "" {
+			scanned = fmt.Sprintf("time budget %s exhausted after %d of %d entries; ", p.Budget, p.ScannedEntries, p.TotalEntries)
+		}
+		return fmt.Sprintf("SAMPLED RESULT: %s%g%% sample (%d files); estimates are shown with 95%% confidence margins",
+			scanned, p.SamplePercent, p.SampledFiles)
+	}
+	return fmt.Sprintf("PARTIAL RESULT: time budget %s exhausted after %d of %d entries (%.1f%%); estimates assume the remaining entries are distributed alike",
+		p.Budget, p.ScannedEntries, p.TotalEntries, p.ratio()*100)
+}
+
+// readEntriesUntil は reader が PartialReader であれば期限まで、そうでなければすべてのエントリを読み込みます。
+func readEntriesUntil(reader ArchiveReader, path string, deadline time.Time) ([]FileEntry, int, error) {
+	if pr, ok := reader.(PartialReader); ok {
+		return pr.ReadEntriesUntil(path, deadline)
+	}
+	entries, err := reader.ReadEntries(path)
+	return entries, len(entries), err
+}
+
+// ReadEntriesUntil は PartialReader の実装です。ZIP以外の形式は常にすべてのエントリを読み込みます。
+// キャッシュを使う場合、すべてのエントリを読み込めたときだけ保存します。
+func (a AutoArchiveReader) ReadEntriesUntil(archivePath string, deadline time.Time) ([]FileEntry, int, error) {
+	if a.Cache == nil {
+		return a.readEntriesUntil(archivePath, deadline)
+	}
+	if entries, ok := a.Cache.Load(archivePath); ok {
+		return entries, len(entries), nil
+	}
+	entries, total, err := a.readEntriesUntil(archivePath, deadline)
+	if err == nil && len(entries) == total {
+		_ = a.Cache.Store(archivePath, entries)
+	}
+	return entries, total, err
+}
+
+// readEntriesSample は reader が SamplingReader であれば読み込みながら、そうでなければ読み込んだ後にファイルのエントリを抽出します。
+// 抽出したエントリ、期限までに読み込んだエントリの数、アーカイブ内のエントリの総数を返します。
+func readEntriesSample(reader ArchiveReader, path string, deadline time.Time, percent float64) ([]FileEntry, int, int, error) {
+	if sr, ok := reader.(SamplingReader); ok {
+		return sr.ReadEntriesSample(path, deadline, percent)
+	}
+	entries, total, err := readEntriesUntil(reader, path, deadline)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sampled, _ := SampleEntries(entries, percent)
+	return sampled, len(entries), total, nil
+}
+
+// ReadEntriesSample は SamplingReader の実装です。ZIP以外の形式はすべて読み込んでから抽出します。
+// 抽出した結果はキャッシュに保存しませんが、キャッシュにすべてのエントリがあればそれを使います。
+func (a AutoArchiveReader) ReadEntriesSample(archivePath string, deadline time.Time, percent float64) ([]FileEntry, int, int, error) {
+	if a.Cache != nil {
+		if entries, ok := a.Cache.Load(archivePath); ok {
+			sampled, _ := SampleEntries(entries, percent)
+			return sampled, len(entries), len(entries), nil
+		}
+	}
+	if r, ok := registeredArchiveReader(archivePath); ok {
+		return readEntriesSample(r, archivePath, deadline, percent)
+	}
+	if a.kind(archivePath) == "zip" {
+		return a.Zip.ReadEntriesSample(archivePath, deadline, percent)
+	}
+	entries, err := a.readEntries(archivePath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	sampled, _ := SampleEntries(entries, percent)
+	return sampled, len(entries), len(entries), nil
+}
+
+// readEntriesUntil はキャッシュを使わずに、期限までエントリを読み込みます。
+func (a AutoArchiveReader) readEntriesUntil(archivePath string, deadline time.Time) ([]FileEntry, int, error) {
+	if r, ok := registeredArchiveReader(archivePath); ok {
+		return readEntriesUntil(r, archivePath, deadline)
+	}
+	if a.kind(archivePath) == "zip" {
+		return a.Zip.ReadEntriesUntil(archivePath, deadline)
+	}
+	entries, err := a.readEntries(archivePath)
+	return entries, len(entries), err
+}
+
+// ParseSamplePercent は -sample の値 ("5%" または "5") を解析します。空の場合は0です。(純粋関数)
+func ParseSamplePercent(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid sample percentage: %s", s)
+	}
+	return p, nil
+}
+
+// SampleEntries はファイルのエントリを percent パーセントの割合で抽出します。フォルダのエントリはすべて残します。(純粋関数)
+// 同じアーカイブで結果が変わらないよう、乱数ではなく復号前のエントリ名のハッシュ値で抽出するかを決めます。
+func SampleEntries(entries []FileEntry, percent float64) ([]FileEntry, int) {
+	var sampled []FileEntry
+	files := 0
+	for _, e := range entries {
+		if !e.IsDir {
+			name := e.RawName
+			if name == "" {
+				name = e.Name
+			}
+			if !sampledName(name, percent) {
+				continue
+			}
+			files++
+		}
+		sampled = append(sampled, e)
+	}
+	return sampled, files
+}
+
+// sampledName は復号前のエントリ名 name のファイルを percent パーセントの抽出に含めるかを返します。(純粋関数)
+func sampledName(name string, percent float64) bool {
+	return sampleHash(name)>>32 < uint64(percent/100*float64(math.MaxUint32))
+}
+
+// sampleHash はエントリ名のハッシュ値です。(純粋関数)
+// 連番のように末尾だけが異なる名前でも偏らないよう、FNV-1a の値を MurmurHash3 の最終処理で攪拌します。
+func sampleHash(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
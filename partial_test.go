@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// ZipArchiveReader の期限による打ち切りのテスト
+func TestZipArchiveReaderUntil(t *testing.T) {
+	b := obuziptest.New()
+	for i := 0; i < 3000; i++ {
+		b.Files(fmt.Sprintf("dir%d/%04d.txt", i%2, i))
+	}
+	zipPath := b.WriteFile(t)
+
+	entries, total, err := (ZipArchiveReader{}).ReadEntriesUntil(zipPath, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != entryCheckInterval || total != 3000 {
+		t.Errorf("expected %d of 3000 entries, got %d of %d", entryCheckInterval, len(entries), total)
+	}
+
+	entries, total, err = (ZipArchiveReader{}).ReadEntriesUntil(zipPath, time.Time{})
+	if err != nil || len(entries) != 3000 || total != 3000 {
+		t.Errorf("expected all entries, got %d of %d (%v)", len(entries), total, err)
+	}
+}
+
+// partialFakeReader は総数の半分だけを読み込んだものとして返す PartialReader です。
+type partialFakeReader struct {
+	entries []FileEntry
+}
+
+func (r partialFakeReader) ReadEntries(string) ([]FileEntry, error) {
+	return r.entries, nil
+}
+
+func (r partialFakeReader) ReadEntriesUntil(_ string, deadline time.Time) ([]FileEntry, int, error) {
+	if deadline.IsZero() {
+		return r.entries, len(r.entries), nil
+	}
+	return r.entries[:len(r.entries)/2], len(r.entries), nil
+}
+
+// 時間の上限で打ち切った場合の推定値のテスト
+func TestAppRunTimeBudget(t *testing.T) {
+	var entries []FileEntry
+	for i := 0; i < 6; i++ {
+		entries = append(entries, FileEntry{Name: fmt.Sprintf("dir1/%d.txt", i)})
+	}
+	for i := 0; i < 2; i++ {
+		entries = append(entries, FileEntry{Name: fmt.Sprintf("dir2/%d.txt", i)})
+	}
+	// 前半の4件 (すべて dir1) だけが読み込まれる
+	app := &App{Reader: partialFakeReader{entries: entries}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	out := new(bytes.Buffer)
+	if err := app.Run(AppConfig{ZipPath: "a.zip", Threshold: 5, Format: "json", TimeBudget: time.Minute}, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if report.Partial == nil || report.Partial.ScannedEntries != 4 || report.Partial.TotalEntries != 8 || report.Partial.EstimatedTotalFiles != 8 {
+		t.Fatalf("unexpected partial: %+v", report.Partial)
+	}
+	if len(report.Folders) != 1 || report.Folders[0].Count != 4 || report.Folders[0].Estimate != 8 {
+		t.Errorf("unexpected folders: %+v", report.Folders)
+	}
+	if len(report.Findings) == 0 || report.Findings[0].Rule != RulePartial {
+		t.Errorf("expected partial finding first, got %+v", report.Findings)
+	}
+
+	t.Run("テキスト出力の表示", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := app.Run(AppConfig{ZipPath: "a.zip", Threshold: 5, Format: "text", TimeBudget: time.Minute}, out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "PARTIAL RESULT: time budget 1m0s exhausted after 4 of 8 entries (50.0%)") ||
+			!strings.Contains(out.String(), "| Estimated") {
+			t.Errorf("unexpected output: %q", out.String())
+		}
+	})
+}
+
+// ParseSamplePercent のテスト
+func TestParseSamplePercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{name: "未指定", input: "", expected: 0},
+		{name: "パーセント記号付き", input: "5%", expected: 5},
+		{name: "小数", input: "0.5", expected: 0.5},
+		{name: "0は不正", input: "0%", wantErr: true},
+		{name: "100を超える", input: "150%", wantErr: true},
+		{name: "数値でない", input: "half", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSamplePercent(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSamplePercent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseSamplePercent() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// 抽出した場合の推定値と信頼区間のテスト
+func TestAppRunSample(t *testing.T) {
+	entries := []FileEntry{{Name: "dir1/", IsDir: true}}
+	for i := 0; i < 4000; i++ {
+		entries = append(entries, FileEntry{Name: fmt.Sprintf("dir%d/%04d.txt", i%2+1, i)})
+	}
+	sampled, files := SampleEntries(entries, 10)
+	if files < 300 || files > 500 {
+		t.Fatalf("sampled %d files, want about 400", files)
+	}
+	if !sampled[0].IsDir {
+		t.Error("directory entries should be kept")
+	}
+	if again, _ := SampleEntries(entries, 10); len(again) != len(sampled) {
+		t.Error("sampling should be deterministic")
+	}
+
+	app := &App{Reader: partialFakeReader{entries: entries}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	out := new(bytes.Buffer)
+	if err := app.Run(AppConfig{ZipPath: "a.zip", Threshold: 1000, Format: "json", SamplePercent: 10}, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if report.Partial == nil || report.Partial.Budget != "" || report.Partial.SampledFiles != files || report.Partial.EstimatedTotalMargin == 0 {
+		t.Fatalf("unexpected partial: %+v", report.Partial)
+	}
+	if len(report.Folders) != 2 {
+		t.Fatalf("unexpected folders: %+v", report.Folders)
+	}
+	for _, f := range report.Folders {
+		if f.EstimateMargin == 0 || f.Estimate-f.EstimateMargin > 2000 || f.Estimate+f.EstimateMargin < 2000 {
+			t.Errorf("2000 is outside the interval of %+v", f)
+		}
+	}
+}
+
+// ZipArchiveReader.ReadEntriesSample のテスト (読み込みながら抽出しても、読み込んだ後の抽出と同じ結果になる)
+func TestZipArchiveReaderReadEntriesSample(t *testing.T) {
+	var names []string
+	for i := 0; i < 500; i++ {
+		names = append(names, fmt.Sprintf("dir%d/%04d.txt", i%3, i))
+	}
+	zipPath := obuziptest.New().Dir("dir0/").Files(names...).WriteFile(t)
+	z := ZipArchiveReader{Workers: 4}
+	all, err := z.ReadEntries(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := SampleEntries(all, 20)
+
+	got, scanned, total, err := z.ReadEntriesSample(zipPath, time.Time{}, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned != len(all) || total != len(all) {
+		t.Errorf("expected %d scanned entries, got %d of %d", len(all), scanned, total)
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(got))
+	}
+	for i := range got {
+		if got[i].Name != expected[i].Name {
+			t.Fatalf("entry %d: expected %q, got %q", i, expected[i].Name, got[i].Name)
+		}
+	}
+	if !got[0].IsDir {
+		t.Error("directory entries should be kept")
+	}
+}
+
+// -sample と -time-budget を -manifest、-detail-csv と併用できないことのテスト
+func TestRunCLISampleRejectsFullOutputs(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt").WriteFile(t)
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "-sample と -manifest", args: []string{"-sample", "10%", "-manifest", filepath.Join(dir, "m.csv")}},
+		{name: "-time-budget と -detail-csv", args: []string{"-time-budget", "1m", "-detail-csv", filepath.Join(dir, "d.csv")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"count", "-zip", zipPath}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != 2 {
+				t.Fatalf("expected code 2, got %d (stderr: %s)", code, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// PathCompareOptions はエントリ名を比較する前の正規化方法です。
+type PathCompareOptions struct {
+	// Normalize はUnicode正規化の関数です。nil の場合は正規化しません。
+	Normalize func(string) string
+	// IgnoreCase が true の場合、大文字と小文字を区別しません。
+	IgnoreCase bool
+	// IncludeDirs が true の場合、ディレクトリのエントリも比較します。
+	IncludeDirs bool
+}
+
+// PathDifference は片方のアーカイブにしか存在しないエントリです。
+type PathDifference struct {
+	// Side は存在する側 ("A" または "B") です。
+	Side string
+	Name string
+}
+
+// PathComparison は2つのアーカイブのエントリ名の比較結果です。
+type PathComparison struct {
+	// Differences は正規化しても対応が取れないエントリです。
+	Differences []PathDifference
+	// Matched は対応が取れたエントリの数です。
+	Matched int
+	// Respelled は対応は取れたが、元の名前が異なるエントリの数です (区切り文字や正規化の違いなど)。
+	Respelled int
+}
+
+// CanonicalPath はプラットフォームによる表記の違いを取り除いた比較用のパスを返します。(純粋関数)
+// 区切り文字を "/" に揃え、先頭の "./" や "/" と末尾の "/" を取り除いてから正規化します。
+func CanonicalPath(name string, opts PathCompareOptions) string {
+	p := strings.ReplaceAll(name, "\\", "/")
+	p = strings.TrimPrefix(p, "./")
+	p = strings.Trim(p, "/")
+	if opts.Normalize != nil {
+		p = opts.Normalize(p)
+	}
+	if opts.IgnoreCase {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// ComparePathSets は2つのアーカイブのエントリを正規化したパスで比較します。(純粋関数)
+// 同じ正規化パスのエントリが複数ある場合は、その数の違いも差分として扱います。
+// 差分はパスの昇順、同じ場合は A, B の順でソートされます。
+func ComparePathSets(a, b []FileEntry, opts PathCompareOptions) PathComparison {
+	index := func(entries []FileEntry) map[string][]string {
+		m := make(map[string][]string)
+		for _, e := range entries {
+			if e.IsDir && !opts.IncludeDirs {
+				continue
+			}
+			key := CanonicalPath(e.Name, opts)
+			if key == "" {
+				continue
+			}
+			m[key] = append(m[key], e.Name)
+		}
+		return m
+	}
+	ia, ib := index(a), index(b)
+
+	var cmp PathComparison
+	for key, namesA := range ia {
+		namesB := ib[key]
+		n := min(len(namesA), len(namesB))
+		cmp.Matched += n
+		for i := 0; i < n; i++ {
+			if namesA[i] != namesB[i] {
+				cmp.Respelled++
+			}
+		}
+		for _, name := range namesA[n:] {
+			cmp.Differences = append(cmp.Differences, PathDifference{Side: "A", Name: name})
+		}
+		for _, name := range namesB[n:] {
+			cmp.Differences = append(cmp.Differences, PathDifference{Side: "B", Name: name})
+		}
+	}
+	for key, namesB := range ib {
+		if _, ok := ia[key]; ok {
+			continue
+		}
+		for _, name := range namesB {
+			cmp.Differences = append(cmp.Differences, PathDifference{Side: "B", Name: name})
+		}
+	}
+
+	sort.Slice(cmp.Differences, func(i, j int) bool {
+		di, dj := cmp.Differences[i], cmp.Differences[j]
+		if di.Name == dj.Name {
+			return di.Side < dj.Side
+		}
+		return di.Name < dj.Name
+	})
+	return cmp
+}
+
+// WritePathComparisonText は比較結果をプレーンテキストでWriterに出力します。
+func WritePathComparisonText(w io.Writer, cmp PathComparison) error {
+	_, err := fmt.Fprintf(w, "Matched: %d (respelled: %d), Differences: %d\n", cmp.Matched, cmp.Respelled, len(cmp.Differences))
+	if err != nil {
+		return err
+	}
+	for _, d := range cmp.Differences {
+		mark := "-"
+		if d.Side == "B" {
+			mark = "+"
+		}
+		if _, err := fmt.Fprintf(w, "  %s %s\n", mark, d.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePathComparisonCSV は比較結果の差分をCSV形式でWriterに出力します。
+func WritePathComparisonCSV(w io.Writer, cmp PathComparison, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Side", "Entry Name"}); err != nil {
+			return err
+		}
+		for _, d := range cmp.Differences {
+			if err := writer.Write([]string{d.Side, d.Name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectPathComparisonWriter は出力形式名に対応する比較結果の書き出し関数を返します。CSVは opts に従って書き出します。
+func selectPathComparisonWriter(format string, opts CSVOptions) (func(io.Writer, PathComparison) error, error) {
+	switch format {
+	case "", "text":
+		return WritePathComparisonText, nil
+	case "csv":
+		return func(w io.Writer, cmp PathComparison) error {
+			return WritePathComparisonCSV(w, cmp, opts)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// runCompare は compare コマンドを実行します。
+// 差分がある場合は終了コード1で終了します。
+func runCompare(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "compare", "compare [options] <a.zip> <b.zip>")
+	var rf readerFlags
+	rf.register(fs)
+	normalization := fs.String("normalize", "nfc", "比較前のUnicode正規化 (nfc, nfd, nfkc, nfkd, none)")
+	ignoreCase := fs.Bool("ignore-case", false, "大文字と小文字を区別しない")
+	includeDirs := fs.Bool("dirs", false, "ディレクトリのエントリも比較する")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError(fs, "compare requires two zip paths")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectPathComparisonWriter(*format, csvOpts)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	normalize, err := ParseNormalization(*normalization)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	a, _, err := app.readEntries(fs.Arg(0), false)
+	if err != nil {
+		return err
+	}
+	b, _, err := app.readEntries(fs.Arg(1), false)
+	if err != nil {
+		return err
+	}
+
+	cmp := ComparePathSets(a, b, PathCompareOptions{Normalize: normalize, IgnoreCase: *ignoreCase, IncludeDirs: *includeDirs})
+	if err := write(env.Stdout, cmp); err != nil {
+		return err
+	}
+	if len(cmp.Differences) > 0 {
+		return fmt.Errorf("%d difference(s) found", len(cmp.Differences))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// ComparePathSets のテスト (区切り文字・正規化・大文字小文字の違いを除いた比較)
+func TestComparePathSets(t *testing.T) {
+	windows := []FileEntry{
+		{Name: "docs\\\u30ac\u30a4\u30c9.pdf"}, // NFC
+		{Name: `docs\README.txt`},
+		{Name: "docs/", IsDir: true},
+		{Name: "only-windows.txt"},
+	}
+	linux := []FileEntry{
+		{Name: "./docs/\u30ab\u3099\u30a4\u30c8\u3099.pdf"}, // NFD
+		{Name: "docs/readme.txt"},
+		{Name: "only-linux.txt"},
+	}
+
+	normalize, err := ParseNormalization("nfc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("正規化と大文字小文字の無視", func(t *testing.T) {
+		cmp := ComparePathSets(windows, linux, PathCompareOptions{Normalize: normalize, IgnoreCase: true})
+		expected := PathComparison{
+			Differences: []PathDifference{{Side: "B", Name: "only-linux.txt"}, {Side: "A", Name: "only-windows.txt"}},
+			Matched:     2,
+			Respelled:   2,
+		}
+		if !reflect.DeepEqual(cmp, expected) {
+			t.Errorf("expected %+v, got %+v", expected, cmp)
+		}
+	})
+
+	t.Run("正規化なし", func(t *testing.T) {
+		cmp := ComparePathSets(windows, linux, PathCompareOptions{})
+		if cmp.Matched != 0 || len(cmp.Differences) != 6 {
+			t.Errorf("unexpected result: %+v", cmp)
+		}
+	})
+
+	t.Run("同じパスの重複", func(t *testing.T) {
+		cmp := ComparePathSets([]FileEntry{{Name: "a.txt"}, {Name: "A.TXT"}}, []FileEntry{{Name: "a.txt"}}, PathCompareOptions{IgnoreCase: true})
+		expected := PathComparison{Differences: []PathDifference{{Side: "A", Name: "A.TXT"}}, Matched: 1}
+		if !reflect.DeepEqual(cmp, expected) {
+			t.Errorf("expected %+v, got %+v", expected, cmp)
+		}
+	})
+}
+
+// WritePathComparisonCSV のテスト (文字コードと区切り文字)
+func TestWritePathComparisonCSV(t *testing.T) {
+	cmp := PathComparison{Differences: []PathDifference{{Side: "A", Name: "docs/a;b.txt"}, {Side: "B", Name: "docs/c.txt"}}}
+	buf := new(bytes.Buffer)
+	if err := WritePathComparisonCSV(buf, cmp, CSVOptions{Encoding: "utf8", Delimiter: "semicolon"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Side;Entry Name\nA;\"docs/a;b.txt\"\nB;docs/c.txt\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// PathStyle は出力するフォルダパスの区切り文字の表記です。
+// 集計キーは常に "/" 区切りで、表記の変換は出力時に行います。ゼロ値は PathStyleWindows と同じです。
+type PathStyle string
+
+const (
+	// PathStyleWindows はバックスラッシュで区切ります。既定の表記です。
+	PathStyleWindows PathStyle = "windows"
+	// PathStylePOSIX はスラッシュで区切ります。
+	PathStylePOSIX PathStyle = "posix"
+	// PathStyleNative は実行中のOSの表記です。Windowsでは PathStyleWindows、それ以外では PathStylePOSIX です。
+	PathStyleNative PathStyle = "native"
+)
+
+// ParsePathStyle は -path-style の指定を解析します。空の場合は PathStyleWindows です。
+func ParsePathStyle(s string) (PathStyle, error) {
+	switch style := PathStyle(strings.ToLower(strings.TrimSpace(s))); style {
+	case "":
+		return PathStyleWindows, nil
+	case PathStyleWindows, PathStylePOSIX, PathStyleNative:
+		return style, nil
+	default:
+		return "", fmt.Errorf("unknown path style: %s (available: windows, posix, native)", s)
+	}
+}
+
+// Folder は "/" 区切りの集計キーの区切り文字を表記に合わせて変換します。(純粋関数)
+func (s PathStyle) Folder(key string) string {
+	if s == PathStyleNative {
+		if runtime.GOOS != "windows" {
+			return key
+		}
+		s = PathStyleWindows
+	}
+	if s == PathStylePOSIX {
+		return key
+	}
+	return strings.ReplaceAll(key, "/", "\\")
+}
+
+// FolderFormat は集計キーのフォルダパスを出力する際の表記です。ゼロ値はバックスラッシュ区切りで、ルートは "(Root)" です。
+type FolderFormat struct {
+	// Style は区切り文字の表記です。
+	Style PathStyle
+	// RootLabel はルートフォルダの表記です。空の場合は "(Root)" です。
+	RootLabel string
+	// emptyRoot が true の場合、ルートフォルダを空文字列で表します。JSONでは実際のパスと同じ扱いで結合できるようにします。
+	emptyRoot bool
+}
+
+// Folder は "/" 区切りの集計キーを表記に合わせて変換します。(純粋関数)
+func (f FolderFormat) Folder(key string) string {
+	if key == "(Root)" {
+		switch {
+		case f.emptyRoot:
+			return ""
+		case f.RootLabel != "":
+			return f.RootLabel
+		}
+		return key
+	}
+	return f.Style.Folder(key)
+}
+
+// Report はフォルダの行と検出事項のフォルダパスを表記に合わせて変換したレポートの複製を返します。(純粋関数)
+func (f FolderFormat) Report(report *Report) *Report {
+	out := *report
+	out.Folders = f.Folders(report.Folders)
+	if report.Findings != nil {
+		out.Findings = make([]Finding, len(report.Findings))
+		for i, finding := range report.Findings {
+			if finding.Folder != "" {
+				finding.Folder = f.Folder(finding.Folder)
+			}
+			out.Findings[i] = finding
+		}
+	}
+	return &out
+}
+
+// Folders はフォルダの行のパスを表記に合わせて変換した複製を返します。(純粋関数)
+func (f FolderFormat) Folders(folders []FolderCount) []FolderCount {
+	if folders == nil {
+		return nil
+	}
+	out := make([]FolderCount, len(folders))
+	for i, r := range folders {
+		r.root = r.isRoot()
+		r.Path = f.Folder(r.Path)
+		out[i] = r
+	}
+	return out
+}
+
+// Paths は集計キーの一覧を表記に合わせて変換した複製を返します。(純粋関数)
+func (f FolderFormat) Paths(keys []string) []string {
+	if keys == nil {
+		return nil
+	}
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = f.Folder(k)
+	}
+	return out
+}
+
+// pathStyleValue は -path-style フラグです。
+type pathStyleValue struct {
+	style *PathStyle
+}
+
+func (v pathStyleValue) String() string {
+	if v.style == nil {
+		return ""
+	}
+	return string(*v.style)
+}
+
+func (v pathStyleValue) Set(s string) error {
+	style, err := ParsePathStyle(s)
+	if err != nil {
+		return err
+	}
+	*v.style = style
+	return nil
+}
+
+// registerFolderFormatFlags は -path-style と -root-label フラグを登録します。
+func registerFolderFormatFlags(fs *flag.FlagSet, format *FolderFormat) {
+	format.Style = PathStyleWindows
+	fs.StringVar(&format.RootLabel, "root-label", "(Root)", "ルートフォルダの表記 (JSON出力では常に空文字列)")
+	fs.Var(pathStyleValue{&format.Style}, "path-style", "出力するフォルダパスの区切り文字 (windows: \\、posix: /、native: 実行中のOSに合わせる)")
+}
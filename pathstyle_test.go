@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// PathStyle の解析と変換のテスト
+func TestPathStyle(t *testing.T) {
+	native := `a\b\c`
+	if runtime.GOOS != "windows" {
+		native = "a/b/c"
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "省略時はバックスラッシュ", input: "", expected: `a\b\c`},
+		{name: "windows", input: "windows", expected: `a\b\c`},
+		{name: "posix", input: "POSIX", expected: "a/b/c"},
+		{name: "native", input: "native", expected: native},
+		{name: "不明な表記", input: "mac", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, err := ParsePathStyle(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", style)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := style.Folder("a/b/c"); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if got := style.Folder("(Root)"); got != "(Root)" {
+				t.Errorf("expected root unchanged, got %q", got)
+			}
+		})
+	}
+}
+
+// 出力形式ごとの書き出しで表記を変換するテスト
+func TestResultWriterPathStyle(t *testing.T) {
+	report := &Report{
+		Folders:  []FolderCount{{Path: "a/b", Count: 2}},
+		Findings: []Finding{{Severity: SeverityWarn, Rule: RuleThreshold, Folder: "a/b"}},
+	}
+	for style, expected := range map[PathStyle]string{"": "\xEF\xBB\xBFFolder Path,File Count\na\\b,2\n", PathStylePOSIX: "\xEF\xBB\xBFFolder Path,File Count\na/b,2\n"} {
+		w, err := NewResultWriter("csv", WriterOptions{Paths: FolderFormat{Style: style}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		if err := w.Write(out, report); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != expected {
+			t.Errorf("style %q: expected %q, got %q", style, expected, out.String())
+		}
+	}
+	if report.Folders[0].Path != "a/b" || report.Findings[0].Folder != "a/b" {
+		t.Errorf("report must not be modified: %+v", report)
+	}
+}
+
+// ルートフォルダの表記のテスト
+func TestFolderFormatRoot(t *testing.T) {
+	report := &Report{
+		Folders:  []FolderCount{{Path: "(Root)", Count: 3}, {Path: "a/b", Count: 2}},
+		Findings: []Finding{{Severity: SeverityWarn, Rule: RuleThreshold, Folder: "(Root)"}},
+	}
+	tests := []struct {
+		name     string
+		format   string
+		label    string
+		expected []string
+	}{
+		{name: "既定の表記", format: "csv", expected: []string{"(Root)", `a\b`}},
+		{name: "ラベルを指定", format: "csv", label: "/", expected: []string{"/", `a\b`}},
+		{name: "JSONは空文字列", format: "json", label: "/", expected: []string{"", `a\b`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewResultWriter(tt.format, WriterOptions{Paths: FolderFormat{RootLabel: tt.label}, CSV: CSVOptions{Encoding: "utf8"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := new(bytes.Buffer)
+			if err := w.Write(out, report); err != nil {
+				t.Fatal(err)
+			}
+			var folders []string
+			if tt.format == "json" {
+				var got Report
+				if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range got.Folders {
+					folders = append(folders, r.Path)
+				}
+			} else {
+				records, err := csv.NewReader(out).ReadAll()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range records[1:] {
+					folders = append(folders, r[0])
+				}
+			}
+			if !reflect.DeepEqual(folders, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, folders)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ZIPの拡張フィールドのうち、Unixの所有者を記録するもののヘッダIDです。
+const (
+	extraPKWAREUnix  = 0x000d
+	extraInfoZipUnix = 0x5855
+	extraUnixOwner   = 0x7875
+)
+
+// UnixOwner はエントリの所有者のユーザーIDとグループIDです。
+type UnixOwner struct {
+	UID uint32
+	GID uint32
+}
+
+// String は "UID:GID" の形式で返します。
+func (o *UnixOwner) String() string {
+	if o == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", o.UID, o.GID)
+}
+
+// parseUnixOwner は拡張フィールドから所有者を読み取ります。記録されていない場合は nil を返します。(純粋関数)
+// Info-ZIP の新しい形式 (0x7875) を優先し、無い場合は 0x5855、0x000d の16ビットの値を使います。
+func parseUnixOwner(extra []byte) *UnixOwner {
+	var legacy *UnixOwner
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		data := extra[4 : 4+size]
+		extra = extra[4+size:]
+		switch id {
+		case extraUnixOwner:
+			if owner, ok := parseUnixOwnerV1(data); ok {
+				return owner
+			}
+		case extraInfoZipUnix, extraPKWAREUnix:
+			// 先頭の8バイトはアクセス日時と更新日時
+			if legacy == nil && len(data) >= 12 {
+				legacy = &UnixOwner{
+					UID: uint32(binary.LittleEndian.Uint16(data[8:])),
+					GID: uint32(binary.LittleEndian.Uint16(data[10:])),
+				}
+			}
+		}
+	}
+	return legacy
+}
+
+// parseUnixOwnerV1 は 0x7875 の拡張フィールドの内容 (版、UIDの長さ、UID、GIDの長さ、GID) を読み取ります。
+func parseUnixOwnerV1(data []byte) (*UnixOwner, bool) {
+	if len(data) < 2 || data[0] != 1 {
+		return nil, false
+	}
+	readID := func(b []byte) (uint32, []byte, bool) {
+		if len(b) < 1 || len(b) < 1+int(b[0]) || b[0] > 8 {
+			return 0, nil, false
+		}
+		n := int(b[0])
+		var v uint64
+		for i := n; i > 0; i-- {
+			v = v<<8 | uint64(b[i])
+		}
+		if v > 1<<32-1 {
+			return 0, nil, false
+		}
+		return uint32(v), b[1+n:], true
+	}
+	uid, rest, ok := readID(data[1:])
+	if !ok {
+		return nil, false
+	}
+	gid, _, ok := readID(rest)
+	if !ok {
+		return nil, false
+	}
+	return &UnixOwner{UID: uid, GID: gid}, true
+}
+
+// RiskyPermission は展開先のLinuxサーバーで問題になるパーミッションのエントリです。
+type RiskyPermission struct {
+	Name string
+	// Folder は集計結果と同じ集計キーのフォルダパスです。ディレクトリのエントリは親フォルダです。
+	Folder string
+	Mode   fs.FileMode
+	Owner  *UnixOwner
+	// Problems は "world-writable"、"setuid"、"setgid" のうち該当するものです。
+	Problems []string
+}
+
+// FolderPermissions はフォルダごとの問題のあるパーミッションのエントリの数です。
+type FolderPermissions struct {
+	Folder        string
+	WorldWritable int
+	Setuid        int
+	Setgid        int
+}
+
+// permissionProblems はパーミッションの問題を返します。(純粋関数)
+// シンボリックリンクのパーミッションは使われないため対象にしません。
+func permissionProblems(mode fs.FileMode) []string {
+	if mode&fs.ModeSymlink != 0 {
+		return nil
+	}
+	var problems []string
+	if mode.Perm()&0o002 != 0 {
+		problems = append(problems, "world-writable")
+	}
+	if mode&fs.ModeSetuid != 0 {
+		problems = append(problems, "setuid")
+	}
+	if mode&fs.ModeSetgid != 0 {
+		problems = append(problems, "setgid")
+	}
+	return problems
+}
+
+// ComputePermissions は誰でも書き込めるエントリと setuid、setgid のエントリをフォルダごとに数えます。(純粋関数)
+// ファイルに加え、ディレクトリのエントリも対象にします。Unixのパーミッションが記録されていないエントリは対象外です。
+// フォルダは問題のあるエントリの合計の降順、同じ場合はパスの昇順、エントリは名前の昇順でソートされます。
+func ComputePermissions(entries []FileEntry, opts AggregateOptions) ([]FolderPermissions, []RiskyPermission) {
+	var risky []RiskyPermission
+	counts := make(map[string]*FolderPermissions)
+	for _, e := range entries {
+		if !opts.Filter.Match(e) {
+			continue
+		}
+		problems := permissionProblems(e.Mode)
+		if len(problems) == 0 {
+			continue
+		}
+		folder := FolderKey(strings.TrimSuffix(e.Name, "/"), opts)
+		risky = append(risky, RiskyPermission{Name: e.Name, Folder: folder, Mode: e.Mode, Owner: e.Owner, Problems: problems})
+		fp, ok := counts[folder]
+		if !ok {
+			fp = &FolderPermissions{Folder: folder}
+			counts[folder] = fp
+		}
+		for _, p := range problems {
+			switch p {
+			case "world-writable":
+				fp.WorldWritable++
+			case "setuid":
+				fp.Setuid++
+			case "setgid":
+				fp.Setgid++
+			}
+		}
+	}
+
+	folders := make([]FolderPermissions, 0, len(counts))
+	for _, fp := range counts {
+		folders = append(folders, *fp)
+	}
+	total := func(fp FolderPermissions) int { return fp.WorldWritable + fp.Setuid + fp.Setgid }
+	sort.Slice(folders, func(i, j int) bool {
+		if ti, tj := total(folders[i]), total(folders[j]); ti != tj {
+			return ti > tj
+		}
+		return folders[i].Folder < folders[j].Folder
+	})
+	sort.Slice(risky, func(i, j int) bool { return risky[i].Name < risky[j].Name })
+	return folders, risky
+}
+
+// WritePermissionsText はフォルダごとの件数と、list が true の場合はエントリの一覧をプレーンテキストで出力します。
+func WritePermissionsText(w io.Writer, folders []FolderPermissions, risky []RiskyPermission, list bool, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-50s | %14s | %6s | %6s\n", lang.T("Folder Path"), lang.T("World-Writable"), "Setuid", "Setgid")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 86))
+	for _, f := range folders {
+		if _, err := fmt.Fprintf(w, "%-50s | %14d | %6d | %6d\n", f.Folder, f.WorldWritable, f.Setuid, f.Setgid); err != nil {
+			return err
+		}
+	}
+	if !list || len(risky) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n%s:\n", lang.T("Entries")); err != nil {
+		return err
+	}
+	for _, r := range risky {
+		owner := r.Owner.String()
+		if owner == "" {
+			owner = "-"
+		}
+		if _, err := fmt.Fprintf(w, "  %s %-11s %s (%s)\n", r.Mode, owner, r.Name, strings.Join(r.Problems, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePermissionsCSV は問題のあるエントリごとの一覧をCSV形式で出力します。
+// UIDとGIDは記録されていない場合は空です。
+func WritePermissionsCSV(w io.Writer, risky []RiskyPermission, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Name", "Mode", "UID", "GID", "Problems"}); err != nil {
+			return err
+		}
+		for _, r := range risky {
+			uid, gid := "", ""
+			if r.Owner != nil {
+				uid, gid = strconv.FormatUint(uint64(r.Owner.UID), 10), strconv.FormatUint(uint64(r.Owner.GID), 10)
+			}
+			if err := writer.Write([]string{r.Folder, r.Name, r.Mode.String(), uid, gid, strings.Join(r.Problems, " ")}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runPerms は perms コマンドを実行します。
+func runPerms(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "perms", "perms -zip <path> [-list] [-format text|csv]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	format := fs.String("format", "text", "出力形式 (text: フォルダごとの件数, csv: エントリごとの一覧)")
+	list := fs.Bool("list", false, "text 形式でエントリごとのパーミッションと所有者も出力する")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	if *format != "text" && *format != "csv" {
+		return usageError(fs, "unknown format: %s", *format)
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	folders, risky := ComputePermissions(entries, opts)
+	for i := range folders {
+		folders[i].Folder = cfg.Paths.Folder(folders[i].Folder)
+	}
+	for i := range risky {
+		risky[i].Folder = cfg.Paths.Folder(risky[i].Folder)
+	}
+	if *format == "csv" {
+		return WritePermissionsCSV(env.Stdout, risky, csvOpts)
+	}
+	return WritePermissionsText(env.Stdout, folders, risky, *list, env.lang)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+// parseUnixOwner のテスト
+func TestParseUnixOwner(t *testing.T) {
+	tests := []struct {
+		name     string
+		extra    []byte
+		expected *UnixOwner
+	}{
+		{name: "記録なし", extra: nil, expected: nil},
+		{
+			name:     "0x7875 (4バイトのID)",
+			extra:    []byte{0x75, 0x78, 11, 0, 1, 4, 0xe8, 0x03, 0, 0, 4, 0xe9, 0x03, 0, 0},
+			expected: &UnixOwner{UID: 1000, GID: 1001},
+		},
+		{
+			name:     "0x5855 (16ビットのID)",
+			extra:    []byte{0x55, 0x58, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xf4, 0x01, 0x64, 0},
+			expected: &UnixOwner{UID: 500, GID: 100},
+		},
+		{
+			name: "0x7875 を優先",
+			extra: []byte{
+				0x55, 0x58, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xf4, 0x01, 0x64, 0,
+				0x75, 0x78, 5, 0, 1, 1, 7, 1, 8,
+			},
+			expected: &UnixOwner{UID: 7, GID: 8},
+		},
+		{name: "長さが足りない", extra: []byte{0x75, 0x78, 11, 0, 1, 4}, expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUnixOwner(tt.extra); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ComputePermissions のテスト
+func TestComputePermissions(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "bin/", IsDir: true, Mode: fs.ModeDir | 0o777},
+		{Name: "bin/tool", Mode: fs.ModeSetuid | fs.ModeSetgid | 0o755, Owner: &UnixOwner{}},
+		{Name: "bin/link", Mode: fs.ModeSymlink | 0o777},
+		{Name: "docs/open.txt", Mode: 0o666},
+		{Name: "docs/plain.txt", Mode: 0o644},
+		{Name: "docs/windows.txt"},
+	}
+	folders, risky := ComputePermissions(entries, AggregateOptions{})
+	wantFolders := []FolderPermissions{
+		{Folder: "bin", Setuid: 1, Setgid: 1},
+		{Folder: "(Root)", WorldWritable: 1},
+		{Folder: "docs", WorldWritable: 1},
+	}
+	if !reflect.DeepEqual(folders, wantFolders) {
+		t.Errorf("expected %+v, got %+v", wantFolders, folders)
+	}
+	if len(risky) != 3 || risky[0].Name != "bin/" || !reflect.DeepEqual(risky[1].Problems, []string{"setuid", "setgid"}) {
+		t.Errorf("unexpected entries: %+v", risky)
+	}
+}
+
+// WritePermissionsCSV のテスト (文字コードと区切り文字)
+func TestWritePermissionsCSV(t *testing.T) {
+	risky := []RiskyPermission{
+		{Name: "bin/run.sh", Folder: "bin", Mode: fs.ModeSetuid | 0o777, Owner: &UnixOwner{UID: 1000, GID: 100}, Problems: []string{"world-writable", "setuid"}},
+		{Name: "tmp/", Folder: "(Root)", Mode: fs.ModeDir | 0o777, Problems: []string{"world-writable"}},
+	}
+	buf := new(bytes.Buffer)
+	if err := WritePermissionsCSV(buf, risky, CSVOptions{Encoding: "utf8", Delimiter: "tab"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Folder Path\tName\tMode\tUID\tGID\tProblems\n" +
+		"bin\tbin/run.sh\turwxrwxrwx\t1000\t100\tworld-writable setuid\n" +
+		"(Root)\ttmp/\tdrwxrwxrwx\t\t\tworld-writable\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
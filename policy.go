@@ -0,0 +1,130 @@
+package main
+
+import "fmt"
+
+// 集計結果の条件を満たさなかった場合の終了コードです。
+const (
+	exitOverLimit = 2
+	exitEmpty     = 3
+)
+
+// ResultPolicy は集計結果に応じて終了コードを決める条件です。ゼロ値は常に成功します。
+type ResultPolicy struct {
+	// FailIfOver が正の場合、ファイル数がこの値を超えるフォルダがあれば失敗とします。
+	FailIfOver int
+	// FailIfEmpty が true の場合、集計対象のファイルが1件もなければ失敗とします。
+	FailIfEmpty bool
+}
+
+// PolicyViolation は集計結果が ResultPolicy の条件を満たさなかったことを表すエラーです。
+type PolicyViolation struct {
+	Code   int
+	Reason string
+}
+
+func (e *PolicyViolation) Error() string {
+	return e.Reason
+}
+
+// Findings は条件を満たさなかった項目を ERROR の検出事項として返します。(純粋関数)
+// folders にはしきい値で絞り込む前の、FailIfOver を超えるフォルダが含まれている必要があります。
+func (p ResultPolicy) Findings(folders []FolderCount, totalFiles int) []Finding {
+	var findings []Finding
+	if p.FailIfEmpty && totalFiles == 0 {
+		findings = append(findings, Finding{Severity: SeverityError, Rule: RuleFailIfEmpty, Message: "archive has no files"})
+	}
+	if p.FailIfOver <= 0 {
+		return findings
+	}
+	for _, f := range folders {
+		if f.Count > p.FailIfOver {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Rule:     RuleFailIfOver,
+				Folder:   f.Path,
+				Message:  fmt.Sprintf("%d files exceed %d", f.Count, p.FailIfOver),
+			})
+		}
+	}
+	return findings
+}
+
+// Check は集計結果が条件を満たすか判定し、満たさない場合は *PolicyViolation を返します。(純粋関数)
+// folders にはしきい値で絞り込む前の、FailIfOver を超えるフォルダが含まれている必要があります。
+func (p ResultPolicy) Check(folders []FolderCount, totalFiles int) error {
+	return PolicyError(p.Findings(folders, totalFiles))
+}
+
+// PolicyError は検出事項に終了条件の規則が含まれる場合、対応する *PolicyViolation を返します。(純粋関数)
+// アーカイブが空の場合、上限を超えるフォルダ、仕様を満たさないフォルダ、ERROR の実行ファイル、ERROR の命名規則の違反、
+// 管理ファイルとの不一致の順に優先します。
+func PolicyError(findings []Finding) error {
+	over, expect, flagged, misnamed, mismatched := 0, 0, 0, 0, 0
+	limit, violation, executable, naming, control := "", "", "", "", ""
+	for _, f := range findings {
+		switch f.Rule {
+		case RuleFailIfEmpty:
+			return &PolicyViolation{Code: exitEmpty, Reason: f.Message}
+		case RuleFailIfOver:
+			over++
+			limit = f.Message
+		case RuleExpect:
+			expect++
+			violation = f.Folder + ": " + f.Message
+		case RuleExecutable:
+			if f.Severity == SeverityError {
+				flagged++
+				executable = f.Message
+			}
+		case RuleNaming:
+			if f.Severity == SeverityError {
+				misnamed++
+				naming = f.Folder
+			}
+		case RuleControl:
+			if f.Severity == SeverityError {
+				mismatched++
+				control = f.Message
+				if f.Folder != "" {
+					control = f.Folder + ": " + f.Message
+				}
+			}
+		}
+	}
+	if over > 0 {
+		return &PolicyViolation{
+			Code:   exitOverLimit,
+			Reason: fmt.Sprintf("%d folder(s) over the limit (e.g. %s)", over, limit),
+		}
+	}
+	if expect > 0 {
+		return &PolicyViolation{
+			Code:   exitExpectation,
+			Reason: fmt.Sprintf("%d folder(s) do not meet the expectation spec (e.g. %s)", expect, violation),
+		}
+	}
+	if flagged > 0 {
+		return &PolicyViolation{
+			Code:   exitFlagged,
+			Reason: fmt.Sprintf("%d flagged entries found (e.g. %s)", flagged, executable),
+		}
+	}
+	if misnamed > 0 {
+		return &PolicyViolation{
+			Code:   exitNaming,
+			Reason: fmt.Sprintf("%d folder(s) do not match the naming rules (e.g. %s)", misnamed, naming),
+		}
+	}
+	if mismatched > 0 {
+		return &PolicyViolation{
+			Code:   exitControl,
+			Reason: fmt.Sprintf("%d control file mismatch(es) (e.g. %s)", mismatched, control),
+		}
+	}
+	return nil
+}
+
+// overLimitThreshold は Check に渡すフォルダを集計するためのしきい値を返します。
+func (p ResultPolicy) overLimitThreshold() int {
+	return p.FailIfOver + 1
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// ResultPolicy.Check のテスト
+func TestResultPolicyCheck(t *testing.T) {
+	folders := []FolderCount{{Path: "a", Count: 10}, {Path: "b", Count: 5}}
+
+	tests := []struct {
+		name       string
+		policy     ResultPolicy
+		folders    []FolderCount
+		totalFiles int
+		expected   int
+	}{
+		{name: "条件なし", policy: ResultPolicy{}, folders: folders, totalFiles: 15, expected: 0},
+		{name: "上限を超える", policy: ResultPolicy{FailIfOver: 9}, folders: folders, totalFiles: 15, expected: exitOverLimit},
+		{name: "上限ちょうどは成功", policy: ResultPolicy{FailIfOver: 10}, folders: folders, totalFiles: 15, expected: 0},
+		{name: "空のアーカイブ", policy: ResultPolicy{FailIfOver: 1, FailIfEmpty: true}, totalFiles: 0, expected: exitEmpty},
+		{name: "空でも条件がなければ成功", policy: ResultPolicy{FailIfOver: 1}, totalFiles: 0, expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.folders, tt.totalFiles)
+			code := 0
+			var violation *PolicyViolation
+			if errors.As(err, &violation) {
+				code = violation.Code
+			} else if err != nil {
+				t.Fatalf("unexpected error type: %v", err)
+			}
+			if code != tt.expected {
+				t.Errorf("expected code %d, got %d (%v)", tt.expected, code, err)
+			}
+		})
+	}
+}
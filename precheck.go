@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// アーカイブを開く前の検査で見つかる問題の種類です。errors.Is で判定できます。
+var (
+	ErrArchiveNotFound   = errors.New("archive not found")
+	ErrArchivePermission = errors.New("archive is not readable")
+	ErrArchiveIsDir      = errors.New("archive path is a directory")
+	ErrArchiveEmpty      = errors.New("archive is empty (0 bytes)")
+	ErrNotZip            = errors.New("file is not a zip archive")
+)
+
+// ArchiveOpenError はアーカイブを開く前の検査で見つかった問題と、その対処方法です。
+type ArchiveOpenError struct {
+	Path string
+	Err  error
+	Hint string
+}
+
+func (e *ArchiveOpenError) Error() string {
+	return fmt.Sprintf("%v: %s (%s)", e.Err, e.Path, e.Hint)
+}
+
+func (e *ArchiveOpenError) Unwrap() error {
+	return e.Err
+}
+
+// zipSignatures はZIPファイルの先頭に現れるシグネチャです。
+// 通常のローカルファイルヘッダ、空のアーカイブ、分割アーカイブの先頭の順です。
+var zipSignatures = [][]byte{
+	[]byte("PK\x03\x04"),
+	[]byte("PK\x05\x06"),
+	[]byte("PK\x07\x08"),
+}
+
+// CheckArchiveFile はアーカイブのパスが存在し、読み取り可能な空でないZIPファイルであるか検査します。
+func CheckArchiveFile(archivePath string) error {
+	info, err := os.Stat(archivePath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && filepath.Separator == '\\' && isUNCPath(archivePath):
+		return &ArchiveOpenError{Path: archivePath, Err: ErrArchiveNotFound, Hint: "check that the server and share are reachable and that you are signed in to the share"}
+	case errors.Is(err, fs.ErrNotExist):
+		return &ArchiveOpenError{Path: archivePath, Err: ErrArchiveNotFound, Hint: "check the path and that the network share is reachable"}
+	case errors.Is(err, fs.ErrPermission):
+		return &ArchiveOpenError{Path: archivePath, Err: ErrArchivePermission, Hint: "check read permission on the file and its parent folders"}
+	case err != nil:
+		return fmt.Errorf("failed to stat archive: %w", err)
+	case info.IsDir():
+		return &ArchiveOpenError{Path: archivePath, Err: ErrArchiveIsDir, Hint: "specify the .zip file itself, not a folder"}
+	case info.Size() == 0:
+		return &ArchiveOpenError{Path: archivePath, Err: ErrArchiveEmpty, Hint: "the transfer may be incomplete; copy the file again"}
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return &ArchiveOpenError{Path: archivePath, Err: ErrArchivePermission, Hint: "check read permission on the file, or whether another process has locked it"}
+		}
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 4)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("failed to read archive header: %w", err)
+	}
+	for _, sig := range zipSignatures {
+		if bytes.Equal(head[:n], sig) {
+			return nil
+		}
+	}
+	// 先頭にスタブを付けたZIP (自己解凍形式、起動用のスクリプトを付けた .jar など) は、末尾のセントラルディレクトリから読み込める
+	if _, err := zip.NewReader(f, info.Size()); err == nil {
+		return nil
+	}
+	return &ArchiveOpenError{
+		Path: archivePath,
+		Err:  ErrNotZip,
+		Hint: fmt.Sprintf("file starts with % x; it may be another archive format or corrupted", head[:n]),
+	}
+}
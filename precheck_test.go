@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// CheckArchiveFile のテスト
+func TestCheckArchiveFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	zipData, err := os.ReadFile(writeTestZip(t, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected error
+	}{
+		{name: "正常なZIP", path: writeTestZip(t, "a.txt"), expected: nil},
+		{name: "空のZIP", path: writeTestZip(t), expected: nil},
+		{name: "先頭にスタブを付けたZIP", path: writeFile("sfx.zip", append([]byte("#!/bin/sh\n"), zipData...)), expected: nil},
+		{name: "存在しない", path: filepath.Join(dir, "missing.zip"), expected: ErrArchiveNotFound},
+		{name: "ディレクトリ", path: dir, expected: ErrArchiveIsDir},
+		{name: "0バイト", path: writeFile("empty.zip", nil), expected: ErrArchiveEmpty},
+		{name: "ZIPでない", path: writeFile("rar.zip", []byte("Rar!\x1a\x07")), expected: ErrNotZip},
+		{name: "短すぎる", path: writeFile("short.zip", []byte("PK")), expected: ErrNotZip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckArchiveFile(tt.path)
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+			var openErr *ArchiveOpenError
+			if !errors.As(err, &openErr) || openErr.Hint == "" {
+				t.Errorf("expected ArchiveOpenError with hint, got %v", err)
+			}
+		})
+	}
+}
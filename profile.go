@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configEnv は設定ファイルのパスを指定する環境変数です。
+const configEnv = "OBUZIPCOUNT_CONFIG"
+
+// ConfigFile は設定ファイルの内容です。
+//
+//	{
+//	  "profiles": {
+//	    "vendorA": {"encoding-fallbacks": "cp932", "exclude": "__MACOSX,Thumbs.db", "threshold": 500, "format": "csv"}
+//	  },
+//	  "categories": {"cad": [".dwg", ".dxf"]},
+//	  "update": {"url": "https://api.github.com/repos/<owner>/<repo>/releases/latest"}
+//	}
+type ConfigFile struct {
+	// Profiles は納品元ごとのフラグの既定値の組です。キーはプロファイル名です。
+	Profiles map[string]Profile `json:"profiles"`
+	// Categories は types コマンドで既定の分類に追加する、分類名と拡張子の組です。
+	Categories map[string][]string `json:"categories"`
+	// Update は update コマンドで使うリリースのURLです。
+	Update UpdateConfig `json:"update"`
+}
+
+// Profile はフラグ名 (先頭の "-" を除く) と値の組です。値は文字列、数値、真偽値で指定します。
+type Profile map[string]string
+
+// UnmarshalJSON は値の型によらず、フラグに渡す文字列として読み込みます。
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	*p = make(Profile, len(raw))
+	for name, v := range raw {
+		switch v := v.(type) {
+		case string:
+			(*p)[name] = v
+		case json.Number:
+			(*p)[name] = v.String()
+		case bool:
+			(*p)[name] = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("invalid value for %s: must be a string, number or boolean", name)
+		}
+	}
+	return nil
+}
+
+// defaultConfigPath は -config が省略された場合の設定ファイルのパスを返します。
+// 環境変数 OBUZIPCOUNT_CONFIG、ユーザーの設定ディレクトリの obuzipcount/config.json の順に使います。
+func defaultConfigPath() (string, error) {
+	if p := os.Getenv(configEnv); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config file: %w", err)
+	}
+	return filepath.Join(dir, "obuzipcount", "config.json"), nil
+}
+
+// LoadConfigFile は設定ファイルを読み込みます。
+func LoadConfigFile(configPath string) (*ConfigFile, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var cfg ConfigFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	return &cfg, nil
+}
+
+// registerProfileFlags は全コマンド共通のプロファイル関連のフラグを登録します。
+// プロファイルの適用は parseFlags でフラグの解析後に行います。
+func registerProfileFlags(fs *flag.FlagSet) {
+	fs.String("profile", "", "設定ファイルに定義したプロファイル名。プロファイルの値をフラグの既定値として使う")
+	fs.String("config", "", "プロファイルやファイルの分類を定義した設定ファイルのパス (省略時は $"+configEnv+" または ユーザー設定ディレクトリの obuzipcount/config.json)")
+}
+
+// applyProfile は -profile で指定されたプロファイルの値を、コマンドラインで指定されていないフラグに設定します。
+// コマンドラインの指定はプロファイルより優先します。
+// 1つのプロファイルを複数のコマンドで使えるよう、コマンドに無いフラグは無視します。
+func applyProfile(fs *flag.FlagSet) error {
+	nameFlag, pathFlag := fs.Lookup("profile"), fs.Lookup("config")
+	if nameFlag == nil || nameFlag.Value.String() == "" {
+		return nil
+	}
+	name := nameFlag.Value.String()
+	configPath := pathFlag.Value.String()
+	if configPath == "" {
+		var err error
+		if configPath, err = defaultConfigPath(); err != nil {
+			return err
+		}
+	}
+	cfg, err := LoadConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return usageError(fs, "unknown profile: %s (available: %s)", name, strings.Join(profileNames(cfg), ", "))
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	names := make([]string, 0, len(profile))
+	for n := range profile {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if n == "profile" || n == "config" {
+			return usageError(fs, "profile %s: -%s cannot be set in a profile", name, n)
+		}
+		if explicit[n] || fs.Lookup(n) == nil {
+			continue
+		}
+		if err := fs.Set(n, profile[n]); err != nil {
+			return usageError(fs, "profile %s: invalid value for -%s: %v", name, n, err)
+		}
+	}
+	return nil
+}
+
+// profileNames は設定ファイルに定義されたプロファイル名を昇順で返します。(純粋関数)
+func profileNames(cfg *ConfigFile) []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for n := range cfg.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
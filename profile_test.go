@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// -profile による設定ファイルのプロファイルの適用のテスト
+func TestProfile(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt", "dir1/Thumbs.db", "__MACOSX/dir1/._a.txt", "c.txt").WriteFile(t)
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{"profiles": {
+		"vendorA": {"exclude": "__MACOSX,thumbs.db", "threshold": 2, "format": "csv", "stats": false},
+		"broken": {"threshold": "many"}
+	}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+		unexpected   string
+	}{
+		{name: "プロファイルの値を使う", args: []string{"count", "-zip", zipPath, "-config", configPath, "-profile", "vendorA"}, expectedOut: "dir1,2", unexpected: "(Root)"},
+		{name: "コマンドラインの指定を優先", args: []string{"count", "-zip", zipPath, "-config", configPath, "-profile", "vendorA", "-threshold", "1"}, expectedOut: "(Root),1"},
+		{name: "コマンドに無いフラグは無視", args: []string{"validate", "-zip", zipPath, "-config", configPath, "-profile", "vendorA"}, expectedOut: "Entries: 5"},
+		{name: "未定義のプロファイル", args: []string{"count", "-zip", zipPath, "-config", configPath, "-profile", "vendorB"}, expectedCode: 2},
+		{name: "不正な値", args: []string{"count", "-zip", zipPath, "-config", configPath, "-profile", "broken"}, expectedCode: 2},
+		{name: "設定ファイルが無い", args: []string{"count", "-zip", zipPath, "-config", configPath + ".none", "-profile", "vendorA"}, expectedCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			code := runCLI(tt.args, stdout, stderr)
+			if code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+			if tt.unexpected != "" && strings.Contains(stdout.String(), tt.unexpected) {
+				t.Errorf("output should not contain %q, got: %s", tt.unexpected, stdout.String())
+			}
+		})
+	}
+}
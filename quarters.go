@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unknownQuarter は更新日時が記録されていないエントリの四半期の表記です。
+const unknownQuarter = "(Unknown)"
+
+// QuarterCount は最上位フォルダと四半期の組み合わせごとのファイル数です。
+type QuarterCount struct {
+	// Folder は最上位フォルダです。ルート直下のファイルは "(Root)" です。
+	Folder string
+	// Quarter は "2023-Q1" 形式の暦年の四半期です。
+	Quarter string
+	Count   int
+}
+
+// ComputeQuarterCounts はファイルを最上位フォルダと更新日時の四半期の組み合わせで集計します。(純粋関数)
+// 更新日時はZIPに記録されたままの日時で判定します。記録が無いファイルの四半期は "(Unknown)" です。
+// 件数が minCount 未満の組み合わせは除外されます。
+// 結果はフォルダ、四半期の昇順でソートされます。"(Unknown)" は各フォルダの最後です。
+func ComputeQuarterCounts(entries []FileEntry, minCount int) []QuarterCount {
+	type key struct{ folder, quarter string }
+	counts := make(map[key]int)
+	for _, e := range entries {
+		if e.IsDir {
+			continue
+		}
+		name := strings.Trim(e.Name, "/")
+		if name == "" {
+			continue
+		}
+		folder := "(Root)"
+		if top, _, ok := strings.Cut(name, "/"); ok {
+			folder = top
+		}
+		counts[key{folder, quarterOf(e)}]++
+	}
+
+	results := make([]QuarterCount, 0, len(counts))
+	for k, n := range counts {
+		if n < minCount {
+			continue
+		}
+		results = append(results, QuarterCount{Folder: k.folder, Quarter: k.quarter, Count: n})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Folder != b.Folder {
+			return a.Folder < b.Folder
+		}
+		if (a.Quarter == unknownQuarter) != (b.Quarter == unknownQuarter) {
+			return b.Quarter == unknownQuarter
+		}
+		return a.Quarter < b.Quarter
+	})
+	return results
+}
+
+// quarterOf はエントリの更新日時の四半期を返します。(純粋関数)
+func quarterOf(e FileEntry) string {
+	if e.Modified.IsZero() {
+		return unknownQuarter
+	}
+	return fmt.Sprintf("%04d-Q%d", e.Modified.Year(), (int(e.Modified.Month())-1)/3+1)
+}
+
+// WriteQuarterCountsText は四半期ごとのファイル数をプレーンテキストでWriterに出力します。
+func WriteQuarterCountsText(w io.Writer, results []QuarterCount, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-40s | %-9s | %s\n", lang.T("Top-Level Folder"), lang.T("Quarter"), lang.T("File Count"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, r := range results {
+		_, err := fmt.Fprintf(w, "%-40s | %-9s | %d\n", r.Folder, r.Quarter, r.Count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteQuarterCountsCSV は四半期ごとのファイル数をCSV形式でWriterに出力します。
+func WriteQuarterCountsCSV(w io.Writer, results []QuarterCount, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Top-Level Folder", "Quarter", "File Count"}); err != nil {
+			return err
+		}
+		for _, r := range results {
+			if err := writer.Write([]string{r.Folder, r.Quarter, strconv.Itoa(r.Count)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectQuarterCountsWriter は出力形式名に対応する書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectQuarterCountsWriter(format string, opts WriterOptions) (func(io.Writer, []QuarterCount) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, results []QuarterCount) error {
+			return WriteQuarterCountsText(w, results, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, results []QuarterCount) error {
+			return WriteQuarterCountsCSV(w, results, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// runQuarters は quarters コマンドを実行します。
+func runQuarters(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "quarters", "quarters -zip <path> [-threshold N] [-format text|csv]")
+	var rf readerFlags
+	rf.register(fs)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	minCount := fs.Int("threshold", 0, "出力する組み合わせのファイル数の下限")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectQuarterCountsWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	return write(env.Stdout, ComputeQuarterCounts(entries, *minCount))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ComputeQuarterCounts のテスト
+func TestComputeQuarterCounts(t *testing.T) {
+	at := func(y int, m time.Month) time.Time { return time.Date(y, m, 15, 10, 0, 0, 0, time.UTC) }
+	entries := []FileEntry{
+		{Name: "経理/2023/a.pdf", Modified: at(2023, time.March)},
+		{Name: "経理/2023/b.pdf", Modified: at(2023, time.January)},
+		{Name: "経理/2023/c.pdf", Modified: at(2023, time.April)},
+		{Name: "経理/old.pdf"},
+		{Name: "経理/2023/", IsDir: true, Modified: at(2023, time.March)},
+		{Name: "人事/x.doc", Modified: at(2022, time.December)},
+		{Name: "readme.txt", Modified: at(2024, time.July)},
+	}
+
+	result := ComputeQuarterCounts(entries, 0)
+	expected := []QuarterCount{
+		{Folder: "(Root)", Quarter: "2024-Q3", Count: 1},
+		{Folder: "人事", Quarter: "2022-Q4", Count: 1},
+		{Folder: "経理", Quarter: "2023-Q1", Count: 2},
+		{Folder: "経理", Quarter: "2023-Q2", Count: 1},
+		{Folder: "経理", Quarter: "(Unknown)", Count: 1},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	t.Run("下限で絞り込みCSV出力", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteQuarterCountsCSV(out, ComputeQuarterCounts(entries, 2), CSVOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		expected := "\xEF\xBB\xBFTop-Level Folder,Quarter,File Count\n経理,2023-Q1,2\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("Shift_JISのタブ区切り", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		if err := WriteQuarterCountsCSV(out, ComputeQuarterCounts(entries, 2), CSVOptions{Encoding: "shift_jis", Delimiter: "tab"}); err != nil {
+			t.Fatal(err)
+		}
+		expected := "Top-Level Folder\tQuarter\tFile Count\n\x8co\x97\x9d\t2023-Q1\t2\n"
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RawFolderCount は復号前のバイト列をキーにしたフォルダの件数です。
+type RawFolderCount struct {
+	// RawPath は復号前のフォルダパスを EscapeRawName でエスケープしたものです。
+	RawPath string
+	// Path は復号後のフォルダパスの集計キーです。
+	Path  string
+	Count int
+	// Merged は復号後のパスが他の復号前のフォルダと同じになることを表します。
+	Merged bool
+}
+
+// AggregateRawFolders はエントリ名を復号せず、復号前のバイト列のフォルダごとにファイル数を集計します。(純粋関数)
+// 復号後のパスが同じになる複数のフォルダは Merged とします。
+// 結果は復号後のパスの昇順、同じ場合は復号前のパスの昇順でソートされます。
+func AggregateRawFolders(entries []FileEntry, threshold int) []RawFolderCount {
+	folders := make(map[string]*RawFolderCount)
+	for _, f := range entries {
+		if f.IsDir {
+			continue
+		}
+		raw := f.RawName
+		if raw == "" {
+			raw = f.Name
+		}
+		rawDir := path.Dir(raw)
+		fc, ok := folders[rawDir]
+		if !ok {
+			rawPath := "(Root)"
+			if rawDir != "." {
+				rawPath = EscapeRawName(rawDir)
+			}
+			fc = &RawFolderCount{RawPath: rawPath, Path: FolderKey(f.Name, AggregateOptions{})}
+			folders[rawDir] = fc
+		}
+		fc.Count++
+	}
+
+	decoded := make(map[string]int)
+	for _, fc := range folders {
+		decoded[fc.Path]++
+	}
+
+	var results []RawFolderCount
+	for _, fc := range folders {
+		if fc.Count < threshold {
+			continue
+		}
+		fc.Merged = decoded[fc.Path] > 1
+		results = append(results, *fc)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Path == results[j].Path {
+			return results[i].RawPath < results[j].RawPath
+		}
+		return results[i].Path < results[j].Path
+	})
+	return results
+}
+
+// EscapeRawName は印字可能なASCII以外のバイトを "\xNN" 形式にエスケープします。(純粋関数)
+// Shift_JIS の2バイト目に現れるバックスラッシュ (0x5C) もエスケープします。
+func EscapeRawName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c < 0x7F && c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "\\x%02x", c)
+	}
+	return b.String()
+}
+
+// WriteRawFoldersText は復号前のフォルダごとの集計結果をプレーンテキストでWriterに出力します。
+func WriteRawFoldersText(w io.Writer, results []RawFolderCount, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-50s | %-40s | %8s | %s\n", lang.T("Raw Path"), lang.T("Decoded Path"), lang.T("Count"), lang.T("Flag"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 115))
+	for _, r := range results {
+		_, err := fmt.Fprintf(w, "%-50s | %-40s | %8d | %s\n", r.RawPath, r.Path, r.Count, mergedFlag(r))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRawFoldersCSV は復号前のフォルダごとの集計結果をCSV形式でWriterに出力します。
+func WriteRawFoldersCSV(w io.Writer, results []RawFolderCount, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Raw Path", "Decoded Path", "File Count", "Flag"}); err != nil {
+			return err
+		}
+		for _, r := range results {
+			if err := writer.Write([]string{r.RawPath, r.Path, strconv.Itoa(r.Count), mergedFlag(r)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectRawFoldersWriter は出力形式名に対応する書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectRawFoldersWriter(format string, opts WriterOptions) (func(io.Writer, []RawFolderCount) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, results []RawFolderCount) error {
+			return WriteRawFoldersText(w, results, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, results []RawFolderCount) error {
+			return WriteRawFoldersCSV(w, results, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func mergedFlag(r RawFolderCount) string {
+	if r.Merged {
+		return "MERGED"
+	}
+	return ""
+}
+
+// runRawNames は raw-names コマンドを実行します。
+func runRawNames(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "raw-names", "raw-names -zip <path> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	threshold := fs.Int("threshold", 0, "出力するフォルダのファイル数の下限")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var paths FolderFormat
+	registerFolderFormatFlags(fs, &paths)
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectRawFoldersWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	entries, _, err := app.readEntries(*zipPath, false)
+	if err != nil {
+		return err
+	}
+	folders := AggregateRawFolders(entries, *threshold)
+	for i := range folders {
+		folders[i].Path = paths.Folder(folders[i].Path)
+	}
+	return write(env.Stdout, folders)
+}
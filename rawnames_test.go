@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// AggregateRawFolders のテスト (復号で統合されるフォルダの検出)
+func TestAggregateRawFolders(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "表/a.txt", RawName: "\x95\x5c/a.txt"},
+		{Name: "表/b.txt", RawName: "\x95\x5c/b.txt"},
+		{Name: "表/c.txt", RawName: "\xe8\xa1\xa8/c.txt"},
+		{Name: "docs/d.txt", RawName: "docs/d.txt"},
+		{Name: "e.txt"},
+		{Name: "docs/", IsDir: true},
+	}
+	result := AggregateRawFolders(entries, 1)
+	expected := []RawFolderCount{
+		{RawPath: "(Root)", Path: "(Root)", Count: 1},
+		{RawPath: "docs", Path: "docs", Count: 1},
+		{RawPath: `\x95\x5c`, Path: "表", Count: 2, Merged: true},
+		{RawPath: `\xe8\xa1\xa8`, Path: "表", Count: 1, Merged: true},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	if got := AggregateRawFolders(entries, 2); len(got) != 1 || got[0].RawPath != `\x95\x5c` {
+		t.Errorf("unexpected result with threshold: %+v", got)
+	}
+}
+
+// EscapeRawName のテスト
+func TestEscapeRawName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "docs/2023", expected: "docs/2023"},
+		{input: "\x83\x5c", expected: `\x83\x5c`},
+		{input: "a\tb", expected: `a\x09b`},
+	}
+	for _, tt := range tests {
+		if got := EscapeRawName(tt.input); got != tt.expected {
+			t.Errorf("EscapeRawName(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// WriteRawFoldersCSV のテスト
+func TestWriteRawFoldersCSV(t *testing.T) {
+	results := []RawFolderCount{
+		{RawPath: `\x95\x5c`, Path: "表", Count: 2, Merged: true},
+		{RawPath: "docs", Path: "docs", Count: 1},
+	}
+	tests := []struct {
+		name     string
+		opts     CSVOptions
+		expected string
+	}{
+		{
+			name:     "既定はBOM付きUTF-8のカンマ区切り",
+			expected: "\xEF\xBB\xBFRaw Path,Decoded Path,File Count,Flag\n\\x95\\x5c,表,2,MERGED\ndocs,docs,1,\n",
+		},
+		{
+			name:     "Shift_JISのタブ区切り",
+			opts:     CSVOptions{Encoding: "shift_jis", Delimiter: "tab"},
+			expected: "Raw Path\tDecoded Path\tFile Count\tFlag\n\\x95\\x5c\t\x95\x5c\t2\tMERGED\ndocs\tdocs\t1\t\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := WriteRawFoldersCSV(buf, results, tt.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
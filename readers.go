@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	archiveReadersMu sync.RWMutex
+	// archiveReaders は拡張子 (小文字、"." 始まり) ごとに登録された ArchiveReader の作成関数です。
+	archiveReaders = map[string]func() ArchiveReader{}
+)
+
+// RegisterArchiveReader は拡張子に対応する ArchiveReader を登録します。同じ拡張子が登録済みの場合は置き換えます。
+// 登録した拡張子のアーカイブは、組み込みの形式の判定より優先して factory で作成した ArchiveReader で読み込みます。
+// ".tar.gz" のように複数のピリオドを含む拡張子も指定でき、一致するもののうち最も長いものを使います。
+func RegisterArchiveReader(ext string, factory func() ArchiveReader) {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	archiveReadersMu.Lock()
+	defer archiveReadersMu.Unlock()
+	archiveReaders[ext] = factory
+}
+
+// ArchiveReaderExtensions は登録済みの拡張子を昇順で返します。
+func ArchiveReaderExtensions() []string {
+	archiveReadersMu.RLock()
+	defer archiveReadersMu.RUnlock()
+	exts := make([]string, 0, len(archiveReaders))
+	for ext := range archiveReaders {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// registeredArchiveReader はパスの拡張子に対応する、登録済みの ArchiveReader を作成します。
+// URLの場合はクエリ文字列を除いたパスの部分で判定します。
+func registeredArchiveReader(archivePath string) (ArchiveReader, bool) {
+	name := archivePath
+	if isRemotePath(archivePath) {
+		if u, err := url.Parse(archivePath); err == nil {
+			name = u.Path
+		}
+	}
+	name = strings.ToLower(name)
+
+	archiveReadersMu.RLock()
+	defer archiveReadersMu.RUnlock()
+	best := ""
+	for ext := range archiveReaders {
+		if strings.HasSuffix(name, ext) && len(ext) > len(best) {
+			best = ext
+		}
+	}
+	if best == "" {
+		return nil, false
+	}
+	return archiveReaders[best](), true
+}
+
+// PluginReadEntries はプラグインが公開する ReadEntries 関数の型です。
+// プラグインは main パッケージの型を参照できないため、エントリは io/fs の FileInfo で返します。
+// Name() はアーカイブ内の "/" 区切りのフルパスとします。
+type PluginReadEntries = func(path string) ([]fs.FileInfo, error)
+
+// pluginArchiveReader はプラグインの ReadEntries 関数を ArchiveReader として扱うためのアダプタです。
+type pluginArchiveReader struct {
+	read PluginReadEntries
+}
+
+func (p pluginArchiveReader) ReadEntries(archivePath string) ([]FileEntry, error) {
+	infos, err := p.read(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]FileEntry, 0, len(infos))
+	for _, info := range infos {
+		size := uint64(max(info.Size(), 0))
+		entries = append(entries, FileEntry{
+			Name:           info.Name(),
+			RawName:        info.Name(),
+			IsDir:          info.IsDir(),
+			Modified:       info.ModTime(),
+			Size:           size,
+			CompressedSize: size,
+		})
+	}
+	return entries, nil
+}
+
+// LoadReaderPlugin はGoのプラグイン (.so) を読み込み、対応する拡張子の ArchiveReader として登録します。
+// プラグインは次の2つのシンボルを公開する必要があります。
+//
+//	var Extensions = []string{".xyz"}
+//	func ReadEntries(path string) ([]fs.FileInfo, error)
+func LoadReaderPlugin(pluginPath string) error {
+	p, err := plugin.Open(pluginPath)
+	if err != nil {
+		return fmt.Errorf("failed to open reader plugin: %w", err)
+	}
+	extSym, err := p.Lookup("Extensions")
+	if err != nil {
+		return fmt.Errorf("invalid reader plugin %s: %w", pluginPath, err)
+	}
+	exts, ok := extSym.(*[]string)
+	if !ok || len(*exts) == 0 {
+		return fmt.Errorf("invalid reader plugin %s: Extensions must be a non-empty []string", pluginPath)
+	}
+	readSym, err := p.Lookup("ReadEntries")
+	if err != nil {
+		return fmt.Errorf("invalid reader plugin %s: %w", pluginPath, err)
+	}
+	read, ok := readSym.(PluginReadEntries)
+	if !ok {
+		return fmt.Errorf("invalid reader plugin %s: ReadEntries must be func(string) ([]fs.FileInfo, error)", pluginPath)
+	}
+	for _, ext := range *exts {
+		RegisterArchiveReader(ext, func() ArchiveReader { return pluginArchiveReader{read: read} })
+	}
+	return nil
+}
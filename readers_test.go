@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeArchiveReader はパスを名前にした1件のエントリを返す ArchiveReader です。
+type fakeArchiveReader struct{ kind string }
+
+func (r fakeArchiveReader) ReadEntries(p string) ([]FileEntry, error) {
+	return []FileEntry{{Name: r.kind + "/" + filepath.Base(p)}}, nil
+}
+
+// RegisterArchiveReader のテスト
+func TestRegisterArchiveReader(t *testing.T) {
+	RegisterArchiveReader("XYZ", func() ArchiveReader { return fakeArchiveReader{kind: "xyz"} })
+	RegisterArchiveReader(".pkg.xyz", func() ArchiveReader { return fakeArchiveReader{kind: "pkg"} })
+	t.Cleanup(func() {
+		archiveReadersMu.Lock()
+		delete(archiveReaders, ".xyz")
+		delete(archiveReaders, ".pkg.xyz")
+		archiveReadersMu.Unlock()
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "大文字の拡張子", path: "/data/A.XYZ", expected: "xyz/A.XYZ"},
+		{name: "最も長い拡張子を優先", path: "/data/b.pkg.xyz", expected: "pkg/b.pkg.xyz"},
+		{name: "URLはクエリを除いて判定", path: "https://example.com/c.xyz?sig=abc", expected: "xyz/c.xyz?sig=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := (AutoArchiveReader{}).ReadEntries(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Name != tt.expected {
+				t.Errorf("expected %s, got %+v", tt.expected, entries)
+			}
+		})
+	}
+
+	if _, ok := registeredArchiveReader("/data/d.zip"); ok {
+		t.Error("expected no reader for .zip")
+	}
+	if got := ArchiveReaderExtensions(); !reflect.DeepEqual(got, []string{".pkg.xyz", ".xyz"}) {
+		t.Errorf("unexpected extensions: %v", got)
+	}
+}
+
+// pluginArchiveReader の変換のテスト
+func TestPluginArchiveReader(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2023, 6, 15, 9, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "a.txt"), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	reader := pluginArchiveReader{read: func(string) ([]fs.FileInfo, error) {
+		info, err := os.Stat(filepath.Join(dir, "a.txt"))
+		return []fs.FileInfo{info}, err
+	}}
+	entries, err := reader.ReadEntries("archive.xyz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []FileEntry{{Name: "a.txt", RawName: "a.txt", Modified: entries[0].Modified, Size: 5, CompressedSize: 5}}
+	if !reflect.DeepEqual(entries, expected) || !entries[0].Modified.Equal(modified) {
+		t.Errorf("expected %+v, got %+v", expected, entries)
+	}
+
+	if err := LoadReaderPlugin(filepath.Join(dir, "missing.so")); err == nil {
+		t.Error("expected error for missing plugin")
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// リモートのアーカイブを範囲指定で読み込む際の既定値です。
+const (
+	defaultRemoteParallelism = 8
+	defaultRemoteChunkSize   = 1 << 20
+	// zipTailSize は末尾のセントラルディレクトリ終端レコードを探す範囲 (レコード本体とコメントの最大長) です。
+	zipTailSize = 22 + 65535
+)
+
+// RemoteOptions はHTTP/HTTPSのアーカイブを範囲指定のリクエストで読み込む方法です。
+// S3などのオブジェクトストレージは署名付きURLで指定します。
+type RemoteOptions struct {
+	// Parallelism は同時に送るリクエストの最大数です。0以下の場合は既定値を使います。
+	Parallelism int
+	// ChunkSize は1回のリクエストで取得するバイト数です。0以下の場合は既定値を使います。
+	ChunkSize int64
+	// Client はリクエストに使うクライアントです。nil の場合は http.DefaultClient を使います。
+	Client *http.Client
+}
+
+// isRemotePath はパスがHTTP/HTTPSのURLかを判定します。(純粋関数)
+func isRemotePath(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://")
+}
+
+// rangeReaderAt はHTTPの範囲指定のリクエストで読み込む io.ReaderAt です。
+// 取得した範囲はチャンク単位でメモリに保持し、同じ範囲を再度取得しません。
+type rangeReaderAt struct {
+	url         string
+	size        int64
+	client      *http.Client
+	parallelism int
+	chunkSize   int64
+	// tail はアーカイブを開く際に取得した末尾の内容で、tailOffset はその位置です。
+	tail       []byte
+	tailOffset int64
+
+	mu     sync.Mutex
+	chunks map[int64][]byte
+}
+
+// openRangeReader はURLの末尾を取得してサイズを調べ、範囲指定で読み込む io.ReaderAt を作成します。
+// 署名付きURLは GET にしか使えないことが多いため、HEAD は使いません。
+func openRangeReader(url string, opts RemoteOptions) (*rangeReaderAt, error) {
+	r := &rangeReaderAt{
+		url:         url,
+		client:      opts.Client,
+		parallelism: opts.Parallelism,
+		chunkSize:   opts.ChunkSize,
+		chunks:      make(map[int64][]byte),
+	}
+	if r.client == nil {
+		r.client = http.DefaultClient
+	}
+	if r.parallelism <= 0 {
+		r.parallelism = defaultRemoteParallelism
+	}
+	if r.chunkSize <= 0 {
+		r.chunkSize = defaultRemoteChunkSize
+	}
+
+	resp, err := r.get(fmt.Sprintf("bytes=-%d", zipTailSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open remote archive: %w", err)
+	}
+	defer resp.Body.Close()
+	var start, end int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &r.size); err != nil {
+		return nil, fmt.Errorf("failed to open remote archive: invalid content range %q", resp.Header.Get("Content-Range"))
+	}
+	r.tail = make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, r.tail); err != nil {
+		return nil, fmt.Errorf("failed to open remote archive: %w", err)
+	}
+	r.tailOffset = start
+	return r, nil
+}
+
+// get は範囲指定のリクエストを送ります。サーバが範囲指定に応じなかった場合はエラーを返します。
+func (r *rangeReaderAt) get(byteRange string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", byteRange)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil, errors.New("server does not support range requests")
+		}
+		return nil, fmt.Errorf("unexpected status %s for range %s", resp.Status, byteRange)
+	}
+	return resp, nil
+}
+
+// ReadAt は io.ReaderAt の実装です。必要なチャンクを並行して取得してから読み込みます。
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if off+n > r.size {
+		n = r.size - off
+	}
+	if off >= r.tailOffset {
+		copy(p[:n], r.tail[off-r.tailOffset:])
+	} else {
+		if err := r.prefetch(off, n); err != nil {
+			return 0, err
+		}
+		r.mu.Lock()
+		for read := int64(0); read < n; {
+			pos := off + read
+			read += int64(copy(p[read:n], r.chunks[pos/r.chunkSize][pos%r.chunkSize:]))
+		}
+		r.mu.Unlock()
+	}
+	if n < int64(len(p)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// prefetch は off から n バイトを含むチャンクのうち、未取得のものを最大 parallelism 件ずつ並行して取得します。
+func (r *rangeReaderAt) prefetch(off, n int64) error {
+	var missing []int64
+	r.mu.Lock()
+	for i := off / r.chunkSize; i*r.chunkSize < off+n; i++ {
+		if _, ok := r.chunks[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	r.mu.Unlock()
+
+	sem := make(chan struct{}, r.parallelism)
+	errs := make([]error, len(missing))
+	var wg sync.WaitGroup
+	for j, index := range missing {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			data, err := r.fetchChunk(index)
+			if err != nil {
+				errs[j] = err
+				return
+			}
+			r.mu.Lock()
+			r.chunks[index] = data
+			r.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// fetchChunk は1つのチャンクを範囲指定のリクエストで取得します。
+func (r *rangeReaderAt) fetchChunk(index int64) ([]byte, error) {
+	start := index * r.chunkSize
+	end := min(start+r.chunkSize, r.size) - 1
+	resp, err := r.get(fmt.Sprintf("bytes=%d-%d", start, end))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote archive: %w", err)
+	}
+	defer resp.Body.Close()
+	data := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("failed to read remote archive: %w", err)
+	}
+	return data, nil
+}
+
+// prefetchCentralDirectory は末尾の終端レコードからセントラルディレクトリの位置を求め、全体を並行して取得します。
+// archive/zip はセントラルディレクトリを小さな単位で順に読むため、事前に取得しておくことで往復の待ち時間を減らします。
+func prefetchCentralDirectory(r *rangeReaderAt) error {
+	offset, size, err := centralDirectoryRange(r.tail, r)
+	if err != nil {
+		return err
+	}
+	// 末尾の取得済みの範囲は除く
+	if end := min(offset+size, r.tailOffset); end > offset {
+		return r.prefetch(offset, end-offset)
+	}
+	return nil
+}
+
+// centralDirectoryRange はアーカイブの末尾の内容からセントラルディレクトリの位置とサイズを求めます。
+// ZIP64の場合は r から ZIP64 の終端レコードを読み込みます。
+func centralDirectoryRange(tail []byte, r io.ReaderAt) (int64, int64, error) {
+	i := bytes.LastIndex(tail, []byte("PK\x05\x06"))
+	if i < 0 || len(tail)-i < 22 {
+		return 0, 0, errors.New("failed to open remote archive: end of central directory not found")
+	}
+	eocd := tail[i:]
+	size := int64(binary.LittleEndian.Uint32(eocd[12:]))
+	offset := int64(binary.LittleEndian.Uint32(eocd[16:]))
+	if offset != 0xFFFFFFFF && size != 0xFFFFFFFF {
+		return offset, size, nil
+	}
+
+	// ZIP64: 終端レコードの直前のロケータが ZIP64 の終端レコードの位置を示す
+	if i < 20 || !bytes.Equal(tail[i-20:i-16], []byte("PK\x06\x07")) {
+		return 0, 0, errors.New("failed to open remote archive: zip64 locator not found")
+	}
+	rec := make([]byte, 56)
+	if _, err := r.ReadAt(rec, int64(binary.LittleEndian.Uint64(tail[i-12:]))); err != nil {
+		return 0, 0, err
+	}
+	if !bytes.Equal(rec[:4], []byte("PK\x06\x06")) {
+		return 0, 0, errors.New("failed to open remote archive: invalid zip64 end of central directory")
+	}
+	return int64(binary.LittleEndian.Uint64(rec[48:])), int64(binary.LittleEndian.Uint64(rec[40:])), nil
+}
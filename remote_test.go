@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// ZipArchiveReader のURLからの範囲読み込みのテスト
+func TestZipArchiveReaderRemote(t *testing.T) {
+	b := obuziptest.New()
+	// セントラルディレクトリが最初に取得する末尾の範囲 (約64KB) に収まらない件数にする
+	for i := 0; i < 2000; i++ {
+		b.Files(fmt.Sprintf("dir%d/file%04d.txt", i%3, i))
+	}
+	data, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests, active, maxActive atomic.Int32
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n := active.Add(1)
+		defer active.Add(-1)
+		mu.Lock()
+		if n > maxActive.Load() {
+			maxActive.Store(n)
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		http.ServeContent(w, r, "a.zip", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	reader := ZipArchiveReader{Remote: RemoteOptions{Parallelism: 4, ChunkSize: 512}}
+	entries, err := reader.ReadEntries(server.URL + "/a.zip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2000 {
+		t.Errorf("expected 2000 entries, got %d", len(entries))
+	}
+	if m := maxActive.Load(); m < 2 || m > 4 {
+		t.Errorf("expected 2 to 4 concurrent requests, got %d", m)
+	}
+
+	t.Run("範囲指定に対応しないサーバ", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(data)
+		}))
+		defer plain.Close()
+		if _, err := reader.ReadEntries(plain.URL + "/a.zip"); err == nil {
+			t.Error("expected error for server without range support")
+		}
+	})
+}
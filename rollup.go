@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// RollupRule はパターンに一致するフォルダを集約先フォルダにまとめるルールです。
+type RollupRule struct {
+	Pattern string
+	Target  string
+}
+
+// LoadRollupRules はロールアップ定義ファイルを読み込みます。
+func LoadRollupRules(rulesPath string) ([]RollupRule, error) {
+	f, err := os.Open(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rollup rules: %w", err)
+	}
+	defer f.Close()
+	return ParseRollupRules(f)
+}
+
+// ParseRollupRules は "scans/2023*/** -> scans/2023" 形式の行を解析します。
+// 空行と "#" で始まる行は無視します。
+func ParseRollupRules(r io.Reader) ([]RollupRule, error) {
+	var rules []RollupRule
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, target, found := strings.Cut(line, "->")
+		if !found {
+			return nil, fmt.Errorf("rollup rules line %d: missing '->'", lineNo)
+		}
+		rule := RollupRule{
+			Pattern: strings.Trim(strings.TrimSpace(pattern), "/"),
+			Target:  strings.Trim(strings.TrimSpace(target), "/"),
+		}
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("rollup rules line %d: empty pattern", lineNo)
+		}
+		if err := validatePathPattern(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("rollup rules line %d: %w", lineNo, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ApplyRollup は最初に一致したルールの集約先を返します。一致しない場合はそのまま返します。(純粋関数)
+func ApplyRollup(rules []RollupRule, dir string) string {
+	for _, rule := range rules {
+		if MatchPathPattern(rule.Pattern, dir) {
+			return rule.Target
+		}
+	}
+	return dir
+}
+
+// MatchPathPattern はスラッシュ区切りのパスがパターンに一致するか判定します。(純粋関数)
+// 各階層は path.Match の構文で比較し、"**" は0個以上の階層に一致します。
+func MatchPathPattern(pattern, p string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	if len(pat) == 0 {
+		return len(segs) == 0
+	}
+	if pat[0] == "**" {
+		for i := 0; i <= len(segs); i++ {
+			if matchSegments(pat[1:], segs[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segs) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pat[0], segs[0]); !ok {
+		return false
+	}
+	return matchSegments(pat[1:], segs[1:])
+}
+
+// validatePathPattern はパターンの各階層が path.Match の構文として正しいか検証します。
+func validatePathPattern(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
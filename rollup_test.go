@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// MatchPathPattern のテスト
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"scans/2023*/**", "scans/20230101", true},
+		{"scans/2023*/**", "scans/20230101/a/b", true},
+		{"scans/2023*/**", "scans/20220101", false},
+		{"scans/2023*/**", "scans", false},
+		{"**/tmp", "a/b/tmp", true},
+		{"**/tmp", "tmp", true},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := MatchPathPattern(tt.pattern, tt.path); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ParseRollupRules のテスト
+func TestParseRollupRules(t *testing.T) {
+	input := "# comment\n\nscans/2023*/** -> scans/2023\n/docs/** -> docs/\n"
+	rules, err := ParseRollupRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []RollupRule{
+		{Pattern: "scans/2023*/**", Target: "scans/2023"},
+		{Pattern: "docs/**", Target: "docs"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected %v, got %v", expected, rules)
+	}
+
+	for _, bad := range []string{"scans/2023", "-> target", "scans/[ -> x"} {
+		if _, err := ParseRollupRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// Aggregate のロールアップ適用のテスト
+func TestAggregateRollup(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "scans/20230101/a.tif"},
+		{Name: "scans/20230102/sub/b.tif"},
+		{Name: "scans/20240101/c.tif"},
+	}
+	rules := []RollupRule{{Pattern: "scans/2023*/**", Target: "scans/2023"}}
+	result, _ := Aggregate(entries, AggregateOptions{Threshold: 1, Rollup: rules})
+	expected := []FolderCount{
+		{Path: "scans/2023", Count: 2},
+		{Path: "scans/20240101", Count: 1},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3PresignExpiry はS3のオブジェクトを読み込むための署名付きURLの有効期間です。
+const s3PresignExpiry = time.Hour
+
+// S3Options はS3のプレフィックスを受け入れフォルダとして監視する際の接続先と認証情報です。
+// 一覧の取得とオブジェクトの読み込みには、どちらも署名付きURL (AWS Signature Version 4) を使います。
+type S3Options struct {
+	Region string
+	// Endpoint はS3互換のストレージのURLです。指定した場合はパス形式 (<Endpoint>/<bucket>/<key>) でアクセスします。
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Client は一覧の取得に使うクライアントです。nil の場合は http.DefaultClient を使います。
+	Client *http.Client
+}
+
+// S3OptionsFromEnv はAWSの標準の環境変数からS3の接続先と認証情報を読み込みます。(純粋関数)
+// 認証情報が無い場合は署名せずにアクセスするため、公開されたバケットだけを読み込めます。
+func S3OptionsFromEnv(getenv func(string) string) S3Options {
+	opts := S3Options{
+		Region:          getenv("AWS_REGION"),
+		Endpoint:        getenv("AWS_ENDPOINT_URL_S3"),
+		AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    getenv("AWS_SESSION_TOKEN"),
+	}
+	if opts.Region == "" {
+		opts.Region = getenv("AWS_DEFAULT_REGION")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = getenv("AWS_ENDPOINT_URL")
+	}
+	return opts
+}
+
+// isS3Path はパスが s3:// で始まるS3のプレフィックスかを判定します。(純粋関数)
+func isS3Path(p string) bool {
+	return strings.HasPrefix(p, "s3://")
+}
+
+// parseS3Path は s3://<bucket>/<prefix> をバケットとプレフィックスに分けます。(純粋関数)
+// プレフィックスはフォルダとして扱うため、末尾に "/" を補います。
+func parseS3Path(p string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(p, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid s3 path: %s", p)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid s3 path: %s", p)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return bucket, prefix, nil
+}
+
+// objectURL はバケットとキーから署名前のURLを返します。キーが空の場合はバケットのURLを返します。(純粋関数)
+func (o S3Options) objectURL(bucket, key string) (*url.URL, error) {
+	if o.Endpoint == "" {
+		return &url.URL{Scheme: "https", Host: bucket + ".s3." + o.Region + ".amazonaws.com", Path: "/" + key}, nil
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", o.Endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	return u, nil
+}
+
+// presign は u のクエリ文字列に有効期間 expires の署名を付けたURLを返します。(純粋関数)
+// 認証情報が無い場合は署名しません。署名の対象のヘッダは host だけで、本文は署名しません。
+func (o S3Options) presign(method string, u *url.URL, now time.Time, expires time.Duration) string {
+	q := u.Query()
+	base := u.Scheme + "://" + u.Host + s3Escape(u.Path, true)
+	if o.AccessKeyID == "" {
+		if len(q) == 0 {
+			return base
+		}
+		return base + "?" + s3CanonicalQuery(q)
+	}
+
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + o.Region + "/s3/aws4_request"
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", o.AccessKeyID+"/"+scope)
+	q.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if o.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", o.SessionToken)
+	}
+	query := s3CanonicalQuery(q)
+	canonicalRequest := strings.Join([]string{method, s3Escape(u.Path, true), query, "host:" + u.Host, "", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + o.SecretAccessKey)
+	for _, part := range []string{date, o.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return base + "?" + query + "&X-Amz-Signature=" + hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3CanonicalQuery は署名の仕様に従い、キーの昇順に並べてエンコードしたクエリ文字列を返します。(純粋関数)
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape は英数字と "-_.~" 以外をパーセントエンコードします。(純粋関数)
+// keepSlash が true の場合は、パスの区切りの "/" をエンコードしません。
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// listBucketResult は ListObjectsV2 の応答のうち、使う部分です。
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// s3Intake はS3のプレフィックス直下のZIPファイルを受け入れフォルダとして一覧します。
+type s3Intake struct {
+	opts   S3Options
+	bucket string
+	prefix string
+}
+
+func newS3Intake(dir string, opts S3Options) (s3Intake, error) {
+	bucket, prefix, err := parseS3Path(dir)
+	if err != nil {
+		return s3Intake{}, err
+	}
+	if _, err := opts.objectURL(bucket, ""); err != nil {
+		return s3Intake{}, err
+	}
+	return s3Intake{opts: opts, bucket: bucket, prefix: prefix}, nil
+}
+
+// List は intakeLister の実装です。アーカイブの名前は s3://<bucket>/<key> です。
+// 区切り文字に "/" を指定して一覧するため、プレフィックスの下のフォルダの中は含みません。
+func (s s3Intake) List(ctx context.Context) (map[string]fileStamp, error) {
+	client := s.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, _ := s.opts.objectURL(s.bucket, "")
+	stamps := make(map[string]fileStamp)
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.prefix}, "delimiter": {"/"}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.opts.presign(http.MethodGet, u, time.Now(), s3PresignExpiry), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3 prefix: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3 prefix: %w", err)
+		}
+		var result listBucketResult
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		} else {
+			err = xml.NewDecoder(resp.Body).Decode(&result)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3 prefix: %w", err)
+		}
+		for _, obj := range result.Contents {
+			if isZipName(obj.Key) {
+				stamps["s3://"+s.bucket+"/"+obj.Key] = fileStamp{size: obj.Size, modTime: obj.LastModified}
+			}
+		}
+		if !result.IsTruncated {
+			return stamps, nil
+		}
+		if result.NextContinuationToken == "" {
+			return nil, errors.New("failed to list s3 prefix: truncated listing without continuation token")
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Locate は intakeLister の実装です。オブジェクトを読み込むための署名付きURLを返します。
+func (s s3Intake) Locate(name string) (string, error) {
+	key, ok := strings.CutPrefix(name, "s3://"+s.bucket+"/")
+	if !ok {
+		return "", fmt.Errorf("invalid s3 path: %s", name)
+	}
+	u, err := s.opts.objectURL(s.bucket, key)
+	if err != nil {
+		return "", err
+	}
+	return s.opts.presign(http.MethodGet, u, time.Now(), s3PresignExpiry), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule はcron形式 (分 時 日 月 曜日) の実行スケジュールです。
+// 各フィールドは "*"、数値、範囲 (1-5)、間隔 (*/15, 0-30/10) とそれらのカンマ区切りを指定できます。
+// 曜日は0 (日曜) から6 (土曜) で、7も日曜として扱います。
+type CronSchedule struct {
+	minutes, hours, days, months, weekdays []bool
+	// daysAny と weekdaysAny は日と曜日が "*" かを表します。両方が指定された場合はどちらかに一致すれば実行します。
+	daysAny, weekdaysAny bool
+}
+
+// cronFields はフィールドごとの値の範囲です。
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59}, {"hour", 0, 23}, {"day", 1, 31}, {"month", 1, 12}, {"weekday", 0, 7},
+}
+
+// ParseCronSchedule はcron形式のスケジュールを解析します。(純粋関数)
+func ParseCronSchedule(s string) (CronSchedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != len(cronFields) {
+		return CronSchedule{}, fmt.Errorf("invalid schedule: %s (expected 5 fields: minute hour day month weekday)", s)
+	}
+	sets := make([][]bool, len(fields))
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return CronSchedule{}, fmt.Errorf("invalid schedule %s field: %w", cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+	// 7は日曜の別名
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+	return CronSchedule{
+		minutes: sets[0], hours: sets[1], days: sets[2], months: sets[3], weekdays: sets[4],
+		daysAny: fields[2] == "*", weekdaysAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField は1つのフィールドを、値ごとに一致するかを表すスライスにします。(純粋関数)
+func parseCronField(field string, lo, hi int) ([]bool, error) {
+	set := make([]bool, hi+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step: %s", part)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("invalid value: %s", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return nil, fmt.Errorf("invalid value: %s", part)
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("value out of range %d-%d: %s", lo, hi, part)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Next は after より後で、スケジュールに一致する最初の時刻 (秒は0) を返します。(純粋関数)
+// 4年以内に一致する時刻が無い場合 (2月30日など) はゼロ値を返します。
+func (c CronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(4, 0, 0)
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] || !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay は日と曜日のフィールドに一致するかを判定します。
+// 両方が指定された場合はcronと同じく、どちらかに一致すれば一致とします。
+func (c CronSchedule) matchDay(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[int(t.Weekday())]
+	switch {
+	case c.daysAny && c.weekdaysAny:
+		return true
+	case c.daysAny:
+		return weekday
+	case c.weekdaysAny:
+		return day
+	}
+	return day || weekday
+}
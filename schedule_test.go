@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// CronSchedule.Next のテスト
+func TestCronScheduleNext(t *testing.T) {
+	after := time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC) // 金曜日
+	tests := []struct {
+		name     string
+		schedule string
+		expected time.Time
+	}{
+		{name: "毎日2時", schedule: "0 2 * * *", expected: time.Date(2024, 3, 16, 2, 0, 0, 0, time.UTC)},
+		{name: "毎分", schedule: "* * * * *", expected: time.Date(2024, 3, 15, 10, 31, 0, 0, time.UTC)},
+		{name: "15分ごと", schedule: "*/15 * * * *", expected: time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{name: "範囲とリスト", schedule: "0,30 9-11 * * *", expected: time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{name: "曜日 (月曜)", schedule: "0 3 * * 1", expected: time.Date(2024, 3, 18, 3, 0, 0, 0, time.UTC)},
+		{name: "7は日曜", schedule: "0 0 * * 7", expected: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "月初", schedule: "0 0 1 * *", expected: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "日と曜日はどちらかに一致", schedule: "0 0 20 * 0", expected: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "うるう日", schedule: "0 0 29 2 *", expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "存在しない日", schedule: "0 0 30 2 *", expected: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCronSchedule(tt.schedule)
+			if err != nil {
+				t.Fatalf("ParseCronSchedule() error = %v", err)
+			}
+			if got := s.Next(after); !got.Equal(tt.expected) {
+				t.Errorf("Next() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// ParseCronSchedule の誤りの検出のテスト
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, s := range []string{"", "0 2 * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCronSchedule(s); err == nil {
+			t.Errorf("ParseCronSchedule(%q) error = nil", s)
+		}
+	}
+}
+
+// expiredReportDirs のテスト
+func TestExpiredReportDirs(t *testing.T) {
+	names := []string{"20240103-020000", "notes", "20240101-020000", "20240102-020000"}
+	tests := []struct {
+		name     string
+		keep     int
+		expected []string
+	}{
+		{name: "古いものを削除", keep: 1, expected: []string{"20240101-020000", "20240102-020000"}},
+		{name: "件数以内", keep: 3, expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiredReportDirs(names, tt.keep); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expiredReportDirs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
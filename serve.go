@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// runServe は serve コマンドを実行します。
+func runServe(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "serve", "serve [-listen <addr>] [-grpc-listen <addr>] [-root <dir>] [-sources <file>]")
+	var rf readerFlags
+	rf.register(fs)
+	listen := fs.String("listen", "127.0.0.1:8080", "待ち受けるアドレス (他のホストから使う場合は \":8080\" などを指定する)")
+	rootDir := fs.String("root", ".", "リクエストで指定できるアーカイブを置くフォルダ。zip にはこのフォルダからの相対パスを指定する")
+	grpcListen := fs.String("grpc-listen", "", "gRPCで待ち受けるアドレス (省略時はgRPCを提供しない)")
+	threshold := fs.Int("threshold", 10000, "リクエストや受け入れフォルダの定義で省略された場合のしきい値")
+	sourcesPath := fs.String("sources", "", "監視する受け入れフォルダの定義ファイル (JSON)。受け入れフォルダごとのしきい値と出力先でZIPファイルを集計します。s3:// の受け入れフォルダはAWSの環境変数の認証情報で読み込みます")
+	pollInterval := fs.Duration("poll-interval", 5*time.Second, "受け入れフォルダを確認する間隔")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *pollInterval <= 0 {
+		return usageError(fs, "-poll-interval must be positive")
+	}
+
+	root, err := filepath.Abs(*rootDir)
+	if err != nil {
+		return usageError(fs, "invalid root: %v", err)
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	errCh := make(chan error, 3)
+	if *sourcesPath != "" {
+		sources, err := LoadIntakeSources(*sourcesPath)
+		if err != nil {
+			return err
+		}
+		intake := &Intake{App: app, Sources: sources, Config: AppConfig{Threshold: *threshold, Normalization: "nfc"}, Interval: *pollInterval, S3: S3OptionsFromEnv(os.Getenv)}
+		go func() { errCh <- intake.Run(context.Background()) }()
+	}
+
+	if *grpcListen != "" {
+		s := NewGRPCServer(app, *threshold, root)
+		defer s.Stop()
+		go func() { errCh <- serveGRPC(s, *grpcListen, env.Logger) }()
+	}
+	go func() {
+		env.Logger.Info("HTTPサービスを開始します", slog.String("listen", *listen), slog.String("root", root))
+		errCh <- http.ListenAndServe(*listen, NewServeHandler(app, *threshold, root))
+	}()
+	// いずれかのサービスが停止した時点で終了する
+	return <-errCh
+}
+
+// ErrOutsideRoot はリクエストで指定されたアーカイブのパスが -root のフォルダの外を指していることを表します。
+var ErrOutsideRoot = errors.New("zip path must be a relative path inside the serve root")
+
+// resolveServePath はリクエストで指定されたパスを root 配下のパスに変換します。
+// 絶対パス、".." で root の外を指すパス、root の外を指すシンボリックリンクは ErrOutsideRoot を返します。
+// 存在しないパスは読み込み時のエラーとするため、そのまま root と連結して返します。
+func resolveServePath(root, p string) (string, error) {
+	local := filepath.FromSlash(p)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, p)
+	}
+	joined := filepath.Join(root, local)
+	resolved, err := filepath.EvalSymlinks(joined)
+	if err != nil {
+		return joined, nil
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve serve root: %w", err)
+	}
+	if rel, err := filepath.Rel(realRoot, resolved); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, p)
+	}
+	return resolved, nil
+}
+
+// contentTypes は出力形式ごとのContent-Typeです。
+var contentTypes = map[string]string{
+	"text":     "text/plain; charset=utf-8",
+	"csv":      "text/csv; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+	"html":     "text/html; charset=utf-8",
+	"json":     "application/json",
+	"sarif":    "application/sarif+json",
+}
+
+// NewServeHandler は集計用のHTTPハンドラを作成します。zip には root からの相対パスを指定します。
+//
+//	GET /count?zip=<path>[&threshold=N][&segments=2:4][&normalize=nfc][&format=csv][&stats=true]
+//	GET /healthz
+//	GET /metrics
+func NewServeHandler(app *App, defaultThreshold int, root string) http.Handler {
+	reg := prometheus.NewRegistry()
+	metrics := newServeMetrics(reg)
+	app = &App{Reader: metricsReader{ArchiveReader: app.Reader, metrics: metrics}, Logger: app.Logger, Stdin: app.Stdin}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /count", func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		defer func() { metrics.duration.Observe(time.Since(start).Seconds()) }()
+
+		cfg, err := parseCountQuery(r, defaultThreshold)
+		if err != nil {
+			metrics.errors.WithLabelValues("bad_request").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if cfg.ZipPath, err = resolveServePath(root, cfg.ZipPath); err != nil {
+			metrics.errors.WithLabelValues("bad_request").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := NewResultWriter(cfg.Format, WriterOptions{CSV: cfg.CSV}); err != nil {
+			metrics.errors.WithLabelValues("bad_request").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// 失敗した場合にエラーの応答を返せるよう、結果は成功してから書き出す
+		var buf bytes.Buffer
+		if err := app.Run(cfg, &buf); err != nil {
+			metrics.errors.WithLabelValues(errorType(err)).Inc()
+			app.Logger.Error("リクエストの処理に失敗しました", slog.String("error", err.Error()))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		metrics.archives.Inc()
+		w.Header().Set("Content-Type", contentTypes[cfg.Format])
+		if _, err := buf.WriteTo(w); err != nil {
+			app.Logger.Warn("応答を書き出せませんでした", slog.String("error", err.Error()))
+		}
+	})
+	return mux
+}
+
+// parseCountQuery はクエリ文字列から集計設定を組み立てます。
+func parseCountQuery(r *http.Request, defaultThreshold int) (AppConfig, error) {
+	q := r.URL.Query()
+	cfg := AppConfig{
+		ZipPath:       q.Get("zip"),
+		Threshold:     defaultThreshold,
+		Format:        q.Get("format"),
+		Normalization: q.Get("normalize"),
+	}
+	if cfg.ZipPath == "" {
+		return AppConfig{}, errors.New("zip path is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = "text"
+	}
+	if cfg.Normalization == "" {
+		cfg.Normalization = "nfc"
+	}
+	if _, err := ParseNormalization(cfg.Normalization); err != nil {
+		return AppConfig{}, err
+	}
+	if s := q.Get("threshold"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return AppConfig{}, fmt.Errorf("invalid threshold: %s", s)
+		}
+		cfg.Threshold = n
+	}
+	segments, err := ParseSegmentRange(q.Get("segments"))
+	if err != nil {
+		return AppConfig{}, err
+	}
+	cfg.Segments = segments
+	if s := q.Get("stats"); s != "" {
+		stats, err := strconv.ParseBool(s)
+		if err != nil {
+			return AppConfig{}, fmt.Errorf("invalid stats: %s", s)
+		}
+		cfg.Stats = stats
+	}
+	return cfg, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// NewServeHandler のテスト
+func TestServeHandler(t *testing.T) {
+	app := &App{
+		Reader: MockArchiveReader{Entries: []FileEntry{{Name: "dir1/a.txt"}, {Name: "dir1/b.txt"}}},
+		Logger: slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil)),
+	}
+	handler := NewServeHandler(app, 1, t.TempDir())
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "正常系", url: "/count?zip=a.zip&format=csv", expectedCode: http.StatusOK, expectedBody: "dir1,2"},
+		{name: "しきい値指定", url: "/count?zip=a.zip&threshold=3", expectedCode: http.StatusOK, expectedBody: "Folder Path"},
+		{name: "zip未指定", url: "/count", expectedCode: http.StatusBadRequest, expectedBody: "zip path is required"},
+		{name: "未知の形式", url: "/count?zip=a.zip&format=xml", expectedCode: http.StatusBadRequest, expectedBody: "unknown format"},
+		{name: "ヘルスチェック", url: "/healthz", expectedCode: http.StatusOK, expectedBody: "ok"},
+		{name: "メトリクス", url: "/metrics", expectedCode: http.StatusOK, expectedBody: "obuzipcount_archives_processed_total 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("body does not contain %q, got: %s", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// errorType のテスト
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: &ArchiveOpenError{Err: ErrArchiveNotFound}, expected: "not_found"},
+		{err: fmt.Errorf("read entries error: %w", &ArchiveOpenError{Err: ErrNotZip}), expected: "invalid_archive"},
+		{err: errors.New("boom"), expected: "internal"},
+	}
+	for _, tt := range tests {
+		if got := errorType(tt.err); got != tt.expected {
+			t.Errorf("errorType(%v): expected %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
+
+// NewServeHandler の -root による制限とエラーの応答のテスト
+func TestServeHandlerRoot(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.zip"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.zip"), filepath.Join(root, "link.zip")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+	ok := NewServeHandler(&App{Reader: MockArchiveReader{Entries: []FileEntry{{Name: "dir1/a.txt"}}}, Logger: logger}, 1, root)
+	failing := NewServeHandler(&App{Reader: MockArchiveReader{Err: errors.New("broken archive")}, Logger: logger}, 1, root)
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		url          string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "フォルダ内の相対パス", handler: ok, url: "/count?zip=sub/a.zip", expectedCode: http.StatusOK, expectedBody: "dir1"},
+		{name: "親フォルダ", handler: ok, url: "/count?zip=../a.zip", expectedCode: http.StatusBadRequest, expectedBody: "inside the serve root"},
+		{name: "絶対パス", handler: ok, url: "/count?zip=" + filepath.ToSlash(filepath.Join(outside, "secret.zip")), expectedCode: http.StatusBadRequest, expectedBody: "inside the serve root"},
+		{name: "外を指すシンボリックリンク", handler: ok, url: "/count?zip=link.zip", expectedCode: http.StatusBadRequest, expectedBody: "inside the serve root"},
+		{name: "失敗した場合は結果を書き出さない", handler: failing, url: "/count?zip=a.zip&format=csv", expectedCode: http.StatusInternalServerError, expectedBody: "broken archive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("body does not contain %q, got: %s", tt.expectedBody, rec.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK && strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+				t.Errorf("error response should not be sent as csv")
+			}
+		})
+	}
+}
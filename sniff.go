@@ -0,0 +1,255 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSniffBytes はエントリの種類の判定に読む先頭のバイト数の既定値です。http.DetectContentType は先頭512バイトまでを使います。
+const defaultSniffBytes = 512
+
+// SniffOptions は内容による種類の判定方法を指定します。
+type SniffOptions struct {
+	// Workers は同時に読み込むエントリの数です。0以下の場合はCPUの数を使います。
+	Workers int
+	// Bytes は1件のエントリから読む先頭のバイト数の上限です。0以下の場合は defaultSniffBytes を使います。
+	Bytes int
+	// Filter を満たすエントリだけを判定します。
+	Filter EntryFilter
+}
+
+// SniffResult は1件のエントリの内容から判定した種類です。
+type SniffResult struct {
+	Name string
+	// MIME は判定したMIMEタイプです。読み込めなかった場合は空です。
+	MIME string
+}
+
+// Sniff はローカルのZIPファイルの各ファイルの先頭を読み、内容からMIMEタイプを判定します。
+// 読むのはエントリごとに opts.Bytes までで、複数のエントリを並行して読み込みます。
+// 暗号化されたエントリや展開できないエントリは MIME を空にします。結果は復号後のエントリ名の順に並べます。
+func (z ZipArchiveReader) Sniff(zipPath string, opts SniffOptions) ([]SniffResult, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer r.Close()
+
+	limit := opts.Bytes
+	if limit <= 0 {
+		limit = defaultSniffBytes
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	entries, _ := z.entries(r.File, time.Time{}, nil)
+	var targets []int
+	for i, e := range entries {
+		if !e.IsDir && opts.Filter.Match(e) {
+			targets = append(targets, i)
+		}
+	}
+	results := make([]SniffResult, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, max(len(targets), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				i := targets[j]
+				results[j] = SniffResult{Name: entries[i].Name, MIME: sniffFile(r.File[i], limit)}
+			}
+		}()
+	}
+	for j := range targets {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+// sniffFile は1件のエントリの先頭を limit バイトまで読み、MIMEタイプを返します。
+// 暗号化されている場合と読み込めない場合は空文字列を返します。
+func sniffFile(f *zip.File, limit int) string {
+	if f.Flags&0x1 != 0 {
+		return ""
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	head, err := io.ReadAll(io.LimitReader(rc, int64(limit)))
+	if err != nil && len(head) == 0 {
+		return ""
+	}
+	return DetectContentType(head)
+}
+
+// DetectContentType は先頭のバイト列からMIMEタイプを判定します。(純粋関数)
+// http.DetectContentType が判定しない実行ファイルの形式を先に調べます。
+func DetectContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return "application/vnd.microsoft.portable-executable"
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "application/x-elf"
+	}
+	return http.DetectContentType(head)
+}
+
+// mimeCategories は判定したMIMEタイプと、FileClassifier の分類名の対応です。
+// テキストや application/octet-stream のように種類を特定できないものは含めません。
+var mimeCategories = map[string]string{
+	"application/zip":                               "archive",
+	"application/x-gzip":                            "archive",
+	"application/x-rar-compressed":                  "archive",
+	"application/x-7z-compressed":                   "archive",
+	"application/pdf":                               "document",
+	"application/x-elf":                             "executable",
+	"application/vnd.microsoft.portable-executable": "executable",
+}
+
+// zipContainerExtensions はZIP形式を内部の形式に使う拡張子です。内容がZIPでも不一致として扱わず、
+// アーカイブとして指定された場合は形式を判定せずにZIPとして読み込みます。
+var zipContainerExtensions = map[string]bool{
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".ods": true, ".odp": true,
+	".jar": true, ".war": true, ".ear": true, ".apk": true, ".epub": true,
+}
+
+// documentContainerExtensions はZIP形式を内部に使う拡張子のうち、文書の形式です。
+// 入れ子のアーカイブを読み込む際は、既定でこれらの中のエントリまで降りずに1つのファイルとして扱います。
+var documentContainerExtensions = map[string]bool{
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".ods": true, ".odp": true, ".epub": true,
+}
+
+// ContainerOptions はZIP形式を内部に使うファイル (.jar、.docx など) の扱いを指定します。
+// 拡張子は "." で始まる小文字で指定します。
+type ContainerOptions struct {
+	// Zip は形式を判定せずにZIPとして読み込み、内容がZIPでも不一致として扱わない拡張子です。
+	// nil の場合は zipContainerExtensions を使います。
+	Zip map[string]bool
+	// Opaque は入れ子のアーカイブを読み込む際に、中のエントリまで降りずに1つのファイルとして扱う拡張子です。
+	// nil の場合は documentContainerExtensions を使います。
+	Opaque map[string]bool
+}
+
+// IsZip は name の拡張子がZIPとして読み込む拡張子かを判定します。(純粋関数)
+func (o ContainerOptions) IsZip(name string) bool {
+	exts := o.Zip
+	if exts == nil {
+		exts = zipContainerExtensions
+	}
+	return exts[strings.ToLower(filepath.Ext(name))]
+}
+
+// IsOpaque は name の拡張子が入れ子のアーカイブとして中まで読み込まない拡張子かを判定します。(純粋関数)
+func (o ContainerOptions) IsOpaque(name string) bool {
+	exts := o.Opaque
+	if exts == nil {
+		exts = documentContainerExtensions
+	}
+	return exts[strings.ToLower(filepath.Ext(name))]
+}
+
+// ParseExtensionList はカンマ区切りの拡張子の一覧を、"." で始まる小文字の拡張子の集合に変換します。(純粋関数)
+// "." は省略できます。空文字列の場合は空の集合を返します。
+func ParseExtensionList(s string) (map[string]bool, error) {
+	exts := make(map[string]bool)
+	for _, ext := range strings.Split(s, ",") {
+		if ext = strings.TrimSpace(ext); ext == "" {
+			continue
+		}
+		ext = "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext == "." || strings.ContainsAny(ext[1:], "./\\") {
+			return nil, fmt.Errorf("invalid extension: %s", ext)
+		}
+		exts[ext] = true
+	}
+	return exts, nil
+}
+
+// extensionList は拡張子の集合を昇順のカンマ区切りの文字列にします。(純粋関数)
+func extensionList(exts map[string]bool) string {
+	list := make([]string, 0, len(exts))
+	for ext := range exts {
+		list = append(list, ext)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+// mimeCategory はMIMEタイプに対応する分類名を返します。特定できない場合は空文字列を返します。(純粋関数)
+func mimeCategory(mime string) string {
+	mime, _, _ = strings.Cut(mime, ";")
+	if category, ok := mimeCategories[mime]; ok {
+		return category
+	}
+	for _, prefix := range []string{"image", "audio", "video"} {
+		if strings.HasPrefix(mime, prefix+"/") {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// SniffMismatch は拡張子の分類と内容から判定した分類が異なるエントリです。
+type SniffMismatch struct {
+	Name string
+	// Category は拡張子から判定した分類名です。
+	Category string
+	// Detected は内容から判定した分類名です。
+	Detected string
+	MIME     string
+}
+
+// FindSniffMismatches は拡張子の分類と内容の分類が異なるエントリを返します。(純粋関数)
+// 内容から分類を特定できないエントリと、containers でZIPとして扱う拡張子でZIPと判定されたエントリは除きます。
+func FindSniffMismatches(results []SniffResult, c FileClassifier, containers ContainerOptions) []SniffMismatch {
+	var mismatches []SniffMismatch
+	for _, r := range results {
+		detected := mimeCategory(r.MIME)
+		if detected == "" {
+			continue
+		}
+		if strings.HasPrefix(r.MIME, "application/zip") && containers.IsZip(r.Name) {
+			continue
+		}
+		if category := c.Classify(r.Name); category != detected {
+			mismatches = append(mismatches, SniffMismatch{Name: r.Name, Category: category, Detected: detected, MIME: r.MIME})
+		}
+	}
+	return mismatches
+}
+
+// WriteSniffMismatches は拡張子と内容が一致しないエントリをプレーンテキストで出力します。
+// 不一致がない場合は何も出力しません。
+func WriteSniffMismatches(w io.Writer, mismatches []SniffMismatch) error {
+	if len(mismatches) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\nExtension mismatches:\n"); err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		if _, err := fmt.Fprintf(w, "  %s (extension: %s, content: %s, %s)\n", m.Name, m.Category, m.Detected, m.MIME); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ZipArchiveReader.Sniff と FindSniffMismatches のテスト
+func TestSniff(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"a/renamed.dat":  "PK\x03\x04rest of zip",
+		"a/report.docx":  "PK\x03\x04rest of docx",
+		"a/photo.jpg":    "\x89PNG\r\n\x1a\n0000",
+		"a/tool.txt":     "MZ\x90\x00",
+		"a/notes.txt":    "plain text",
+		"b/skipped.dat":  "PK\x03\x04",
+		"a/empty.bin":    "",
+		"a/library.gz":   "\x1f\x8b\x08",
+		"a/document.pdf": "%PDF-1.7",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	filter := EntryFilter{Exclude: []string{"b"}}
+	results, err := ZipArchiveReader{}.Sniff(zipPath, SniffOptions{Workers: 2, Bytes: 8, Filter: filter})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("expected 8 results, got %+v", results)
+	}
+
+	mismatches := FindSniffMismatches(results, NewFileClassifier(nil), ContainerOptions{})
+	want := []SniffMismatch{
+		{Name: "a/renamed.dat", Category: "other", Detected: "archive", MIME: "application/zip"},
+		{Name: "a/tool.txt", Category: "document", Detected: "executable", MIME: "application/vnd.microsoft.portable-executable"},
+	}
+	if !reflect.DeepEqual(mismatches, want) {
+		t.Errorf("expected %+v, got %+v", want, mismatches)
+	}
+
+	// ZIPとして扱う拡張子から外した .docx は不一致になる
+	mismatches = FindSniffMismatches(results, NewFileClassifier(nil), ContainerOptions{Zip: map[string]bool{".jar": true}})
+	if len(mismatches) != 3 || mismatches[1].Name != "a/report.docx" {
+		t.Errorf("expected report.docx to mismatch, got %+v", mismatches)
+	}
+}
+
+// ParseExtensionList のテスト
+func TestParseExtensionList(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]bool
+		wantErr  bool
+	}{
+		{name: "正常系", input: ".jar,.DOCX", expected: map[string]bool{".jar": true, ".docx": true}},
+		{name: "ドットを省略", input: "apk, epub", expected: map[string]bool{".apk": true, ".epub": true}},
+		{name: "空文字列", input: "", expected: map[string]bool{}},
+		{name: "多段の拡張子", input: ".tar.gz", wantErr: true},
+		{name: "パスの区切り", input: "a/b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExtensionList(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ContainerOptions のテスト (既定の一覧と、指定した一覧)
+func TestContainerOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           ContainerOptions
+		file           string
+		expectedZip    bool
+		expectedOpaque bool
+	}{
+		{name: "既定のjar", file: "lib/app.JAR", expectedZip: true},
+		{name: "既定のdocx", file: "docs/report.docx", expectedZip: true, expectedOpaque: true},
+		{name: "既定のzip", file: "nested.zip"},
+		{name: "指定した一覧", opts: ContainerOptions{Zip: map[string]bool{".nupkg": true}, Opaque: map[string]bool{".jar": true}}, file: "lib/app.jar", expectedOpaque: true},
+		{name: "空の一覧", opts: ContainerOptions{Zip: map[string]bool{}, Opaque: map[string]bool{}}, file: "docs/report.docx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.IsZip(tt.file); got != tt.expectedZip {
+				t.Errorf("IsZip: expected %v, got %v", tt.expectedZip, got)
+			}
+			if got := tt.opts.IsOpaque(tt.file); got != tt.expectedOpaque {
+				t.Errorf("IsOpaque: expected %v, got %v", tt.expectedOpaque, got)
+			}
+		})
+	}
+}
+
+// AutoArchiveReader のテスト (先頭に起動用のスタブを付けた .jar)
+// スタブがtarのヘッダに見えるため、形式を判定するとtarになる。ZIPとして扱う拡張子の場合だけZIPとして読み込める
+func TestAutoArchiveReaderStubbedContainer(t *testing.T) {
+	var buf bytes.Buffer
+	stub := "#!/bin/sh\nexec java -jar \"$0\" \"$@\"\n"
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "launcher.sh", Mode: 0o755, Size: int64(len(stub))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(stub))
+	// 終端のブロックは書かずに、ZIPのデータを続ける
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"META-INF/MANIFEST.MF", "com/example/Main.class"} {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	jarPath := filepath.Join(t.TempDir(), "app.jar")
+	if err := os.WriteFile(jarPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        ContainerOptions
+		expected    []string
+		expectedErr string
+	}{
+		{name: "既定の一覧ではZIP", expected: []string{"META-INF/MANIFEST.MF", "com/example/Main.class"}},
+		{name: "一覧から外すとtarとして読み込む", opts: ContainerOptions{Zip: map[string]bool{".docx": true}}, expectedErr: "failed to read tar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := (AutoArchiveReader{Containers: tt.opts}).ReadEntries(jarPath)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
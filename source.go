@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdinPath はアーカイブを標準入力から読み込むことを表すパスです。
+const StdinPath = "-"
+
+// AutoArchiveReader はファイルの先頭のシグネチャからアーカイブの形式を判定し、対応する ArchiveReader で読み込みます。
+// RegisterArchiveReader で拡張子に登録された ArchiveReader があれば、判定より優先して使います。
+// 判定できない場合はZIPとして読み込み、ZIPの検査で問題を報告します。
+type AutoArchiveReader struct {
+	Zip ZipArchiveReader
+	ISO IsoArchiveReader
+	Cab CabArchiveReader
+	Msi MsiArchiveReader
+	Tar TarArchiveReader
+	// Compressed はgzip、bzip2で圧縮された単一のファイルと、それで圧縮されたtarを読み込みます。
+	Compressed CompressedArchiveReader
+	// Cache が nil でない場合は、読み込んだエントリの一覧を保存し、変わっていないアーカイブでは読み込みを省きます。
+	Cache *EntryCache
+	// Containers は .jar、.docx などZIP形式を内部に使うファイルの扱いです。入れ子のアーカイブを読み込む処理は Containers.IsOpaque で中まで読み込むかを判定します。
+	Containers ContainerOptions
+}
+
+func (a AutoArchiveReader) ReadEntries(archivePath string) ([]FileEntry, error) {
+	if a.Cache != nil {
+		if entries, ok := a.Cache.Load(archivePath); ok {
+			return entries, nil
+		}
+		entries, err := a.readEntries(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		// キャッシュに保存できなくても読み込みの結果は使えるため、エラーにしない
+		_ = a.Cache.Store(archivePath, entries)
+		return entries, nil
+	}
+	return a.readEntries(archivePath)
+}
+
+// readEntries はキャッシュを使わずに、形式に対応する ArchiveReader で読み込みます。
+func (a AutoArchiveReader) readEntries(archivePath string) ([]FileEntry, error) {
+	if r, ok := registeredArchiveReader(archivePath); ok {
+		return r.ReadEntries(archivePath)
+	}
+	switch a.kind(archivePath) {
+	case "iso":
+		return a.ISO.ReadEntries(archivePath)
+	case "cab":
+		return a.Cab.ReadEntries(archivePath)
+	case "msi":
+		return a.Msi.ReadEntries(archivePath)
+	case "tar":
+		return a.Tar.ReadEntries(archivePath)
+	case "gzip", "bzip2", "xz":
+		return a.Compressed.ReadEntries(archivePath)
+	default:
+		return a.Zip.ReadEntries(archivePath)
+	}
+}
+
+// kind はアーカイブの形式を判定します。URLと、Containers でZIPとして扱う拡張子 (既定は .jar、.docx など) のファイルはZIPとして扱います。
+// (URLは形式の判定のためだけに取得しない)
+func (a AutoArchiveReader) kind(archivePath string) string {
+	if isRemotePath(archivePath) || a.Containers.IsZip(archivePath) {
+		return "zip"
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "zip"
+	}
+	defer f.Close()
+	return detectArchiveKind(f)
+}
+
+// spoolToTempFile はZIPの読み込みに必要なランダムアクセスのため、ストリームを一時ファイルに書き出します。
+// 書き出しと同時に計算したSHA-256も返します。戻り値の cleanup で一時ファイルを削除します。
+func spoolToTempFile(r io.Reader) (tmpPath, sum string, cleanup func(), err error) {
+	f, err := os.CreateTemp("", "obuzipcount-*.zip")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	cleanup = func() { os.Remove(f.Name()) }
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		f.Close()
+		cleanup()
+		return "", "", nil, fmt.Errorf("failed to spool stdin: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", "", nil, fmt.Errorf("failed to spool stdin: %w", err)
+	}
+	return f.Name(), hex.EncodeToString(h.Sum(nil)), cleanup, nil
+}
+
+// hashFile はファイル全体をストリームで読み込み、SHA-256を16進文字列で返します。
+func hashFile(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive for hashing: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash archive: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// folderEntryOverhead はフォルダの階層ごとの節をメモリに保持する場合の、階層の名前以外のおおよそのバイト数です。
+// 節の構造体と、親の節のマップのバケットを含みます。
+const folderEntryOverhead = 64
+
+// folderStatsOverhead はフォルダごとのサイズの統計をメモリに保持する場合の、最大のファイル名以外のおおよそのバイト数です。
+const folderStatsOverhead = 96
+
+// spillRecord は一時ファイルに書き出すフォルダごとの集計途中の値です。
+type spillRecord struct {
+	Key   string
+	Count int
+	Stats *FolderStats
+}
+
+// spillMergeFanIn は同時に開く一時ファイルの上限です。これを超える場合は書き出した一時ファイルを1つに併合します。
+const spillMergeFanIn = 64
+
+// folderSpill は -max-memory を超えた集計途中の値を書き出した一時ファイルの一覧です。
+// 各ファイルは spillSortKey の順に並んでいます。
+type folderSpill struct {
+	dir  string
+	runs []string
+	// created は作成した一時ファイルの数です。ファイル名の連番に使います。
+	created int
+}
+
+// spillSortKey は一時ファイル内の並び順のキーです。(純粋関数)
+// 区切り文字を最小の文字に置き換え、フォルダの直後に配下のフォルダが続くようにします。
+func spillSortKey(key string) string {
+	return strings.ReplaceAll(key, "/", "\x00")
+}
+
+// spill はメモリ上の集計途中の値を1つの一時ファイルに書き出し、メモリから除きます。
+func (a *Accumulator) spill() error {
+	if a.folders.empty() {
+		return nil
+	}
+	if a.spilled == nil {
+		dir, err := os.MkdirTemp(a.opts.SpillDir, "obuzipcount-spill-*")
+		if err != nil {
+			return fmt.Errorf("failed to create spill directory: %w", err)
+		}
+		a.spilled = &folderSpill{dir: dir}
+	}
+	records := a.sortedRecords()
+	runPath, err := a.spilled.writeRun(func(emit func(spillRecord) error) error {
+		for _, r := range records {
+			if err := emit(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.spilled.runs = append(a.spilled.runs, runPath)
+	a.folders = newFolderTrie()
+	a.memory = 0
+	if len(a.spilled.runs) >= spillMergeFanIn {
+		return a.spilled.compact()
+	}
+	return nil
+}
+
+// writeRun は write が emit に渡すレコードを新しい一時ファイルに書き出し、そのパスを返します。
+func (s *folderSpill) writeRun(write func(emit func(spillRecord) error) error) (string, error) {
+	runPath := filepath.Join(s.dir, fmt.Sprintf("run-%d.gob", s.created))
+	s.created++
+	f, err := os.Create(runPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create spill file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	err = write(func(r spillRecord) error { return enc.Encode(r) })
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(runPath)
+		return "", fmt.Errorf("failed to write spill file: %w", err)
+	}
+	return runPath, nil
+}
+
+// compact は書き出したすべての一時ファイルを1つに併合し、同時に開くファイルの数を抑えます。
+func (s *folderSpill) compact() error {
+	runPath, err := s.writeRun(func(emit func(spillRecord) error) error {
+		var emitErr error
+		err := s.merge(nil, func(r spillRecord) {
+			if emitErr == nil {
+				emitErr = emit(r)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return emitErr
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range s.runs {
+		os.Remove(p)
+	}
+	s.runs = []string{runPath}
+	return nil
+}
+
+// errIncompleteResult は書き出した一時ファイルを読み込めず、集計結果が不完全であることを表します。
+var errIncompleteResult = errors.New("aggregation result is incomplete")
+
+// sortedRecords はメモリ上の値を spillSortKey の順に並べます。
+func (a *Accumulator) sortedRecords() []spillRecord {
+	var records []spillRecord
+	a.folders.walk(func(k string, n *folderNode) {
+		records = append(records, spillRecord{Key: k, Count: n.count, Stats: n.stats})
+	})
+	sort.Slice(records, func(i, j int) bool { return spillSortKey(records[i].Key) < spillSortKey(records[j].Key) })
+	return records
+}
+
+// spillCursor は一時ファイルの1つ、またはメモリ上の値を先頭から読む位置です。
+type spillCursor struct {
+	dec  *gob.Decoder
+	file *os.File
+	// records はメモリ上の値です。dec が nil の場合に使います。
+	records []spillRecord
+	current spillRecord
+	// order は書き出した順です。同じフォルダは先に書き出した値から併合し、メモリ上の値は最後にします。
+	order int
+}
+
+// next は次のレコードを読みます。終わりでは false を返します。
+func (c *spillCursor) next() (bool, error) {
+	if c.dec == nil {
+		if len(c.records) == 0 {
+			return false, nil
+		}
+		c.current, c.records = c.records[0], c.records[1:]
+		return true, nil
+	}
+	c.current = spillRecord{}
+	if err := c.dec.Decode(&c.current); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	return true, nil
+}
+
+// spillHeap は一時ファイルの先頭のレコードを spillSortKey の順に取り出すヒープです。
+type spillHeap []*spillCursor
+
+func (h spillHeap) Len() int { return len(h) }
+func (h spillHeap) Less(i, j int) bool {
+	ki, kj := spillSortKey(h[i].current.Key), spillSortKey(h[j].current.Key)
+	if ki != kj {
+		return ki < kj
+	}
+	return h[i].order < h[j].order
+}
+func (h spillHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *spillHeap) Push(x any)   { *h = append(*h, x.(*spillCursor)) }
+func (h *spillHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// close は一時ファイルを閉じます。
+func (c *spillCursor) close() {
+	if c.file != nil {
+		c.file.Close()
+	}
+}
+
+// merge はすべての一時ファイルと、まだ書き出していないメモリ上の値 memory を spillSortKey の順に併合し、
+// 同じフォルダの値を合算して fn に渡します。
+func (s *folderSpill) merge(memory []spillRecord, fn func(spillRecord)) error {
+	h := make(spillHeap, 0, len(s.runs)+1)
+	if len(memory) > 0 {
+		c := &spillCursor{records: memory, order: len(s.runs)}
+		c.next()
+		h = append(h, c)
+	}
+	defer func() {
+		for _, c := range h {
+			c.close()
+		}
+	}()
+	for i, p := range s.runs {
+		f, err := os.Open(p)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %w", err)
+		}
+		c := &spillCursor{dec: gob.NewDecoder(bufio.NewReader(f)), file: f, order: i}
+		ok, err := c.next()
+		if err != nil || !ok {
+			f.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		h = append(h, c)
+	}
+	heap.Init(&h)
+
+	var merged *spillRecord
+	for h.Len() > 0 {
+		c := h[0]
+		r := c.current
+		if merged != nil && merged.Key == r.Key {
+			merged.Count += r.Count
+			merged.Stats = mergeFolderStats(merged.Stats, r.Stats)
+		} else {
+			if merged != nil {
+				fn(*merged)
+			}
+			merged = &r
+		}
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			c.close()
+			heap.Pop(&h)
+		}
+	}
+	if merged != nil {
+		fn(*merged)
+	}
+	return nil
+}
+
+// mergeFolderStats は同じフォルダの2つの統計を合算します。最大のファイルはサイズが同じ場合、先の値を残します。(純粋関数)
+func mergeFolderStats(a, b *FolderStats) *FolderStats {
+	if a == nil || b == nil {
+		if a == nil {
+			return b
+		}
+		return a
+	}
+	s := *a
+	s.TotalSize += b.TotalSize
+	if b.LargestSize > s.LargestSize {
+		s.LargestFile, s.LargestSize = b.LargestFile, b.LargestSize
+	}
+	return &s
+}
+
+// resultFromSpill は一時ファイルに書き出した値とメモリ上の値を併合して Result と同じ結果を求めます。
+// 結果に含めないフォルダはメモリに保持しません。
+func (a *Accumulator) resultFromSpill() ([]FolderCount, error) {
+	var results []FolderCount
+	var other FolderCount
+	// 累積モードで親フォルダの件数を求めるため、現在のフォルダの上位のフォルダを保持する
+	type ancestor struct {
+		key   string
+		count int
+	}
+	var stack []ancestor
+	err := a.spilled.merge(a.sortedRecords(), func(r spillRecord) {
+		k, v := r.Key, r.Count
+		parent := v
+		if a.opts.Cumulative && k != "(Root)" {
+			for len(stack) > 0 && !strings.HasPrefix(k, stack[len(stack)-1].key+"/") {
+				stack = stack[:len(stack)-1]
+			}
+			switch p := parentFolder(k); {
+			case p == "(Root)":
+				// ルートにはすべてのファイルを数える
+				parent = a.files
+			case len(stack) > 0 && stack[len(stack)-1].key == p:
+				parent = stack[len(stack)-1].count
+			default:
+				parent = 0
+			}
+			stack = append(stack, ancestor{key: k, count: v})
+		}
+
+		meets := a.opts.meetsThreshold(k, v, a.files)
+		if !meets && !a.opts.CollapseOther {
+			return
+		}
+		fc := a.folderCount(k, v, r.Stats)
+		if !meets {
+			other.absorb(fc)
+			return
+		}
+		if a.opts.Cumulative {
+			fc.Share = &FolderShare{OfParent: percent(v, parent), OfTotal: percent(v, a.files)}
+		}
+		results = append(results, fc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIncompleteResult, err)
+	}
+	sortFolderCounts(results)
+	if other.OtherFolders > 0 {
+		results = append(results, other)
+	}
+	return results, nil
+}
+
+// Close は集計途中の値を書き出した一時ファイルを削除します。書き出していない場合は何もしません。
+// 一時ファイルを削除した後は、書き出した値は結果に含まれません。
+func (a *Accumulator) Close() error {
+	if a.spilled == nil {
+		return nil
+	}
+	dir := a.spilled.dir
+	a.spilled = nil
+	return os.RemoveAll(dir)
+}
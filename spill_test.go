@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// 一時ファイルを使った集計がメモリ上の集計と同じ結果になることのテスト
+func TestAggregateSpill(t *testing.T) {
+	var entries []FileEntry
+	// 区切り文字より前に並ぶ文字 (空白、"-") を含む名前で、親子のフォルダの並び順を確かめる
+	for i := 0; i < 300; i++ {
+		dirs := []string{"a", "a b", "a-b", "a/b", "a/b/c", fmt.Sprintf("x%02d", i%80), fmt.Sprintf("a/b/y%d", i%7)}
+		entries = append(entries, FileEntry{Name: fmt.Sprintf("%s/%d.txt", dirs[i%len(dirs)], i), Size: uint64(i % 13)})
+	}
+	entries = append(entries, FileEntry{Name: "root.txt", Size: 5})
+
+	tests := []struct {
+		name string
+		opts AggregateOptions
+	}{
+		{name: "しきい値", opts: AggregateOptions{Threshold: 5}},
+		{name: "サイズの統計", opts: AggregateOptions{Threshold: 1, Stats: true}},
+		{name: "累積", opts: AggregateOptions{Threshold: 1, Cumulative: true, Stats: true}},
+		{name: "しきい値未満をまとめる", opts: AggregateOptions{Threshold: 10, CollapseOther: true, Stats: true}},
+		{name: "割合のしきい値", opts: AggregateOptions{ThresholdPercent: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, expectedFiles := Aggregate(entries, tt.opts)
+
+			dir := t.TempDir()
+			opts := tt.opts
+			// すべての新しいフォルダで書き出し、一時ファイルの併合も起こす
+			opts.MaxMemory, opts.SpillDir = 1, dir
+			acc := NewAccumulator(opts)
+			for _, e := range entries {
+				acc.Add(e)
+			}
+			got, gotFiles := acc.Result()
+			if !acc.Spilled() || acc.Err() != nil {
+				t.Fatalf("Spilled() = %v, Err() = %v", acc.Spilled(), acc.Err())
+			}
+			if gotFiles != expectedFiles || !reflect.DeepEqual(got, expected) {
+				t.Errorf("Result() = %+v (%d files), want %+v (%d files)", got, gotFiles, expected, expectedFiles)
+			}
+			if err := acc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if left, _ := os.ReadDir(dir); len(left) != 0 {
+				t.Errorf("spill files were not removed: %v", left)
+			}
+		})
+	}
+}
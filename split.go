@@ -0,0 +1,165 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// splitPartPattern は分割アーカイブの途中のボリューム (.z01, .z02, …) の拡張子です。
+var splitPartPattern = regexp.MustCompile(`(?i)^\.z\d{2,}$`)
+
+// splitVolumePath は分割アーカイブの n 番目 (1始まり) の途中のボリュームのパスを返します。(純粋関数)
+func splitVolumePath(zipPath string, n int) string {
+	return strings.TrimSuffix(zipPath, filepath.Ext(zipPath)) + fmt.Sprintf(".z%02d", n)
+}
+
+// splitVolumes は分割アーカイブのボリュームのパスを先頭から順に返します。
+// .z01 等の途中のボリュームが指定された場合は、最後の .zip を基準に探します。
+// 途中のボリュームが無い場合は zipPath だけを返します。
+func splitVolumes(zipPath string) []string {
+	if splitPartPattern.MatchString(filepath.Ext(zipPath)) {
+		zipPath = strings.TrimSuffix(zipPath, filepath.Ext(zipPath)) + ".zip"
+	}
+	var volumes []string
+	for n := 1; ; n++ {
+		p := splitVolumePath(zipPath, n)
+		if _, err := os.Stat(p); err != nil {
+			break
+		}
+		volumes = append(volumes, p)
+	}
+	return append(volumes, zipPath)
+}
+
+// openSplit は分割アーカイブのボリュームを連結した1つのアーカイブとして読み込みます。
+// セントラルディレクトリの位置はボリュームごとの相対位置ですが、
+// archive/zip は終端レコードからの逆算で開始位置を補正するため、連結したまま読み込めます。
+func openSplit(volumes []string) ([]*zip.File, error) {
+	if err := CheckArchiveFile(volumes[0]); err != nil {
+		return nil, err
+	}
+	set, err := openVolumeSet(volumes)
+	if err != nil {
+		return nil, err
+	}
+	defer set.Close()
+
+	disk, err := zipLastDisk(set, set.size)
+	if err != nil {
+		return nil, err
+	}
+	if disk+1 != len(volumes) {
+		return nil, fmt.Errorf("failed to open split zip: archive has %d volumes but %d were found", disk+1, len(volumes))
+	}
+	r, err := zip.NewReader(set, set.size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open split zip: %w", err)
+	}
+	return r.File, nil
+}
+
+// missingVolumesError は .zip が分割アーカイブの最後のボリュームである場合、
+// 途中のボリュームが見つからないことを表すエラーを返します。分割アーカイブでない場合や読み込めない場合は nil を返します。
+func missingVolumesError(zipPath string) error {
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	disk, err := zipLastDisk(f, info.Size())
+	if err != nil || disk == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to open split zip: archive has %d volumes but %s was not found", disk+1, filepath.Base(splitVolumePath(zipPath, 1)))
+}
+
+// zipLastDisk は終端レコードに記録された、最後のボリュームのディスク番号 (0始まり) を返します。
+func zipLastDisk(r io.ReaderAt, size int64) (int, error) {
+	tailSize := min(size, zipTailSize)
+	tail := make([]byte, tailSize)
+	if _, err := r.ReadAt(tail, size-tailSize); err != nil && !errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("failed to read zip: %w", err)
+	}
+	i := bytes.LastIndex(tail, []byte("PK\x05\x06"))
+	if i < 0 || len(tail)-i < 22 {
+		return 0, errors.New("failed to read zip: end of central directory not found")
+	}
+	disk := int(binary.LittleEndian.Uint16(tail[i+4:]))
+	// ZIP64の場合はロケータに全体のディスク数が記録される
+	if disk == 0xFFFF && i >= 20 && bytes.Equal(tail[i-20:i-16], []byte("PK\x06\x07")) {
+		disk = int(binary.LittleEndian.Uint32(tail[i-4:])) - 1
+	}
+	return disk, nil
+}
+
+// volumeSet は複数のボリュームのファイルを連結した io.ReaderAt です。
+type volumeSet struct {
+	files   []*os.File
+	offsets []int64
+	sizes   []int64
+	size    int64
+}
+
+// openVolumeSet はボリュームを順に開きます。
+func openVolumeSet(paths []string) (*volumeSet, error) {
+	set := &volumeSet{}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			set.Close()
+			return nil, fmt.Errorf("failed to open split zip volume: %w", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			set.Close()
+			return nil, fmt.Errorf("failed to open split zip volume: %w", err)
+		}
+		set.files = append(set.files, f)
+		set.offsets = append(set.offsets, set.size)
+		set.sizes = append(set.sizes, info.Size())
+		set.size += info.Size()
+	}
+	return set, nil
+}
+
+// ReadAt は io.ReaderAt の実装です。ボリュームの境界をまたぐ読み込みにも対応します。
+func (s *volumeSet) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for i, f := range s.files {
+		pos := off + int64(n) - s.offsets[i]
+		if n == len(p) || pos >= s.sizes[i] {
+			continue
+		}
+		m, err := f.ReadAt(p[n:min(int64(len(p)), int64(n)+s.sizes[i]-pos)], pos)
+		n += m
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close はすべてのボリュームを閉じます。
+func (s *volumeSet) Close() error {
+	var errs []error
+	for _, f := range s.files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// writeTestSplitZip はZIPを at の位置で2つのボリュームに分割し、dir に x.z01 と x.zip として書き出します。
+// 終端レコードのディスク番号とセントラルディレクトリの位置は、分割後のボリュームに合わせて書き換えます。
+func writeTestSplitZip(t *testing.T, dir string, data []byte, at int) string {
+	t.Helper()
+	data = bytes.Clone(data)
+	eocd := bytes.LastIndex(data, []byte("PK\x05\x06"))
+	cdOffset := int(binary.LittleEndian.Uint32(data[eocd+16:]))
+	binary.LittleEndian.PutUint16(data[eocd+4:], 1)
+	if at <= cdOffset {
+		binary.LittleEndian.PutUint16(data[eocd+6:], 1)
+		binary.LittleEndian.PutUint32(data[eocd+16:], uint32(cdOffset-at))
+	} else {
+		// 先頭のボリュームの分割シグネチャの分だけずれる
+		binary.LittleEndian.PutUint32(data[eocd+16:], uint32(cdOffset+4))
+	}
+
+	zipPath := filepath.Join(dir, "x.zip")
+	if err := os.WriteFile(filepath.Join(dir, "x.z01"), append([]byte("PK\x07\x08"), data[:at]...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(zipPath, data[at:], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+// 分割アーカイブの読み込みのテスト
+func TestZipArchiveReaderSplit(t *testing.T) {
+	data, err := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt", "dir2/c.txt").File("dir2/big.bin", bytes.Repeat([]byte("x"), 4096)).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdOffset := int(binary.LittleEndian.Uint32(data[bytes.LastIndex(data, []byte("PK\x05\x06"))+16:]))
+
+	tests := []struct {
+		name string
+		at   int
+	}{
+		{name: "データの途中で分割", at: 100},
+		{name: "セントラルディレクトリの途中で分割", at: cdOffset + 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			zipPath := writeTestSplitZip(t, dir, data, tt.at)
+			for _, p := range []string{zipPath, filepath.Join(dir, "x.z01")} {
+				entries, err := (ZipArchiveReader{}).ReadEntries(p)
+				if err != nil {
+					t.Fatalf("unexpected error for %s: %v", filepath.Base(p), err)
+				}
+				if len(entries) != 4 {
+					t.Errorf("expected 4 entries, got %+v", entries)
+				}
+			}
+		})
+	}
+
+	t.Run("途中のボリュームが無い", func(t *testing.T) {
+		dir := t.TempDir()
+		zipPath := writeTestSplitZip(t, dir, data, 100)
+		if err := os.Remove(filepath.Join(dir, "x.z01")); err != nil {
+			t.Fatal(err)
+		}
+		_, err := (ZipArchiveReader{}).ReadEntries(zipPath)
+		if err == nil || !strings.Contains(err.Error(), "x.z01 was not found") {
+			t.Errorf("expected missing volume error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema は履歴データベースのスキーマです。既に存在する場合は何もしません。
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	archive     TEXT    NOT NULL,
+	run_at      TEXT    NOT NULL,
+	threshold   INTEGER NOT NULL,
+	total_files INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS folder_counts (
+	run_id      INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+	folder_path TEXT    NOT NULL,
+	file_count  INTEGER NOT NULL,
+	PRIMARY KEY (run_id, folder_path)
+);
+CREATE INDEX IF NOT EXISTS idx_folder_counts_path ON folder_counts(folder_path);
+`
+
+// RunRecord は1回の集計実行の情報です。
+type RunRecord struct {
+	Archive    string
+	RunAt      time.Time
+	Threshold  int
+	TotalFiles int
+}
+
+// SQLiteStore は集計結果をSQLiteデータベースに蓄積します。
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore はデータベースを開き、スキーマが無ければ作成します。
+func OpenSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Close はデータベースを閉じます。
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// SaveRun は実行記録とフォルダごとの件数を1つのトランザクションで登録し、実行IDを返します。
+func (s *SQLiteStore) SaveRun(run RunRecord, results []FolderCount) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"INSERT INTO runs (archive, run_at, threshold, total_files) VALUES (?, ?, ?, ?)",
+		run.Archive, run.RunAt.Format(time.RFC3339), run.Threshold, run.TotalFiles,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert run: %w", err)
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO folder_counts (run_id, folder_path, file_count) VALUES (?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, r := range results {
+		if _, err := stmt.Exec(runID, r.Path, r.Count); err != nil {
+			return 0, fmt.Errorf("failed to insert folder count: %w", err)
+		}
+	}
+	return runID, tx.Commit()
+}
+
+// RetentionPolicy は履歴データベースに残す実行記録の範囲です。ゼロ値は何も削除しません。
+// 両方を指定した場合は、どちらかの条件で削除対象になった記録を削除します。
+type RetentionPolicy struct {
+	// KeepRuns が正の場合、アーカイブごとに新しい順にこの件数だけ残します。
+	KeepRuns int
+	// KeepDays が正の場合、この日数より古い記録を削除します。
+	KeepDays int
+}
+
+// IsZero は削除条件が指定されていないかを判定します。
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepRuns <= 0 && p.KeepDays <= 0
+}
+
+// Prune は保持条件から外れた実行記録とフォルダごとの件数を削除し、削除した実行記録の数を返します。
+func (s *SQLiteStore) Prune(policy RetentionPolicy, now time.Time) (int, error) {
+	if policy.IsZero() {
+		return 0, nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, archive, run_at FROM runs ORDER BY archive, run_at DESC, id DESC")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query runs: %w", err)
+	}
+	var ids []int64
+	cutoff := now.AddDate(0, 0, -policy.KeepDays)
+	seen := make(map[string]int)
+	for rows.Next() {
+		var (
+			id      int64
+			archive string
+			runAt   string
+		)
+		if err := rows.Scan(&id, &archive, &runAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		seen[archive]++
+		if policy.KeepRuns > 0 && seen[archive] > policy.KeepRuns {
+			ids = append(ids, id)
+			continue
+		}
+		if policy.KeepDays > 0 {
+			t, err := time.Parse(time.RFC3339, runAt)
+			if err == nil && t.Before(cutoff) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	// 外部キー制約は既定で無効のため、フォルダごとの件数も明示的に削除する
+	for _, id := range ids {
+		if _, err := tx.Exec("DELETE FROM folder_counts WHERE run_id = ?", id); err != nil {
+			return 0, fmt.Errorf("failed to delete folder counts: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM runs WHERE id = ?", id); err != nil {
+			return 0, fmt.Errorf("failed to delete run: %w", err)
+		}
+	}
+	return len(ids), tx.Commit()
+}
+
+// runPrune は prune コマンドを実行します。
+func runPrune(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "prune", "prune -sqlite <path> [-keep-runs N] [-keep-days D]")
+	dbPath := fs.String("sqlite", "", "履歴のSQLiteデータベースのパス (必須)")
+	keepRuns := fs.Int("keep-runs", 0, "アーカイブごとに残す新しい実行記録の件数")
+	keepDays := fs.Int("keep-days", 0, "残す実行記録の日数")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dbPath == "" {
+		return usageError(fs, "sqlite path is required")
+	}
+	policy := RetentionPolicy{KeepRuns: *keepRuns, KeepDays: *keepDays}
+	if policy.IsZero() {
+		return usageError(fs, "-keep-runs or -keep-days is required")
+	}
+
+	store, err := OpenSQLiteStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	n, err := store.Prune(policy, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to prune: %w", err)
+	}
+	env.Logger.Info("古い実行記録を削除しました", slog.String("sqlitePath", *dbPath), slog.Int("deletedRuns", n))
+	return nil
+}
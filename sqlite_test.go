@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// SQLiteStore のテスト (スキーマ作成と複数回の追記)
+func TestSQLiteStoreSaveRun(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	results := []FolderCount{
+		{Path: "dir1", Count: 2},
+		{Path: "(Root)", Count: 1},
+	}
+
+	for i := 0; i < 2; i++ {
+		store, err := OpenSQLiteStore(dbPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		run := RunRecord{Archive: "a.zip", RunAt: time.Now(), Threshold: 1, TotalFiles: 3}
+		runID, err := store.SaveRun(run, results)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if runID != int64(i+1) {
+			t.Errorf("expected run id %d, got %d", i+1, runID)
+		}
+		store.Close()
+	}
+
+	store, err := OpenSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	var count int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM folder_counts WHERE folder_path = 'dir1'").Scan(&count); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rows, got %d", count)
+	}
+}
+
+// SQLiteStore.Prune のテスト (件数と日数による保持)
+func TestSQLiteStorePrune(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	setup := func(t *testing.T) *SQLiteStore {
+		store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "history.db"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		for _, r := range []struct {
+			archive string
+			daysAgo int
+		}{
+			{"a.zip", 40}, {"a.zip", 20}, {"a.zip", 1}, {"b.zip", 50},
+		} {
+			run := RunRecord{Archive: r.archive, RunAt: now.AddDate(0, 0, -r.daysAgo), Threshold: 1, TotalFiles: 1}
+			if _, err := store.SaveRun(run, []FolderCount{{Path: "dir1", Count: 1}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return store
+	}
+	countRows := func(t *testing.T, store *SQLiteStore, table string) int {
+		var n int
+		if err := store.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return n
+	}
+
+	tests := []struct {
+		name      string
+		policy    RetentionPolicy
+		deleted   int
+		remaining int
+	}{
+		{name: "条件なし", policy: RetentionPolicy{}, deleted: 0, remaining: 4},
+		{name: "アーカイブごとに1件", policy: RetentionPolicy{KeepRuns: 1}, deleted: 2, remaining: 2},
+		{name: "30日", policy: RetentionPolicy{KeepDays: 30}, deleted: 2, remaining: 2},
+		{name: "両方", policy: RetentionPolicy{KeepRuns: 2, KeepDays: 10}, deleted: 3, remaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := setup(t)
+			deleted, err := store.Prune(tt.policy, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if deleted != tt.deleted {
+				t.Errorf("expected %d deleted runs, got %d", tt.deleted, deleted)
+			}
+			if n := countRows(t, store, "runs"); n != tt.remaining {
+				t.Errorf("expected %d runs, got %d", tt.remaining, n)
+			}
+			if n := countRows(t, store, "folder_counts"); n != tt.remaining {
+				t.Errorf("expected %d folder counts, got %d", tt.remaining, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// specialModes はデバイスなど、通常のファイルとして集計しないエントリの種類のビットです。
+const specialModes = fs.ModeDevice | fs.ModeCharDevice | fs.ModeNamedPipe | fs.ModeSocket | fs.ModeIrregular
+
+// entryKind はシンボリックリンクと特殊なエントリの種類名を返します。通常のファイルとディレクトリは空文字列です。(純粋関数)
+func entryKind(e FileEntry) string {
+	switch m := e.Mode; {
+	case m&fs.ModeSymlink != 0:
+		return "symlink"
+	case m&fs.ModeCharDevice != 0:
+		return "character device"
+	case m&fs.ModeDevice != 0:
+		return "device"
+	case m&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case m&fs.ModeSocket != 0:
+		return "socket"
+	case m&fs.ModeIrregular != 0:
+		return "irregular"
+	}
+	return ""
+}
+
+// SpecialEntry はシンボリックリンクまたは特殊なエントリです。
+type SpecialEntry struct {
+	Name string
+	// Folder は集計結果と同じ集計キーのフォルダパスです。
+	Folder string
+	// Kind は "symlink"、"device" などの種類名です。
+	Kind string
+}
+
+// FindSpecialEntries はシンボリックリンクと特殊なエントリを返します。(純粋関数)
+// 見落としを防ぐため、集計の絞り込みの条件にかかわらずすべてのエントリを対象にします。
+func FindSpecialEntries(entries []FileEntry, opts AggregateOptions) []SpecialEntry {
+	var special []SpecialEntry
+	for _, e := range entries {
+		if e.IsDir {
+			continue
+		}
+		if kind := entryKind(e); kind != "" {
+			special = append(special, SpecialEntry{Name: e.Name, Folder: FolderKey(e.Name, opts), Kind: kind})
+		}
+	}
+	return special
+}
+
+// countSpecialEntries はシンボリックリンクとそれ以外の特殊なエントリの数を返します。(純粋関数)
+func countSpecialEntries(special []SpecialEntry) (symlinks, others int) {
+	for _, s := range special {
+		if s.Kind == "symlink" {
+			symlinks++
+		} else {
+			others++
+		}
+	}
+	return symlinks, others
+}
+
+// SpecialEntryFindings はシンボリックリンクと特殊なエントリを検出事項として返します。(純粋関数)
+// report が true の場合はエントリごとに WARN、それ以外は件数をまとめた1件の INFO とします。
+// followSymlinks が true の場合、シンボリックリンクはファイル数に含めているため件数のまとめから除きます。
+func SpecialEntryFindings(special []SpecialEntry, report, followSymlinks bool) []Finding {
+	if report {
+		findings := make([]Finding, 0, len(special))
+		for _, s := range special {
+			findings = append(findings, Finding{
+				Severity: SeverityWarn,
+				Rule:     RuleSymlink,
+				Folder:   s.Folder,
+				Message:  s.Kind + ": " + s.Name,
+			})
+		}
+		return findings
+	}
+	symlinks, others := countSpecialEntries(special)
+	if followSymlinks {
+		symlinks = 0
+	}
+	if symlinks+others == 0 {
+		return nil
+	}
+	return []Finding{{
+		Severity: SeverityInfo,
+		Rule:     RuleSymlink,
+		Message:  fmt.Sprintf("%d symlink(s) and %d special entries are not counted as files", symlinks, others),
+	}}
+}
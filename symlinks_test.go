@@ -0,0 +1,81 @@
+package main
+
+import (
+	"archive/zip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// シンボリックリンクと特殊なエントリの読み込みと集計のテスト
+func TestSpecialEntries(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, e := range []struct {
+		name string
+		mode fs.FileMode
+	}{
+		{"a/file.txt", 0o644},
+		{"a/link", fs.ModeSymlink | 0o777},
+		{"a/fifo", fs.ModeNamedPipe | 0o644},
+		{"b/link", fs.ModeSymlink | 0o777},
+	} {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Store}
+		h.SetMode(e.mode)
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("target"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	entries, err := ZipArchiveReader{}.ReadEntries(zipPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	special := FindSpecialEntries(entries, AggregateOptions{})
+	want := []SpecialEntry{
+		{Name: "a/link", Folder: "a", Kind: "symlink"},
+		{Name: "a/fifo", Folder: "a", Kind: "named pipe"},
+		{Name: "b/link", Folder: "b", Kind: "symlink"},
+	}
+	if !reflect.DeepEqual(special, want) {
+		t.Fatalf("expected %+v, got %+v", want, special)
+	}
+
+	tests := []struct {
+		name     string
+		follow   bool
+		expected []FolderCount
+		findings int
+	}{
+		{name: "既定ではファイル数に含めない", follow: false, expected: []FolderCount{{Path: "a", Count: 1}}, findings: 1},
+		{name: "リンクをファイルとして集計", follow: true, expected: []FolderCount{{Path: "a", Count: 2}, {Path: "b", Count: 1}}, findings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _ := Aggregate(entries, AggregateOptions{Filter: EntryFilter{Symlinks: tt.follow}})
+			if !reflect.DeepEqual(results, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, results)
+			}
+			findings := SpecialEntryFindings(special, false, tt.follow)
+			if len(findings) != tt.findings || findings[0].Severity != SeverityInfo {
+				t.Errorf("unexpected findings: %+v", findings)
+			}
+		})
+	}
+
+	if findings := SpecialEntryFindings(special, true, false); len(findings) != 3 || findings[0].Message != "symlink: a/link" {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ulikunitz/xz"
+)
+
+// 単一ファイルの圧縮形式とtarのシグネチャです。
+var (
+	gzipSignature  = []byte{0x1f, 0x8b}
+	bzip2Signature = []byte("BZh")
+	xzSignature    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	// tarMagic は ustar 形式 (POSIX と GNU) のヘッダの257バイト目からの識別子です。
+	tarMagic       = []byte("ustar")
+	tarMagicOffset = 257
+)
+
+// isTarHeader はtarのヘッダブロックの先頭かを判定します。(純粋関数)
+func isTarHeader(head []byte) bool {
+	return len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic)
+}
+
+// TarArchiveReader はtarアーカイブ (.tar) 内のエントリを一覧する実装です。
+// tarは圧縮しないため、CompressedSize は Size と同じ値になります。
+type TarArchiveReader struct {
+	// Decoder はUTF-8でないエントリ名の復号方法です。nil の場合は名前をそのまま使います。
+	Decoder NameDecoder
+}
+
+func (t TarArchiveReader) ReadEntries(tarPath string) ([]FileEntry, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar: %w", err)
+	}
+	defer f.Close()
+	return t.read(f)
+}
+
+// read はストリームからtarのヘッダを順に読み、エントリを返します。内容は読み飛ばします。
+func (t TarArchiveReader) read(r io.Reader) ([]FileEntry, error) {
+	tr := tar.NewReader(r)
+	var entries []FileEntry
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		// PAXのグローバルヘッダはエントリではない
+		if h.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		name := h.Name
+		if !utf8.ValidString(name) && t.Decoder != nil {
+			if decoded, confidence := t.Decoder.Decode([]byte(name)); confidence > ConfidenceNone {
+				name = decoded
+			}
+		}
+		isDir := h.Typeflag == tar.TypeDir
+		if isDir && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		var size uint64
+		if h.Typeflag == tar.TypeReg || h.Typeflag == tar.TypeRegA {
+			size = uint64(h.Size)
+		}
+		entries = append(entries, FileEntry{
+			Name:           name,
+			RawName:        h.Name,
+			IsDir:          isDir,
+			Modified:       h.ModTime,
+			Size:           size,
+			CompressedSize: size,
+			Mode:           h.FileInfo().Mode(),
+			Owner:          &UnixOwner{UID: uint32(h.Uid), GID: uint32(h.Gid)},
+			Accessed:       h.AccessTime,
+		})
+	}
+}
+
+// CompressedArchiveReader はgzip、bzip2またはxzで圧縮された単一のファイル (.gz, .tgz, .bz2, .xz など) を読み込む実装です。
+// 展開した内容がtarであれば TarArchiveReader で読み込み、それ以外は圧縮前のファイル1件のエントリとして返します。
+type CompressedArchiveReader struct {
+	Tar TarArchiveReader
+}
+
+func (c CompressedArchiveReader) ReadEntries(archivePath string) ([]FileEntry, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed file: %w", err)
+	}
+
+	single := FileEntry{Name: singleFileName(archivePath), Modified: info.ModTime(), CompressedSize: uint64(info.Size())}
+	var r io.Reader
+	switch detectArchiveKind(f) {
+	case "gzip":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer zr.Close()
+		// gzipのヘッダに圧縮前の名前と日時が記録されていれば使う
+		if zr.Name != "" {
+			single.Name = filepath.Base(filepath.FromSlash(zr.Name))
+		}
+		if !zr.ModTime.IsZero() {
+			single.Modified = zr.ModTime
+		}
+		r = zr
+	case "bzip2":
+		r = bzip2.NewReader(f)
+	case "xz":
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open xz: %w", err)
+		}
+		r = xr
+	default:
+		return nil, errors.New("failed to open compressed file: unknown format")
+	}
+
+	br := bufio.NewReaderSize(r, tarMagicOffset+len(tarMagic))
+	head, _ := br.Peek(tarMagicOffset + len(tarMagic))
+	if isTarHeader(head) {
+		return c.Tar.read(br)
+	}
+	n, err := io.Copy(io.Discard, br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	single.RawName = single.Name
+	single.Size = uint64(n)
+	return []FileEntry{single}, nil
+}
+
+// singleFileName は圧縮されたファイルのパスから、圧縮前のファイル名を求めます。(純粋関数)
+// ".tgz"、".tbz2" と ".txz" は ".tar" に、".gz"、".bz2" と ".xz" などは拡張子を除いた名前にします。
+func singleFileName(archivePath string) string {
+	base := filepath.Base(archivePath)
+	ext := filepath.Ext(base)
+	switch strings.ToLower(ext) {
+	case ".tgz", ".tbz", ".tbz2", ".txz":
+		return strings.TrimSuffix(base, ext) + ".tar"
+	case ".gz", ".gzip", ".bz2", ".bzip2", ".xz":
+		if name := strings.TrimSuffix(base, ext); name != "" {
+			return name
+		}
+	}
+	return base
+}
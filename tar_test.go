@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// buildTestTar は指定した名前のファイルとフォルダを持つtarを作成します。名前が "/" で終わるものはフォルダです。
+func buildTestTar(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		h := &tar.Header{Name: name, Mode: 0o644, Typeflag: tar.TypeReg, Size: 3}
+		if name[len(name)-1] == '/' {
+			h.Typeflag, h.Size, h.Mode = tar.TypeDir, 0, 0o755
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size > 0 {
+			tw.Write([]byte("abc"))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gzipBytes はデータをgzipで圧縮します。name はヘッダに記録する圧縮前の名前です。
+func gzipBytes(t *testing.T, data []byte, name string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = name
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// xzBytes はデータをxzで圧縮します。
+func xzBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testBzip2Hello は "hello\n" をbzip2で圧縮したデータです。
+var testBzip2Hello = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xc1, 0xc0,
+	0x80, 0xe2, 0x00, 0x00, 0x01, 0x41, 0x00, 0x00, 0x10, 0x02, 0x44, 0xa0,
+	0x00, 0x30, 0xcd, 0x00, 0xc3, 0x46, 0x29, 0x97, 0x17, 0x72, 0x45, 0x38,
+	0x50, 0x90, 0xc1, 0xc0, 0x80, 0xe2,
+}
+
+// AutoArchiveReader でのtarと圧縮された単一ファイルの読み込みのテスト
+func TestAutoArchiveReaderTarAndCompressed(t *testing.T) {
+	tarData := buildTestTar(t, "dir1/", "dir1/a.txt", "dir1/b.txt", "c.txt")
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		expected []string
+		size     uint64
+	}{
+		{name: "tar", file: "delivery.tar", data: tarData, expected: []string{"dir1/", "dir1/a.txt", "dir1/b.txt", "c.txt"}, size: 3},
+		{name: "tar.gz", file: "delivery.tgz", data: gzipBytes(t, tarData, ""), expected: []string{"dir1/", "dir1/a.txt", "dir1/b.txt", "c.txt"}, size: 3},
+		{name: "gzipの単一ファイル", file: "report.csv.gz", data: gzipBytes(t, []byte("a,b\n"), ""), expected: []string{"report.csv"}, size: 4},
+		{name: "gzipのヘッダの名前を使う", file: "upload.gz", data: gzipBytes(t, []byte("a,b\n"), "original.csv"), expected: []string{"original.csv"}, size: 4},
+		{name: "bzip2の単一ファイル", file: "hello.txt.bz2", data: testBzip2Hello, expected: []string{"hello.txt"}, size: 6},
+		{name: "tar.xz", file: "delivery.txz", data: xzBytes(t, tarData), expected: []string{"dir1/", "dir1/a.txt", "dir1/b.txt", "c.txt"}, size: 3},
+		{name: "xzの単一ファイル", file: "report.csv.xz", data: xzBytes(t, []byte("a,b\n")), expected: []string{"report.csv"}, size: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := (AutoArchiveReader{}).ReadEntries(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("expected %d entries, got %+v", len(tt.expected), entries)
+			}
+			for i, name := range tt.expected {
+				if entries[i].Name != name {
+					t.Errorf("entry %d: expected %q, got %q", i, name, entries[i].Name)
+				}
+			}
+			if last := entries[len(entries)-1]; last.Size != tt.size {
+				t.Errorf("expected size %d, got %d", tt.size, last.Size)
+			}
+		})
+	}
+
+	t.Run("壊れたxz", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "delivery.tar.xz")
+		if err := os.WriteFile(path, append(append([]byte{}, xzSignature...), make([]byte, 32)...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (AutoArchiveReader{}).ReadEntries(path); err == nil || !strings.Contains(err.Error(), "failed to open xz") {
+			t.Errorf("expected xz error, got %v", err)
+		}
+	})
+}
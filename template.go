@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// templateFuncs はテンプレートから使える関数です。
+// 固定長の出力やWiki記法のために、文字数での桁揃えと文字列の置換を提供します。
+var templateFuncs = template.FuncMap{
+	"formatSize": FormatSize,
+	"padLeft":    padLeft,
+	"padRight":   padRight,
+	"replace":    strings.ReplaceAll,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"add":        func(a, b int) int { return a + b },
+}
+
+// padLeft は文字数が width になるよう左側を空白で埋めます。width を超える場合は末尾を切り詰めます。(純粋関数)
+func padLeft(width int, v any) string {
+	s := fmt.Sprint(v)
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return string([]rune(s)[:width])
+}
+
+// padRight は文字数が width になるよう右側を空白で埋めます。width を超える場合は末尾を切り詰めます。(純粋関数)
+func padRight(width int, v any) string {
+	s := fmt.Sprint(v)
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return string([]rune(s)[:width])
+}
+
+// newTemplateWriter はテンプレートファイルで結果を書き出す ResultWriter を作成します。
+// テンプレートには *Report が渡されるため、フォルダ、合計、アーカイブの情報、検出事項をすべて参照できます。
+func newTemplateWriter(opts WriterOptions) (ResultWriter, error) {
+	if opts.Template == "" {
+		return nil, fmt.Errorf("template format requires a template file")
+	}
+	tmpl, err := template.New(filepath.Base(opts.Template)).Funcs(templateFuncs).ParseFiles(opts.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return ResultWriterFunc(func(w io.Writer, report *Report) error {
+		return tmpl.Execute(w, report)
+	}), nil
+}
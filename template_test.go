@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// template 形式の出力のテスト
+func TestTemplateWriter(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	report := &Report{Archive: "delivery.zip", TotalFiles: 3, Folders: []FolderCount{
+		{Path: `dir1\sub`, Count: 2, Stats: &FolderStats{TotalSize: 2048}},
+		{Path: "(Root)", Count: 1, Stats: &FolderStats{}},
+	}}
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "Confluenceの表",
+			template: "||Folder||Files||\n{{range .Folders}}|{{replace .Path `\\` `/`}}|{{.Count}}|\n{{end}}",
+			expected: "||Folder||Files||\n|dir1/sub|2|\n|(Root)|1|\n",
+		},
+		{
+			name:     "固定長",
+			template: "{{range .Folders}}{{padRight 10 .Path}}{{padLeft 6 .Count}}{{padLeft 8 (formatSize .Stats.TotalSize)}}\n{{end}}TOTAL{{padLeft 5 .TotalFiles}}",
+			expected: "dir1\\sub       2  2.0 KB\n(Root)         1     0 B\nTOTAL    3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewResultWriter("template", WriterOptions{Template: write(tt.name+".tmpl", tt.template)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			buf := new(bytes.Buffer)
+			if err := w.Write(buf, report); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	t.Run("テンプレートの誤り", func(t *testing.T) {
+		if _, err := NewResultWriter("template", WriterOptions{}); err == nil {
+			t.Error("expected error without template file")
+		}
+		if _, err := NewResultWriter("template", WriterOptions{Template: write("bad.tmpl", "{{.Folders")}); err == nil {
+			t.Error("expected error for invalid template")
+		}
+	})
+
+	t.Run("-template の指定で画面に出力", func(t *testing.T) {
+		zipPath := obuziptest.New().Files("dir1/a.txt", "c.txt").WriteFile(t)
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-template", write("cli.tmpl", "{{.Archive}}:{{.TotalFiles}}")}, stdout, stderr)
+		if code != 0 || stdout.String() != zipPath+":2" {
+			t.Errorf("unexpected result: %d, %q (stderr: %s)", code, stdout.String(), stderr.String())
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// ThresholdSuggestion はフォルダごとのファイル数の分布と、そこから求めたしきい値の提案です。
+type ThresholdSuggestion struct {
+	Folders int
+	Min     int
+	Q1      float64
+	Median  float64
+	Q3      float64
+	P90     float64
+	P99     float64
+	Max     int
+	// Suggested は外れ値とみなす最小のファイル数です。
+	Suggested int
+	// Outliers は Suggested 以上のファイル数を持つフォルダの数です。
+	Outliers int
+}
+
+// SuggestThreshold はフォルダごとのファイル数の分布から外れ値の下限をしきい値として提案します。(純粋関数)
+// ファイル数の分布は裾が長いため、対数をとった値に Tukey の方法 (Q3 + k × IQR) を適用します。
+func SuggestThreshold(counts []int, k float64) ThresholdSuggestion {
+	if len(counts) == 0 {
+		return ThresholdSuggestion{}
+	}
+	sorted := append([]int(nil), counts...)
+	sort.Ints(sorted)
+
+	s := ThresholdSuggestion{
+		Folders: len(sorted),
+		Min:     sorted[0],
+		Q1:      quantile(sorted, 0.25),
+		Median:  quantile(sorted, 0.5),
+		Q3:      quantile(sorted, 0.75),
+		P90:     quantile(sorted, 0.9),
+		P99:     quantile(sorted, 0.99),
+		Max:     sorted[len(sorted)-1],
+	}
+
+	logQ1 := math.Log(math.Max(s.Q1, 1))
+	logQ3 := math.Log(math.Max(s.Q3, 1))
+	fence := math.Exp(logQ3 + k*(logQ3-logQ1))
+	s.Suggested = int(math.Floor(fence)) + 1
+
+	idx := sort.SearchInts(sorted, s.Suggested)
+	s.Outliers = len(sorted) - idx
+	return s
+}
+
+// quantile は昇順にソートされた値の分位点を線形補間で求めます。(純粋関数)
+func quantile(sorted []int, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	frac := pos - float64(lo)
+	return float64(sorted[lo])*(1-frac) + float64(sorted[hi])*frac
+}
+
+// WriteThresholdSuggestion はしきい値の提案をプレーンテキストでWriterに出力します。
+func WriteThresholdSuggestion(w io.Writer, s ThresholdSuggestion) error {
+	_, err := fmt.Fprintf(w, `Folders   : %d
+Min       : %d
+Q1        : %.1f
+Median    : %.1f
+Q3        : %.1f
+P90       : %.1f
+P99       : %.1f
+Max       : %d
+
+Suggested threshold: %d (%d folder(s) at or above)
+`, s.Folders, s.Min, s.Q1, s.Median, s.Q3, s.P90, s.P99, s.Max, s.Suggested, s.Outliers)
+	return err
+}
+
+// runAnalyzeThreshold は analyze-threshold コマンドを実行します。
+func runAnalyzeThreshold(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "analyze-threshold", "analyze-threshold -zip <path> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	zipPath := fs.String("zip", "", "対象のZIPファイルのパスまたはURL (必須、\"-\" で標準入力)")
+	k := fs.Float64("k", 1.5, "外れ値の判定に使う四分位範囲の倍率")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *zipPath == "" {
+		return usageError(fs, "zip path is required")
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	// 分布は全フォルダを対象に求める
+	opts.Threshold = 0
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	results, _, err := app.Analyze(*zipPath, opts)
+	if err != nil {
+		return err
+	}
+
+	counts := make([]int, len(results))
+	for i, r := range results {
+		counts[i] = r.Count
+	}
+	return WriteThresholdSuggestion(env.Stdout, SuggestThreshold(counts, *k))
+}
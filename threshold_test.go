@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// SuggestThreshold のテスト
+func TestSuggestThreshold(t *testing.T) {
+	t.Run("外れ値を含む分布", func(t *testing.T) {
+		counts := []int{10, 12, 9, 11, 10, 13, 8, 10, 5000, 12000}
+		s := SuggestThreshold(counts, 1.5)
+		if s.Folders != 10 || s.Min != 8 || s.Max != 12000 || s.Median != 10.5 {
+			t.Errorf("unexpected distribution: %+v", s)
+		}
+		if s.Suggested <= 13 || s.Suggested > 5000 {
+			t.Errorf("expected suggestion between 13 and 5000, got %d", s.Suggested)
+		}
+		if s.Outliers != 2 {
+			t.Errorf("expected 2 outliers, got %d", s.Outliers)
+		}
+	})
+
+	t.Run("空の入力", func(t *testing.T) {
+		if s := SuggestThreshold(nil, 1.5); s != (ThresholdSuggestion{}) {
+			t.Errorf("expected zero value, got %+v", s)
+		}
+	})
+}
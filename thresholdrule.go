@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ThresholdRule はパターンに一致するフォルダに適用するしきい値です。
+type ThresholdRule struct {
+	// Pattern はスラッシュ区切りのフォルダパスと比較するパターン (MatchPathPattern の構文) です。
+	Pattern   string
+	Threshold int
+}
+
+// ParseThresholdRule は "images/**=50000" 形式のルールを解析します。(純粋関数)
+func ParseThresholdRule(s string) (ThresholdRule, error) {
+	pattern, value, found := strings.Cut(s, "=")
+	pattern = strings.Trim(strings.ReplaceAll(strings.TrimSpace(pattern), "\\", "/"), "/")
+	if !found || pattern == "" {
+		return ThresholdRule{}, fmt.Errorf("invalid threshold rule: %s (use <pattern>=<threshold>)", s)
+	}
+	if err := validatePathPattern(pattern); err != nil {
+		return ThresholdRule{}, fmt.Errorf("invalid threshold rule: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return ThresholdRule{}, fmt.Errorf("invalid threshold rule: %s (threshold must be a non-negative integer)", s)
+	}
+	return ThresholdRule{Pattern: pattern, Threshold: n}, nil
+}
+
+// FolderThreshold は集計キーのフォルダパスに適用するしきい値を返します。(純粋関数)
+// 最初に一致したルールのしきい値を使い、一致するルールがない場合は Threshold を返します。
+// ルールは正規化、ロールアップ、階層範囲を適用した後のフォルダパスと比較します。ルートは空のパスとして扱います。
+func (o AggregateOptions) FolderThreshold(folder string) int {
+	if len(o.ThresholdRules) == 0 {
+		return o.Threshold
+	}
+	dir := ""
+	if folder != "(Root)" {
+		dir = folder
+	}
+	for _, rule := range o.ThresholdRules {
+		if MatchPathPattern(rule.Pattern, dir) {
+			return rule.Threshold
+		}
+	}
+	return o.Threshold
+}
+
+// meetsThreshold はフォルダのファイル数がしきい値を満たすかを判定します。(純粋関数)
+// FolderThreshold 以上で、ThresholdPercent が指定されている場合は total に対する割合もそれ以上のときに満たします。
+func (o AggregateOptions) meetsThreshold(folder string, count, total int) bool {
+	if count < o.FolderThreshold(folder) {
+		return false
+	}
+	return o.ThresholdPercent <= 0 || percent(count, total) >= o.ThresholdPercent
+}
+
+// thresholdRuleFlag は繰り返し指定できる -rule フラグです。
+type thresholdRuleFlag []ThresholdRule
+
+func (f *thresholdRuleFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, r := range *f {
+		parts[i] = fmt.Sprintf("%s=%d", r.Pattern, r.Threshold)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *thresholdRuleFlag) Set(s string) error {
+	r, err := ParseThresholdRule(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, r)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ParseThresholdRule のテスト
+func TestParseThresholdRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ThresholdRule
+		wantErr  bool
+	}{
+		{name: "通常のルール", input: "images/**=50000", expected: ThresholdRule{Pattern: "images/**", Threshold: 50000}},
+		{name: "区切り文字と空白の正規化", input: " \\docs\\** = 1000", expected: ThresholdRule{Pattern: "docs/**", Threshold: 1000}},
+		{name: "しきい値0", input: "tmp=0", expected: ThresholdRule{Pattern: "tmp", Threshold: 0}},
+		{name: "= がない", input: "images/**", wantErr: true},
+		{name: "パターンが空", input: "=10", wantErr: true},
+		{name: "負のしきい値", input: "a=-1", wantErr: true},
+		{name: "数値でないしきい値", input: "a=many", wantErr: true},
+		{name: "不正なパターン", input: "[a=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseThresholdRule(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("expected %+v, got %+v, %v", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+// パターンごとのしきい値を使った集計のテスト
+func TestAggregateThresholdRules(t *testing.T) {
+	var entries []FileEntry
+	add := func(dir string, n int) {
+		for i := 0; i < n; i++ {
+			entries = append(entries, FileEntry{Name: dir + "/" + string(rune('a'+i)) + ".txt"})
+		}
+	}
+	add("images/2023", 5)
+	add("images", 2)
+	add("docs/spec", 3)
+	add("other", 3)
+	entries = append(entries, FileEntry{Name: "root.txt"})
+
+	opts := AggregateOptions{
+		Threshold: 3,
+		ThresholdRules: []ThresholdRule{
+			{Pattern: "images/**", Threshold: 6},
+			{Pattern: "docs/**", Threshold: 1},
+			{Pattern: "**", Threshold: 100},
+		},
+	}
+	results, total := Aggregate(entries, opts)
+	expected := []FolderCount{{Path: "docs/spec", Count: 3}}
+	if !reflect.DeepEqual(results, expected) || total != 14 {
+		t.Errorf("expected %+v, got %+v (%d)", expected, results, total)
+	}
+
+	// 一致しないフォルダは全体のしきい値を使う
+	opts.ThresholdRules = opts.ThresholdRules[:2]
+	results, _ = Aggregate(entries, opts)
+	expected = []FolderCount{{Path: "docs/spec", Count: 3}, {Path: "other", Count: 3}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+	if got := opts.FolderThreshold("(Root)"); got != 3 {
+		t.Errorf("expected root threshold 3, got %d", got)
+	}
+}
+
+// 全体に対する割合のしきい値を使った集計のテスト
+func TestAggregateThresholdPercent(t *testing.T) {
+	var entries []FileEntry
+	add := func(dir string, n int) {
+		for i := 0; i < n; i++ {
+			entries = append(entries, FileEntry{Name: dir + "/" + string(rune('a'+i)) + ".txt"})
+		}
+	}
+	add("a", 10)
+	add("b", 6)
+	add("c", 4)
+
+	tests := []struct {
+		name     string
+		opts     AggregateOptions
+		expected []string
+	}{
+		{name: "割合だけ", opts: AggregateOptions{ThresholdPercent: 25}, expected: []string{"a", "b"}},
+		{name: "境界の割合を含む", opts: AggregateOptions{ThresholdPercent: 20}, expected: []string{"a", "b", "c"}},
+		{name: "件数と割合の両方を満たす", opts: AggregateOptions{Threshold: 7, ThresholdPercent: 20}, expected: []string{"a"}},
+		{name: "ルールは件数のしきい値だけを置き換える", opts: AggregateOptions{
+			Threshold:        100,
+			ThresholdRules:   []ThresholdRule{{Pattern: "c", Threshold: 1}},
+			ThresholdPercent: 10,
+		}, expected: []string{"c"}},
+		{name: "割合0は無効", opts: AggregateOptions{Threshold: 5}, expected: []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, total := Aggregate(entries, tt.opts)
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Path)
+			}
+			if !reflect.DeepEqual(got, tt.expected) || total != 20 {
+				t.Errorf("expected %v, got %v (%d)", tt.expected, got, total)
+			}
+		})
+	}
+}
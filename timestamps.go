@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// ZIPの拡張フィールドのうち、日時を記録するもののヘッダIDです。
+const (
+	extraNTFS         = 0x000a
+	extraExtendedTime = 0x5455
+)
+
+// エントリの日時の記録元です。
+const (
+	// TimeSourceDOS は拡張フィールドが無く、MS-DOS形式 (2秒単位、タイムゾーンなし) の日時を使ったことを表します。
+	TimeSourceDOS = "dos"
+	// TimeSourceNTFS はNTFSの拡張フィールド (0x000a、100ナノ秒単位) の日時を表します。
+	TimeSourceNTFS = "ntfs"
+	// TimeSourceExtended は拡張タイムスタンプ (0x5455、UTCの秒単位) の日時を表します。
+	TimeSourceExtended = "extended"
+	// TimeSourceUnix はUnixの拡張フィールド (0x5855、0x000d) の日時を表します。
+	TimeSourceUnix = "unix"
+)
+
+// windowsEpoch はNTFSの日時の起点です。
+var windowsEpoch = time.Date(1601, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// EntryTimes は拡張フィールドに記録された日時です。記録されていない日時はゼロ値です。
+type EntryTimes struct {
+	Modified time.Time
+	Accessed time.Time
+	Created  time.Time
+	// Source は Modified の記録元です。更新日時を記録した拡張フィールドが無い場合は TimeSourceDOS です。
+	Source string
+}
+
+// parseEntryTimes は拡張フィールドから日時を読み取ります。(純粋関数)
+// 更新日時を記録したフィールドが複数ある場合は、archive/zip と同じく最後のものを使います。
+// 中央ディレクトリの拡張タイムスタンプはフラグに関わらず更新日時だけを記録するため、長さのある日時だけを読みます。
+func parseEntryTimes(extra []byte) EntryTimes {
+	times := EntryTimes{Source: TimeSourceDOS}
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		data := extra[4 : 4+size]
+		extra = extra[4+size:]
+		switch id {
+		case extraNTFS:
+			parseNTFSTimes(data, &times)
+		case extraExtendedTime:
+			if len(data) < 5 || data[0]&0x1 == 0 {
+				continue
+			}
+			flags, data := data[0], data[1:]
+			times.Modified, times.Source = unixTime(data), TimeSourceExtended
+			data = data[4:]
+			if flags&0x2 != 0 && len(data) >= 4 {
+				times.Accessed = unixTime(data)
+				data = data[4:]
+			}
+			if flags&0x4 != 0 && len(data) >= 4 {
+				times.Created = unixTime(data)
+			}
+		case extraInfoZipUnix, extraPKWAREUnix:
+			if len(data) < 8 {
+				continue
+			}
+			times.Accessed = unixTime(data)
+			times.Modified, times.Source = unixTime(data[4:]), TimeSourceUnix
+		}
+	}
+	return times
+}
+
+// parseNTFSTimes はNTFSの拡張フィールドの属性1 (更新、アクセス、作成の日時) を読み取ります。
+func parseNTFSTimes(data []byte, times *EntryTimes) {
+	if len(data) < 4 {
+		return
+	}
+	// 先頭の4バイトは予約領域
+	data = data[4:]
+	for len(data) >= 4 {
+		tag := binary.LittleEndian.Uint16(data)
+		size := int(binary.LittleEndian.Uint16(data[2:]))
+		if len(data) < 4+size {
+			return
+		}
+		attr := data[4 : 4+size]
+		data = data[4+size:]
+		if tag != 1 || size != 24 {
+			continue
+		}
+		times.Modified = ntfsTime(attr)
+		times.Accessed = ntfsTime(attr[8:])
+		times.Created = ntfsTime(attr[16:])
+		times.Source = TimeSourceNTFS
+	}
+}
+
+// unixTime はリトルエンディアンの32ビットのUnix時刻を読み取ります。(純粋関数)
+func unixTime(b []byte) time.Time {
+	return time.Unix(int64(binary.LittleEndian.Uint32(b)), 0).UTC()
+}
+
+// ntfsTime はリトルエンディアンの64ビットのNTFSの日時 (1601年からの100ナノ秒単位) を読み取ります。0の場合はゼロ値を返します。(純粋関数)
+func ntfsTime(b []byte) time.Time {
+	ticks := binary.LittleEndian.Uint64(b)
+	if ticks == 0 {
+		return time.Time{}
+	}
+	return time.Unix(windowsEpoch.Unix()+int64(ticks/1e7), int64(ticks%1e7)*100).UTC()
+}
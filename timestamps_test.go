@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// parseEntryTimes のテスト
+func TestParseEntryTimes(t *testing.T) {
+	modified := time.Date(2024, 4, 1, 9, 30, 15, 123456700, time.UTC)
+	created := time.Date(2023, 12, 24, 18, 0, 0, 0, time.UTC)
+
+	ntfs := func(mtime, atime, ctime time.Time) []byte {
+		b := []byte{0x0a, 0x00, 32, 0, 0, 0, 0, 0, 1, 0, 24, 0}
+		for _, ts := range []time.Time{mtime, atime, ctime} {
+			b = binary.LittleEndian.AppendUint64(b, uint64((ts.Unix()-windowsEpoch.Unix())*1e7+int64(ts.Nanosecond())/100))
+		}
+		return b
+	}
+	extended := func(flags byte, times ...time.Time) []byte {
+		b := []byte{0x55, 0x54, byte(1 + 4*len(times)), 0, flags}
+		for _, ts := range times {
+			b = binary.LittleEndian.AppendUint32(b, uint32(ts.Unix()))
+		}
+		return b
+	}
+
+	tests := []struct {
+		name     string
+		extra    []byte
+		expected EntryTimes
+	}{
+		{name: "拡張フィールドなし", extra: nil, expected: EntryTimes{Source: TimeSourceDOS}},
+		{
+			name:     "NTFS",
+			extra:    ntfs(modified, modified, created),
+			expected: EntryTimes{Modified: modified, Accessed: modified, Created: created, Source: TimeSourceNTFS},
+		},
+		{
+			name:     "拡張タイムスタンプ (中央ディレクトリ)",
+			extra:    extended(0x7, modified),
+			expected: EntryTimes{Modified: modified.Truncate(time.Second), Source: TimeSourceExtended},
+		},
+		{
+			name:     "拡張タイムスタンプ (すべての日時)",
+			extra:    extended(0x5, modified, created),
+			expected: EntryTimes{Modified: modified.Truncate(time.Second), Created: created, Source: TimeSourceExtended},
+		},
+		{
+			name:     "最後のフィールドを優先",
+			extra:    append(ntfs(created, created, created), extended(0x1, modified)...),
+			expected: EntryTimes{Modified: modified.Truncate(time.Second), Accessed: created, Created: created, Source: TimeSourceExtended},
+		},
+		{name: "更新日時のフラグなし", extra: extended(0x4, created), expected: EntryTimes{Source: TimeSourceDOS}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseEntryTimes(tt.extra)
+			if !got.Modified.Equal(tt.expected.Modified) || !got.Accessed.Equal(tt.expected.Accessed) ||
+				!got.Created.Equal(tt.expected.Created) || got.Source != tt.expected.Source {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrendRun は推移に含める1回の実行記録です。
+type TrendRun struct {
+	ID    int64
+	RunAt time.Time
+}
+
+// FolderTrend はフォルダごとの実行記録ごとのファイル数の推移です。
+type FolderTrend struct {
+	Folder string
+	// Counts は実行記録ごとのファイル数で、TrendRun と同じ順です。記録されていない実行では0です。
+	Counts []int
+	// MaxChange は連続する実行記録の間のファイル数の変化率 (パーセント) のうち、絶対値が最大のものです。
+	// 0件から増えた場合は100とします。
+	MaxChange float64
+	// Flagged は MaxChange の絶対値が指定された変化率を超えたかを表します。
+	Flagged bool
+}
+
+// LoadTrend はアーカイブの新しい実行記録を最大 limit 件、古い順に返し、フォルダごとのファイル数を実行IDごとに返します。
+// archive が空の場合は、最も新しい実行記録のアーカイブを対象にします。
+func (s *SQLiteStore) LoadTrend(archive string, limit int) (string, []TrendRun, map[int64]map[string]int, error) {
+	if archive == "" {
+		err := s.db.QueryRow("SELECT archive FROM runs ORDER BY run_at DESC, id DESC LIMIT 1").Scan(&archive)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, nil, errors.New("no runs recorded")
+		}
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to query runs: %w", err)
+		}
+	}
+	rows, err := s.db.Query("SELECT id, run_at FROM runs WHERE archive = ? ORDER BY run_at DESC, id DESC LIMIT ?", archive, limit)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	var runs []TrendRun
+	for rows.Next() {
+		var (
+			run   TrendRun
+			runAt string
+		)
+		if err := rows.Scan(&run.ID, &runAt); err != nil {
+			rows.Close()
+			return "", nil, nil, err
+		}
+		run.RunAt, _ = time.Parse(time.RFC3339, runAt)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return "", nil, nil, err
+	}
+	rows.Close()
+	if len(runs) == 0 {
+		return "", nil, nil, fmt.Errorf("no runs recorded for %s", archive)
+	}
+	// 新しい順に取得したため、古い順に並べ直す
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+
+	counts := make(map[int64]map[string]int, len(runs))
+	for _, run := range runs {
+		folders := make(map[string]int)
+		rows, err := s.db.Query("SELECT folder_path, file_count FROM folder_counts WHERE run_id = ?", run.ID)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to query folder counts: %w", err)
+		}
+		for rows.Next() {
+			var (
+				folder string
+				count  int
+			)
+			if err := rows.Scan(&folder, &count); err != nil {
+				rows.Close()
+				return "", nil, nil, err
+			}
+			folders[folder] = count
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return "", nil, nil, err
+		}
+		rows.Close()
+		counts[run.ID] = folders
+	}
+	return archive, runs, counts, nil
+}
+
+// ComputeTrend は実行記録ごとのファイル数からフォルダごとの推移を求めます。(純粋関数)
+// 変化率の絶対値が changePercent を超えたフォルダに印を付けます。
+// 結果は変化率の絶対値の降順、同じ場合はパスの昇順でソートされます。
+func ComputeTrend(runs []TrendRun, counts map[int64]map[string]int, changePercent float64) []FolderTrend {
+	trends := make(map[string]*FolderTrend)
+	for i, run := range runs {
+		for folder, n := range counts[run.ID] {
+			t, ok := trends[folder]
+			if !ok {
+				t = &FolderTrend{Folder: folder, Counts: make([]int, len(runs))}
+				trends[folder] = t
+			}
+			t.Counts[i] = n
+		}
+	}
+
+	result := make([]FolderTrend, 0, len(trends))
+	for _, t := range trends {
+		for i := 1; i < len(t.Counts); i++ {
+			if change := changeRate(t.Counts[i-1], t.Counts[i]); math.Abs(change) > math.Abs(t.MaxChange) {
+				t.MaxChange = change
+			}
+		}
+		t.Flagged = math.Abs(t.MaxChange) > changePercent
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ci, cj := math.Abs(result[i].MaxChange), math.Abs(result[j].MaxChange)
+		if ci != cj {
+			return ci > cj
+		}
+		return result[i].Folder < result[j].Folder
+	})
+	return result
+}
+
+// changeRate は prev から cur へのファイル数の変化率 (パーセント) を返します。0件から増えた場合は100です。(純粋関数)
+func changeRate(prev, cur int) float64 {
+	if prev == 0 {
+		if cur == 0 {
+			return 0
+		}
+		return 100
+	}
+	return float64(cur-prev) * 100 / float64(prev)
+}
+
+// sparkBlocks はスパークラインの高さの文字です。
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline はファイル数の推移を最大値を基準とした高さの文字列にします。(純粋関数)
+func sparkline(counts []int) string {
+	maxCount := 0
+	for _, n := range counts {
+		maxCount = max(maxCount, n)
+	}
+	var b strings.Builder
+	for _, n := range counts {
+		level := 0
+		if maxCount > 0 {
+			level = n * (len(sparkBlocks) - 1) / maxCount
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// WriteTrendText はフォルダごとの推移をプレーンテキストで出力します。
+// 変化率が大きいフォルダの行は先頭に "*" を付け、推移をスパークラインで示します。
+func WriteTrendText(w io.Writer, archive string, runs []TrendRun, trends []FolderTrend, lang Lang) error {
+	if _, err := fmt.Fprintf(w, "\n%s: %s (%d %s)\n\n", lang.T("Archive"), archive, len(runs), lang.T("runs")); err != nil {
+		return err
+	}
+	header := fmt.Sprintf("  %-50s", lang.T("Folder Path"))
+	for _, run := range runs {
+		header += fmt.Sprintf(" | %10s", run.RunAt.Format("2006-01-02"))
+	}
+	header += fmt.Sprintf(" | %10s | %s", lang.T("Max Change"), lang.T("Trend"))
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, strings.Repeat("-", len(header)))
+	for _, t := range trends {
+		mark := " "
+		if t.Flagged {
+			mark = "*"
+		}
+		line := fmt.Sprintf("%s %-50s", mark, t.Folder)
+		for _, n := range t.Counts {
+			line += fmt.Sprintf(" | %10d", n)
+		}
+		line += fmt.Sprintf(" | %+9.1f%% | %s", t.MaxChange, sparkline(t.Counts))
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTrendCSV はフォルダごとの推移をCSV形式で出力します。実行記録ごとの列の見出しは実行日時です。
+func WriteTrendCSV(w io.Writer, _ string, runs []TrendRun, trends []FolderTrend, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		header := []string{"Folder Path"}
+		for _, run := range runs {
+			header = append(header, run.RunAt.Format(time.RFC3339))
+		}
+		header = append(header, "Max Change", "Flagged")
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		for _, t := range trends {
+			record := []string{t.Folder}
+			for _, n := range t.Counts {
+				record = append(record, strconv.Itoa(n))
+			}
+			record = append(record, strconv.FormatFloat(t.MaxChange, 'f', 1, 64), strconv.FormatBool(t.Flagged))
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectTrendWriter は出力形式名に対応する推移の書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectTrendWriter(format string, opts WriterOptions) (func(io.Writer, string, []TrendRun, []FolderTrend) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, archive string, runs []TrendRun, trends []FolderTrend) error {
+			return WriteTrendText(w, archive, runs, trends, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, archive string, runs []TrendRun, trends []FolderTrend) error {
+			return WriteTrendCSV(w, archive, runs, trends, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// runTrend は trend コマンドを実行します。
+func runTrend(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "trend", "trend -sqlite <path> [-archive <path>] [-runs N] [-change-pct P]")
+	dbPath := fs.String("sqlite", "", "count -sqlite で記録した履歴のSQLiteデータベースのパス (必須)")
+	archive := fs.String("archive", "", "対象のアーカイブのパス (省略時は最も新しい実行記録のアーカイブ)")
+	limit := fs.Int("runs", 10, "推移に含める新しい実行記録の件数")
+	changePct := fs.Float64("change-pct", 20, "実行記録の間のファイル数の変化率 (パーセント) がこの値を超えるフォルダに印を付ける")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dbPath == "" {
+		return usageError(fs, "sqlite path is required")
+	}
+	if *limit <= 0 {
+		return usageError(fs, "runs must be positive")
+	}
+	if *changePct < 0 {
+		return usageError(fs, "change percentage must not be negative")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectTrendWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	store, err := OpenSQLiteStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	name, runs, counts, err := store.LoadTrend(*archive, *limit)
+	if err != nil {
+		return err
+	}
+	return write(env.Stdout, name, runs, ComputeTrend(runs, counts, *changePct))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ComputeTrend のテスト
+func TestComputeTrend(t *testing.T) {
+	runs := []TrendRun{{ID: 1}, {ID: 2}, {ID: 3}}
+	counts := map[int64]map[string]int{
+		1: {"stable": 100, "grown": 10, "removed": 5},
+		2: {"stable": 105, "grown": 10},
+		3: {"stable": 110, "grown": 30, "added": 2},
+	}
+	expected := []FolderTrend{
+		{Folder: "grown", Counts: []int{10, 10, 30}, MaxChange: 200, Flagged: true},
+		{Folder: "added", Counts: []int{0, 0, 2}, MaxChange: 100, Flagged: true},
+		{Folder: "removed", Counts: []int{5, 0, 0}, MaxChange: -100, Flagged: true},
+		{Folder: "stable", Counts: []int{100, 105, 110}, MaxChange: 5, Flagged: false},
+	}
+	if got := ComputeTrend(runs, counts, 20); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+// trend コマンドのテスト (履歴データベースからの読み込み)
+func TestRunTrend(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	store, err := OpenSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 4, 1, 2, 0, 0, 0, time.UTC)
+	for i, n := range []int{10, 11, 30} {
+		run := RunRecord{Archive: "a.zip", RunAt: start.AddDate(0, 0, i), Threshold: 1, TotalFiles: n}
+		if _, err := store.SaveRun(run, []FolderCount{{Path: "dir1", Count: n}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.SaveRun(RunRecord{Archive: "b.zip", RunAt: start, Threshold: 1, TotalFiles: 1}, []FolderCount{{Path: "other", Count: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if code := runCLI([]string{"trend", "-sqlite", dbPath, "-runs", "2"}, stdout, stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"Archive: a.zip (2 runs)", "2024-04-02 | 2024-04-03", "* dir1", "+172.7% | ▃█"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q, got: %s", want, out)
+		}
+	}
+	if strings.Contains(out, "other") {
+		t.Errorf("output contains another archive: %s", out)
+	}
+
+	t.Run("CSVの文字コードと区切り文字", func(t *testing.T) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"trend", "-sqlite", dbPath, "-runs", "2", "-format", "csv", "-csv-encoding", "utf8", "-csv-delimiter", "tab"}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		expected := "Folder Path\t2024-04-02T02:00:00Z\t2024-04-03T02:00:00Z\tMax Change\tFlagged\ndir1\t11\t30\t172.7\ttrue\n"
+		if stdout.String() != expected {
+			t.Errorf("expected %q, got %q", expected, stdout.String())
+		}
+	})
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// FolderNode はフォルダツリーの1つのフォルダです。
+type FolderNode struct {
+	Name string
+	// Path は集計キーと同じ "/" 区切りのフォルダパスです。ルートは "(Root)" です。
+	Path string
+	// Files はフォルダ直下のファイル数、Total は配下のすべてのファイル数です。
+	Files    int
+	Total    int
+	Children []*FolderNode
+	parent   *FolderNode
+	expanded bool
+}
+
+// BuildFolderTree はエントリを集計キーのフォルダパスごとのツリーにまとめます。(純粋関数)
+// 集計キーは FolderKey で求めるため、ロールアップや階層範囲は count と同じように適用されます。
+func BuildFolderTree(entries []FileEntry, opts AggregateOptions) *FolderNode {
+	root := &FolderNode{Name: "(Root)", Path: "(Root)", expanded: true}
+	index := map[string]*FolderNode{"": root}
+	var node func(p string) *FolderNode
+	node = func(p string) *FolderNode {
+		if n, ok := index[p]; ok {
+			return n
+		}
+		parentPath, name := "", p
+		if i := strings.LastIndexByte(p, '/'); i >= 0 {
+			parentPath, name = p[:i], p[i+1:]
+		}
+		parent := node(parentPath)
+		n := &FolderNode{Name: name, Path: p, parent: parent}
+		parent.Children = append(parent.Children, n)
+		index[p] = n
+		return n
+	}
+	for _, e := range entries {
+		if e.IsDir || !opts.Filter.Match(e) {
+			continue
+		}
+		key := FolderKey(e.Name, opts)
+		if key == "(Root)" {
+			key = ""
+		}
+		n := node(key)
+		n.Files++
+		for ; n != nil; n = n.parent {
+			n.Total++
+		}
+	}
+	return root
+}
+
+// treeSort はツリーの並び順です。
+type treeSort int
+
+const (
+	sortByTotal treeSort = iota
+	sortByFiles
+	sortByName
+)
+
+var treeSortNames = map[treeSort]string{sortByTotal: "配下の件数", sortByFiles: "直下の件数", sortByName: "名前"}
+
+// less は並び順に従って a を b より前に表示するかを返します。件数が同じ場合は名前の昇順です。
+func (s treeSort) less(a, b *FolderNode) bool {
+	switch s {
+	case sortByTotal:
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+	case sortByFiles:
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+	}
+	return a.Path < b.Path
+}
+
+// treeRow は画面に表示する1行です。
+type treeRow struct {
+	node  *FolderNode
+	depth int
+}
+
+// entriesLoadedMsg はエントリの読み込みが終わったことを表すメッセージです。
+type entriesLoadedMsg struct {
+	entries []FileEntry
+	err     error
+}
+
+// treeModel はフォルダツリーを操作する画面の状態です。
+type treeModel struct {
+	archive string
+	opts    AggregateOptions
+	load    tea.Cmd
+	// paths は表示するフォルダパスとルートフォルダの表記です。
+	paths FolderFormat
+
+	root    *FolderNode
+	err     error
+	rows    []treeRow
+	cursor  int
+	offset  int
+	height  int
+	sort    treeSort
+	query   string
+	editing bool
+}
+
+// newTreeModel は load でエントリを読み込み、フォルダツリーを表示するモデルを作成します。
+func newTreeModel(archive string, opts AggregateOptions, load func() ([]FileEntry, error)) treeModel {
+	return treeModel{
+		archive: archive,
+		opts:    opts,
+		height:  20,
+		load: func() tea.Msg {
+			entries, err := load()
+			return entriesLoadedMsg{entries: entries, err: err}
+		},
+	}
+}
+
+func (m treeModel) Init() tea.Cmd {
+	return m.load
+}
+
+func (m treeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case entriesLoadedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		m.root = BuildFolderTree(msg.entries, m.opts)
+		m.refresh()
+	case tea.WindowSizeMsg:
+		m.height = max(msg.Height-4, 1)
+		m.scroll()
+	case tea.KeyMsg:
+		if m.editing {
+			return m.updateQuery(msg)
+		}
+		return m.updateKey(msg)
+	}
+	return m, nil
+}
+
+// updateQuery は検索語の入力中のキー操作を処理します。
+func (m treeModel) updateQuery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEnter:
+		m.editing = false
+	case tea.KeyEsc:
+		m.editing, m.query = false, ""
+	case tea.KeyBackspace:
+		if r := []rune(m.query); len(r) > 0 {
+			m.query = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.query += string(msg.Runes)
+	}
+	m.cursor = 0
+	m.refresh()
+	return m, nil
+}
+
+// updateKey はツリーの操作のキーを処理します。
+func (m treeModel) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor--
+	case "down", "j":
+		m.cursor++
+	case "pgup":
+		m.cursor -= m.height
+	case "pgdown":
+		m.cursor += m.height
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = len(m.rows) - 1
+	case "right", "l", "enter":
+		if n := m.current(); n != nil && len(n.Children) > 0 {
+			n.expanded = true
+		}
+	case "left", "h":
+		if n := m.current(); n != nil {
+			if n.expanded && n != m.root {
+				n.expanded = false
+			} else if n.parent != nil && m.query == "" {
+				m.cursor = m.indexOf(n.parent)
+			}
+		}
+	case "s":
+		m.sort = (m.sort + 1) % 3
+	case "/":
+		m.editing = true
+	case "esc":
+		m.query = ""
+	}
+	m.refresh()
+	return m, nil
+}
+
+// current はカーソル位置のフォルダを返します。
+func (m treeModel) current() *FolderNode {
+	if m.cursor < 0 || m.cursor >= len(m.rows) {
+		return nil
+	}
+	return m.rows[m.cursor].node
+}
+
+func (m treeModel) indexOf(n *FolderNode) int {
+	for i, r := range m.rows {
+		if r.node == n {
+			return i
+		}
+	}
+	return 0
+}
+
+// refresh は展開状態、並び順、検索語から表示する行を作り直し、カーソルを範囲内に収めます。
+func (m *treeModel) refresh() {
+	if m.root == nil {
+		return
+	}
+	selected := m.current()
+	m.rows = m.rows[:0]
+	if m.query != "" {
+		// 検索中はパスに検索語を含むフォルダを階層を問わず並べる。区切り文字はどちらの表記でも一致させる
+		q := strings.ToLower(strings.ReplaceAll(m.query, "\\", "/"))
+		var walk func(n *FolderNode)
+		walk = func(n *FolderNode) {
+			if strings.Contains(strings.ToLower(n.Path), q) {
+				m.rows = append(m.rows, treeRow{node: n})
+			}
+			for _, c := range n.Children {
+				walk(c)
+			}
+		}
+		walk(m.root)
+		sort.SliceStable(m.rows, func(i, j int) bool { return m.sort.less(m.rows[i].node, m.rows[j].node) })
+	} else {
+		var walk func(n *FolderNode, depth int)
+		walk = func(n *FolderNode, depth int) {
+			m.rows = append(m.rows, treeRow{node: n, depth: depth})
+			if !n.expanded {
+				return
+			}
+			children := append([]*FolderNode(nil), n.Children...)
+			sort.Slice(children, func(i, j int) bool { return m.sort.less(children[i], children[j]) })
+			for _, c := range children {
+				walk(c, depth+1)
+			}
+		}
+		walk(m.root, 0)
+		if selected != nil && !m.editing {
+			if i := m.indexOf(selected); m.rows[i].node == selected {
+				m.cursor = i
+			}
+		}
+	}
+	m.cursor = min(max(m.cursor, 0), max(len(m.rows)-1, 0))
+	m.scroll()
+}
+
+// scroll はカーソルが画面内に入るよう表示位置を調整します。
+func (m *treeModel) scroll() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+}
+
+func (m treeModel) View() string {
+	if m.root == nil {
+		if m.err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%s を読み込んでいます…\n", m.archive)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  ファイル数: %d  並び順: %s\n", m.archive, m.root.Total, treeSortNames[m.sort])
+	fmt.Fprintf(&b, "%-60s %10s %10s\n", "Folder", "Total", "Files")
+	for i := m.offset; i < min(m.offset+m.height, len(m.rows)); i++ {
+		r := m.rows[i]
+		marker := "  "
+		if len(r.node.Children) > 0 && m.query == "" {
+			marker = "▸ "
+			if r.node.expanded {
+				marker = "▾ "
+			}
+		}
+		label := r.node.Name
+		if m.query != "" || r.node.parent == nil {
+			label = m.paths.Folder(r.node.Path)
+		}
+		line := fmt.Sprintf("%s%s%s", strings.Repeat("  ", r.depth), marker, label)
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+		fmt.Fprintf(&b, "%s%-58s %10d %10d\n", cursor, line, r.node.Total, r.node.Files)
+	}
+	switch {
+	case m.editing:
+		fmt.Fprintf(&b, "\n検索: %s_", m.query)
+	case m.query != "":
+		fmt.Fprintf(&b, "\n検索: %s (%d件)  esc: 解除  q: 終了", m.query, len(m.rows))
+	default:
+		b.WriteString("\n↑↓: 移動  →/enter: 展開  ←: 折りたたみ  s: 並び順  /: 検索  q: 終了")
+	}
+	return b.String()
+}
+
+// runTUI はアーカイブを読み込み、フォルダツリーを操作できる画面を表示します。
+// 画面の表示を崩さないよう、ログは出力しません。
+func runTUI(reader ArchiveReader, cfg AppConfig, in io.Reader, out io.Writer) error {
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: slog.New(slog.DiscardHandler)}
+	model := newTreeModel(cfg.ZipPath, opts, func() ([]FileEntry, error) {
+		entries, _, err := app.readEntries(cfg.ZipPath, false)
+		return entries, err
+	})
+	model.paths = cfg.Paths
+	if in == nil {
+		in = os.Stdin
+	}
+	final, err := tea.NewProgram(model, tea.WithAltScreen(), tea.WithInput(in), tea.WithOutput(out)).Run()
+	if err != nil {
+		return fmt.Errorf("failed to run tui: %w", err)
+	}
+	return final.(treeModel).err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var treeTestEntries = []FileEntry{
+	{Name: "a/x/1.txt"}, {Name: "a/x/2.txt"}, {Name: "a/3.txt"},
+	{Name: "b/4.txt"}, {Name: "b/5.txt"}, {Name: "b/6.txt"}, {Name: "b/7.txt"},
+	{Name: "root.txt"}, {Name: "a/", IsDir: true},
+}
+
+// BuildFolderTree のテスト
+func TestBuildFolderTree(t *testing.T) {
+	root := BuildFolderTree(treeTestEntries, AggregateOptions{})
+	if root.Total != 8 || root.Files != 1 || len(root.Children) != 2 {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+	a := root.Children[0]
+	if a.Path != "a" || a.Total != 3 || a.Files != 1 || a.Children[0].Path != "a/x" || a.Children[0].Files != 2 {
+		t.Errorf("unexpected folder a: %+v", a)
+	}
+}
+
+// treeModel のキー操作のテスト
+func TestTreeModel(t *testing.T) {
+	var m tea.Model = newTreeModel("test.zip", AggregateOptions{}, nil)
+	m, _ = m.Update(entriesLoadedMsg{entries: treeTestEntries})
+	keys := func(ks ...string) {
+		for _, k := range ks {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			switch k {
+			case "down":
+				msg = tea.KeyMsg{Type: tea.KeyDown}
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			}
+			m, _ = m.Update(msg)
+		}
+	}
+	paths := func() []string {
+		var p []string
+		for _, r := range m.(treeModel).rows {
+			p = append(p, r.node.Path)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []string
+		cursor   string
+	}{
+		{name: "配下の件数の降順", expected: []string{"(Root)", "b", "a"}, cursor: "(Root)"},
+		{name: "展開", keys: []string{"down", "down", "enter"}, expected: []string{"(Root)", "b", "a", "a/x"}, cursor: "a"},
+		{name: "名前順でもカーソルのフォルダを保つ", keys: []string{"s", "s"}, expected: []string{"(Root)", "a", "a/x", "b"}, cursor: "a"},
+		{name: "親へ移動", keys: []string{"down", "h", "h"}, expected: []string{"(Root)", "a", "b"}, cursor: "a"},
+		{name: "検索", keys: []string{"/", "x", "enter"}, expected: []string{"a/x"}, cursor: "a/x"},
+		{name: "検索の解除", keys: []string{"esc"}, expected: []string{"(Root)", "a", "b"}, cursor: "(Root)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys(tt.keys...)
+			if got := paths(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected rows %v, got %v", tt.expected, got)
+			}
+			if got := m.(treeModel).current().Path; got != tt.cursor {
+				t.Errorf("expected cursor on %s, got %s", tt.cursor, got)
+			}
+		})
+	}
+
+	if view := m.View(); !strings.Contains(view, "ファイル数: 8") {
+		t.Errorf("unexpected view: %s", view)
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// updatePublicKey はリリースの署名を検証するEd25519の公開鍵 (base64) です。
+// ビルド時に -ldflags "-X main.updatePublicKey=..." で埋め込みます。埋め込んだ鍵は設定ファイルやフラグで置き換えられません。
+var updatePublicKey = ""
+
+// リリースに含めるファイルの名前です。
+const (
+	// checksumsAssetName はアセットごとのSHA-256を "<16進数>  <ファイル名>" の行で並べたファイルです。
+	checksumsAssetName = "checksums.txt"
+	// signatureAssetName はリリースのタグと checksums.txt のEd25519の署名 (base64) です。
+	// 署名する内容は releaseSignedPayload で作ります。
+	signatureAssetName = checksumsAssetName + ".sig"
+)
+
+// maxUpdateDownload はダウンロードする実行ファイルの大きさの上限です。
+const maxUpdateDownload = 512 << 20
+
+// UpdateConfig は設定ファイルの update の内容です。
+//
+//	{"update": {"url": "https://api.github.com/repos/<owner>/<repo>/releases/latest"}}
+//
+// 署名を検証する公開鍵は、ミラーを書き換えられる人が差し替えられないよう設定ファイルでは指定できません。
+type UpdateConfig struct {
+	// URL は最新のリリースをGitHubのリリースと同じ形式のJSONで返すURLです。社内のミラーも指定できます。
+	URL string `json:"url"`
+}
+
+// Release はGitHubのリリースのうち、更新に使う項目です。
+type Release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset はリリースに添付されたファイルです。
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// asset は名前の一致するファイルを返します。
+func (r *Release) asset(name string) (ReleaseAsset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return ReleaseAsset{}, false
+}
+
+// Updater はリリースのURLから新しい実行ファイルを取得し、検証します。
+type Updater struct {
+	URL string
+	// PublicKey が nil でない場合はタグと checksums.txt の署名を必ず検証します。nil の場合はチェックサムだけを検証します。
+	PublicKey ed25519.PublicKey
+	// Client はリクエストに使うクライアントです。nil の場合は http.DefaultClient を使います。
+	Client *http.Client
+	// GOOS と GOARCH は取得する実行ファイルの対象です。空の場合は実行中の環境を使います。
+	GOOS, GOARCH string
+}
+
+// updateAssetName は対象の環境の実行ファイルのアセット名を返します。(純粋関数)
+func updateAssetName(goos, goarch string) string {
+	name := fmt.Sprintf("obuzipcount_%s_%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// assetName は取得する実行ファイルのアセット名です。
+func (u Updater) assetName() string {
+	goos, goarch := u.GOOS, u.GOARCH
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return updateAssetName(goos, goarch)
+}
+
+// Latest は最新のリリースの情報を取得します。
+func (u Updater) Latest() (*Release, error) {
+	data, err := u.get(u.URL, 4<<20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check release: %w", err)
+	}
+	var rel Release
+	if err := json.Unmarshal(data, &rel); err != nil {
+		return nil, fmt.Errorf("failed to check release: %w", err)
+	}
+	if rel.TagName == "" {
+		return nil, errors.New("failed to check release: tag_name is missing")
+	}
+	return &rel, nil
+}
+
+// Download はリリースの実行ファイルを取得し、署名とチェックサムを検証して内容を返します。
+func (u Updater) Download(rel *Release) ([]byte, error) {
+	name := u.assetName()
+	binAsset, ok := rel.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no asset %s", rel.TagName, name)
+	}
+	sumAsset, ok := rel.asset(checksumsAssetName)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no asset %s", rel.TagName, checksumsAssetName)
+	}
+	sums, err := u.get(sumAsset.URL, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", checksumsAssetName, err)
+	}
+	if u.PublicKey != nil {
+		sigAsset, ok := rel.asset(signatureAssetName)
+		if !ok {
+			return nil, fmt.Errorf("release %s has no asset %s", rel.TagName, signatureAssetName)
+		}
+		sig, err := u.get(sigAsset.URL, 4<<10)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", signatureAssetName, err)
+		}
+		if err := verifyReleaseSignature(u.PublicKey, releaseSignedPayload(rel.TagName, sums), sig); err != nil {
+			return nil, err
+		}
+	}
+	want, ok := parseChecksums(sums)[name]
+	if !ok {
+		return nil, fmt.Errorf("%s has no checksum for %s", checksumsAssetName, name)
+	}
+	bin, err := u.get(binAsset.URL, maxUpdateDownload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if got := sha256.Sum256(bin); hex.EncodeToString(got[:]) != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %x, want %s", name, got, want)
+	}
+	return bin, nil
+}
+
+// get はURLの内容を limit バイトまで取得し、2xx以外の応答をエラーにします。
+func (u Updater) get(url string, limit int64) ([]byte, error) {
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream, application/json")
+	req.Header.Set("User-Agent", "obuzipcount/"+version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %s", url, FormatSize(uint64(limit)))
+	}
+	return data, nil
+}
+
+// parseChecksums は checksums.txt をファイル名とSHA-256 (16進数の小文字) の組にします。(純粋関数)
+// sha256sum の出力と同じ "<16進数>  <ファイル名>" の行を読み、バイナリモードの印 "*" は除きます。
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// releaseSignedPayload はリリースの署名の対象を返します。(純粋関数)
+// 古いリリースの checksums.txt と署名を別のタグとして配布されないよう、1行目にタグを含めます。
+func releaseSignedPayload(tag string, sums []byte) []byte {
+	return append([]byte("obuzipcount "+tag+"\n"), sums...)
+}
+
+// verifyReleaseSignature は payload が公開鍵に対応する秘密鍵で署名されているかを検証します。
+func verifyReleaseSignature(key ed25519.PublicKey, payload, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("invalid release signature: %w", err)
+	}
+	if !ed25519.Verify(key, payload, raw) {
+		return errors.New("invalid release signature: verification failed")
+	}
+	return nil
+}
+
+// ParsePublicKey はbase64のEd25519の公開鍵を読み込みます。空の場合は nil を返します。
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: must be a base64 Ed25519 key of %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// compareVersions は "v1.2.3" 形式のバージョンを比べ、a が古ければ負、新しければ正、同じなら0を返します。(純粋関数)
+// "-rc1" などの後ろに続く部分があるバージョンは、同じ番号の正式なバージョンより古いものとします。
+// 番号として読めないバージョンは false を返します。
+func compareVersions(a, b string) (int, bool) {
+	pa, sa, okA := parseVersion(a)
+	pb, sb, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case sa == sb:
+		return 0, true
+	case sa == "":
+		return 1, true
+	case sb == "":
+		return -1, true
+	case sa < sb:
+		return -1, true
+	default:
+		return 1, true
+	}
+}
+
+// parseVersion は "v1.2.3-rc1" を番号の列と後ろに続く部分に分けます。(純粋関数)
+func parseVersion(v string) ([]int, string, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, suffix, _ := strings.Cut(v, "-")
+	if v == "" {
+		return nil, "", false
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		parts = append(parts, n)
+	}
+	return parts, suffix, true
+}
+
+// oldExecutableSuffix は置き換える前の実行ファイルの名前に付ける接尾辞です。
+// Windows では実行中のファイルを削除できないため、名前を変えて残し、次の更新で削除します。
+const oldExecutableSuffix = ".old"
+
+// replaceExecutable は exePath の実行ファイルを data に置き換えます。
+// 同じフォルダに書き出してから名前を変えるため、途中で失敗しても元のファイルは残ります。
+func replaceExecutable(exePath string, data []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	oldPath := exePath + oldExecutableSuffix
+	// 前回の更新で残したファイルを削除する (実行中の場合は削除できないため、エラーにしない)
+	os.Remove(oldPath)
+
+	f, err := os.CreateTemp(filepath.Dir(exePath), filepath.Base(exePath)+".new-*")
+	if err != nil {
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	newPath := f.Name()
+	defer os.Remove(newPath)
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(newPath, info.Mode().Perm()|0o111)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+
+	if err := os.Rename(exePath, oldPath); err != nil {
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	if err := os.Rename(newPath, exePath); err != nil {
+		// 元の実行ファイルを戻す
+		os.Rename(oldPath, exePath)
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	// Windows 以外では実行中でも削除できる
+	os.Remove(oldPath)
+	return nil
+}
+
+// loadUpdateConfig は設定ファイルの update を読み込みます。-config を省略し、既定の設定ファイルが無い場合は空の設定を返します。
+func loadUpdateConfig(configPath string) (UpdateConfig, error) {
+	explicit := configPath != ""
+	if !explicit {
+		var err error
+		if configPath, err = defaultConfigPath(); err != nil {
+			return UpdateConfig{}, nil
+		}
+	}
+	cfg, err := LoadConfigFile(configPath)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return UpdateConfig{}, nil
+		}
+		return UpdateConfig{}, err
+	}
+	return cfg.Update, nil
+}
+
+// runUpdate は update コマンドを実行します。
+func runUpdate(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "update", "update [-check] [-force] [-url <url>] [-public-key <base64> | -insecure]")
+	url := fs.String("url", "", "最新のリリースを返すURL (GitHubのリリースAPIの形式。省略時は設定ファイルの update.url)")
+	publicKey := fs.String("public-key", "", "リリースの署名を検証するEd25519の公開鍵 (base64)。ビルド時に鍵を埋め込んでいない場合だけ指定できる")
+	insecure := fs.Bool("insecure", false, "公開鍵が無い場合に、署名を検証せずチェックサムだけで置き換える")
+	check := fs.Bool("check", false, "新しいバージョンがあるかを表示するだけで、置き換えない")
+	force := fs.Bool("force", false, "実行中のバージョンが比べられない場合 (開発版など) も置き換える。古いまたは同じバージョンには置き換えない")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg, err := loadUpdateConfig(fs.Lookup("config").Value.String())
+	if err != nil {
+		return err
+	}
+	if *url == "" {
+		*url = cfg.URL
+	}
+	if *url == "" {
+		return usageError(fs, "release url is required (-url or update.url in the config file)")
+	}
+	keyText := updatePublicKey
+	if *publicKey != "" {
+		if keyText != "" {
+			return usageError(fs, "-public-key cannot override the key embedded at build time")
+		}
+		keyText = *publicKey
+	}
+	key, err := ParsePublicKey(keyText)
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+	if key == nil && !*check {
+		if !*insecure {
+			return usageError(fs, "no release signing key is embedded in this build; use -public-key, or -insecure to verify checksums only")
+		}
+		env.Logger.Warn("公開鍵が無いため、リリースの署名を検証しません")
+	}
+
+	u := Updater{URL: *url, PublicKey: key}
+	rel, err := u.Latest()
+	if err != nil {
+		return err
+	}
+	cmp, ok := compareVersions(version, rel.TagName)
+	upToDate := ok && cmp >= 0
+	fmt.Fprintf(env.Stdout, "Current: %s\nLatest:  %s\n", version, rel.TagName)
+	if *check {
+		msg := "Update available. Run \"obuzipcount update\" to install it."
+		switch {
+		case !ok:
+			msg = "Versions cannot be compared. Run \"obuzipcount update -force\" to install the latest release."
+		case upToDate:
+			msg = "Already up to date."
+		}
+		_, err := fmt.Fprintln(env.Stdout, msg)
+		return err
+	}
+	// 古いリリースへの置き換えは -force でも行わない
+	if upToDate {
+		_, err := fmt.Fprintln(env.Stdout, "Already up to date.")
+		return err
+	}
+	if !ok && !*force {
+		return fmt.Errorf("cannot compare version %s with %s; use -force to install", version, rel.TagName)
+	}
+
+	bin, err := u.Download(rel)
+	if err != nil {
+		return err
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	if err := replaceExecutable(exePath, bin); err != nil {
+		return err
+	}
+	env.Logger.Info("実行ファイルを更新しました", slog.String("path", exePath), slog.String("version", rel.TagName))
+	_, err = fmt.Fprintf(env.Stdout, "Updated to %s.\n", rel.TagName)
+	return err
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// compareVersions のテスト
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+		ok       bool
+	}{
+		{a: "v1.2.3", b: "v1.2.3", expected: 0, ok: true},
+		{a: "v1.2.3", b: "v1.10.0", expected: -1, ok: true},
+		{a: "1.3", b: "v1.2.9", expected: 1, ok: true},
+		{a: "v1.2", b: "v1.2.0", expected: 0, ok: true},
+		{a: "v1.3.0-rc1", b: "v1.3.0", expected: -1, ok: true},
+		{a: "v1.3.0-rc2", b: "v1.3.0-rc1", expected: 1, ok: true},
+		{a: "dev", b: "v1.0.0", ok: false},
+		{a: "v1.x", b: "v1.0.0", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			got, ok := compareVersions(tt.a, tt.b)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("compareVersions(%q, %q) = %d, %v, want %d, %v", tt.a, tt.b, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+// parseChecksums のテスト
+func TestParseChecksums(t *testing.T) {
+	data := []byte("ABCD  obuzipcount_linux_amd64\n1234 *obuzipcount_windows_amd64.exe\n\nbroken line here\n")
+	got := parseChecksums(data)
+	if len(got) != 2 || got["obuzipcount_linux_amd64"] != "abcd" || got["obuzipcount_windows_amd64.exe"] != "1234" {
+		t.Errorf("parseChecksums() = %v", got)
+	}
+}
+
+// releaseServer はリリースの情報とアセットを返すテスト用のサーバです。
+type releaseServer struct {
+	tag    string
+	assets map[string][]byte
+}
+
+func (s *releaseServer) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest" {
+			rel := Release{TagName: s.tag}
+			for name := range s.assets {
+				rel.Assets = append(rel.Assets, ReleaseAsset{Name: name, URL: srv.URL + "/download/" + name})
+			}
+			json.NewEncoder(w).Encode(rel)
+			return
+		}
+		data, ok := s.assets[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newSignedRelease は実行ファイル、チェックサム、署名を含むリリースを作成します。
+func newSignedRelease(t *testing.T, tag string, bin []byte) (*releaseServer, ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := updateAssetName("linux", "amd64")
+	sums := []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256(bin), name))
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, releaseSignedPayload(tag, sums)))
+	return &releaseServer{tag: tag, assets: map[string][]byte{
+		name:               bin,
+		checksumsAssetName: sums,
+		signatureAssetName: []byte(sig + "\n"),
+	}}, pub, priv
+}
+
+// Updater.Download の署名とチェックサムの検証のテスト
+func TestUpdaterDownload(t *testing.T) {
+	bin := []byte("new binary")
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// modify はリリースを変更します。鍵は検証に使う公開鍵です。
+		modify  func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey
+		wantErr string
+	}{
+		{name: "署名とチェックサムが一致", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey { return pub }},
+		{name: "公開鍵が無ければチェックサムだけを検証", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			delete(s.assets, signatureAssetName)
+			return nil
+		}},
+		{name: "改ざんされた実行ファイル", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			s.assets[updateAssetName("linux", "amd64")] = []byte("tampered")
+			return pub
+		}, wantErr: "checksum mismatch"},
+		{name: "別の鍵の署名", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			return otherPub
+		}, wantErr: "invalid release signature"},
+		{name: "書き換えられたチェックサム", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			s.assets[checksumsAssetName] = []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("evil")), updateAssetName("linux", "amd64")))
+			return pub
+		}, wantErr: "invalid release signature"},
+		{name: "古いリリースの署名を別のタグで配布", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			s.tag = "v3.0.0"
+			return pub
+		}, wantErr: "invalid release signature"},
+		{name: "署名の無いリリース", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			delete(s.assets, signatureAssetName)
+			return pub
+		}, wantErr: "has no asset checksums.txt.sig"},
+		{name: "対象の環境の実行ファイルが無い", modify: func(s *releaseServer, pub ed25519.PublicKey) ed25519.PublicKey {
+			delete(s.assets, updateAssetName("linux", "amd64"))
+			return pub
+		}, wantErr: "has no asset obuzipcount_linux_amd64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, pub, _ := newSignedRelease(t, "v2.0.0", bin)
+			key := tt.modify(s, pub)
+			srv := s.start(t)
+			u := Updater{URL: srv.URL + "/latest", PublicKey: key, GOOS: "linux", GOARCH: "amd64"}
+			rel, err := u.Latest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := u.Download(rel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, bin) {
+				t.Fatalf("Download() = %q, %v", got, err)
+			}
+		})
+	}
+}
+
+// replaceExecutable のテスト
+func TestReplaceExecutable(t *testing.T) {
+	dir := t.TempDir()
+	exePath := filepath.Join(dir, "obuzipcount")
+	if err := os.WriteFile(exePath, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// 前回の更新で残ったファイル
+	if err := os.WriteFile(exePath+oldExecutableSuffix, []byte("older"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceExecutable(exePath, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exePath)
+	if err != nil || string(data) != "new" {
+		t.Errorf("executable = %q, %v", data, err)
+	}
+	if info, err := os.Stat(exePath); err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("executable is not executable: %v, %v", info.Mode(), err)
+	}
+	left, _ := os.ReadDir(dir)
+	if len(left) != 1 {
+		t.Errorf("temporary files were left: %v", left)
+	}
+}
+
+// update -check のテスト (実行ファイルは置き換えない)
+func TestRunUpdateCheck(t *testing.T) {
+	s, pub, _ := newSignedRelease(t, "v2.0.0", []byte("new binary"))
+	srv := s.start(t)
+	key := base64.StdEncoding.EncodeToString(pub)
+
+	tests := []struct {
+		name    string
+		current string
+		args    []string
+		// embedded はビルド時に埋め込んだ鍵です。空の場合は -public-key で鍵を指定します。
+		embedded     string
+		noKey        bool
+		expectedCode int
+		expectedOut  string
+	}{
+		{name: "新しいバージョンがある", current: "v1.0.0", args: []string{"-check"}, expectedOut: "Update available"},
+		{name: "最新", current: "v2.0.0", args: []string{"-check"}, expectedOut: "Already up to date"},
+		{name: "比べられないバージョン", current: "dev", args: []string{"-check"}, expectedOut: "cannot be compared"},
+		{name: "最新なら置き換えない", current: "v2.1.0", expectedOut: "Already up to date"},
+		{name: "比べられなければ -force が必要", current: "dev", expectedCode: 1},
+		{name: "-force でも古いバージョンには置き換えない", current: "v2.1.0", args: []string{"-force"}, expectedOut: "Already up to date"},
+		{name: "鍵が無ければ置き換えない", current: "v1.0.0", noKey: true, expectedCode: 2},
+		{name: "鍵が無くても確認はできる", current: "v1.0.0", noKey: true, args: []string{"-check"}, expectedOut: "Update available"},
+		{name: "-insecure ならチェックサムだけで最新か判定", current: "v2.0.0", noKey: true, args: []string{"-insecure"}, expectedOut: "Already up to date"},
+		{name: "埋め込んだ鍵は置き換えられない", current: "v1.0.0", embedded: key, expectedCode: 2},
+		{name: "埋め込んだ鍵で確認", current: "v1.0.0", embedded: key, noKey: true, args: []string{"-check"}, expectedOut: "Update available"},
+		{name: "URLが必要", current: "v1.0.0", args: []string{"-url", "", "-config", filepath.Join(t.TempDir(), "none.json")}, expectedCode: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved, savedKey := version, updatePublicKey
+			version, updatePublicKey = tt.current, tt.embedded
+			t.Cleanup(func() { version, updatePublicKey = saved, savedKey })
+
+			args := []string{"update", "-url", srv.URL + "/latest"}
+			if !tt.noKey {
+				args = append(args, "-public-key", key)
+			}
+			args = append(args, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != tt.expectedCode {
+				t.Fatalf("expected code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationIssue はアーカイブ内のエントリで見つかった構造上の問題です。
+type ValidationIssue struct {
+	Name    string
+	Problem string
+	// Detail は問題の詳細です。空の場合は出力しません。
+	Detail string
+}
+
+// Windows で展開できない名前の問題の種類です。
+const (
+	ProblemControlChar      = "control character"
+	ProblemTrailingSpaceDot = "trailing space or dot"
+	ProblemReservedName     = "reserved name"
+	ProblemLongPath         = "path too long"
+)
+
+// nameAnomalyProblems は名前の問題の種類を、レポートに出力する順に並べたものです。
+var nameAnomalyProblems = []string{ProblemControlChar, ProblemTrailingSpaceDot, ProblemReservedName, ProblemLongPath}
+
+// maxWindowsPath は Windows で拡張パスを使わずに扱えるパスの文字数の上限です。
+const maxWindowsPath = 260
+
+// windowsReservedNames は拡張子の有無によらず Windows でファイル名に使えないデバイス名です。
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ValidateEntries はエントリ名の問題を検査します。(純粋関数)
+// 1つのエントリに複数の問題がある場合は、問題ごとに1件として返します。
+func ValidateEntries(entries []FileEntry) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, e := range entries {
+		switch {
+		case e.Name == "":
+			issues = append(issues, ValidationIssue{Name: e.Name, Problem: "empty name"})
+			continue
+		case !utf8.ValidString(e.Name):
+			issues = append(issues, ValidationIssue{Name: e.Name, Problem: "undecodable name"})
+		}
+		for _, problem := range nameAnomalies(e.Name) {
+			issues = append(issues, ValidationIssue{Name: e.Name, Problem: problem})
+		}
+	}
+	return issues
+}
+
+// nameAnomalies は Windows で展開できない名前の問題を返します。(純粋関数)
+// 末尾の空白・ピリオドと予約名はパスの要素ごとに検査します。
+func nameAnomalies(name string) []string {
+	name = strings.TrimSuffix(name, "/")
+	var problems []string
+	if strings.IndexFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7F }) >= 0 {
+		problems = append(problems, ProblemControlChar)
+	}
+	trailing, reserved := false, false
+	for _, c := range strings.Split(name, "/") {
+		if c == "" || c == "." || c == ".." {
+			continue
+		}
+		if strings.HasSuffix(c, " ") || strings.HasSuffix(c, ".") {
+			trailing = true
+		}
+		base, _, _ := strings.Cut(c, ".")
+		if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+			reserved = true
+		}
+	}
+	if trailing {
+		problems = append(problems, ProblemTrailingSpaceDot)
+	}
+	if reserved {
+		problems = append(problems, ProblemReservedName)
+	}
+	if utf8.RuneCountInString(name) > maxWindowsPath {
+		problems = append(problems, ProblemLongPath)
+	}
+	return problems
+}
+
+// WriteValidationReport は検査結果をプレーンテキストでWriterに出力します。
+// 構造上の問題や名前の問題がある場合は、問題の種類ごとの件数をそれぞれ別の節に出力します。
+func WriteValidationReport(w io.Writer, totalEntries int, issues []ValidationIssue, lang Lang) error {
+	if _, err := fmt.Fprintf(w, "%s: %d, %s: %d\n", lang.T("Entries"), totalEntries, lang.T("Problems"), len(issues)); err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Problem]++
+		line := fmt.Sprintf("%s: %q", issue.Problem, issue.Name)
+		if issue.Detail != "" {
+			line += " (" + issue.Detail + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	if err := writeProblemCounts(w, lang.T("Structural problems"), structureProblems, counts); err != nil {
+		return err
+	}
+	return writeProblemCounts(w, lang.T("Name anomalies (break extraction on Windows)"), nameAnomalyProblems, counts)
+}
+
+// writeProblemCounts は問題の種類ごとの件数を1つの節として出力します。該当する問題がない場合は出力しません。
+func writeProblemCounts(w io.Writer, title string, problems []string, counts map[string]int) error {
+	total := 0
+	for _, problem := range problems {
+		total += counts[problem]
+	}
+	if total == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n%s: %d\n", title, total); err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		if _, err := fmt.Fprintf(w, "  %-22s %d\n", problem+":", counts[problem]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
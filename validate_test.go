@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ValidateEntries のテスト
+func TestValidateEntries(t *testing.T) {
+	long := strings.Repeat("a/", 130) + "b.txt"
+	tests := []struct {
+		name     string
+		entry    string
+		expected []string
+	}{
+		{name: "問題のない名前", entry: "dir1/a.txt", expected: nil},
+		{name: "空の名前", entry: "", expected: []string{"empty name"}},
+		{name: "制御文字", entry: "dir1/a\tb.txt", expected: []string{ProblemControlChar}},
+		{name: "末尾の空白", entry: "dir1 /a.txt", expected: []string{ProblemTrailingSpaceDot}},
+		{name: "末尾のピリオド", entry: "dir1/a.", expected: []string{ProblemTrailingSpaceDot}},
+		{name: "ディレクトリの末尾のスラッシュは対象外", entry: "dir1/", expected: nil},
+		{name: "予約名", entry: "con/a.txt", expected: []string{ProblemReservedName}},
+		{name: "拡張子付きの予約名", entry: "dir1/NUL.txt", expected: []string{ProblemReservedName}},
+		{name: "予約名を含む名前は対象外", entry: "CONFIG/COM10.txt", expected: nil},
+		{name: "260文字を超えるパス", entry: long, expected: []string{ProblemLongPath}},
+		{name: "複数の問題", entry: "AUX./\x01", expected: []string{ProblemControlChar, ProblemTrailingSpaceDot, ProblemReservedName}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range ValidateEntries([]FileEntry{{Name: tt.entry}}) {
+				got = append(got, issue.Problem)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// WriteValidationReport の名前の問題の節のテスト
+func TestWriteValidationReport(t *testing.T) {
+	issues := ValidateEntries([]FileEntry{{Name: "CON"}, {Name: "PRN.txt"}, {Name: "a. "}, {Name: "ok.txt"}})
+	buf := new(bytes.Buffer)
+	if err := WriteValidationReport(buf, 4, issues, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Entries: 4, Problems: 3\n",
+		"Name anomalies (break extraction on Windows): 3\n",
+		"  control character:     0\n",
+		"  trailing space or dot: 1\n",
+		"  reserved name:         2\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q, got: %s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteValidationReport(buf, 1, nil, ""); err != nil || strings.Contains(buf.String(), "Name anomalies") {
+		t.Errorf("unexpected section for clean archive: %s, %v", buf.String(), err)
+	}
+}
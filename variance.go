@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FolderVariance は複数アーカイブ間でのフォルダごとの件数のばらつきを保持します。
+type FolderVariance struct {
+	Path   string
+	Min    int
+	Max    int
+	Mean   float64
+	StdDev float64
+	// CV は変動係数 (標準偏差 / 平均) です。
+	CV       float64
+	Volatile bool
+}
+
+// ComputeFolderVariance は複数の集計結果からフォルダごとの最小・最大・平均件数と変動係数を求めます。(純粋関数)
+// あるアーカイブに存在しないフォルダは件数0として扱います。
+// 最大件数がしきい値以上のフォルダが対象で、変動係数が cvLimit 以上のものを Volatile とします。
+// 結果は変動係数の降順、同じ場合はパスの昇順でソートされます。
+func ComputeFolderVariance(runs [][]FolderCount, threshold int, cvLimit float64) []FolderVariance {
+	counts := make(map[string][]int)
+	for i, results := range runs {
+		for _, r := range results {
+			if _, ok := counts[r.Path]; !ok {
+				counts[r.Path] = make([]int, len(runs))
+			}
+			counts[r.Path][i] = r.Count
+		}
+	}
+
+	var variances []FolderVariance
+	for p, values := range counts {
+		v := FolderVariance{Path: p, Min: values[0], Max: values[0]}
+		sum := 0
+		for _, n := range values {
+			v.Min = min(v.Min, n)
+			v.Max = max(v.Max, n)
+			sum += n
+		}
+		if v.Max < threshold {
+			continue
+		}
+		v.Mean = float64(sum) / float64(len(values))
+		sq := 0.0
+		for _, n := range values {
+			sq += (float64(n) - v.Mean) * (float64(n) - v.Mean)
+		}
+		v.StdDev = math.Sqrt(sq / float64(len(values)))
+		if v.Mean > 0 {
+			v.CV = v.StdDev / v.Mean
+		}
+		v.Volatile = v.CV >= cvLimit
+		variances = append(variances, v)
+	}
+
+	sort.Slice(variances, func(i, j int) bool {
+		if variances[i].CV == variances[j].CV {
+			return variances[i].Path < variances[j].Path
+		}
+		return variances[i].CV > variances[j].CV
+	})
+	return variances
+}
+
+// WriteVarianceText はばらつきの集計結果をプレーンテキストでWriterに出力します。
+func WriteVarianceText(w io.Writer, variances []FolderVariance, lang Lang) error {
+	_, err := fmt.Fprintf(w, "\n%-50s | %8s | %8s | %10s | %6s | %s\n", lang.T("Folder Path"), lang.T("Min"), lang.T("Max"), lang.T("Avg"), lang.T("CV"), lang.T("Flag"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 100))
+	for _, v := range variances {
+		_, err := fmt.Fprintf(w, "%-50s | %8d | %8d | %10.1f | %6.2f | %s\n", v.Path, v.Min, v.Max, v.Mean, v.CV, volatileFlag(v))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteVarianceCSV はばらつきの集計結果をCSV形式でWriterに出力します。
+func WriteVarianceCSV(w io.Writer, variances []FolderVariance, opts CSVOptions) error {
+	return writeCSVRecords(w, opts, func(writer csvRecordWriter) error {
+		if err := writer.Write([]string{"Folder Path", "Min", "Max", "Avg", "StdDev", "CV", "Flag"}); err != nil {
+			return err
+		}
+		for _, v := range variances {
+			record := []string{
+				v.Path,
+				strconv.Itoa(v.Min),
+				strconv.Itoa(v.Max),
+				strconv.FormatFloat(v.Mean, 'f', 2, 64),
+				strconv.FormatFloat(v.StdDev, 'f', 2, 64),
+				strconv.FormatFloat(v.CV, 'f', 4, 64),
+				volatileFlag(v),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectVarianceWriter は出力形式名に対応するばらつき集計結果の書き出し関数を返します。テキストの見出しは opts.Lang に合わせて訳し、CSVは opts.CSV に従って書き出します。
+func selectVarianceWriter(format string, opts WriterOptions) (func(io.Writer, []FolderVariance) error, error) {
+	switch format {
+	case "", "text":
+		return func(w io.Writer, variances []FolderVariance) error {
+			return WriteVarianceText(w, variances, opts.Lang)
+		}, nil
+	case "csv":
+		return func(w io.Writer, variances []FolderVariance) error {
+			return WriteVarianceCSV(w, variances, opts.CSV)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func volatileFlag(v FolderVariance) string {
+	if v.Volatile {
+		return "VOLATILE"
+	}
+	return ""
+}
+
+// runVariance は variance コマンドを実行します。
+func runVariance(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "variance", "variance [options] <a.zip> <b.zip> [more.zip...]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 0)
+	cvLimit := fs.Float64("cv", 0.5, "ばらつきが大きいと判定する変動係数 (標準偏差 / 平均)")
+	format := fs.String("format", "text", "出力形式 (text, csv)")
+	var cf csvFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageError(fs, "variance requires at least two zip paths")
+	}
+	csvOpts, err := cf.options(fs)
+	if err != nil {
+		return err
+	}
+	write, err := selectVarianceWriter(*format, WriterOptions{Lang: env.lang, CSV: csvOpts})
+	if err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	opts, err := cfg.aggregateOptions()
+	if err != nil {
+		return err
+	}
+	// しきい値はアーカイブ間の最大件数に対して適用するため、集計時は全フォルダを対象にする
+	opts.Threshold = 0
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	app := &App{Reader: reader, Logger: env.Logger}
+	runs := make([][]FolderCount, 0, fs.NArg())
+	for _, zipPath := range fs.Args() {
+		results, _, err := app.Analyze(zipPath, opts)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, results)
+	}
+
+	variances := ComputeFolderVariance(runs, cfg.Threshold, *cvLimit)
+	for i := range variances {
+		variances[i].Path = cfg.Paths.Folder(variances[i].Path)
+	}
+	return write(env.Stdout, variances)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// ComputeFolderVariance のテスト
+func TestComputeFolderVariance(t *testing.T) {
+	runs := [][]FolderCount{
+		{{Path: "stable", Count: 10}, {Path: "volatile", Count: 1}, {Path: "tiny", Count: 1}},
+		{{Path: "stable", Count: 10}, {Path: "volatile", Count: 9}, {Path: "tiny", Count: 1}},
+		{{Path: "stable", Count: 10}},
+	}
+
+	result := ComputeFolderVariance(runs, 5, 0.5)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 folders, got %v", result)
+	}
+
+	v := result[0]
+	if v.Path != "volatile" || v.Min != 0 || v.Max != 9 || !v.Volatile {
+		t.Errorf("unexpected volatile folder result: %+v", v)
+	}
+	if math.Abs(v.Mean-10.0/3) > 1e-9 {
+		t.Errorf("expected mean %f, got %f", 10.0/3, v.Mean)
+	}
+
+	s := result[1]
+	if s.Path != "stable" || s.StdDev != 0 || s.CV != 0 || s.Volatile {
+		t.Errorf("unexpected stable folder result: %+v", s)
+	}
+}
+
+// WriteVarianceCSV のテスト (文字コードと区切り文字)
+func TestWriteVarianceCSV(t *testing.T) {
+	variances := []FolderVariance{{Path: "docs", Min: 1, Max: 9, Mean: 5, StdDev: 4, CV: 0.8, Volatile: true}}
+	buf := new(bytes.Buffer)
+	if err := WriteVarianceCSV(buf, variances, CSVOptions{Encoding: "utf8", Delimiter: "semicolon"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Folder Path;Min;Max;Avg;StdDev;CV;Flag\ndocs;1;9;5.00;4.00;0.8000;VOLATILE\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProblemCorruptData は展開した内容がCRC-32と一致しない、または展開できないエントリの問題の種類です。
+const ProblemCorruptData = "corrupt data"
+
+// verifyProgressInterval は検証の進捗を通知する間隔です。
+const verifyProgressInterval = 2 * time.Second
+
+// VerifyProgress は内容の検証の進捗です。
+type VerifyProgress struct {
+	Entries      int
+	TotalEntries int
+	Bytes        uint64
+	TotalBytes   uint64
+}
+
+// VerifyOptions は内容の検証方法を指定します。
+type VerifyOptions struct {
+	// Workers は同時に展開するエントリの数です。0以下の場合はCPUの数を使います。
+	Workers int
+	// Progress は検証中に一定の間隔で、および終了時に呼び出されます。nil の場合は通知しません。
+	Progress func(VerifyProgress)
+}
+
+// Verify はローカルのZIPファイルのすべてのエントリを展開してCRC-32を検証し、問題のあったエントリを返します。
+// 一覧の読み込みと異なりエントリの内容をすべて読むため、複数のエントリを並行して展開します。
+// 2つ目の戻り値は検証したファイルの数です。問題は復号後のエントリ名の順に並べます。
+func (z ZipArchiveReader) Verify(zipPath string, opts VerifyOptions) ([]ValidationIssue, int, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer r.Close()
+
+	entries, _ := z.entries(r.File, time.Time{}, nil)
+	var targets []int
+	progress := VerifyProgress{}
+	for i, e := range entries {
+		if !e.IsDir {
+			targets = append(targets, i)
+			progress.TotalBytes += e.Size
+		}
+	}
+	progress.TotalEntries = len(targets)
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var done, doneBytes atomic.Uint64
+	var mu sync.Mutex
+	var issues []ValidationIssue
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, max(len(targets), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				n, err := verifyFile(r.File[i])
+				doneBytes.Add(n)
+				done.Add(1)
+				if err != nil {
+					mu.Lock()
+					issues = append(issues, ValidationIssue{Name: entries[i].Name, Problem: ProblemCorruptData, Detail: err.Error()})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	stop := make(chan struct{})
+	report := func() {
+		if opts.Progress != nil {
+			p := progress
+			p.Entries, p.Bytes = int(done.Load()), doneBytes.Load()
+			opts.Progress(p)
+		}
+	}
+	var ticker sync.WaitGroup
+	ticker.Add(1)
+	go func() {
+		defer ticker.Done()
+		t := time.NewTicker(verifyProgressInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				report()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for _, i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(stop)
+	ticker.Wait()
+	report()
+
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Name < issues[j].Name })
+	return issues, len(targets), nil
+}
+
+// verifyFile は1件のエントリを展開し、読み込んだバイト数を返します。
+// archive/zip は末尾まで読んだ時点でCRC-32とサイズを検証し、一致しない場合は zip.ErrChecksum を返します。
+func verifyFile(f *zip.File) (uint64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	n, err := io.Copy(io.Discard, rc)
+	return uint64(n), err
+}
+
+// WriteCorruptFolders は内容に問題のあったエントリの数を、集計と同じフォルダパスごとにプレーンテキストで出力します。
+// フォルダパスは paths の表記で出力します。問題のあったエントリがない場合は何も出力しません。
+func WriteCorruptFolders(w io.Writer, issues []ValidationIssue, paths FolderFormat) error {
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		if issue.Problem == ProblemCorruptData {
+			counts[paths.Folder(FolderKey(issue.Name, AggregateOptions{}))]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	folders := make([]FolderCount, 0, len(counts))
+	for p, c := range counts {
+		folders = append(folders, FolderCount{Path: p, Count: c})
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if folders[i].Count != folders[j].Count {
+			return folders[i].Count > folders[j].Count
+		}
+		return folders[i].Path < folders[j].Path
+	})
+	if _, err := fmt.Fprintf(w, "\nCorrupt entries by folder:\n"); err != nil {
+		return err
+	}
+	for _, f := range folders {
+		if _, err := fmt.Fprintf(w, "  %-50s %d\n", f.Path, f.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
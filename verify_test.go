@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ZipArchiveReader.Verify のテスト
+func TestVerify(t *testing.T) {
+	data, _ := rawZip(t, 0, "a.txt", "dir/b.txt", "dir/c.txt", "dir2/d.txt")
+	// dir/c.txt の内容を書き換える
+	i := bytes.Index(data, []byte("content of dir/c.txt"))
+	data[i] = 'C'
+	zipPath := filepath.Join(t.TempDir(), "test.zip")
+	if err := os.WriteFile(zipPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last VerifyProgress
+	issues, verified, err := ZipArchiveReader{}.Verify(zipPath, VerifyOptions{Workers: 3, Progress: func(p VerifyProgress) { last = p }})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verified != 4 {
+		t.Errorf("expected 4 verified entries, got %d", verified)
+	}
+	if len(issues) != 1 || issues[0].Name != "dir/c.txt" || issues[0].Problem != ProblemCorruptData {
+		t.Errorf("expected corrupt dir/c.txt, got %+v", issues)
+	}
+	if last.Entries != 4 || last.TotalEntries != 4 || last.Bytes != last.TotalBytes {
+		t.Errorf("unexpected final progress: %+v", last)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteCorruptFolders(buf, issues, FolderFormat{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\nCorrupt entries by folder:\n  dir "; !bytes.HasPrefix(buf.Bytes(), []byte(want)) {
+		t.Errorf("expected prefix %q, got %q", want, buf.String())
+	}
+}
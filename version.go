@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+)
+
+// version、commit、buildDate はビルド時に -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..." で埋め込まれます。
+// commit と buildDate が空の場合は、go build が記録したVCSの情報を使います。
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// capabilitiesSchema は version -format json の出力の形式の版です。項目を削除または意味を変更した場合に上げます。
+const capabilitiesSchema = 1
+
+// builtinArchiveFormats は内容から判定して読み込める組み込みのアーカイブ形式です。
+var builtinArchiveFormats = []string{"zip", "tar", "gzip", "bzip2", "xz", "iso", "cab", "msi"}
+
+// BuildInfo はバージョンとビルドの情報です。
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// Capabilities は実行ファイルが対応する機能の一覧です。自動化の処理が、古い版に無いオプションを使う前に確かめるためのものです。
+type Capabilities struct {
+	Schema int `json:"schema"`
+	BuildInfo
+	// Commands はサブコマンド名の一覧です。
+	Commands []string `json:"commands"`
+	// Formats は読み込めるアーカイブ形式の一覧です。
+	Formats []string `json:"formats"`
+	// ZipExtensions は形式を判定せずにZIPとして読み込む拡張子の一覧です。
+	ZipExtensions []string `json:"zipExtensions"`
+	// ReaderExtensions はプラグインなどで登録された ArchiveReader の拡張子の一覧です。
+	ReaderExtensions []string `json:"readerExtensions"`
+	// Writers は -format と -output で指定できる出力形式の一覧です。
+	Writers []string `json:"writers"`
+	// Encodings は -encoding-fallbacks などで指定できる文字コード名の一覧です。
+	Encodings []string `json:"encodings"`
+	// Languages は -lang で指定できる言語の一覧です。
+	Languages []string `json:"languages"`
+}
+
+// currentBuildInfo は埋め込まれた情報と go build が記録した情報から BuildInfo を返します。
+func currentBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// currentCapabilities は登録済みのサブコマンド、読み込み、出力形式、文字コードから Capabilities を返します。
+func currentCapabilities() Capabilities {
+	c := Capabilities{
+		Schema:           capabilitiesSchema,
+		BuildInfo:        currentBuildInfo(),
+		Formats:          slices.Clone(builtinArchiveFormats),
+		ReaderExtensions: ArchiveReaderExtensions(),
+		Writers:          ResultWriterNames(),
+		Encodings:        NameDecoderNames(),
+		Languages:        []string{string(LangJA), string(LangEN)},
+	}
+	for _, cmd := range commands {
+		c.Commands = append(c.Commands, cmd.Name)
+	}
+	for ext := range zipContainerExtensions {
+		c.ZipExtensions = append(c.ZipExtensions, ext)
+	}
+	sort.Strings(c.ZipExtensions)
+	return c
+}
+
+// WriteVersionText はバージョンとビルドの情報を書き出します。
+func WriteVersionText(w io.Writer, info BuildInfo) error {
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	_, err := fmt.Fprintf(w, "obuzipcount %s\ncommit: %s\nbuilt:  %s\ngo:     %s %s\n",
+		info.Version, orUnknown(info.Commit), orUnknown(info.BuildDate), info.GoVersion, info.Platform)
+	return err
+}
+
+// runVersion は version コマンドを実行します。
+func runVersion(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "version", "version [-format text|json]")
+	format := fs.String("format", "text", "出力形式 (text: バージョンとビルドの情報, json: ビルドの情報と対応する機能の一覧)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch *format {
+	case "text":
+		return WriteVersionText(env.Stdout, currentBuildInfo())
+	case "json":
+		enc := json.NewEncoder(env.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(currentCapabilities())
+	default:
+		return usageError(fs, "unknown format: %s", *format)
+	}
+}
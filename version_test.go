@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// version コマンドの出力のテスト
+func TestRunVersion(t *testing.T) {
+	savedCommit, savedDate := commit, buildDate
+	commit, buildDate = "0123abc", "2026-01-02T03:04:05Z"
+	t.Cleanup(func() { commit, buildDate = savedCommit, savedDate })
+
+	t.Run("テキスト", func(t *testing.T) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"version"}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		for _, want := range []string{"obuzipcount dev\n", "commit: 0123abc\n", "built:  2026-01-02T03:04:05Z\n", "go:     go"} {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("output does not contain %q, got: %s", want, stdout.String())
+			}
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"version", "-format", "json"}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		var got Capabilities
+		if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+		}
+		if got.Schema != capabilitiesSchema || got.Version != "dev" || got.Commit != "0123abc" || got.BuildDate != "2026-01-02T03:04:05Z" {
+			t.Errorf("unexpected build info: %+v", got.BuildInfo)
+		}
+		checks := []struct {
+			name   string
+			values []string
+			want   string
+			// sorted は登録された名前の一覧として昇順に並ぶことを確かめるかです。
+			sorted bool
+		}{
+			{name: "commands", values: got.Commands, want: "count"},
+			{name: "formats", values: got.Formats, want: "tar"},
+			{name: "zipExtensions", values: got.ZipExtensions, want: ".docx", sorted: true},
+			{name: "writers", values: got.Writers, want: "csv", sorted: true},
+			{name: "encodings", values: got.Encodings, want: "cp932", sorted: true},
+			{name: "languages", values: got.Languages, want: "en"},
+		}
+		for _, c := range checks {
+			if !slices.Contains(c.values, c.want) {
+				t.Errorf("%s does not contain %q, got: %v", c.name, c.want, c.values)
+			}
+			if c.sorted && !slices.IsSorted(c.values) {
+				t.Errorf("%s is not sorted: %v", c.name, c.values)
+			}
+		}
+		// 登録がない場合も null ではなく空の配列を出力する
+		if !bytes.Contains(stdout.Bytes(), []byte(`"readerExtensions": [`)) {
+			t.Errorf("readerExtensions should be an array, got: %s", stdout.String())
+		}
+	})
+
+	t.Run("不明な形式", func(t *testing.T) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"version", "-format", "xml"}, stdout, stderr); code != 2 {
+			t.Errorf("expected code 2, got %d", code)
+		}
+	})
+}
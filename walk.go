@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Walk はアーカイブのエントリを1件ずつ fn に渡します。
+// fn が fs.SkipAll を返した場合は残りのエントリを渡さずに nil を返し、それ以外のエラーはそのまま返します。
+// 独自の入力元のエントリを Accumulator に渡す場合と同じ書き方で、アーカイブのエントリを処理できます。
+func Walk(reader ArchiveReader, archivePath string, fn func(FileEntry) error) error {
+	entries, err := reader.ReadEntries(archivePath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			if errors.Is(err, fs.SkipAll) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Accumulator はエントリを1件ずつ受け取り、Aggregate と同じ方法でフォルダごとに集計します。
+// エントリ全体をメモリに保持しないため、独自の入力元から逐次渡す場合に使います。
+// opts.MaxMemory を指定した場合は、フォルダごとの値がそれを超えた時点で一時ファイルに書き出し、Result で併合します。
+type Accumulator struct {
+	opts AggregateOptions
+	// folders はフォルダごとの件数とサイズの統計です。
+	folders *folderTrie
+	files   int
+	// memory はメモリ上のフォルダごとの値のおおよそのバイト数です。
+	memory uint64
+	// spilled は書き出した一時ファイルです。書き出していない場合は nil です。
+	spilled *folderSpill
+	// err は一時ファイルの最初のエラーです。書き出しに失敗した後はメモリ上で集計を続けます。
+	err error
+}
+
+// NewAccumulator は集計方法を指定して Accumulator を作成します。
+func NewAccumulator(opts AggregateOptions) *Accumulator {
+	return &Accumulator{opts: opts, folders: newFolderTrie()}
+}
+
+// Add はエントリを集計に加えます。ディレクトリと絞り込みの条件を満たさないエントリは無視します。
+func (a *Accumulator) Add(f FileEntry) {
+	if f.IsDir || !a.opts.Filter.Match(f) {
+		return
+	}
+	a.files++
+	n := a.folder(FolderKey(f.Name, a.opts))
+	a.addTo(n, f)
+	if a.opts.Cumulative {
+		for !a.folders.isRoot(n) {
+			if a.folders.isTop(n) {
+				n = a.folder("(Root)")
+			} else {
+				n = n.parent
+			}
+			a.addTo(n, f)
+		}
+	}
+	if a.opts.MaxMemory > 0 && a.memory > a.opts.MaxMemory && a.err == nil {
+		a.err = a.spill()
+	}
+}
+
+// folder は集計キーのフォルダの節を返し、なければ作成します。
+func (a *Accumulator) folder(key string) *folderNode {
+	n, added := a.folders.node(key)
+	a.memory += added
+	return n
+}
+
+// addTo はエントリをフォルダの件数とサイズの統計に加えます。
+func (a *Accumulator) addTo(n *folderNode, f FileEntry) {
+	n.count++
+	if a.opts.Stats {
+		st := n.stats
+		if st == nil {
+			st = &FolderStats{}
+			n.stats = st
+			a.memory += folderStatsOverhead
+		}
+		st.TotalSize += f.Size
+		if f.Size > st.LargestSize || st.LargestFile == "" {
+			a.memory += uint64(max(len(path.Base(f.Name))-len(st.LargestFile), 0))
+			st.LargestFile, st.LargestSize = path.Base(f.Name), f.Size
+		}
+	}
+}
+
+// Err は一時ファイルを使えなかった場合、そのエラーを返します。
+// 書き出しの失敗ではメモリ上で集計を続けるため結果は正しいままですが、
+// Result で読み込みに失敗した場合は errIncompleteResult を含むエラーを返し、結果は不完全です。
+func (a *Accumulator) Err() error {
+	return a.err
+}
+
+// Spilled は集計途中の値を一時ファイルに書き出したかを表します。
+func (a *Accumulator) Spilled() bool {
+	return a.spilled != nil
+}
+
+// parentFolder は集計キーのフォルダパスの親フォルダを返します。最上位のフォルダの親は "(Root)" です。(純粋関数)
+func parentFolder(folder string) string {
+	if i := strings.LastIndexByte(folder, '/'); i >= 0 {
+		return folder[:i]
+	}
+	return "(Root)"
+}
+
+// percent は n の total に対する割合 (パーセント) を返します。total が0の場合は0を返します。(純粋関数)
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// Result はしきい値以上のフォルダを件数の降順、同じ場合はパスの昇順で返します。2つ目の戻り値は集計したファイル数です。
+// 割合のしきい値は、それまでに加えたすべてのファイル数に対して判定します。
+// CollapseOther が指定されている場合は、しきい値未満のフォルダをまとめた1行を末尾に加えます。
+// 呼び出した後も続けてエントリを加えられます。
+// 一時ファイルに書き出していた場合はそれらを併合します。読み込みに失敗した場合は Err でエラーを返し、メモリ上の値だけで求めます。
+func (a *Accumulator) Result() ([]FolderCount, int) {
+	if a.spilled != nil {
+		results, err := a.resultFromSpill()
+		if err == nil {
+			return results, a.files
+		}
+		a.err = err
+	}
+	var results []FolderCount
+	var other FolderCount
+	a.folders.walk(func(k string, n *folderNode) {
+		v := n.count
+		meets := a.opts.meetsThreshold(k, v, a.files)
+		if !meets && !a.opts.CollapseOther {
+			return
+		}
+		fc := a.folderCount(k, v, n.stats)
+		if !meets {
+			other.absorb(fc)
+			return
+		}
+		if a.opts.Cumulative {
+			parent := v
+			switch {
+			case a.folders.isRoot(n):
+			case a.folders.isTop(n):
+				parent = 0
+				if r := a.folders.find("(Root)"); r != nil {
+					parent = r.count
+				}
+			default:
+				parent = n.parent.count
+			}
+			fc.Share = &FolderShare{OfParent: percent(v, parent), OfTotal: percent(v, a.files)}
+		}
+		results = append(results, fc)
+	})
+
+	sortFolderCounts(results)
+	if other.OtherFolders > 0 {
+		results = append(results, other)
+	}
+	return results, a.files
+}
+
+// folderCount はフォルダの件数と統計から結果の行を作成します。統計は複製し、平均のサイズを求めます。
+func (a *Accumulator) folderCount(k string, v int, st *FolderStats) FolderCount {
+	fc := FolderCount{Path: k, Count: v}
+	if st != nil {
+		s := *st
+		s.AverageSize = s.TotalSize / uint64(v)
+		fc.Stats = &s
+	}
+	return fc
+}
+
+// sortFolderCounts は件数の降順、件数が同じ場合はパスの昇順で並べます。
+func sortFolderCounts(results []FolderCount) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count == results[j].Count {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].Count > results[j].Count
+	})
+}
+
+// otherFolderPath はしきい値未満の n 個のフォルダをまとめた行のパスです。(純粋関数)
+func otherFolderPath(n int) string {
+	return fmt.Sprintf("(Other: %d folders)", n)
+}
+
+// absorb はしきい値未満のフォルダ fc を、フォルダをまとめた行に加えます。fc がまとめた行の場合は、まとめたフォルダをすべて加えます。
+// 最大のファイルはサイズが同じ場合、名前の昇順で先のものを残します。
+func (o *FolderCount) absorb(fc FolderCount) {
+	o.OtherFolders += max(fc.OtherFolders, 1)
+	o.Count += fc.Count
+	o.Path = otherFolderPath(o.OtherFolders)
+	if fc.Stats == nil {
+		return
+	}
+	if o.Stats == nil {
+		o.Stats = &FolderStats{}
+	}
+	s := o.Stats
+	s.TotalSize += fc.Stats.TotalSize
+	if fc.Stats.LargestSize > s.LargestSize || s.LargestFile == "" ||
+		(fc.Stats.LargestSize == s.LargestSize && fc.Stats.LargestFile < s.LargestFile) {
+		s.LargestFile, s.LargestSize = fc.Stats.LargestFile, fc.Stats.LargestSize
+	}
+	s.AverageSize = s.TotalSize / uint64(o.Count)
+}
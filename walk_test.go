@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// Walk と Accumulator のテスト
+func TestWalkAccumulator(t *testing.T) {
+	zipPath := obuziptest.New().Files("dir1/a.txt", "dir1/b.txt", "dir2/c.txt", "d.txt").Dir("empty").WriteFile(t)
+	opts := AggregateOptions{Threshold: 1, Stats: true}
+
+	acc := NewAccumulator(opts)
+	var names []string
+	err := Walk(ZipArchiveReader{}, zipPath, func(e FileEntry) error {
+		names = append(names, e.Name)
+		acc.Add(e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 5 {
+		t.Errorf("expected 5 entries, got %v", names)
+	}
+
+	entries, err := (ZipArchiveReader{}).ReadEntries(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantResults, wantTotal := Aggregate(entries, opts)
+	results, total := acc.Result()
+	if !reflect.DeepEqual(results, wantResults) || total != wantTotal {
+		t.Errorf("expected %+v (%d), got %+v (%d)", wantResults, wantTotal, results, total)
+	}
+	// Result を呼び出した後も加えられる
+	acc.Add(FileEntry{Name: "dir2/e.txt"})
+	if results, total := acc.Result(); total != 5 || results[0].Count != 2 || results[1].Path != "dir2" || results[1].Count != 2 {
+		t.Errorf("unexpected result after Add: %+v (%d)", results, total)
+	}
+
+	t.Run("途中で打ち切り", func(t *testing.T) {
+		n := 0
+		err := Walk(ZipArchiveReader{}, zipPath, func(FileEntry) error {
+			if n++; n == 2 {
+				return fs.SkipAll
+			}
+			return nil
+		})
+		if err != nil || n != 2 {
+			t.Errorf("expected stop after 2 entries, got %d, %v", n, err)
+		}
+
+		stop := errors.New("stop")
+		if err := Walk(ZipArchiveReader{}, zipPath, func(FileEntry) error { return stop }); !errors.Is(err, stop) {
+			t.Errorf("expected callback error, got %v", err)
+		}
+	})
+}
+
+// 累積モードの集計と割合のテスト
+func TestAggregateCumulative(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "a/b/1.txt"}, {Name: "a/b/2.txt"}, {Name: "a/b/3.txt"},
+		{Name: "a/c/1.txt"}, {Name: "a/1.txt"}, {Name: "1.txt"}, {Name: "a/b/", IsDir: true},
+	}
+	results, total := Aggregate(entries, AggregateOptions{Threshold: 2, Cumulative: true})
+	expected := []FolderCount{
+		{Path: "(Root)", Count: 6, Share: &FolderShare{OfParent: 100, OfTotal: 100}},
+		{Path: "a", Count: 5, Share: &FolderShare{OfParent: 500.0 / 6, OfTotal: 500.0 / 6}},
+		{Path: "a/b", Count: 3, Share: &FolderShare{OfParent: 60, OfTotal: 50}},
+	}
+	if !reflect.DeepEqual(results, expected) || total != 6 {
+		t.Errorf("expected %+v (6), got %+v (%d)", expected, results, total)
+	}
+
+	// 累積モードでない場合は割合を求めない
+	results, _ = Aggregate(entries, AggregateOptions{Threshold: 2})
+	if len(results) != 1 || results[0].Path != "a/b" || results[0].Share != nil {
+		t.Errorf("unexpected non-cumulative result: %+v", results)
+	}
+}
+
+// しきい値未満のフォルダをまとめた行のテスト
+func TestAggregateCollapseOther(t *testing.T) {
+	entries := []FileEntry{
+		{Name: "a/1.txt", Size: 1}, {Name: "a/2.txt", Size: 2}, {Name: "a/3.txt", Size: 3},
+		{Name: "b/1.txt", Size: 5}, {Name: "c/1.txt", Size: 5}, {Name: "c/2.txt", Size: 1}, {Name: "1.txt", Size: 2},
+	}
+	results, total := Aggregate(entries, AggregateOptions{Threshold: 3, Stats: true, CollapseOther: true})
+	expected := []FolderCount{
+		{Path: "a", Count: 3, Stats: &FolderStats{TotalSize: 6, AverageSize: 2, LargestFile: "3.txt", LargestSize: 3}},
+		{Path: "(Other: 3 folders)", Count: 4, OtherFolders: 3, Stats: &FolderStats{TotalSize: 13, AverageSize: 3, LargestFile: "1.txt", LargestSize: 5}},
+	}
+	if !reflect.DeepEqual(results, expected) || total != 7 {
+		t.Errorf("expected %+v (7), got %+v (%d)", expected, results, total)
+	}
+
+	// しきい値未満のフォルダがなければ行を加えない
+	results, _ = Aggregate(entries, AggregateOptions{Threshold: 1, CollapseOther: true})
+	for _, r := range results {
+		if r.OtherFolders > 0 {
+			t.Errorf("unexpected other row: %+v", r)
+		}
+	}
+
+	// 部分的な読み込みでは推定値がしきい値未満のフォルダもまとめる
+	p := PartialScan{ScannedEntries: 1, TotalEntries: 2}
+	opts := AggregateOptions{Threshold: 6, CollapseOther: true}
+	results, _ = Aggregate(entries, p.scaledOptions(opts))
+	results = p.applyEstimates(results, opts)
+	expected = []FolderCount{
+		{Path: "a", Count: 3, Estimate: 6},
+		{Path: "(Other: 3 folders)", Count: 4, Estimate: 8, OtherFolders: 3},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+}
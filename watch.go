@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// outputExtensions は出力形式ごとのレポートファイルの拡張子です。
+var outputExtensions = map[string]string{
+	"text":     ".txt",
+	"csv":      ".csv",
+	"markdown": ".md",
+	"html":     ".html",
+	"json":     ".json",
+}
+
+// pendingArchive はコピー完了待ちのアーカイブです。
+type pendingArchive struct {
+	lastEvent time.Time
+	size      int64
+}
+
+// debouncer はコピー途中のファイルを処理しないよう、一定時間変化がなくなったファイルを選び出します。
+type debouncer struct {
+	settle  time.Duration
+	pending map[string]*pendingArchive
+}
+
+func newDebouncer(settle time.Duration) *debouncer {
+	return &debouncer{settle: settle, pending: make(map[string]*pendingArchive)}
+}
+
+// Touch はファイルの作成・更新を記録します。
+func (d *debouncer) Touch(p string, now time.Time) {
+	if pa, ok := d.pending[p]; ok {
+		pa.lastEvent = now
+		return
+	}
+	d.pending[p] = &pendingArchive{lastEvent: now, size: -1}
+}
+
+// Ready は最後の更新から settle 以上経過し、前回の確認からサイズが変わっていないファイルを返します。
+// 返したファイルと、存在しなくなったファイルは待ち状態から除きます。
+func (d *debouncer) Ready(now time.Time, stat func(string) (os.FileInfo, error)) []string {
+	var ready []string
+	for p, pa := range d.pending {
+		if now.Sub(pa.lastEvent) < d.settle {
+			continue
+		}
+		info, err := stat(p)
+		if err != nil {
+			delete(d.pending, p)
+			continue
+		}
+		if info.Size() != pa.size {
+			// サイズが変わっていればまだコピー中とみなし、次の確認まで待つ
+			pa.size, pa.lastEvent = info.Size(), now
+			continue
+		}
+		ready = append(ready, p)
+		delete(d.pending, p)
+	}
+	return ready
+}
+
+// Watcher は受け入れフォルダを監視し、届いたZIPファイルごとにレポートを出力します。
+type Watcher struct {
+	App *App
+	// Config は各アーカイブの集計に使う設定です。ZipPath と Outputs はアーカイブごとに設定します。
+	Config  AppConfig
+	OutDir  string
+	Formats []string
+	// Settle はファイルの更新が止まってから処理を始めるまでの待ち時間です。
+	Settle time.Duration
+	// Notify が nil でない場合は、アーカイブごとの処理結果を条件に従って通知します。
+	Notify *NotifySettings
+	// ReportNames はアーカイブごとのレポートのファイル名 (拡張子を除く) です。
+	// 含まれないアーカイブは、アーカイブのファイル名から拡張子を除いた名前を使います。
+	ReportNames map[string]string
+}
+
+// Watch は ctx が終了するまで dir を監視します。
+// 届いたファイルは1つのキューに入れ、到着順に1件ずつ処理します。
+func (w *Watcher) Watch(ctx context.Context, dir string) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer fw.Close()
+	// fsnotify は Windows で長いパスに拡張パスの接頭辞を補わないため、ここで補う
+	watchDir, err := longPathForAPI(dir)
+	if err != nil {
+		return fmt.Errorf("failed to watch directory: %w", err)
+	}
+	if err := fw.Add(watchDir); err != nil {
+		return fmt.Errorf("failed to watch directory: %w", err)
+	}
+
+	queue := make(chan string, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range queue {
+			if err := w.Process(p); err != nil {
+				w.App.Logger.Error("アーカイブの処理に失敗しました", slog.String("zipPath", p), slog.String("error", err.Error()))
+			}
+		}
+	}()
+	defer func() {
+		close(queue)
+		<-done
+	}()
+
+	d := newDebouncer(w.Settle)
+	ticker := time.NewTicker(max(w.Settle/2, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	w.App.Logger.Info("フォルダの監視を開始します", slog.String("dir", dir))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Create|fsnotify.Write) && isZipName(ev.Name) {
+				d.Touch(ev.Name, time.Now())
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.App.Logger.Warn("監視中にエラーが発生しました", slog.String("error", err.Error()))
+		case now := <-ticker.C:
+			for _, p := range d.Ready(now, os.Stat) {
+				select {
+				case queue <- p:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// Process は1つのアーカイブを集計し、出力フォルダに形式ごとのレポートを書き出します。
+// Notify が設定されている場合は、処理結果を通知してから結果を返します。
+func (w *Watcher) Process(zipPath string) error {
+	return w.ProcessAs(zipPath, zipPath)
+}
+
+// ProcessAs は zipPath から読み込んだアーカイブを name のアーカイブとして Process と同様に処理します。
+// レポートのファイル名、レポートに記録するアーカイブ、通知には name を使います。
+func (w *Watcher) ProcessAs(name, zipPath string) error {
+	cfg := w.Config
+	cfg.ZipPath = zipPath
+	if name != zipPath {
+		cfg.ArchiveName = name
+	}
+	cfg.CsvPath = ""
+	cfg.Outputs = nil
+	base, ok := w.ReportNames[name]
+	if !ok {
+		base = reportBase(name)
+	}
+	for _, format := range w.Formats {
+		ext, ok := outputExtensions[format]
+		if !ok {
+			ext = "." + format
+		}
+		cfg.Outputs = append(cfg.Outputs, OutputTarget{Format: format, Path: filepath.Join(w.OutDir, base+ext)})
+	}
+	if w.Notify == nil {
+		return w.App.Run(cfg, io.Discard)
+	}
+
+	// 通知に含めるため、json 形式の集計結果を標準出力の代わりに受け取る
+	var report bytes.Buffer
+	n := Notification{Archive: name, Status: BatchOK}
+	for _, out := range cfg.Outputs {
+		n.Attachments = append(n.Attachments, out.Path)
+	}
+	cfg.Outputs = append(cfg.Outputs, OutputTarget{Format: "json"})
+	err := w.App.Run(cfg, &report)
+	if err != nil {
+		n.Status, n.Error = BatchFailed, err.Error()
+	}
+	n.Report = report.Bytes()
+	w.Notify.Send(n, w.App.Logger)
+	return err
+}
+
+// reportBase はアーカイブの名前から、レポートのファイル名 (拡張子を除く) を返します。(純粋関数)
+func reportBase(name string) string {
+	return strings.TrimSuffix(path.Base(filepath.ToSlash(name)), path.Ext(name))
+}
+
+// uniqueReportNames はアーカイブごとに、他と重ならないレポートのファイル名 (拡張子を除く) を返します。(純粋関数)
+// dir1/a.zip と dir2/a.zip のように同じ名前になるアーカイブが複数ある場合は、
+// レポートが上書きされないよう、それぞれの名前にパスのハッシュの先頭8文字を付けます (a-1f2e3d4c)。
+// 大文字と小文字を区別しないファイルシステムを考慮し、名前は大文字と小文字を区別せずに比べます。
+func uniqueReportNames(paths []string) map[string]string {
+	archives := make(map[string]map[string]bool)
+	for _, p := range paths {
+		key := strings.ToLower(reportBase(p))
+		if archives[key] == nil {
+			archives[key] = make(map[string]bool)
+		}
+		archives[key][filepath.Clean(p)] = true
+	}
+	names := make(map[string]string, len(paths))
+	for _, p := range paths {
+		base := reportBase(p)
+		if len(archives[strings.ToLower(base)]) > 1 {
+			sum := sha256.Sum256([]byte(filepath.ToSlash(filepath.Clean(p))))
+			base += "-" + hex.EncodeToString(sum[:4])
+		}
+		names[p] = base
+	}
+	return names
+}
+
+// isZipName はファイル名の拡張子が .zip かを判定します。(純粋関数)
+func isZipName(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
+
+// runWatch は watch コマンドを実行します。
+func runWatch(env *cliEnv, args []string) error {
+	fs := newFlagSet(env, "watch", "watch -dir <dir> -out-dir <dir> [options] | watch -sources <file> [options]")
+	var rf readerFlags
+	rf.register(fs)
+	var af aggregateFlags
+	af.register(fs, 10000)
+	dir := fs.String("dir", "", "監視する受け入れフォルダ (必須)")
+	outDir := fs.String("out-dir", "", "レポートを出力するフォルダ (必須)")
+	formats := fs.String("formats", "csv,json", "出力する形式 (カンマ区切り)")
+	settle := fs.Duration("settle", 2*time.Second, "ファイルの更新が止まってから処理を始めるまでの待ち時間")
+	sourcesPath := fs.String("sources", "", "監視する受け入れフォルダの定義ファイル (JSON)。受け入れフォルダごとのしきい値と出力先で並行して監視する")
+	pollInterval := fs.Duration("poll-interval", 5*time.Second, "-sources の s3:// の受け入れフォルダを確認する間隔")
+	var nf notifyFlags
+	nf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *pollInterval <= 0 {
+		return usageError(fs, "-poll-interval must be positive")
+	}
+	if *sourcesPath != "" {
+		// 受け入れフォルダごとの設定は定義ファイルで指定する
+		for _, name := range []string{"dir", "out-dir", "formats"} {
+			if flagSet(fs, name) {
+				return usageError(fs, "cannot be combined with -sources: -%s", name)
+			}
+		}
+	} else if *dir == "" || *outDir == "" {
+		return usageError(fs, "dir and out-dir are required")
+	}
+
+	var cfg AppConfig
+	if err := af.apply(fs, &cfg); err != nil {
+		return err
+	}
+	notify, err := nf.newSettings(fs)
+	if err != nil {
+		return err
+	}
+	// ルールファイル等の誤りは監視を始める前に検出する
+	if _, err := cfg.aggregateOptions(); err != nil {
+		return err
+	}
+	if *sourcesPath != "" {
+		sources, err := LoadIntakeSources(*sourcesPath)
+		if err != nil {
+			return err
+		}
+		reader, err := rf.newReader(fs)
+		if err != nil {
+			return err
+		}
+		intake := &Intake{App: &App{Reader: reader, Logger: env.Logger}, Sources: sources, Config: cfg, Interval: *pollInterval, Settle: *settle, Notify: notify, S3: S3OptionsFromEnv(os.Getenv)}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return intake.Run(ctx)
+	}
+
+	w := &Watcher{Config: cfg, OutDir: *outDir, Settle: *settle, Notify: notify}
+	for _, f := range strings.Split(*formats, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if _, err := NewResultWriter(f, WriterOptions{}); err != nil {
+			return usageError(fs, "%v", err)
+		}
+		w.Formats = append(w.Formats, f)
+	}
+	if len(w.Formats) == 0 {
+		return usageError(fs, "at least one format is required")
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	reader, err := rf.newReader(fs)
+	if err != nil {
+		return err
+	}
+	w.App = &App{Reader: reader, Logger: env.Logger}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return w.Watch(ctx, *dir)
+}
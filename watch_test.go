@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// debouncer のテスト (更新が止まりサイズが変わらなくなったファイルだけを返す)
+func TestDebouncer(t *testing.T) {
+	sizes := map[string]int64{"a.zip": 100}
+	stat := func(p string) (os.FileInfo, error) {
+		size, ok := sizes[p]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return fakeFileInfo{size: size}, nil
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newDebouncer(time.Second)
+	d.Touch("a.zip", start)
+	d.Touch("gone.zip", start)
+
+	if got := d.Ready(start.Add(500*time.Millisecond), stat); len(got) != 0 {
+		t.Errorf("expected nothing before settle, got %v", got)
+	}
+	// 初回の確認ではサイズを記録するだけ
+	if got := d.Ready(start.Add(time.Second), stat); len(got) != 0 {
+		t.Errorf("expected nothing on first size check, got %v", got)
+	}
+	sizes["a.zip"] = 200
+	if got := d.Ready(start.Add(2*time.Second), stat); len(got) != 0 {
+		t.Errorf("expected nothing while size changes, got %v", got)
+	}
+	if got := d.Ready(start.Add(3*time.Second), stat); len(got) != 1 || got[0] != "a.zip" {
+		t.Errorf("expected [a.zip], got %v", got)
+	}
+	if len(d.pending) != 0 {
+		t.Errorf("expected no pending files, got %v", d.pending)
+	}
+}
+
+type fakeFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (f fakeFileInfo) Size() int64 { return f.size }
+
+// Watcher のテスト (届いたZIPファイルのレポート出力)
+func TestWatcher(t *testing.T) {
+	inDir, outDir := t.TempDir(), t.TempDir()
+	w := &Watcher{
+		App:     &App{Reader: ZipArchiveReader{}, Logger: slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))},
+		Config:  AppConfig{Threshold: 1},
+		OutDir:  outDir,
+		Formats: []string{"csv", "json"},
+		Settle:  50 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.Watch(ctx, inDir) }()
+	// 監視の開始を待ってからファイルを置く
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := os.ReadFile(writeTestZip(t, "dir1/a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "delivery.zip"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "note.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonPath := filepath.Join(outDir, "delivery.json")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(jsonPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("report was not written")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "delivery.csv")); err != nil {
+		t.Errorf("csv report was not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "note.json")); err == nil {
+		t.Error("non-zip file should not be processed")
+	}
+}
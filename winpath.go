@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Windows のローカルのパスとUNCパス (\\server\share\...) の扱いです。
+// 260文字を超えるパスは、os パッケージがファイルを開く際に拡張パスの接頭辞 (\\?\、UNCでは \\?\UNC\) を補うため、
+// -zip や -csv にはそのまま渡せます。os パッケージを通さない監視 (watch -dir) には longPathForAPI で接頭辞を補います。
+// 接頭辞を付けて指定したパスも同じファイルとして扱えるよう、アーカイブを識別する値は接頭辞を除いた表記から求めます。
+
+// extendedPathPrefix は Windows の拡張パスの接頭辞です。
+const extendedPathPrefix = `\\?\`
+
+// maxShortDirPath は拡張パスの接頭辞なしで扱えるフォルダのパスの長さの上限です。
+// MAX_PATH (260) から 8.3 形式のファイル名の12文字を除いた長さです。
+const maxShortDirPath = 248
+
+// isPathSeparatorByte は Windows のパスの区切り文字 (\ または /) かを判定します。(純粋関数)
+func isPathSeparatorByte(c byte) bool {
+	return c == '\\' || c == '/'
+}
+
+// stripExtendedPrefix は Windows の拡張パスの接頭辞を除いた通常の表記を返します。(純粋関数)
+// \\?\C:\dir は C:\dir に、\\?\UNC\server\share は \\server\share になります。
+// 接頭辞の無いパスと、デバイスのパス (\\.\) はそのまま返します。
+func stripExtendedPrefix(p string) string {
+	if len(p) < len(extendedPathPrefix) || !isPathSeparatorByte(p[0]) || !isPathSeparatorByte(p[1]) || p[2] != '?' || !isPathSeparatorByte(p[3]) {
+		return p
+	}
+	rest := p[len(extendedPathPrefix):]
+	if len(rest) >= 4 && strings.EqualFold(rest[:3], "UNC") && isPathSeparatorByte(rest[3]) {
+		return `\\` + rest[4:]
+	}
+	return rest
+}
+
+// isUNCPath はパスがUNCパス (\\server\share\...) かを判定します。拡張パスの接頭辞を付けたUNCパスを含みます。(純粋関数)
+func isUNCPath(p string) bool {
+	p = stripExtendedPrefix(p)
+	return len(p) > 2 && isPathSeparatorByte(p[0]) && isPathSeparatorByte(p[1]) && p[2] != '.' && p[2] != '?'
+}
+
+// canonicalLocalPath はアーカイブを識別するための絶対パスを返します。
+// Windows では拡張パスの接頭辞を除き、同じファイルを指す2つの表記を同じ値にします。
+func canonicalLocalPath(p string) (string, error) {
+	if filepath.Separator == '\\' {
+		p = stripExtendedPrefix(p)
+	}
+	return filepath.Abs(p)
+}
+
+// addExtendedPrefix は Windows の絶対パスに拡張パスの接頭辞を付けます。(純粋関数)
+// UNCパスは \\?\UNC\ を付けます。接頭辞の付いたパスとデバイスのパスはそのまま返します。
+// 接頭辞の付いたパスは区切り文字が変換されないため、/ を \ に置き換えます。
+func addExtendedPrefix(abs string) string {
+	if stripExtendedPrefix(abs) != abs {
+		return abs
+	}
+	abs = strings.ReplaceAll(abs, "/", `\`)
+	if strings.HasPrefix(abs, `\\.\`) {
+		return abs
+	}
+	if isUNCPath(abs) {
+		return extendedPathPrefix + `UNC\` + abs[2:]
+	}
+	return extendedPathPrefix + abs
+}
+
+// longPathForAPI は os パッケージを通さずに Windows の API に渡すパスを返します。
+// Windows で長いパスは絶対パスにして拡張パスの接頭辞を付け、それ以外はそのまま返します。
+func longPathForAPI(p string) (string, error) {
+	if filepath.Separator != '\\' {
+		return p, nil
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	if len(abs) < maxShortDirPath {
+		return p, nil
+	}
+	return addExtendedPrefix(abs), nil
+}
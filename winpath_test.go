@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stripExtendedPrefix と isUNCPath のテスト
+func TestWindowsPathForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		unc      bool
+	}{
+		{name: "ドライブのパス", input: `C:\data\a.zip`, expected: `C:\data\a.zip`},
+		{name: "拡張パスのドライブ", input: `\\?\C:\data\a.zip`, expected: `C:\data\a.zip`},
+		{name: "UNCパス", input: `\\server\share\a.zip`, expected: `\\server\share\a.zip`, unc: true},
+		{name: "拡張パスのUNC", input: `\\?\UNC\server\share\a.zip`, expected: `\\server\share\a.zip`, unc: true},
+		{name: "小文字のUNCとスラッシュ", input: `//?/unc/server/share/a.zip`, expected: `\\server/share/a.zip`, unc: true},
+		{name: "デバイスのパス", input: `\\.\PhysicalDrive0`, expected: `\\.\PhysicalDrive0`},
+		{name: "相対パス", input: `data\a.zip`, expected: `data\a.zip`},
+		{name: "短いパス", input: `\\?`, expected: `\\?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripExtendedPrefix(tt.input); got != tt.expected {
+				t.Errorf("stripExtendedPrefix(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+			if got := isUNCPath(tt.input); got != tt.unc {
+				t.Errorf("isUNCPath(%q) = %v, want %v", tt.input, got, tt.unc)
+			}
+		})
+	}
+}
+
+// addExtendedPrefix のテスト
+func TestAddExtendedPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "ドライブのパス", input: `C:\data\deep`, expected: `\\?\C:\data\deep`},
+		{name: "UNCパス", input: `\\server\share\deep`, expected: `\\?\UNC\server\share\deep`},
+		{name: "スラッシュの区切り", input: `C:/data/deep`, expected: `\\?\C:\data\deep`},
+		{name: "接頭辞の付いたパス", input: `\\?\C:\data`, expected: `\\?\C:\data`},
+		{name: "接頭辞の付いたUNCパス", input: `\\?\UNC\server\share`, expected: `\\?\UNC\server\share`},
+		{name: "デバイスのパス", input: `\\.\PhysicalDrive0`, expected: `\\.\PhysicalDrive0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addExtendedPrefix(tt.input); got != tt.expected {
+				t.Errorf("addExtendedPrefix(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// longPathDir は260文字を超える深いフォルダを作成し、そのパスを返します。
+func longPathDir(t *testing.T, base string) string {
+	t.Helper()
+	dir := base
+	for i := 0; len(dir) <= 300; i++ {
+		dir = filepath.Join(dir, strings.Repeat(string(rune('a'+i%26)), 40))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// 260文字を超えるパスを -zip、-csv、フォルダの指定に渡せることのテスト
+func TestLongPaths(t *testing.T) {
+	base := t.TempDir()
+	dir := longPathDir(t, base)
+	data, err := os.ReadFile(writeTestZip(t, "dir1/a.txt", "dir1/b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(dir, "delivery.zip")
+	if err := os.WriteFile(zipPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	csvPath := filepath.Join(dir, "result.csv")
+	relDir, err := filepath.Rel(base, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		// output は結果を書き出すファイルです。空の場合は標準出力に dir1 を含むことを確かめます。
+		output string
+	}{
+		{name: "-zip", args: []string{"count", "-zip", zipPath, "-threshold", "1"}},
+		{name: "-csv", args: []string{"count", "-zip", zipPath, "-threshold", "1", "-csv", csvPath}, output: csvPath},
+		{name: "相対パス", args: []string{"count", "-zip", filepath.Join(relDir, "delivery.zip"), "-threshold", "1"}},
+		{name: "フォルダの指定", args: []string{"batch", "-threshold", "1", "-out-dir", filepath.Join(dir, "reports"), "-formats", "json", dir}, output: filepath.Join(dir, "reports", "delivery.json")},
+	}
+	t.Chdir(base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(tt.args, stdout, stderr); code != 0 {
+				t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+			}
+			out := stdout.Bytes()
+			if tt.output != "" {
+				if out, err = os.ReadFile(tt.output); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Contains(out, []byte("dir1")) {
+				t.Errorf("output does not contain dir1, got: %s", out)
+			}
+		})
+	}
+}
+
+// UNCパスと、拡張パスの接頭辞を付けたUNCパスで、260文字を超える深いフォルダのアーカイブを読み込めることのテスト (Windowsのみ)
+// 管理共有 (\\localhost\C$) を使うため、共有が使えない環境では省略します。
+func TestUNCPaths(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("UNC paths are only available on Windows")
+	}
+	base, err := filepath.Abs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume := filepath.VolumeName(base)
+	if len(volume) != 2 {
+		t.Skipf("not on a drive letter: %s", base)
+	}
+	uncBase := `\\localhost\` + volume[:1] + `$` + base[len(volume):]
+	if _, err := os.Stat(uncBase); err != nil {
+		t.Skipf("administrative share is not available: %v", err)
+	}
+	data, err := os.ReadFile(writeTestZip(t, "dir1/a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(longPathDir(t, uncBase), "delivery.zip")
+	if err := os.WriteFile(zipPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fingerprints []string
+	for _, p := range []string{zipPath, `\\?\UNC` + zipPath[1:]} {
+		entries, err := (ZipArchiveReader{}).ReadEntries(p)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("ReadEntries(%s) = %d entries, %v", p, len(entries), err)
+		}
+		fp, err := archiveFingerprint(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+	if fingerprints[0] != fingerprints[1] {
+		t.Errorf("fingerprints differ between %s and its extended form", zipPath)
+	}
+}
@@ -0,0 +1,751 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ResultWriter は集計結果を1つの出力形式で書き出します。
+type ResultWriter interface {
+	Write(w io.Writer, report *Report) error
+}
+
+// ResultWriterFunc は関数を ResultWriter として扱うためのアダプタです。
+type ResultWriterFunc func(w io.Writer, report *Report) error
+
+// Write は ResultWriter の実装です。
+func (f ResultWriterFunc) Write(w io.Writer, report *Report) error {
+	return f(w, report)
+}
+
+// WriterOptions は出力形式ごとの設定です。
+type WriterOptions struct {
+	CSV CSVOptions
+	// Color はテキスト出力で重要度に応じた色を付けるかを表します。ファイルへの出力では常に無効です。
+	Color bool
+	// Template は template 形式で使う text/template のテンプレートファイルのパスです。
+	Template string
+	// Lang はテキスト出力の見出しの言語です。
+	Lang Lang
+	// Columns は text, csv, json 形式で出力する列の名前です。空の場合は形式ごとの既定の列です。
+	Columns []string
+	// Paths はフォルダパスの区切り文字とルートフォルダの表記です。json 形式ではルートフォルダを空文字列で表します。
+	Paths FolderFormat
+}
+
+// ResultWriterFactory は設定から ResultWriter を作成します。設定が不正な場合はエラーを返します。
+type ResultWriterFactory func(opts WriterOptions) (ResultWriter, error)
+
+var (
+	resultWritersMu sync.RWMutex
+	// resultWriters は -format で指定できる出力形式の一覧です。
+	resultWriters = map[string]ResultWriterFactory{
+		"text": func(opts WriterOptions) (ResultWriter, error) {
+			return ResultWriterFunc(func(w io.Writer, report *Report) error {
+				if len(opts.Columns) > 0 {
+					return writeTextColumns(w, report, opts.Columns, opts.Color, opts.Lang)
+				}
+				return writeText(w, report, opts.Color, opts.Lang)
+			}), nil
+		},
+		"sarif": staticWriter(WriteSARIF),
+		"markdown": func(opts WriterOptions) (ResultWriter, error) {
+			return ResultWriterFunc(func(w io.Writer, report *Report) error {
+				return writeMarkdown(w, report, opts.Lang)
+			}), nil
+		},
+		"html": func(opts WriterOptions) (ResultWriter, error) {
+			return ResultWriterFunc(func(w io.Writer, report *Report) error {
+				return writeHTML(w, report, opts.Lang)
+			}), nil
+		},
+		"json": func(opts WriterOptions) (ResultWriter, error) {
+			if len(opts.Columns) > 0 {
+				return ResultWriterFunc(func(w io.Writer, report *Report) error {
+					return writeJSONColumns(w, report, opts.Columns)
+				}), nil
+			}
+			return ResultWriterFunc(WriteJSON), nil
+		},
+		"template": newTemplateWriter,
+		"csv": func(opts WriterOptions) (ResultWriter, error) {
+			if err := opts.CSV.validate(); err != nil {
+				return nil, err
+			}
+			csvOpts := opts.CSV
+			csvOpts.Columns = opts.Columns
+			return ResultWriterFunc(func(w io.Writer, report *Report) error {
+				return WriteCSVWithOptions(w, report, csvOpts)
+			}), nil
+		},
+	}
+)
+
+// staticWriter は設定を使わない書き出し関数の ResultWriterFactory を返します。
+func staticWriter(f ResultWriterFunc) ResultWriterFactory {
+	return func(WriterOptions) (ResultWriter, error) {
+		return f, nil
+	}
+}
+
+// RegisterResultWriter は出力形式を名前で登録します。同じ名前が登録済みの場合は置き換えます。
+func RegisterResultWriter(name string, factory ResultWriterFactory) {
+	resultWritersMu.Lock()
+	defer resultWritersMu.Unlock()
+	resultWriters[name] = factory
+}
+
+// NewResultWriter は出力形式名に対応する ResultWriter を作成します。空の場合は text です。
+func NewResultWriter(format string, opts WriterOptions) (ResultWriter, error) {
+	if format == "" {
+		format = "text"
+	}
+	resultWritersMu.RLock()
+	factory, ok := resultWriters[format]
+	resultWritersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+	w, err := factory(opts)
+	if err != nil {
+		return nil, err
+	}
+	// 集計キーは "/" 区切りのため、どの形式でも書き出す直前に表記を変換する
+	paths := opts.Paths
+	paths.emptyRoot = format == "json"
+	return ResultWriterFunc(func(out io.Writer, report *Report) error {
+		return w.Write(out, paths.Report(report))
+	}), nil
+}
+
+// ResultWriterNames は登録済みの出力形式名を昇順で返します。
+func ResultWriterNames() []string {
+	resultWritersMu.RLock()
+	defer resultWritersMu.RUnlock()
+	names := make([]string, 0, len(resultWriters))
+	for name := range resultWriters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OutputTarget は1つの出力先です。Path が空または "-" の場合は標準出力に書き出します。
+type OutputTarget struct {
+	Format string
+	Path   string
+	// Append が true の場合、既存のファイルを上書きせずに実行日時とアーカイブ名の列を付けた行を追記します。CSVだけが対応します。
+	Append bool
+}
+
+// ParseOutputTarget は "csv:result.csv"、"csv=result.csv" や "text" 形式の出力先の指定を解析します。
+// 形式とパスは最初の ":" または "=" で区切るため、パスには C:\ のようなドライブ名を含められます。
+func ParseOutputTarget(s string) (OutputTarget, error) {
+	format, p := s, ""
+	if i := strings.IndexAny(s, ":="); i >= 0 {
+		format, p = s[:i], s[i+1:]
+	}
+	if format == "" {
+		return OutputTarget{}, fmt.Errorf("invalid output: %q", s)
+	}
+	return OutputTarget{Format: format, Path: p}, nil
+}
+
+// ParseOutputTargets は "csv=result.csv,text=-,json=result.json" のようなカンマ区切りの出力先の一覧を解析します。
+// 空の要素は無視します。カンマを含むパスは -output を繰り返して1つずつ指定します。
+func ParseOutputTargets(s string) ([]OutputTarget, error) {
+	var targets []OutputTarget
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		t, err := ParseOutputTarget(item)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("invalid output: %q", s)
+	}
+	return targets, nil
+}
+
+// isStdout は出力先が標準出力かを判定します。
+func (t OutputTarget) isStdout() bool {
+	return t.Path == "" || t.Path == StdinPath
+}
+
+// resolvedOutput は書き出し方法が決まった出力先です。
+type resolvedOutput struct {
+	OutputTarget
+	writer ResultWriter
+}
+
+// resolveOutputs はすべての出力先の ResultWriter を作成します。
+// 長時間の解析後に失敗しないよう、集計の前に呼び出します。
+func resolveOutputs(targets []OutputTarget, opts WriterOptions) ([]resolvedOutput, error) {
+	outputs := make([]resolvedOutput, 0, len(targets))
+	for _, t := range targets {
+		targetOpts := opts
+		if !t.isStdout() {
+			targetOpts.Color = false
+		}
+		if t.Append {
+			if t.Format != "csv" || t.isStdout() {
+				return nil, fmt.Errorf("append is supported only for csv files: %s", t.Format)
+			}
+			targetOpts.CSV.RunStamp = true
+		}
+		w, err := NewResultWriter(t.Format, targetOpts)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, resolvedOutput{OutputTarget: t, writer: w})
+	}
+	return outputs, nil
+}
+
+// outputDispatcher は1つの集計結果を、形式の異なる複数の出力先 (画面とファイル) に書き出します。
+type outputDispatcher struct {
+	outputs []resolvedOutput
+	// noClobber は既存のファイルを上書きしないかです。追記する出力先には適用しません。
+	noClobber bool
+	// onFile はファイルに書き出すたびに呼び出されます。nil の場合は呼び出しません。
+	onFile func(OutputTarget)
+}
+
+// newOutputDispatcher はすべての出力先の ResultWriter を作成します。
+// 長時間の解析後に失敗しないよう、集計の前に呼び出します。同じファイルを複数回指定した場合と、
+// noClobber が true で追記しない出力先のファイルが既にある場合はエラーを返します。
+func newOutputDispatcher(targets []OutputTarget, opts WriterOptions, noClobber bool) (*outputDispatcher, error) {
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		if t.isStdout() {
+			continue
+		}
+		p := filepath.Clean(t.Path)
+		if seen[p] {
+			return nil, fmt.Errorf("output file is specified more than once: %s", t.Path)
+		}
+		seen[p] = true
+		if noClobber && !t.Append {
+			if err := checkNoClobber(t.Path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	outputs, err := resolveOutputs(targets, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &outputDispatcher{outputs: outputs, noClobber: noClobber}, nil
+}
+
+// Write は結果をすべての出力先に、指定された順に書き出します。
+// 1つの出力先に書き出せなくても残りの出力先には書き出し、すべてのエラーをまとめて返します。
+func (d *outputDispatcher) Write(stdout io.Writer, report *Report) error {
+	var errs []error
+	for _, out := range d.outputs {
+		if out.isStdout() {
+			if err := out.writer.Write(stdout, report); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		var err error
+		if out.Append {
+			err = appendCSVFile(out.Path, out.writer, report)
+		} else {
+			err = writeOutputFile(out.Path, out.writer, report, d.noClobber)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", out.Path, err))
+			continue
+		}
+		if d.onFile != nil {
+			d.onFile(out.OutputTarget)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeOutputFile は結果を一時ファイルに書いてからファイルに置き換えます。
+// noClobber が true の場合、ファイルが既にあればエラーを返します。
+func writeOutputFile(outPath string, w ResultWriter, report *Report, noClobber bool) error {
+	return writeFileAtomic(outPath, noClobber, func(file io.Writer) error {
+		if err := w.Write(file, report); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	})
+}
+
+// appendCSVFile はCSV形式の結果をファイルの末尾に追記します。
+// 新しいファイルまたは空のファイルにはBOMと見出し行も書き出し、既存のファイルには見出し行が一致する場合だけ見出し以外の行を追記します。
+// 既存の内容は書き直さず、O_APPEND で開いて1回の書き込みで追記するため、他のプロセスが同時に追記しても行は混ざりません。
+// 一時ファイルを介した置き換えではないため、書き込みの途中で中断すると途中までの行が残ります。
+func appendCSVFile(outPath string, w ResultWriter, report *Report) error {
+	var buf bytes.Buffer
+	if err := w.Write(&buf, report); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	f, err := os.OpenFile(outPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read output file: %w", err)
+	}
+	data := buf.Bytes()
+	if info.Size() > 0 {
+		if data, err = appendedRows(f, info.Size(), data); err != nil {
+			return err
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// appendedRows は既存のファイルの見出し行が data の見出し行と一致することを確かめ、data から見出し行を除いた行を返します。
+// 既存のファイルの最後の行に改行が無い場合は、追記する行と連結しないよう先頭に改行を補います。
+func appendedRows(f *os.File, size int64, data []byte) ([]byte, error) {
+	bom := []byte{0xEF, 0xBB, 0xBF}
+	header, rows, _ := bytes.Cut(bytes.TrimPrefix(data, bom), []byte("\n"))
+	// 既存の見出し行は、BOM と追記する見出し行と改行 ("\r\n") の長さまで読めば比べられる
+	head := make([]byte, min(size, int64(len(bom)+len(header)+2)))
+	if _, err := f.ReadAt(head, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+	existingHeader, _, _ := bytes.Cut(bytes.TrimPrefix(head, bom), []byte("\n"))
+	if !bytes.Equal(bytes.TrimRight(existingHeader, "\r"), header) {
+		return nil, errors.New("existing csv header does not match the appended columns")
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, size-1); err != nil {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+	if last[0] != '\n' {
+		rows = append([]byte{'\n'}, rows...)
+	}
+	return rows, nil
+}
+
+// WriteCSV は結果をCSV形式でWriterに出力します。
+func WriteCSV(w io.Writer, report *Report) error {
+	return WriteCSVWithOptions(w, report, CSVOptions{})
+}
+
+// WriteText は結果をプレーンテキストでWriterに出力します。
+func WriteText(w io.Writer, report *Report) error {
+	return writeText(w, report, false, "")
+}
+
+// writeText は結果をプレーンテキストで出力します。
+// color が true の場合は、検出事項の重要度に応じてフォルダの行に色を付けます。見出しは lang に合わせて訳します。
+func writeText(w io.Writer, report *Report, color bool, lang Lang) error {
+	if err := writeTextPreamble(w, report, color, lang); err != nil {
+		return err
+	}
+	severities := folderSeverities(report.Findings)
+	if report.Stats {
+		if err := writeTextWithStats(w, report, severities, color, lang); err != nil {
+			return err
+		}
+		return writeTextFindings(w, report.Findings, color, lang)
+	}
+	header := fmt.Sprintf("%-60s | %s", lang.T("Folder Path"), lang.T("File Count"))
+	if report.Partial != nil {
+		header += " | " + lang.T("Estimated")
+	}
+	if report.Cumulative {
+		header = fmt.Sprintf("%-60s | %10s", lang.T("Folder Path"), lang.T("File Count"))
+		if report.Partial != nil {
+			header += fmt.Sprintf(" | %10s", lang.T("Estimated"))
+		}
+		header += fmt.Sprintf(" | %11s | %10s", lang.T("% of Parent"), lang.T("% of Total"))
+	}
+	if _, err := fmt.Fprintf(w, "\n%s\n", header); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 80))
+	for _, r := range report.Folders {
+		line := fmt.Sprintf("%-60s | %d", r.Path, r.Count)
+		if report.Partial != nil {
+			line = fmt.Sprintf("%-60s | %10d | %s", r.Path, r.Count, r.estimateText())
+		}
+		if report.Cumulative {
+			line = fmt.Sprintf("%-60s | %10d", r.Path, r.Count)
+			if report.Partial != nil {
+				line += fmt.Sprintf(" | %10s", r.estimateText())
+			}
+			share := r.shareOrZero()
+			line += fmt.Sprintf(" | %10.1f%% | %9.1f%%", share.OfParent, share.OfTotal)
+		}
+		if _, err := fmt.Fprintln(w, colorize(line, severities[r.Path], color)); err != nil {
+			return err
+		}
+	}
+	return writeTextFindings(w, report.Findings, color, lang)
+}
+
+// writeTextPreamble は表の前に、アーカイブのハッシュ値と部分的な結果であることの説明を出力します。
+func writeTextPreamble(w io.Writer, report *Report, color bool, lang Lang) error {
+	if report.Info != nil || report.SHA256 != "" {
+		if _, err := fmt.Fprintf(w, "\n%s: %s\n", lang.T("Archive"), report.Archive); err != nil {
+			return err
+		}
+		if report.Info != nil {
+			for _, line := range archiveInfoLines(report.Info, lang) {
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return err
+				}
+			}
+		}
+		if report.SHA256 != "" {
+			if _, err := fmt.Fprintf(w, "SHA-256: %s\n", report.SHA256); err != nil {
+				return err
+			}
+		}
+	}
+	if report.Partial != nil {
+		if _, err := fmt.Fprintln(w, "\n"+colorize(report.Partial.notice(), SeverityWarn, color)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTextFindings は重要度が WARN 以上の検出事項をプレーンテキストで出力します。
+func writeTextFindings(w io.Writer, findings []Finding, color bool, lang Lang) error {
+	notable := notableFindings(findings)
+	if len(notable) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n%s:\n", lang.T("Findings")); err != nil {
+		return err
+	}
+	for _, f := range notable {
+		line := fmt.Sprintf("  %-5s %s", f.Severity, f.Rule)
+		if f.Folder != "" {
+			line += " " + f.Folder
+		}
+		line += ": " + f.Message
+		if _, err := fmt.Fprintln(w, colorize(line, f.Severity, color)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTextWithStats はサイズの統計の列を含めてプレーンテキストで出力します。
+func writeTextWithStats(w io.Writer, report *Report, severities map[string]Severity, color bool, lang Lang) error {
+	header := fmt.Sprintf("%-60s | %10s |", lang.T("Folder Path"), lang.T("File Count"))
+	if report.Cumulative {
+		header += fmt.Sprintf(" %11s | %10s |", lang.T("% of Parent"), lang.T("% of Total"))
+	}
+	if _, err := fmt.Fprintf(w, "\n%s %10s | %10s | %s\n", header, lang.T("Total Size"), lang.T("Avg Size"), lang.T("Largest File")); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 120))
+	for _, r := range report.Folders {
+		st := r.statsOrZero()
+		line := fmt.Sprintf("%-60s | %10d |", r.Path, r.Count)
+		if report.Cumulative {
+			share := r.shareOrZero()
+			line += fmt.Sprintf(" %10.1f%% | %9.1f%% |", share.OfParent, share.OfTotal)
+		}
+		line += fmt.Sprintf(" %10s | %10s | %s (%s)", FormatSize(st.TotalSize), FormatSize(st.AverageSize), st.LargestFile, FormatSize(st.LargestSize))
+		if _, err := fmt.Fprintln(w, colorize(line, severities[r.Path], color)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown は結果をMarkdownの表形式でWriterに出力します。
+func WriteMarkdown(w io.Writer, report *Report) error {
+	return writeMarkdown(w, report, "")
+}
+
+// writeMarkdown は結果をMarkdownの表形式で出力します。見出しは lang に合わせて訳します。
+func writeMarkdown(w io.Writer, report *Report, lang Lang) error {
+	if report.Info != nil || report.SHA256 != "" {
+		lines := []string{lang.T("Archive") + ": " + escapeMarkdown(report.Archive)}
+		if report.Info != nil {
+			for _, line := range archiveInfoLines(report.Info, lang) {
+				lines = append(lines, escapeMarkdown(line))
+			}
+		}
+		if report.SHA256 != "" {
+			lines = append(lines, "SHA-256: `"+report.SHA256+"`")
+		}
+		if _, err := fmt.Fprintf(w, "- %s\n\n", strings.Join(lines, "\n- ")); err != nil {
+			return err
+		}
+	}
+	if report.Partial != nil {
+		if _, err := fmt.Fprintf(w, "> **%s**\n\n", escapeMarkdown(report.Partial.notice())); err != nil {
+			return err
+		}
+	}
+	header, align := fmt.Sprintf("| %s | %s |", lang.T("Folder Path"), lang.T("File Count")), "| --- | ---: |"
+	if report.Partial != nil {
+		header += " " + lang.T("Estimated") + " |"
+		align += " ---: |"
+	}
+	if report.Cumulative {
+		header += fmt.Sprintf(" %s | %s |", lang.T("% of Parent"), lang.T("% of Total"))
+		align += " ---: | ---: |"
+	}
+	if report.Stats {
+		header += fmt.Sprintf(" %s | %s | %s | %s |", lang.T("Total Size"), lang.T("Avg Size"), lang.T("Largest File"), lang.T("Largest Size"))
+		align += " ---: | ---: | --- | ---: |"
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, align); err != nil {
+		return err
+	}
+	for _, r := range report.Folders {
+		line := fmt.Sprintf("| %s | %d |", escapeMarkdown(r.Path), r.Count)
+		if report.Partial != nil {
+			line += fmt.Sprintf(" %s |", r.estimateText())
+		}
+		if report.Cumulative {
+			share := r.shareOrZero()
+			line += fmt.Sprintf(" %.1f%% | %.1f%% |", share.OfParent, share.OfTotal)
+		}
+		if report.Stats {
+			st := r.statsOrZero()
+			line += fmt.Sprintf(" %s | %s | %s | %s |",
+				FormatSize(st.TotalSize), FormatSize(st.AverageSize), escapeMarkdown(st.LargestFile), FormatSize(st.LargestSize))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	notable := notableFindings(report.Findings)
+	if len(notable) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprint(w, "\n### Findings\n\n"); err != nil {
+		return err
+	}
+	for _, f := range notable {
+		line := fmt.Sprintf("- **%s** `%s`", f.Severity, f.Rule)
+		if f.Folder != "" {
+			line += " " + escapeMarkdown(f.Folder)
+		}
+		line += ": " + escapeMarkdown(f.Message)
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeMarkdown はMarkdownの表セル内で意味を持つ文字をエスケープします。(純粋関数)
+func escapeMarkdown(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '\\', '|', '*', '_', '`', '[', ']', '<', '>':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// htmlReport はHTMLレポートのテンプレートに渡すデータです。
+type htmlReport struct {
+	Archive string
+	SHA256  string
+	// Info はアーカイブ全体の情報の行です。
+	Info  []string
+	Stats bool
+	// Cumulative は割合の列を表示するかを表します。
+	Cumulative bool
+	Rows       []htmlReportRow
+	Total      int
+	Findings   []Finding
+	// Partial は部分的な結果であることの説明です。すべて読み込んだ場合は空です。
+	Partial string
+	// Lang は表の見出しの言語です。
+	Lang Lang
+}
+
+type htmlReportRow struct {
+	Path    string
+	Count   int
+	Percent float64
+	Stats   FolderStats
+	Share   FolderShare
+}
+
+// WriteHTML は結果を単一ファイルで完結するHTMLレポートとしてWriterに出力します。
+// 表は列見出しのクリックでソートでき、件数は最大値を基準とした棒グラフで表示されます。
+func WriteHTML(w io.Writer, report *Report) error {
+	return writeHTML(w, report, "")
+}
+
+// writeHTML は結果をHTMLレポートとして出力します。見出しは lang に合わせて訳します。
+func writeHTML(w io.Writer, report *Report, lang Lang) error {
+	data := htmlReport{Archive: report.Archive, SHA256: report.SHA256, Stats: report.Stats, Cumulative: report.Cumulative, Findings: notableFindings(report.Findings), Lang: lang}
+	if report.Info != nil {
+		data.Info = archiveInfoLines(report.Info, lang)
+	}
+	if report.Partial != nil {
+		data.Partial = report.Partial.notice()
+	}
+	maxCount := 0
+	for _, r := range report.Folders {
+		data.Total += r.Count
+		if r.Count > maxCount {
+			maxCount = r.Count
+		}
+	}
+	// 累積の件数は上位のフォルダと重複するため、ファイル数は集計したファイルの総数を表示する
+	if report.Cumulative {
+		data.Total = report.TotalFiles
+	}
+	for _, r := range report.Folders {
+		row := htmlReportRow{Path: r.Path, Count: r.Count, Stats: r.statsOrZero(), Share: r.shareOrZero()}
+		if maxCount > 0 {
+			row.Percent = float64(r.Count) * 100 / float64(maxCount)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return htmlReportTemplate.Execute(w, data)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{"size": FormatSize}).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>Folder Count Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+td.count { text-align: right; white-space: nowrap; }
+td.bar { width: 30%; }
+.bar-fill { background: #4a90d9; height: 1em; }
+.WARN { color: #b58900; }
+.ERROR { color: #dc322f; }
+</style>
+</head>
+<body>
+<h1>Folder Count Report</h1>
+{{- if or .SHA256 .Info}}
+<p>{{.Lang.T "Archive"}}: {{.Archive}}{{range .Info}}<br>{{.}}{{end}}{{if .SHA256}}<br>SHA-256: <code>{{.SHA256}}</code>{{end}}</p>
+{{- end}}
+{{- if .Partial}}
+<p class="WARN"><strong>{{.Partial}}</strong></p>
+{{- end}}
+<p>Folders: {{len .Rows}} / Files: {{.Total}}</p>
+<table id="report">
+<thead>
+<tr><th data-type="string">{{.Lang.T "Folder Path"}}</th><th data-type="number">{{.Lang.T "File Count"}}</th>
+{{- if .Cumulative}}<th data-type="number">{{.Lang.T "% of Parent"}}</th><th data-type="number">{{.Lang.T "% of Total"}}</th>{{end -}}
+{{- if .Stats}}<th data-type="number">{{.Lang.T "Total Size"}}</th><th data-type="number">{{.Lang.T "Avg Size"}}</th><th data-type="string">{{.Lang.T "Largest File"}}</th>{{end -}}
+<th></th></tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr><td>{{.Path}}</td><td class="count">{{.Count}}</td>
+{{- if $.Cumulative}}<td class="count" data-value="{{.Share.OfParent}}">{{printf "%.1f" .Share.OfParent}}%</td><td class="count" data-value="{{.Share.OfTotal}}">{{printf "%.1f" .Share.OfTotal}}%</td>{{end -}}
+{{- if $.Stats}}<td class="count" data-value="{{.Stats.TotalSize}}">{{size .Stats.TotalSize}}</td><td class="count" data-value="{{.Stats.AverageSize}}">{{size .Stats.AverageSize}}</td><td>{{.Stats.LargestFile}} ({{size .Stats.LargestSize}})</td>{{end -}}
+<td class="bar"><div class="bar-fill" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- if .Findings}}
+<h2>{{.Lang.T "Findings"}}</h2>
+<ul>
+{{- range .Findings}}
+<li class="{{.Severity}}"><strong>{{.Severity}}</strong> <code>{{.Rule}}</code>{{if .Folder}} {{.Folder}}{{end}}: {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+<script>
+document.querySelectorAll("#report th[data-type]").forEach(function (th, col) {
+  var asc = false;
+  th.addEventListener("click", function () {
+    asc = !asc;
+    var tbody = document.querySelector("#report tbody");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    var numeric = th.dataset.type === "number";
+    rows.sort(function (a, b) {
+      var x = a.cells[col].dataset.value || a.cells[col].textContent;
+      var y = b.cells[col].dataset.value || b.cells[col].textContent;
+      var c = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+      return asc ? c : -c;
+    });
+    rows.forEach(function (r) { tbody.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// statsOrZero はサイズの統計を返します。統計を求めていない場合はゼロ値を返します。
+func (r FolderCount) statsOrZero() FolderStats {
+	if r.Stats == nil {
+		return FolderStats{}
+	}
+	return *r.Stats
+}
+
+// shareOrZero は割合を返します。累積モードでない場合はゼロ値を返します。
+func (r FolderCount) shareOrZero() FolderShare {
+	if r.Share == nil {
+		return FolderShare{}
+	}
+	return *r.Share
+}
+
+// FormatSize はバイト数を "1.5 MB" のような読みやすい表記に変換します。(純粋関数)
+func FormatSize(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit && exp < 4; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// WriteJSON は結果とアーカイブの情報をJSON形式でWriterに出力します。
+func WriteJSON(w io.Writer, report *Report) error {
+	out := *report
+	if out.Folders == nil {
+		out.Folders = []FolderCount{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ResultWriter の登録と作成のテスト
+func TestResultWriterRegistry(t *testing.T) {
+	RegisterResultWriter("count-only", staticWriter(func(w io.Writer, report *Report) error {
+		_, err := fmt.Fprintf(w, "%d", report.TotalFiles)
+		return err
+	}))
+	t.Cleanup(func() {
+		resultWritersMu.Lock()
+		delete(resultWriters, "count-only")
+		resultWritersMu.Unlock()
+	})
+
+	w, err := NewResultWriter("count-only", WriterOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := new(bytes.Buffer)
+	if err := w.Write(out, &Report{TotalFiles: 7}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "7" {
+		t.Errorf("expected %q, got %q", "7", out.String())
+	}
+
+	if _, err := NewResultWriter("xlsx", WriterOptions{}); err == nil {
+		t.Error("expected error for unknown format, got nil")
+	}
+	if _, err := NewResultWriter("csv", WriterOptions{CSV: CSVOptions{Encoding: "utf16"}}); err == nil {
+		t.Error("expected error for invalid csv options, got nil")
+	}
+}
+
+// ParseOutputTarget のテスト
+func TestParseOutputTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected OutputTarget
+		wantErr  bool
+	}{
+		{input: "text", expected: OutputTarget{Format: "text"}},
+		{input: "csv:out.csv", expected: OutputTarget{Format: "csv", Path: "out.csv"}},
+		{input: `json:C:\out\r.json`, expected: OutputTarget{Format: "json", Path: `C:\out\r.json`}},
+		{input: "csv=out.csv", expected: OutputTarget{Format: "csv", Path: "out.csv"}},
+		{input: `json=C:\out\r.json`, expected: OutputTarget{Format: "json", Path: `C:\out\r.json`}},
+		{input: "text=-", expected: OutputTarget{Format: "text", Path: "-"}},
+		{input: ":out.csv", wantErr: true},
+		{input: "=out.csv", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseOutputTarget(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ParseOutputTargets のテスト
+func TestParseOutputTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []OutputTarget
+		wantErr  bool
+	}{
+		{name: "1つ", input: "text", expected: []OutputTarget{{Format: "text"}}},
+		{
+			name:     "カンマ区切り",
+			input:    "csv=out.csv, text=-,json:C:\\out\\r.json",
+			expected: []OutputTarget{{Format: "csv", Path: "out.csv"}, {Format: "text", Path: "-"}, {Format: "json", Path: `C:\out\r.json`}},
+		},
+		{name: "空の要素は無視", input: "text,,csv=a.csv,", expected: []OutputTarget{{Format: "text"}, {Format: "csv", Path: "a.csv"}}},
+		{name: "空", input: " , ", wantErr: true},
+		{name: "形式の無い要素", input: "text,=a.csv", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutputTargets(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// outputDispatcher のテスト
+func TestOutputDispatcher(t *testing.T) {
+	report := &Report{Folders: []FolderCount{{Path: "dir1", Count: 2}}, TotalFiles: 2}
+	dir := t.TempDir()
+
+	t.Run("同じファイルの重複指定", func(t *testing.T) {
+		p := filepath.Join(dir, "out.csv")
+		_, err := newOutputDispatcher([]OutputTarget{{Format: "csv", Path: p}, {Format: "json", Path: filepath.Join(dir, ".", "out.csv")}}, WriterOptions{}, false)
+		if err == nil || !strings.Contains(err.Error(), "more than once") {
+			t.Errorf("expected duplicate error, got %v", err)
+		}
+	})
+
+	t.Run("書き出せない出力先があっても残りに書き出す", func(t *testing.T) {
+		bad := filepath.Join(dir, "missing", "out.csv")
+		good := filepath.Join(dir, "out.json")
+		d, err := newOutputDispatcher([]OutputTarget{{Format: "csv", Path: bad}, {Format: "text", Path: "-"}, {Format: "json", Path: good}}, WriterOptions{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var written []string
+		d.onFile = func(t OutputTarget) { written = append(written, t.Path) }
+		out := new(bytes.Buffer)
+		err = d.Write(out, report)
+		if err == nil || !strings.Contains(err.Error(), bad) {
+			t.Errorf("expected error for %s, got %v", bad, err)
+		}
+		if !strings.Contains(out.String(), "dir1") {
+			t.Errorf("stdout does not contain text report, got: %s", out.String())
+		}
+		if !reflect.DeepEqual(written, []string{good}) {
+			t.Errorf("expected %v, got %v", []string{good}, written)
+		}
+	})
+}
+
+// 複数の出力先への書き出しのテスト (CSVファイルと画面表示の併用)
+func TestAppRunMultipleOutputs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+	app := &App{
+		Reader: MockArchiveReader{Entries: []FileEntry{{Name: "dir1/a.txt"}, {Name: "dir1/b.txt"}}},
+		Logger: logger,
+	}
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "out.csv")
+	jsonPath := filepath.Join(dir, "out.json")
+	cfg := AppConfig{
+		ZipPath:   "dummy.zip",
+		Threshold: 1,
+		CsvPath:   csvPath,
+		Outputs:   []OutputTarget{{Format: "text"}, {Format: "json", Path: jsonPath}},
+	}
+
+	out := new(bytes.Buffer)
+	if err := app.Run(cfg, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("dir1")) {
+		t.Errorf("stdout does not contain text report, got: %s", out.String())
+	}
+	for _, p := range []string{csvPath, jsonPath} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", p, err)
+		}
+		if !bytes.Contains(data, []byte("dir1")) {
+			t.Errorf("%s does not contain report, got: %s", filepath.Base(p), data)
+		}
+	}
+}
+
+// count の -csv と -format、-output の一覧指定で画面とファイルに同時に出力するテスト
+func TestRunCLIDualOutput(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/b.txt")
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "out.csv")
+	jsonPath := filepath.Join(dir, "out.json")
+
+	tests := []struct {
+		name        string
+		args        []string
+		files       []string
+		expectedOut string
+	}{
+		{name: "-csv だけは画面に表示しない", args: []string{"-csv", csvPath}, files: []string{csvPath}},
+		{name: "-csv と -format", args: []string{"-csv", csvPath, "-format", "text"}, files: []string{csvPath}, expectedOut: "Folder Path"},
+		{name: "-output の一覧", args: []string{"-output", "csv=" + csvPath + ",text=-,json=" + jsonPath}, files: []string{csvPath, jsonPath}, expectedOut: "Folder Path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, p := range []string{csvPath, jsonPath} {
+				os.Remove(p)
+			}
+			args := append([]string{"count", "-zip", zipPath, "-threshold", "1"}, tt.args...)
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			if code := runCLI(args, stdout, stderr); code != 0 {
+				t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+			}
+			if tt.expectedOut == "" && stdout.Len() != 0 {
+				t.Errorf("expected no stdout, got: %s", stdout.String())
+			}
+			if !strings.Contains(stdout.String(), tt.expectedOut) {
+				t.Errorf("output does not contain %q, got: %s", tt.expectedOut, stdout.String())
+			}
+			for _, p := range tt.files {
+				if data, err := os.ReadFile(p); err != nil || !bytes.Contains(data, []byte("dir1")) {
+					t.Errorf("%s does not contain report: %q, %v", filepath.Base(p), data, err)
+				}
+			}
+		})
+	}
+}
+
+// appendCSVFile のテスト
+func TestAppendCSVFile(t *testing.T) {
+	dir := t.TempDir()
+	report := func(day int) *Report {
+		return &Report{
+			Archive: "inbox/daily.zip",
+			RunAt:   time.Date(2024, 4, day, 0, 0, 0, 0, time.UTC),
+			Folders: []FolderCount{{Path: "dir1", Count: day}},
+		}
+	}
+	writer := func(t *testing.T, csv CSVOptions) ResultWriter {
+		t.Helper()
+		outputs, err := resolveOutputs([]OutputTarget{{Format: "csv", Path: "x.csv", Append: true}}, WriterOptions{CSV: csv})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return outputs[0].writer
+	}
+
+	t.Run("見出し行は新しいファイルにだけ出力", func(t *testing.T) {
+		p := filepath.Join(dir, "daily.csv")
+		w := writer(t, CSVOptions{})
+		for day := 1; day <= 2; day++ {
+			if err := appendCSVFile(p, w, report(day)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "\xEF\xBB\xBFRun Timestamp,Archive,Folder Path,File Count\n" +
+			"2024-04-01T00:00:00Z,daily.zip,dir1,1\n" +
+			"2024-04-02T00:00:00Z,daily.zip,dir1,2\n"
+		if string(data) != expected {
+			t.Errorf("expected %q, got %q", expected, data)
+		}
+	})
+
+	t.Run("既存のファイルを置き換えずに追記", func(t *testing.T) {
+		p := filepath.Join(dir, "inplace.csv")
+		w := writer(t, CSVOptions{})
+		if err := appendCSVFile(p, w, report(1)); err != nil {
+			t.Fatal(err)
+		}
+		before, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := appendCSVFile(p, w, report(2)); err != nil {
+			t.Fatal(err)
+		}
+		if after, err := os.Stat(p); err != nil || !os.SameFile(before, after) {
+			t.Errorf("file should be appended in place, got %v", err)
+		}
+	})
+
+	t.Run("末尾に改行の無いファイル", func(t *testing.T) {
+		p := filepath.Join(dir, "noeol.csv")
+		if err := os.WriteFile(p, []byte("Run Timestamp,Archive,Folder Path,File Count\r\nold,a.zip,x,1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := appendCSVFile(p, writer(t, CSVOptions{Encoding: "utf8"}), report(3)); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(p)
+		if !strings.HasSuffix(string(data), "old,a.zip,x,1\n2024-04-03T00:00:00Z,daily.zip,dir1,3\n") {
+			t.Errorf("unexpected content: %q", data)
+		}
+	})
+
+	t.Run("見出し行の異なるファイル", func(t *testing.T) {
+		p := filepath.Join(dir, "other.csv")
+		original := "Folder Path,File Count\ndir1,1\n"
+		if err := os.WriteFile(p, []byte(original), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := appendCSVFile(p, writer(t, CSVOptions{Encoding: "utf8"}), report(1))
+		if err == nil || !strings.Contains(err.Error(), "header does not match") {
+			t.Errorf("expected header mismatch, got %v", err)
+		}
+		if data, _ := os.ReadFile(p); string(data) != original {
+			t.Errorf("file should not be changed, got %q", data)
+		}
+	})
+
+	t.Run("CSV以外の形式", func(t *testing.T) {
+		if _, err := resolveOutputs([]OutputTarget{{Format: "json", Path: "x.json", Append: true}}, WriterOptions{}); err == nil {
+			t.Error("expected error for json append")
+		}
+	})
+}
+
+// count の -csv-append のテスト
+func TestRunCLICSVAppend(t *testing.T) {
+	zipPath := writeTestZip(t, "dir1/a.txt", "dir1/b.txt")
+	csvPath := filepath.Join(t.TempDir(), "daily.csv")
+	for range 2 {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		if code := runCLI([]string{"count", "-zip", zipPath, "-threshold", "1", "-csv-append", csvPath, "-csv-encoding", "utf8"}, stdout, stderr); code != 0 {
+			t.Fatalf("expected code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("expected no stdout, got: %s", stdout.String())
+		}
+	}
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != "Run Timestamp,Archive,Folder Path,File Count" {
+		t.Fatalf("expected one header and two rows, got %q", lines)
+	}
+	for _, line := range lines[1:] {
+		if !strings.HasSuffix(line, ",test.zip,dir1,2") {
+			t.Errorf("unexpected row %q", line)
+		}
+	}
+}
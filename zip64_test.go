@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-ObuZipCount/obuziptest"
+)
+
+// zip64TestEntries は65535件を超え、一部のファイルが4GBを超えるエントリを作成します。
+// dir0 のファイルだけを 5GB とし、それ以外は 1KB とします。
+func zip64TestEntries(n int) []obuziptest.SyntheticEntry {
+	entries := make([]obuziptest.SyntheticEntry, n)
+	for i := range entries {
+		size := uint64(1 << 10)
+		if i%4 == 0 {
+			size = 5 << 30
+		}
+		entries[i] = obuziptest.SyntheticEntry{
+			Name:           fmt.Sprintf("dir%d/file%06d.bin", i%4, i),
+			Size:           size,
+			CompressedSize: size / 2,
+			Offset:         uint64(i) << 20,
+		}
+	}
+	return entries
+}
+
+// ZIP64のアーカイブの集計のテスト
+func TestZip64LargeArchive(t *testing.T) {
+	const n = 70000
+	zipPath := obuziptest.WriteSynthetic(t, zip64TestEntries(n))
+
+	entries, err := (ZipArchiveReader{}).ReadEntries(zipPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != n {
+		t.Fatalf("expected %d entries, got %d", n, len(entries))
+	}
+
+	results, total := Aggregate(entries, AggregateOptions{Stats: true})
+	if total != n || len(results) != 4 {
+		t.Fatalf("unexpected aggregate: %d files, %+v", total, results)
+	}
+	for _, r := range results {
+		if r.Count != n/4 {
+			t.Errorf("%s: expected %d files, got %d", r.Path, n/4, r.Count)
+		}
+		want := FolderStats{TotalSize: n / 4 << 10, AverageSize: 1 << 10, LargestFile: r.Stats.LargestFile, LargestSize: 1 << 10}
+		if r.Path == "dir0" {
+			want = FolderStats{TotalSize: n / 4 * (5 << 30), AverageSize: 5 << 30, LargestFile: "file000000.bin", LargestSize: 5 << 30}
+		}
+		if *r.Stats != want {
+			t.Errorf("%s: expected %+v, got %+v", r.Path, want, *r.Stats)
+		}
+	}
+	if s := FormatSize(n / 4 * (5 << 30)); s != "85.4 TB" {
+		t.Errorf("unexpected formatted size: %s", s)
+	}
+
+	report := ComputeCompression(entries, AggregateOptions{}, 1)
+	wantTotal := uint64(n/4)*(5<<30) + uint64(n-n/4)<<10
+	if report.UncompressedSize != wantTotal || report.CompressedSize != wantTotal/2 || report.Ratio != 0.5 {
+		t.Errorf("unexpected compression totals: %+v", report)
+	}
+}
+
+// ZIP64の終端レコードの読み込みのテスト
+func TestZip64EndOfCentralDirectory(t *testing.T) {
+	entries := zip64TestEntries(70000)
+	data := obuziptest.Synthetic(entries)
+
+	offset, size, err := centralDirectoryRange(data[max(0, len(data)-zipTailSize):], bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// セントラルディレクトリの後に ZIP64 の終端レコード (56)、ロケータ (20)、終端レコード (22) が続く
+	if !bytes.HasPrefix(data[offset:], []byte("PK\x01\x02")) || offset+size != int64(len(data)-56-20-22) {
+		t.Errorf("unexpected central directory range: %d+%d", offset, size)
+	}
+
+	disk, err := zipLastDisk(bytes.NewReader(data), int64(len(data)))
+	if err != nil || disk != 0 {
+		t.Errorf("expected single volume, got %d, %v", disk, err)
+	}
+
+	t.Run("URLからの読み込み", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "a.zip", time.Time{}, bytes.NewReader(data))
+		}))
+		defer server.Close()
+		got, err := (ZipArchiveReader{}).ReadEntries(server.URL + "/a.zip")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != len(entries) || got[0].Size != 5<<30 {
+			t.Errorf("unexpected entries: %d, %+v", len(got), got[0])
+		}
+	})
+}
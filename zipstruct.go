@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ZIPの構造上の問題の種類です。
+const (
+	ProblemCentralDirectory  = "central directory"
+	ProblemBadLocalHeader    = "bad local header"
+	ProblemHeaderMismatch    = "header mismatch"
+	ProblemCRCMismatch       = "CRC mismatch"
+	ProblemUnsupportedMethod = "unsupported method"
+	ProblemDataOutOfRange    = "data out of range"
+)
+
+// structureProblems は構造上の問題の種類を、レポートに出力する順に並べたものです。
+var structureProblems = []string{
+	ProblemCentralDirectory, ProblemBadLocalHeader, ProblemHeaderMismatch,
+	ProblemCRCMismatch, ProblemUnsupportedMethod, ProblemDataOutOfRange,
+}
+
+// zipMethodNames はZIPの圧縮方式の番号と名前です。archive/zip で展開できるのは store と deflate だけです。
+var zipMethodNames = map[uint16]string{
+	0: "store", 1: "shrink", 6: "implode", 8: "deflate", 9: "deflate64", 12: "bzip2",
+	14: "lzma", 93: "zstd", 95: "xz", 96: "jpeg", 97: "wavpack", 98: "ppmd", 99: "aes",
+}
+
+// ZIPのヘッダの固定長部分の大きさと、データディスクリプタを使うことを表すフラグです。
+const (
+	centralHeaderLen = 46
+	localHeaderLen   = 30
+	flagDataDesc     = 0x8
+)
+
+// zipEnd はセントラルディレクトリの終端レコードの内容です。
+type zipEnd struct {
+	// base はアーカイブの前に付加されたデータ (自己解凍形式など) の大きさです。
+	base    int64
+	offset  int64
+	size    int64
+	entries int64
+	// zip64 はZIP64の終端レコードを持つかを表します。
+	zip64 bool
+	// comment はアーカイブのコメントの復号前の文字列です。
+	comment string
+}
+
+// readZipEnd は末尾の終端レコード (ZIP64の場合はZIP64の終端レコード) を読み込みます。
+func readZipEnd(r io.ReaderAt, size int64) (zipEnd, error) {
+	tailStart := max(size-zipTailSize, 0)
+	tail := make([]byte, size-tailStart)
+	if _, err := r.ReadAt(tail, tailStart); err != nil && !errors.Is(err, io.EOF) {
+		return zipEnd{}, fmt.Errorf("failed to read zip: %w", err)
+	}
+	i := bytes.LastIndex(tail, []byte("PK\x05\x06"))
+	if i < 0 || len(tail)-i < 22 {
+		return zipEnd{}, errors.New("end of central directory not found")
+	}
+	eocd := tail[i:]
+	end := zipEnd{
+		entries: int64(binary.LittleEndian.Uint16(eocd[10:])),
+		size:    int64(binary.LittleEndian.Uint32(eocd[12:])),
+		offset:  int64(binary.LittleEndian.Uint32(eocd[16:])),
+	}
+	if n := int(binary.LittleEndian.Uint16(eocd[20:])); 22+n <= len(eocd) {
+		end.comment = string(eocd[22 : 22+n])
+	}
+	cdEnd := tailStart + int64(i)
+	if i >= 20 && bytes.Equal(tail[i-20:i-16], []byte("PK\x06\x07")) {
+		pos := int64(binary.LittleEndian.Uint64(tail[i-12:]))
+		rec := make([]byte, 56)
+		if _, err := r.ReadAt(rec, pos); err != nil || !bytes.Equal(rec[:4], []byte("PK\x06\x06")) {
+			return zipEnd{}, errors.New("invalid zip64 end of central directory")
+		}
+		end.entries = int64(binary.LittleEndian.Uint64(rec[32:]))
+		end.size = int64(binary.LittleEndian.Uint64(rec[40:]))
+		end.offset = int64(binary.LittleEndian.Uint64(rec[48:]))
+		end.zip64 = true
+		cdEnd = pos
+	}
+	end.base = cdEnd - end.offset - end.size
+	if end.offset < 0 || end.size < 0 || end.base < 0 {
+		return zipEnd{}, errors.New("central directory is out of range")
+	}
+	return end, nil
+}
+
+// centralRecord はセントラルディレクトリの1件のレコードのうち、ローカルファイルヘッダとの照合に使う値です。
+type centralRecord struct {
+	name           string
+	flags          uint16
+	method         uint16
+	crc32          uint32
+	compressedSize uint64
+	headerOffset   int64
+}
+
+// parseCentralDirectory はセントラルディレクトリのレコードを順に読み込みます。(純粋関数)
+// 途中で壊れたレコードが見つかった場合は、そこまでのレコードと問題を返します。
+func parseCentralDirectory(cd []byte) ([]centralRecord, *ValidationIssue) {
+	var records []centralRecord
+	for pos := 0; pos < len(cd); {
+		rec := cd[pos:]
+		if len(rec) < centralHeaderLen || !bytes.Equal(rec[:4], []byte("PK\x01\x02")) {
+			return records, &ValidationIssue{Problem: ProblemCentralDirectory, Detail: fmt.Sprintf("invalid record at offset %d", pos)}
+		}
+		nameLen := int(binary.LittleEndian.Uint16(rec[28:]))
+		extraLen := int(binary.LittleEndian.Uint16(rec[30:]))
+		commentLen := int(binary.LittleEndian.Uint16(rec[32:]))
+		n := centralHeaderLen + nameLen + extraLen + commentLen
+		if len(rec) < n {
+			return records, &ValidationIssue{Problem: ProblemCentralDirectory, Detail: fmt.Sprintf("truncated record at offset %d", pos)}
+		}
+		r := centralRecord{
+			name:           string(rec[centralHeaderLen : centralHeaderLen+nameLen]),
+			flags:          binary.LittleEndian.Uint16(rec[8:]),
+			method:         binary.LittleEndian.Uint16(rec[10:]),
+			crc32:          binary.LittleEndian.Uint32(rec[16:]),
+			compressedSize: uint64(binary.LittleEndian.Uint32(rec[20:])),
+			headerOffset:   int64(binary.LittleEndian.Uint32(rec[42:])),
+		}
+		applyZip64Extra(&r, binary.LittleEndian.Uint32(rec[24:]), rec[centralHeaderLen+nameLen:centralHeaderLen+nameLen+extraLen])
+		records = append(records, r)
+		pos += n
+	}
+	return records, nil
+}
+
+// applyZip64Extra は 0xFFFFFFFF が記録された値を、ZIP64の拡張フィールドの値で置き換えます。
+// 拡張フィールドには展開後のサイズ、圧縮後のサイズ、ヘッダの位置のうち置き換えるものだけがこの順に記録されます。
+func applyZip64Extra(r *centralRecord, uncompressedSize uint32, extra []byte) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		n := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+n {
+			return
+		}
+		field := extra[4 : 4+n]
+		extra = extra[4+n:]
+		if id != 0x0001 {
+			continue
+		}
+		next := func() (uint64, bool) {
+			if len(field) < 8 {
+				return 0, false
+			}
+			v := binary.LittleEndian.Uint64(field)
+			field = field[8:]
+			return v, true
+		}
+		if uncompressedSize == 0xFFFFFFFF {
+			next()
+		}
+		if r.compressedSize == 0xFFFFFFFF {
+			if v, ok := next(); ok {
+				r.compressedSize = v
+			}
+		}
+		if r.headerOffset == 0xFFFFFFFF {
+			if v, ok := next(); ok {
+				r.headerOffset = int64(v)
+			}
+		}
+		return
+	}
+}
+
+// InspectZip はZIPのセントラルディレクトリとローカルファイルヘッダを照合し、構造上の問題を返します。
+// エントリの内容は展開しないため、アーカイブの大きさによらずヘッダの読み込みだけで終わります。
+// 終端レコードが見つからないなど、構造を読み取れない場合はエラーを返します。
+func InspectZip(r io.ReaderAt, size int64) ([]ValidationIssue, error) {
+	end, err := readZipEnd(r, size)
+	if err != nil {
+		return nil, err
+	}
+	cdStart := end.base + end.offset
+	if cdStart+end.size > size {
+		return nil, errors.New("central directory is out of range")
+	}
+	cd := make([]byte, end.size)
+	if _, err := r.ReadAt(cd, cdStart); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read central directory: %w", err)
+	}
+
+	records, broken := parseCentralDirectory(cd)
+	var issues []ValidationIssue
+	if broken != nil {
+		issues = append(issues, *broken)
+	}
+	if int64(len(records)) != end.entries {
+		issues = append(issues, ValidationIssue{
+			Problem: ProblemCentralDirectory,
+			Detail:  fmt.Sprintf("end record says %d entries, found %d", end.entries, len(records)),
+		})
+	}
+	for _, rec := range records {
+		issues = append(issues, inspectLocalHeader(r, end.base, cdStart, rec)...)
+	}
+	return issues, nil
+}
+
+// inspectLocalHeader は1件のエントリのローカルファイルヘッダを、セントラルディレクトリのレコードと照合します。
+func inspectLocalHeader(r io.ReaderAt, base, cdStart int64, rec centralRecord) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(problem, detail string) {
+		issues = append(issues, ValidationIssue{Name: rec.name, Problem: problem, Detail: detail})
+	}
+	if rec.method != 0 && rec.method != 8 {
+		name, ok := zipMethodNames[rec.method]
+		if !ok {
+			name = "unknown"
+		}
+		add(ProblemUnsupportedMethod, fmt.Sprintf("method %d (%s)", rec.method, name))
+	}
+
+	offset := base + rec.headerOffset
+	hdr := make([]byte, localHeaderLen)
+	if offset+localHeaderLen > cdStart {
+		add(ProblemBadLocalHeader, fmt.Sprintf("offset %d is out of range", rec.headerOffset))
+		return issues
+	}
+	if _, err := r.ReadAt(hdr, offset); err != nil || !bytes.Equal(hdr[:4], []byte("PK\x03\x04")) {
+		add(ProblemBadLocalHeader, fmt.Sprintf("no local header at offset %d", rec.headerOffset))
+		return issues
+	}
+	nameLen := int64(binary.LittleEndian.Uint16(hdr[26:]))
+	extraLen := int64(binary.LittleEndian.Uint16(hdr[28:]))
+	name := make([]byte, nameLen)
+	if _, err := r.ReadAt(name, offset+localHeaderLen); err != nil {
+		add(ProblemBadLocalHeader, "name is truncated")
+		return issues
+	}
+	if string(name) != rec.name {
+		add(ProblemHeaderMismatch, fmt.Sprintf("local name %q", name))
+	}
+	if method := binary.LittleEndian.Uint16(hdr[8:]); method != rec.method {
+		add(ProblemHeaderMismatch, fmt.Sprintf("local method %d, central method %d", method, rec.method))
+	}
+	// データディスクリプタを使う場合、ローカルファイルヘッダのCRCは0が記録される
+	if rec.flags&flagDataDesc == 0 {
+		if crc := binary.LittleEndian.Uint32(hdr[14:]); crc != rec.crc32 {
+			add(ProblemCRCMismatch, fmt.Sprintf("local %08x, central %08x", crc, rec.crc32))
+		}
+	}
+	dataStart := offset + localHeaderLen + nameLen + extraLen
+	if rec.compressedSize > uint64(cdStart-min(dataStart, cdStart)) {
+		add(ProblemDataOutOfRange, fmt.Sprintf("%d bytes of data from offset %d overrun the central directory", rec.compressedSize, dataStart-base))
+	}
+	return issues
+}
+
+// inspectZipFile はローカルのZIPファイルの構造を検査します。
+func inspectZipFile(zipPath string) ([]ValidationIssue, error) {
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip: %w", err)
+	}
+	return InspectZip(f, info.Size())
+}
+
+// canInspectZip はパスがヘッダの構造を検査できる単一のローカルのZIPファイルかを返します。
+// URL、標準入力、分割アーカイブ、ZIP以外の形式と、開く前の検査で問題のあるファイルは検査しません。
+func canInspectZip(archivePath string) bool {
+	if isRemotePath(archivePath) || archivePath == StdinPath || len(splitVolumes(archivePath)) > 1 {
+		return false
+	}
+	if _, ok := registeredArchiveReader(archivePath); ok || CheckArchiveFile(archivePath) != nil {
+		return false
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return detectArchiveKind(f) == "zip"
+}
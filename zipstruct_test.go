@@ -0,0 +1,121 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+// rawZip は圧縮せずに格納したエントリのZIPを作成し、内容と各ローカルファイルヘッダの位置を返します。
+func rawZip(t *testing.T, method uint16, names ...string) ([]byte, []int) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	var offsets []int
+	for _, name := range names {
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, buf.Len())
+		data := []byte("content of " + name)
+		fw, err := w.CreateRaw(&zip.FileHeader{
+			Name:               name,
+			Method:             method,
+			CRC32:              crc32.ChecksumIEEE(data),
+			CompressedSize64:   uint64(len(data)),
+			UncompressedSize64: uint64(len(data)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), offsets
+}
+
+// InspectZip のテスト
+func TestInspectZip(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   uint16
+		corrupt  func(data []byte, offsets []int)
+		expected []ValidationIssue
+	}{
+		{name: "問題のないZIP", corrupt: func([]byte, []int) {}},
+		{
+			name:     "ローカルファイルヘッダのCRCが異なる",
+			corrupt:  func(data []byte, offsets []int) { data[offsets[1]+14] ^= 0xFF },
+			expected: []ValidationIssue{{Name: "dir/b.txt", Problem: ProblemCRCMismatch}},
+		},
+		{
+			name:     "ローカルファイルヘッダのシグネチャが壊れている",
+			corrupt:  func(data []byte, offsets []int) { data[offsets[0]] = 'X' },
+			expected: []ValidationIssue{{Name: "a.txt", Problem: ProblemBadLocalHeader}},
+		},
+		{
+			name:     "ローカルファイルヘッダの名前が異なる",
+			corrupt:  func(data []byte, offsets []int) { data[offsets[1]+30] = 'D' },
+			expected: []ValidationIssue{{Name: "dir/b.txt", Problem: ProblemHeaderMismatch}},
+		},
+		{
+			name:    "未対応の圧縮方式",
+			method:  14,
+			corrupt: func([]byte, []int) {},
+			expected: []ValidationIssue{
+				{Name: "a.txt", Problem: ProblemUnsupportedMethod, Detail: "method 14 (lzma)"},
+				{Name: "dir/b.txt", Problem: ProblemUnsupportedMethod, Detail: "method 14 (lzma)"},
+			},
+		},
+		{
+			name: "圧縮後のサイズがセントラルディレクトリを越える",
+			corrupt: func(data []byte, offsets []int) {
+				cd := bytes.Index(data, []byte("PK\x01\x02"))
+				binary.LittleEndian.PutUint32(data[cd+20:], 1<<20)
+			},
+			expected: []ValidationIssue{{Name: "a.txt", Problem: ProblemDataOutOfRange}},
+		},
+		{
+			name: "終端レコードのエントリ数が異なる",
+			corrupt: func(data []byte, offsets []int) {
+				eocd := bytes.LastIndex(data, []byte("PK\x05\x06"))
+				binary.LittleEndian.PutUint16(data[eocd+10:], 3)
+			},
+			expected: []ValidationIssue{{Problem: ProblemCentralDirectory, Detail: "end record says 3 entries, found 2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, offsets := rawZip(t, tt.method, "a.txt", "dir/b.txt")
+			tt.corrupt(data, offsets)
+			issues, err := InspectZip(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// 詳細は期待値に指定した場合だけ比較する
+			for i := range issues {
+				if i < len(tt.expected) && tt.expected[i].Detail == "" {
+					issues[i].Detail = ""
+				}
+			}
+			if !reflect.DeepEqual(issues, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, issues)
+			}
+		})
+	}
+
+	t.Run("終端レコードがない", func(t *testing.T) {
+		data, _ := rawZip(t, zip.Store, "a.txt")
+		data = data[:bytes.LastIndex(data, []byte("PK\x05\x06"))]
+		if _, err := InspectZip(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}